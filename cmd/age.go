@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// ageCmd represents the age command
+var ageCmd = &cobra.Command{
+	Use:   "age [IMAGE]",
+	Short: "Shows how old the files of each layer are and lists stale content.",
+	Long: `Shows a histogram of file ages (by modification time) for each layer, followed by the oldest files in the final
+image (e.g. an old vendored copy of a library in /opt), which helps prioritize refresh work.
+
+Files with modification times clamped to the epoch (as done by reproducible builds) are not counted.`,
+	Args: cobra.ExactArgs(1),
+	Run:  doAge,
+}
+
+func init() {
+	rootCmd.AddCommand(ageCmd)
+	ageCmd.Flags().Int("stale-years", 3, "list files last modified more than this many years ago")
+	ageCmd.Flags().Int("top", 20, "number of stale files to list (0 lists all)")
+}
+
+// doAge implements the steps taken for the age command
+func doAge(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	staleYears, _ := cmd.Flags().GetInt("stale-years")
+	top, _ := cmd.Flags().GetInt("top")
+
+	_, trees, _, _, err := image.LoadImage(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+
+	now := time.Now()
+	fmt.Println()
+	color.New(color.Bold).Printf("%5s  ", "Layer")
+	for _, bucket := range filetree.AgeBuckets {
+		color.New(color.Bold).Printf("%7s  ", bucket.Label)
+	}
+	fmt.Println()
+	for idx, row := range filetree.AgeHistogram(trees, now) {
+		fmt.Printf("%5d  ", idx)
+		for _, count := range row {
+			cell := "-"
+			if count > 0 {
+				cell = fmt.Sprintf("%d", count)
+			}
+			fmt.Printf("%7s  ", cell)
+		}
+		fmt.Println()
+	}
+
+	findings := filetree.StaleFiles(trees, now.AddDate(-staleYears, 0, 0))
+	var total uint64
+	for _, finding := range findings {
+		total += uint64(finding.Size)
+	}
+
+	fmt.Println()
+	template := "%10s  %10s  %5s  %s\n"
+	color.New(color.Bold).Printf(template, "Modified", "Size", "Layer", "Path")
+	for idx, finding := range findings {
+		if top > 0 && idx >= top {
+			break
+		}
+		fmt.Printf(template, finding.ModTime.Format("2006-01-02"), humanize.Bytes(uint64(finding.Size)), fmt.Sprintf("%d", finding.Layer), finding.Path)
+	}
+
+	fmt.Println()
+	fmt.Printf("%s %d files older than %d years (%s)\n", color.New(color.Bold).Sprint("Stale content:"), len(findings), staleYears, humanize.Bytes(total))
+}
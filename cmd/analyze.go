@@ -3,6 +3,8 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/wagoodman/dive/filetree"
+
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"github.com/wagoodman/dive/image"
@@ -34,5 +36,111 @@ func analyze(cmd *cobra.Command, args []string) {
 	}
 	color.New(color.Bold).Println("Analyzing Image")
 	manifest, refTrees, efficiency, inefficiencies := image.InitializeData(userImage)
+	if image.Degraded() {
+		color.New(color.FgYellow).Println("The memory limit was reached, the analysis is partial (see the layer details)")
+	}
+
+	if squash, _ := cmd.Flags().GetString("simulate-squash"); squash != "" {
+		if err := printSquashSimulation(squash, refTrees); err != nil {
+			fmt.Println(err)
+			utils.Exit(1)
+		}
+		return
+	}
+
+	rules, path, err := loadHideRules()
+	if err != nil {
+		fmt.Println(err)
+	} else {
+		ui.HideRules = &ui.HideRuleStore{Rules: rules, Path: path, Family: image.Family(userImage)}
+	}
+
+	actions, err := loadActions()
+	if err != nil {
+		fmt.Println(err)
+		utils.Exit(1)
+	}
+	ui.Actions = &ui.ActionStore{Actions: actions, Image: userImage}
+
+	searches, err := loadSearches()
+	if err != nil {
+		fmt.Println(err)
+	} else {
+		ui.Searches = searches
+	}
+
+	compareTo, _ := cmd.Flags().GetString("compare-to")
+	if compareTo != "" {
+		comparison, err := compareImages(userImage, compareTo, manifest, refTrees)
+		if err != nil {
+			fmt.Println(err)
+			utils.Exit(1)
+		}
+		ui.ImageComparison = comparison
+	}
+
+	ui.MaxFPS, _ = cmd.Flags().GetInt("max-fps")
+	ui.CompactWidth, _ = cmd.Flags().GetInt("compact-width")
+	ui.WideWidth, _ = cmd.Flags().GetInt("wide-width")
+
+	if numericIDs, _ := cmd.Flags().GetBool("numeric-ids"); !numericIDs {
+		filetree.SetAccounts(filetree.AccountsOf(filetree.StackRange(refTrees, 0, len(refTrees)-1)))
+	}
+
+	accessLog, _ := cmd.Flags().GetString("access-log")
+	if accessLog != "" {
+		profile, err := readAccessProfile(accessLog)
+		if err != nil {
+			fmt.Println(err)
+			utils.Exit(1)
+		}
+		usage := profile.Usage(filetree.StackRange(refTrees, 0, len(refTrees)-1))
+		ui.ImageAccess = &ui.StartupAccess{Profile: profile, Usage: usage}
+	}
 	ui.Run(manifest, refTrees, efficiency, inefficiencies)
 }
+
+// compareImages compares the analyzed image against another build of it: either the given reference, or (with
+// "previous") the prior build of the same repository.
+func compareImages(userImage, compareTo string, layers []*image.Layer, trees []*filetree.FileTree) (*ui.Comparison, error) {
+	reference := compareTo
+	if compareTo == "previous" {
+		var err error
+		reference, err = image.PreviousTag(userImage)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	color.New(color.Bold).Printf("Comparing to %s\n", reference)
+	otherLayers, otherTrees, _, _, err := image.LoadImage(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	lower := filetree.StackRange(otherTrees, 0, len(otherTrees)-1)
+	upper := filetree.StackRange(trees, 0, len(trees)-1)
+	combined, err := filetree.CompareTrees(lower, upper)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := combined.DiffStats("")
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := &ui.Comparison{
+		Reference: reference,
+		Added:     ui.ComparisonCount{Files: summary[filetree.Added].Count, Size: summary[filetree.Added].Size},
+		Removed:   ui.ComparisonCount{Files: summary[filetree.Removed].Count, Size: summary[filetree.Removed].Size},
+		Changed:   ui.ComparisonCount{Files: summary[filetree.Changed].Count, Size: summary[filetree.Changed].Size},
+		Growth:    image.Growth(otherLayers, layers),
+	}
+	for _, layer := range otherLayers {
+		comparison.OldSize += layer.History.Size
+	}
+	for _, layer := range layers {
+		comparison.NewSize += layer.History.Size
+	}
+	return comparison, nil
+}
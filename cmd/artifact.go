@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// artifactCmd represents the artifact command
+var artifactCmd = &cobra.Command{
+	Use:   "artifact [LAYOUT]",
+	Short: "Analyzes an OCI artifact (e.g. a Helm chart or a WASM module) stored in an OCI image layout.",
+	Long: `Analyzes an OCI artifact, or any image, stored in an OCI image layout directory (as written by e.g.
+"oras copy --to-oci-layout" or "skopeo copy ... oci:DIR"). The layers holding a tar (compressed or not) are listed
+with their files, any other layer is summarized by its content type.
+
+When the layout holds several manifests, select one by its name (org.opencontainers.image.ref.name) with --ref.`,
+	Args: cobra.ExactArgs(1),
+	Run:  doArtifact,
+}
+
+func init() {
+	rootCmd.AddCommand(artifactCmd)
+	artifactCmd.Flags().String("ref", "", "name of the manifest to analyze (when the layout holds several)")
+	artifactCmd.Flags().Bool("tree", false, "show the file tree of each tar layer")
+}
+
+// doArtifact implements the steps taken for the artifact command
+func doArtifact(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	reference, _ := cmd.Flags().GetString("ref")
+	showTree, _ := cmd.Flags().GetBool("tree")
+
+	artifact, err := image.LoadArtifact(args[0], reference)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+
+	if artifact.Reference != "" {
+		fmt.Printf("%s %s\n", color.New(color.Bold).Sprint("Reference:"), artifact.Reference)
+	}
+	fmt.Printf("%s %s\n", color.New(color.Bold).Sprint("Type:"), artifact.ArtifactType)
+	var keys []string
+	for key := range artifact.Annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Printf("  %s=%s\n", key, artifact.Annotations[key])
+	}
+
+	fmt.Println()
+	template := "%5s  %10s  %-19s  %s\n"
+	color.New(color.Bold).Printf(template, "Layer", "Size", "Digest", "Content")
+	var total uint64
+	for idx, layer := range artifact.Layers {
+		total += uint64(layer.Size)
+		content := layer.ContentType
+		if layer.Tree != nil {
+			content = fmt.Sprintf("tar, %d files (%s)", layer.Tree.Size, humanize.Bytes(layer.Tree.FileSize))
+		}
+		if layer.Title != "" {
+			content += " from " + layer.Title
+		}
+		fmt.Printf(template, fmt.Sprintf("%d", idx), humanize.Bytes(uint64(layer.Size)), shortDigest(layer.Digest), content)
+		fmt.Printf("%5s  %10s  %-19s  %s\n", "", "", "", color.New(color.Faint).Sprint(layer.MediaType))
+	}
+	fmt.Println()
+	fmt.Printf("%s %d layers (%s)\n", color.New(color.Bold).Sprint("Total:"), len(artifact.Layers), humanize.Bytes(total))
+
+	if !showTree {
+		return
+	}
+	for idx, layer := range artifact.Layers {
+		if layer.Tree == nil {
+			continue
+		}
+		fmt.Println()
+		color.New(color.Bold).Printf("Layer %d (%s)\n", idx, shortDigest(layer.Digest))
+		fmt.Print(layer.Tree.StringWith(treeGlyphs, true))
+	}
+}
+
+// shortDigest abbreviates the given digest (e.g. "sha256:0123456789ab").
+func shortDigest(digest string) string {
+	algorithm, hash := "", digest
+	if pos := strings.Index(digest, ":"); pos >= 0 {
+		algorithm, hash = digest[:pos+1], digest[pos+1:]
+	}
+	if len(hash) > 12 {
+		hash = hash[:12]
+	}
+	return algorithm + hash
+}
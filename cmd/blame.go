@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// blameCmd represents the blame command
+var blameCmd = &cobra.Command{
+	Use:   "blame [IMAGE] [DIRECTORY]",
+	Short: "Shows how many bytes each layer contributed to a directory.",
+	Long: `Shows, for every layer, how many bytes of files it added to the given directory, overwrote (providing files of
+earlier layers again) and deleted, along with how many of its bytes remain in the directory in the final image. This
+tells which instruction to look at when a directory is larger than expected.`,
+	Args: cobra.ExactArgs(2),
+	Run:  doBlame,
+}
+
+func init() {
+	rootCmd.AddCommand(blameCmd)
+	blameCmd.Flags().String("format", "text", "output format (text or json)")
+	addSchemaFlag(blameCmd, blameOutput{})
+}
+
+// blameOutput is the JSON output of the blame command
+type blameOutput struct {
+	SchemaVersion string        `json:"schemaVersion"`
+	Layers        []blameResult `json:"layers"`
+}
+
+// blameResult is a single layer of the output of the blame command
+type blameResult struct {
+	Layer       int    `json:"layer"`
+	Command     string `json:"command"`
+	Added       uint64 `json:"added"`
+	Overwritten uint64 `json:"overwritten"`
+	Deleted     uint64 `json:"deleted"`
+	Remaining   uint64 `json:"remaining"`
+}
+
+// doBlame implements the steps taken for the blame command
+func doBlame(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	format, _ := cmd.Flags().GetString("format")
+	if format != "text" && format != "json" {
+		fmt.Fprintf(os.Stderr, "unknown format '%s' (expected text or json)\n", format)
+		utils.Exit(1)
+	}
+	image.Quiet = format == "json"
+
+	layers, trees, _, _, err := image.LoadImage(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+	blame, err := filetree.BlameDirectory(trees, args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+
+	var results []blameResult
+	for _, layer := range blame {
+		result := blameResult{
+			Layer:       layer.Layer,
+			Added:       layer.Added,
+			Overwritten: layer.Overwritten,
+			Deleted:     layer.Deleted,
+			Remaining:   layer.Remaining,
+		}
+		if command := layers[len(layers)-1-layer.Layer]; command != nil {
+			result.Command = command.Command()
+		}
+		results = append(results, result)
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(blameOutput{SchemaVersion: image.SchemaVersion, Layers: results}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			utils.Exit(1)
+		}
+		return
+	}
+
+	fmt.Println()
+	template := "%5s  %10s  %11s  %10s  %10s  %s\n"
+	color.New(color.Bold).Printf(template, "Layer", "Added", "Overwritten", "Deleted", "Remaining", "Command")
+	var remaining uint64
+	for _, result := range results {
+		if result.Added == 0 && result.Overwritten == 0 && result.Deleted == 0 {
+			continue
+		}
+		remaining += result.Remaining
+		fmt.Printf(template, fmt.Sprintf("%d", result.Layer), bytesOrDash(result.Added), bytesOrDash(result.Overwritten),
+			bytesOrDash(result.Deleted), bytesOrDash(result.Remaining), result.Command)
+	}
+	fmt.Println()
+	fmt.Printf("%s %s\n", color.New(color.Bold).Sprint("Final size:"), humanize.Bytes(remaining))
+}
+
+// bytesOrDash shows the given size, or a dash for nothing at all.
+func bytesOrDash(size uint64) string {
+	if size == 0 {
+		return "-"
+	}
+	return humanize.Bytes(size)
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// catCmd represents the cat command
+var catCmd = &cobra.Command{
+	Use:   "cat [IMAGE] [PATH]",
+	Short: "Prints the contents of a file from an image.",
+	Long: `Prints the contents of the file at the given path to stdout, as seen in the final image (or as seen in the
+given layer with --layer, counting from 0 for the oldest layer). Files removed or replaced by later layers are
+resolved the same way a container would see them.`,
+	Args: cobra.ExactArgs(2),
+	Run:  doCat,
+}
+
+func init() {
+	rootCmd.AddCommand(catCmd)
+	catCmd.Flags().Int("layer", -1, "show the file as of the given layer (defaults to the final image)")
+}
+
+// doCat implements the steps taken for the cat command
+func doCat(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	layer, _ := cmd.Flags().GetInt("layer")
+
+	err := image.ReadFile(args[0], args[1], layer, os.Stdout)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+}
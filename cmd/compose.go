@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// composeCmd represents the compose command
+var composeCmd = &cobra.Command{
+	Use:   "compose [IMAGE] [IMAGE]...",
+	Short: "Previews the shared paths of several images mounted together, reporting collisions between them.",
+	Long: `Simulates the combined view of several images that will share paths (e.g. the containers of a pod sharing
+volumes): the shared paths of each image are stacked on top of those of the images before it, and every path is
+attributed to the images providing it.
+
+Files provided by more than one image are reported as collisions (the last image wins), along with how they differ,
+as are directories whose owner or mode differs between images. Without --volume the whole filesystems are composed.`,
+	Args: cobra.MinimumNArgs(2),
+	Run:  doCompose,
+}
+
+func init() {
+	rootCmd.AddCommand(composeCmd)
+	composeCmd.Flags().StringSlice("volume", nil, "a path shared between the images (may be given multiple times)")
+	composeCmd.Flags().Bool("exit-code", false, "exit with 1 if any collision conflicts (differs between images)")
+}
+
+// doCompose implements the steps taken for the compose command
+func doCompose(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	volumes, _ := cmd.Flags().GetStringSlice("volume")
+	exitCode, _ := cmd.Flags().GetBool("exit-code")
+
+	var sources []filetree.CompositionSource
+	for _, imageID := range args {
+		_, trees, _, _, err := image.LoadImage(imageID)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			utils.Exit(1)
+		}
+		sources = append(sources, filetree.CompositionSource{Name: imageID, Tree: filetree.StackRange(trees, 0, len(trees)-1)})
+	}
+
+	composition, err := filetree.Compose(sources, volumes)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+
+	fmt.Println()
+	color.New(color.Bold).Println("Composed view")
+	template := "%-40s  %8s  %10s\n"
+	color.New(color.Bold).Printf(template, "Image", "Files", "Size")
+	for _, source := range sources {
+		var files int
+		var size uint64
+		composition.Tree.VisitDepthParentFirst(func(node *filetree.FileNode) error {
+			providers := composition.Sources[node.Path()]
+			if node.IsLeaf() && !node.Data.FileInfo.TarHeader.FileInfo().IsDir() && len(providers) > 0 && providers[len(providers)-1] == source.Name {
+				files++
+				size += uint64(node.Data.FileInfo.Size())
+			}
+			return nil
+		}, nil)
+		fmt.Printf(template, source.Name, fmt.Sprintf("%d", files), humanize.Bytes(size))
+	}
+
+	fmt.Println()
+	if len(composition.Collisions) == 0 {
+		fmt.Println("No collisions between the images.")
+		return
+	}
+
+	var conflicts int
+	for _, collision := range composition.Collisions {
+		marker := " "
+		if collision.OwnershipConflict() {
+			marker = color.RedString("!")
+		} else if collision.Conflicts() {
+			marker = color.YellowString("~")
+		}
+		if collision.Conflicts() {
+			conflicts++
+		}
+		fmt.Printf("%s %s\n", marker, collision.Path)
+		for idx, source := range collision.Sources {
+			var changes []string
+			for _, change := range collision.Changes[idx] {
+				changes = append(changes, change.String())
+			}
+			detail := "identical"
+			if idx == 0 {
+				detail = "first"
+			} else if len(changes) > 0 {
+				detail = strings.Join(changes, ", ")
+			}
+			fmt.Printf("    %-40s  %s\n", source, detail)
+		}
+	}
+	fmt.Printf("\n%s %d paths, %d conflicting\n", color.New(color.Bold).Sprint("Collisions:"), len(composition.Collisions), conflicts)
+
+	if exitCode && conflicts > 0 {
+		utils.Exit(1)
+	}
+}
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mitchellh/go-homedir"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/wagoodman/dive/ui"
+	"github.com/wagoodman/dive/utils"
+)
+
+// systemConfigPath is the machine wide config file, read before any other config file.
+const systemConfigPath = "/etc/dive/config.yaml"
+
+// configPaths lists the config files to read, lowest precedence first: the system config, the user config
+// (~/.dive.yaml), the repository config (the nearest .dive.yaml from the working directory up to the root of the
+// repository), and the file given with --config.
+func configPaths() []string {
+	paths := []string{systemConfigPath}
+	if home, err := homedir.Dir(); err == nil {
+		paths = append(paths, filepath.Join(home, ".dive.yaml"))
+	}
+	if repoConfig := findRepoConfig(); repoConfig != "" && !containsPath(paths, repoConfig) {
+		paths = append(paths, repoConfig)
+	}
+	if cfgFile != "" {
+		paths = append(paths, cfgFile)
+	}
+	return paths
+}
+
+// findRepoConfig finds the nearest .dive.yaml from the working directory, stopping at the root of the repository
+// (the directory holding .git).
+func findRepoConfig() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		candidate := filepath.Join(dir, ".dive.yaml")
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func containsPath(paths []string, path string) bool {
+	for _, existing := range paths {
+		if filepath.Clean(existing) == filepath.Clean(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// configValue formats a config value the way it would be given on the command line.
+func configValue(value interface{}) string {
+	if values, ok := value.([]interface{}); ok {
+		parts := make([]string, len(values))
+		for idx, item := range values {
+			parts[idx] = fmt.Sprint(item)
+		}
+		return strings.Join(parts, ",")
+	}
+	return fmt.Sprint(value)
+}
+
+// lookupConfig finds the configured value for a flag of the given command. A setting may be given for every command
+// (e.g. "memory-limit: 4GB") or for a single command (e.g. "growth: {dockerfile: Dockerfile}"), where the command
+// specific setting wins.
+func lookupConfig(config *viper.Viper, command, flag string) (interface{}, bool) {
+	if config == nil {
+		return nil, false
+	}
+	for _, key := range []string{command + "." + flag, flag} {
+		if config.IsSet(key) {
+			return config.Get(key), true
+		}
+	}
+	return nil, false
+}
+
+// executingCommand finds the command that is being run.
+func executingCommand() *cobra.Command {
+	args := os.Args[1:]
+	if pluginArguments, ok := pluginArgs(os.Args); ok {
+		args = pluginArguments
+	}
+	cmd, _, err := rootCmd.Find(args)
+	if err != nil || cmd == nil {
+		return rootCmd
+	}
+	return cmd
+}
+
+// initConfig reads the layered config files and applies them (and the selected profile) to the flags that were not
+// given on the command line. The precedence, highest first, is:
+//
+//  1. command line flags
+//  2. environment variables (DIVE_<FLAG>, e.g. DIVE_MEMORY_LIMIT)
+//  3. the selected profile (--profile or DIVE_PROFILE)
+//  4. the --config file
+//  5. the repository config (.dive.yaml)
+//  6. the user config (~/.dive.yaml)
+//  7. the system config (/etc/dive/config.yaml)
+//
+// Config keys are flag names, and profiles are listed under the "profiles" key, for example:
+//
+//	memory-limit: 4GB
+//	profiles:
+//	  strict-ci:
+//	    layers: 0-3
+//	    diff:
+//	      exit-code: true
+func initConfig() {
+	viper.SetConfigType("yaml")
+	for _, path := range configPaths() {
+		file, err := os.Open(path)
+		if err != nil {
+			if path == cfgFile {
+				fmt.Fprintln(os.Stderr, err)
+				utils.Exit(1)
+			}
+			continue
+		}
+		err = viper.MergeConfig(file)
+		file.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid config file %s: %v\n", path, err)
+			utils.Exit(1)
+		}
+		log.Debugf("Using config file: %s", path)
+	}
+
+	profileName, _ := rootCmd.PersistentFlags().GetString("profile")
+	if profileName == "" {
+		profileName = os.Getenv("DIVE_PROFILE")
+	}
+	var profile *viper.Viper
+	if profileName != "" {
+		profile = viper.Sub("profiles." + profileName)
+		if profile == nil {
+			fmt.Fprintf(os.Stderr, "unknown config profile '%s'\n", profileName)
+			utils.Exit(1)
+		}
+	}
+
+	cmd := executingCommand()
+	var applyErr error
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if applyErr != nil || flag.Changed || flag.Name == "config" || flag.Name == "profile" {
+			return
+		}
+		value := os.Getenv("DIVE_" + strings.ToUpper(strings.Replace(flag.Name, "-", "_", -1)))
+		if value == "" {
+			setting, ok := lookupConfig(profile, cmd.Name(), flag.Name)
+			if !ok {
+				setting, ok = lookupConfig(viper.GetViper(), cmd.Name(), flag.Name)
+			}
+			if !ok {
+				return
+			}
+			value = configValue(setting)
+		}
+		if err := flag.Value.Set(value); err != nil {
+			applyErr = fmt.Errorf("invalid config value for %s: %v", flag.Name, err)
+		}
+	})
+	if applyErr != nil {
+		fmt.Fprintln(os.Stderr, applyErr)
+		utils.Exit(1)
+	}
+}
+
+// loadActions reads the user-defined actions of the file tree from the "actions" config key, for example:
+//
+//	actions:
+//	  - key: e
+//	    name: Open in editor
+//	    command: $EDITOR %file
+//	    interactive: true
+//	  - key: S
+//	    name: Scan
+//	    command: ./scan.sh %image %layer %path
+func loadActions() ([]ui.Action, error) {
+	var actions []ui.Action
+	if err := viper.UnmarshalKey("actions", &actions); err != nil {
+		return nil, fmt.Errorf("invalid actions config: %v", err)
+	}
+	for _, action := range actions {
+		if err := action.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return actions, nil
+}
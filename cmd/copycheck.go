@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// copyCheckCmd represents the copy-check command
+var copyCheckCmd = &cobra.Command{
+	Use:   "copy-check [IMAGE]",
+	Short: "Verifies that the COPY/ADD layers of an image only contain the sources declared in the Dockerfile.",
+	Args:  cobra.ExactArgs(1),
+	Run:   doCopyCheck,
+}
+
+func init() {
+	rootCmd.AddCommand(copyCheckCmd)
+	copyCheckCmd.Flags().String("context", ".", "the build context directory the image was built from")
+	copyCheckCmd.Flags().StringP("file", "f", "", "the Dockerfile the image was built from (default is CONTEXT/Dockerfile)")
+}
+
+// doCopyCheck implements the steps taken for the copy-check command
+func doCopyCheck(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	contextDir, _ := cmd.Flags().GetString("context")
+	dockerfilePath, _ := cmd.Flags().GetString("file")
+	if dockerfilePath == "" {
+		dockerfilePath = filepath.Join(contextDir, "Dockerfile")
+	}
+
+	dockerfile, err := os.Open(dockerfilePath)
+	if err != nil {
+		fmt.Println(err)
+		utils.Exit(1)
+	}
+	defer dockerfile.Close()
+
+	color.New(color.Bold).Println("Analyzing Image")
+	layers, _, _, _ := image.InitializeData(args[0])
+
+	findings, err := image.CheckCopySources(layers, contextDir, dockerfile)
+	if err != nil {
+		fmt.Println(err)
+		utils.Exit(1)
+	}
+
+	fmt.Println()
+	if len(findings) == 0 {
+		fmt.Println("All COPY/ADD layers match their declared sources.")
+		return
+	}
+
+	var total int64
+	var lastLine = -1
+	template := "  %10s  %s  %s\n"
+	for _, finding := range findings {
+		if finding.Instruction.Line != lastLine {
+			color.New(color.Bold).Printf("Line %d: %s\n", finding.Instruction.Line, finding.Instruction.String())
+			lastLine = finding.Instruction.Line
+		}
+		fmt.Printf(template, humanize.Bytes(uint64(finding.Size)), finding.Path, finding.Reason)
+		total += finding.Size
+	}
+	fmt.Printf("\n%s %d files, %s\n", color.New(color.Bold).Sprint("Unexpected content:"), len(findings), humanize.Bytes(uint64(total)))
+	utils.Exit(1)
+}
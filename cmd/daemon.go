@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Keeps the analysis of recently explored images in memory, for instant startup of later runs.",
+	Long: `Runs a cache server in the foreground, which keeps the analysis of the most recently explored images in memory.
+While it runs, "dive IMAGE" asks it for the analysis rather than saving and reading the image again, so the user
+interface starts instantly when the image did not change (which helps when dive is part of a tight rebuild loop). A
+rebuilt tag is analyzed again by the server.
+
+The server listens on a unix socket that only the user may access, in the runtime directory of the user
+($XDG_RUNTIME_DIR) or else in a directory of its own in the temporary directory. The socket may be changed with the
+'daemon-socket' config key (or --socket, for the server only). A socket that belongs to another user is never used.
+Runs with --layers or --context do not use the server.`,
+	Args: cobra.NoArgs,
+	Run:  doDaemon,
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+	daemonCmd.Flags().String("socket", "", "unix socket to listen on (defaults to the 'daemon-socket' config, or a socket in the runtime directory)")
+	daemonCmd.Flags().Int("max-images", 5, "number of images to keep the analysis of (the least recently used are dropped)")
+}
+
+// initCacheSocket selects the socket of the cache server (see the daemon command) as configured.
+func initCacheSocket() {
+	if socket := viper.GetString("daemon-socket"); socket != "" {
+		image.CacheSocket = socket
+	}
+}
+
+// doDaemon implements the steps taken for the daemon command
+func doDaemon(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	if socket, _ := cmd.Flags().GetString("socket"); socket != "" {
+		image.CacheSocket = socket
+	}
+	maxImages, _ := cmd.Flags().GetInt("max-images")
+	if maxImages < 1 {
+		fmt.Fprintln(os.Stderr, "--max-images must be at least 1")
+		utils.Exit(1)
+	}
+
+	// a socket left behind by a server that did not exit cleanly is replaced, a running server is not
+	if _, err := os.Stat(image.CacheSocket); err == nil {
+		if conn, err := net.DialTimeout("unix", image.CacheSocket, time.Second); err == nil {
+			conn.Close()
+			fmt.Fprintf(os.Stderr, "a cache server is already listening on %s\n", image.CacheSocket)
+			utils.Exit(1)
+		}
+		os.Remove(image.CacheSocket)
+	}
+	listener, err := image.ListenCache(image.CacheSocket)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+
+	// closing the listener removes the socket and ends serving
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	stopped := make(chan struct{})
+	go func() {
+		<-signals
+		close(stopped)
+		listener.Close()
+	}()
+
+	fmt.Printf("Caching analyses on %s (press Ctrl+C to stop)\n", image.CacheSocket)
+	err = image.ServeCache(listener, maxImages)
+	select {
+	case <-stopped:
+	default:
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			utils.Exit(1)
+		}
+	}
+}
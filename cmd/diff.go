@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+const (
+	diffExitIdentical = 0
+	diffExitDiffer    = 1
+	diffExitError     = 2
+)
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff [IMAGE|DIR] [IMAGE|DIR]",
+	Short: "Compares the final filesystems of two images.",
+	Long: `Compares the final (fully stacked) filesystems of two images, listing every file that was added, removed, or
+modified in the second image relative to the first.
+
+Either image may be replaced by a directory, such as the build context, to see how the image differs from it. The
+directory is compared as the build would copy it: the paths excluded by its .dockerignore are left out and files are
+owned by root. With --dir-path only that directory of the image is compared (e.g. the COPY destination).
+
+With --exit-code the command exits with 0 when the filesystems are identical, 1 when they differ, and 2 when
+an error occurred. With --patch the differences are written in a tab separated, patch-like format (one line per file
+with its size change in bytes), which is simple for other tools to read or to attach to a pull request. With --tree the
+changed files are shown in a tree of both images, colored by how each changed.`,
+	Args: cobra.ExactArgs(2),
+	Run:  doDiff,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().Bool("exit-code", false, "exit with 1 if there are differences and 0 if there are none")
+	diffCmd.Flags().Bool("stat", false, "only show the summary of changes")
+	diffCmd.Flags().Bool("patch", false, "write the differences in a patch-like format")
+	diffCmd.Flags().Bool("tree", false, "show the differences as a tree, colored by how each file changed")
+	diffCmd.Flags().String("dir-path", "/", "where a compared directory is copied to in the image, only this part of the image is compared")
+}
+
+// summarizeChanges summarizes the files (not directories) of the combined tree of two compared images (see
+// image.Compare) that were added, removed, or modified in the upper image. The given function is called for each such
+// file with its change marker (A, D, or M).
+func summarizeChanges(combined *filetree.FileTree, each func(marker string, size uint64, node *filetree.FileNode)) (filetree.DiffStats, error) {
+	markers := map[filetree.DiffType]string{filetree.Added: "A", filetree.Removed: "D", filetree.Changed: "M"}
+	err := combined.VisitDepthParentFirst(func(node *filetree.FileNode) error {
+		marker, ok := markers[node.Data.DiffType]
+		if !ok || node.Data.FileInfo.TarHeader.FileInfo().IsDir() || !node.IsLeaf() {
+			return nil
+		}
+		each(marker, uint64(node.Data.FileInfo.TarHeader.FileInfo().Size()), node)
+		return nil
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return combined.DiffStats("")
+}
+
+// compareSources compares the final filesystems of the given images, either of which may be a directory instead (see
+// image.DirectoryTree), in which case only the given directory of the image is compared.
+func compareSources(lowerSource, upperSource, dirPath string) (*image.Comparison, error) {
+	isDir := func(source string) bool {
+		info, err := os.Stat(source)
+		return err == nil && info.IsDir()
+	}
+	if !isDir(lowerSource) && !isDir(upperSource) {
+		return image.Compare(lowerSource, upperSource)
+	}
+
+	var trees [2]*filetree.FileTree
+	for idx, source := range []string{lowerSource, upperSource} {
+		if isDir(source) {
+			tree, err := image.DirectoryTree(source, dirPath)
+			if err != nil {
+				return nil, err
+			}
+			trees[idx] = tree
+		} else {
+			_, layerTrees, _, _, err := image.LoadImage(source)
+			if err != nil {
+				return nil, err
+			}
+			trees[idx] = filetree.StackRange(layerTrees, 0, len(layerTrees)-1)
+		}
+		trees[idx].Name = source
+		trees[idx].OnlyBeneath(dirPath)
+	}
+	return image.CompareTrees(trees[0], trees[1])
+}
+
+// doDiff implements the steps taken for the diff command
+func doDiff(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	exitCode, _ := cmd.Flags().GetBool("exit-code")
+	statOnly, _ := cmd.Flags().GetBool("stat")
+	patch, _ := cmd.Flags().GetBool("patch")
+	asTree, _ := cmd.Flags().GetBool("tree")
+	image.Quiet = patch
+	dirPath, _ := cmd.Flags().GetString("dir-path")
+
+	comparison, err := compareSources(args[0], args[1], dirPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(diffExitError)
+	}
+
+	switch {
+	case patch:
+		formatted, err := filetree.FormatDiff(comparison.Lower, comparison.Upper)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			utils.Exit(diffExitError)
+		}
+		fmt.Print(formatted)
+	case asTree:
+		// only the changes are shown, along with the directories holding them
+		comparison.Combined.VisitDepthParentFirst(func(node *filetree.FileNode) error {
+			node.Data.ViewInfo.Hidden = node.Data.DiffType == filetree.Unchanged
+			return nil
+		}, nil)
+		fmt.Println()
+		fmt.Print(comparison.Combined.StringWith(treeGlyphs, true))
+		fmt.Println()
+	default:
+		fmt.Println()
+	}
+	summary, err := summarizeChanges(comparison.Combined, func(marker string, size uint64, node *filetree.FileNode) {
+		if !statOnly && !patch && !asTree {
+			fmt.Printf("%s %10s  %s\n", marker, humanize.Bytes(size), node.Path())
+		}
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(diffExitError)
+	}
+
+	added, removed, changed := summary[filetree.Added], summary[filetree.Removed], summary[filetree.Changed]
+	if !patch {
+		fmt.Printf("%d files added (%s), %d files removed (%s), %d files modified (%s)\n",
+			added.Count, humanize.Bytes(added.Size),
+			removed.Count, humanize.Bytes(removed.Size),
+			changed.Count, humanize.Bytes(changed.Size))
+	}
+
+	if exitCode && added.Count+removed.Count+changed.Count > 0 {
+		utils.Exit(diffExitDiffer)
+	}
+	utils.Exit(diffExitIdentical)
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// digestsCmd represents the digests command
+var digestsCmd = &cobra.Command{
+	Use:   "digests [IMAGE]",
+	Short: "Lists the digest of each layer alongside the diff_id recorded in the image config.",
+	Long: `Lists each layer in order (oldest first) with the diff_id recorded in the image config next to the digest of the
+layer tar as saved by the daemon, flagging any mismatch (which indicates a corrupt or tampered layer store).
+
+Note that registry blob digests and compressed sizes are not known to the daemon, so are not shown.`,
+	Args: cobra.ExactArgs(1),
+	Run:  doDigests,
+}
+
+func init() {
+	rootCmd.AddCommand(digestsCmd)
+}
+
+// doDigests implements the steps taken for the digests command
+func doDigests(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	layers, _, _, _, err := image.LoadImage(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+
+	mismatches := 0
+	fmt.Println()
+	template := "%5s  %-71s  %-8s  %10s  %s\n"
+	color.New(color.Bold).Printf(template, "Layer", "Diff ID (config)", "Tar", "Size", "Tar Path")
+	// the layers are ordered newest first
+	for idx := len(layers) - 1; idx >= 0; idx-- {
+		layer := layers[idx]
+		status := color.GreenString("%-8s", "match")
+		switch {
+		case layer.Digest == "":
+			// the layer was not selected for analysis
+			status = color.New(color.Faint).Sprintf("%-8s", "skipped")
+		case layer.Digest != layer.Id():
+			mismatches++
+			status = color.RedString("%-8s", "MISMATCH")
+		}
+		fmt.Printf(template, fmt.Sprintf("%d", len(layers)-1-idx), layer.Id(), status, humanize.Bytes(layer.Tree.FileSize), layer.TarPath)
+		if layer.Digest != "" && layer.Digest != layer.Id() {
+			fmt.Printf("%5s  %-71s\n", "", layer.Digest+" (tar)")
+		}
+	}
+
+	fmt.Println()
+	if mismatches > 0 {
+		color.New(color.FgRed, color.Bold).Printf("%d layers do not match the diff_id recorded in the image config\n", mismatches)
+		utils.Exit(1)
+	}
+	fmt.Println("Every layer matches the diff_id recorded in the image config")
+}
@@ -0,0 +1,212 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-isatty"
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// doctorTimeout bounds each network check, such that an unreachable endpoint does not hold up the report.
+const doctorTimeout = 5 * time.Second
+
+// doctorCmd represents the doctor command
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Checks the environment dive runs in, suggesting fixes for any problem found.",
+	Long: `Checks the environment dive runs in: access to the Docker daemon, reachability of the registry, writability of the
+directories dive writes to, the capabilities of the terminal (unicode and color), and the availability of FUSE. Each
+problem found is reported with a suggested fix. The command exits with an error when any check fails.`,
+	Args: cobra.NoArgs,
+	Run:  doDoctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().String("registry", "registry-1.docker.io", "registry to check the reachability of")
+}
+
+// checkStatus is the outcome of a single environment check.
+type checkStatus int
+
+const (
+	checkPassed checkStatus = iota
+	checkWarned
+	checkFailed
+)
+
+// checkResult is the outcome of a single environment check, along with how to fix a problem it found.
+type checkResult struct {
+	Name   string
+	Status checkStatus
+	Detail string
+	Fix    string
+}
+
+// checkDaemon verifies that the Docker daemon can be reached.
+func checkDaemon() checkResult {
+	result := checkResult{Name: "Docker daemon"}
+	host, version, err := image.DaemonVersion(doctorTimeout)
+	if err == nil {
+		result.Detail = fmt.Sprintf("%s at %s", version, host)
+		return result
+	}
+	result.Status = checkFailed
+	result.Detail = err.Error()
+	switch {
+	case strings.Contains(err.Error(), "permission denied"):
+		result.Fix = "add your user to the docker group (sudo usermod -aG docker $USER) and log in again, or run dive with sudo"
+	case strings.Contains(err.Error(), "no such file") || strings.Contains(err.Error(), "connection refused") || strings.Contains(err.Error(), "Cannot connect"):
+		result.Fix = "start the Docker daemon, or point dive to a running daemon with DOCKER_HOST or --context"
+	default:
+		result.Fix = "check DOCKER_HOST, DOCKER_TLS_VERIFY and DOCKER_CERT_PATH (or --context), and that the daemon is running"
+	}
+	return result
+}
+
+// checkRegistry verifies that the given registry answers to its API endpoint (an unauthorized answer is fine).
+func checkRegistry(registry string) checkResult {
+	result := checkResult{Name: "Registry"}
+	endpoint := "https://" + strings.TrimSuffix(registry, "/") + "/v2/"
+	response, err := (&http.Client{Timeout: doctorTimeout}).Get(endpoint)
+	if err != nil {
+		result.Status = checkFailed
+		result.Detail = err.Error()
+		result.Fix = "check the network connection, the proxy settings (HTTPS_PROXY, NO_PROXY) and the firewall"
+		return result
+	}
+	response.Body.Close()
+	result.Detail = fmt.Sprintf("%s answered %s", registry, response.Status)
+	if response.StatusCode >= 500 {
+		result.Status = checkWarned
+		result.Fix = "the registry reports an error, try again later"
+	}
+	return result
+}
+
+// checkWritable verifies that files can be created in the given directory.
+func checkWritable(name, dir, fix string) checkResult {
+	result := checkResult{Name: name, Detail: dir}
+	file, err := ioutil.TempFile(dir, ".dive-doctor")
+	if err != nil {
+		result.Status = checkFailed
+		result.Detail = err.Error()
+		result.Fix = fix
+		return result
+	}
+	file.Close()
+	os.Remove(file.Name())
+	return result
+}
+
+// checkTerminal verifies that the terminal can show the user interface: an interactive terminal that supports
+// unicode and color.
+func checkTerminal() []checkResult {
+	terminal := checkResult{Name: "Terminal", Detail: os.Getenv("TERM")}
+	if !isatty.IsTerminal(os.Stdout.Fd()) && !isatty.IsCygwinTerminal(os.Stdout.Fd()) {
+		terminal.Status = checkWarned
+		terminal.Detail = "output is not a terminal"
+		terminal.Fix = "run dive from an interactive terminal to explore images (with docker run, pass -it)"
+	} else if terminal.Detail == "" || terminal.Detail == "dumb" {
+		terminal.Status = checkWarned
+		terminal.Detail = fmt.Sprintf("TERM=%q", terminal.Detail)
+		terminal.Fix = "set TERM to your terminal type (e.g. TERM=xterm-256color)"
+	}
+
+	unicode := checkResult{Name: "Unicode"}
+	locale := os.Getenv("LC_ALL")
+	if locale == "" {
+		locale = os.Getenv("LC_CTYPE")
+	}
+	if locale == "" {
+		locale = os.Getenv("LANG")
+	}
+	unicode.Detail = fmt.Sprintf("locale %q", locale)
+	normalized := strings.ToLower(strings.Replace(locale, "-", "", -1))
+	if !strings.Contains(normalized, "utf8") {
+		unicode.Status = checkWarned
+		unicode.Fix = "use a UTF-8 locale (e.g. export LANG=C.UTF-8), otherwise the tree and borders may be garbled"
+	}
+
+	colors := checkResult{Name: "Color"}
+	switch {
+	case os.Getenv("NO_COLOR") != "":
+		colors.Status = checkWarned
+		colors.Detail = "disabled by NO_COLOR"
+		colors.Fix = "unset NO_COLOR to tell added, removed and modified files apart by color"
+	case os.Getenv("COLORTERM") == "truecolor" || os.Getenv("COLORTERM") == "24bit":
+		colors.Detail = "24-bit color"
+	case strings.Contains(os.Getenv("TERM"), "256color"):
+		colors.Detail = "256 colors"
+	case color.NoColor:
+		colors.Status = checkWarned
+		colors.Detail = "not supported by the terminal"
+		colors.Fix = "use a terminal that supports color (e.g. TERM=xterm-256color)"
+	default:
+		colors.Detail = "16 colors"
+	}
+	return []checkResult{terminal, unicode, colors}
+}
+
+// checkFuse verifies that FUSE filesystems can be mounted (by the FUSE device and a fusermount helper).
+func checkFuse() checkResult {
+	result := checkResult{Name: "FUSE"}
+	if _, err := os.Stat("/dev/fuse"); err != nil {
+		result.Status = checkWarned
+		result.Detail = "/dev/fuse is not available"
+		result.Fix = "install FUSE and load its module (modprobe fuse); in a container, pass --device /dev/fuse"
+		return result
+	}
+	for _, helper := range []string{"fusermount3", "fusermount"} {
+		if path, err := exec.LookPath(helper); err == nil {
+			result.Detail = path
+			return result
+		}
+	}
+	result.Status = checkWarned
+	result.Detail = "fusermount is not installed"
+	result.Fix = "install the fuse3 (or fuse) package"
+	return result
+}
+
+// doDoctor implements the steps taken for the doctor command
+func doDoctor(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	registry, _ := cmd.Flags().GetString("registry")
+
+	results := []checkResult{checkDaemon(), checkRegistry(registry)}
+	results = append(results, checkWritable("Temporary directory", os.TempDir(), "set TMPDIR to a writable directory with room for the largest image to analyze"))
+	if home, err := homedir.Dir(); err == nil {
+		results = append(results, checkWritable("Home directory", home, "make the home directory writable (it holds the config and hide rules), or set HOME"))
+	}
+	results = append(results, checkTerminal()...)
+	results = append(results, checkFuse())
+
+	symbols := map[checkStatus]string{
+		checkPassed: color.New(color.FgGreen).Sprint("✔"),
+		checkWarned: color.New(color.FgYellow).Sprint("!"),
+		checkFailed: color.New(color.FgRed).Sprint("✘"),
+	}
+	failed := false
+	for _, result := range results {
+		fmt.Printf("%s %-20s %s\n", symbols[result.Status], result.Name, result.Detail)
+		if result.Fix != "" {
+			fmt.Printf("  %s %s\n", color.New(color.Bold).Sprint("fix:"), result.Fix)
+		}
+		failed = failed || result.Status == checkFailed
+	}
+	if failed {
+		utils.Exit(1)
+	}
+}
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// duplicatesCmd represents the duplicates command
+var duplicatesCmd = &cobra.Command{
+	Use:   "duplicates [IMAGE]",
+	Short: "Lists file contents that are stored more than once across the layers of an image.",
+	Long: `Groups the files of all layers of an image by their content digest, and lists the contents that are stored more
+than once (by several layers, or at several paths) with the bytes that would be saved by storing each only once.
+
+With --max-reclaimable the command exits with 1 when more bytes than given could be reclaimed, e.g. to fail a CI
+build that starts duplicating files.`,
+	Args: cobra.ExactArgs(1),
+	Run:  doDuplicates,
+}
+
+func init() {
+	rootCmd.AddCommand(duplicatesCmd)
+	duplicatesCmd.Flags().String("format", "text", "output format (text or json)")
+	duplicatesCmd.Flags().String("max-reclaimable", "", "fail when more than this many bytes could be reclaimed (e.g. 10MB)")
+	addSchemaFlag(duplicatesCmd, duplicatesResult{})
+}
+
+// duplicatesResult is the output of the duplicates command
+type duplicatesResult struct {
+	SchemaVersion string            `json:"schemaVersion"`
+	Reclaimable   int64             `json:"reclaimable"`
+	Duplicates    []duplicateResult `json:"duplicates"`
+}
+
+// duplicateResult is a single file content that is stored more than once
+type duplicateResult struct {
+	Digest      string       `json:"digest"`
+	Size        int64        `json:"size"`
+	Reclaimable int64        `json:"reclaimable"`
+	Copies      []storedCopy `json:"copies"`
+}
+
+// storedCopy is where a duplicated file content is stored
+type storedCopy struct {
+	Layer int    `json:"layer"`
+	Path  string `json:"path"`
+}
+
+// doDuplicates implements the steps taken for the duplicates command
+func doDuplicates(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	format, _ := cmd.Flags().GetString("format")
+	if format != "text" && format != "json" {
+		fmt.Fprintf(os.Stderr, "unknown format '%s' (expected text or json)\n", format)
+		utils.Exit(1)
+	}
+	image.Quiet = format == "json"
+	var limit uint64
+	if value, _ := cmd.Flags().GetString("max-reclaimable"); value != "" {
+		var err error
+		if limit, err = humanize.ParseBytes(value); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --max-reclaimable: %v\n", err)
+			utils.Exit(1)
+		}
+	}
+
+	filetree.EnableAnalysis(filetree.AnalyzeSHA256)
+	_, trees, _, _, err := image.LoadImage(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+	duplicates := filetree.Duplicates(trees)
+
+	result := duplicatesResult{SchemaVersion: image.SchemaVersion, Reclaimable: filetree.ReclaimableSize(duplicates), Duplicates: []duplicateResult{}}
+	for _, duplicate := range duplicates {
+		entry := duplicateResult{Digest: duplicate.Digest, Size: duplicate.Size, Reclaimable: duplicate.ReclaimableSize()}
+		for _, stored := range duplicate.Copies {
+			entry.Copies = append(entry.Copies, storedCopy{Layer: stored.Layer, Path: stored.Path})
+		}
+		result.Duplicates = append(result.Duplicates, entry)
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			utils.Exit(1)
+		}
+	} else {
+		fmt.Println()
+		template := "%6s  %12s  %s\n"
+		color.New(color.Bold).Printf(template, "Copies", "Reclaimable", "Path (layer)")
+		for _, duplicate := range result.Duplicates {
+			for idx, stored := range duplicate.Copies {
+				copies, reclaimable := "", ""
+				if idx == 0 {
+					copies, reclaimable = fmt.Sprintf("%d", len(duplicate.Copies)), humanize.Bytes(uint64(duplicate.Reclaimable))
+				}
+				fmt.Printf(template, copies, reclaimable, fmt.Sprintf("%s (%d)", stored.Path, stored.Layer))
+			}
+		}
+		fmt.Printf("\n%d duplicated file contents, %s reclaimable\n", len(result.Duplicates), humanize.Bytes(uint64(result.Reclaimable)))
+	}
+
+	if limit > 0 && uint64(result.Reclaimable) > limit {
+		if format == "text" {
+			color.New(color.FgRed, color.Bold).Print("FAIL ")
+			fmt.Printf("more than %s could be reclaimed\n", humanize.Bytes(limit))
+		}
+		utils.Exit(1)
+	}
+}
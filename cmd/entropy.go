@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// entropyCmd represents the entropy command
+var entropyCmd = &cobra.Command{
+	Use:   "entropy [IMAGE]",
+	Short: "Lists the largest already compressed or encrypted files (high entropy content) in an image.",
+	Long: `Lists the largest files with high entropy content (compressed archives, encrypted blobs, model weights, ...)
+along with the layer that added them. Such files bloat layers since they cannot be compressed any further. Files
+with an accepted exception (see the "suppressions" config key, or the dive.ignore image labels) are listed apart.`,
+	Args: cobra.ExactArgs(1),
+	Run:  doEntropy,
+}
+
+func init() {
+	rootCmd.AddCommand(entropyCmd)
+	entropyCmd.Flags().Int("top", 20, "number of files to list")
+	entropyCmd.Flags().Float64("threshold", filetree.HighEntropyThreshold, "minimum entropy (bits per byte, up to 8) of a listed file")
+}
+
+// doEntropy implements the steps taken for the entropy command
+func doEntropy(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	top, _ := cmd.Flags().GetInt("top")
+	threshold, _ := cmd.Flags().GetFloat64("threshold")
+
+	filetree.EnableAnalysis(filetree.AnalyzeEntropy)
+	layers, trees, _, _, err := image.LoadImage(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+	suppressions, err := loadSuppressions(layers)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+
+	var findings []filetree.EntropyFinding
+	var accepted []suppressed
+	var total uint64
+	for _, finding := range filetree.HighEntropyFiles(trees, threshold) {
+		if suppression, ok := suppressions.Match("entropy", finding.Path, time.Now()); ok {
+			accepted = append(accepted, suppressed{check: "entropy", path: finding.Path, suppression: suppression})
+			continue
+		}
+		findings = append(findings, finding)
+		total += uint64(finding.Size)
+	}
+
+	fmt.Println()
+	template := "%7s  %10s  %5s  %s\n"
+	color.New(color.Bold).Printf(template, "Entropy", "Size", "Layer", "Path")
+	for idx, finding := range findings {
+		if top > 0 && idx >= top {
+			break
+		}
+		fmt.Printf(template, fmt.Sprintf("%.2f", finding.Entropy), humanize.Bytes(uint64(finding.Size)), fmt.Sprintf("%d", finding.Layer), finding.Path)
+	}
+
+	printSuppressed(accepted)
+	fmt.Println()
+	fmt.Printf("%s %d files (%s)\n", color.New(color.Bold).Sprint("High entropy content:"), len(findings), humanize.Bytes(total))
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// exportChangesCmd represents the export-changes command
+var exportChangesCmd = &cobra.Command{
+	Use:   "export-changes [IMAGE]",
+	Short: "Writes the files added, removed and changed by each layer to a directory (one file per layer).",
+	Long: `Writes the files added, removed and changed by each layer (relative to the layers below it) to one JSON or CSV
+file per layer in the output directory, along with an index.json describing every layer and its file.`,
+	Args: cobra.ExactArgs(1),
+	Run:  doExportChanges,
+}
+
+func init() {
+	rootCmd.AddCommand(exportChangesCmd)
+	exportChangesCmd.Flags().StringP("output", "o", "", "directory to write the change lists to (required)")
+	exportChangesCmd.Flags().String("format", "json", "format of the change lists (json or csv)")
+	addSchemaFlag(exportChangesCmd, exportIndex{})
+}
+
+// exportedChange is a single entry of an exported change list
+type exportedChange struct {
+	Path   string `json:"path"`
+	Change string `json:"change"`
+	Size   int64  `json:"size"`
+	Mode   string `json:"mode"`
+	// Changes describes what changed for modified files (e.g. "mode 0644→0755")
+	Changes []string `json:"changes,omitempty"`
+}
+
+// exportedChangeList is a change list exported as JSON (the changes of a single layer)
+type exportedChangeList struct {
+	SchemaVersion string           `json:"schemaVersion"`
+	Layer         int              `json:"layer"`
+	Changes       []exportedChange `json:"changes"`
+}
+
+// exportedLayer describes a single layer and its change list within the export index
+type exportedLayer struct {
+	Index   int    `json:"index"`
+	Digest  string `json:"digest"`
+	Command string `json:"command"`
+	// NormalizedCommand is the Dockerfile instruction that created the layer (see image.NormalizeCommand)
+	NormalizedCommand string `json:"normalizedCommand"`
+	File              string `json:"file"`
+	Added             int    `json:"added"`
+	Removed           int    `json:"removed"`
+	Changed           int    `json:"changed"`
+	Size              uint64 `json:"size"`
+}
+
+// exportIndex is the index.json of an export, listing every layer (oldest first)
+type exportIndex struct {
+	SchemaVersion string          `json:"schemaVersion"`
+	Image         string          `json:"image"`
+	Layers        []exportedLayer `json:"layers"`
+}
+
+// doExportChanges implements the steps taken for the export-changes command
+func doExportChanges(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	outputDir, _ := cmd.Flags().GetString("output")
+	format, _ := cmd.Flags().GetString("format")
+	if outputDir == "" {
+		fmt.Fprintln(os.Stderr, "an output directory is required (--output)")
+		utils.Exit(1)
+	}
+	if format != "json" && format != "csv" {
+		fmt.Fprintf(os.Stderr, "unknown format '%s' (expected json or csv)\n", format)
+		utils.Exit(1)
+	}
+
+	layers, trees, _, _, err := image.LoadImage(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+
+	index := exportIndex{SchemaVersion: image.SchemaVersion, Image: args[0]}
+	for idx := range trees {
+		// trees are ordered oldest first, while the layers are ordered newest first
+		layer := layers[len(layers)-1-idx]
+		changes, err := filetree.LayerChanges(trees, idx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			utils.Exit(1)
+		}
+
+		entry := exportedLayer{
+			Index:             idx,
+			Digest:            layer.Id(),
+			Command:           layer.History.CreatedBy,
+			NormalizedCommand: layer.Command(),
+			File:              fmt.Sprintf("layer-%03d.%s", idx, format),
+			Size:              trees[idx].FileSize,
+		}
+		exported := make([]exportedChange, len(changes))
+		for changeIdx, change := range changes {
+			switch change.DiffType {
+			case filetree.Added:
+				entry.Added++
+			case filetree.Removed:
+				entry.Removed++
+			default:
+				entry.Changed++
+			}
+			exported[changeIdx] = exportedChange{
+				Path:   change.Path,
+				Change: strings.ToLower(change.DiffType.String()),
+				Size:   change.Size,
+				Mode:   change.Mode.String(),
+			}
+			for _, attribute := range change.Attributes {
+				exported[changeIdx].Changes = append(exported[changeIdx].Changes, attribute.String())
+			}
+		}
+
+		if err := writeChangeList(filepath.Join(outputDir, entry.File), format, idx, exported); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			utils.Exit(1)
+		}
+		index.Layers = append(index.Layers, entry)
+	}
+
+	if err := writeJSONFile(filepath.Join(outputDir, "index.json"), index); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+	fmt.Printf("Wrote the changes of %d layers to %s\n", len(index.Layers), outputDir)
+}
+
+// writeChangeList writes the changes of the given layer to the given file in the given format (json or csv).
+func writeChangeList(path, format string, layer int, changes []exportedChange) error {
+	if format == "json" {
+		return writeJSONFile(path, exportedChangeList{SchemaVersion: image.SchemaVersion, Layer: layer, Changes: changes})
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"path", "change", "size", "mode", "changes"})
+	for _, change := range changes {
+		writer.Write([]string{change.Path, change.Change, strconv.FormatInt(change.Size, 10), change.Mode, strings.Join(change.Changes, "; ")})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeJSONFile writes the given value as indented JSON to the given file.
+func writeJSONFile(path string, value interface{}) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
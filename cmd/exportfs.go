@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// exportFsCmd represents the export-fs command
+var exportFsCmd = &cobra.Command{
+	Use:   "export-fs [IMAGE]",
+	Short: "Writes the filesystem a container of the image sees, as a tar archive or a file listing.",
+	Long: `Writes the final filesystem of an image (every layer stacked, honoring whiteouts and opaque directories), which
+is what a container of the image sees, without running it. With --format tar the files are written as a tar archive
+(with their contents), with --format list as a listing of every path with its mode, owner and size.`,
+	Args: cobra.ExactArgs(1),
+	Run:  doExportFs,
+}
+
+func init() {
+	rootCmd.AddCommand(exportFsCmd)
+	exportFsCmd.Flags().StringP("output", "o", "", "file to write to (standard output when not given)")
+	exportFsCmd.Flags().String("format", filetree.ExportTar, "format of the export (tar or list)")
+}
+
+// doExportFs implements the steps taken for the export-fs command
+func doExportFs(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	outputPath, _ := cmd.Flags().GetString("output")
+	format, _ := cmd.Flags().GetString("format")
+	if format != filetree.ExportTar && format != filetree.ExportList {
+		fmt.Fprintf(os.Stderr, "unknown format '%s' (expected tar or list)\n", format)
+		utils.Exit(1)
+	}
+	// the export may be written to standard output, which must hold nothing else
+	image.Quiet = outputPath == ""
+
+	var writer io.Writer = os.Stdout
+	if outputPath != "" {
+		file, err := os.Create(outputPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			utils.Exit(1)
+		}
+		defer file.Close()
+		writer = file
+	}
+
+	_, trees, _, _, err := image.LoadImage(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+	if format == filetree.ExportTar {
+		err = image.ExportSquashed(args[0], trees, writer)
+	} else {
+		err = filetree.StackRange(trees, 0, len(trees)-1).ExportSquashed(writer, format, nil)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+	if outputPath != "" {
+		fmt.Printf("Wrote the filesystem of %s to %s\n", args[0], outputPath)
+	}
+}
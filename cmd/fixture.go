@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/testkit"
+	"github.com/wagoodman/dive/utils"
+)
+
+// fixtureCmd represents the fixture command
+var fixtureCmd = &cobra.Command{
+	Use:   "fixture [FEATURE...]",
+	Short: "Builds a small test image exercising the given layer features.",
+	Long: `Builds a small, reproducible image archive (in the format of "docker save") whose layers exercise the given
+features, or all features when none are given: ` + strings.Join(testkit.Features(), ", ") + `.
+
+The archive is written to the --output file, and with --load it is loaded into the docker daemon as --tag. Replacing an
+existing file and loading the image (which moves the tag) ask for confirmation first (or pass --yes).`,
+	Run: doFixture,
+}
+
+func init() {
+	rootCmd.AddCommand(fixtureCmd)
+	fixtureCmd.Flags().StringP("output", "o", "dive-fixture.tar", "file to write the image archive to")
+	fixtureCmd.Flags().StringP("tag", "t", "dive-fixture:latest", "tag of the image")
+	fixtureCmd.Flags().Bool("load", false, "load the image into the docker daemon (docker load)")
+	addGuardFlags(fixtureCmd)
+}
+
+// doFixture implements the steps taken for the fixture command
+func doFixture(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	output, _ := cmd.Flags().GetString("output")
+	tag, _ := cmd.Flags().GetString("tag")
+	load, _ := cmd.Flags().GetBool("load")
+
+	step, destructive := overwriteStep(output, "the image archive of "+tag)
+	steps := []string{step}
+	if load {
+		steps = append(steps, fmt.Sprintf("load the archive into the docker daemon, tagging it %s (untagging any image it refers to now)", tag))
+		destructive = true
+	}
+	if ok, err := guard(cmd, steps, destructive); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	} else if !ok {
+		return
+	}
+
+	fixture, err := testkit.Fixture(tag, args...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+	err = fixture.WriteArchive(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+	fmt.Printf("Wrote %s (%d layers)\n", output, len(fixture.Layers))
+
+	if load {
+		err = utils.RunDockerCmd("load", "-i", output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			utils.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// growthCmd represents the growth command
+var growthCmd = &cobra.Command{
+	Use:   "growth [OLD-IMAGE] [NEW-IMAGE]",
+	Short: "Attributes the size growth between two builds of an image to the layers that caused it.",
+	Long: `Compares two builds of an image (e.g. last week's and today's tag) and ranks the layers, by the instructions
+that created them, by how much they grew. Layers are matched across builds by their instructions, so new and
+removed instructions are reported on their own.
+
+With --dockerfile each layer is traced back to its Dockerfile line, and with --blame that line is attributed to the
+commit that last changed it (using git blame).`,
+	Args: cobra.ExactArgs(2),
+	Run:  doGrowth,
+}
+
+func init() {
+	rootCmd.AddCommand(growthCmd)
+	growthCmd.Flags().StringP("dockerfile", "f", "", "Dockerfile the newer image was built from")
+	growthCmd.Flags().Bool("blame", false, "attribute each instruction to the last commit that changed it (requires --dockerfile)")
+}
+
+// blameLine is the commit that last changed a single line of a file.
+type blameLine struct {
+	commit  string
+	author  string
+	summary string
+}
+
+// gitBlame returns the commit that last changed each line (by line number) of the given file.
+func gitBlame(path string) (map[int]blameLine, error) {
+	cmd := exec.Command("git", "blame", "--line-porcelain", filepath.Base(path))
+	cmd.Dir = filepath.Dir(path)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("could not run git blame on %s: %v", path, err)
+	}
+
+	result := make(map[int]blameLine)
+	var current blameLine
+	var lineNumber int
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "\t"):
+			result[lineNumber] = current
+		case strings.HasPrefix(line, "author "):
+			current.author = strings.TrimPrefix(line, "author ")
+		case strings.HasPrefix(line, "summary "):
+			current.summary = strings.TrimPrefix(line, "summary ")
+		default:
+			// a header line: "<commit> <original line> <final line> [<lines in group>]"
+			fields := strings.Fields(line)
+			if len(fields) >= 3 && len(fields[0]) == 40 {
+				current = blameLine{commit: fields[0][:8]}
+				lineNumber, _ = strconv.Atoi(fields[2])
+			}
+		}
+	}
+	return result, nil
+}
+
+// contentInstructionLines maps each layer of the newer image (by chronological index) to the line of the Dockerfile
+// instruction that created it. The last content-producing instructions (RUN/COPY/ADD) of the final stage created the
+// last layers of the image.
+func contentInstructionLines(dockerfilePath string, layerCount int) (map[int]int, error) {
+	file, err := os.Open(dockerfilePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	instructions, err := image.ParseDockerfile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []int
+	for _, instruction := range image.FinalStage(instructions) {
+		switch instruction.Command {
+		case "RUN", "COPY", "ADD":
+			lines = append(lines, instruction.Line)
+		}
+	}
+
+	result := make(map[int]int)
+	for idx := range lines {
+		layer := layerCount - len(lines) + idx
+		if layer >= 0 {
+			result[layer] = lines[idx]
+		}
+	}
+	return result, nil
+}
+
+// signedBytes shows a size change with an explicit sign.
+func signedBytes(delta int64) string {
+	if delta < 0 {
+		return "-" + humanize.Bytes(uint64(-delta))
+	}
+	return "+" + humanize.Bytes(uint64(delta))
+}
+
+// doGrowth implements the steps taken for the growth command
+func doGrowth(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	dockerfilePath, _ := cmd.Flags().GetString("dockerfile")
+	blame, _ := cmd.Flags().GetBool("blame")
+	if blame && dockerfilePath == "" {
+		fmt.Fprintln(os.Stderr, "--blame requires --dockerfile")
+		utils.Exit(1)
+	}
+
+	oldLayers, _, _, _, err := image.LoadImage(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+	newLayers, _, _, _, err := image.LoadImage(args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+
+	var instructionLines map[int]int
+	var blameLines map[int]blameLine
+	if dockerfilePath != "" {
+		instructionLines, err = contentInstructionLines(dockerfilePath, len(newLayers))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			utils.Exit(1)
+		}
+	}
+	if blame {
+		blameLines, err = gitBlame(dockerfilePath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			utils.Exit(1)
+		}
+	}
+
+	var oldSize, newSize uint64
+	for _, layer := range oldLayers {
+		oldSize += layer.History.Size
+	}
+	for _, layer := range newLayers {
+		newSize += layer.History.Size
+	}
+
+	fmt.Println()
+	template := "%10s  %10s  %10s  %5s  %s\n"
+	color.New(color.Bold).Printf(template, "Growth", "Before", "After", "Layer", "Instruction")
+	for _, growth := range image.Growth(oldLayers, newLayers) {
+		layer := "-"
+		if growth.Layer >= 0 {
+			layer = strconv.Itoa(growth.Layer)
+		}
+		instruction := growth.Instruction
+		if len(instruction) > 80 {
+			instruction = instruction[:77] + "..."
+		}
+		fmt.Printf(template, signedBytes(growth.Delta()), humanize.Bytes(growth.OldSize), humanize.Bytes(growth.NewSize), layer, instruction)
+
+		if line, ok := instructionLines[growth.Layer]; ok && growth.Layer >= 0 {
+			attribution := fmt.Sprintf("%s:%d", filepath.Base(dockerfilePath), line)
+			if blamed, ok := blameLines[line]; ok {
+				attribution += fmt.Sprintf(" %s %s (%s)", blamed.commit, blamed.summary, blamed.author)
+			}
+			fmt.Printf("%10s  %10s  %10s  %5s  %s\n", "", "", "", "", color.New(color.Faint).Sprint(attribution))
+		}
+	}
+
+	fmt.Println()
+	fmt.Printf("%s %s → %s (%s)\n", color.New(color.Bold).Sprint("Image size:"), humanize.Bytes(oldSize), humanize.Bytes(newSize), signedBytes(int64(newSize)-int64(oldSize)))
+}
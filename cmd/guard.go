@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mattn/go-isatty"
+	"github.com/spf13/cobra"
+)
+
+// errNotConfirmed is returned by guard when a destructive change was neither confirmed with --yes nor on a terminal.
+var errNotConfirmed = errors.New("not confirmed (run again with --yes to go ahead, or with --dry-run to only see what would be done)")
+
+// addGuardFlags adds the flags of a command that deletes or overwrites state: --dry-run and --yes (see guard).
+func addGuardFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("dry-run", false, "only show what would be done, without doing it")
+	cmd.Flags().BoolP("yes", "y", false, "delete or overwrite without asking for confirmation")
+}
+
+// guard shows what the command is about to do (one line per step) and returns if it should go ahead. Nothing is done
+// on a --dry-run. Steps that delete or overwrite state (destructive) need to be confirmed, either with --yes or by
+// answering the prompt when running on a terminal, otherwise errNotConfirmed is returned.
+func guard(cmd *cobra.Command, steps []string, destructive bool) (bool, error) {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	yes, _ := cmd.Flags().GetBool("yes")
+	if dryRun {
+		fmt.Println("Would:")
+		for _, step := range steps {
+			fmt.Println("  " + step)
+		}
+		return false, nil
+	}
+	if !destructive || yes {
+		return true, nil
+	}
+
+	if !isatty.IsTerminal(os.Stdin.Fd()) && !isatty.IsCygwinTerminal(os.Stdin.Fd()) {
+		for _, step := range steps {
+			fmt.Fprintln(os.Stderr, step)
+		}
+		return false, errNotConfirmed
+	}
+	fmt.Println("This will:")
+	for _, step := range steps {
+		fmt.Println("  " + step)
+	}
+	fmt.Print("Continue? [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	fmt.Println("Nothing was changed")
+	return false, nil
+}
+
+// overwriteStep describes writing to the given file, noting when an existing file is replaced (which is destructive).
+func overwriteStep(path, what string) (string, bool) {
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		return fmt.Sprintf("replace %s with %s", path, what), true
+	}
+	return fmt.Sprintf("write %s to %s", what, path), false
+}
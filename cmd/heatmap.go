@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// heatmapCmd represents the heatmap command
+var heatmapCmd = &cobra.Command{
+	Use:   "heatmap [IMAGE]",
+	Short: "Shows the bytes each layer adds per top level directory as a heatmap table.",
+	Args:  cobra.ExactArgs(1),
+	Run:   doHeatmap,
+}
+
+func init() {
+	rootCmd.AddCommand(heatmapCmd)
+	heatmapCmd.Flags().Int("depth", 1, "number of path components to group by (1 for /usr, 2 for /usr/lib)")
+	heatmapCmd.Flags().Int("columns", 8, "maximum number of directories to show (largest first)")
+}
+
+// heatColor picks the color of a heatmap cell by its size relative to the largest cell.
+func heatColor(size, max uint64) *color.Color {
+	if size == 0 || max == 0 {
+		return color.New(color.Faint)
+	}
+	ratio := float64(size) / float64(max)
+	switch {
+	case ratio >= 0.75:
+		return color.New(color.FgRed, color.Bold)
+	case ratio >= 0.5:
+		return color.New(color.FgHiRed)
+	case ratio >= 0.25:
+		return color.New(color.FgYellow)
+	default:
+		return color.New(color.FgGreen)
+	}
+}
+
+// doHeatmap implements the steps taken for the heatmap command
+func doHeatmap(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	depth, _ := cmd.Flags().GetInt("depth")
+	columns, _ := cmd.Flags().GetInt("columns")
+
+	layers, trees, _, _, err := image.LoadImage(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+
+	matrix := filetree.SizeByPrefix(trees, depth)
+	prefixes := matrix.Prefixes
+	if columns > 0 && len(prefixes) > columns {
+		prefixes = prefixes[:columns]
+	}
+	max := matrix.Max()
+
+	widths := make([]int, len(prefixes))
+	for col, prefix := range prefixes {
+		widths[col] = len(prefix)
+		if widths[col] < 8 {
+			widths[col] = 8
+		}
+	}
+
+	fmt.Println()
+	color.New(color.Bold).Printf("%5s  ", "Layer")
+	for col, prefix := range prefixes {
+		color.New(color.Bold).Printf("%*s  ", widths[col], prefix)
+	}
+	color.New(color.Bold).Println("Command")
+
+	for idx, row := range matrix.Sizes {
+		// trees are ordered oldest first, while the layers are ordered newest first
+		layer := layers[len(layers)-1-idx]
+		fmt.Printf("%5d  ", idx)
+		for col := range prefixes {
+			cell := "-"
+			if row[col] > 0 {
+				cell = humanize.Bytes(row[col])
+			}
+			heatColor(row[col], max).Printf("%*s  ", widths[col], cell)
+		}
+		command := layer.Command()
+		if len(command) > 60 {
+			command = command[:57] + "..."
+		}
+		fmt.Println(command)
+	}
+
+	fmt.Printf("%5s  ", "Total")
+	for col := range prefixes {
+		color.New(color.Bold).Printf("%*s  ", widths[col], humanize.Bytes(matrix.Totals[col]))
+	}
+	fmt.Println()
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// hideRulesFileName is the name of the file (in the home directory) that holds the persistent hide rules.
+const hideRulesFileName = ".dive-hidden.yaml"
+
+// hideCmd represents the hide command
+var hideCmd = &cobra.Command{
+	Use:   "hide [IMAGE] [PATTERN]",
+	Short: "Always hides a path (or glob pattern) when exploring an image.",
+	Long: `Always hides a path, or a glob pattern (where ** matches any number of directories), when exploring any build of the
+given image (e.g. the rule is shared between ubuntu:18.04 and ubuntu:20.04). Running the command again with the same
+pattern removes the rule, which asks for confirmation first (or pass --yes). Rules can also be toggled from the file
+tree with "h".
+
+Without arguments all rules are listed.`,
+	Args: cobra.RangeArgs(0, 2),
+	Run:  doHide,
+}
+
+func init() {
+	rootCmd.AddCommand(hideCmd)
+	hideCmd.Flags().Bool("all-images", false, "apply the rule to every image (the only argument is the pattern)")
+	addGuardFlags(hideCmd)
+}
+
+// hideRulesPath returns the location of the persistent hide rules file.
+func hideRulesPath() (string, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, hideRulesFileName), nil
+}
+
+// loadHideRules reads the persistent hide rules, which are empty when none were saved yet.
+func loadHideRules() (filetree.HideRules, string, error) {
+	path, err := hideRulesPath()
+	if err != nil {
+		return nil, "", err
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return make(filetree.HideRules), path, nil
+	}
+	if err != nil {
+		return nil, path, err
+	}
+	defer file.Close()
+	rules, err := filetree.ReadHideRules(file)
+	if err != nil {
+		return nil, path, fmt.Errorf("could not read %s: %v", path, err)
+	}
+	return rules, path, nil
+}
+
+// doHide implements the steps taken for the hide command
+func doHide(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	allImages, _ := cmd.Flags().GetBool("all-images")
+
+	rules, path, err := loadHideRules()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+
+	var family, pattern string
+	switch {
+	case len(args) == 0:
+		var families []string
+		for family := range rules {
+			families = append(families, family)
+		}
+		sort.Strings(families)
+		for _, family := range families {
+			fmt.Println(family + ":")
+			for _, pattern := range rules[family] {
+				fmt.Println("  " + pattern)
+			}
+		}
+		return
+	case allImages && len(args) == 1:
+		family, pattern = filetree.AllImages, args[0]
+	case !allImages && len(args) == 2:
+		family, pattern = image.Family(args[0]), args[1]
+	default:
+		fmt.Fprintln(os.Stderr, "expected an image and a pattern (or only a pattern with --all-images)")
+		utils.Exit(1)
+	}
+
+	hidden := rules.Toggle(family, pattern)
+	step := fmt.Sprintf("always hide %s for %s (saved to %s)", pattern, family, path)
+	if !hidden {
+		step = fmt.Sprintf("remove the rule hiding %s for %s from %s", pattern, family, path)
+	}
+	if ok, err := guard(cmd, []string{step}, !hidden); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	} else if !ok {
+		return
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+	defer file.Close()
+	if err := rules.Write(file); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+
+	if hidden {
+		fmt.Printf("Always hiding %s for %s\n", pattern, family)
+	} else {
+		fmt.Printf("No longer hiding %s for %s\n", pattern, family)
+	}
+}
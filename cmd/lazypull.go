@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// lazyPullCmd represents the lazy-pull command
+var lazyPullCmd = &cobra.Command{
+	Use:   "lazy-pull [IMAGE]",
+	Short: "Reports how well the layer ordering of an image supports lazy pulling, given the files read at container start.",
+	Args:  cobra.ExactArgs(1),
+	Run:   doLazyPull,
+}
+
+func init() {
+	rootCmd.AddCommand(lazyPullCmd)
+	lazyPullCmd.Flags().String("profile", "", "file listing the paths read at container start (JSON array, one path per line, or an access log)")
+	lazyPullCmd.MarkFlagRequired("profile")
+}
+
+// readAccessProfile reads the access profile (or access log) of a container start from the given file.
+func readAccessProfile(path string) (filetree.AccessProfile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return filetree.ReadAccessProfile(file)
+}
+
+// doLazyPull implements the steps taken for the lazy-pull command
+func doLazyPull(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	profilePath, _ := cmd.Flags().GetString("profile")
+	profile, err := readAccessProfile(profilePath)
+	if err != nil {
+		fmt.Println(err)
+		utils.Exit(1)
+	}
+
+	color.New(color.Bold).Println("Analyzing Image")
+	_, trees, _, _ := image.InitializeData(args[0])
+
+	report := filetree.LazyPull(trees, profile)
+
+	fmt.Println()
+	template := "%5s  %10s  %10s  %5s\n"
+	color.New(color.Bold).Printf(template, "Layer", "Size", "Hot Size", "Files")
+	for _, layer := range report.Layers {
+		fmt.Printf(template, fmt.Sprintf("%d", layer.Index), humanize.Bytes(layer.Size), humanize.Bytes(layer.HotSize), fmt.Sprintf("%d", layer.HotFiles))
+	}
+
+	fmt.Println()
+	fmt.Printf("%s %s\n", color.New(color.Bold).Sprint("Read at startup:"), humanize.Bytes(report.HotSize))
+	fmt.Printf("%s %s\n", color.New(color.Bold).Sprint("Fetched at startup:"), humanize.Bytes(report.FetchSize))
+	fmt.Printf("%s %d %%\n", color.New(color.Bold).Sprint("Lazy pull efficiency:"), int(100.0*report.Efficiency()))
+
+	usage := profile.Usage(filetree.StackRange(trees, 0, len(trees)-1))
+	fmt.Printf("%s %s (%d files)\n", color.New(color.Bold).Sprint("Never read at startup:"), humanize.Bytes(usage.UnreadSize), usage.UnreadFiles)
+
+	if len(report.Missing) > 0 {
+		fmt.Printf("\n%s\n", color.New(color.Bold).Sprint("Profiled paths not found in the image:"))
+		for _, path := range report.Missing {
+			fmt.Println("  " + path)
+		}
+	}
+
+	if len(report.Suggestions) > 0 {
+		fmt.Printf("\n%s\n", color.New(color.Bold).Sprint("Suggestions:"))
+		for _, suggestion := range report.Suggestions {
+			fmt.Println("  " + suggestion)
+		}
+	}
+}
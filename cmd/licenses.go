@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// licensesCmd represents the licenses command
+var licensesCmd = &cobra.Command{
+	Use:   "licenses [IMAGE]",
+	Short: "Summarizes the licenses declared by the license files in an image.",
+	Long: `Finds the license files (LICENSE, COPYING, NOTICE, debian copyright files, ...) in the final filesystem of an
+image and summarizes the licenses they declare.
+
+With --deny the command exits with 1 when any of the given licenses are found (e.g. --deny GPL-3.0,AGPL-3.0).`,
+	Args: cobra.ExactArgs(1),
+	Run:  doLicenses,
+}
+
+func init() {
+	rootCmd.AddCommand(licensesCmd)
+	licensesCmd.Flags().Bool("json", false, "write the license report as JSON")
+	licensesCmd.Flags().StringSlice("deny", nil, "licenses that are not allowed in the image (comma separated)")
+	addSchemaFlag(licensesCmd, licenseReport{})
+}
+
+// licenseReport is the JSON form of the license report
+type licenseReport struct {
+	SchemaVersion string         `json:"schemaVersion"`
+	Summary       map[string]int `json:"summary"`
+	Files         []licenseFile  `json:"files"`
+	Denied        []string       `json:"denied,omitempty"`
+}
+
+type licenseFile struct {
+	Path     string   `json:"path"`
+	Licenses []string `json:"licenses"`
+}
+
+// normalizeLicense reduces a license identifier to a comparable form, such that "GPL-3.0", "GPL-3.0-only",
+// "GPL-3.0-or-later" and the debian style "GPL-3+" are all considered the same license.
+func normalizeLicense(license string) string {
+	license = strings.ToLower(strings.TrimSpace(license))
+	license = strings.TrimSuffix(license, "+")
+	license = strings.TrimSuffix(license, "-only")
+	license = strings.TrimSuffix(license, "-or-later")
+	license = strings.TrimSuffix(license, ".0")
+	return license
+}
+
+// doLicenses implements the steps taken for the licenses command
+func doLicenses(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	asJSON, _ := cmd.Flags().GetBool("json")
+	deny, _ := cmd.Flags().GetStringSlice("deny")
+	image.Quiet = asJSON
+
+	_, trees, _, _, err := image.LoadImage(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+
+	findings := filetree.LicenseFindings(filetree.StackRange(trees, 0, len(trees)-1))
+	report := licenseReport{SchemaVersion: image.SchemaVersion, Summary: filetree.LicenseSummary(findings)}
+	for _, finding := range findings {
+		report.Files = append(report.Files, licenseFile{Path: finding.Path, Licenses: finding.Licenses})
+	}
+
+	denied := make(map[string]bool)
+	for _, license := range deny {
+		denied[normalizeLicense(license)] = true
+	}
+	var licenses []string
+	for license := range report.Summary {
+		licenses = append(licenses, license)
+		if denied[normalizeLicense(license)] {
+			report.Denied = append(report.Denied, license)
+		}
+	}
+	sort.Strings(licenses)
+	sort.Strings(report.Denied)
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			utils.Exit(1)
+		}
+	} else {
+		fmt.Println()
+		template := "%5s  %s\n"
+		color.New(color.Bold).Printf(template, "Files", "License")
+		for _, license := range licenses {
+			fmt.Printf(template, fmt.Sprintf("%d", report.Summary[license]), license)
+		}
+		if len(report.Denied) > 0 {
+			fmt.Printf("\n%s\n", color.New(color.Bold).Sprint("Denied licenses:"))
+			for _, finding := range findings {
+				for _, license := range finding.Licenses {
+					if denied[normalizeLicense(license)] {
+						fmt.Printf("  %s (%s)\n", finding.Path, license)
+					}
+				}
+			}
+		}
+	}
+
+	if len(report.Denied) > 0 {
+		utils.Exit(1)
+	}
+}
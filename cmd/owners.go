@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// ownersCmd represents the owners command
+var ownersCmd = &cobra.Command{
+	Use:   "owners [IMAGE]",
+	Short: "Attributes the bytes of an image to the owners of its paths (CODEOWNERS-style).",
+	Long: `Attributes the bytes of an image to owners (teams or people) using a CODEOWNERS-style file, where each line is a
+path pattern followed by its owners and the last matching line wins:
+
+  *                 @platform
+  /usr/lib/python3  @data-team
+  *.so              @native-team
+
+For every owner the size in the final image, the bytes added by all layers, and the wasted bytes (duplicated,
+overwritten or removed files) are shown. With --baseline, the growth of each owner's share since the baseline
+image is shown too.`,
+	Args: cobra.ExactArgs(1),
+	Run:  doOwners,
+}
+
+func init() {
+	rootCmd.AddCommand(ownersCmd)
+	ownersCmd.Flags().StringP("file", "f", "CODEOWNERS", "the path ownership file")
+	ownersCmd.Flags().String("baseline", "", "an earlier build of the image to show the growth of each owner's share against")
+	ownersCmd.Flags().Bool("json", false, "write the attribution as JSON")
+	addSchemaFlag(ownersCmd, ownersOutput{})
+}
+
+// ownersOutput is the output of the owners command with --json
+type ownersOutput struct {
+	SchemaVersion string        `json:"schemaVersion"`
+	Owners        []ownerResult `json:"owners"`
+}
+
+// ownerResult is the attribution of a single owner as written with --json
+type ownerResult struct {
+	Owner      string `json:"owner"`
+	Files      int    `json:"files"`
+	Size       uint64 `json:"size"`
+	LayerSize  uint64 `json:"layerSize"`
+	WastedSize uint64 `json:"wastedSize"`
+	Growth     *int64 `json:"growth,omitempty"`
+}
+
+// loadOwnerSizes analyzes the given image and attributes its bytes to the given owners.
+func loadOwnerSizes(imageID string, owners *filetree.Owners) []filetree.OwnerSize {
+	_, trees, _, inefficiencies, err := image.LoadImage(imageID)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+	return filetree.OwnerSizes(trees, inefficiencies, owners)
+}
+
+// doOwners implements the steps taken for the owners command
+func doOwners(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	ownersPath, _ := cmd.Flags().GetString("file")
+	baseline, _ := cmd.Flags().GetString("baseline")
+	asJSON, _ := cmd.Flags().GetBool("json")
+	image.Quiet = asJSON
+
+	file, err := os.Open(ownersPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+	owners, err := filetree.ReadOwners(file)
+	file.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not read %s: %v\n", ownersPath, err)
+		utils.Exit(1)
+	}
+
+	sizes := loadOwnerSizes(args[0], owners)
+	var baselineSizes map[string]uint64
+	if baseline != "" {
+		baselineSizes = make(map[string]uint64)
+		for _, size := range loadOwnerSizes(baseline, owners) {
+			baselineSizes[size.Owner] = size.Size
+		}
+	}
+
+	results := make([]ownerResult, len(sizes))
+	for idx, size := range sizes {
+		results[idx] = ownerResult{
+			Owner:      size.Owner,
+			Files:      size.Files,
+			Size:       size.Size,
+			LayerSize:  size.LayerSize,
+			WastedSize: size.WastedSize,
+		}
+		if baselineSizes != nil {
+			growth := int64(size.Size) - int64(baselineSizes[size.Owner])
+			results[idx].Growth = &growth
+		}
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(ownersOutput{SchemaVersion: image.SchemaVersion, Owners: results}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			utils.Exit(1)
+		}
+		return
+	}
+
+	fmt.Println()
+	template := "%10s  %10s  %10s  %10s  %8s  %s\n"
+	color.New(color.Bold).Printf(template, "Size", "Growth", "Layers", "Wasted", "Files", "Owner")
+	for _, result := range results {
+		growth := "-"
+		if result.Growth != nil {
+			growth = signedBytes(*result.Growth)
+		}
+		fmt.Printf(template, humanize.Bytes(result.Size), growth, humanize.Bytes(result.LayerSize),
+			humanize.Bytes(result.WastedSize), fmt.Sprintf("%d", result.Files), result.Owner)
+	}
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// pluginName is the name dive is registered as when installed as a docker CLI plugin (~/.docker/cli-plugins/docker-dive).
+const pluginName = "dive"
+
+// pluginMetadata describes dive to the docker CLI, see https://github.com/docker/cli/tree/master/cli-plugins.
+type pluginMetadata struct {
+	SchemaVersion    string `json:"SchemaVersion"`
+	Vendor           string `json:"Vendor"`
+	Version          string `json:"Version"`
+	ShortDescription string `json:"ShortDescription"`
+	URL              string `json:"URL"`
+}
+
+// pluginMetadataCmd is queried by the docker CLI to discover dive as a plugin
+var pluginMetadataCmd = &cobra.Command{
+	Use:    "docker-cli-plugin-metadata",
+	Hidden: true,
+	Args:   cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		pluginVersion := "unknown"
+		if version != nil {
+			pluginVersion = version.Version
+		}
+		json.NewEncoder(os.Stdout).Encode(pluginMetadata{
+			SchemaVersion:    "0.1.0",
+			Vendor:           "wagoodman",
+			Version:          pluginVersion,
+			ShortDescription: "Explore the contents of each layer of an image",
+			URL:              "https://github.com/wagoodman/dive",
+		})
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(pluginMetadataCmd)
+}
+
+// pluginArgs detects when dive is run by the docker CLI as a plugin (as "docker-dive dive [ARGS]"), returning the
+// arguments meant for dive.
+func pluginArgs(args []string) ([]string, bool) {
+	if len(args) < 2 || !strings.HasPrefix(filepath.Base(args[0]), "docker-") || args[1] != pluginName {
+		return nil, false
+	}
+	return args[2:], true
+}
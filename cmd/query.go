@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// queryCmd represents the query command
+var queryCmd = &cobra.Command{
+	Use:   "query [IMAGE] [EXPRESSION]",
+	Short: "Lists the files of an image that match a query expression.",
+	Long: `Lists the files of an image that match a query expression, for example:
+
+  dive query image:tag 'size > 10MB and path glob "/usr/lib/**" and diff == added'
+
+Conditions compare a field to a value and can be combined with "and", "or", "not" and parentheses:
+
+  path, name     ==, !=, glob (with ** matching any number of directories), ~ (regular expression)
+  type           ==, != (file, dir, symlink, hardlink, other)
+  diff           ==, != (added, removed, changed, unchanged)
+  size           ==, !=, >, >=, <, <= (e.g. 10MB, 512KiB)
+  mode           ==, !=, >, >=, <, <= (octal, e.g. 0755 or 04000)
+  uid, gid       ==, !=, >, >=, <, <=
+
+Files are queried in the given layer (the last layer by default), as stacked on all layers below it, with the diff
+relative to the layers below (just as the "Current Layer Contents" pane shows).`,
+	Args: cobra.ExactArgs(2),
+	Run:  doQuery,
+}
+
+func init() {
+	rootCmd.AddCommand(queryCmd)
+	queryCmd.Flags().StringSlice("columns", []string{"diff", "size", "path"}, "fields to show for each match ("+strings.Join(filetree.QueryFields, ", ")+")")
+	queryCmd.Flags().Int("layer", -1, "the layer to query (counting from the oldest layer, the last layer by default)")
+}
+
+// isQueryField indicates if the given name is a field that can be queried and shown.
+func isQueryField(name string) bool {
+	for _, field := range filetree.QueryFields {
+		if field == name {
+			return true
+		}
+	}
+	return false
+}
+
+// doQuery implements the steps taken for the query command
+func doQuery(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	columns, _ := cmd.Flags().GetStringSlice("columns")
+	layerIndex, _ := cmd.Flags().GetInt("layer")
+
+	for _, column := range columns {
+		if !isQueryField(column) {
+			fmt.Fprintf(os.Stderr, "unknown column '%s' (expected one of %s)\n", column, strings.Join(filetree.QueryFields, ", "))
+			utils.Exit(1)
+		}
+	}
+	evaluator, err := filetree.CompileQuery(args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid query: %v\n", err)
+		utils.Exit(1)
+	}
+
+	_, trees, _, _, err := image.LoadImage(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+	if layerIndex < 0 {
+		layerIndex = len(trees) - 1
+	}
+	if layerIndex >= len(trees) {
+		fmt.Fprintf(os.Stderr, "layer %d does not exist (the image has %d layers)\n", layerIndex, len(trees))
+		utils.Exit(1)
+	}
+
+	bottom := layerIndex - 1
+	if bottom < 0 {
+		bottom = 0
+	}
+	tree := filetree.StackRange(trees, 0, bottom)
+	tree.Compare(trees[layerIndex])
+
+	var rows [][]string
+	err = tree.ObserveDepthParentFirst(func(node *filetree.FileNode) error {
+		if evaluator(node) {
+			row := make([]string, len(columns))
+			for idx, column := range columns {
+				row[idx] = filetree.QueryValue(node, column)
+			}
+			rows = append(rows, row)
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+
+	widths := make([]int, len(columns))
+	for idx, column := range columns {
+		widths[idx] = len(column)
+		for _, row := range rows {
+			if len(row[idx]) > widths[idx] {
+				widths[idx] = len(row[idx])
+			}
+		}
+	}
+
+	fmt.Println()
+	for idx, column := range columns {
+		color.New(color.Bold).Printf("%-*s  ", widths[idx], column)
+	}
+	fmt.Println()
+	for _, row := range rows {
+		for idx, value := range row {
+			fmt.Printf("%-*s  ", widths[idx], value)
+		}
+		fmt.Println()
+	}
+	fmt.Println()
+	fmt.Printf("%s %d files\n", color.New(color.Bold).Sprint("Matches:"), len(rows))
+}
@@ -2,11 +2,15 @@ package cmd
 
 import (
 	"fmt"
+	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/i18n"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/ui"
 	"github.com/wagoodman/dive/utils"
 	"os"
 
+	"github.com/dustin/go-humanize"
 	"github.com/k0kubun/go-ansi"
-	"github.com/mitchellh/go-homedir"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -19,13 +23,21 @@ var rootCmd = &cobra.Command{
 	Use:   "dive [IMAGE]",
 	Short: "Docker Image Visualizer & Explorer",
 	Long: `This tool provides a way to discover and explore the contents of a docker image. Additionally the tool estimates
-the amount of wasted space and identifies the offending files from the image.`,
+the amount of wasted space and identifies the offending files from the image.
+
+Images stored in an OCI image layout directory (e.g. written by "skopeo copy ... oci:DIR" or buildah) are read without
+a Docker daemon: give the image as oci:DIR, or as oci:DIR:REF to select the image named REF in the layout. Images are
+read straight from their registry, without a Docker daemon, when given as registry://HOST/REPOSITORY:TAG (using the
+credentials stored by "docker login").`,
 	Args: cobra.MaximumNArgs(1),
 	Run:  analyze,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
+	if args, ok := pluginArgs(os.Args); ok {
+		rootCmd.SetArgs(args)
+	}
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		utils.Exit(1)
@@ -38,36 +50,197 @@ func init() {
 	cobra.OnInitialize(initConfig)
 	cobra.OnInitialize(initLogging)
 
-	// TODO: add config options
-	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.dive.yaml)")
+	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "additional config file (read after the system, user, and repository config files)")
+	rootCmd.PersistentFlags().String("profile", "", "name of the config profile to apply (from the 'profiles' section of the config files)")
 
 	rootCmd.PersistentFlags().BoolP("version", "v", false, "display version number")
+	rootCmd.PersistentFlags().String("events", "", "emit progress and result events in the given format while analyzing (ndjson)")
+	rootCmd.PersistentFlags().String("events-file", "", "file to write events to (defaults to stderr)")
+	addSchemaFlag(rootCmd, image.Events)
+
+	rootCmd.PersistentFlags().String("layers", "", "only analyze the given layers, counting from the oldest layer (e.g. 0-3,7)")
+
+	cobra.OnInitialize(initEvents)
+	rootCmd.PersistentFlags().String("context", "", "name of the docker context to use (overrides DOCKER_HOST and the current context)")
+
+	rootCmd.PersistentFlags().String("memory-limit", "", "degrade the analysis (instead of running out of memory) when approaching this memory use (e.g. 4GB)")
+
+	cobra.OnInitialize(initLayers)
+	cobra.OnInitialize(initDockerContext)
+	rootCmd.PersistentFlags().Bool("diagnose", false, "write a diagnostics bundle (zip) to attach to a bug report when an image cannot be analyzed")
+
+	rootCmd.Flags().String("simulate-squash", "", "instead of exploring the image, report its size if each of the given layer ranges were squashed into a single layer (e.g. 1-4,6-9, counting from the oldest layer)")
+	rootCmd.Flags().String("compare-to", "", "also compare the image to another build of it: an image reference, or 'previous' for the prior tag of the same repository")
+	rootCmd.Flags().Int("compact-width", ui.CompactWidth, "show fewer columns and a narrower layer pane on terminals narrower than this (e.g. tmux splits)")
+	rootCmd.Flags().Int("wide-width", ui.WideWidth, "show the index and digest of each layer on terminals at least this wide")
+	rootCmd.Flags().Int("max-fps", ui.MaxFPS, "redraw the user interface at most this many times per second while navigating (0 redraws on every key press)")
+	rootCmd.Flags().Bool("numeric-ids", false, "show the numeric UID:GID of files rather than the user and group names from the /etc/passwd and /etc/group of the image")
+	rootCmd.Flags().String("access-log", "", "mark the files read at container start, given an access log (e.g. from fatrace, opensnoop, or a JSON array of paths)")
+
+	rootCmd.PersistentFlags().String("locale", "", "language of the user interface (defaults to the LC_ALL, LC_MESSAGES, or LANG locale)")
+
+	rootCmd.PersistentFlags().String("comparison", "metadata", "how files are compared between layers and images: 'metadata' (type and md5 checksum) or 'content' (type, sha256 digest, and link target)")
+
+	rootCmd.PersistentFlags().String("glyphs", "unicode", "characters to draw file trees with: 'unicode', 'ascii' (for terminals and logs that garble box drawing characters), or 'custom' (from the 'custom-glyphs' config)")
+
+	cobra.OnInitialize(initMemoryLimit)
+	cobra.OnInitialize(initLocale)
+	cobra.OnInitialize(initComparison)
+	cobra.OnInitialize(initGlyphs)
+	cobra.OnInitialize(initCacheSocket)
+	cobra.OnInitialize(initDiagnostics)
+
+	rootCmd.PersistentFlags().String("otel-endpoint", "", "export a trace of the analysis phases and image size metrics to this OpenTelemetry collector (OTLP over HTTP, defaults to OTEL_EXPORTER_OTLP_ENDPOINT when that is set)")
+	cobra.OnInitialize(initTelemetry)
 }
 
-// initConfig reads in config file and ENV variables if set.
-func initConfig() {
-	if cfgFile != "" {
-		// Use config file from the flag.
-		viper.SetConfigFile(cfgFile)
-	} else {
-		// Find home directory.
-		home, err := homedir.Dir()
-		if err != nil {
-			fmt.Println(err)
+// initTelemetry enables the OpenTelemetry export requested by the --otel-endpoint flag (or the environment), which is
+// sent when dive exits.
+func initTelemetry() {
+	endpoint, _ := rootCmd.PersistentFlags().GetString("otel-endpoint")
+	if endpoint == "" {
+		endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if endpoint == "" {
+		return
+	}
+	diveVersion := ""
+	if version != nil {
+		diveVersion = version.Version
+	}
+	image.EnableTelemetry(endpoint, diveVersion)
+	utils.OnCleanup(func() {
+		if err := image.ExportTelemetry(); err != nil {
+			log.Warn(err)
+		}
+	})
+}
+
+// initDiagnostics enables the diagnostics bundle requested by the --diagnose flag.
+func initDiagnostics() {
+	diagnose, _ := rootCmd.PersistentFlags().GetBool("diagnose")
+	if !diagnose {
+		return
+	}
+	environment := map[string]string{}
+	if version != nil {
+		environment["dive"] = version.Version
+		environment["commit"] = version.Commit
+	}
+	image.EnableDiagnostics(environment)
+}
+
+// initLocale selects the language of the user interface: the --locale flag, otherwise the locale of the environment
+// (when there is a translation for it).
+func initLocale() {
+	name, _ := rootCmd.PersistentFlags().GetString("locale")
+	if name == "" {
+		var ok bool
+		if name, ok = i18n.EnvironmentLocale(); !ok {
+			return
+		}
+	}
+	if err := i18n.SetLocale(name); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+}
+
+// initComparison selects how files are compared as requested by the --comparison flag.
+func initComparison() {
+	name, _ := rootCmd.PersistentFlags().GetString("comparison")
+	if err := filetree.SetDefaultComparer(name); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+}
+
+// treeGlyphs draw the file trees printed by commands (and shown in the user interface).
+var treeGlyphs = filetree.DefaultGlyphs
+
+// initGlyphs selects the characters file trees are drawn with as requested by the --glyphs flag. A custom set starts
+// from the unicode set, replacing the glyphs given in the config, for example:
+//
+//	glyphs: custom
+//	custom-glyphs:
+//	  middle: "├╴"
+//	  last: "╰╴"
+func initGlyphs() {
+	name, _ := rootCmd.PersistentFlags().GetString("glyphs")
+	if name == "custom" {
+		treeGlyphs = filetree.DefaultGlyphs
+		if err := viper.UnmarshalKey("custom-glyphs", &treeGlyphs); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid custom-glyphs config: %v\n", err)
 			utils.Exit(1)
 		}
+	} else if glyphs, ok := filetree.GlyphSets[name]; ok {
+		treeGlyphs = glyphs
+	} else {
+		fmt.Fprintf(os.Stderr, "unknown glyphs '%s' (expected unicode, ascii or custom)\n", name)
+		utils.Exit(1)
+	}
+	ui.Glyphs = treeGlyphs
+}
+
+// initMemoryLimit caps the memory used while loading images as requested by the --memory-limit flag.
+func initMemoryLimit() {
+	limit, _ := rootCmd.PersistentFlags().GetString("memory-limit")
+	if limit == "" {
+		return
+	}
+	bytes, err := humanize.ParseBytes(limit)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --memory-limit: %v\n", err)
+		utils.Exit(1)
+	}
+	image.SetMemoryLimit(bytes)
+}
+
+// initDockerContext selects the daemon to connect to: the docker context given with --context, otherwise the daemon
+// given by DOCKER_HOST, otherwise the current docker context (as chosen by "docker context use" or DOCKER_CONTEXT).
+// The context is only inspected once the daemon is connected to.
+func initDockerContext() {
+	name, _ := rootCmd.PersistentFlags().GetString("context")
+	utils.DockerContext = name
+	image.SetDockerContext(name)
+}
 
-		// Search config in home directory with name ".dive" (without extension).
-		viper.AddConfigPath(home)
-		viper.SetConfigName(".dive")
+// initLayers limits the analysis to the layers requested by the --layers flag.
+func initLayers() {
+	spec, _ := rootCmd.PersistentFlags().GetString("layers")
+	if spec == "" {
+		return
+	}
+	selection, err := image.ParseLayerSelection(spec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid --layers: %v\n", err)
+		utils.Exit(1)
 	}
+	image.SelectLayers(selection)
+}
 
-	viper.AutomaticEnv() // read in environment variables that match
+// initEvents enables the event output requested by the --events flags.
+func initEvents() {
+	format, _ := rootCmd.PersistentFlags().GetString("events")
+	if format == "" {
+		return
+	}
+	if format != "ndjson" {
+		fmt.Fprintf(os.Stderr, "unknown events format '%s' (expected ndjson)\n", format)
+		utils.Exit(1)
+	}
 
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+	path, _ := rootCmd.PersistentFlags().GetString("events-file")
+	if path == "" {
+		image.EnableEvents(os.Stderr)
+		return
+	}
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
 	}
+	image.EnableEvents(file)
 }
 
 // initLogging sets up the loggin object with a formatter and location
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// jsonSchemaDialect is the JSON Schema version the schemas printed with --schema are written in.
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// addSchemaFlag adds the --schema flag to a command with JSON output, which prints the JSON Schema of the output (of
+// the type of the given value) instead of running the command.
+func addSchemaFlag(cmd *cobra.Command, output interface{}) {
+	cmd.Flags().Bool("schema", false, fmt.Sprintf("print the JSON Schema of the JSON output (version %s) and exit", image.SchemaVersion))
+	validate, run := cmd.Args, cmd.Run
+	cmd.Args = func(cmd *cobra.Command, args []string) error {
+		if schema, _ := cmd.Flags().GetBool("schema"); schema || validate == nil {
+			return nil
+		}
+		return validate(cmd, args)
+	}
+	cmd.Run = func(cmd *cobra.Command, args []string) {
+		if schema, _ := cmd.Flags().GetBool("schema"); !schema {
+			run(cmd, args)
+			return
+		}
+		defer utils.Cleanup()
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(outputSchema(cmd.CommandPath(), output)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			utils.Exit(1)
+		}
+	}
+}
+
+// outputSchema returns the JSON Schema of the JSON output of the given command, given a value of the type written. For
+// the events (see image.Events), each line is one of the events.
+func outputSchema(title string, output interface{}) map[string]interface{} {
+	var schema map[string]interface{}
+	if events, ok := output.([]image.Event); ok {
+		alternatives := make([]interface{}, len(events))
+		for idx, event := range events {
+			alternative := jsonSchema(reflect.TypeOf(event))
+			constrainProperty(alternative, "type", event.EventType())
+			constrainProperty(alternative, "schemaVersion", image.SchemaVersion)
+			alternatives[idx] = alternative
+		}
+		schema = map[string]interface{}{"oneOf": alternatives}
+	} else {
+		schema = jsonSchema(reflect.TypeOf(output))
+		constrainProperty(schema, "schemaVersion", image.SchemaVersion)
+	}
+	schema["$schema"] = jsonSchemaDialect
+	schema["title"] = title
+	return schema
+}
+
+// constrainProperty restricts the given property of the given object schema (if it has the property) to a single value.
+func constrainProperty(schema map[string]interface{}, name string, value interface{}) {
+	if properties, ok := schema["properties"].(map[string]interface{}); ok {
+		if property, ok := properties[name].(map[string]interface{}); ok {
+			property["const"] = value
+		}
+	}
+}
+
+// jsonSchema describes the JSON encoding (see encoding/json) of the values of the given type as a JSON Schema: the
+// fields of structs are named by their json tags, and the fields tagged omitempty are optional.
+func jsonSchema(typ reflect.Type) map[string]interface{} {
+	switch typ.Kind() {
+	case reflect.Ptr:
+		return jsonSchema(typ.Elem())
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		required := make([]string, 0)
+		for idx := 0; idx < typ.NumField(); idx++ {
+			field := typ.Field(idx)
+			tag := field.Tag.Get("json")
+			if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+				// the fields of an embedded struct are encoded as fields of the struct
+				embedded := jsonSchema(field.Type)
+				for name, property := range embedded["properties"].(map[string]interface{}) {
+					properties[name] = property
+				}
+				required = append(required, embedded["required"].([]string)...)
+				continue
+			}
+			if field.PkgPath != "" || tag == "-" {
+				continue
+			}
+			name, options := field.Name, ""
+			if tag != "" {
+				parts := strings.SplitN(tag, ",", 2)
+				if parts[0] != "" {
+					name = parts[0]
+				}
+				if len(parts) > 1 {
+					options = parts[1]
+				}
+			}
+			properties[name] = jsonSchema(field.Type)
+			if strings.Contains(options, "omitempty") {
+				continue
+			}
+			if field.Type.Kind() == reflect.Ptr {
+				// a nil pointer is encoded as null
+				properties[name] = map[string]interface{}{"anyOf": []interface{}{properties[name], map[string]interface{}{"type": "null"}}}
+			}
+			required = append(required, name)
+		}
+		return map[string]interface{}{"type": "object", "properties": properties, "required": required}
+	case reflect.Slice, reflect.Array:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			// encoded as base64
+			return map[string]interface{}{"type": "string"}
+		}
+		// a nil slice is encoded as null
+		return map[string]interface{}{"type": []string{"array", "null"}, "items": jsonSchema(typ.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": []string{"object", "null"}, "additionalProperties": jsonSchema(typ.Elem())}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+	return map[string]interface{}{}
+}
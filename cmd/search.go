@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+	"github.com/wagoodman/dive/ui"
+	"gopkg.in/yaml.v2"
+)
+
+// filterHistoryFileName is the name of the file (in the home directory) that holds the history of the filter queries.
+const filterHistoryFileName = ".dive-filter-history"
+
+// loadSearches reads the history of the filter queries, and the named searches of the "searches" config key, for
+// example:
+//
+//	searches:
+//	  pycache: __pycache__|\.pyc$
+//	  docs: ^/usr/share/(doc|man)/
+func loadSearches() (*ui.SearchStore, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return nil, err
+	}
+	store := &ui.SearchStore{
+		HistoryPath: filepath.Join(home, filterHistoryFileName),
+		Saved:       viper.GetStringMapString("searches"),
+		Save:        saveSearch,
+	}
+	contents, err := ioutil.ReadFile(store.HistoryPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	store.History = ui.ReadFilterHistory(string(contents))
+	return store, nil
+}
+
+// saveSearch adds (or replaces) a named search in the user config (~/.dive.yaml), keeping its other settings. The
+// file is rewritten, so comments in it are not kept.
+func saveSearch(name, query string) error {
+	home, err := homedir.Dir()
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(home, ".dive.yaml")
+
+	var config yaml.MapSlice
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if err := yaml.Unmarshal(contents, &config); err != nil {
+			return fmt.Errorf("invalid config file %s: %v", path, err)
+		}
+	}
+
+	found := false
+	for idx, item := range config {
+		if item.Key != "searches" {
+			continue
+		}
+		searches, _ := item.Value.(yaml.MapSlice)
+		config[idx].Value = setMapItem(searches, name, query)
+		found = true
+	}
+	if !found {
+		config = append(config, yaml.MapItem{Key: "searches", Value: yaml.MapSlice{{Key: name, Value: query}}})
+	}
+
+	contents, err := yaml.Marshal(config)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, contents, mode)
+}
+
+// setMapItem sets the value of the given key, keeping the order of the other keys.
+func setMapItem(items yaml.MapSlice, key string, value interface{}) yaml.MapSlice {
+	for idx, item := range items {
+		if item.Key == key {
+			items[idx].Value = value
+			return items
+		}
+	}
+	return append(items, yaml.MapItem{Key: key, Value: value})
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/wagoodman/dive/filetree"
+)
+
+// parseSquashRanges parses a comma separated list of inclusive layer ranges (e.g. "1-4,6-9"), which may not overlap.
+func parseSquashRanges(spec string) ([][2]int, error) {
+	var ranges [][2]int
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		bounds := strings.SplitN(part, "-", 2)
+		if len(bounds) != 2 {
+			return nil, fmt.Errorf("invalid layer range '%s' (expected e.g. 1-4)", part)
+		}
+		from, fromErr := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		to, toErr := strconv.Atoi(strings.TrimSpace(bounds[1]))
+		if fromErr != nil || toErr != nil || from < 0 || to < from {
+			return nil, fmt.Errorf("invalid layer range '%s' (expected e.g. 1-4)", part)
+		}
+		for _, other := range ranges {
+			if from <= other[1] && other[0] <= to {
+				return nil, fmt.Errorf("layer range '%s' overlaps %d-%d", part, other[0], other[1])
+			}
+		}
+		ranges = append(ranges, [2]int{from, to})
+	}
+	if len(ranges) == 0 {
+		return nil, fmt.Errorf("no layer ranges given")
+	}
+	return ranges, nil
+}
+
+// printSquashSimulation reports the size of the image if each of the given layer ranges (see parseSquashRanges) were
+// squashed into a single layer, with the savings of each range.
+func printSquashSimulation(spec string, trees []*filetree.FileTree) error {
+	ranges, err := parseSquashRanges(spec)
+	if err != nil {
+		return err
+	}
+	var simulations []filetree.SquashSimulation
+	for _, bounds := range ranges {
+		simulation, err := filetree.SimulateSquash(trees, bounds[0], bounds[1])
+		if err != nil {
+			return err
+		}
+		simulations = append(simulations, simulation)
+	}
+
+	var imageSize, savings uint64
+	for _, tree := range trees {
+		imageSize += tree.FileSize
+	}
+	fmt.Println()
+	template := "%-9s  %10s  %10s  %10s\n"
+	color.New(color.Bold).Printf(template, "Layers", "Size", "Squashed", "Savings")
+	for _, simulation := range simulations {
+		savings += simulation.Savings()
+		fmt.Printf(template, fmt.Sprintf("%d-%d", simulation.From, simulation.To), humanize.Bytes(simulation.Size),
+			humanize.Bytes(simulation.SquashedSize), humanize.Bytes(simulation.Savings()))
+	}
+	fmt.Printf("\n%s %s → %s (saves %s)\n", color.New(color.Bold).Sprint("Image size:"), humanize.Bytes(imageSize),
+		humanize.Bytes(imageSize-savings), humanize.Bytes(savings))
+	return nil
+}
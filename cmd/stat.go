@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// statCmd represents the stat command
+var statCmd = &cobra.Command{
+	Use:   "stat [IMAGE] [PATH]",
+	Short: "Shows the metadata of a single path in an image.",
+	Long: `Shows the metadata of a single path in an image (size, mode, owner, modification time, link target, digest),
+along with the layer that introduced it, the layer that last modified it and whether it survives to the final image.`,
+	Args: cobra.ExactArgs(2),
+	Run:  doStat,
+}
+
+func init() {
+	rootCmd.AddCommand(statCmd)
+	statCmd.Flags().String("format", "text", "output format (text or json)")
+	addSchemaFlag(statCmd, statResult{})
+}
+
+// statResult is the output of the stat command
+type statResult struct {
+	SchemaVersion string `json:"schemaVersion"`
+	Path          string `json:"path"`
+	Size          int64  `json:"size"`
+	Mode          string `json:"mode"`
+	UID           int    `json:"uid"`
+	GID           int    `json:"gid"`
+	ModTime       string `json:"mtime"`
+	LinkTarget    string `json:"linkTarget,omitempty"`
+	Digest        string `json:"digest,omitempty"`
+	AddedLayer    int    `json:"addedLayer"`
+	Layer         int    `json:"layer"`
+	LayerCommand  string `json:"layerCommand"`
+	RemovedLayer  *int   `json:"removedLayer,omitempty"`
+	InFinalImage  bool   `json:"inFinalImage"`
+	// Warnings are the issues found while analyzing the path, which may make the result incomplete
+	Warnings []filetree.Warning `json:"warnings,omitempty"`
+}
+
+// doStat implements the steps taken for the stat command
+func doStat(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	format, _ := cmd.Flags().GetString("format")
+	if format != "text" && format != "json" {
+		fmt.Fprintf(os.Stderr, "unknown format '%s' (expected text or json)\n", format)
+		utils.Exit(1)
+	}
+	image.Quiet = format == "json"
+
+	layers, trees, _, _, err := image.LoadImage(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+
+	stat, err := filetree.StatPath(trees, args[1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+
+	header := stat.Info.TarHeader
+	result := statResult{
+		SchemaVersion: image.SchemaVersion,
+		Path:          "/" + strings.TrimPrefix(stat.Info.Path, "/"),
+		Size:          header.Size,
+		Mode:          header.FileInfo().Mode().String(),
+		UID:           header.Uid,
+		GID:           header.Gid,
+		ModTime:       header.ModTime.UTC().Format(time.RFC3339),
+		LinkTarget:    header.Linkname,
+		AddedLayer:    stat.AddedLayer,
+		Layer:         stat.Layer,
+		LayerCommand:  layers[len(layers)-1-stat.Layer].Command(),
+		InFinalImage:  stat.InFinalImage(),
+	}
+	if !header.FileInfo().IsDir() && header.Linkname == "" {
+		result.Digest = fmt.Sprintf("md5:%x", stat.Info.MD5sum)
+	}
+	if !stat.InFinalImage() {
+		result.RemovedLayer = &stat.RemovedLayer
+	}
+	for _, warning := range filetree.CollectWarnings(trees) {
+		if warning.Path == result.Path {
+			result.Warnings = append(result.Warnings, warning)
+		}
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			utils.Exit(1)
+		}
+		return
+	}
+
+	template := "%-15s %v\n"
+	fmt.Printf(template, "Path:", result.Path)
+	fmt.Printf(template, "Size:", result.Size)
+	fmt.Printf(template, "Mode:", result.Mode)
+	fmt.Printf(template, "Owner:", fmt.Sprintf("%d:%d", result.UID, result.GID))
+	fmt.Printf(template, "Modified:", result.ModTime)
+	if result.LinkTarget != "" {
+		fmt.Printf(template, "Link target:", result.LinkTarget)
+	}
+	if result.Digest != "" {
+		fmt.Printf(template, "Digest:", result.Digest)
+	}
+	fmt.Printf(template, "Added in:", fmt.Sprintf("layer %d", result.AddedLayer))
+	fmt.Printf(template, "Last changed:", fmt.Sprintf("layer %d (%s)", result.Layer, result.LayerCommand))
+	if result.RemovedLayer != nil {
+		fmt.Printf(template, "Removed in:", fmt.Sprintf("layer %d", *result.RemovedLayer))
+	}
+	fmt.Printf(template, "In final image:", result.InFinalImage)
+	for _, warning := range result.Warnings {
+		fmt.Printf(template, "Warning:", fmt.Sprintf("%s (%s)", warning.Message, warning.Kind))
+	}
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/viper"
+	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/image"
+)
+
+// suppressed is a finding that was not reported as such, since an accepted exception applies to it.
+type suppressed struct {
+	check       string
+	path        string
+	suppression filetree.Suppression
+}
+
+// loadSuppressions collects the accepted exceptions of the image: those declared by its labels (see
+// filetree.SuppressionsFromLabels) and those listed under the "suppressions" config key, for example:
+//
+//	suppressions:
+//	  - check: volumes
+//	    path: /var/lib/mysql/**
+//	    justification: the seed data is copied to the volume on first start
+//	    expires: 2024-12-31
+//
+// Every suppression given in the config needs a justification. Expired suppressions are reported (and ignored).
+func loadSuppressions(layers []*image.Layer) (filetree.Suppressions, error) {
+	var configured filetree.Suppressions
+	if err := viper.UnmarshalKey("suppressions", &configured, viper.DecodeHook(dateAsString)); err != nil {
+		return nil, fmt.Errorf("invalid suppressions config: %v", err)
+	}
+	for idx := range configured {
+		configured[idx].Source = "config"
+		if err := configured[idx].Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	suppressions := append(filetree.SuppressionsFromLabels(image.Labels(layers)), configured...)
+	for _, suppression := range suppressions.Expired(time.Now()) {
+		color.New(color.FgYellow).Printf("Suppression of %s expired on %s (%s), it no longer applies\n", suppression.Path, suppression.Expires, suppression.Source)
+	}
+	return suppressions, nil
+}
+
+// dateAsString keeps a date of the config (which YAML reads as a time) as the date it was written, where a string is
+// expected.
+func dateAsString(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if date, ok := data.(time.Time); ok && to.Kind() == reflect.String {
+		return date.Format("2006-01-02"), nil
+	}
+	return data, nil
+}
+
+// printSuppressed lists the findings that were suppressed, along with the justification of each.
+func printSuppressed(findings []suppressed) {
+	if len(findings) == 0 {
+		return
+	}
+	fmt.Println()
+	color.New(color.Bold).Printf("Suppressed (%d accepted exceptions):\n", len(findings))
+	for _, finding := range findings {
+		fmt.Printf("  %-8s %s: %s\n", finding.check, finding.path, finding.suppression)
+	}
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+	"gopkg.in/yaml.v2"
+)
+
+// testCmd represents the test command
+var testCmd = &cobra.Command{
+	Use:   "test [IMAGE]",
+	Short: "Checks the final filesystem of an image against a set of assertions.",
+	Long: `Checks the final (fully stacked) filesystem of an image against the assertions in the given YAML file, e.g.:
+
+  tests:
+    - name: python is installed
+      path: /usr/bin/python3
+      exists: true
+      mode: "0755"
+      owner: "0:0"
+      sizeUnder: 50MB
+    - name: no build cache
+      path: /tmp/build
+      exists: false
+
+Failing assertions of paths with an accepted exception (see the "suppressions" config key, or the dive.ignore image
+labels) are reported as skipped rather than failed. The command exits with 1 when any assertion fails. With --junit
+the results are also written as a JUnit XML report.`,
+	Args: cobra.ExactArgs(1),
+	Run:  doTest,
+}
+
+func init() {
+	rootCmd.AddCommand(testCmd)
+	testCmd.Flags().StringP("file", "f", "", "YAML file with the assertions to check")
+	testCmd.Flags().String("junit", "", "write a JUnit XML report to the given file")
+	testCmd.MarkFlagRequired("file")
+}
+
+// testFile is the YAML file holding the assertions
+type testFile struct {
+	Tests []filetree.Assertion `yaml:"tests"`
+}
+
+// junit report elements
+type junitTestSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Skipped  int             `xml:"skipped,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Details string `xml:",chardata"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// doTest implements the steps taken for the test command
+func doTest(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	testPath, _ := cmd.Flags().GetString("file")
+	junitPath, _ := cmd.Flags().GetString("junit")
+
+	contents, err := ioutil.ReadFile(testPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+	var tests testFile
+	if err = yaml.UnmarshalStrict(contents, &tests); err != nil {
+		fmt.Fprintf(os.Stderr, "could not read %s: %v\n", testPath, err)
+		utils.Exit(1)
+	}
+
+	layers, trees, _, _, err := image.LoadImage(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+	suppressions, err := loadSuppressions(layers)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+	tree := filetree.StackRange(trees, 0, len(trees)-1)
+
+	suite := junitSuite{Name: args[0], Tests: len(tests.Tests)}
+	var accepted []suppressed
+	fmt.Println()
+	for _, assertion := range tests.Tests {
+		result := assertion.Evaluate(tree)
+		testCase := junitTestCase{Name: assertion.Title(), ClassName: args[0]}
+		suppression, isSuppressed := suppressions.Match("test", assertion.Path, time.Now())
+		if result.Passed() {
+			fmt.Printf("%s %s\n", color.New(color.FgGreen).Sprint("PASS"), assertion.Title())
+		} else if isSuppressed {
+			suite.Skipped++
+			fmt.Printf("%s %s\n", color.New(color.FgYellow).Sprint("SKIP"), assertion.Title())
+			testCase.Skipped = &junitSkipped{Message: suppression.String()}
+			accepted = append(accepted, suppressed{check: "test", path: assertion.Path, suppression: suppression})
+		} else {
+			suite.Failures++
+			fmt.Printf("%s %s\n", color.New(color.FgRed).Sprint("FAIL"), assertion.Title())
+			for _, failure := range result.Failures {
+				fmt.Println("     " + failure)
+			}
+			testCase.Failure = &junitFailure{Message: result.Failures[0], Details: strings.Join(result.Failures, "\n")}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	printSuppressed(accepted)
+	fmt.Printf("\n%d passed, %d failed, %d suppressed\n", suite.Tests-suite.Failures-suite.Skipped, suite.Failures, suite.Skipped)
+
+	if junitPath != "" {
+		report, err := xml.MarshalIndent(junitTestSuites{Suites: []junitSuite{suite}}, "", "  ")
+		if err == nil {
+			err = ioutil.WriteFile(junitPath, append([]byte(xml.Header), report...), 0644)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not write JUnit report: %v\n", err)
+			utils.Exit(1)
+		}
+	}
+
+	if suite.Failures > 0 {
+		utils.Exit(1)
+	}
+}
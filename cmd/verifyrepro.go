@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// verifyReproCmd represents the verify-repro command
+var verifyReproCmd = &cobra.Command{
+	Use:   "verify-repro [IMAGE] [IMAGE]",
+	Short: "Verifies that two builds of an image have semantically identical filesystems.",
+	Long: `Compares the final filesystems of two builds of the same image (e.g. built from the same commit on two machines),
+to verify that the build is reproducible. Modification times are not compared, and the paths that legitimately differ
+between builds (logs, caches, compiled byte code and machine identifiers) are ignored:
+
+  ` + strings.Join(filetree.NondeterministicPaths, "\n  ") + `
+
+More paths (glob patterns, where ** matches any number of directories) may be ignored with --ignore, and --strict
+ignores none of the paths above. Every remaining difference is listed.
+
+The command exits with 0 when the builds are identical, 1 when they differ, and 2 when an error occurred.`,
+	Args: cobra.ExactArgs(2),
+	Run:  doVerifyRepro,
+}
+
+func init() {
+	rootCmd.AddCommand(verifyReproCmd)
+	verifyReproCmd.Flags().StringSlice("ignore", nil, "additional paths to ignore (may be given multiple times)")
+	verifyReproCmd.Flags().Bool("strict", false, "do not ignore the paths that commonly differ between builds")
+	verifyReproCmd.Flags().String("format", "text", "output format (text or json)")
+	addSchemaFlag(verifyReproCmd, reproResult{})
+}
+
+// reproResult is the output of the verify-repro command
+type reproResult struct {
+	SchemaVersion string            `json:"schemaVersion"`
+	Reproducible  bool              `json:"reproducible"`
+	Ignored       int               `json:"ignored"`
+	Differences   []reproDifference `json:"differences"`
+}
+
+// reproDifference is a single file that differs between the builds
+type reproDifference struct {
+	Path   string `json:"path"`
+	Change string `json:"change"`
+	// Changes describes what differs for modified files (e.g. "mode 0644→0755")
+	Changes []string `json:"changes,omitempty"`
+}
+
+// doVerifyRepro implements the steps taken for the verify-repro command
+func doVerifyRepro(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	format, _ := cmd.Flags().GetString("format")
+	if format != "text" && format != "json" {
+		fmt.Fprintf(os.Stderr, "unknown format '%s' (expected text or json)\n", format)
+		utils.Exit(diffExitError)
+	}
+	image.Quiet = format == "json"
+	ignore, _ := cmd.Flags().GetStringSlice("ignore")
+	if strict, _ := cmd.Flags().GetBool("strict"); !strict {
+		ignore = append(ignore, filetree.NondeterministicPaths...)
+	}
+
+	var trees [2]*filetree.FileTree
+	for idx, source := range args {
+		_, layerTrees, _, _, err := image.LoadImage(source)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			utils.Exit(diffExitError)
+		}
+		trees[idx] = filetree.StackRange(layerTrees, 0, len(layerTrees)-1)
+	}
+	report, err := filetree.CompareBuilds(trees[0], trees[1], ignore)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(diffExitError)
+	}
+
+	result := reproResult{SchemaVersion: image.SchemaVersion, Reproducible: report.Reproducible(), Ignored: report.Ignored, Differences: []reproDifference{}}
+	for _, difference := range report.Differences {
+		entry := reproDifference{Path: difference.Path, Change: strings.ToLower(difference.DiffType.String())}
+		for _, attribute := range difference.Attributes {
+			entry.Changes = append(entry.Changes, attribute.String())
+		}
+		result.Differences = append(result.Differences, entry)
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			utils.Exit(diffExitError)
+		}
+	} else {
+		fmt.Println()
+		markers := map[string]string{"added": "A", "removed": "D", "changed": "M"}
+		for _, difference := range result.Differences {
+			fmt.Printf("%s %s", markers[difference.Change], difference.Path)
+			if len(difference.Changes) > 0 {
+				fmt.Printf(" (%s)", strings.Join(difference.Changes, ", "))
+			}
+			fmt.Println()
+		}
+		if len(result.Differences) > 0 {
+			fmt.Println()
+		}
+		if result.Reproducible {
+			color.New(color.FgGreen, color.Bold).Print("Reproducible: ")
+			fmt.Print("the filesystems are identical")
+		} else {
+			color.New(color.FgRed, color.Bold).Print("Not reproducible: ")
+			fmt.Printf("%d files differ", len(result.Differences))
+		}
+		fmt.Printf(" (%d differences on ignored paths)\n", result.Ignored)
+	}
+
+	if !result.Reproducible {
+		utils.Exit(diffExitDiffer)
+	}
+	utils.Exit(diffExitIdentical)
+}
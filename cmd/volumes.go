@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// volumesCmd represents the volumes command
+var volumesCmd = &cobra.Command{
+	Use:   "volumes [IMAGE]",
+	Short: "Lists content baked into the image under declared volumes (which is shadowed at runtime).",
+	Long: `Cross-references the VOLUME declarations of an image with the files baked under those paths. Any such content
+is hidden as soon as a volume (or tmpfs) is mounted there at runtime, yet still takes up space in the image.
+
+Mount points that are not declared in the image (e.g. tmpfs mounts given to docker run) can be checked with --tmpfs.
+Files with an accepted exception (see the "suppressions" config key, or the dive.ignore image labels) are listed
+apart.`,
+	Args: cobra.ExactArgs(1),
+	Run:  doVolumes,
+}
+
+func init() {
+	rootCmd.AddCommand(volumesCmd)
+	volumesCmd.Flags().StringSlice("tmpfs", nil, "additional mount points to check (may be given multiple times)")
+	volumesCmd.Flags().Int("top", 10, "number of files to list per volume (0 lists all)")
+}
+
+// doVolumes implements the steps taken for the volumes command
+func doVolumes(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	extra, _ := cmd.Flags().GetStringSlice("tmpfs")
+	top, _ := cmd.Flags().GetInt("top")
+
+	layers, trees, _, _, err := image.LoadImage(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+
+	var volumes []string
+	for _, volume := range append(image.Volumes(layers), extra...) {
+		volumes = append(volumes, path.Clean("/"+volume))
+	}
+	fmt.Println()
+	if len(volumes) == 0 {
+		fmt.Println("The image declares no volumes.")
+		return
+	}
+
+	suppressions, err := loadSuppressions(layers)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+
+	byVolume := make(map[string][]filetree.VolumeFinding)
+	var accepted []suppressed
+	for _, finding := range filetree.VolumeFindings(trees, volumes) {
+		if suppression, ok := suppressions.Match("volumes", finding.Path, time.Now()); ok {
+			accepted = append(accepted, suppressed{check: "volumes", path: finding.Path, suppression: suppression})
+			continue
+		}
+		byVolume[finding.Volume] = append(byVolume[finding.Volume], finding)
+	}
+
+	var total uint64
+	template := "  %10s  %5s  %s\n"
+	for _, volume := range volumes {
+		findings := byVolume[volume]
+		var size uint64
+		for _, finding := range findings {
+			size += uint64(finding.Size)
+		}
+		total += size
+
+		if len(findings) == 0 {
+			fmt.Printf("%s %s\n", color.New(color.Bold).Sprint(volume), "(no baked content)")
+			continue
+		}
+		color.New(color.Bold).Printf("%s (%d files, %s shadowed at runtime)\n", volume, len(findings), humanize.Bytes(size))
+		color.New(color.Bold).Printf(template, "Size", "Layer", "Path")
+		for idx, finding := range findings {
+			if top > 0 && idx >= top {
+				fmt.Printf("  ... and %d more\n", len(findings)-top)
+				break
+			}
+			fmt.Printf(template, humanize.Bytes(uint64(finding.Size)), fmt.Sprintf("%d", finding.Layer), finding.Path)
+		}
+		fmt.Println()
+	}
+
+	printSuppressed(accepted)
+	fmt.Println()
+	fmt.Printf("%s %s\n", color.New(color.Bold).Sprint("Content shadowed by volumes:"), humanize.Bytes(total))
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/image"
+	"github.com/wagoodman/dive/utils"
+)
+
+// wasteCmd represents the waste command
+var wasteCmd = &cobra.Command{
+	Use:   "waste [IMAGE]",
+	Short: "Attributes the wasted space of an image to the Dockerfile instructions that caused it.",
+	Long: `Attributes the wasted space of an image to the instructions (layers) that caused it: an instruction that
+overwrites or removes a file of an earlier layer wastes the bytes of the copy it hides, which the earlier layer still
+stores. For every such instruction the wasted bytes and the files it made redundant are listed, largest first.
+
+With --max-wasted the command exits with 1 when more bytes than given are wasted, e.g. to fail a CI build.`,
+	Args: cobra.ExactArgs(1),
+	Run:  doWaste,
+}
+
+func init() {
+	rootCmd.AddCommand(wasteCmd)
+	wasteCmd.Flags().String("format", "text", "output format (text or json)")
+	wasteCmd.Flags().String("max-wasted", "", "fail when more than this many bytes are wasted (e.g. 10MB)")
+	addSchemaFlag(wasteCmd, wasteResult{})
+}
+
+// wasteResult is the output of the waste command
+type wasteResult struct {
+	SchemaVersion string             `json:"schemaVersion"`
+	Wasted        int64              `json:"wasted"`
+	Instructions  []instructionWaste `json:"instructions"`
+}
+
+// instructionWaste is the space wasted by a single instruction
+type instructionWaste struct {
+	Layer       int      `json:"layer"`
+	Instruction string   `json:"instruction"`
+	Wasted      int64    `json:"wasted"`
+	Paths       []string `json:"paths"`
+}
+
+// doWaste implements the steps taken for the waste command
+func doWaste(cmd *cobra.Command, args []string) {
+	defer utils.Cleanup()
+
+	format, _ := cmd.Flags().GetString("format")
+	if format != "text" && format != "json" {
+		fmt.Fprintf(os.Stderr, "unknown format '%s' (expected text or json)\n", format)
+		utils.Exit(1)
+	}
+	image.Quiet = format == "json"
+	var limit uint64
+	if value, _ := cmd.Flags().GetString("max-wasted"); value != "" {
+		var err error
+		if limit, err = humanize.ParseBytes(value); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid --max-wasted: %v\n", err)
+			utils.Exit(1)
+		}
+	}
+
+	layers, _, _, inefficiencies, err := image.LoadImage(args[0])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		utils.Exit(1)
+	}
+
+	result := wasteResult{SchemaVersion: image.SchemaVersion, Instructions: []instructionWaste{}}
+	for _, waste := range filetree.WasteByLayer(inefficiencies) {
+		// the layers are ordered newest first
+		layer := layers[len(layers)-1-waste.Layer]
+		instruction := layer.Command()
+		if instruction == "" {
+			// the history does not record the instruction
+			instruction = layer.ShortId()
+		}
+		result.Wasted += waste.WastedSize
+		result.Instructions = append(result.Instructions, instructionWaste{
+			Layer:       waste.Layer,
+			Instruction: instruction,
+			Wasted:      waste.WastedSize,
+			Paths:       waste.Paths,
+		})
+	}
+
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			utils.Exit(1)
+		}
+	} else {
+		fmt.Println()
+		template := "%5s  %10s  %s\n"
+		color.New(color.Bold).Printf(template, "Layer", "Wasted", "Instruction")
+		for _, instruction := range result.Instructions {
+			fmt.Printf(template, fmt.Sprintf("%d", instruction.Layer), humanize.Bytes(uint64(instruction.Wasted)), instruction.Instruction)
+			for _, path := range instruction.Paths {
+				fmt.Printf(template, "", "", "  "+path)
+			}
+		}
+		fmt.Printf("\n%s wasted by %d instructions\n", humanize.Bytes(uint64(result.Wasted)), len(result.Instructions))
+	}
+
+	if limit > 0 && uint64(result.Wasted) > limit {
+		if format == "text" {
+			color.New(color.FgRed, color.Bold).Print("FAIL ")
+			fmt.Printf("more than %s is wasted\n", humanize.Bytes(limit))
+		}
+		utils.Exit(1)
+	}
+}
@@ -0,0 +1,241 @@
+package filetree
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+// AccessProfile is the set of absolute paths that were read while a container started.
+type AccessProfile map[string]bool
+
+// LayerAccess summarizes how much of a single layer is needed at container start.
+type LayerAccess struct {
+	Index    int
+	Size     uint64
+	HotFiles int
+	HotSize  uint64
+}
+
+// LazyPullReport describes how well the ordering of the given layers supports lazy pulling, given an AccessProfile.
+type LazyPullReport struct {
+	Layers      []LayerAccess
+	HotSize     uint64
+	FetchSize   uint64
+	Missing     []string
+	Suggestions []string
+}
+
+// accessLogPath extracts the accessed path from a single line of an access log: a plain path, a JSON object with a
+// "path" (or "filename") field as written by eBPF tracing tools, or the output of a file access monitor such as fatrace
+// ("sh(1234): O /etc/passwd") or opensnoop ("1234 sh 3 0 /etc/passwd"), where the path is the last absolute path on the
+// line. Lines without a path (e.g. column headers) yield an empty string.
+func accessLogPath(line string) (string, error) {
+	switch {
+	case strings.HasPrefix(line, "/"):
+		return line, nil
+	case strings.HasPrefix(line, "{"):
+		var event struct {
+			Path     string `json:"path"`
+			Filename string `json:"filename"`
+		}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			return "", fmt.Errorf("could not parse access log entry: %v", err)
+		}
+		if event.Path != "" {
+			return event.Path, nil
+		}
+		return event.Filename, nil
+	}
+	fields := strings.Fields(line)
+	for idx := len(fields) - 1; idx >= 0; idx-- {
+		if strings.HasPrefix(fields[idx], "/") {
+			return fields[idx], nil
+		}
+	}
+	return "", nil
+}
+
+// ReadAccessProfile reads an access profile, which is either a JSON array of paths or an access log of one access per
+// line (a plain path, a JSON event, or the output of a file access monitor such as fatrace or opensnoop).
+func ReadAccessProfile(reader io.Reader) (AccessProfile, error) {
+	contents, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	if trimmed := bytes.TrimSpace(contents); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &paths)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse access profile: %v", err)
+		}
+	} else {
+		scanner := bufio.NewScanner(bytes.NewReader(contents))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			accessed, err := accessLogPath(line)
+			if err != nil {
+				return nil, err
+			}
+			if accessed != "" {
+				paths = append(paths, accessed)
+			}
+		}
+		if err = scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	profile := make(AccessProfile)
+	for _, value := range paths {
+		profile[path.Clean("/"+value)] = true
+	}
+	return profile, nil
+}
+
+// providers returns the index of the layer that supplies each file in the final (stacked) filesystem.
+func providers(trees []*FileTree) map[string]int {
+	result := make(map[string]int)
+	StackFiles(trees, func(layer int, node *FileNode) {
+		result[node.Path()] = layer
+	}, func(layer int, path string) {
+		delete(result, path)
+	})
+	return result
+}
+
+// LazyPull determines which layers must be fetched to satisfy the reads in the given profile and suggests
+// reordering when the needed bytes are buried in large layers.
+func LazyPull(trees []*FileTree, profile AccessProfile) *LazyPullReport {
+	report := &LazyPullReport{
+		Layers: make([]LayerAccess, len(trees)),
+	}
+	for idx, tree := range trees {
+		report.Layers[idx] = LayerAccess{Index: idx, Size: tree.FileSize}
+	}
+
+	provided := providers(trees)
+	for accessed := range profile {
+		idx, ok := provided[accessed]
+		if !ok {
+			report.Missing = append(report.Missing, accessed)
+			continue
+		}
+		node, err := trees[idx].lookup(accessed)
+		if err != nil {
+			report.Missing = append(report.Missing, accessed)
+			continue
+		}
+		size := uint64(node.Data.FileInfo.TarHeader.FileInfo().Size())
+		report.Layers[idx].HotFiles++
+		report.Layers[idx].HotSize += size
+		report.HotSize += size
+	}
+	sort.Strings(report.Missing)
+
+	for _, layer := range report.Layers {
+		if layer.HotFiles == 0 {
+			continue
+		}
+		report.FetchSize += layer.Size
+
+		// most of this layer is never read at startup, the hot files would be cheaper to fetch on their own
+		if layer.Index > 0 && layer.HotSize*2 < layer.Size {
+			report.Suggestions = append(report.Suggestions, fmt.Sprintf("layer %d: only %s of %s is read at startup (%d files), consider moving these files into an earlier or smaller layer",
+				layer.Index, humanize.Bytes(layer.HotSize), humanize.Bytes(layer.Size), layer.HotFiles))
+		}
+	}
+
+	return report
+}
+
+// Efficiency is the fraction of fetched bytes that are actually read at startup (1.0 is ideal).
+func (report *LazyPullReport) Efficiency() float64 {
+	if report.FetchSize == 0 {
+		return 1.0
+	}
+	return float64(report.HotSize) / float64(report.FetchSize)
+}
+
+// UnreadDir is a directory of which no file is read at container start.
+type UnreadDir struct {
+	Path  string
+	Files int
+	Size  uint64
+}
+
+// AccessUsage summarizes how much of the final filesystem of an image is read at container start, given an
+// AccessProfile. Bytes that are never read are candidates for removal from the image.
+type AccessUsage struct {
+	ReadFiles   int
+	ReadSize    uint64
+	UnreadFiles int
+	UnreadSize  uint64
+	// UnreadDirs are the top-most directories of which no file is read, largest first
+	UnreadDirs []UnreadDir
+}
+
+// Usage determines which files of the given (stacked) tree are read at container start and which are never read.
+func (profile AccessProfile) Usage(tree *FileTree) AccessUsage {
+	// the directories holding an accessed path are (partially) read
+	readDirs := map[string]bool{"/": true}
+	for accessed := range profile {
+		for dir := path.Dir(accessed); !readDirs[dir]; dir = path.Dir(dir) {
+			readDirs[dir] = true
+		}
+	}
+
+	var usage AccessUsage
+	unreadDirs := make(map[string]*UnreadDir)
+	tree.ObserveDepthParentFirst(func(node *FileNode) error {
+		info := node.Data.FileInfo.TarHeader.FileInfo()
+		if info.IsDir() || !node.IsLeaf() {
+			parent := node.Parent.Path()
+			if !readDirs[node.Path()] && !profile[node.Path()] && (readDirs[parent] || profile[parent]) {
+				unreadDirs[node.Path()] = &UnreadDir{Path: node.Path()}
+			}
+			return nil
+		}
+		size := uint64(info.Size())
+		if profile[node.Path()] {
+			usage.ReadFiles++
+			usage.ReadSize += size
+			return nil
+		}
+		usage.UnreadFiles++
+		usage.UnreadSize += size
+		for dir := path.Dir(node.Path()); dir != "/"; dir = path.Dir(dir) {
+			if unread, ok := unreadDirs[dir]; ok {
+				unread.Files++
+				unread.Size += size
+				break
+			}
+		}
+		return nil
+	}, nil)
+
+	for _, unread := range unreadDirs {
+		if unread.Files > 0 {
+			usage.UnreadDirs = append(usage.UnreadDirs, *unread)
+		}
+	}
+	sort.Slice(usage.UnreadDirs, func(i, j int) bool {
+		if usage.UnreadDirs[i].Size != usage.UnreadDirs[j].Size {
+			return usage.UnreadDirs[i].Size > usage.UnreadDirs[j].Size
+		}
+		return usage.UnreadDirs[i].Path < usage.UnreadDirs[j].Path
+	})
+	return usage
+}
@@ -0,0 +1,132 @@
+package filetree
+
+import (
+	"archive/tar"
+	"crypto/md5"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fileOfSize is a regular file of the given size, whose contents (checksum) differ from files of other sizes.
+func fileOfSize(path string, size int64) FileInfo {
+	return FileInfo{
+		Path:      path,
+		TypeFlag:  tar.TypeReg,
+		MD5sum:    md5.Sum([]byte(strconv.FormatInt(size, 10))),
+		TarHeader: tar.Header{Name: path, Typeflag: tar.TypeReg, Size: size},
+	}
+}
+
+func TestReadAccessProfile(t *testing.T) {
+	profile, err := ReadAccessProfile(strings.NewReader(`["/etc/hosts", "usr/bin/app"]`))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !profile["/etc/hosts"] || !profile["/usr/bin/app"] || len(profile) != 2 {
+		t.Errorf("Unexpected JSON profile: %+v", profile)
+	}
+
+	profile, err = ReadAccessProfile(strings.NewReader("# startup reads\n/etc/hosts\n\n/usr/bin/app\n"))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !profile["/etc/hosts"] || !profile["/usr/bin/app"] || len(profile) != 2 {
+		t.Errorf("Unexpected line profile: %+v", profile)
+	}
+
+	log := `PID    COMM               FD ERR PATH
+1      app                 3   0 /etc/ld.so.cache
+app(1): RO /usr/lib/libc.so.6
+{"pid": 1, "comm": "app", "filename": "/etc/hosts"}
+{"pid": 1, "comm": "app", "path": "/usr/bin/app"}
+`
+	profile, err = ReadAccessProfile(strings.NewReader(log))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	for _, expected := range []string{"/etc/ld.so.cache", "/usr/lib/libc.so.6", "/etc/hosts", "/usr/bin/app"} {
+		if !profile[expected] {
+			t.Errorf("Expected %s in the access log profile", expected)
+		}
+	}
+	if len(profile) != 4 {
+		t.Errorf("Unexpected access log profile: %+v", profile)
+	}
+}
+
+func TestAccessUsage(t *testing.T) {
+	tree := NewFileTree()
+	tree.AddPath("/etc/hosts", fileOfSize("/etc/hosts", 10))
+	tree.AddPath("/etc/motd", fileOfSize("/etc/motd", 5))
+	tree.AddPath("/usr/bin/app", fileOfSize("/usr/bin/app", 100))
+	tree.AddPath("/usr/share/doc/app/README", fileOfSize("/usr/share/doc/app/README", 400))
+	tree.AddPath("/usr/share/man/app.1", fileOfSize("/usr/share/man/app.1", 50))
+	tree.AddPath("/opt/cache/blob", fileOfSize("/opt/cache/blob", 1000))
+
+	usage := AccessProfile{"/etc/hosts": true, "/usr/bin/app": true}.Usage(tree)
+	if usage.ReadFiles != 2 || usage.ReadSize != 110 {
+		t.Errorf("Expected 2 files (110 bytes) to be read, got %d (%d bytes)", usage.ReadFiles, usage.ReadSize)
+	}
+	if usage.UnreadFiles != 4 || usage.UnreadSize != 1455 {
+		t.Errorf("Expected 4 files (1455 bytes) never to be read, got %d (%d bytes)", usage.UnreadFiles, usage.UnreadSize)
+	}
+
+	expected := []UnreadDir{
+		{Path: "/opt", Files: 1, Size: 1000},
+		{Path: "/usr/share", Files: 2, Size: 450},
+	}
+	if len(usage.UnreadDirs) != len(expected) {
+		t.Fatalf("Expected unread directories %+v, got %+v", expected, usage.UnreadDirs)
+	}
+	for idx, dir := range expected {
+		if usage.UnreadDirs[idx] != dir {
+			t.Errorf("Expected unread directory %+v, got %+v", dir, usage.UnreadDirs[idx])
+		}
+	}
+}
+
+func TestLazyPull(t *testing.T) {
+	lower := NewFileTree()
+	lower.AddPath("/etc/hosts", fileOfSize("/etc/hosts", 10))
+	lower.AddPath("/etc/old", fileOfSize("/etc/old", 10))
+	lower.FileSize = 20
+
+	upper := NewFileTree()
+	upper.AddPath("/usr/bin/app", fileOfSize("/usr/bin/app", 100))
+	upper.AddPath("/usr/share/blob", fileOfSize("/usr/share/blob", 900))
+	upper.AddPath("/etc/.wh.old", FileInfo{})
+	upper.FileSize = 1000
+
+	profile := AccessProfile{"/etc/hosts": true, "/usr/bin/app": true, "/etc/old": true}
+	report := LazyPull([]*FileTree{lower, upper}, profile)
+
+	if report.HotSize != 110 {
+		t.Errorf("Expected hot size of 110, got %d", report.HotSize)
+	}
+	if report.FetchSize != 1020 {
+		t.Errorf("Expected fetch size of 1020, got %d", report.FetchSize)
+	}
+	if len(report.Missing) != 1 || report.Missing[0] != "/etc/old" {
+		t.Errorf("Expected removed file to be missing, got %v", report.Missing)
+	}
+	if report.Layers[1].HotFiles != 1 {
+		t.Errorf("Expected 1 hot file in layer 1, got %d", report.Layers[1].HotFiles)
+	}
+	if len(report.Suggestions) != 1 {
+		t.Errorf("Expected a single suggestion, got %v", report.Suggestions)
+	}
+}
+
+func TestProviders(t *testing.T) {
+	trees := opaqueLayers()
+	expected := map[string]int{"/app/lib/kept.so": 1, "/application": 0}
+	if provided := providers(trees[:2]); !reflect.DeepEqual(provided, expected) {
+		t.Errorf("Expected %v, got %v", expected, provided)
+	}
+	expected = map[string]int{"/app/lib/big.so": 2, "/app/lib/kept.so": 2, "/application": 2, "/etc/hosts": 2}
+	if provided := providers(trees); !reflect.DeepEqual(provided, expected) {
+		t.Errorf("Expected %v, got %v", expected, provided)
+	}
+}
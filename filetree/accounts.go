@@ -0,0 +1,91 @@
+package filetree
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	passwdPath = "/etc/passwd"
+	groupPath  = "/etc/group"
+)
+
+// Accounts are the user and group names of an image by their numeric ID, as declared by its /etc/passwd and
+// /etc/group.
+type Accounts struct {
+	Users  map[int]string
+	Groups map[int]string
+}
+
+// accounts are the names shown for the owners of files (numeric IDs are shown when nil), see SetAccounts.
+var accounts *Accounts
+
+// SetAccounts selects the user and group names shown for the owners of files in the tree metadata (see
+// MetadataString), or numeric IDs when nil.
+func SetAccounts(names *Accounts) {
+	accounts = names
+}
+
+// isAccountFile indicates if the given path is an account database (/etc/passwd or /etc/group), whose names are read
+// along with the file.
+func isAccountFile(filePath string) bool {
+	cleaned := path.Clean("/" + filePath)
+	return cleaned == passwdPath || cleaned == groupPath
+}
+
+// parseAccounts reads the names by ID of an account database: lines of colon separated fields starting with the name,
+// a password placeholder and the numeric ID (as in both /etc/passwd and /etc/group). The first name of an ID wins, as
+// with getpwuid.
+func parseAccounts(contents []byte) map[int]string {
+	names := make(map[int]string)
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, ":")
+		if len(fields) < 3 || fields[0] == "" {
+			continue
+		}
+		id, err := strconv.Atoi(fields[2])
+		if err != nil {
+			continue
+		}
+		if _, ok := names[id]; !ok {
+			names[id] = fields[0]
+		}
+	}
+	return names
+}
+
+// AccountsOf returns the user and group names declared by the /etc/passwd and /etc/group of the given tree (typically
+// the final tree of an image, see StackRange), which are empty when the tree has neither file (or their contents were
+// not read).
+func AccountsOf(tree *FileTree) *Accounts {
+	result := &Accounts{Users: map[int]string{}, Groups: map[int]string{}}
+	if node, err := tree.lookup(passwdPath); err == nil {
+		result.Users = node.Data.FileInfo.AccountNames
+	}
+	if node, err := tree.lookup(groupPath); err == nil {
+		result.Groups = node.Data.FileInfo.AccountNames
+	}
+	return result
+}
+
+// Owner shows the given owner as "user:group", using the numeric ID of a user or group without a name.
+func (names *Accounts) Owner(uid, gid int) string {
+	user, ok := names.Users[uid]
+	if !ok {
+		user = strconv.Itoa(uid)
+	}
+	group, ok := names.Groups[gid]
+	if !ok {
+		group = strconv.Itoa(gid)
+	}
+	return fmt.Sprintf("%s:%s", user, group)
+}
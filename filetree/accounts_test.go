@@ -0,0 +1,39 @@
+package filetree
+
+import (
+	"archive/tar"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAccounts(t *testing.T) {
+	passwd := "root:x:0:0:root:/root:/bin/sh\n# comment\nnode:x:1000:1000::/home/node:/bin/sh\ntoor:x:0:0::/:/bin/sh\nbroken\n"
+	group := "root:x:0:\nnode:x:1000:\n"
+
+	lower := NewFileTree()
+	for path, contents := range map[string]string{"etc/passwd": passwd, "etc/group": group} {
+		header := &tar.Header{Name: path, Typeflag: tar.TypeReg, Size: int64(len(contents)), Mode: 0644}
+		lower.AddPath(path, fileInfoOf(t, bytes.NewReader([]byte(contents)), header, path))
+	}
+	upper := NewFileTree()
+	node, _ := upper.AddPath("/app/index.js", FileInfo{TarHeader: tar.Header{Typeflag: tar.TypeReg, Uid: 1000, Gid: 1001}})
+
+	names := AccountsOf(StackRange([]*FileTree{lower, upper}, 0, 1))
+	if owner := names.Owner(1000, 1001); owner != "node:1001" {
+		t.Errorf("Expected owner node:1001, got %s", owner)
+	}
+	if owner := names.Owner(0, 0); owner != "root:root" {
+		t.Errorf("Expected the first name of an ID to win, got %s", owner)
+	}
+
+	SetAccounts(names)
+	defer SetAccounts(nil)
+	if metadata := node.metadata(); !strings.Contains(metadata, "node:1001") {
+		t.Errorf("Expected the owner name in the metadata, got %q", metadata)
+	}
+
+	if owner := AccountsOf(upper).Owner(1000, 1000); owner != "1000:1000" {
+		t.Errorf("Expected numeric IDs without account databases, got %s", owner)
+	}
+}
@@ -0,0 +1,95 @@
+package filetree
+
+import (
+	"sort"
+	"time"
+)
+
+// AgeBucket is a range of file ages within an age histogram.
+type AgeBucket struct {
+	Label string
+	// MaxAge is the (exclusive) upper bound of ages in the bucket, the last bucket has no upper bound (zero)
+	MaxAge time.Duration
+}
+
+const day = 24 * time.Hour
+
+// AgeBuckets are the ranges used by AgeHistogram, from youngest to oldest.
+var AgeBuckets = []AgeBucket{
+	{Label: "<1m", MaxAge: 30 * day},
+	{Label: "<6m", MaxAge: 182 * day},
+	{Label: "<1y", MaxAge: 365 * day},
+	{Label: "<2y", MaxAge: 2 * 365 * day},
+	{Label: "<5y", MaxAge: 5 * 365 * day},
+	{Label: "5y+"},
+}
+
+// AgeFinding is a file in the final image along with its modification time and the layer that provided it.
+type AgeFinding struct {
+	Path    string
+	Layer   int
+	Size    int64
+	ModTime time.Time
+}
+
+// hasModTime indicates if the file carries a meaningful modification time. Reproducible builds commonly clamp all
+// mtimes to the epoch, which says nothing about the age of the content.
+func hasModTime(node *FileNode) bool {
+	return node.Data.FileInfo.TarHeader.ModTime.Unix() > 0
+}
+
+// ageBucket returns the index of the AgeBuckets entry that the given age falls in.
+func ageBucket(age time.Duration) int {
+	for idx, bucket := range AgeBuckets {
+		if bucket.MaxAge == 0 || age < bucket.MaxAge {
+			return idx
+		}
+	}
+	return len(AgeBuckets) - 1
+}
+
+// AgeHistogram counts the files added or modified by each layer within each of the AgeBuckets, relative to the given
+// time. The result is indexed by layer, then by bucket. Files without a meaningful modification time are not counted.
+func AgeHistogram(trees []*FileTree, now time.Time) [][]int {
+	histogram := make([][]int, len(trees))
+	for idx, tree := range trees {
+		histogram[idx] = make([]int, len(AgeBuckets))
+		tree.VisitDepthParentFirst(func(node *FileNode) error {
+			if !node.IsLeaf() || node.IsWhiteout() || node.Data.FileInfo.TarHeader.FileInfo().IsDir() || !hasModTime(node) {
+				return nil
+			}
+			histogram[idx][ageBucket(now.Sub(node.Data.FileInfo.TarHeader.ModTime))]++
+			return nil
+		}, nil)
+	}
+	return histogram
+}
+
+// StaleFiles lists the files of the final (stacked) image that were last modified before the given time, oldest first.
+func StaleFiles(trees []*FileTree, before time.Time) []AgeFinding {
+	var findings []AgeFinding
+	for filePath, layer := range providers(trees) {
+		node, err := trees[layer].GetNode(filePath)
+		if err != nil || !hasModTime(node) {
+			continue
+		}
+		header := node.Data.FileInfo.TarHeader
+		if !header.ModTime.Before(before) {
+			continue
+		}
+		findings = append(findings, AgeFinding{
+			Path:    filePath,
+			Layer:   layer,
+			Size:    header.FileInfo().Size(),
+			ModTime: header.ModTime,
+		})
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		if !findings[i].ModTime.Equal(findings[j].ModTime) {
+			return findings[i].ModTime.Before(findings[j].ModTime)
+		}
+		return findings[i].Path < findings[j].Path
+	})
+	return findings
+}
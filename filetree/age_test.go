@@ -0,0 +1,54 @@
+package filetree
+
+import (
+	"testing"
+	"time"
+)
+
+func fileModifiedAt(path string, modTime time.Time) FileInfo {
+	info := fileOfSize(path, 100)
+	info.TarHeader.ModTime = modTime
+	return info
+}
+
+func TestAgeHistogram(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	base := NewFileTree()
+	base.AddPath("/opt/openssl/libssl.so", fileModifiedAt("/opt/openssl/libssl.so", now.AddDate(-4, 0, 0)))
+	base.AddPath("/etc/reproducible", fileModifiedAt("/etc/reproducible", time.Unix(0, 0)))
+	app := NewFileTree()
+	app.AddPath("/app/main", fileModifiedAt("/app/main", now.Add(-time.Hour)))
+	app.AddPath("/app/vendor/old.js", fileModifiedAt("/app/vendor/old.js", now.AddDate(-10, 0, 0)))
+
+	histogram := AgeHistogram([]*FileTree{base, app}, now)
+	expected := [][]int{{0, 0, 0, 0, 1, 0}, {1, 0, 0, 0, 0, 1}}
+	for layer := range expected {
+		for bucket := range expected[layer] {
+			if histogram[layer][bucket] != expected[layer][bucket] {
+				t.Errorf("Layer %d: expected %v, got %v", layer, expected[layer], histogram[layer])
+				break
+			}
+		}
+	}
+}
+
+func TestStaleFiles(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	base := NewFileTree()
+	base.AddPath("/opt/openssl/libssl.so", fileModifiedAt("/opt/openssl/libssl.so", now.AddDate(-4, 0, 0)))
+	base.AddPath("/opt/legacy/tool", fileModifiedAt("/opt/legacy/tool", now.AddDate(-8, 0, 0)))
+	base.AddPath("/etc/reproducible", fileModifiedAt("/etc/reproducible", time.Unix(0, 0)))
+	app := NewFileTree()
+	app.AddPath("/app/main", fileModifiedAt("/app/main", now.Add(-time.Hour)))
+	app.AddPath("/opt/.wh.legacy", FileInfo{})
+
+	findings := StaleFiles([]*FileTree{base, app}, now.AddDate(-3, 0, 0))
+	if len(findings) != 1 {
+		t.Fatalf("Expected 1 stale file, got %d: %+v", len(findings), findings)
+	}
+	if findings[0].Path != "/opt/openssl/libssl.so" || findings[0].Layer != 0 {
+		t.Errorf("Unexpected finding: %+v", findings[0])
+	}
+}
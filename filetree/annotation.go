@@ -0,0 +1,83 @@
+package filetree
+
+import (
+	"sync"
+)
+
+// Badge is a short marker shown next to a file in the tree, describing a finding of an analyzer.
+type Badge struct {
+	Symbol string
+	Name   string
+}
+
+// Annotation attaches a Badge to the file at the given path.
+type Annotation struct {
+	Path  string
+	Badge Badge
+}
+
+// Annotations collects the badges of every annotated path. Analyzers report annotations from their own goroutines, so
+// all access is synchronized.
+type Annotations struct {
+	lock   sync.RWMutex
+	badges map[string][]Badge
+	legend []Badge
+}
+
+// NewAnnotations creates an empty set of annotations.
+func NewAnnotations() *Annotations {
+	return &Annotations{
+		badges: make(map[string][]Badge),
+	}
+}
+
+// Add attaches the annotation's badge to its path, returning false if the path already had this badge.
+func (annotations *Annotations) Add(annotation Annotation) bool {
+	annotations.lock.Lock()
+	defer annotations.lock.Unlock()
+
+	for _, badge := range annotations.badges[annotation.Path] {
+		if badge == annotation.Badge {
+			return false
+		}
+	}
+	annotations.badges[annotation.Path] = append(annotations.badges[annotation.Path], annotation.Badge)
+
+	known := false
+	for _, badge := range annotations.legend {
+		if badge == annotation.Badge {
+			known = true
+			break
+		}
+	}
+	if !known {
+		annotations.legend = append(annotations.legend, annotation.Badge)
+	}
+	return true
+}
+
+// Badges returns the badges attached to the given path.
+func (annotations *Annotations) Badges(path string) []Badge {
+	annotations.lock.RLock()
+	defer annotations.lock.RUnlock()
+
+	return append([]Badge(nil), annotations.badges[path]...)
+}
+
+// Has indicates if the given path has the badge with the given name.
+func (annotations *Annotations) Has(path, name string) bool {
+	for _, badge := range annotations.Badges(path) {
+		if badge.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Legend returns every badge in use, in the order they were first reported.
+func (annotations *Annotations) Legend() []Badge {
+	annotations.lock.RLock()
+	defer annotations.lock.RUnlock()
+
+	return append([]Badge(nil), annotations.legend...)
+}
@@ -0,0 +1,52 @@
+package filetree
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAnnotations(t *testing.T) {
+	large := Badge{Symbol: "L", Name: "large"}
+	wasted := Badge{Symbol: "W", Name: "wasted"}
+
+	annotations := NewAnnotations()
+	var wg sync.WaitGroup
+	for _, annotation := range []Annotation{{"/app/blob", large}, {"/app/blob", wasted}, {"/var/cache/pkg", wasted}} {
+		wg.Add(1)
+		go func(annotation Annotation) {
+			defer wg.Done()
+			annotations.Add(annotation)
+		}(annotation)
+	}
+	wg.Wait()
+
+	if annotations.Add(Annotation{"/app/blob", large}) {
+		t.Errorf("Expected a repeated annotation to not be added")
+	}
+	if len(annotations.Badges("/app/blob")) != 2 {
+		t.Errorf("Expected 2 badges, got %v", annotations.Badges("/app/blob"))
+	}
+	if !annotations.Has("/var/cache/pkg", "wasted") || annotations.Has("/var/cache/pkg", "large") {
+		t.Errorf("Unexpected badges for /var/cache/pkg: %v", annotations.Badges("/var/cache/pkg"))
+	}
+	if len(annotations.Legend()) != 2 {
+		t.Errorf("Expected 2 badges in the legend, got %v", annotations.Legend())
+	}
+}
+
+func TestRenderBadges(t *testing.T) {
+	tree := NewFileTree()
+	node, _ := tree.AddPath("/blob", FileInfo{})
+	node.Data.ViewInfo.Badges = []Badge{{Symbol: "L", Name: "large"}, {Symbol: "W", Name: "wasted"}}
+
+	actual := PlainRenderer{Glyphs: DefaultGlyphs}.Render(tree.Rows(0, tree.Size))
+	if expected := "└── blob LW\n"; actual != expected {
+		t.Errorf("Expected %q got %q", expected, actual)
+	}
+
+	copied := tree.Copy()
+	if !strings.Contains(copied.String(false), "LW") {
+		t.Errorf("Expected badges to be kept when copying a tree")
+	}
+}
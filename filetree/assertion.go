@@ -0,0 +1,113 @@
+package filetree
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+// Assertion is a single expectation about a path in an image (typically evaluated against the final, fully stacked
+// tree). Only the expectations that are given are checked.
+type Assertion struct {
+	Name string `yaml:"name"`
+	Path string `yaml:"path"`
+	// Exists expects the path to be present (true) or absent (false)
+	Exists *bool `yaml:"exists"`
+	// Mode expects the permissions as octal (e.g. "0755") or in 'ls -l' form (e.g. "-rwxr-xr-x")
+	Mode string `yaml:"mode"`
+	// Owner expects the owner as "uid" or "uid:gid"
+	Owner string `yaml:"owner"`
+	// SizeUnder expects the size (of a file, or of all files in a directory) to be below the given size (e.g. "50MB")
+	SizeUnder string `yaml:"sizeUnder"`
+}
+
+// AssertionResult is the outcome of evaluating an Assertion.
+type AssertionResult struct {
+	Assertion Assertion
+	Failures  []string
+}
+
+// Passed indicates if every expectation of the assertion was met.
+func (result AssertionResult) Passed() bool {
+	return len(result.Failures) == 0
+}
+
+// Title names the assertion for reporting, falling back to the path when the assertion has no name.
+func (assertion Assertion) Title() string {
+	if assertion.Name != "" {
+		return assertion.Name
+	}
+	return assertion.Path
+}
+
+// Evaluate checks every expectation of the assertion against the given tree.
+func (assertion Assertion) Evaluate(tree *FileTree) AssertionResult {
+	result := AssertionResult{Assertion: assertion}
+	fail := func(format string, args ...interface{}) {
+		result.Failures = append(result.Failures, fmt.Sprintf(format, args...))
+	}
+
+	node, err := tree.GetNode(assertion.Path)
+	exists := err == nil && node.Data.DiffType != Removed
+	if assertion.Exists != nil && *assertion.Exists != exists {
+		if exists {
+			fail("expected %s to be absent, but it exists", assertion.Path)
+		} else {
+			fail("expected %s to exist, but it does not", assertion.Path)
+		}
+		return result
+	}
+	if !exists {
+		if assertion.Mode != "" || assertion.Owner != "" || assertion.SizeUnder != "" {
+			fail("expected %s to exist, but it does not", assertion.Path)
+		}
+		return result
+	}
+
+	header := node.Data.FileInfo.TarHeader
+	if assertion.Mode != "" {
+		mode := header.FileInfo().Mode()
+		if octal, err := strconv.ParseUint(assertion.Mode, 8, 32); err == nil {
+			if os.FileMode(octal).Perm() != mode.Perm() {
+				fail("expected mode %s, got %04o", assertion.Mode, mode.Perm())
+			}
+		} else if assertion.Mode != mode.String() {
+			fail("expected mode %s, got %s", assertion.Mode, mode.String())
+		}
+	}
+
+	if assertion.Owner != "" {
+		owner := fmt.Sprintf("%d:%d", header.Uid, header.Gid)
+		if !strings.Contains(assertion.Owner, ":") {
+			owner = strconv.Itoa(header.Uid)
+		}
+		if assertion.Owner != owner {
+			fail("expected owner %s, got %s", assertion.Owner, owner)
+		}
+	}
+
+	if assertion.SizeUnder != "" {
+		limit, err := humanize.ParseBytes(assertion.SizeUnder)
+		if err != nil {
+			fail("invalid size %q: %v", assertion.SizeUnder, err)
+		} else {
+			var size uint64
+			node.VisitDepthChildFirst(func(current *FileNode) error {
+				if current.Data.DiffType != Removed && !current.Data.FileInfo.TarHeader.FileInfo().IsDir() {
+					size += uint64(current.Data.FileInfo.TarHeader.Size)
+				}
+				return nil
+			}, nil)
+			if !header.FileInfo().IsDir() {
+				size = uint64(header.Size)
+			}
+			if size >= limit {
+				fail("expected size under %s, got %s", humanize.Bytes(limit), humanize.Bytes(size))
+			}
+		}
+	}
+	return result
+}
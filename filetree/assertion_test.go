@@ -0,0 +1,46 @@
+package filetree
+
+import (
+	"archive/tar"
+	"strings"
+	"testing"
+)
+
+func TestAssertionEvaluate(t *testing.T) {
+	yes, no := true, false
+
+	tree := NewFileTree()
+	python := fileOfSize("/usr/bin/python3", 5*1000*1000)
+	python.TarHeader.Mode = 0755
+	python.TarHeader.Uid = 0
+	python.TarHeader.Gid = 0
+	tree.AddPath(python.Path, python)
+	tree.AddPath("/app/data/a.bin", fileOfSize("/app/data/a.bin", 600*1000))
+	tree.AddPath("/app/data/b.bin", fileOfSize("/app/data/b.bin", 600*1000))
+	dataDir, _ := tree.GetNode("/app/data")
+	dataDir.Data.FileInfo.TarHeader = tar.Header{Typeflag: tar.TypeDir, Mode: 0755}
+
+	cases := []struct {
+		assertion Assertion
+		failures  []string
+	}{
+		{Assertion{Path: "/usr/bin/python3", Exists: &yes, Mode: "0755", Owner: "0:0", SizeUnder: "10MB"}, nil},
+		{Assertion{Path: "/usr/bin/python3", Mode: "-rwxr-xr-x", Owner: "0"}, nil},
+		{Assertion{Path: "/usr/bin/python3", Mode: "0644", Owner: "1000"}, []string{"expected mode 0644, got 0755", "expected owner 1000, got 0"}},
+		{Assertion{Path: "/usr/bin/python3", SizeUnder: "1MB"}, []string{"expected size under 1.0 MB, got 5.0 MB"}},
+		{Assertion{Path: "/app/data", SizeUnder: "1MB"}, []string{"expected size under 1.0 MB, got 1.2 MB"}},
+		{Assertion{Path: "/tmp/build", Exists: &no}, nil},
+		{Assertion{Path: "/usr/bin/python3", Exists: &no}, []string{"expected /usr/bin/python3 to be absent, but it exists"}},
+		{Assertion{Path: "/usr/bin/python2", Mode: "0755"}, []string{"expected /usr/bin/python2 to exist, but it does not"}},
+	}
+
+	for _, test := range cases {
+		result := test.assertion.Evaluate(tree)
+		if strings.Join(result.Failures, "; ") != strings.Join(test.failures, "; ") {
+			t.Errorf("Expected failures %q for %+v, got %q", test.failures, test.assertion, result.Failures)
+		}
+		if result.Passed() != (len(test.failures) == 0) {
+			t.Errorf("Unexpected pass state for %+v", test.assertion)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package filetree
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// LayerBlame is what a single layer contributed to the size of a directory.
+type LayerBlame struct {
+	// Layer is the index of the layer (counting from the oldest layer)
+	Layer int
+	// Added is the bytes of the files the layer added to the directory (that were not there before)
+	Added uint64
+	// Overwritten is the bytes of the files the layer provided again (replacing the files of an earlier layer)
+	Overwritten uint64
+	// Deleted is the bytes of the files of earlier layers the layer removed from the directory
+	Deleted uint64
+	// Remaining is the bytes of the files the layer provided that are still in the directory in the final image
+	Remaining uint64
+}
+
+// blamedFile is a file of the directory as stacked so far, along with the layer that last provided it.
+type blamedFile struct {
+	size  uint64
+	layer int
+}
+
+// BlameDirectory follows the files beneath the given directory through the given layer trees (oldest first), noting
+// the bytes each layer added, overwrote and deleted, and the bytes of each layer that remain in the final image. Only
+// files are counted (not directories), by the bytes they add to their layer (see FileInfo.Size).
+func BlameDirectory(trees []*FileTree, dirPath string) ([]LayerBlame, error) {
+	dirPath = path.Clean("/" + strings.TrimSpace(dirPath))
+	key := strings.TrimSuffix(dirPath, "/")
+
+	blame := make([]LayerBlame, len(trees))
+	current := make(map[string]blamedFile)
+	found := false
+	for idx, tree := range trees {
+		blame[idx].Layer = idx
+
+		// whiteouts and opaque directories remove files before the layer provides its own (see Stack)
+		for filePath, file := range current {
+			if isRemovedBy(tree, filePath) {
+				blame[idx].Deleted += file.size
+				delete(current, filePath)
+			}
+		}
+
+		dir, err := tree.lookup(dirPath)
+		if err != nil {
+			continue
+		}
+		found = true
+		visitKeyed(dir, key, func(node *FileNode, filePath string) {
+			if node == dir || node.IsWhiteout() || node.Data.FileInfo.TarHeader.FileInfo().IsDir() || len(node.Children) > 0 {
+				return
+			}
+			size := uint64(node.Data.FileInfo.Size())
+			if _, exists := current[filePath]; exists {
+				blame[idx].Overwritten += size
+			} else {
+				blame[idx].Added += size
+			}
+			current[filePath] = blamedFile{size: size, layer: idx}
+		})
+	}
+	if !found {
+		return nil, fmt.Errorf("%w in any layer: %s", ErrPathNotFound, dirPath)
+	}
+
+	for _, file := range current {
+		blame[file.layer].Remaining += file.size
+	}
+	return blame, nil
+}
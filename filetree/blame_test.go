@@ -0,0 +1,42 @@
+package filetree
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestBlameDirectory(t *testing.T) {
+	base := NewFileTree()
+	base.AddPath("/usr/lib/libc.so", fileOfSize("/usr/lib/libc.so", 100))
+	base.AddPath("/usr/lib/libssl.so", fileOfSize("/usr/lib/libssl.so", 50))
+	base.AddPath("/etc/hosts", fileOfSize("/etc/hosts", 5))
+
+	update := NewFileTree()
+	update.AddPath("/usr/lib/libssl.so", fileOfSize("/usr/lib/libssl.so", 60))
+	update.AddPath("/usr/lib/python/site.py", fileOfSize("/usr/lib/python/site.py", 30))
+
+	cleanup := NewFileTree()
+	cleanup.AddPath("/usr/lib/.wh.python", FileInfo{})
+
+	blame, err := BlameDirectory([]*FileTree{base, update, cleanup}, "/usr/lib/")
+	if err != nil {
+		t.Fatalf("Could not blame: %v", err)
+	}
+	expected := []LayerBlame{
+		{Layer: 0, Added: 150, Remaining: 100},
+		{Layer: 1, Added: 30, Overwritten: 60, Remaining: 60},
+		{Layer: 2, Deleted: 30},
+	}
+	if len(blame) != len(expected) {
+		t.Fatalf("Expected %d layers, got %d", len(expected), len(blame))
+	}
+	for idx := range expected {
+		if blame[idx] != expected[idx] {
+			t.Errorf("Expected layer %d blame %+v, got %+v", idx, expected[idx], blame[idx])
+		}
+	}
+
+	if _, err := BlameDirectory([]*FileTree{base}, "/opt"); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("Expected a missing directory to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package filetree
+
+import (
+	"os"
+)
+
+// Change is a single file that a layer adds, removes or changes relative to the layers below it.
+type Change struct {
+	Path     string
+	DiffType DiffType
+	Size     int64
+	Mode     os.FileMode
+	// Attributes are the attributes that changed (only for Changed files)
+	Attributes []AttributeChange
+}
+
+// LayerChanges lists the files (tree leaves) that the given layer adds, removes or changes relative to the layers
+// below it, in path order, with the attributes the file has after the layer. Every file of the first layer is added.
+func LayerChanges(trees []*FileTree, index int) ([]Change, error) {
+	tree := trees[index]
+	if index > 0 {
+		tree = StackRange(trees, 0, index-1)
+		if err := tree.Compare(trees[index]); err != nil {
+			return nil, err
+		}
+	}
+
+	var changes []Change
+	err := tree.ObserveDepthParentFirst(func(node *FileNode) error {
+		diffType := node.Data.DiffType
+		if index == 0 {
+			diffType = Added
+		}
+		if !node.IsLeaf() || node.IsWhiteout() || diffType == Unchanged {
+			return nil
+		}
+		header := node.Data.FileInfo.TarHeader
+		if diffType == Changed {
+			// the stacked tree keeps the attributes from below, while the change is described by the layer itself
+			if upper, err := trees[index].GetNode(node.Path()); err == nil {
+				header = upper.Data.FileInfo.TarHeader
+			}
+		}
+		changes = append(changes, Change{
+			Path:       node.Path(),
+			DiffType:   diffType,
+			Size:       header.Size,
+			Mode:       header.FileInfo().Mode(),
+			Attributes: node.Data.Changes,
+		})
+		return nil
+	}, nil)
+	return changes, err
+}
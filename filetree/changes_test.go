@@ -0,0 +1,50 @@
+package filetree
+
+import (
+	"testing"
+)
+
+func TestLayerChanges(t *testing.T) {
+	base := NewFileTree()
+	base.AddPath("/etc/hosts", fileOfSize("/etc/hosts", 10))
+	base.AddPath("/etc/motd", fileOfSize("/etc/motd", 20))
+	base.AddPath("/tmp/cache", fileOfSize("/tmp/cache", 30))
+
+	upper := NewFileTree()
+	motd := fileOfSize("/etc/motd", 25)
+	motd.MD5sum = [16]byte{1}
+	upper.AddPath("/etc/motd", motd)
+	upper.AddPath("/tmp/.wh.cache", FileInfo{})
+	upper.AddPath("/app/main", fileOfSize("/app/main", 40))
+
+	trees := []*FileTree{base, upper}
+
+	changes, err := LayerChanges(trees, 0)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if len(changes) != 3 || changes[0].DiffType != Added {
+		t.Errorf("Expected every file of the first layer to be added, got %+v", changes)
+	}
+
+	changes, err = LayerChanges(trees, 1)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := []Change{
+		{Path: "/app/main", DiffType: Added, Size: 40},
+		{Path: "/etc/motd", DiffType: Changed, Size: 25},
+		{Path: "/tmp/cache", DiffType: Removed, Size: 30},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %d changes, got %+v", len(expected), changes)
+	}
+	for idx, change := range expected {
+		if changes[idx].Path != change.Path || changes[idx].DiffType != change.DiffType || changes[idx].Size != change.Size {
+			t.Errorf("Expected change %+v, got %+v", change, changes[idx])
+		}
+	}
+	if attributes := changes[1].Attributes; len(attributes) != 1 || attributes[0].String() != "size 20 B→25 B" {
+		t.Errorf("Expected the size change of /etc/motd, got %v", attributes)
+	}
+}
@@ -0,0 +1,69 @@
+package filetree
+
+import (
+	"strings"
+)
+
+// packageDatabaseRoots are the directories in which package managers keep their databases, caches and logs. These
+// change (often in hundreds of files) whenever a package is installed, which buries the changes of interest.
+var packageDatabaseRoots = []string{
+	// dpkg/apt
+	"/var/lib/dpkg",
+	"/var/lib/apt",
+	"/var/cache/apt",
+	"/var/cache/debconf",
+	"/var/log/apt",
+	// rpm/yum/dnf
+	"/var/lib/rpm",
+	"/usr/lib/sysimage/rpm",
+	"/var/lib/yum",
+	"/var/lib/dnf",
+	"/var/cache/yum",
+	"/var/cache/dnf",
+	// apk
+	"/lib/apk/db",
+	"/var/cache/apk",
+}
+
+// IsPackageChurnRoot indicates if the given path is a directory where package managers keep their databases.
+func IsPackageChurnRoot(path string) bool {
+	for _, root := range packageDatabaseRoots {
+		if path == root {
+			return true
+		}
+	}
+	return false
+}
+
+// IsPackageChurn indicates if the given path belongs to a package manager database (is within one of its directories).
+func IsPackageChurn(path string) bool {
+	for _, root := range packageDatabaseRoots {
+		if path == root || strings.HasPrefix(path, root+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// PackageChurnRoots returns the package manager database directories present in the given tree that contain any
+// added, removed or changed files.
+func PackageChurnRoots(tree *FileTree) []*FileNode {
+	var roots []*FileNode
+	for _, root := range packageDatabaseRoots {
+		node, err := tree.GetNode(root)
+		if err != nil {
+			continue
+		}
+		changed := false
+		node.VisitDepthChildFirst(func(child *FileNode) error {
+			if child.Data.DiffType != Unchanged {
+				changed = true
+			}
+			return nil
+		}, nil)
+		if changed {
+			roots = append(roots, node)
+		}
+	}
+	return roots
+}
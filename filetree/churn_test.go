@@ -0,0 +1,48 @@
+package filetree
+
+import (
+	"testing"
+)
+
+func TestIsPackageChurn(t *testing.T) {
+	cases := map[string]bool{
+		"/var/lib/dpkg":                true,
+		"/var/lib/dpkg/status":         true,
+		"/var/lib/dpkg/info/bash.list": true,
+		"/lib/apk/db/installed":        true,
+		"/var/lib/dpkgx":               false,
+		"/usr/bin/dpkg":                false,
+	}
+	for path, expected := range cases {
+		if actual := IsPackageChurn(path); actual != expected {
+			t.Errorf("IsPackageChurn(%q): expected %v, got %v", path, expected, actual)
+		}
+	}
+	if !IsPackageChurnRoot("/var/lib/rpm") || IsPackageChurnRoot("/var/lib/rpm/Packages") {
+		t.Errorf("Expected only the database directory to be a root")
+	}
+}
+
+func TestPackageChurnRoots(t *testing.T) {
+	lower := NewFileTree()
+	lower.AddPath("/var/lib/dpkg/status", fileOfSize("/var/lib/dpkg/status", 10))
+	lower.AddPath("/var/cache/apt/pkgcache.bin", fileOfSize("/var/cache/apt/pkgcache.bin", 10))
+
+	upper := NewFileTree()
+	upper.AddPath("/var/lib/dpkg/status", fileOfSize("/var/lib/dpkg/status", 20))
+	upper.AddPath("/var/lib/dpkg/info/curl.list", fileOfSize("/var/lib/dpkg/info/curl.list", 20))
+
+	upper.Root.Children["var"].Children["lib"].Children["dpkg"].Children["status"].Data.FileInfo.MD5sum = [16]byte{1}
+	if err := lower.Compare(upper); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	roots := PackageChurnRoots(lower)
+	if len(roots) != 1 || roots[0].Path() != "/var/lib/dpkg" {
+		var paths []string
+		for _, root := range roots {
+			paths = append(paths, root.Path())
+		}
+		t.Errorf("Expected only /var/lib/dpkg to have churn, got %v", paths)
+	}
+}
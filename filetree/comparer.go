@@ -0,0 +1,96 @@
+package filetree
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Comparer determines how a file changed between two trees (e.g. a lower and an upper layer), by listing the
+// attributes that differ. A file without changes is Unchanged, any other file is Changed.
+type Comparer interface {
+	Changes(lower, upper FileInfo) []AttributeChange
+}
+
+// MetadataComparer compares files by their type and content checksum (md5), see FileInfo.Compare.
+type MetadataComparer struct{}
+
+// Changes lists every attribute that differs between the given files (see FileInfo.AttributeChanges), when their type
+// or contents differ. A file whose mode or owner changed alone is Unchanged.
+func (MetadataComparer) Changes(lower, upper FileInfo) []AttributeChange {
+	if lower.Compare(upper) == Unchanged {
+		return nil
+	}
+	changes := lower.AttributeChanges(upper)
+	if len(changes) == 0 {
+		// the type flags differ, but name the same kind of file (e.g. an old-style regular file)
+		changes = append(changes, AttributeChange{Attribute: "type"})
+	}
+	return changes
+}
+
+// ContentComparer compares files by their type, content digest (sha256) and link target alone, such that a file that
+// was rewritten with identical contents (e.g. by a chmod or chown, or by reinstalling a package) is Unchanged.
+type ContentComparer struct{}
+
+// Changes lists the type, contents and link target changes between the given files. The content checksum (md5) is
+// compared when the digest of either file is not known (e.g. for a partial analysis, or when AnalyzeSHA256 is not
+// enabled).
+func (ContentComparer) Changes(lower, upper FileInfo) []AttributeChange {
+	digested := lower.SHA256 != [32]byte{} && upper.SHA256 != [32]byte{}
+	var changes []AttributeChange
+	for _, change := range lower.AttributeChanges(upper) {
+		switch change.Attribute {
+		case "type", "link":
+			changes = append(changes, change)
+		case "size", "contents":
+			if !digested || lower.SHA256 != upper.SHA256 {
+				changes = append(changes, change)
+			}
+		}
+	}
+	return changes
+}
+
+// Comparers are the available comparers by name.
+var Comparers = map[string]Comparer{
+	"metadata": MetadataComparer{},
+	"content":  ContentComparer{},
+}
+
+// DefaultComparer is the Comparer of every tree that is not given one.
+var DefaultComparer Comparer = MetadataComparer{}
+
+// SetDefaultComparer selects the DefaultComparer by name (see Comparers), enabling the analysis it relies on.
+func SetDefaultComparer(name string) error {
+	comparer, ok := Comparers[name]
+	if !ok {
+		var names []string
+		for known := range Comparers {
+			names = append(names, known)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("unknown comparison '%s' (expected one of %s)", name, strings.Join(names, ", "))
+	}
+	DefaultComparer = comparer
+	if _, ok := comparer.(ContentComparer); ok {
+		EnableAnalysis(AnalyzeSHA256)
+	}
+	return nil
+}
+
+// comparer is the Comparer of the tree.
+func (tree *FileTree) comparer() Comparer {
+	if tree == nil || tree.Comparer == nil {
+		return DefaultComparer
+	}
+	return tree.Comparer
+}
+
+// compareInfo determines the DiffType between the given files with the given Comparer.
+func compareInfo(comparer Comparer, lower, upper FileInfo) DiffType {
+	if len(comparer.Changes(lower, upper)) == 0 {
+		return Unchanged
+	}
+	return Changed
+}
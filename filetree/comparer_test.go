@@ -0,0 +1,54 @@
+package filetree
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+)
+
+func TestContentComparer(t *testing.T) {
+	withAnalysis(t, AnalyzeSHA256)
+	infoOf := func(path, content string, mode int64, uid int) FileInfo {
+		header := &tar.Header{Name: path, Typeflag: tar.TypeReg, Mode: mode, Uid: uid, Size: int64(len(content))}
+		return fileInfoOf(t, bytes.NewBufferString(content), header, path)
+	}
+
+	lower := NewFileTree()
+	lower.AddPath("/etc/rewritten", infoOf("/etc/rewritten", "same", 0644, 0))
+	lower.AddPath("/etc/modified", infoOf("/etc/modified", "before", 0644, 0))
+	upper := NewFileTree()
+	upper.AddPath("/etc/rewritten", infoOf("/etc/rewritten", "same", 0755, 1000))
+	upper.AddPath("/etc/modified", infoOf("/etc/modified", "after!", 0644, 0))
+
+	compare := func(comparer Comparer) map[string]DiffType {
+		tree := lower.Copy()
+		tree.Comparer = comparer
+		if err := tree.Compare(upper); err != nil {
+			t.Fatalf("Expected no error, got: %v", err)
+		}
+		result := make(map[string]DiffType)
+		for _, path := range []string{"/etc/rewritten", "/etc/modified"} {
+			node, _ := tree.GetNode(path)
+			result[path] = node.Data.DiffType
+		}
+		return result
+	}
+
+	if result := compare(nil); result["/etc/rewritten"] != Unchanged || result["/etc/modified"] != Changed {
+		t.Errorf("Expected type and contents changes to be found by default, got %v", result)
+	}
+	if result := compare(ContentComparer{}); result["/etc/rewritten"] != Unchanged || result["/etc/modified"] != Changed {
+		t.Errorf("Expected only content changes to be found, got %v", result)
+	}
+
+	if err := SetDefaultComparer("bogus"); err == nil {
+		t.Errorf("Expected an unknown comparison to be rejected")
+	}
+
+	enabled := enabledAnalyses
+	defer func() { DefaultComparer, enabledAnalyses = MetadataComparer{}, enabled }()
+	enabledAnalyses = 0
+	if err := SetDefaultComparer("content"); err != nil || enabledAnalyses&AnalyzeSHA256 == 0 {
+		t.Errorf("Expected the content comparison to enable the sha256 digests, got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package filetree
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// CompositionSource is one of the images composed into a single mount view, given by its final (stacked) tree.
+type CompositionSource struct {
+	Name string
+	Tree *FileTree
+}
+
+// Collision is a path provided by more than one source of a composition. Changes lists how each later source differs
+// from the first source providing the path (the changes of the first source are always empty).
+type Collision struct {
+	Path    string
+	Sources []string
+	Changes [][]AttributeChange
+}
+
+// Conflicts indicates if any source provides the path with a different type, contents, mode, owner or link target.
+func (collision Collision) Conflicts() bool {
+	for _, changes := range collision.Changes {
+		if len(changes) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// OwnershipConflict indicates if the sources disagree on the owner (uid:gid) or mode of the path, which breaks access
+// to shared volumes when the containers run as different users.
+func (collision Collision) OwnershipConflict() bool {
+	for _, changes := range collision.Changes {
+		for _, change := range changes {
+			if change.Attribute == "owner" || change.Attribute == "mode" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Composition is the simulated view of the shared paths of several images mounted together (e.g. the containers of a
+// pod sharing volumes), where later sources are stacked on top of earlier ones.
+type Composition struct {
+	Tree *FileTree
+	// Sources lists the sources providing each path of the composed tree, in composition order (the last one wins)
+	Sources    map[string][]string
+	Collisions []Collision
+}
+
+// beneathAny indicates if the given path is one of the given directories or is beneath one of them.
+func beneathAny(nodePath string, dirs []string) bool {
+	for _, dir := range dirs {
+		if dir == "/" || nodePath == dir || strings.HasPrefix(nodePath, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Compose stacks the given paths (e.g. the mount points of shared volumes, the whole filesystem when none are given)
+// of each source on top of each other, attributing every path of the composed view to the sources providing it.
+// Files provided by several sources are reported as collisions, as are directories whose owner or mode differs between
+// sources.
+func Compose(sources []CompositionSource, shared []string) (*Composition, error) {
+	dirs := []string{"/"}
+	if len(shared) > 0 {
+		dirs = make([]string, len(shared))
+		for idx, dir := range shared {
+			dirs[idx] = path.Clean("/" + dir)
+		}
+	}
+
+	composition := &Composition{Tree: NewFileTree(), Sources: make(map[string][]string)}
+	provided := make(map[string][]FileInfo)
+	for _, source := range sources {
+		layer := NewFileTree()
+		err := source.Tree.VisitDepthParentFirst(func(node *FileNode) error {
+			nodePath := node.Path()
+			if node.IsWhiteout() || !beneathAny(nodePath, dirs) {
+				return nil
+			}
+			if _, err := layer.AddPath(nodePath, node.Data.FileInfo); err != nil {
+				return err
+			}
+			composition.Sources[nodePath] = append(composition.Sources[nodePath], source.Name)
+			provided[nodePath] = append(provided[nodePath], node.Data.FileInfo)
+			return nil
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+		if err = composition.Tree.Stack(layer); err != nil {
+			return nil, err
+		}
+	}
+
+	for nodePath, infos := range provided {
+		if len(infos) < 2 {
+			continue
+		}
+		collision := Collision{Path: nodePath, Sources: composition.Sources[nodePath], Changes: make([][]AttributeChange, len(infos))}
+		for idx := 1; idx < len(infos); idx++ {
+			collision.Changes[idx] = infos[0].AttributeChanges(infos[idx])
+		}
+		node, _ := composition.Tree.GetNode(nodePath)
+		isDir := infos[0].TarHeader.FileInfo().IsDir() || node != nil && !node.IsLeaf()
+		if isDir && !collision.OwnershipConflict() {
+			continue
+		}
+		composition.Collisions = append(composition.Collisions, collision)
+	}
+	sort.Slice(composition.Collisions, func(i, j int) bool {
+		return composition.Collisions[i].Path < composition.Collisions[j].Path
+	})
+	return composition, nil
+}
@@ -0,0 +1,63 @@
+package filetree
+
+import (
+	"archive/tar"
+	"testing"
+)
+
+func TestCompose(t *testing.T) {
+	dirOwnedBy := func(path string, uid int) FileInfo {
+		return FileInfo{Path: path, TypeFlag: tar.TypeDir, TarHeader: tar.Header{Name: path, Typeflag: tar.TypeDir, Mode: 0755, Uid: uid, Gid: uid}}
+	}
+
+	app := NewFileTree()
+	app.AddPath("/shared", dirOwnedBy("/shared", 1000))
+	app.AddPath("/shared/config.yaml", fileOfSize("/shared/config.yaml", 10))
+	app.AddPath("/shared/app.sock", fileOfSize("/shared/app.sock", 0))
+	app.AddPath("/etc/hosts", fileOfSize("/etc/hosts", 100))
+
+	sidecar := NewFileTree()
+	sidecar.AddPath("/shared", dirOwnedBy("/shared", 0))
+	sidecar.AddPath("/shared/config.yaml", fileOfSize("/shared/config.yaml", 20))
+	sidecar.AddPath("/shared/app.sock", fileOfSize("/shared/app.sock", 0))
+	sidecar.AddPath("/shared/proxy.yaml", fileOfSize("/shared/proxy.yaml", 5))
+	sidecar.AddPath("/etc/hosts", fileOfSize("/etc/hosts", 200))
+
+	composition, err := Compose([]CompositionSource{{Name: "app", Tree: app}, {Name: "sidecar", Tree: sidecar}}, []string{"shared/"})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if _, err := composition.Tree.GetNode("/etc/hosts"); err == nil {
+		t.Errorf("Expected paths that are not shared to be left out of the composition")
+	}
+	config, err := composition.Tree.GetNode("/shared/config.yaml")
+	if err != nil || config.Data.FileInfo.TarHeader.Size != 20 {
+		t.Errorf("Expected the last source to win a collision, got %+v (%v)", config, err)
+	}
+	if sources := composition.Sources["/shared/proxy.yaml"]; len(sources) != 1 || sources[0] != "sidecar" {
+		t.Errorf("Expected /shared/proxy.yaml to be attributed to the sidecar, got %v", sources)
+	}
+
+	expected := map[string]struct{ conflicts, ownership bool }{
+		"/shared":             {true, true},
+		"/shared/app.sock":    {false, false},
+		"/shared/config.yaml": {true, false},
+	}
+	if len(composition.Collisions) != len(expected) {
+		t.Fatalf("Expected %d collisions, got %+v", len(expected), composition.Collisions)
+	}
+	for _, collision := range composition.Collisions {
+		want, ok := expected[collision.Path]
+		if !ok {
+			t.Errorf("Unexpected collision of %s", collision.Path)
+			continue
+		}
+		if collision.Conflicts() != want.conflicts || collision.OwnershipConflict() != want.ownership {
+			t.Errorf("Unexpected conflicts of %s: %+v", collision.Path, collision.Changes)
+		}
+		if len(collision.Sources) != 2 || collision.Sources[0] != "app" || collision.Sources[1] != "sidecar" {
+			t.Errorf("Expected %s to be attributed to both sources, got %v", collision.Path, collision.Sources)
+		}
+	}
+}
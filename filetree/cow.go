@@ -0,0 +1,104 @@
+package filetree
+
+// Stacked trees (see Stack and StackRange) share the subtrees they did not modify with the trees they were stacked
+// from, rather than copying every node: a node belongs to the tree of its Tree field, any other node reachable from a
+// tree is shared with the tree it belongs to. Shared nodes are never modified; a tree copies a shared node (and the
+// path to it) into itself before modifying it (copy-on-write). Note that trees given to Stack or StackRange must not
+// be modified while the stacked tree is in use. Visitors given to VisitDepthChildFirst and VisitDepthParentFirst may
+// modify any node, so every shared node is copied into the tree first; consumers that only read the tree use
+// ObserveDepthChildFirst, ObserveDepthParentFirst and lookup instead, which keep the nodes shared. A node of a tree
+// that lent its nodes to another tree is not removed by Remove, since the node may have been reached from the other
+// tree; RemovePath removes it from the tree given.
+
+// shallowCopy replaces the (shared) node with a copy belonging to the given parent's tree. The copy shares the
+// children of the node.
+func (node *FileNode) shallowCopy(parent *FileNode) *FileNode {
+	owned := *node
+	owned.Tree = parent.Tree
+	owned.Parent = parent
+	owned.Data.FileInfo = *node.Data.FileInfo.Copy()
+	owned.Children = make(map[string]*FileNode, len(node.Children))
+	for name, child := range node.Children {
+		owned.Children[name] = child
+	}
+	parent.Children[node.Name] = &owned
+	// the kept rows refer to the shared node
+	parent.Tree.rowList = nil
+	if parent.Tree.nodes != nil {
+		parent.Tree.nodes[owned.indexKey()] = &owned
+	}
+	return &owned
+}
+
+// ownChild returns the child of the given name (nil if there is none), first copying it into the tree of this node
+// when it is shared, such that it may be modified.
+func (node *FileNode) ownChild(name string) *FileNode {
+	child := node.Children[name]
+	if child != nil && child.Tree != node.Tree {
+		child = child.shallowCopy(node)
+	}
+	return child
+}
+
+// ownSubtree copies every shared node beneath this node into the tree of this node.
+func (node *FileNode) ownSubtree() {
+	stack := []*FileNode{node}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for name := range current.Children {
+			stack = append(stack, current.ownChild(name))
+		}
+	}
+}
+
+// count returns the number of nodes of the subtree of this node (including this node).
+func (node *FileNode) count() int {
+	var count int
+	stack := []*FileNode{node}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		count++
+		for _, child := range current.Children {
+			stack = append(stack, child)
+		}
+	}
+	return count
+}
+
+// share adds the given node (of another tree) as a child of this node, sharing the whole subtree of the node.
+func (node *FileNode) share(child *FileNode) {
+	node.Children[child.Name] = child
+	node.invalidateSize()
+	node.invalidateRows()
+	node.Tree.Size += child.count()
+	node.Tree.indexSubtree(child)
+	node.Tree.shared = true
+	child.Tree.lent = true
+}
+
+// materialize copies every shared node into the tree, such that any node of the tree may be modified.
+func (tree *FileTree) materialize() {
+	if !tree.shared {
+		return
+	}
+	tree.Root.ownSubtree()
+	tree.shared = false
+}
+
+// shallowCopy returns a copy of the tree sharing every node but the root.
+func (tree *FileTree) shallowCopy() *FileTree {
+	newTree := NewFileTree()
+	newTree.Size = tree.Size
+	newTree.FileSize = tree.FileSize
+	newTree.Comparer = tree.Comparer
+	newTree.Root.Data = tree.Root.Data
+	newTree.Root.Opaque = tree.Root.Opaque
+	for name, child := range tree.Root.Children {
+		newTree.Root.Children[name] = child
+	}
+	newTree.shared = len(tree.Root.Children) > 0
+	tree.lent = tree.lent || newTree.shared
+	return newTree
+}
@@ -0,0 +1,248 @@
+package filetree
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func cowLayers() []*FileTree {
+	lower := NewFileTree()
+	lower.AddPath("/usr/lib/a.so", fileOfSize("/usr/lib/a.so", 10))
+	lower.AddPath("/usr/lib/b.so", fileOfSize("/usr/lib/b.so", 20))
+	lower.AddPath("/etc/hosts", fileOfSize("/etc/hosts", 1))
+
+	upper := NewFileTree()
+	upper.AddPath("/etc/hosts", fileOfSize("/etc/hosts", 2))
+	upper.AddPath("/opt/app/bin", fileOfSize("/opt/app/bin", 100))
+	upper.AddPath("/usr/lib/.wh.a.so", FileInfo{})
+	return []*FileTree{lower, upper}
+}
+
+func TestStackRangeSharesSubtrees(t *testing.T) {
+	trees := cowLayers()
+	stacked := StackRange(trees, 0, 1)
+
+	expected :=
+		`├── etc
+│   └── hosts
+├── opt
+│   └── app
+│       └── bin
+└── usr
+    └── lib
+        └── b.so
+`
+	if actual := stacked.String(false); actual != expected {
+		t.Errorf("Expected tree string:\n--->%s<---\nGot:\n--->%s<---", expected, actual)
+	}
+	if stacked.Size != 8 {
+		t.Errorf("Expected 8 nodes, got %d", stacked.Size)
+	}
+
+	// unmodified subtrees are shared with the layers
+	opt, _ := stacked.lookup("/opt")
+	if original, _ := trees[1].GetNode("/opt"); opt != original {
+		t.Errorf("Expected /opt to be shared with the upper layer")
+	}
+	lib, _ := stacked.lookup("/usr/lib/b.so")
+	if original, _ := trees[0].GetNode("/usr/lib/b.so"); lib != original {
+		t.Errorf("Expected /usr/lib/b.so to be shared with the lower layer")
+	}
+
+	// modifications of the stacked tree never reach the layers
+	stacked.RemovePath("/usr/lib/b.so")
+	bin, _ := stacked.GetNode("/opt/app/bin")
+	bin.AssignDiffType(Removed)
+	stacked.AddPath("/opt/app/lib", fileOfSize("/opt/app/lib", 5))
+	if _, err := trees[0].GetNode("/usr/lib/b.so"); err != nil {
+		t.Errorf("Expected the lower layer to keep /usr/lib/b.so: %v", err)
+	}
+	if original, _ := trees[1].GetNode("/opt/app/bin"); original.Data.DiffType != Unchanged {
+		t.Errorf("Expected the upper layer to keep its diff types, got %v", original.Data.DiffType)
+	}
+	if _, err := trees[1].GetNode("/opt/app/lib"); err == nil {
+		t.Errorf("Expected the upper layer not to gain /opt/app/lib")
+	}
+	if trees[0].Size != 6 || trees[1].Size != 8 {
+		t.Errorf("Expected the layer sizes to be kept, got %d and %d", trees[0].Size, trees[1].Size)
+	}
+	if stacked.Size != 8 {
+		t.Errorf("Expected 8 nodes after the modifications, got %d", stacked.Size)
+	}
+	if actual := stacked.Root.AggregateSize(); actual != 7 {
+		t.Errorf("Expected 7 bytes after the modifications, got %d", actual)
+	}
+}
+
+func TestCompareSharedTree(t *testing.T) {
+	trees := cowLayers()
+	lower := StackRange(trees, 0, 0)
+	if err := lower.Compare(trees[1]); err != nil {
+		t.Fatalf("could not compare: %v", err)
+	}
+
+	expected := map[string]DiffType{
+		"/usr/lib/a.so": Removed,
+		"/usr/lib/b.so": Unchanged,
+		"/etc/hosts":    Changed,
+		"/opt/app/bin":  Added,
+	}
+	for nodePath, diffType := range expected {
+		node, err := lower.GetNode(nodePath)
+		if err != nil || node.Data.DiffType != diffType {
+			t.Errorf("Expected %s to be %v, got %v (%v)", nodePath, diffType, node, err)
+		}
+	}
+	trees[0].VisitDepthChildFirst(func(node *FileNode) error {
+		if node.Data.DiffType != Unchanged {
+			t.Errorf("Expected the layer to be unchanged, got %s %v", node.Path(), node.Data.DiffType)
+		}
+		return nil
+	}, nil)
+}
+
+func TestResolvePathSharedTree(t *testing.T) {
+	lower := NewFileTree()
+	lower.AddPath("/usr/lib/libc.so", fileOfSize("/usr/lib/libc.so", 1))
+	lower.AddPath("/usr/share/doc", FileInfo{})
+	upper := NewFileTree()
+	upper.AddPath("/usr/share/.wh.doc", FileInfo{})
+	upper.AddPath("/usr/share/man", FileInfo{})
+	stacked := StackRange([]*FileTree{lower, upper}, 0, 1)
+
+	// the parent of the shared /usr/lib is the stacked /usr, not the /usr of the lower layer
+	if _, err := stacked.ResolvePath("/usr/lib/../share/doc"); err == nil {
+		t.Errorf("Expected /usr/share/doc to be removed")
+	}
+	if _, err := stacked.ResolvePath("/usr/lib/../share/man"); err != nil {
+		t.Errorf("Expected /usr/share/man to be resolved: %v", err)
+	}
+}
+
+func TestObserveSharedTree(t *testing.T) {
+	trees := cowLayers()
+	stacked := StackRange(trees, 0, 1)
+
+	var paths []string
+	var shared int
+	stacked.ObserveDepthParentFirst(func(node *FileNode) error {
+		paths = append(paths, node.Path())
+		if node.Tree != stacked {
+			shared++
+		}
+		return nil
+	}, nil)
+	expected := []string{"/etc", "/etc/hosts", "/opt", "/opt/app", "/opt/app/bin", "/usr", "/usr/lib", "/usr/lib/b.so"}
+	if strings.Join(paths, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected to visit %v, got %v", expected, paths)
+	}
+	// the visit must not copy the shared nodes into the tree
+	if shared == 0 || !stacked.shared {
+		t.Errorf("Expected the shared nodes to be visited in place (%d shared)", shared)
+	}
+}
+
+// benchmarkLayers builds the given number of layers of the given number of files each, every layer adding files of its
+// own and replacing a few files of the layer below it, such that stacking them shares most subtrees.
+func benchmarkLayers(layers, files int) []*FileTree {
+	trees := make([]*FileTree, layers)
+	for idx := range trees {
+		trees[idx] = NewFileTree()
+		for file := 0; file < files; file++ {
+			path := fmt.Sprintf("/layer%d/dir%d/file%d", idx, file%100, file)
+			trees[idx].AddPath(path, fileOfSize(path, int64(file)))
+		}
+		if idx > 0 {
+			for file := 0; file < files/100; file++ {
+				path := fmt.Sprintf("/layer%d/dir%d/file%d", idx-1, file%100, file)
+				trees[idx].AddPath(path, fileOfSize(path, 1))
+			}
+		}
+	}
+	return trees
+}
+
+func BenchmarkStackRange(b *testing.B) {
+	trees := benchmarkLayers(20, 5000)
+	b.Run("shared", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			StackRange(trees, 0, len(trees)-1)
+		}
+	})
+	// every tree-level visit used to copy the shared nodes into the tree first
+	b.Run("copied", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			StackRange(trees, 0, len(trees)-1).materialize()
+		}
+	})
+}
+
+func BenchmarkLayerChanges(b *testing.B) {
+	trees := benchmarkLayers(20, 5000)
+	for n := 0; n < b.N; n++ {
+		LayerChanges(trees, len(trees)-1)
+	}
+}
+
+func TestOnlyBeneathSharedTree(t *testing.T) {
+	trees := cowLayers()
+	trees[0].AddPath("/opt/app/other", fileOfSize("/opt/app/other", 7))
+	trees[0].AddPath("/opt/tool", fileOfSize("/opt/tool", 3))
+	before := []string{trees[0].String(false), trees[1].String(false)}
+	sizes := []int{trees[0].Size, trees[1].Size}
+
+	stacked := StackRange(trees, 0, 1)
+	stacked.OnlyBeneath("/opt/app")
+
+	expected :=
+		`└── opt
+    └── app
+        ├── bin
+        └── other
+`
+	if actual := stacked.String(false); actual != expected {
+		t.Errorf("Expected tree string:\n--->%s<---\nGot:\n--->%s<---", expected, actual)
+	}
+	if stacked.Size != 4 {
+		t.Errorf("Expected 4 nodes, got %d", stacked.Size)
+	}
+	if actual := stacked.Root.AggregateSize(); actual != 107 {
+		t.Errorf("Expected 107 bytes, got %d", actual)
+	}
+	for idx, tree := range trees {
+		if actual := tree.String(false); actual != before[idx] || tree.Size != sizes[idx] {
+			t.Errorf("Expected layer %d to be unchanged, got %d nodes:\n%s", idx, tree.Size, actual)
+		}
+	}
+}
+
+func TestRemoveSharedNode(t *testing.T) {
+	trees := cowLayers()
+	stacked := StackRange(trees, 0, 1)
+
+	// a node reached through the stacked tree belongs to the layer it was shared from
+	opt := stacked.Root.Children["opt"]
+	if err := opt.Children["app"].Remove(); err == nil {
+		t.Errorf("Expected a node shared with a stacked tree not to be removed")
+	}
+	if _, err := trees[1].GetNode("/opt/app/bin"); err != nil {
+		t.Errorf("Expected the upper layer to keep /opt/app: %v", err)
+	}
+
+	if err := stacked.RemovePath("/opt/app"); err != nil {
+		t.Errorf("Expected the path to be removed from the stacked tree: %v", err)
+	}
+	if _, err := stacked.GetNode("/opt/app"); err == nil {
+		t.Errorf("Expected /opt/app to be removed from the stacked tree")
+	}
+	if _, err := trees[1].GetNode("/opt/app/bin"); err != nil || trees[1].Size != 8 {
+		t.Errorf("Expected the upper layer to be unchanged: %v", err)
+	}
+
+	// FindAll only reads the tree, keeping its nodes shared
+	matches, err := stacked.FindAll("/usr/lib/*")
+	if err != nil || len(matches) != 1 || matches[0].Tree != trees[0] {
+		t.Errorf("Expected the shared node of the lower layer, got %v (%v)", matches, err)
+	}
+}
@@ -4,9 +4,12 @@ import (
 	"archive/tar"
 	"bytes"
 	"crypto/md5"
+	"crypto/sha256"
 	"fmt"
-	"github.com/sirupsen/logrus"
+	"github.com/dustin/go-humanize"
+	"hash"
 	"io"
+	"path"
 )
 
 const (
@@ -21,12 +24,26 @@ type NodeData struct {
 	ViewInfo ViewInfo
 	FileInfo FileInfo
 	DiffType DiffType
+	// Changes are the attributes of the file that changed, when the file itself (not just its children) is Changed
+	Changes []AttributeChange
+}
+
+// AttributeChange is a single attribute of a file that differs between two layers.
+type AttributeChange struct {
+	Attribute string
+	Old       string
+	New       string
 }
 
 // ViewInfo contains UI specific detail for a specific FileNode
 type ViewInfo struct {
 	Collapsed bool
 	Hidden    bool
+	Badges    []Badge
+	// Page is the page of children shown for a directory with more children than fit a page (see Paginate)
+	Page int
+	// PagedChildren is the number of visible children of a directory that is paginated (0 when it is not)
+	PagedChildren int
 }
 
 // FileInfo contains tar metadata for a specific FileNode
@@ -34,12 +51,45 @@ type FileInfo struct {
 	Path      string
 	TypeFlag  byte
 	MD5sum    [16]byte
+	SHA256    [32]byte
 	TarHeader tar.Header
+	// Xattrs are the extended attributes of the file by name (e.g. "security.capability" or "user.mime_type"), holding
+	// the raw values (see XattrsString, Capabilities and SELinuxLabel)
+	Xattrs   map[string]string
+	Licenses []string
+	Entropy  float64
+	// AccountNames are the names by numeric ID declared by an account database (/etc/passwd or /etc/group)
+	AccountNames map[int]string
+	// Tags are the findings analyzers attached to the file, by key (e.g. "secret"), see SetTag
+	Tags map[string]string
+	// LinkID identifies the inode of the file within its layer: the path of the file, or for a hard link the path of
+	// the file it links to, such that every hard link to the same file shares a LinkID
+	LinkID string
 }
 
 // DiffType defines the comparison result between two FileNodes
 type DiffType int
 
+// Analysis is an analysis of the contents of files beyond the md5 checksum, which is only done when it is needed (see
+// EnableAnalysis), since it costs time for every file read.
+type Analysis int
+
+const (
+	// AnalyzeSHA256 computes the sha256 digest of the contents (FileInfo.SHA256)
+	AnalyzeSHA256 Analysis = 1 << iota
+	// AnalyzeEntropy computes the entropy of the contents (FileInfo.Entropy)
+	AnalyzeEntropy
+)
+
+// enabledAnalyses are the analyses done for every file read (see EnableAnalysis).
+var enabledAnalyses Analysis
+
+// EnableAnalysis adds the given analyses of the contents of every file read from then on (see NewFileInfo). It is meant
+// to be called before any layer is read, by the commands that use the results.
+func EnableAnalysis(analyses Analysis) {
+	enabledAnalyses |= analyses
+}
+
 // NewNodeData creates an empty NodeData struct for a FileNode
 func NewNodeData() *NodeData {
 	return &NodeData{
@@ -55,6 +105,7 @@ func (data *NodeData) Copy() *NodeData {
 		ViewInfo: *data.ViewInfo.Copy(),
 		FileInfo: *data.FileInfo.Copy(),
 		DiffType: data.DiffType,
+		Changes:  append([]AttributeChange(nil), data.Changes...),
 	}
 }
 
@@ -73,28 +124,89 @@ func (view *ViewInfo) Copy() (newView *ViewInfo) {
 	return newView
 }
 
-// NewFileInfo extracts the metadata from a tar header and file contents and generates a new FileInfo object.
-func NewFileInfo(reader *tar.Reader, header *tar.Header, path string) FileInfo {
+// NewFileInfo extracts the metadata from a tar header and file contents (e.g. the tar reader positioned at the file)
+// and generates a new FileInfo object. The sha256 digest and the entropy of the contents are only computed when
+// enabled (see EnableAnalysis). An error is returned when the contents cannot be read (e.g. a truncated layer).
+func NewFileInfo(reader io.Reader, header *tar.Header, path string) (FileInfo, error) {
+	info := NewFileInfoFromHeader(header, path)
 	if header.Typeflag == tar.TypeDir {
-		return FileInfo{
-			Path:      path,
-			TypeFlag:  header.Typeflag,
-			MD5sum:    [16]byte{},
-			TarHeader: *header,
-		}
+		return info, nil
+	}
+	// stream the contents (rather than reading the whole file into memory), only keeping license files
+	checksum := md5.New()
+	writers := []io.Writer{checksum}
+	var digest hash.Hash
+	if enabledAnalyses&AnalyzeSHA256 != 0 {
+		digest = sha256.New()
+		writers = append(writers, digest)
 	}
-	fileBytes := make([]byte, header.Size)
-	_, err := reader.Read(fileBytes)
-	if err != nil && err != io.EOF {
-		logrus.Panic(err)
+	var histogram *byteHistogram
+	if enabledAnalyses&AnalyzeEntropy != 0 {
+		histogram = &byteHistogram{}
+		writers = append(writers, histogram)
+	}
+	var license, accountDB bytes.Buffer
+	if isLicenseFile(path) {
+		writers = append(writers, &license)
+	} else if isAccountFile(path) {
+		writers = append(writers, &accountDB)
+	}
+	if _, err := io.Copy(io.MultiWriter(writers...), reader); err != nil {
+		return info, fmt.Errorf("could not read %s: %v", path, err)
+	}
+	copy(info.MD5sum[:], checksum.Sum(nil))
+	if digest != nil {
+		copy(info.SHA256[:], digest.Sum(nil))
+	}
+	if histogram != nil {
+		info.Entropy = histogram.entropy()
+	}
+	if isLicenseFile(path) {
+		info.Licenses = detectLicenses(license.Bytes())
+	} else if isAccountFile(path) {
+		info.AccountNames = parseAccounts(accountDB.Bytes())
 	}
 
-	return FileInfo{
+	return info, nil
+}
+
+// NewFileInfoFromHeader generates a new FileInfo object from the metadata of a tar header alone, without reading the
+// file contents (so the content digest, entropy and licenses are not known).
+func NewFileInfoFromHeader(header *tar.Header, path string) FileInfo {
+	info := FileInfo{
 		Path:      path,
 		TypeFlag:  header.Typeflag,
-		MD5sum:    md5.Sum(fileBytes),
+		MD5sum:    [16]byte{},
 		TarHeader: *header,
+		LinkID:    linkID(header, path),
+	}
+	if attributes := xattrs(header); len(attributes) > 0 {
+		info.Xattrs = attributes
+	}
+	return info
+}
+
+// linkID determines the inode identity of the file with the given header and path (see FileInfo.LinkID).
+func linkID(header *tar.Header, filePath string) string {
+	if header.Typeflag == tar.TypeLink {
+		return path.Clean("/" + header.Linkname)
 	}
+	return path.Clean("/" + filePath)
+}
+
+// Size is the number of bytes the file adds to the layer. The bytes of an inode are counted once, for the file its
+// LinkID names: a hard link shares the bytes of the file it links to, which are counted for that file only (some tar
+// writers record the size of the linked file in the hard link header).
+func (data *FileInfo) Size() int64 {
+	id := data.LinkID
+	if id == "" {
+		// the identity was not noted (e.g. a FileInfo that was not read from a layer)
+		id = linkID(&data.TarHeader, data.Path)
+	}
+	if id != path.Clean("/"+data.Path) {
+		return 0
+	}
+	return data.TarHeader.FileInfo().Size()
 }
 
 // Copy duplicates a FileInfo
@@ -103,14 +215,22 @@ func (data *FileInfo) Copy() *FileInfo {
 		return nil
 	}
 	return &FileInfo{
-		Path:      data.Path,
-		TypeFlag:  data.TypeFlag,
-		MD5sum:    data.MD5sum,
-		TarHeader: data.TarHeader,
+		Path:         data.Path,
+		TypeFlag:     data.TypeFlag,
+		MD5sum:       data.MD5sum,
+		SHA256:       data.SHA256,
+		TarHeader:    data.TarHeader,
+		Xattrs:       copyXattrs(data.Xattrs),
+		Licenses:     append([]string(nil), data.Licenses...),
+		Entropy:      data.Entropy,
+		AccountNames: data.AccountNames,
+		Tags:         copyTags(data.Tags),
+		LinkID:       data.LinkID,
 	}
 }
 
-// Compare determines the DiffType between two FileInfos based on the type and contents of each given FileInfo
+// Compare determines the DiffType between two FileInfos based on the type and contents of each given FileInfo. The
+// other attributes (see AttributeChanges) only describe how a Changed file changed.
 func (data *FileInfo) Compare(other FileInfo) DiffType {
 	if data.TypeFlag == other.TypeFlag {
 		if bytes.Compare(data.MD5sum[:], other.MD5sum[:]) == 0 {
@@ -120,6 +240,64 @@ func (data *FileInfo) Compare(other FileInfo) DiffType {
 	return Changed
 }
 
+// String shows the change of an attribute (e.g. "mode 0644→0755").
+func (change AttributeChange) String() string {
+	if change.Old == "" && change.New == "" {
+		return change.Attribute
+	}
+	return fmt.Sprintf("%s %s→%s", change.Attribute, change.Old, change.New)
+}
+
+// typeNames name the kinds of files a tar can hold.
+var typeNames = map[byte]string{
+	tar.TypeReg:     "file",
+	tar.TypeRegA:    "file",
+	tar.TypeDir:     "dir",
+	tar.TypeSymlink: "symlink",
+	tar.TypeLink:    "hardlink",
+	tar.TypeChar:    "char device",
+	tar.TypeBlock:   "block device",
+	tar.TypeFifo:    "fifo",
+}
+
+func typeName(flag byte) string {
+	if name, ok := typeNames[flag]; ok {
+		return name
+	}
+	return fmt.Sprintf("type %q", flag)
+}
+
+// AttributeChanges lists the attributes (type, size, contents, mode, owner, link target, capabilities and xattrs) that
+// differ in the given (newer) FileInfo.
+func (data *FileInfo) AttributeChanges(other FileInfo) []AttributeChange {
+	var changes []AttributeChange
+	before, after := data.TarHeader, other.TarHeader
+	if typeName(data.TypeFlag) != typeName(other.TypeFlag) {
+		changes = append(changes, AttributeChange{Attribute: "type", Old: typeName(data.TypeFlag), New: typeName(other.TypeFlag)})
+	}
+	if before.Size != after.Size {
+		changes = append(changes, AttributeChange{Attribute: "size", Old: humanize.Bytes(uint64(before.Size)), New: humanize.Bytes(uint64(after.Size))})
+	} else if !bytes.Equal(data.MD5sum[:], other.MD5sum[:]) {
+		changes = append(changes, AttributeChange{Attribute: "contents"})
+	}
+	if before.Mode&07777 != after.Mode&07777 {
+		changes = append(changes, AttributeChange{Attribute: "mode", Old: fmt.Sprintf("%04o", before.Mode&07777), New: fmt.Sprintf("%04o", after.Mode&07777)})
+	}
+	if before.Uid != after.Uid || before.Gid != after.Gid {
+		changes = append(changes, AttributeChange{Attribute: "owner", Old: fmt.Sprintf("%d:%d", before.Uid, before.Gid), New: fmt.Sprintf("%d:%d", after.Uid, after.Gid)})
+	}
+	if before.Linkname != after.Linkname {
+		changes = append(changes, AttributeChange{Attribute: "link", Old: before.Linkname, New: after.Linkname})
+	}
+	if data.Capabilities() != other.Capabilities() {
+		changes = append(changes, AttributeChange{Attribute: "capabilities", Old: data.Capabilities(), New: other.Capabilities()})
+	}
+	if !sameXattrs(data.Xattrs, other.Xattrs) {
+		changes = append(changes, AttributeChange{Attribute: "xattrs", Old: data.XattrsString(), New: other.XattrsString()})
+	}
+	return changes
+}
+
 // String of a DiffType
 func (diff DiffType) String() string {
 	switch diff {
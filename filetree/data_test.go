@@ -1,9 +1,63 @@
 package filetree
 
 import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"strings"
 	"testing"
 )
 
+// fileInfoOf reads the FileInfo of a file from its contents, failing the test when they cannot be read.
+func fileInfoOf(t *testing.T, reader io.Reader, header *tar.Header, path string) FileInfo {
+	t.Helper()
+	info, err := NewFileInfo(reader, header, path)
+	if err != nil {
+		t.Fatalf("could not read %s: %v", path, err)
+	}
+	return info
+}
+
+// withAnalysis enables the given analyses of file contents for the rest of the test.
+func withAnalysis(t *testing.T, analyses Analysis) {
+	t.Helper()
+	enabled := enabledAnalyses
+	EnableAnalysis(analyses)
+	t.Cleanup(func() { enabledAnalyses = enabled })
+}
+
+func TestNewFileInfoAnalysis(t *testing.T) {
+	contents := "127.0.0.1 localhost\n"
+	header := &tar.Header{Name: "etc/hosts", Typeflag: tar.TypeReg, Size: int64(len(contents))}
+
+	info := fileInfoOf(t, strings.NewReader(contents), header, header.Name)
+	if info.MD5sum == [16]byte{} || info.SHA256 != [32]byte{} || info.Entropy != 0 {
+		t.Errorf("Expected only the md5 checksum by default, got %x %x %v", info.MD5sum, info.SHA256, info.Entropy)
+	}
+
+	withAnalysis(t, AnalyzeSHA256|AnalyzeEntropy)
+	info = fileInfoOf(t, strings.NewReader(contents), header, header.Name)
+	if info.SHA256 != sha256.Sum256([]byte(contents)) || info.Entropy != entropy([]byte(contents)) {
+		t.Errorf("Expected the digest and entropy to be computed, got %x %v", info.SHA256, info.Entropy)
+	}
+}
+
+func TestNewFileInfoTruncated(t *testing.T) {
+	header := &tar.Header{Name: "etc/hosts", Typeflag: tar.TypeReg, Size: 100}
+	_, err := NewFileInfo(io.MultiReader(bytes.NewReader([]byte("127.0.0.1")), failingReader{}), header, header.Name)
+	if err == nil || !strings.Contains(err.Error(), "etc/hosts") {
+		t.Errorf("Expected an error naming the file, got %v", err)
+	}
+}
+
+// failingReader fails every read, as an interrupted download does.
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, io.ErrUnexpectedEOF
+}
+
 func TestAssignDiffType(t *testing.T) {
 	tree := NewFileTree()
 	node, err := tree.AddPath("/usr", *BlankFileChangeInfo("/usr"))
@@ -39,3 +93,47 @@ func BlankFileChangeInfo(path string) (f *FileInfo) {
 	}
 	return &result
 }
+
+func TestAttributeChanges(t *testing.T) {
+	before := fileOfSize("/app/run.sh", 1200)
+	before.TarHeader.Mode = 0644
+
+	same := before
+	if changes := before.AttributeChanges(same); len(changes) != 0 || before.Compare(same) != Unchanged {
+		t.Errorf("Expected no changes, got %v", changes)
+	}
+
+	after := before
+	after.TarHeader.Size = 3400
+	after.TarHeader.Mode = 0755
+	after.TarHeader.Uid = 1000
+	after.TarHeader.Gid = 1000
+	expected := []string{"size 1.2 kB→3.4 kB", "mode 0644→0755", "owner 0:0→1000:1000"}
+	changes := before.AttributeChanges(after)
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, changes)
+	}
+	for idx, change := range changes {
+		if change.String() != expected[idx] {
+			t.Errorf("Expected %q, got %q", expected[idx], change.String())
+		}
+	}
+
+	// a file is classified by its type and contents alone, so a permission change alone leaves it unchanged
+	chmodded := before
+	chmodded.TarHeader.Mode = 0755
+	if before.Compare(chmodded) != Unchanged || len(MetadataComparer{}.Changes(before, chmodded)) != 0 {
+		t.Errorf("Expected a mode change alone not to mark the file as changed")
+	}
+
+	edited := chmodded
+	edited.MD5sum = [16]byte{1}
+	if before.Compare(edited) != Changed {
+		t.Errorf("Expected a contents change to mark the file as changed")
+	}
+	expected = []string{"contents", "mode 0644→0755"}
+	changes = MetadataComparer{}.Changes(before, edited)
+	if len(changes) != len(expected) || changes[0].String() != expected[0] || changes[1].String() != expected[1] {
+		t.Errorf("Expected %v for a changed file, got %v", expected, changes)
+	}
+}
@@ -0,0 +1,37 @@
+package filetree
+
+// DiffStat holds the number of files (and their bytes) with a single DiffType.
+type DiffStat struct {
+	Count int
+	Size  uint64
+}
+
+// DiffStats holds the DiffStat of every DiffType (Added, Removed, Changed and Unchanged).
+type DiffStats map[DiffType]DiffStat
+
+// DiffStats counts the files (not directories or whiteouts) of the compared tree (see Compare and CompareTree) by
+// DiffType, along with their bytes. The count is restricted to the subtree at the given path, when one is given (an
+// empty path or "/" counts the whole tree). The tree is not modified, so shared nodes are counted in place.
+func (tree *FileTree) DiffStats(path string) (DiffStats, error) {
+	stats := DiffStats{Unchanged: {}, Changed: {}, Added: {}, Removed: {}}
+
+	start := tree.Root
+	if path != "" && path != "/" {
+		node, err := tree.lookup(path)
+		if err != nil {
+			return nil, err
+		}
+		start = node
+	}
+
+	visitKeyed(start, "", func(node *FileNode, _ string) {
+		if node == tree.Root || !node.IsLeaf() || node.IsWhiteout() || node.Data.FileInfo.TarHeader.FileInfo().IsDir() {
+			return
+		}
+		stat := stats[node.Data.DiffType]
+		stat.Count++
+		stat.Size += uint64(node.Data.FileInfo.TarHeader.FileInfo().Size())
+		stats[node.Data.DiffType] = stat
+	})
+	return stats, nil
+}
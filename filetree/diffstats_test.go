@@ -0,0 +1,58 @@
+package filetree
+
+import (
+	"archive/tar"
+	"testing"
+)
+
+func TestDiffStats(t *testing.T) {
+	lowerTree := NewFileTree()
+	upperTree := NewFileTree()
+	file := func(path string, size int64, md5sum byte) FileInfo {
+		return FileInfo{
+			Path:      path,
+			TypeFlag:  tar.TypeReg,
+			MD5sum:    [16]byte{md5sum},
+			TarHeader: tar.Header{Name: path, Typeflag: tar.TypeReg, Size: size},
+		}
+	}
+	lowerTree.AddPath("/etc/hosts", file("/etc/hosts", 10, 0))
+	lowerTree.AddPath("/etc/sudoers", file("/etc/sudoers", 20, 0))
+	lowerTree.AddPath("/usr/bin/bash", file("/usr/bin/bash", 300, 0))
+	upperTree.AddPath("/etc/hosts", file("/etc/hosts", 10, 0))
+	upperTree.AddPath("/etc/sudoers", file("/etc/sudoers", 25, 1))
+	upperTree.AddPath("/usr/bin/zsh", file("/usr/bin/zsh", 400, 0))
+	upperTree.AddPath("/opt/app/run", file("/opt/app/run", 50, 0))
+
+	if err := lowerTree.CompareTree(upperTree); err != nil {
+		t.Fatalf("Expected no error comparing the trees, got %v", err)
+	}
+
+	stats, err := lowerTree.DiffStats("")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := DiffStats{
+		Unchanged: {Count: 1, Size: 10},
+		Changed:   {Count: 1, Size: 20},
+		Added:     {Count: 2, Size: 450},
+		Removed:   {Count: 1, Size: 300},
+	}
+	for diffType, stat := range expected {
+		if stats[diffType] != stat {
+			t.Errorf("Expected %v %s files, got %v", stat, diffType, stats[diffType])
+		}
+	}
+
+	stats, err = lowerTree.DiffStats("/usr")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if stats[Added] != (DiffStat{Count: 1, Size: 400}) || stats[Removed] != (DiffStat{Count: 1, Size: 300}) || stats[Changed].Count != 0 {
+		t.Errorf("Expected only the changes beneath /usr, got %v", stats)
+	}
+
+	if _, err := lowerTree.DiffStats("/missing"); err == nil {
+		t.Errorf("Expected an error for a missing path")
+	}
+}
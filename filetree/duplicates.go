@@ -0,0 +1,76 @@
+package filetree
+
+import (
+	"archive/tar"
+	"encoding/hex"
+	"sort"
+)
+
+// StoredCopy is a file as stored by a single layer (counting from the oldest layer).
+type StoredCopy struct {
+	Layer int
+	Path  string
+}
+
+// Duplicate is file content (identified by its sha256 digest) that is stored more than once across the layers of an
+// image, either by several layers or at several paths.
+type Duplicate struct {
+	Digest string
+	Size   int64
+	// Copies are the stored copies of the content, in layer and then path order
+	Copies []StoredCopy
+}
+
+// ReclaimableSize is the number of bytes that would be saved by storing the content only once.
+func (duplicate Duplicate) ReclaimableSize() int64 {
+	return duplicate.Size * int64(len(duplicate.Copies)-1)
+}
+
+// Duplicates groups the regular files of all the given layers by their content digest and returns the content that is
+// stored more than once, ordered by the reclaimable size (largest first). Empty files, and files whose contents were
+// not read (see NewFileInfoFromHeader), are not considered.
+func Duplicates(trees []*FileTree) []Duplicate {
+	var zero [32]byte
+	byDigest := make(map[[32]byte]*Duplicate)
+	for idx, tree := range trees {
+		tree.VisitDepthParentFirst(func(node *FileNode) error {
+			info := node.Data.FileInfo
+			if node.IsWhiteout() || info.SHA256 == zero || info.TarHeader.Size == 0 {
+				return nil
+			}
+			if info.TarHeader.Typeflag != tar.TypeReg && info.TarHeader.Typeflag != tar.TypeRegA {
+				return nil
+			}
+			duplicate, ok := byDigest[info.SHA256]
+			if !ok {
+				duplicate = &Duplicate{Digest: "sha256:" + hex.EncodeToString(info.SHA256[:]), Size: info.TarHeader.Size}
+				byDigest[info.SHA256] = duplicate
+			}
+			duplicate.Copies = append(duplicate.Copies, StoredCopy{Layer: idx, Path: node.Path()})
+			return nil
+		}, nil)
+	}
+
+	result := make([]Duplicate, 0)
+	for _, duplicate := range byDigest {
+		if len(duplicate.Copies) > 1 {
+			result = append(result, *duplicate)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ReclaimableSize() != result[j].ReclaimableSize() {
+			return result[i].ReclaimableSize() > result[j].ReclaimableSize()
+		}
+		return result[i].Digest < result[j].Digest
+	})
+	return result
+}
+
+// ReclaimableSize is the total number of bytes that would be saved by storing the content of each duplicate once.
+func ReclaimableSize(duplicates []Duplicate) int64 {
+	var total int64
+	for _, duplicate := range duplicates {
+		total += duplicate.ReclaimableSize()
+	}
+	return total
+}
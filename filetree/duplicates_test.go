@@ -0,0 +1,45 @@
+package filetree
+
+import (
+	"crypto/sha256"
+	"testing"
+)
+
+func fileWithContents(path, contents string) FileInfo {
+	info := fileOfSize(path, int64(len(contents)))
+	info.SHA256 = sha256.Sum256([]byte(contents))
+	return info
+}
+
+func TestDuplicates(t *testing.T) {
+	first := NewFileTree()
+	first.AddPath("/usr/lib/libbig.so", fileWithContents("/usr/lib/libbig.so", "a large shared library"))
+	first.AddPath("/etc/motd", fileWithContents("/etc/motd", "hi"))
+	first.AddPath("/etc/empty", fileWithContents("/etc/empty", ""))
+
+	second := NewFileTree()
+	second.AddPath("/usr/lib/libbig.so", fileWithContents("/usr/lib/libbig.so", "a large shared library"))
+	second.AddPath("/opt/app/libbig.so", fileWithContents("/opt/app/libbig.so", "a large shared library"))
+	second.AddPath("/etc/issue", fileWithContents("/etc/issue", "hi"))
+	second.AddPath("/etc/other", fileWithContents("/etc/other", "ho"))
+	second.AddPath("/etc/empty2", fileWithContents("/etc/empty2", ""))
+
+	result := Duplicates([]*FileTree{first, second})
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 duplicates, got %+v", result)
+	}
+
+	library := result[0]
+	if library.Size != 22 || len(library.Copies) != 3 || library.ReclaimableSize() != 44 {
+		t.Errorf("Expected the library to be stored 3 times, got %+v", library)
+	}
+	if library.Copies[0] != (StoredCopy{Layer: 0, Path: "/usr/lib/libbig.so"}) || library.Copies[1].Layer != 1 {
+		t.Errorf("Expected the copies in layer order, got %+v", library.Copies)
+	}
+	if result[1].Copies[0].Path != "/etc/motd" || result[1].Copies[1].Path != "/etc/issue" {
+		t.Errorf("Expected the motd to be duplicated at another path, got %+v", result[1])
+	}
+	if total := ReclaimableSize(result); total != 46 {
+		t.Errorf("Expected 46 reclaimable bytes, got %d", total)
+	}
+}
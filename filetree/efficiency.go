@@ -8,8 +8,12 @@ import (
 
 // EfficiencyData represents the storage and reference statistics for a given file tree path.
 type EfficiencyData struct {
-	Path              string
-	Nodes             []*FileNode
+	Path  string
+	Nodes []*FileNode
+	// Layers and Sizes are the layer (counting from the oldest layer) of each of the Nodes, and the bytes it stores
+	// (or, for whiteouts, removes)
+	Layers            []int
+	Sizes             []int64
 	CumulativeSize    int64
 	minDiscoveredSize int64
 }
@@ -40,8 +44,17 @@ func Efficiency(trees []*FileTree) (float64, EfficiencySlice) {
 	inefficientMatches := make(EfficiencySlice, 0)
 	currentTree := 0
 
-	visitor := func(node *FileNode) error {
-		path := node.Path()
+	// the stacked tree of the layers below the current layer, stacked once per layer (when needed)
+	var lowerTree *FileTree
+	lowerTreeOf := func() *FileTree {
+		if lowerTree == nil {
+			lowerTree = StackRange(trees, 0, currentTree-1)
+		}
+		return lowerTree
+	}
+
+	// record notes that the given node stores (or, for whiteouts, removes) the given number of bytes at the given path
+	record := func(path string, node *FileNode, sizeBytes int64) {
 		if _, ok := efficiencyMap[path]; !ok {
 			efficiencyMap[path] = &EfficiencyData{
 				Path:              path,
@@ -50,7 +63,20 @@ func Efficiency(trees []*FileTree) (float64, EfficiencySlice) {
 			}
 		}
 		data := efficiencyMap[path]
+		data.CumulativeSize += sizeBytes
+		if data.minDiscoveredSize < 0 || sizeBytes < data.minDiscoveredSize {
+			data.minDiscoveredSize = sizeBytes
+		}
+		data.Nodes = append(data.Nodes, node)
+		data.Layers = append(data.Layers, currentTree)
+		data.Sizes = append(data.Sizes, sizeBytes)
+
+		if len(data.Nodes) == 2 {
+			inefficientMatches = append(inefficientMatches, data)
+		}
+	}
 
+	visitor := func(node *FileNode) error {
 		// this node may have had children that were deleted, however, we won't explicitly list out every child, only
 		// the top-most parent with the cumulative size. These operations will need to be done on the full (stacked)
 		// tree.
@@ -59,11 +85,10 @@ func Efficiency(trees []*FileTree) (float64, EfficiencySlice) {
 
 		if node.IsWhiteout() {
 			sizer := func(curNode *FileNode) error {
-				sizeBytes += curNode.Data.FileInfo.TarHeader.FileInfo().Size()
+				sizeBytes += curNode.Data.FileInfo.Size()
 				return nil
 			}
-			stackedTree := StackRange(trees, 0, currentTree-1)
-			previousTreeNode, err := stackedTree.GetNode(node.Path())
+			previousTreeNode, err := lowerTreeOf().lookup(node.Path())
 			if err != nil {
 				logrus.Debug(fmt.Sprintf("CurrentTree: %d : %s", currentTree, err))
 			} else if previousTreeNode.Data.FileInfo.TarHeader.FileInfo().IsDir() {
@@ -71,26 +96,42 @@ func Efficiency(trees []*FileTree) (float64, EfficiencySlice) {
 			}
 
 		} else {
-			sizeBytes = node.Data.FileInfo.TarHeader.FileInfo().Size()
+			sizeBytes = node.Data.FileInfo.Size()
 		}
 
-		data.CumulativeSize += sizeBytes
-		if data.minDiscoveredSize < 0 || sizeBytes < data.minDiscoveredSize {
-			data.minDiscoveredSize = sizeBytes
-		}
-		data.Nodes = append(data.Nodes, node)
+		record(node.Path(), node, sizeBytes)
+		return nil
+	}
 
-		if len(data.Nodes) == 2 {
-			inefficientMatches = append(inefficientMatches, data)
+	// files hidden by an opaque directory (and not provided again by the same layer) are removed like whiteouts, the
+	// opaque directory standing in for the whiteout node
+	opaqueVisitor := func(node *FileNode) error {
+		if !node.Opaque || currentTree == 0 {
+			return nil
 		}
-
-		return nil
+		previousTreeNode, err := lowerTreeOf().lookup(node.Path())
+		if err != nil {
+			return nil
+		}
+		layer := trees[currentTree]
+		return previousTreeNode.VisitDepthChildFirst(func(curNode *FileNode) error {
+			if curNode == previousTreeNode || !curNode.IsLeaf() {
+				return nil
+			}
+			if _, err := layer.GetNode(curNode.Path()); err == nil {
+				return nil
+			}
+			record(curNode.Path(), node, curNode.Data.FileInfo.Size())
+			return nil
+		}, nil)
 	}
 	visitEvaluator := func(node *FileNode) bool {
 		return node.IsLeaf()
 	}
 	for idx, tree := range trees {
 		currentTree = idx
+		lowerTree = nil
+		tree.VisitDepthParentFirst(opaqueVisitor, nil)
 		tree.VisitDepthChildFirst(visitor, visitEvaluator)
 	}
 
@@ -0,0 +1,91 @@
+package filetree
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	// HighEntropyThreshold is the entropy (in bits per byte) above which content is considered already compressed or
+	// encrypted. Text and executables are typically well below 7, compressed archives and ciphertext close to 8.
+	HighEntropyThreshold = 7.5
+	// entropyMinSize is the smallest file considered when looking for high entropy content, since the entropy of
+	// small files is not meaningful (and small files do not bloat layers).
+	entropyMinSize = 4 * 1024
+)
+
+// EntropyFinding is a file with high entropy content, as added by a layer.
+type EntropyFinding struct {
+	Path    string
+	Layer   int
+	Size    int64
+	Entropy float64
+}
+
+// byteHistogram counts the occurrences of each byte value written to it, such that the entropy of content can be
+// determined while it is streamed.
+type byteHistogram struct {
+	counts [256]int64
+	total  int64
+}
+
+func (histogram *byteHistogram) Write(data []byte) (int, error) {
+	for _, value := range data {
+		histogram.counts[value]++
+	}
+	histogram.total += int64(len(data))
+	return len(data), nil
+}
+
+// entropy returns the shannon entropy of the counted bytes in bits per byte, from 0 (a single repeated byte) to 8
+// (uniformly random bytes).
+func (histogram *byteHistogram) entropy() float64 {
+	if histogram.total == 0 {
+		return 0
+	}
+	var result float64
+	total := float64(histogram.total)
+	for _, count := range histogram.counts {
+		if count == 0 {
+			continue
+		}
+		probability := float64(count) / total
+		result -= probability * math.Log2(probability)
+	}
+	return result
+}
+
+// entropy returns the shannon entropy of the given bytes in bits per byte.
+func entropy(data []byte) float64 {
+	var histogram byteHistogram
+	histogram.Write(data)
+	return histogram.entropy()
+}
+
+// HighEntropyFiles lists every file added by any of the given layers whose content has at least the given entropy,
+// largest first.
+func HighEntropyFiles(trees []*FileTree, threshold float64) []EntropyFinding {
+	var findings []EntropyFinding
+	for idx, tree := range trees {
+		tree.VisitDepthParentFirst(func(node *FileNode) error {
+			info := node.Data.FileInfo
+			size := info.TarHeader.FileInfo().Size()
+			if node.IsWhiteout() || info.TarHeader.FileInfo().IsDir() || size < entropyMinSize {
+				return nil
+			}
+			if info.Entropy >= threshold {
+				findings = append(findings, EntropyFinding{
+					Path:    node.Path(),
+					Layer:   idx,
+					Size:    size,
+					Entropy: info.Entropy,
+				})
+			}
+			return nil
+		}, nil)
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Size > findings[j].Size
+	})
+	return findings
+}
@@ -0,0 +1,57 @@
+package filetree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestEntropy(t *testing.T) {
+	if actual := entropy(make([]byte, 1024)); actual != 0 {
+		t.Errorf("Expected no entropy for repeated bytes, got %f", actual)
+	}
+
+	counting := make([]byte, 256*16)
+	for idx := range counting {
+		counting[idx] = byte(idx)
+	}
+	if actual := entropy(counting); actual != 8 {
+		t.Errorf("Expected an entropy of 8 for uniformly distributed bytes, got %f", actual)
+	}
+
+	text := []byte("the quick brown fox jumps over the lazy dog, again and again and again")
+	if actual := entropy(text); actual > 5 {
+		t.Errorf("Expected a low entropy for text, got %f", actual)
+	}
+}
+
+func TestHighEntropyFiles(t *testing.T) {
+	random := make([]byte, 64*1024)
+	rand.New(rand.NewSource(42)).Read(random)
+
+	blob := fileOfSize("/app/model.bin", int64(len(random)))
+	blob.Entropy = entropy(random)
+	archive := fileOfSize("/app/vendor.tar.gz", 8*1024)
+	archive.Entropy = 7.9
+	small := fileOfSize("/app/key", 256)
+	small.Entropy = 7.9
+	text := fileOfSize("/app/main.js", 64*1024)
+	text.Entropy = 4.5
+
+	lower := NewFileTree()
+	lower.AddPath(archive.Path, archive)
+	lower.AddPath(text.Path, text)
+	upper := NewFileTree()
+	upper.AddPath(blob.Path, blob)
+	upper.AddPath(small.Path, small)
+
+	findings := HighEntropyFiles([]*FileTree{lower, upper}, HighEntropyThreshold)
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %d: %+v", len(findings), findings)
+	}
+	if findings[0].Path != "/app/model.bin" || findings[0].Layer != 1 {
+		t.Errorf("Unexpected first finding: %+v", findings[0])
+	}
+	if findings[1].Path != "/app/vendor.tar.gz" || findings[1].Layer != 0 {
+		t.Errorf("Unexpected second finding: %+v", findings[1])
+	}
+}
@@ -0,0 +1,29 @@
+package filetree
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrPathNotFound is the (wrapped) error of a lookup of a path that does not exist in the tree.
+	ErrPathNotFound = errors.New("path does not exist")
+	// ErrNotADirectory is the (wrapped) error of a lookup of a path beneath a file that is not a directory.
+	ErrNotADirectory = errors.New("not a directory")
+	// ErrWhiteoutConflict is the (wrapped) error of adding a path that cannot be a node of the tree, as it is a
+	// whiteout flag file (other than an opaque whiteout) or lies beneath a whiteout.
+	ErrWhiteoutConflict = errors.New("whiteout conflict")
+	// ErrSymlinkLoop is the (wrapped) error of resolving a path through symlinks that loop, or through more than
+	// MaxSymlinkDepth symlinks.
+	ErrSymlinkLoop = errors.New("too many levels of symbolic links")
+)
+
+// missingChild is the error of a lookup of the given path that found no child of the given node: ErrNotADirectory
+// when the node is known to be a file (not a directory), ErrPathNotFound otherwise.
+func missingChild(node *FileNode, nodePath string) error {
+	info := node.Data.FileInfo
+	if node.Parent != nil && info.Path != "" && !info.TarHeader.FileInfo().IsDir() {
+		return fmt.Errorf("%w: %s", ErrNotADirectory, nodePath)
+	}
+	return fmt.Errorf("%w: %s", ErrPathNotFound, nodePath)
+}
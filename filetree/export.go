@@ -0,0 +1,120 @@
+package filetree
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// ExportTar exports the tree as a tar archive
+	ExportTar = "tar"
+	// ExportList exports the tree as a listing of its paths, one per line
+	ExportList = "list"
+)
+
+// ContentOpener opens the content of a regular file of the tree, for a tar export.
+type ContentOpener func(node *FileNode) (io.ReadCloser, error)
+
+// ExportSquashed writes the filesystem the tree describes, typically the final stacked tree of an image (see
+// StackRange), which honors the whiteouts and opaque directories of every layer: this is what a container of the
+// image sees. The format is either ExportTar, a tar archive of every path (parents first) with the content of each
+// regular file opened with the given function, or ExportList, a listing of every path with its mode, owner and size
+// (which needs no content). Whiteouts left in the tree are not exported.
+func (tree *FileTree) ExportSquashed(writer io.Writer, format string, open ContentOpener) error {
+	switch format {
+	case ExportList:
+		return tree.exportList(writer)
+	case ExportTar:
+		if open == nil {
+			return fmt.Errorf("a tar export needs the contents of the files")
+		}
+		return tree.exportTar(writer, open)
+	}
+	return fmt.Errorf("unknown export format '%s' (expected %s or %s)", format, ExportTar, ExportList)
+}
+
+// visitExported visits every node to export in path order (parents first), without modifying the tree.
+func (tree *FileTree) visitExported(visitor func(node *FileNode) error) error {
+	stack := []*FileNode{tree.Root}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if node != tree.Root {
+			if err := visitor(node); err != nil {
+				return err
+			}
+		}
+		names := node.sortedChildNames()
+		for idx := len(names) - 1; idx >= 0; idx-- {
+			if child := node.Children[names[idx]]; !child.IsWhiteout() {
+				stack = append(stack, child)
+			}
+		}
+	}
+	return nil
+}
+
+// exportHeader returns the tar header of the node, standing in a directory header for a directory that was only
+// implied by the paths beneath it (without an entry of its own in any layer).
+func exportHeader(node *FileNode) tar.Header {
+	header := node.Data.FileInfo.TarHeader
+	if header.Name == "" && header.Typeflag == 0 && len(node.Children) > 0 {
+		header = tar.Header{Typeflag: tar.TypeDir, Mode: 0755}
+	}
+	if header.Typeflag == 0 {
+		// the legacy flag of a regular file
+		header.Typeflag = tar.TypeReg
+	}
+	header.Name = strings.TrimPrefix(node.Path(), "/")
+	if header.Typeflag == tar.TypeDir {
+		header.Name += "/"
+	}
+	return header
+}
+
+// exportList writes a line for every path: its mode, owner, size and path (along with the target of a link).
+func (tree *FileTree) exportList(writer io.Writer) error {
+	return tree.visitExported(func(node *FileNode) error {
+		header := exportHeader(node)
+		line := fmt.Sprintf("%s %5d:%-5d %12d %s", header.FileInfo().Mode(), header.Uid, header.Gid, node.Data.FileInfo.Size(), node.Path())
+		switch header.Typeflag {
+		case tar.TypeSymlink:
+			line += " -> " + header.Linkname
+		case tar.TypeLink:
+			line += " => /" + strings.TrimPrefix(header.Linkname, "/")
+		}
+		_, err := io.WriteString(writer, line+"\n")
+		return err
+	})
+}
+
+// exportTar writes a tar entry for every path, taking the content of regular files from the given function.
+func (tree *FileTree) exportTar(writer io.Writer, open ContentOpener) error {
+	tarWriter := tar.NewWriter(writer)
+	err := tree.visitExported(func(node *FileNode) error {
+		header := exportHeader(node)
+		if header.Typeflag != tar.TypeReg {
+			header.Size = 0
+			return tarWriter.WriteHeader(&header)
+		}
+
+		content, err := open(node)
+		if err != nil {
+			return fmt.Errorf("could not open %s: %v", node.Path(), err)
+		}
+		defer content.Close()
+		if err := tarWriter.WriteHeader(&header); err != nil {
+			return err
+		}
+		if _, err := io.CopyN(tarWriter, content, header.Size); err != nil {
+			return fmt.Errorf("could not export %s: %v", node.Path(), err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return tarWriter.Close()
+}
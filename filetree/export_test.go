@@ -0,0 +1,72 @@
+package filetree
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/wagoodman/dive/testkit"
+)
+
+func TestExportSquashed(t *testing.T) {
+	fixture, err := testkit.Fixture("", "whiteouts", "opaque", "hardlinks")
+	if err != nil {
+		t.Fatalf("could not build fixture: %v", err)
+	}
+	var trees []*FileTree
+	for _, layer := range fixture.Layers {
+		trees = append(trees, fixtureTree(t, layer))
+	}
+	stacked := StackRange(trees, 0, len(trees)-1)
+
+	var listing bytes.Buffer
+	if err := stacked.ExportSquashed(&listing, ExportList, nil); err != nil {
+		t.Fatalf("Expected no error listing the tree, got %v", err)
+	}
+	for _, expected := range []string{" /etc/hostname\n", " /opt/cache/c.bin\n", " /usr/local/lib/libfixture.so.0 -> libfixture.so.1\n"} {
+		if !strings.Contains(listing.String(), expected) {
+			t.Errorf("Expected the listing to hold %q:\n%s", expected, listing.String())
+		}
+	}
+
+	var archive bytes.Buffer
+	opened := 0
+	err = stacked.ExportSquashed(&archive, ExportTar, func(node *FileNode) (io.ReadCloser, error) {
+		opened++
+		return ioutil.NopCloser(strings.NewReader(strings.Repeat("x", int(node.Data.FileInfo.TarHeader.Size)))), nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error exporting the tree, got %v", err)
+	}
+	var names []string
+	reader := tar.NewReader(&archive)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Expected a valid tar archive, got %v", err)
+		}
+		names = append(names, header.Name)
+	}
+	exported := strings.Join(names, " ")
+	for _, removed := range []string{"etc/motd", "usr/bin/tool", "opt/cache/a.bin", ".wh."} {
+		if strings.Contains(exported, removed) {
+			t.Errorf("Expected %s not to be exported: %s", removed, exported)
+		}
+	}
+	if names[0] != "etc/" || !strings.Contains(exported, "opt/cache/c.bin") {
+		t.Errorf("Expected the paths in order (parents first), got %s", exported)
+	}
+	if opened != 3 {
+		t.Errorf("Expected the contents of 3 regular files to be opened, got %d", opened)
+	}
+
+	if err := stacked.ExportSquashed(&archive, ExportTar, nil); err == nil {
+		t.Errorf("Expected a tar export without contents to fail")
+	}
+}
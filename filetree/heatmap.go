@@ -0,0 +1,87 @@
+package filetree
+
+import (
+	"sort"
+	"strings"
+)
+
+// SizeMatrix holds the bytes each layer adds under each directory prefix (layers × prefixes).
+type SizeMatrix struct {
+	Prefixes []string
+	// Sizes is indexed by layer, then by prefix (in the same order as Prefixes)
+	Sizes  [][]uint64
+	Totals []uint64
+}
+
+// pathPrefix returns the leading directory of the given file path, limited to the given number of path components.
+// Files that are shallower than the requested depth are attributed to their parent directory.
+func pathPrefix(filePath string, depth int) string {
+	parts := strings.Split(strings.Trim(filePath, "/"), "/")
+	// never attribute a file to itself, only to a directory
+	parts = parts[:len(parts)-1]
+	if len(parts) > depth {
+		parts = parts[:depth]
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// SizeByPrefix aggregates the size of every file added or modified by each layer by its leading directory (e.g.
+// "/usr", "/var" at depth 1, or "/usr/lib" at depth 2). Prefixes are ordered by their total size, largest first.
+func SizeByPrefix(trees []*FileTree, depth int) *SizeMatrix {
+	if depth < 1 {
+		depth = 1
+	}
+
+	perLayer := make([]map[string]uint64, len(trees))
+	totals := make(map[string]uint64)
+	for idx, tree := range trees {
+		perLayer[idx] = make(map[string]uint64)
+		tree.VisitDepthParentFirst(func(node *FileNode) error {
+			if !node.IsLeaf() || node.IsWhiteout() || node.Data.FileInfo.TarHeader.FileInfo().IsDir() {
+				return nil
+			}
+			prefix := pathPrefix(node.Path(), depth)
+			size := uint64(node.Data.FileInfo.TarHeader.FileInfo().Size())
+			perLayer[idx][prefix] += size
+			totals[prefix] += size
+			return nil
+		}, nil)
+	}
+
+	matrix := &SizeMatrix{}
+	for prefix := range totals {
+		matrix.Prefixes = append(matrix.Prefixes, prefix)
+	}
+	sort.Slice(matrix.Prefixes, func(i, j int) bool {
+		left, right := matrix.Prefixes[i], matrix.Prefixes[j]
+		if totals[left] == totals[right] {
+			return left < right
+		}
+		return totals[left] > totals[right]
+	})
+
+	for _, prefix := range matrix.Prefixes {
+		matrix.Totals = append(matrix.Totals, totals[prefix])
+	}
+	for idx := range trees {
+		row := make([]uint64, len(matrix.Prefixes))
+		for col, prefix := range matrix.Prefixes {
+			row[col] = perLayer[idx][prefix]
+		}
+		matrix.Sizes = append(matrix.Sizes, row)
+	}
+	return matrix
+}
+
+// Max returns the largest single cell of the matrix.
+func (matrix *SizeMatrix) Max() uint64 {
+	var max uint64
+	for _, row := range matrix.Sizes {
+		for _, size := range row {
+			if size > max {
+				max = size
+			}
+		}
+	}
+	return max
+}
@@ -0,0 +1,50 @@
+package filetree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPathPrefix(t *testing.T) {
+	cases := []struct {
+		path     string
+		depth    int
+		expected string
+	}{
+		{"/usr/lib/libc.so", 1, "/usr"},
+		{"/usr/lib/libc.so", 2, "/usr/lib"},
+		{"/usr/lib/libc.so", 3, "/usr/lib"},
+		{"/app", 1, "/"},
+	}
+	for _, test := range cases {
+		if actual := pathPrefix(test.path, test.depth); actual != test.expected {
+			t.Errorf("Expected prefix of %q (depth %d) to be %q got %q", test.path, test.depth, test.expected, actual)
+		}
+	}
+}
+
+func TestSizeByPrefix(t *testing.T) {
+	lower := NewFileTree()
+	lower.AddPath("/usr/lib/libc.so", fileOfSize("/usr/lib/libc.so", 100))
+	lower.AddPath("/etc/passwd", fileOfSize("/etc/passwd", 10))
+
+	upper := NewFileTree()
+	upper.AddPath("/usr/bin/app", fileOfSize("/usr/bin/app", 50))
+	upper.AddPath("/var/cache/apt/pkg.bin", fileOfSize("/var/cache/apt/pkg.bin", 200))
+	upper.AddPath("/etc/.wh.passwd", FileInfo{})
+
+	matrix := SizeByPrefix([]*FileTree{lower, upper}, 1)
+
+	if expected := []string{"/var", "/usr", "/etc"}; !reflect.DeepEqual(matrix.Prefixes, expected) {
+		t.Errorf("Expected prefixes %v got %v", expected, matrix.Prefixes)
+	}
+	if expected := [][]uint64{{0, 100, 10}, {200, 50, 0}}; !reflect.DeepEqual(matrix.Sizes, expected) {
+		t.Errorf("Expected sizes %v got %v", expected, matrix.Sizes)
+	}
+	if expected := []uint64{200, 150, 10}; !reflect.DeepEqual(matrix.Totals, expected) {
+		t.Errorf("Expected totals %v got %v", expected, matrix.Totals)
+	}
+	if matrix.Max() != 200 {
+		t.Errorf("Expected max of 200 got %d", matrix.Max())
+	}
+}
@@ -0,0 +1,82 @@
+package filetree
+
+import (
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+// AllImages is the image family of hide rules that apply to every image.
+const AllImages = "*"
+
+// HideRules are path patterns (globs, where "**" matches any number of directories) that are always hidden from view,
+// kept per image family (the image repository, e.g. "ubuntu" for "ubuntu:18.04"). Unlike the filter, these rules
+// persist between sessions, so the same noise in a base image is only hidden once.
+type HideRules map[string][]string
+
+// ReadHideRules parses hide rules from YAML, keyed by image family:
+//
+//	ubuntu:
+//	  - /usr/share/doc/**
+//	"*":
+//	  - /var/cache/apt
+func ReadHideRules(reader io.Reader) (HideRules, error) {
+	contents, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	rules := make(HideRules)
+	if err := yaml.UnmarshalStrict(contents, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// Write serializes the hide rules as YAML (the form read by ReadHideRules).
+func (rules HideRules) Write(writer io.Writer) error {
+	contents, err := yaml.Marshal(rules)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(contents)
+	return err
+}
+
+// Patterns returns the patterns that apply to the given image family (including those that apply to all images).
+func (rules HideRules) Patterns(family string) []string {
+	patterns := append([]string(nil), rules[family]...)
+	if family != AllImages {
+		patterns = append(patterns, rules[AllImages]...)
+	}
+	return patterns
+}
+
+// Matches indicates if the given path is hidden by any rule of the given image family.
+func (rules HideRules) Matches(family, path string) bool {
+	for _, pattern := range rules.Patterns(family) {
+		if globMatch(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Toggle adds the given pattern to the rules of the image family, or removes it if it is already present. It returns
+// true if the pattern was added.
+func (rules HideRules) Toggle(family, pattern string) bool {
+	patterns := rules[family]
+	for idx, existing := range patterns {
+		if existing == pattern {
+			rules[family] = append(patterns[:idx:idx], patterns[idx+1:]...)
+			if len(rules[family]) == 0 {
+				delete(rules, family)
+			}
+			return false
+		}
+	}
+	rules[family] = append(patterns, pattern)
+	sort.Strings(rules[family])
+	return true
+}
@@ -0,0 +1,64 @@
+package filetree
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestHideRules(t *testing.T) {
+	rules, err := ReadHideRules(strings.NewReader(`
+ubuntu:
+  - /usr/share/doc/**
+"*":
+  - /var/cache/apt
+`))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	cases := []struct {
+		family, path string
+		expected     bool
+	}{
+		{"ubuntu", "/usr/share/doc/bash/copyright", true},
+		{"ubuntu", "/usr/share/docs", false},
+		{"ubuntu", "/var/cache/apt", true},
+		{"alpine", "/var/cache/apt", true},
+		{"alpine", "/usr/share/doc/bash/copyright", false},
+	}
+	for _, test := range cases {
+		if actual := rules.Matches(test.family, test.path); actual != test.expected {
+			t.Errorf("Matches(%q, %q): expected %v, got %v", test.family, test.path, test.expected, actual)
+		}
+	}
+}
+
+func TestHideRulesToggle(t *testing.T) {
+	rules := make(HideRules)
+	if !rules.Toggle("alpine", "/etc/apk") {
+		t.Errorf("Expected the rule to be added")
+	}
+	if !rules.Matches("alpine", "/etc/apk") {
+		t.Errorf("Expected the added rule to match")
+	}
+
+	var buffer bytes.Buffer
+	if err := rules.Write(&buffer); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	reread, err := ReadHideRules(&buffer)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if !reread.Matches("alpine", "/etc/apk") {
+		t.Errorf("Expected the rule to survive a round trip, got %v", reread)
+	}
+
+	if rules.Toggle("alpine", "/etc/apk") {
+		t.Errorf("Expected the rule to be removed")
+	}
+	if len(rules) != 0 {
+		t.Errorf("Expected no rules left, got %v", rules)
+	}
+}
@@ -0,0 +1,194 @@
+package filetree
+
+import (
+	"strings"
+)
+
+// The nodes of a tree are indexed by path, such that a lookup (GetNode, RemovePath, Compare) does not walk the tree
+// from the root. The index is built on the first lookup and kept complete from then on: nodes are added when a child
+// is added or shared into the tree, replaced when a shared node is copied into the tree, and dropped (along with their
+// descendants) when a child is removed. Building the index modifies the tree, so a tree that is read from several
+// goroutines at once is indexed upfront (see Freeze).
+
+// indexKey returns the key of the node in the index of its tree: the path of the node, keeping the prefix of a
+// whiteout (which Path drops), such that a whiteout and the path it removes are told apart.
+func (node *FileNode) indexKey() string {
+	if node.Parent == nil || !node.IsWhiteout() {
+		return node.Path()
+	}
+	return strings.TrimSuffix(node.Parent.indexKey(), "/") + "/" + node.Name
+}
+
+// indexPath returns the index key of the given slash-delimited path (e.g. "a/node/path/" is "/a/node/path").
+func indexPath(path string) string {
+	key := "/" + strings.Trim(path, "/")
+	if !strings.Contains(key, "//") {
+		return key
+	}
+	var names []string
+	for _, name := range strings.Split(key, "/") {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return "/" + strings.Join(names, "/")
+}
+
+// index returns the index of the tree, building it when the tree was not looked up before.
+func (tree *FileTree) index() map[string]*FileNode {
+	if tree.nodes == nil {
+		tree.nodes = make(map[string]*FileNode, tree.Size+1)
+		tree.nodes["/"] = tree.Root
+		for name, child := range tree.Root.Children {
+			visitKeyed(child, "/"+name, tree.indexNode)
+		}
+	}
+	return tree.nodes
+}
+
+// indexed looks the given path up in the index, returning the node at the path or, when there is none, the deepest
+// node along the path (which is not the node sought).
+func (tree *FileTree) indexed(path string) (node *FileNode, found bool) {
+	nodes := tree.index()
+	key := indexPath(path)
+	if node, ok := nodes[key]; ok {
+		return node, true
+	}
+	for key != "/" {
+		key = key[:strings.LastIndex(key, "/")]
+		if key == "" {
+			key = "/"
+		}
+		if node, ok := nodes[key]; ok {
+			return node, false
+		}
+	}
+	return tree.Root, false
+}
+
+// visitKeyed visits the given node (at the given index key) and everything beneath it, along with their keys. The
+// nodes are visited in any order and in place (without copying shared nodes into the tree).
+func visitKeyed(node *FileNode, key string, visitor func(node *FileNode, key string)) {
+	type entry struct {
+		node *FileNode
+		key  string
+	}
+	stack := []entry{{node, key}}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		visitor(current.node, current.key)
+		for name, child := range current.node.Children {
+			stack = append(stack, entry{child, current.key + "/" + name})
+		}
+	}
+}
+
+// indexNode adds a node to the index of the tree.
+func (tree *FileTree) indexNode(node *FileNode, key string) {
+	tree.nodes[key] = node
+}
+
+// unindexNode drops a node from the index of the tree.
+func (tree *FileTree) unindexNode(node *FileNode, key string) {
+	delete(tree.nodes, key)
+}
+
+// indexSubtree adds the given node (and everything beneath it) to the index of the tree, if the tree is indexed.
+func (tree *FileTree) indexSubtree(node *FileNode) {
+	if tree.nodes != nil {
+		visitKeyed(node, node.indexKey(), tree.indexNode)
+	}
+}
+
+// unindexSubtree drops the given node (and everything beneath it) from the index of the tree, if the tree is indexed.
+func (tree *FileTree) unindexSubtree(node *FileNode) {
+	if tree.nodes != nil {
+		visitKeyed(node, node.indexKey(), tree.unindexNode)
+	}
+}
+
+// pathsBeneath tracks a set of paths by the directories above them, such that the paths beneath a directory (e.g. the
+// ones removed by a whiteout or hidden by an opaque directory) are found without scanning the whole set.
+type pathsBeneath map[string]map[string]bool
+
+// add adds the given (clean, absolute) path to the set.
+func (paths pathsBeneath) add(path string) {
+	for dir := path; dir != "/"; {
+		dir = dirOf(dir)
+		if paths[dir] == nil {
+			paths[dir] = make(map[string]bool)
+		}
+		paths[dir][path] = true
+	}
+}
+
+// remove drops the given path from the set.
+func (paths pathsBeneath) remove(path string) {
+	for dir := path; dir != "/"; {
+		dir = dirOf(dir)
+		delete(paths[dir], path)
+		if len(paths[dir]) == 0 {
+			delete(paths, dir)
+		}
+	}
+}
+
+// beneath returns the paths of the set beneath the given directory (not the directory itself).
+func (paths pathsBeneath) beneath(dir string) []string {
+	result := make([]string, 0, len(paths[dir]))
+	for path := range paths[dir] {
+		result = append(result, path)
+	}
+	return result
+}
+
+// StackFiles follows the files (not directories) of the filesystem as the given layer trees are stacked, oldest first:
+// added is called for each file a layer provides, removed for each file a later layer removes, either by a whiteout of
+// the file (or of a directory above it) or by an opaque directory above it which the layer does not provide the file
+// in again. The trees are only read.
+func StackFiles(trees []*FileTree, added func(layer int, node *FileNode), removed func(layer int, path string)) {
+	files := make(map[string]bool)
+	beneath := make(pathsBeneath)
+	for idx, tree := range trees {
+		remove := func(path string) {
+			delete(files, path)
+			beneath.remove(path)
+			removed(idx, path)
+		}
+		tree.ObserveDepthParentFirst(func(node *FileNode) error {
+			path := node.Path()
+			if node.IsWhiteout() {
+				if files[path] {
+					remove(path)
+				}
+				for _, key := range beneath.beneath(path) {
+					remove(key)
+				}
+				return nil
+			}
+			if node.Opaque {
+				for _, key := range beneath.beneath(path) {
+					if _, err := tree.lookup(key); err != nil {
+						remove(key)
+					}
+				}
+			}
+			if node.IsLeaf() && !node.Data.FileInfo.TarHeader.FileInfo().IsDir() {
+				files[path] = true
+				beneath.add(path)
+				added(idx, node)
+			}
+			return nil
+		}, nil)
+	}
+}
+
+// dirOf returns the directory holding the given (clean, absolute) path.
+func dirOf(path string) string {
+	idx := strings.LastIndex(path, "/")
+	if idx <= 0 {
+		return "/"
+	}
+	return path[:idx]
+}
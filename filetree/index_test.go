@@ -0,0 +1,182 @@
+package filetree
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// checkIndex verifies that the index of the tree holds exactly the nodes reachable from its root.
+func checkIndex(t *testing.T, tree *FileTree) {
+	t.Helper()
+	nodes := tree.index()
+	if len(nodes) != tree.Size+1 {
+		t.Errorf("Expected %d indexed nodes, got %d", tree.Size+1, len(nodes))
+	}
+	visitKeyed(tree.Root, "", func(node *FileNode, key string) {
+		if key == "" {
+			key = "/"
+		}
+		if nodes[key] != node {
+			t.Errorf("Expected %s to be indexed", key)
+		}
+	})
+}
+
+func TestIndex(t *testing.T) {
+	tree := NewFileTree()
+	tree.AddPath("/etc/nginx/nginx.conf", FileInfo{})
+	tree.AddPath("/etc/hosts", FileInfo{})
+	tree.AddPath("/var/log/nginx/access.log", FileInfo{})
+	if node, err := tree.GetNode("etc//nginx/"); err != nil || node.Path() != "/etc/nginx" {
+		t.Fatalf("Expected to find /etc/nginx, got %v (%v)", node, err)
+	}
+	checkIndex(t, tree)
+
+	tree.AddPath("/etc/nginx/conf.d/default.conf", FileInfo{})
+	tree.AddPath("/var/log/.wh.nginx", FileInfo{})
+	tree.RemovePath("/etc/nginx")
+	checkIndex(t, tree)
+	if _, err := tree.GetNode("/etc/nginx/conf.d"); err == nil {
+		t.Errorf("Expected a removed path not to be found")
+	}
+	if node, err := tree.GetNode("/var/log/.wh.nginx"); err != nil || !node.IsWhiteout() {
+		t.Errorf("Expected to find the whiteout apart from the path it removes, got %v (%v)", node, err)
+	}
+	if node, _ := tree.GetNode("/var/log/nginx"); node == nil || node.IsWhiteout() {
+		t.Errorf("Expected to find the path the whiteout removes apart from the whiteout")
+	}
+}
+
+func TestIndexStacked(t *testing.T) {
+	lower := NewFileTree()
+	lower.AddPath("/usr/bin/ls", FileInfo{})
+	upper := NewFileTree()
+	upper.AddPath("/usr/lib/libc.so", FileInfo{})
+	upper.AddPath("/opt/app/bin/app", FileInfo{})
+
+	stacked := StackRange([]*FileTree{lower, upper}, 0, 1)
+	checkIndex(t, stacked)
+
+	// the node is owned (copied into the stacked tree) when it is to be modified
+	shared, _ := stacked.lookup("/opt/app/bin")
+	owned, _ := stacked.GetNode("/opt/app/bin")
+	if shared == owned || owned.Tree != stacked {
+		t.Fatalf("Expected the shared node to be copied into the stacked tree")
+	}
+	if indexed, _ := stacked.lookup("/opt/app/bin"); indexed != owned {
+		t.Errorf("Expected the copied node to be indexed")
+	}
+	stacked.RemovePath("/opt/app")
+	checkIndex(t, stacked)
+	if _, err := upper.GetNode("/opt/app/bin/app"); err != nil {
+		t.Errorf("Expected the stacked tree not to modify the upper tree: %v", err)
+	}
+}
+
+func TestIndexFrozenConcurrentReads(t *testing.T) {
+	tree := NewFileTree()
+	tree.AddPath("/etc/hosts", FileInfo{})
+	tree.AddPath("/usr/bin/ls", FileInfo{})
+	tree.AddPath("/usr/lib/libc.so", FileInfo{})
+	frozen := tree.Freeze()
+
+	// run with -race: lookups of a frozen tree must not build any state of the tree
+	var wg sync.WaitGroup
+	for idx := 0; idx < 4; idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if node, err := frozen.GetNode("/usr/bin/ls"); err != nil || !node.Exists() {
+				t.Errorf("Expected to find /usr/bin/ls: %v", err)
+			}
+			if _, err := frozen.ResolvePath("/etc/hosts"); err != nil {
+				t.Errorf("Expected to resolve /etc/hosts: %v", err)
+			}
+			if matches, err := frozen.FindAll("/usr/**"); err != nil || len(matches) != 5 {
+				t.Errorf("Expected /usr and 4 paths beneath it, got %d (%v)", len(matches), err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPathsBeneath(t *testing.T) {
+	paths := make(pathsBeneath)
+	for _, path := range []string{"/etc/hosts", "/etc/nginx/nginx.conf", "/etc/nginx/conf.d/default.conf", "/etcetera", "/bin"} {
+		paths.add(path)
+	}
+	paths.remove("/etc/nginx/nginx.conf")
+
+	cases := []struct {
+		dir      string
+		expected []string
+	}{
+		{"/etc", []string{"/etc/hosts", "/etc/nginx/conf.d/default.conf"}},
+		{"/etc/nginx", []string{"/etc/nginx/conf.d/default.conf"}},
+		{"/", []string{"/bin", "/etc/hosts", "/etc/nginx/conf.d/default.conf", "/etcetera"}},
+		{"/etc/hosts", []string{}},
+		{"/var", []string{}},
+	}
+	for _, test := range cases {
+		beneath := paths.beneath(test.dir)
+		sort.Strings(beneath)
+		if !reflect.DeepEqual(beneath, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.dir, test.expected, beneath)
+		}
+	}
+
+	paths.remove("/etc/nginx/conf.d/default.conf")
+	if _, ok := paths["/etc/nginx"]; ok {
+		t.Errorf("Expected directories without paths beneath them to be dropped")
+	}
+}
+
+// opaqueLayers are layers of which the second removes files of the first with an opaque directory (providing one of
+// them again) and a whiteout, and the third provides them all again.
+func opaqueLayers() []*FileTree {
+	first := NewFileTree()
+	first.AddPath("/app/lib/big.so", fileOfSize("/app/lib/big.so", 500))
+	first.AddPath("/app/lib/kept.so", fileOfSize("/app/lib/kept.so", 50))
+	first.AddPath("/application", fileOfSize("/application", 10))
+	first.AddPath("/etc/hosts", fileOfSize("/etc/hosts", 10))
+
+	second := NewFileTree()
+	second.AddPath("/app/lib/kept.so", fileOfSize("/app/lib/kept.so", 50))
+	app, _ := second.GetNode("/app")
+	app.Opaque = true
+	second.AddPath("/etc/.wh.hosts", FileInfo{})
+
+	third := NewFileTree()
+	third.AddPath("/app/lib/big.so", fileOfSize("/app/lib/big.so", 500))
+	third.AddPath("/app/lib/kept.so", fileOfSize("/app/lib/kept.so", 60))
+	third.AddPath("/application", fileOfSize("/application", 10))
+	third.AddPath("/etc/hosts", fileOfSize("/etc/hosts", 20))
+	return []*FileTree{first, second, third}
+}
+
+func TestStackFiles(t *testing.T) {
+	trees := opaqueLayers()
+	var events []string
+	StackFiles(trees, func(layer int, node *FileNode) {
+		events = append(events, fmt.Sprintf("%d+%s", layer, node.Path()))
+	}, func(layer int, path string) {
+		events = append(events, fmt.Sprintf("%d-%s", layer, path))
+	})
+	sort.Strings(events)
+	expected := []string{
+		"0+/app/lib/big.so", "0+/app/lib/kept.so", "0+/application", "0+/etc/hosts",
+		"1+/app/lib/kept.so", "1-/app/lib/big.so", "1-/etc/hosts",
+		"2+/app/lib/big.so", "2+/app/lib/kept.so", "2+/application", "2+/etc/hosts",
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Expected %v, got %v", expected, events)
+	}
+	for _, tree := range trees {
+		if tree.shared || tree.lent {
+			t.Errorf("Expected the trees to be read only")
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package filetree
+
+import (
+	"archive/tar"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// SchemaVersion is the version of every JSON output of dive (including the serialized FileTree), written as its
+// "schemaVersion" field. Within a major version outputs only gain fields (a minor version bump), so parsers should
+// ignore the fields they do not know; removing, renaming or retyping a field bumps the major version.
+const SchemaVersion = "1.0"
+
+// compatibleSchema indicates if JSON output of the given schema version can be read, which is the case for outputs of
+// the same major version.
+func compatibleSchema(version string) bool {
+	return strings.SplitN(version, ".", 2)[0] == strings.SplitN(SchemaVersion, ".", 2)[0]
+}
+
+// jsonFileInfo is the serialized form of a FileInfo.
+type jsonFileInfo struct {
+	Path         string            `json:"path,omitempty"`
+	TypeFlag     byte              `json:"typeFlag,omitempty"`
+	MD5sum       string            `json:"md5,omitempty"`
+	SHA256       string            `json:"sha256,omitempty"`
+	TarHeader    tar.Header        `json:"header"`
+	Licenses     []string          `json:"licenses,omitempty"`
+	Entropy      float64           `json:"entropy,omitempty"`
+	AccountNames map[int]string    `json:"accountNames,omitempty"`
+	Tags         map[string]string `json:"tags,omitempty"`
+	LinkID       string            `json:"linkId,omitempty"`
+}
+
+// jsonNode is the serialized form of a FileNode and its children (in name order).
+type jsonNode struct {
+	Name     string            `json:"name"`
+	Info     jsonFileInfo      `json:"info"`
+	DiffType string            `json:"diffType"`
+	Changes  []AttributeChange `json:"changes,omitempty"`
+	Opaque   bool              `json:"opaque,omitempty"`
+	Children []*jsonNode       `json:"children,omitempty"`
+}
+
+// jsonTree is the serialized form of a FileTree.
+type jsonTree struct {
+	SchemaVersion string      `json:"schemaVersion"`
+	Name          string      `json:"name,omitempty"`
+	FileSize      uint64      `json:"fileSize"`
+	Opaque        bool        `json:"opaque,omitempty"`
+	Children      []*jsonNode `json:"children"`
+	Warnings      []Warning   `json:"warnings,omitempty"`
+}
+
+// diffTypes maps the serialized names of DiffTypes back to the DiffTypes.
+var diffTypes = map[string]DiffType{
+	"unchanged": Unchanged,
+	"changed":   Changed,
+	"added":     Added,
+	"removed":   Removed,
+}
+
+func newJSONNode(node *FileNode) *jsonNode {
+	info := node.Data.FileInfo
+	result := &jsonNode{
+		Name: node.Name,
+		Info: jsonFileInfo{
+			Path:         info.Path,
+			TypeFlag:     info.TypeFlag,
+			TarHeader:    info.TarHeader,
+			Licenses:     info.Licenses,
+			Entropy:      info.Entropy,
+			AccountNames: info.AccountNames,
+			Tags:         info.Tags,
+			LinkID:       info.LinkID,
+		},
+		DiffType: strings.ToLower(node.Data.DiffType.String()),
+		Changes:  node.Data.Changes,
+		Opaque:   node.Opaque,
+	}
+	if info.MD5sum != [16]byte{} {
+		result.Info.MD5sum = hex.EncodeToString(info.MD5sum[:])
+	}
+	if info.SHA256 != [32]byte{} {
+		result.Info.SHA256 = hex.EncodeToString(info.SHA256[:])
+	}
+	for _, name := range node.sortedChildNames() {
+		result.Children = append(result.Children, newJSONNode(node.Children[name]))
+	}
+	return result
+}
+
+// addTo adds the serialized node (and its children) as a child of the given node.
+func (serialized *jsonNode) addTo(parent *FileNode) error {
+	info := FileInfo{
+		Path:         serialized.Info.Path,
+		TypeFlag:     serialized.Info.TypeFlag,
+		TarHeader:    serialized.Info.TarHeader,
+		Licenses:     serialized.Info.Licenses,
+		Entropy:      serialized.Info.Entropy,
+		AccountNames: serialized.Info.AccountNames,
+		Tags:         copyTags(serialized.Info.Tags),
+		LinkID:       serialized.Info.LinkID,
+	}
+	info.Xattrs = copyXattrs(xattrs(&info.TarHeader))
+	if serialized.Info.MD5sum != "" {
+		sum, err := hex.DecodeString(serialized.Info.MD5sum)
+		if err != nil || len(sum) != len(info.MD5sum) {
+			return fmt.Errorf("invalid md5 of %s: %q", serialized.Name, serialized.Info.MD5sum)
+		}
+		copy(info.MD5sum[:], sum)
+	}
+	if serialized.Info.SHA256 != "" {
+		sum, err := hex.DecodeString(serialized.Info.SHA256)
+		if err != nil || len(sum) != len(info.SHA256) {
+			return fmt.Errorf("invalid sha256 of %s: %q", serialized.Name, serialized.Info.SHA256)
+		}
+		copy(info.SHA256[:], sum)
+	}
+	diffType, ok := diffTypes[serialized.DiffType]
+	if !ok {
+		return fmt.Errorf("invalid diff type of %s: %q", serialized.Name, serialized.DiffType)
+	}
+	if serialized.Name == "" || strings.Contains(serialized.Name, "/") {
+		return fmt.Errorf("invalid node name %q", serialized.Name)
+	}
+
+	node := parent.AddChild(serialized.Name, info)
+	if node == nil {
+		return fmt.Errorf("could not add node %q", serialized.Name)
+	}
+	node.Data.DiffType = diffType
+	node.Data.Changes = serialized.Changes
+	node.Opaque = serialized.Opaque
+	for _, child := range serialized.Children {
+		if err := child.addTo(node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalJSON serializes the tree: every node (by name, with its children) along with its FileInfo, DiffType and
+// attribute changes, such that an analysis can be saved and compared later. UI state (ViewInfo) is not serialized.
+func (tree *FileTree) MarshalJSON() ([]byte, error) {
+	serialized := jsonTree{SchemaVersion: SchemaVersion, Name: tree.Name, FileSize: tree.FileSize, Opaque: tree.Root.Opaque, Children: []*jsonNode{}, Warnings: tree.Warnings}
+	for _, name := range tree.Root.sortedChildNames() {
+		serialized.Children = append(serialized.Children, newJSONNode(tree.Root.Children[name]))
+	}
+	return json.Marshal(serialized)
+}
+
+// UnmarshalJSON replaces the contents of the tree with a tree serialized by MarshalJSON (of a compatible schema version,
+// see SchemaVersion).
+func (tree *FileTree) UnmarshalJSON(data []byte) error {
+	var serialized jsonTree
+	if err := json.Unmarshal(data, &serialized); err != nil {
+		return err
+	}
+	if !compatibleSchema(serialized.SchemaVersion) {
+		return fmt.Errorf("unsupported tree schema version %q (expected %s)", serialized.SchemaVersion, SchemaVersion)
+	}
+
+	*tree = *NewFileTree()
+	tree.Root.Tree = tree
+	tree.Name = serialized.Name
+	tree.FileSize = serialized.FileSize
+	tree.Root.Opaque = serialized.Opaque
+	tree.Warnings = serialized.Warnings
+	for _, child := range serialized.Children {
+		if err := child.addTo(tree.Root); err != nil {
+			return err
+		}
+	}
+	return nil
+}
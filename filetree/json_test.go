@@ -0,0 +1,142 @@
+package filetree
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestFileTreeJSONRoundTrip(t *testing.T) {
+	lower := NewFileTree()
+	lower.Name = "layer"
+	lower.FileSize = 30
+	lower.AddPath("/etc/motd", fileOfSize("/etc/motd", 10))
+	lower.AddPath("/etc/hosts", fileOfSize("/etc/hosts", 20))
+
+	upper := NewFileTree()
+	motd := fileOfSize("/etc/motd", 15)
+	motd.MD5sum = [16]byte{0xde, 0xad}
+	motd.Licenses = []string{"MIT"}
+	upper.AddPath("/etc/motd", motd)
+	upper.AddPath("/usr/bin/app", fileOfSize("/usr/bin/app", 40))
+	if err := lower.Compare(upper); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	data, err := json.Marshal(lower)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	restored := NewFileTree()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	if restored.Name != "layer" || restored.FileSize != 30 || restored.Size != lower.Size {
+		t.Errorf("Expected tree attributes to be restored, got name=%q fileSize=%d size=%d", restored.Name, restored.FileSize, restored.Size)
+	}
+	if restored.String(true) != lower.String(true) {
+		t.Errorf("Expected the restored tree to render the same:\n%s\ngot:\n%s", lower.String(true), restored.String(true))
+	}
+
+	node, err := restored.GetNode("/etc/motd")
+	if err != nil {
+		t.Fatalf("Expected /etc/motd to be restored: %v", err)
+	}
+	if node.Data.DiffType != Changed || len(node.Data.Changes) != 1 || node.Data.Changes[0].String() != "size 10 B→15 B" {
+		t.Errorf("Expected the change of /etc/motd to be restored, got %v %v", node.Data.DiffType, node.Data.Changes)
+	}
+	if node.Data.FileInfo.TarHeader.Size != 10 || node.Tree != restored {
+		t.Errorf("Expected the FileInfo of /etc/motd to be restored, got %+v", node.Data.FileInfo)
+	}
+	added, err := restored.GetNode("/usr/bin/app")
+	if err != nil || added.Data.DiffType != Added || added.Path() != "/usr/bin/app" {
+		t.Errorf("Expected /usr/bin/app to be restored as added, got %v (%v)", added, err)
+	}
+
+	if err := json.Unmarshal([]byte(`{"schemaVersion":"1.0","children":[{"name":"x","diffType":"bogus"}]}`), restored); err == nil {
+		t.Errorf("Expected an error for an invalid diff type")
+	}
+}
+
+func TestFileTreeJSONSchemaVersion(t *testing.T) {
+	data, err := json.Marshal(NewFileTree())
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	var envelope struct {
+		SchemaVersion string `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil || envelope.SchemaVersion != SchemaVersion {
+		t.Errorf("Expected schema version %s, got %q (%v)", SchemaVersion, envelope.SchemaVersion, err)
+	}
+
+	for _, version := range []string{"1.7", ""} {
+		tree := NewFileTree()
+		err := json.Unmarshal([]byte(`{"schemaVersion":"`+version+`","fileSize":0,"children":[]}`), tree)
+		if compatible := version == "1.7"; (err == nil) != compatible {
+			t.Errorf("schema version %q: expected compatible=%v, got %v", version, compatible, err)
+		}
+	}
+	if err := json.Unmarshal([]byte(`{"schemaVersion":"2.0","children":[]}`), NewFileTree()); err == nil {
+		t.Errorf("Expected an error for another major schema version")
+	}
+}
+
+func TestFileTreeJSONHardlinks(t *testing.T) {
+	tree := NewFileTree()
+	lib := fileOfSize("usr/lib/libfoo.so.1", 1000)
+	lib.LinkID = "/usr/lib/libfoo.so.1"
+	link := fileOfSize("usr/lib/libfoo.so", 1000)
+	link.TarHeader.Typeflag, link.TarHeader.Linkname = tar.TypeLink, "usr/lib/libfoo.so.1"
+	link.LinkID = "/usr/lib/libfoo.so.1"
+	tree.AddPath(lib.Path, lib)
+	tree.AddPath(link.Path, link)
+
+	data, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	restored := NewFileTree()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	node, err := restored.GetNode("/usr/lib/libfoo.so")
+	if err != nil || node.Data.FileInfo.LinkID != "/usr/lib/libfoo.so.1" {
+		t.Fatalf("Expected the inode of the hard link to be restored, got %+v (%v)", node, err)
+	}
+	if size := restored.Root.AggregateSize(); size != 1000 {
+		t.Errorf("Expected the linked file to be counted once, got %d bytes", size)
+	}
+}
+
+func TestFileTreeJSONXattrs(t *testing.T) {
+	header := &tar.Header{
+		Name:     "usr/bin/ping",
+		Typeflag: tar.TypeReg,
+		PAXRecords: map[string]string{
+			"SCHILY.xattr.security.capability": capabilityData(vfsCapRevision2|vfsCapFlagsEffective, 1<<13, 0),
+			"SCHILY.xattr.security.selinux":    "system_u:object_r:ping_exec_t:s0\x00",
+		},
+	}
+	tree := NewFileTree()
+	tree.AddPath("/usr/bin/ping", NewFileInfoFromHeader(header, header.Name))
+
+	data, err := json.Marshal(tree)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	// the xattrs are only serialized within the tar header
+	if strings.Contains(string(data), "capabilities") || strings.Contains(string(data), "selinuxLabel") {
+		t.Errorf("Expected the capabilities and SELinux label to be serialized once, got %s", data)
+	}
+	restored := NewFileTree()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	node, err := restored.GetNode("/usr/bin/ping")
+	if err != nil || node.Data.FileInfo.Capabilities() != "cap_net_raw=ep" || node.Data.FileInfo.SELinuxLabel() != "system_u:object_r:ping_exec_t:s0" {
+		t.Errorf("Expected the xattrs to be restored, got %+v (%v)", node, err)
+	}
+}
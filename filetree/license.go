@@ -0,0 +1,116 @@
+package filetree
+
+import (
+	"bufio"
+	"bytes"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// licenseFilePattern matches the names of files that typically hold license text.
+var licenseFilePattern = regexp.MustCompile(`(?i)^(licen[cs]e|copying|unlicense|notice)([._-].*)?$`)
+
+// licenseSignatures map distinctive license text to an SPDX identifier, checked in order (more specific first).
+var licenseSignatures = []struct {
+	id       string
+	keywords []string
+}{
+	{"AGPL-3.0", []string{"GNU AFFERO GENERAL PUBLIC LICENSE"}},
+	{"LGPL-3.0", []string{"GNU LESSER GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"LGPL-2.1", []string{"GNU LESSER GENERAL PUBLIC LICENSE", "Version 2.1"}},
+	{"LGPL-2.0", []string{"GNU LIBRARY GENERAL PUBLIC LICENSE"}},
+	{"GPL-3.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 3"}},
+	{"GPL-2.0", []string{"GNU GENERAL PUBLIC LICENSE", "Version 2"}},
+	{"Apache-2.0", []string{"Apache License", "Version 2.0"}},
+	{"MPL-2.0", []string{"Mozilla Public License", "2.0"}},
+	{"EPL-2.0", []string{"Eclipse Public License - v 2.0"}},
+	{"Unlicense", []string{"This is free and unencumbered software released into the public domain"}},
+	{"BSD-3-Clause", []string{"Redistribution and use in source and binary forms", "Neither the name"}},
+	{"BSD-2-Clause", []string{"Redistribution and use in source and binary forms"}},
+	{"ISC", []string{"Permission to use, copy, modify, and/or distribute this software for any purpose"}},
+	{"MIT", []string{"Permission is hereby granted, free of charge"}},
+}
+
+// LicenseFinding is a single file that declares one or more licenses.
+type LicenseFinding struct {
+	Path     string
+	Licenses []string
+}
+
+// isLicenseFile indicates if the given path is likely to hold license text or package license metadata.
+func isLicenseFile(filePath string) bool {
+	name := path.Base(filePath)
+	if licenseFilePattern.MatchString(name) {
+		return true
+	}
+	// debian packages ship their license metadata as /usr/share/doc/<package>/copyright
+	return name == "copyright" && strings.Contains(filePath, "share/doc/")
+}
+
+// detectLicenses determines the SPDX identifiers of the licenses declared by the given license file contents. Debian
+// machine-readable copyright files are read by their "License:" fields, all other files by their license text.
+func detectLicenses(contents []byte) []string {
+	found := make(map[string]bool)
+
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "License:") {
+			if value := strings.TrimSpace(strings.TrimPrefix(line, "License:")); value != "" {
+				found[value] = true
+			}
+		}
+	}
+
+	if len(found) == 0 {
+		text := strings.Join(strings.Fields(string(contents)), " ")
+		for _, signature := range licenseSignatures {
+			matched := true
+			for _, keyword := range signature.keywords {
+				if !strings.Contains(text, keyword) {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				found[signature.id] = true
+				break
+			}
+		}
+	}
+
+	var licenses []string
+	for license := range found {
+		licenses = append(licenses, license)
+	}
+	sort.Strings(licenses)
+	return licenses
+}
+
+// LicenseFindings lists every license file in the given tree (typically the fully stacked image) that is not removed.
+func LicenseFindings(tree *FileTree) []LicenseFinding {
+	var findings []LicenseFinding
+	tree.ObserveDepthParentFirst(func(node *FileNode) error {
+		if len(node.Data.FileInfo.Licenses) > 0 && node.Data.DiffType != Removed {
+			findings = append(findings, LicenseFinding{
+				Path:     node.Path(),
+				Licenses: node.Data.FileInfo.Licenses,
+			})
+		}
+		return nil
+	}, nil)
+	return findings
+}
+
+// LicenseSummary counts the number of license files declaring each license.
+func LicenseSummary(findings []LicenseFinding) map[string]int {
+	summary := make(map[string]int)
+	for _, finding := range findings {
+		for _, license := range finding.Licenses {
+			summary[license]++
+		}
+	}
+	return summary
+}
@@ -0,0 +1,61 @@
+package filetree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIsLicenseFile(t *testing.T) {
+	cases := map[string]bool{
+		"/app/LICENSE":                       true,
+		"/app/node_modules/x/LICENSE.md":     true,
+		"/usr/share/common-licenses/COPYING": true,
+		"/usr/share/doc/bash/copyright":      true,
+		"/etc/copyright":                     false,
+		"/app/licenses.go":                   false,
+		"/app/main.go":                       false,
+	}
+	for path, expected := range cases {
+		if actual := isLicenseFile(path); actual != expected {
+			t.Errorf("Expected isLicenseFile(%q) to be %v", path, expected)
+		}
+	}
+}
+
+func TestDetectLicenses(t *testing.T) {
+	cases := []struct {
+		contents string
+		expected []string
+	}{
+		{"MIT License\n\nPermission is hereby granted, free of charge, to any person", []string{"MIT"}},
+		{"                    GNU GENERAL PUBLIC LICENSE\n                       Version 3, 29 June 2007", []string{"GPL-3.0"}},
+		{"GNU LESSER GENERAL PUBLIC LICENSE\nVersion 2.1, February 1999", []string{"LGPL-2.1"}},
+		{"Apache License\n                           Version 2.0, January 2004", []string{"Apache-2.0"}},
+		{"Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/\n\nFiles: *\nLicense: GPL-3+\n\nFiles: lib/*\nLicense: LGPL-2.1+\n", []string{"GPL-3+", "LGPL-2.1+"}},
+		{"just some notes", nil},
+	}
+	for _, test := range cases {
+		if actual := detectLicenses([]byte(test.contents)); !reflect.DeepEqual(actual, test.expected) {
+			t.Errorf("Expected licenses %v got %v for %q", test.expected, actual, test.contents)
+		}
+	}
+}
+
+func TestLicenseFindings(t *testing.T) {
+	tree := NewFileTree()
+	tree.AddPath("/app/LICENSE", FileInfo{Licenses: []string{"MIT"}})
+	tree.AddPath("/app/vendor/LICENSE", FileInfo{Licenses: []string{"MIT"}})
+	tree.AddPath("/usr/share/doc/bash/copyright", FileInfo{Licenses: []string{"GPL-3+"}})
+	tree.AddPath("/app/main.go", FileInfo{})
+
+	findings := LicenseFindings(tree)
+	if len(findings) != 3 {
+		t.Fatalf("Expected 3 findings, got %d: %+v", len(findings), findings)
+	}
+
+	summary := LicenseSummary(findings)
+	expected := map[string]int{"MIT": 2, "GPL-3+": 1}
+	if !reflect.DeepEqual(summary, expected) {
+		t.Errorf("Expected summary %v got %v", expected, summary)
+	}
+}
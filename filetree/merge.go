@@ -0,0 +1,84 @@
+package filetree
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ConflictPolicy decides which version of a path is kept when two merged trees provide it differently.
+type ConflictPolicy int
+
+const (
+	// KeepOurs keeps the node of the owning tree.
+	KeepOurs ConflictPolicy = iota
+	// KeepTheirs replaces the node of the owning tree with the node of the merged tree.
+	KeepTheirs
+	// FailOnConflict stops merging at the first conflict, returning an error.
+	FailOnConflict
+)
+
+// MergeConflict is a path that two merged trees provide differently (e.g. a different size, mode or owner), as judged
+// by the Comparer of the owning tree.
+type MergeConflict struct {
+	Path    string
+	Ours    FileInfo
+	Theirs  FileInfo
+	Changes []AttributeChange
+}
+
+// isDir indicates if the given node is a directory (by its metadata or by having children).
+func isDir(node *FileNode) bool {
+	return !node.IsLeaf() || node.Data.FileInfo.TarHeader.FileInfo().IsDir()
+}
+
+// Merge adds every path of the given tree (e.g. the final tree of an independently built image) to the owning tree,
+// returning the paths both trees provide differently (in path order). The given policy decides which version of a
+// conflicting path is kept; when a file and a directory conflict, the contents of the directory only remain when the
+// directory is kept. Whiteouts of the given tree are not merged.
+func (tree *FileTree) Merge(other *FileTree, policy ConflictPolicy) ([]MergeConflict, error) {
+	var conflicts []MergeConflict
+	// skipped notes the nodes of the given tree whose descendants are not merged
+	skipped := make(map[*FileNode]bool)
+
+	merge := func(theirs *FileNode) error {
+		nodePath := theirs.Path()
+		ours, err := tree.GetNode(nodePath)
+		if err != nil {
+			_, err = tree.AddPath(nodePath, theirs.Data.FileInfo)
+			return err
+		}
+
+		changes := tree.comparer().Changes(ours.Data.FileInfo, theirs.Data.FileInfo)
+		if len(changes) == 0 {
+			return nil
+		}
+		conflicts = append(conflicts, MergeConflict{Path: nodePath, Ours: ours.Data.FileInfo, Theirs: theirs.Data.FileInfo, Changes: changes})
+
+		switch policy {
+		case FailOnConflict:
+			return fmt.Errorf("conflicting path %s", nodePath)
+		case KeepTheirs:
+			ours.Data.FileInfo = *theirs.Data.FileInfo.Copy()
+			ours.invalidateSize()
+			if !isDir(theirs) {
+				for name := range ours.Children {
+					ours.removeChild(name)
+				}
+			}
+		default:
+			if !isDir(ours) {
+				skipped[theirs] = true
+			}
+		}
+		return nil
+	}
+	evaluator := func(theirs *FileNode) bool {
+		return !theirs.IsWhiteout() && !skipped[theirs.Parent]
+	}
+
+	err := other.VisitDepthParentFirst(merge, evaluator)
+	sort.Slice(conflicts, func(i, j int) bool {
+		return conflicts[i].Path < conflicts[j].Path
+	})
+	return conflicts, err
+}
@@ -0,0 +1,70 @@
+package filetree
+
+import (
+	"archive/tar"
+	"testing"
+)
+
+func mergeFixtures() (*FileTree, *FileTree) {
+	ours := NewFileTree()
+	ours.AddPath("/etc/hosts", fileOfSize("/etc/hosts", 100))
+	ours.AddPath("/etc/motd", fileOfSize("/etc/motd", 10))
+	ours.AddPath("/opt/app", fileOfSize("/opt/app", 5))
+
+	theirs := NewFileTree()
+	theirs.AddPath("/etc/hosts", fileOfSize("/etc/hosts", 200))
+	theirs.AddPath("/etc/motd", fileOfSize("/etc/motd", 10))
+	theirs.AddPath("/etc/passwd", fileOfSize("/etc/passwd", 50))
+	theirs.AddPath("/opt/app", FileInfo{TypeFlag: tar.TypeDir, TarHeader: tar.Header{Typeflag: tar.TypeDir, Mode: 0755}})
+	theirs.AddPath("/opt/app/bin", fileOfSize("/opt/app/bin", 1000))
+	return ours, theirs
+}
+
+func TestMerge(t *testing.T) {
+	ours, theirs := mergeFixtures()
+	conflicts, err := ours.Merge(theirs, KeepOurs)
+	if err != nil {
+		t.Fatalf("could not merge: %v", err)
+	}
+	if len(conflicts) != 2 || conflicts[0].Path != "/etc/hosts" || conflicts[1].Path != "/opt/app" {
+		t.Fatalf("Expected /etc/hosts and /opt/app to conflict, got %+v", conflicts)
+	}
+	if conflicts[0].Changes[0].Attribute != "size" || conflicts[0].Ours.Size() != 100 || conflicts[0].Theirs.Size() != 200 {
+		t.Errorf("Expected a size conflict of /etc/hosts, got %+v", conflicts[0])
+	}
+
+	if node, _ := ours.GetNode("/etc/hosts"); node == nil || node.Data.FileInfo.Size() != 100 {
+		t.Errorf("Expected our /etc/hosts to be kept, got %v", node)
+	}
+	if _, err := ours.GetNode("/etc/passwd"); err != nil {
+		t.Errorf("Expected /etc/passwd to be merged: %v", err)
+	}
+	if _, err := ours.GetNode("/opt/app/bin"); err == nil {
+		t.Errorf("Expected the contents of their /opt/app not to be merged beneath our file")
+	}
+}
+
+func TestMergeKeepTheirs(t *testing.T) {
+	ours, theirs := mergeFixtures()
+	conflicts, err := ours.Merge(theirs, KeepTheirs)
+	if err != nil || len(conflicts) != 2 {
+		t.Fatalf("Expected 2 conflicts, got %+v (%v)", conflicts, err)
+	}
+	if node, _ := ours.GetNode("/etc/hosts"); node == nil || node.Data.FileInfo.Size() != 200 {
+		t.Errorf("Expected their /etc/hosts to be kept, got %v", node)
+	}
+	if _, err := ours.GetNode("/opt/app/bin"); err != nil {
+		t.Errorf("Expected their /opt/app to replace our file: %v", err)
+	}
+	if ours.Root.AggregateSize() != 1260 {
+		t.Errorf("Expected the merged tree to be 1260 bytes, got %d", ours.Root.AggregateSize())
+	}
+}
+
+func TestMergeFailOnConflict(t *testing.T) {
+	ours, theirs := mergeFixtures()
+	conflicts, err := ours.Merge(theirs, FailOnConflict)
+	if err == nil || len(conflicts) != 1 || conflicts[0].Path != "/etc/hosts" {
+		t.Errorf("Expected to fail at /etc/hosts, got %+v (%v)", conflicts, err)
+	}
+}
@@ -12,7 +12,9 @@ import (
 )
 
 const (
-	AttributeFormat = "%s%s %10s %10s "
+	AttributeFormat = "%s%s%s %10s %10s "
+	// CompactAttributeFormat is the AttributeFormat of narrow terminals, which only shows the size
+	CompactAttributeFormat = "%10s "
 )
 
 var diffTypeColor = map[DiffType]*color.Color{
@@ -29,7 +31,16 @@ type FileNode struct {
 	Name     string
 	Data     NodeData
 	Children map[string]*FileNode
-	path     string
+	// Opaque indicates the directory hides everything lower layers placed in it (marked by an opaque whiteout file)
+	Opaque bool
+	path   string
+	// aggregateSize is the cached cumulative size of this node and its (non-removed) descendants, valid when sized
+	aggregateSize int64
+	sized         bool
+	// rows is the cached number of rows the visible descendants of this node take in the rendered tree, valid when
+	// counted (see CountRows)
+	rows    int
+	counted bool
 }
 
 // NewNode creates a new FileNode relative to the given parent node with a payload.
@@ -48,74 +59,72 @@ func NewNode(parent *FileNode, name string, data FileInfo) (node *FileNode) {
 	return node
 }
 
-// renderTreeLine returns a string representing this FileNode in the context of a greater ASCII tree.
-func (node *FileNode) renderTreeLine(spaces []bool, last bool, collapsed bool) string {
-	var otherBranches string
-	for _, space := range spaces {
-		if space {
-			otherBranches += noBranchSpace
-		} else {
-			otherBranches += branchSpace
-		}
-	}
-
-	thisBranch := middleItem
-	if last {
-		thisBranch = lastItem
-	}
-
-	collapsedIndicator := uncollapsedItem
-	if collapsed {
-		collapsedIndicator = collapsedItem
-	}
-
-	return otherBranches + thisBranch + collapsedIndicator + node.String() + newLine
-}
-
 // Copy duplicates the existing node relative to a new parent node.
 func (node *FileNode) Copy(parent *FileNode) *FileNode {
 	newNode := NewNode(parent, node.Name, node.Data.FileInfo)
 	newNode.Data.ViewInfo = node.Data.ViewInfo
 	newNode.Data.DiffType = node.Data.DiffType
+	newNode.Data.Changes = node.Data.Changes
+	newNode.Opaque = node.Opaque
+	newNode.aggregateSize = node.aggregateSize
+	newNode.sized = node.sized
 	for name, child := range node.Children {
 		newNode.Children[name] = child.Copy(newNode)
-		child.Parent = newNode
 	}
 	return newNode
 }
 
 // AddChild creates a new node relative to the current FileNode.
 func (node *FileNode) AddChild(name string, data FileInfo) (child *FileNode) {
-	// never allow processing of purely whiteout flag files (for now)
+	// never allow processing of purely whiteout flag files (opaque directories are marked by AddPath instead)
 	if strings.HasPrefix(name, doubleWhiteoutPrefix) {
 		return nil
 	}
 
 	child = NewNode(node, name, data)
-	if node.Children[name] != nil {
+	if existing := node.ownChild(name); existing != nil {
 		// tree node already exists, replace the payload, keep the children
-		node.Children[name].Data.FileInfo = *data.Copy()
+		existing.Data.FileInfo = *data.Copy()
+		existing.invalidateSize()
 	} else {
+		node.invalidateSize()
+		node.invalidateRows()
 		node.Children[name] = child
 		node.Tree.Size++
+		node.Tree.indexSubtree(child)
 	}
 
 	return child
 }
 
-// Remove deletes the current FileNode from it's parent FileNode's relations.
+// Remove deletes the current FileNode from it's parent FileNode's relations. A node of a tree that lent its nodes to
+// other trees (see cow.go) is not removed, since it may be reached from those trees as well (e.g. a node found beneath
+// a node of a stacked tree): nodes are removed from a tree that shares them with RemovePath, which copies the path to
+// the node into the tree first.
 func (node *FileNode) Remove() error {
-	if node == node.Tree.Root {
+	if node == node.Tree.Root || node.Parent == nil {
 		return fmt.Errorf("cannot remove the tree root")
 	}
-	for _, child := range node.Children {
-		child.Remove()
+	if node.Tree.lent {
+		return fmt.Errorf("cannot remove %s: the node is shared with other trees", node.Path())
 	}
-	delete(node.Parent.Children, node.Name)
-	node.Tree.Size--
+	node.Parent.removeChild(node.Name)
 	return nil
 }
 
+// removeChild deletes the child of the given name (and everything beneath it) from the current FileNode.
+func (node *FileNode) removeChild(name string) {
+	child := node.Children[name]
+	if child == nil {
+		return
+	}
+	delete(node.Children, name)
+	node.invalidateSize()
+	node.invalidateRows()
+	node.Tree.Size -= child.count()
+	node.Tree.unindexSubtree(child)
+}
+
 // String shows the filename formatted into the proper color (by DiffType), additionally indicating if it is a symlink.
 func (node *FileNode) String() string {
 	var display string
@@ -124,103 +133,147 @@ func (node *FileNode) String() string {
 	}
 
 	display = node.Name
-	if node.Data.FileInfo.TarHeader.Typeflag == tar.TypeSymlink || node.Data.FileInfo.TarHeader.Typeflag == tar.TypeLink {
-		display += " → " + node.Data.FileInfo.TarHeader.Linkname
+	if linkTarget := node.linkTarget(); linkTarget != "" {
+		display += DefaultGlyphs.Link + linkTarget
 	}
 	return diffTypeColor[node.Data.DiffType].Sprint(display)
 }
 
-// MetadatString returns the FileNode metadata in a columnar string.
+// linkTarget returns the target of the symlink or hardlink this FileNode represents (empty for all other files).
+func (node *FileNode) linkTarget() string {
+	if node.Data.FileInfo.TarHeader.Typeflag == tar.TypeSymlink || node.Data.FileInfo.TarHeader.Typeflag == tar.TypeLink {
+		return node.Data.FileInfo.TarHeader.Linkname
+	}
+	return ""
+}
+
+// MetadatString returns the FileNode metadata in a columnar string, followed by the xattrs of the file (if any, see
+// FileInfo.XattrsString).
 func (node *FileNode) MetadataString() string {
 	if node == nil {
 		return ""
 	}
+	return diffTypeColor[node.Data.DiffType].Sprint(node.metadata() + node.Data.FileInfo.XattrsString())
+}
+
+// metadata returns the uncolored FileNode metadata in a columnar string.
+func (node *FileNode) metadata() string {
 
 	fileMode := permbits.FileMode(node.Data.FileInfo.TarHeader.FileInfo().Mode()).String()
 	dir := "-"
 	if node.Data.FileInfo.TarHeader.FileInfo().IsDir() {
 		dir = "d"
 	}
+	// similar to 'ls -l', indicate additional security attributes (and any other xattrs) after the permission bits
+	security := " "
+	if node.Data.FileInfo.Capabilities() != "" {
+		security = "+"
+	} else if node.Data.FileInfo.SELinuxLabel() != "" {
+		security = "."
+	} else if len(node.Data.FileInfo.Xattrs) > 0 {
+		security = "@"
+	}
 	user := node.Data.FileInfo.TarHeader.Uid
 	group := node.Data.FileInfo.TarHeader.Gid
 	userGroup := fmt.Sprintf("%d:%d", user, group)
+	if accounts != nil {
+		userGroup = accounts.Owner(user, group)
+	}
 
-	var sizeBytes int64
-
-	if node.Data.FileInfo.TarHeader.FileInfo().IsDir() {
+	return fmt.Sprintf(AttributeFormat, dir, fileMode, security, userGroup, node.displaySize())
+}
 
-		sizer := func(curNode *FileNode) error {
-			if curNode.Data.DiffType != Removed {
-				sizeBytes += curNode.Data.FileInfo.TarHeader.FileInfo().Size()
-			}
-			return nil
-		}
+// compactMetadata returns the uncolored FileNode metadata of narrow terminals (see CompactAttributeFormat).
+func (node *FileNode) compactMetadata() string {
+	return fmt.Sprintf(CompactAttributeFormat, node.displaySize())
+}
 
-		node.VisitDepthChildFirst(sizer, nil)
+// displaySize returns the size shown for the FileNode: the size of a file, or the aggregate size of a directory.
+func (node *FileNode) displaySize() string {
+	var sizeBytes int64
+	if node.Data.FileInfo.TarHeader.FileInfo().IsDir() {
+		sizeBytes = node.AggregateSize()
 	} else {
-		sizeBytes = node.Data.FileInfo.TarHeader.FileInfo().Size()
+		sizeBytes = node.Data.FileInfo.Size()
 	}
-
-	size := humanize.Bytes(uint64(sizeBytes))
-
-	return diffTypeColor[node.Data.DiffType].Sprint(fmt.Sprintf(AttributeFormat, dir, fileMode, userGroup, size))
+	return humanize.Bytes(uint64(sizeBytes))
 }
 
-// VisitDepthChildFirst iterates a tree depth-first (starting at this FileNode), evaluating the deepest depths first (visit on bubble up)
-func (node *FileNode) VisitDepthChildFirst(visitor Visitor, evaluator VisitEvaluator) error {
-	var keys []string
+// sortedChildNames returns the names of the children of this FileNode in the order they should be visited.
+func (node *FileNode) sortedChildNames() []string {
+	keys := make([]string, 0, len(node.Children))
 	for key := range node.Children {
 		keys = append(keys, key)
 	}
 	sort.Strings(keys)
-	for _, name := range keys {
-		child := node.Children[name]
-		err := child.VisitDepthChildFirst(visitor, evaluator)
-		if err != nil {
-			return err
-		}
-	}
-	// never visit the root node
-	if node == node.Tree.Root {
-		return nil
-	} else if evaluator != nil && evaluator(node) || evaluator == nil {
-		return visitor(node)
+	return keys
+}
+
+// VisitDepthChildFirst iterates a tree depth-first (starting at this FileNode), evaluating the deepest depths first (visit on bubble up)
+func (node *FileNode) VisitDepthChildFirst(visitor Visitor, evaluator VisitEvaluator) error {
+	// the traversal is iterative (not recursive) to support arbitrarily deep trees
+	type frame struct {
+		node  *FileNode
+		keys  []string
+		index int
 	}
 
+	stack := []*frame{{node: node, keys: node.sortedChildNames()}}
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		if top.index < len(top.keys) {
+			child, exists := top.node.Children[top.keys[top.index]]
+			top.index++
+			if exists {
+				stack = append(stack, &frame{node: child, keys: child.sortedChildNames()})
+			}
+			continue
+		}
+		stack = stack[:len(stack)-1]
+
+		// never visit the root node
+		if top.node == top.node.Tree.Root {
+			continue
+		} else if evaluator != nil && evaluator(top.node) || evaluator == nil {
+			err := visitor(top.node)
+			if err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
 // VisitDepthParentFirst iterates a tree depth-first (starting at this FileNode), evaluating the shallowest depths first (visit while sinking down)
 func (node *FileNode) VisitDepthParentFirst(visitor Visitor, evaluator VisitEvaluator) error {
-	var err error
-
-	doVisit := evaluator != nil && evaluator(node) || evaluator == nil
-
-	if !doVisit {
-		return nil
-	}
+	// the traversal is iterative (not recursive) to support arbitrarily deep trees
+	stack := []*FileNode{node}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		doVisit := evaluator != nil && evaluator(current) || evaluator == nil
+		if !doVisit {
+			continue
+		}
 
-	// never visit the root node
-	if node != node.Tree.Root {
-		err = visitor(node)
-		if err != nil {
-			return err
+		// never visit the root node
+		if current != current.Tree.Root {
+			err := visitor(current)
+			if err != nil {
+				return err
+			}
 		}
-	}
 
-	var keys []string
-	for key := range node.Children {
-		keys = append(keys, key)
-	}
-	sort.Strings(keys)
-	for _, name := range keys {
-		child := node.Children[name]
-		err = child.VisitDepthParentFirst(visitor, evaluator)
-		if err != nil {
-			return err
+		// push the children in reverse order such that they are visited in order
+		keys := current.sortedChildNames()
+		for idx := len(keys) - 1; idx >= 0; idx-- {
+			if child, exists := current.Children[keys[idx]]; exists {
+				stack = append(stack, child)
+			}
 		}
 	}
-	return err
+	return nil
 }
 
 // IsWhiteout returns an indication if this file may be a overlay-whiteout file.
@@ -228,6 +281,16 @@ func (node *FileNode) IsWhiteout() bool {
 	return strings.HasPrefix(node.Name, whiteoutPrefix)
 }
 
+// beneathOpaque indicates if any parent directory of the node is opaque, hiding the paths of lower layers beneath it.
+func (node *FileNode) beneathOpaque() bool {
+	for parent := node.Parent; parent != nil; parent = parent.Parent {
+		if parent.Opaque {
+			return true
+		}
+	}
+	return false
+}
+
 // IsLeaf returns true is the current node has no child nodes.
 func (node *FileNode) IsLeaf() bool {
 	return len(node.Children) == 0
@@ -236,10 +299,13 @@ func (node *FileNode) IsLeaf() bool {
 // Path returns a slash-delimited string from the root of the greater tree to the current node (e.g. /a/path/to/here)
 func (node *FileNode) Path() string {
 	if node.path == "" {
-		var path []string
-		curNode := node
-		for {
-			if curNode.Parent == nil {
+		// collect the names up to the root (or the closest ancestor with a known path) and join them in reverse, which
+		// keeps this linear in the depth of the node
+		var names []string
+		var prefix string
+		for curNode := node; curNode.Parent != nil; curNode = curNode.Parent {
+			if curNode != node && curNode.path != "" && !strings.HasPrefix(curNode.Name, whiteoutPrefix) {
+				prefix = curNode.path
 				break
 			}
 
@@ -248,11 +314,13 @@ func (node *FileNode) Path() string {
 				// white out prefixes are fictitious on leaf nodes
 				name = strings.TrimPrefix(name, whiteoutPrefix)
 			}
+			names = append(names, name)
+		}
 
-			path = append([]string{name}, path...)
-			curNode = curNode.Parent
+		for left, right := 0, len(names)-1; left < right; left, right = left+1, right-1 {
+			names[left], names[right] = names[right], names[left]
 		}
-		node.path = "/" + strings.Join(path, "/")
+		node.path = strings.TrimSuffix(prefix, "/") + "/" + strings.Join(names, "/")
 	}
 	return node.path
 }
@@ -282,12 +350,15 @@ func (node *FileNode) AssignDiffType(diffType DiffType) error {
 		return nil
 	}
 
+	if node.Data.DiffType != diffType {
+		node.invalidateSize()
+	}
 	node.Data.DiffType = diffType
 
 	// if we've removed this node, then all children have been removed as well
 	if diffType == Removed {
-		for _, child := range node.Children {
-			err = child.AssignDiffType(diffType)
+		for name := range node.Children {
+			err = node.ownChild(name).AssignDiffType(diffType)
 			if err != nil {
 				return err
 			}
@@ -318,5 +389,5 @@ func (node *FileNode) compare(other *FileNode) DiffType {
 	}
 	// TODO: fails on nil
 
-	return node.Data.FileInfo.Compare(other.Data.FileInfo)
+	return compareInfo(node.Tree.comparer(), node.Data.FileInfo, other.Data.FileInfo)
 }
@@ -1,6 +1,7 @@
 package filetree
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -105,8 +106,8 @@ func TestIsWhiteout(t *testing.T) {
 		t.Errorf("Expected path '%s' to be a whiteout file", p2.Name)
 	}
 
-	if p3 != nil {
-		t.Errorf("Expected to not be able to add path '%s'", p2.Name)
+	if p3 == nil || p3.Name != "public3" || !p3.Opaque || p3.IsWhiteout() {
+		t.Errorf("Expected the opaque whiteout to mark its directory opaque, got %v", p3)
 	}
 }
 
@@ -148,3 +149,59 @@ func TestDiffTypeFromRemovedChildren(t *testing.T) {
 	}
 
 }
+
+func TestVisitDeepTree(t *testing.T) {
+	depth := 10000
+	tree := NewFileTree()
+	deepest, err := tree.AddPath(strings.Repeat("/d", depth), FileInfo{})
+	if err != nil {
+		t.Fatalf("could not setup test: %v", err)
+	}
+
+	var parentFirst, childFirst []*FileNode
+	tree.VisitDepthParentFirst(func(node *FileNode) error {
+		parentFirst = append(parentFirst, node)
+		return nil
+	}, nil)
+	tree.VisitDepthChildFirst(func(node *FileNode) error {
+		childFirst = append(childFirst, node)
+		return nil
+	}, nil)
+
+	if len(parentFirst) != depth || len(childFirst) != depth {
+		t.Fatalf("Expected to visit %d nodes, got %d (parent first) and %d (child first)", depth, len(parentFirst), len(childFirst))
+	}
+	if parentFirst[depth-1] != deepest || childFirst[0] != deepest {
+		t.Errorf("Expected the deepest node to be visited last (parent first) and first (child first)")
+	}
+	if expected := strings.Repeat("/d", depth); deepest.Path() != expected {
+		t.Errorf("Unexpected path of the deepest node (length %d)", len(deepest.Path()))
+	}
+	if expected := strings.Repeat("/d", depth-1); deepest.Parent.Path() != expected {
+		t.Errorf("Unexpected path of the parent of the deepest node (length %d)", len(deepest.Parent.Path()))
+	}
+}
+
+func TestVisitOrder(t *testing.T) {
+	tree := NewFileTree()
+	tree.AddPath("/b/2", FileInfo{})
+	tree.AddPath("/a", FileInfo{})
+	tree.AddPath("/b/1", FileInfo{})
+
+	var parentFirst, childFirst []string
+	tree.VisitDepthParentFirst(func(node *FileNode) error {
+		parentFirst = append(parentFirst, node.Path())
+		return nil
+	}, nil)
+	tree.VisitDepthChildFirst(func(node *FileNode) error {
+		childFirst = append(childFirst, node.Path())
+		return nil
+	}, nil)
+
+	if expected := []string{"/a", "/b", "/b/1", "/b/2"}; strings.Join(parentFirst, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected parent first order %v got %v", expected, parentFirst)
+	}
+	if expected := []string{"/a", "/b/1", "/b/2", "/b"}; strings.Join(childFirst, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected child first order %v got %v", expected, childFirst)
+	}
+}
@@ -0,0 +1,131 @@
+package filetree
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Unowned is the owner that paths matching no ownership rule are attributed to.
+const Unowned = "(unowned)"
+
+// ownerRule assigns the owners of every path matching the pattern.
+type ownerRule struct {
+	pattern string
+	owners  string
+}
+
+// Owners maps paths to their owners (teams or people) with CODEOWNERS-style rules, where the last matching rule wins.
+type Owners struct {
+	rules []ownerRule
+}
+
+// OwnerSize is the share of an image attributed to a single owner.
+type OwnerSize struct {
+	Owner string
+	// Files and Size are the files (and their bytes) in the final image
+	Files int
+	Size  uint64
+	// LayerSize is the bytes added by all layers, including files later overwritten or removed
+	LayerSize uint64
+	// WastedSize is the bytes of files that are duplicated, overwritten or removed across layers
+	WastedSize uint64
+}
+
+// ReadOwners parses CODEOWNERS-style rules, one per line: a path pattern followed by one or more owners, e.g.
+//
+//	# the platform team owns everything not owned by anyone else
+//	*                @platform
+//	/usr/lib/python3 @data-team
+//	*.so             @native-team
+//
+// A pattern starting with "/" is relative to the image root, any other pattern matches at any depth. A pattern
+// matching a directory also matches everything within it.
+func ReadOwners(reader io.Reader) (*Owners, error) {
+	owners := &Owners{}
+	scanner := bufio.NewScanner(reader)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("line %d: expected a pattern followed by owners", lineNumber)
+		}
+		owners.rules = append(owners.rules, ownerRule{pattern: fields[0], owners: strings.Join(fields[1:], " ")})
+	}
+	return owners, scanner.Err()
+}
+
+// matchOwnerPattern indicates if the given CODEOWNERS-style pattern matches the given absolute path.
+func matchOwnerPattern(pattern, path string) bool {
+	pattern = strings.TrimSuffix(pattern, "/")
+	if strings.HasPrefix(pattern, "/") {
+		pattern = strings.TrimPrefix(pattern, "/")
+	} else {
+		pattern = "**/" + pattern
+	}
+	name := strings.TrimPrefix(path, "/")
+	return globMatch(pattern, name) || globMatch(pattern+"/**", name)
+}
+
+// Owner returns the owners of the given path (as written in the matching rule), or Unowned if no rule matches.
+func (owners *Owners) Owner(path string) string {
+	for idx := len(owners.rules) - 1; idx >= 0; idx-- {
+		if matchOwnerPattern(owners.rules[idx].pattern, path) {
+			return owners.rules[idx].owners
+		}
+	}
+	return Unowned
+}
+
+// OwnerSizes attributes the bytes of an image (given as its layer trees, oldest first, and its inefficiencies) to the
+// owners of each file, largest share of the final image first.
+func OwnerSizes(trees []*FileTree, inefficiencies EfficiencySlice, owners *Owners) []OwnerSize {
+	sizes := make(map[string]*OwnerSize)
+	get := func(path string) *OwnerSize {
+		owner := owners.Owner(path)
+		if _, ok := sizes[owner]; !ok {
+			sizes[owner] = &OwnerSize{Owner: owner}
+		}
+		return sizes[owner]
+	}
+
+	for filePath, layer := range providers(trees) {
+		node, err := trees[layer].GetNode(filePath)
+		if err != nil {
+			continue
+		}
+		size := get(filePath)
+		size.Files++
+		size.Size += uint64(node.Data.FileInfo.TarHeader.Size)
+	}
+	for _, tree := range trees {
+		tree.VisitDepthParentFirst(func(node *FileNode) error {
+			if node.IsLeaf() && !node.IsWhiteout() && !node.Data.FileInfo.TarHeader.FileInfo().IsDir() {
+				get(node.Path()).LayerSize += uint64(node.Data.FileInfo.TarHeader.Size)
+			}
+			return nil
+		}, nil)
+	}
+	for _, data := range inefficiencies {
+		if len(data.Nodes) > 1 {
+			get(data.Path).WastedSize += uint64(data.CumulativeSize)
+		}
+	}
+
+	var result []OwnerSize
+	for _, size := range sizes {
+		result = append(result, *size)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Size != result[j].Size {
+			return result[i].Size > result[j].Size
+		}
+		return result[i].Owner < result[j].Owner
+	})
+	return result
+}
@@ -0,0 +1,73 @@
+package filetree
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOwner(t *testing.T) {
+	owners, err := ReadOwners(strings.NewReader(`
+# everything else
+*                 @platform
+/usr/lib/python3  @data
+*.so              @native
+/opt/             @vendor @platform
+`))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+
+	cases := map[string]string{
+		"/etc/hosts":                          "@platform",
+		"/usr/lib/python3/site.py":            "@data",
+		"/usr/lib/python3/_ssl.so":            "@native",
+		"/usr/local/usr/lib/python3/x.py":     "@platform",
+		"/opt/tool/bin/tool":                  "@vendor @platform",
+		"/opt/tool/lib/libx.so":               "@vendor @platform",
+		"/usr/lib/x86_64-linux-gnu/libssl.so": "@native",
+	}
+	for path, expected := range cases {
+		if actual := owners.Owner(path); actual != expected {
+			t.Errorf("Owner(%q): expected %q, got %q", path, expected, actual)
+		}
+	}
+
+	empty, _ := ReadOwners(strings.NewReader(""))
+	if owner := empty.Owner("/etc/hosts"); owner != Unowned {
+		t.Errorf("Expected %q, got %q", Unowned, owner)
+	}
+
+	if _, err := ReadOwners(strings.NewReader("/etc\n")); err == nil {
+		t.Errorf("Expected an error for a rule without owners")
+	}
+}
+
+func TestOwnerSizes(t *testing.T) {
+	owners, _ := ReadOwners(strings.NewReader("/app @app\n/usr @base\n"))
+
+	base := NewFileTree()
+	base.AddPath("/usr/bin/tool", fileOfSize("/usr/bin/tool", 100))
+	base.AddPath("/app/big", fileOfSize("/app/big", 1000))
+	app := NewFileTree()
+	app.AddPath("/app/.wh.big", FileInfo{})
+	app.AddPath("/app/main", fileOfSize("/app/main", 50))
+	app.AddPath("/etc/hosts", fileOfSize("/etc/hosts", 5))
+
+	sizes := OwnerSizes([]*FileTree{base, app}, nil, owners)
+	expected := map[string]OwnerSize{
+		"@base": {Owner: "@base", Files: 1, Size: 100, LayerSize: 100},
+		"@app":  {Owner: "@app", Files: 1, Size: 50, LayerSize: 1050},
+		Unowned: {Owner: Unowned, Files: 1, Size: 5, LayerSize: 5},
+	}
+	if len(sizes) != len(expected) {
+		t.Fatalf("Expected %d owners, got %+v", len(expected), sizes)
+	}
+	if sizes[0].Owner != "@base" {
+		t.Errorf("Expected the largest owner first, got %+v", sizes)
+	}
+	for _, size := range sizes {
+		if size != expected[size.Owner] {
+			t.Errorf("Expected %+v, got %+v", expected[size.Owner], size)
+		}
+	}
+}
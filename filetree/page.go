@@ -0,0 +1,88 @@
+package filetree
+
+// DirectoryPageSize is the number of children of a single directory that are shown at once. Directories with more
+// children (e.g. caches with tens of thousands of files) are paginated, which keeps the rendered tree small.
+var DirectoryPageSize = 500
+
+// Paginate hides the visible children of each directory that are not on the directory's current page (see
+// ViewInfo.Page), when a directory has more visible children than fit a page (DirectoryPageSize). It returns the
+// number of paginated directories. It only ever hides nodes, so it should be applied after all other visibility rules
+// (such as FilterNodes).
+func (tree *FileTree) Paginate() int {
+	var paginated int
+	paginate := func(node *FileNode) error {
+		node.paginate(DirectoryPageSize)
+		if node.Data.ViewInfo.PagedChildren > 0 {
+			paginated++
+		}
+		return nil
+	}
+	// the root is never visited
+	paginate(tree.Root)
+	tree.VisitDepthParentFirst(paginate, nil)
+	return paginated
+}
+
+// paginate hides the visible children of this node that are not on its current page.
+func (node *FileNode) paginate(pageSize int) {
+	view := &node.Data.ViewInfo
+	view.PagedChildren = 0
+	if pageSize <= 0 || len(node.Children) <= pageSize || view.Hidden {
+		return
+	}
+
+	var visible []*FileNode
+	for _, name := range node.sortedChildNames() {
+		if child := node.Children[name]; !child.Data.ViewInfo.Hidden {
+			visible = append(visible, child)
+		}
+	}
+	if len(visible) <= pageSize {
+		return
+	}
+
+	pages := (len(visible) + pageSize - 1) / pageSize
+	if view.Page >= pages {
+		view.Page = pages - 1
+	}
+	if view.Page < 0 {
+		view.Page = 0
+	}
+	view.PagedChildren = len(visible)
+	for idx, child := range visible {
+		if idx/pageSize != view.Page {
+			child.Data.ViewInfo.Hidden = true
+		}
+	}
+}
+
+// PageRange returns the (1-based, inclusive) range of children shown for a paginated directory, of the total number
+// of visible children, or false if the directory is not paginated.
+func (node *FileNode) PageRange() (first, last, total int, paged bool) {
+	view := node.Data.ViewInfo
+	if view.PagedChildren == 0 {
+		return 0, 0, 0, false
+	}
+	first = view.Page*DirectoryPageSize + 1
+	last = first + DirectoryPageSize - 1
+	if last > view.PagedChildren {
+		last = view.PagedChildren
+	}
+	return first, last, view.PagedChildren, true
+}
+
+// TurnPage moves a paginated directory to the next (or, with a negative delta, a previous) page, returning false if
+// there is no such page. The children are only shown (or hidden) on the next Paginate.
+func (node *FileNode) TurnPage(delta int) bool {
+	view := &node.Data.ViewInfo
+	if view.PagedChildren == 0 {
+		return false
+	}
+	pages := (view.PagedChildren + DirectoryPageSize - 1) / DirectoryPageSize
+	page := view.Page + delta
+	if page < 0 || page >= pages {
+		return false
+	}
+	view.Page = page
+	return true
+}
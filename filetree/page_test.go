@@ -0,0 +1,81 @@
+package filetree
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	defer func(size int) { DirectoryPageSize = size }(DirectoryPageSize)
+	DirectoryPageSize = 2
+
+	tree := NewFileTree()
+	for idx := 0; idx < 5; idx++ {
+		tree.AddPath(fmt.Sprintf("/cache/%d", idx), FileInfo{})
+	}
+	tree.AddPath("/etc/hosts", FileInfo{})
+	tree.AddPath("/etc/motd", FileInfo{})
+
+	visibleChildren := func(path string) []string {
+		node, _ := tree.GetNode(path)
+		var names []string
+		for _, name := range node.sortedChildNames() {
+			if !node.Children[name].Data.ViewInfo.Hidden {
+				names = append(names, name)
+			}
+		}
+		return names
+	}
+
+	if count := tree.Paginate(); count != 1 {
+		t.Errorf("Expected only /cache to be paginated, got %d directories", count)
+	}
+	cache, _ := tree.GetNode("/cache")
+	if actual := visibleChildren("/cache"); fmt.Sprint(actual) != "[0 1]" {
+		t.Errorf("Expected the first page to be shown, got %v", actual)
+	}
+	if actual := visibleChildren("/etc"); len(actual) != 2 {
+		t.Errorf("Expected /etc to be shown in full, got %v", actual)
+	}
+	if first, last, total, paged := cache.PageRange(); !paged || first != 1 || last != 2 || total != 5 {
+		t.Errorf("Expected the range 1-2 of 5, got %d-%d of %d (%v)", first, last, total, paged)
+	}
+	if !strings.Contains(tree.String(false), "cache (showing 1–2 of 5)") {
+		t.Errorf("Expected the page to be shown with the directory:\n%s", tree.String(false))
+	}
+
+	// turning to the last page (then past it) shows the remainder
+	for idx := 0; idx < 2; idx++ {
+		if !cache.TurnPage(1) {
+			t.Fatalf("Expected page %d to exist", idx+2)
+		}
+	}
+	if cache.TurnPage(1) {
+		t.Errorf("Expected no page after the last page")
+	}
+	resetHidden(tree)
+	tree.Paginate()
+	if actual := visibleChildren("/cache"); fmt.Sprint(actual) != "[4]" {
+		t.Errorf("Expected the last page to be shown, got %v", actual)
+	}
+	if first, last, _, _ := cache.PageRange(); first != 5 || last != 5 {
+		t.Errorf("Expected the range 5-5, got %d-%d", first, last)
+	}
+
+	// hidden children are not paged, and the page is kept within range
+	resetHidden(tree)
+	for _, name := range []string{"2", "3", "4"} {
+		cache.Children[name].Data.ViewInfo.Hidden = true
+	}
+	if count := tree.Paginate(); count != 0 {
+		t.Errorf("Expected no paginated directories, got %d", count)
+	}
+}
+
+func resetHidden(tree *FileTree) {
+	tree.VisitDepthParentFirst(func(node *FileNode) error {
+		node.Data.ViewInfo.Hidden = false
+		return nil
+	}, nil)
+}
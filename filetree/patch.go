@@ -0,0 +1,99 @@
+package filetree
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+// patchMarkers are the markers of the lines of FormatDiff by DiffType.
+var patchMarkers = map[DiffType]string{Added: "+", Removed: "-", Changed: "~"}
+
+// FormatDiff describes how the given complete (e.g. fully stacked) trees differ in a patch-like format, which reads
+// well in a terminal or a PR comment and is simple to parse. The trees are introduced by their names (as in a unified
+// diff), followed by one line per file (not directory) that was added, removed or modified in the upper tree, in path
+// order, and a summary. Each file line holds tab separated fields: the marker ("+" added, "-" removed, "~" modified),
+// the size change in bytes (signed), the path, and for modified files the attributes that changed:
+//
+//	--- alpine:3.18
+//	+++ alpine:3.19
+//	+	+1234	/etc/app.conf
+//	~	+10	/usr/bin/app	contents, mode 0644→0755
+//	# 1 added (+1.2 kB), 0 removed (+0 B), 1 modified (+10 B)
+//
+// Lines starting with "#" are comments. The trees are not modified.
+func FormatDiff(lower, upper *FileTree) (string, error) {
+	compared, err := CompareTrees(lower, upper)
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	fmt.Fprintf(&result, "--- %s\n", patchName(lower, "lower"))
+	fmt.Fprintf(&result, "+++ %s\n", patchName(upper, "upper"))
+
+	counts := make(map[DiffType]int)
+	deltas := make(map[DiffType]int64)
+	err = compared.ObserveDepthParentFirst(func(node *FileNode) error {
+		marker, ok := patchMarkers[node.Data.DiffType]
+		if !ok || !node.IsLeaf() || node.IsWhiteout() || node.Data.FileInfo.TarHeader.FileInfo().IsDir() {
+			return nil
+		}
+		size := node.Data.FileInfo.TarHeader.Size
+		var delta int64
+		var attributes []string
+		switch node.Data.DiffType {
+		case Added:
+			delta = size
+		case Removed:
+			delta = -size
+		case Changed:
+			other, err := upper.lookup(node.Path())
+			if err != nil {
+				// reached through a symlink of the upper tree (see CompareTree)
+				if other, err = upper.ResolvePath(node.Path()); err != nil {
+					return nil
+				}
+			}
+			delta = other.Data.FileInfo.TarHeader.Size - size
+			for _, change := range compared.comparer().Changes(node.Data.FileInfo, other.Data.FileInfo) {
+				attributes = append(attributes, change.String())
+			}
+		}
+		counts[node.Data.DiffType]++
+		deltas[node.Data.DiffType] += delta
+
+		fields := []string{marker, fmt.Sprintf("%+d", delta), node.Path()}
+		if len(attributes) > 0 {
+			fields = append(fields, strings.Join(attributes, ", "))
+		}
+		result.WriteString(strings.Join(fields, "\t") + "\n")
+		return nil
+	}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	fmt.Fprintf(&result, "# %d added (%s), %d removed (%s), %d modified (%s)\n",
+		counts[Added], signedBytes(deltas[Added]),
+		counts[Removed], signedBytes(deltas[Removed]),
+		counts[Changed], signedBytes(deltas[Changed]))
+	return result.String(), nil
+}
+
+// patchName is the name a tree is introduced by in FormatDiff, or the given fallback for an unnamed tree.
+func patchName(tree *FileTree, fallback string) string {
+	if tree.Name == "" {
+		return fallback
+	}
+	return tree.Name
+}
+
+// signedBytes shows a size change with an explicit sign.
+func signedBytes(delta int64) string {
+	if delta < 0 {
+		return "-" + humanize.Bytes(uint64(-delta))
+	}
+	return "+" + humanize.Bytes(uint64(delta))
+}
@@ -0,0 +1,42 @@
+package filetree
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+)
+
+func TestFormatDiff(t *testing.T) {
+	infoOf := func(path, content string, mode int64) FileInfo {
+		header := &tar.Header{Name: path, Typeflag: tar.TypeReg, Mode: mode, Size: int64(len(content))}
+		return fileInfoOf(t, bytes.NewBufferString(content), header, path)
+	}
+
+	lower := NewFileTree()
+	lower.Name = "app:1"
+	lower.AddPath("/usr/bin/app", infoOf("/usr/bin/app", "binary", 0644))
+	lower.AddPath("/etc/unchanged", infoOf("/etc/unchanged", "same", 0644))
+	lower.AddPath("/var/old", infoOf("/var/old", "12345", 0644))
+	upper := NewFileTree()
+	upper.AddPath("/usr/bin/app", infoOf("/usr/bin/app", "binary v2", 0755))
+	upper.AddPath("/etc/unchanged", infoOf("/etc/unchanged", "same", 0644))
+	upper.AddPath("/etc/app.conf", infoOf("/etc/app.conf", "port=80", 0644))
+
+	actual, err := FormatDiff(lower, upper)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := "--- app:1\n" +
+		"+++ upper\n" +
+		"+\t+7\t/etc/app.conf\n" +
+		"~\t+3\t/usr/bin/app\tsize 6 B→9 B, mode 0644→0755\n" +
+		"-\t-5\t/var/old\n" +
+		"# 1 added (+7 B), 1 removed (-5 B), 1 modified (+3 B)\n"
+	if actual != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, actual)
+	}
+
+	if node, _ := lower.GetNode("/var/old"); node.Data.DiffType != Unchanged {
+		t.Errorf("Expected the lower tree not to be modified")
+	}
+}
@@ -0,0 +1,346 @@
+package filetree
+
+import (
+	"archive/tar"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/dustin/go-humanize"
+)
+
+// QueryFields are the node attributes that can be used within a query.
+var QueryFields = []string{"path", "name", "type", "diff", "size", "mode", "uid", "gid"}
+
+// queryToken is a single lexical element of a query.
+type queryToken struct {
+	text string
+	// quoted indicates the token was a string literal (and should never be treated as a keyword or operator)
+	quoted bool
+	pos    int
+}
+
+// lexQuery splits a query into words, string literals, operators and parentheses.
+func lexQuery(query string) ([]queryToken, error) {
+	var tokens []queryToken
+	runes := []rune(query)
+	for idx := 0; idx < len(runes); {
+		r := runes[idx]
+		switch {
+		case unicode.IsSpace(r):
+			idx++
+		case r == '(' || r == ')':
+			tokens = append(tokens, queryToken{text: string(r), pos: idx})
+			idx++
+		case r == '"':
+			start := idx
+			var value strings.Builder
+			for idx++; idx < len(runes) && runes[idx] != '"'; idx++ {
+				if runes[idx] == '\\' && idx+1 < len(runes) {
+					idx++
+				}
+				value.WriteRune(runes[idx])
+			}
+			if idx >= len(runes) {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			tokens = append(tokens, queryToken{text: value.String(), quoted: true, pos: start})
+			idx++
+		case strings.ContainsRune("=!<>~", r):
+			start := idx
+			idx++
+			if idx < len(runes) && runes[idx] == '=' {
+				idx++
+			}
+			tokens = append(tokens, queryToken{text: string(runes[start:idx]), pos: start})
+		default:
+			start := idx
+			for idx < len(runes) && !unicode.IsSpace(runes[idx]) && !strings.ContainsRune(`()"=!<>~`, runes[idx]) {
+				idx++
+			}
+			tokens = append(tokens, queryToken{text: string(runes[start:idx]), pos: start})
+		}
+	}
+	return tokens, nil
+}
+
+// queryParser builds an evaluator from query tokens by recursive descent:
+//
+//	expression := term { "or" term }
+//	term       := factor { "and" factor }
+//	factor     := "not" factor | "(" expression ")" | field operator value
+type queryParser struct {
+	tokens []queryToken
+	index  int
+}
+
+func (parser *queryParser) peek() (queryToken, bool) {
+	if parser.index >= len(parser.tokens) {
+		return queryToken{}, false
+	}
+	return parser.tokens[parser.index], true
+}
+
+// accept consumes the next token if it is the given (unquoted, case insensitive) keyword or symbol.
+func (parser *queryParser) accept(text string) bool {
+	token, ok := parser.peek()
+	if ok && !token.quoted && strings.EqualFold(token.text, text) {
+		parser.index++
+		return true
+	}
+	return false
+}
+
+func (parser *queryParser) next(expected string) (queryToken, error) {
+	token, ok := parser.peek()
+	if !ok {
+		return token, fmt.Errorf("expected %s at end of query", expected)
+	}
+	parser.index++
+	return token, nil
+}
+
+func (parser *queryParser) expression() (VisitEvaluator, error) {
+	left, err := parser.term()
+	if err != nil {
+		return nil, err
+	}
+	for parser.accept("or") {
+		right, err := parser.term()
+		if err != nil {
+			return nil, err
+		}
+		left = orEvaluator(left, right)
+	}
+	return left, nil
+}
+
+func (parser *queryParser) term() (VisitEvaluator, error) {
+	left, err := parser.factor()
+	if err != nil {
+		return nil, err
+	}
+	for parser.accept("and") {
+		right, err := parser.factor()
+		if err != nil {
+			return nil, err
+		}
+		left = andEvaluator(left, right)
+	}
+	return left, nil
+}
+
+func (parser *queryParser) factor() (VisitEvaluator, error) {
+	if parser.accept("not") {
+		operand, err := parser.factor()
+		if err != nil {
+			return nil, err
+		}
+		return func(node *FileNode) bool { return !operand(node) }, nil
+	}
+	if parser.accept("(") {
+		inner, err := parser.expression()
+		if err != nil {
+			return nil, err
+		}
+		if !parser.accept(")") {
+			token, _ := parser.next("')'")
+			return nil, fmt.Errorf("expected ')' at position %d", token.pos)
+		}
+		return inner, nil
+	}
+
+	field, err := parser.next("a field")
+	if err != nil {
+		return nil, err
+	}
+	operator, err := parser.next("an operator")
+	if err != nil {
+		return nil, err
+	}
+	value, err := parser.next("a value")
+	if err != nil {
+		return nil, err
+	}
+	return comparison(strings.ToLower(field.text), strings.ToLower(operator.text), value.text)
+}
+
+func andEvaluator(left, right VisitEvaluator) VisitEvaluator {
+	return func(node *FileNode) bool { return left(node) && right(node) }
+}
+
+func orEvaluator(left, right VisitEvaluator) VisitEvaluator {
+	return func(node *FileNode) bool { return left(node) || right(node) }
+}
+
+// nodeType names the kind of file a node is. Nodes with children are directories even when the layer does not
+// carry an entry for the directory itself.
+func nodeType(node *FileNode) string {
+	if !node.IsLeaf() {
+		return "dir"
+	}
+	switch node.Data.FileInfo.TarHeader.Typeflag {
+	case tar.TypeDir:
+		return "dir"
+	case tar.TypeSymlink:
+		return "symlink"
+	case tar.TypeLink:
+		return "hardlink"
+	case tar.TypeReg, tar.TypeRegA:
+		return "file"
+	default:
+		return "other"
+	}
+}
+
+// QueryValue returns the value of the given field for a node, as shown in query results.
+func QueryValue(node *FileNode, field string) string {
+	header := node.Data.FileInfo.TarHeader
+	switch field {
+	case "path":
+		return node.Path()
+	case "name":
+		return node.Name
+	case "type":
+		return nodeType(node)
+	case "diff":
+		return strings.ToLower(node.Data.DiffType.String())
+	case "size":
+		return humanize.Bytes(uint64(header.Size))
+	case "mode":
+		return header.FileInfo().Mode().String()
+	case "uid":
+		return strconv.Itoa(header.Uid)
+	case "gid":
+		return strconv.Itoa(header.Gid)
+	}
+	return ""
+}
+
+// comparison builds the evaluator for a single "field operator value" condition.
+func comparison(field, operator, value string) (VisitEvaluator, error) {
+	switch field {
+	case "path", "name", "type", "diff":
+		get := func(node *FileNode) string { return QueryValue(node, field) }
+		if field == "type" || field == "diff" {
+			value = strings.ToLower(value)
+		}
+		switch operator {
+		case "==", "=":
+			return func(node *FileNode) bool { return get(node) == value }, nil
+		case "!=":
+			return func(node *FileNode) bool { return get(node) != value }, nil
+		case "glob":
+			if _, err := path.Match(value, ""); err != nil {
+				return nil, fmt.Errorf("invalid glob %q: %v", value, err)
+			}
+			return func(node *FileNode) bool { return globMatch(value, get(node)) }, nil
+		case "~":
+			pattern, err := regexp.Compile(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression %q: %v", value, err)
+			}
+			return func(node *FileNode) bool { return pattern.MatchString(get(node)) }, nil
+		}
+		return nil, fmt.Errorf("operator '%s' is not supported for %s (use ==, !=, glob, or ~)", operator, field)
+
+	case "size", "mode", "uid", "gid":
+		var expected int64
+		var err error
+		switch field {
+		case "size":
+			var size uint64
+			size, err = humanize.ParseBytes(value)
+			expected = int64(size)
+		case "mode":
+			expected, err = strconv.ParseInt(value, 8, 32)
+		default:
+			expected, err = strconv.ParseInt(value, 10, 64)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value %q", field, value)
+		}
+		get := func(node *FileNode) int64 {
+			header := node.Data.FileInfo.TarHeader
+			switch field {
+			case "size":
+				return header.Size
+			case "mode":
+				return int64(header.Mode & 07777)
+			case "uid":
+				return int64(header.Uid)
+			}
+			return int64(header.Gid)
+		}
+		compare, ok := map[string]func(a, b int64) bool{
+			"==": func(a, b int64) bool { return a == b },
+			"=":  func(a, b int64) bool { return a == b },
+			"!=": func(a, b int64) bool { return a != b },
+			">":  func(a, b int64) bool { return a > b },
+			">=": func(a, b int64) bool { return a >= b },
+			"<":  func(a, b int64) bool { return a < b },
+			"<=": func(a, b int64) bool { return a <= b },
+		}[operator]
+		if !ok {
+			return nil, fmt.Errorf("operator '%s' is not supported for %s (use ==, !=, >, >=, <, or <=)", operator, field)
+		}
+		return func(node *FileNode) bool { return compare(get(node), expected) }, nil
+	}
+	return nil, fmt.Errorf("unknown field '%s' (expected one of %s)", field, strings.Join(QueryFields, ", "))
+}
+
+// globMatch matches a slash-delimited path against a glob pattern, where a "**" component matches any number of
+// path components (including none).
+func globMatch(pattern, name string) bool {
+	return globMatchParts(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func globMatchParts(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for skip := 0; skip <= len(name); skip++ {
+				if globMatchParts(pattern[1:], name[skip:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if matched, _ := path.Match(pattern[0], name[0]); !matched {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// CompileQuery compiles a query expression into an evaluator that selects the matching nodes, for example:
+//
+//	size > 10MB and path glob "/usr/lib/**" and diff == added
+//
+// Conditions compare a field (path, name, type, diff, size, mode, uid, gid) to a value, and may be combined with
+// "and", "or", "not" and parentheses. Note that the evaluator should be applied to each node individually (e.g. from
+// within a visitor), since a parent-first visit does not descend into nodes that are not selected.
+func CompileQuery(query string) (VisitEvaluator, error) {
+	tokens, err := lexQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty query")
+	}
+	parser := &queryParser{tokens: tokens}
+	evaluator, err := parser.expression()
+	if err != nil {
+		return nil, err
+	}
+	if token, ok := parser.peek(); ok {
+		return nil, fmt.Errorf("unexpected '%s' at position %d", token.text, token.pos)
+	}
+	return evaluator, nil
+}
@@ -0,0 +1,96 @@
+package filetree
+
+import (
+	"archive/tar"
+	"sort"
+	"testing"
+)
+
+func queryTree() *FileTree {
+	tree := NewFileTree()
+	tree.AddPath("/usr/lib/libbig.so", fileOfSize("/usr/lib/libbig.so", 20*1000*1000))
+	tree.AddPath("/usr/lib/x86_64/libsmall.so", fileOfSize("/usr/lib/x86_64/libsmall.so", 1000))
+	tree.AddPath("/usr/bin/tool", FileInfo{TarHeader: tar.Header{Typeflag: tar.TypeReg, Size: 30 * 1000 * 1000, Mode: 04755, Uid: 1000}})
+	node, _ := tree.AddPath("/etc/config", fileOfSize("/etc/config", 10))
+	node.Data.DiffType = Added
+	return tree
+}
+
+func queryPaths(t *testing.T, tree *FileTree, query string) []string {
+	evaluator, err := CompileQuery(query)
+	if err != nil {
+		t.Fatalf("Could not compile %q: %v", query, err)
+	}
+	var paths []string
+	tree.VisitDepthParentFirst(func(node *FileNode) error {
+		if evaluator(node) {
+			paths = append(paths, node.Path())
+		}
+		return nil
+	}, nil)
+	sort.Strings(paths)
+	return paths
+}
+
+func TestCompileQuery(t *testing.T) {
+	tree := queryTree()
+	cases := map[string][]string{
+		`size > 10MB and path glob "/usr/lib/**"`:  {"/usr/lib/libbig.so"},
+		`path glob "/usr/lib/**" and type == file`: {"/usr/lib/libbig.so", "/usr/lib/x86_64/libsmall.so"},
+		`diff == added`:                                      {"/etc/config"},
+		`mode >= 04000 or uid == 1000`:                       {"/usr/bin/tool"},
+		`not (type == dir or size > 1kB)`:                    {"/etc/config", "/usr/lib/x86_64/libsmall.so"},
+		`name ~ "^lib.*\\.so$" and not path glob "/usr/*/*"`: {"/usr/lib/x86_64/libsmall.so"},
+		`size>=1000 AND size<=1000`:                          {"/usr/lib/x86_64/libsmall.so"},
+	}
+	for query, expected := range cases {
+		actual := queryPaths(t, tree, query)
+		if len(actual) != len(expected) {
+			t.Errorf("Query %q: expected %v, got %v", query, expected, actual)
+			continue
+		}
+		for idx := range expected {
+			if actual[idx] != expected[idx] {
+				t.Errorf("Query %q: expected %v, got %v", query, expected, actual)
+				break
+			}
+		}
+	}
+}
+
+func TestCompileQueryErrors(t *testing.T) {
+	for _, query := range []string{
+		``,
+		`size >`,
+		`owner == root`,
+		`size glob "*"`,
+		`path > 10`,
+		`(size > 1 and name == a`,
+		`size > 1 name == a`,
+		`path == "unterminated`,
+		`size > lots`,
+	} {
+		if _, err := CompileQuery(query); err == nil {
+			t.Errorf("Expected an error for query %q", query)
+		}
+	}
+}
+
+func TestGlobMatch(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		expected      bool
+	}{
+		{"/usr/lib/**", "/usr/lib/a/b/c.so", true},
+		{"/usr/lib/**", "/usr/lib", true},
+		{"/usr/**/*.so", "/usr/lib/x/c.so", true},
+		{"/usr/*/*.so", "/usr/lib/x/c.so", false},
+		{"/etc/*", "/etc/hosts", true},
+		{"/etc/*", "/etc", false},
+	}
+	for _, test := range cases {
+		if actual := globMatch(test.pattern, test.name); actual != test.expected {
+			t.Errorf("globMatch(%q, %q): expected %v, got %v", test.pattern, test.name, test.expected, actual)
+		}
+	}
+}
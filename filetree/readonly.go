@@ -0,0 +1,179 @@
+package filetree
+
+// ReadOnlyTree is an immutable view of a FileTree, such that a tree shared between several consumers (e.g. the views
+// of the UI, or the stacked trees kept for a whole session) cannot be modified by any of them. The view offers the
+// traversal and query methods of the tree, yielding ReadOnlyNodes and copies of the node metadata. Use Copy to get a
+// (mutable) FileTree back.
+type ReadOnlyTree struct {
+	tree *FileTree
+}
+
+// ReadOnlyNode is an immutable view of a single FileNode of a ReadOnlyTree.
+type ReadOnlyNode struct {
+	node *FileNode
+}
+
+// ReadOnlyVisitor is a function that observes the given node of a ReadOnlyTree.
+type ReadOnlyVisitor func(ReadOnlyNode) error
+
+// Freeze returns an immutable view of the tree as it is now. The view is taken from a copy of the tree (later changes
+// to the tree are not seen by the view) and everything the tree computes lazily (paths, aggregate sizes and the path
+// index) is computed upfront, so the view is safe to read from several goroutines at once. The rows of the view are
+// never counted (see CountRows), so rendering it keeps no state either.
+func (tree *FileTree) Freeze() ReadOnlyTree {
+	frozen := tree.Copy()
+	frozen.Name = tree.Name
+	frozen.Id = tree.Id
+	frozen.ComputeAggregateSizes()
+	frozen.index()
+	frozen.VisitDepthParentFirst(func(node *FileNode) error {
+		node.Path()
+		return nil
+	}, nil)
+	return ReadOnlyTree{tree: frozen}
+}
+
+// Name returns the name of the tree (e.g. the layer it was read from).
+func (tree ReadOnlyTree) Name() string {
+	return tree.tree.Name
+}
+
+// Size returns the number of nodes of the tree.
+func (tree ReadOnlyTree) Size() int {
+	return tree.tree.Size
+}
+
+// FileSize returns the total size of the files of the tree.
+func (tree ReadOnlyTree) FileSize() uint64 {
+	return tree.tree.FileSize
+}
+
+// Root returns the root node of the tree.
+func (tree ReadOnlyTree) Root() ReadOnlyNode {
+	return ReadOnlyNode{node: tree.tree.Root}
+}
+
+// GetNode fetches the node at the given slash-delimited path (see FileTree.GetNode).
+func (tree ReadOnlyTree) GetNode(path string) (ReadOnlyNode, error) {
+	node, err := tree.tree.lookup(path)
+	return ReadOnlyNode{node: node}, err
+}
+
+// ResolvePath fetches the node at the given path, following symlinks (see FileTree.ResolvePath).
+func (tree ReadOnlyTree) ResolvePath(path string) (ReadOnlyNode, error) {
+	node, err := tree.tree.ResolvePath(path)
+	return ReadOnlyNode{node: node}, err
+}
+
+// FindAll returns the nodes (in tree order) whose path matches the given glob pattern (see FileTree.FindAll).
+func (tree ReadOnlyTree) FindAll(pattern string) ([]ReadOnlyNode, error) {
+	nodes, err := tree.tree.FindAll(pattern)
+	return readOnlyNodes(nodes), err
+}
+
+// VisitDepthParentFirst iterates the tree depth-first, evaluating the shallowest depths first.
+func (tree ReadOnlyTree) VisitDepthParentFirst(visitor ReadOnlyVisitor) error {
+	return tree.tree.ObserveDepthParentFirst(func(node *FileNode) error {
+		return visitor(ReadOnlyNode{node: node})
+	}, nil)
+}
+
+// VisitDepthChildFirst iterates the tree depth-first, evaluating the deepest depths first.
+func (tree ReadOnlyTree) VisitDepthChildFirst(visitor ReadOnlyVisitor) error {
+	return tree.tree.ObserveDepthChildFirst(func(node *FileNode) error {
+		return visitor(ReadOnlyNode{node: node})
+	}, nil)
+}
+
+// String returns the entire tree in an ASCII representation.
+func (tree ReadOnlyTree) String(showAttributes bool) string {
+	return tree.tree.String(showAttributes)
+}
+
+// MarshalJSON serializes the tree (see FileTree.MarshalJSON).
+func (tree ReadOnlyTree) MarshalJSON() ([]byte, error) {
+	return tree.tree.MarshalJSON()
+}
+
+// Copy returns a mutable copy of the tree.
+func (tree ReadOnlyTree) Copy() *FileTree {
+	return tree.tree.Freeze().tree
+}
+
+func readOnlyNodes(nodes []*FileNode) []ReadOnlyNode {
+	result := make([]ReadOnlyNode, len(nodes))
+	for idx, node := range nodes {
+		result[idx] = ReadOnlyNode{node: node}
+	}
+	return result
+}
+
+// Exists indicates if the view refers to a node at all (lookups that fail return a view of no node).
+func (node ReadOnlyNode) Exists() bool {
+	return node.node != nil
+}
+
+// Name returns the name of the node.
+func (node ReadOnlyNode) Name() string {
+	return node.node.Name
+}
+
+// Path returns the slash-delimited path of the node from the root of the tree.
+func (node ReadOnlyNode) Path() string {
+	return node.node.Path()
+}
+
+// Info returns a copy of the metadata of the node.
+func (node ReadOnlyNode) Info() FileInfo {
+	return *node.node.Data.FileInfo.Copy()
+}
+
+// DiffType returns how the node changed when the tree was compared to another tree.
+func (node ReadOnlyNode) DiffType() DiffType {
+	return node.node.Data.DiffType
+}
+
+// Changes returns a copy of the attribute changes of the node when the tree was compared to another tree.
+func (node ReadOnlyNode) Changes() []AttributeChange {
+	return append([]AttributeChange(nil), node.node.Data.Changes...)
+}
+
+// AggregateSize returns the cumulative size of the node and all of its descendants, not counting removed nodes.
+func (node ReadOnlyNode) AggregateSize() int64 {
+	return node.node.aggregateSize
+}
+
+// IsLeaf returns true if the node has no child nodes.
+func (node ReadOnlyNode) IsLeaf() bool {
+	return node.node.IsLeaf()
+}
+
+// IsWhiteout indicates if the node removes the path of a lower layer.
+func (node ReadOnlyNode) IsWhiteout() bool {
+	return node.node.IsWhiteout()
+}
+
+// Opaque indicates if the directory hides everything lower layers placed in it.
+func (node ReadOnlyNode) Opaque() bool {
+	return node.node.Opaque
+}
+
+// Parent returns the parent of the node (a view of no node for the root).
+func (node ReadOnlyNode) Parent() ReadOnlyNode {
+	return ReadOnlyNode{node: node.node.Parent}
+}
+
+// Children returns the child nodes of the node, in name order.
+func (node ReadOnlyNode) Children() []ReadOnlyNode {
+	names := node.node.sortedChildNames()
+	result := make([]ReadOnlyNode, len(names))
+	for idx, name := range names {
+		result[idx] = ReadOnlyNode{node: node.node.Children[name]}
+	}
+	return result
+}
+
+// String shows the name of the node, formatted by its DiffType (see FileNode.String).
+func (node ReadOnlyNode) String() string {
+	return node.node.String()
+}
@@ -0,0 +1,120 @@
+package filetree
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestFreeze(t *testing.T) {
+	tree := NewFileTree()
+	tree.Name = "layer"
+	tree.AddPath("/etc/hosts", fileOfSize("/etc/hosts", 100))
+	tree.AddPath("/etc/ssl/cert.pem", fileOfSize("/etc/ssl/cert.pem", 1000))
+	frozen := tree.Freeze()
+
+	// later changes to the tree are not seen by the view
+	tree.AddPath("/etc/passwd", fileOfSize("/etc/passwd", 10))
+	tree.RemovePath("/etc/hosts")
+	if _, err := frozen.GetNode("/etc/passwd"); err == nil {
+		t.Errorf("Expected a path added after freezing not to be seen")
+	}
+	hosts, err := frozen.GetNode("/etc/hosts")
+	if err != nil || !hosts.Exists() {
+		t.Fatalf("Expected a path removed after freezing to be seen: %v", err)
+	}
+
+	// the metadata handed out are copies
+	info := hosts.Info()
+	info.TarHeader.Size = 1
+	info.Path = "/changed"
+	if hosts.Info().TarHeader.Size != 100 || hosts.Info().Path != "/etc/hosts" {
+		t.Errorf("Expected the metadata of the view not to change, got %+v", hosts.Info())
+	}
+
+	if frozen.Name() != "layer" {
+		t.Errorf("Expected the name to be kept, got %q", frozen.Name())
+	}
+	etc, _ := frozen.GetNode("/etc")
+	if etc.AggregateSize() != 1100 {
+		t.Errorf("Expected /etc to be 1100 bytes, got %d", etc.AggregateSize())
+	}
+	var names []string
+	for _, child := range etc.Children() {
+		names = append(names, child.Name())
+	}
+	if len(names) != 2 || names[0] != "hosts" || names[1] != "ssl" {
+		t.Errorf("Expected the children in name order, got %v", names)
+	}
+	if parent := etc.Parent(); parent.Path() != "/" || parent.Parent().Exists() {
+		t.Errorf("Expected the parent of /etc to be the root, got %v", parent.Path())
+	}
+
+	var visited int
+	frozen.VisitDepthParentFirst(func(node ReadOnlyNode) error {
+		visited++
+		return nil
+	})
+	if visited != 4 {
+		t.Errorf("Expected 4 nodes to be visited, got %d", visited)
+	}
+
+	// copies are mutable without changing the view
+	copied := frozen.Copy()
+	copied.RemovePath("/etc/ssl")
+	if _, err := frozen.GetNode("/etc/ssl/cert.pem"); err != nil {
+		t.Errorf("Expected the view not to change with its copy: %v", err)
+	}
+	if node, _ := copied.GetNode("/etc"); node == nil || node.AggregateSize() != 100 {
+		t.Errorf("Expected the copy to be resized, got %v", node)
+	}
+}
+
+func TestFreezeConcurrentReads(t *testing.T) {
+	tree := NewFileTree()
+	tree.AddPath("/etc/hosts", fileOfSize("/etc/hosts", 100))
+	tree.AddPath("/etc/ssl/cert.pem", fileOfSize("/etc/ssl/cert.pem", 1000))
+	tree.AddPath("/usr/bin/ls", fileOfSize("/usr/bin/ls", 10))
+	frozen := tree.Freeze()
+	expected := frozen.String(false)
+
+	// run with -race: every read of the view must leave the tree as it is
+	var wg sync.WaitGroup
+	for idx := 0; idx < 4; idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if rendered := frozen.String(true); rendered == "" {
+				t.Errorf("Expected the tree to be rendered")
+			}
+			if rendered := frozen.String(false); rendered != expected {
+				t.Errorf("Expected the same rendering, got %q", rendered)
+			}
+			if _, err := frozen.MarshalJSON(); err != nil {
+				t.Errorf("Expected the tree to serialize: %v", err)
+			}
+			var size int64
+			frozen.VisitDepthChildFirst(func(node ReadOnlyNode) error {
+				if node.IsLeaf() {
+					size += node.AggregateSize()
+				}
+				return nil
+			})
+			if size != 1110 {
+				t.Errorf("Expected 1110 bytes of files, got %d", size)
+			}
+			frozen.VisitDepthParentFirst(func(node ReadOnlyNode) error {
+				node.Path()
+				node.Children()
+				node.Info()
+				return nil
+			})
+			if etc, err := frozen.GetNode("/etc"); err != nil || etc.AggregateSize() != 1100 {
+				t.Errorf("Expected /etc to be 1100 bytes: %v", err)
+			}
+			if copied := frozen.Copy(); copied.Size != frozen.Size() {
+				t.Errorf("Expected the copy to hold %d nodes, got %d", frozen.Size(), copied.Size)
+			}
+		}()
+	}
+	wg.Wait()
+}
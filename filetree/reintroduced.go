@@ -0,0 +1,43 @@
+package filetree
+
+import (
+	"sort"
+)
+
+// Reintroduction describes a path that was removed by one layer and added again by a later layer. Both the original
+// and the re-added copies are stored in the image, so the size of the original copy is wasted.
+type Reintroduction struct {
+	Path         string
+	AddedLayer   int
+	RemovedLayer int
+	ReaddedLayer int
+	WastedSize   int64
+}
+
+// Reintroductions finds all paths that were deleted in one layer and re-added in a later layer, ordered by
+// the wasted size (largest first).
+func Reintroductions(trees []*FileTree) []*Reintroduction {
+	live := make(map[string]*Reintroduction)
+	removed := make(map[string]*Reintroduction)
+	result := make([]*Reintroduction, 0)
+
+	StackFiles(trees, func(layer int, node *FileNode) {
+		path := node.Path()
+		if entry, ok := removed[path]; ok {
+			entry.ReaddedLayer = layer
+			result = append(result, entry)
+			delete(removed, path)
+		}
+		live[path] = &Reintroduction{Path: path, AddedLayer: layer, WastedSize: node.Data.FileInfo.TarHeader.FileInfo().Size()}
+	}, func(layer int, path string) {
+		entry := live[path]
+		entry.RemovedLayer = layer
+		removed[path] = entry
+		delete(live, path)
+	})
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].WastedSize > result[j].WastedSize
+	})
+	return result
+}
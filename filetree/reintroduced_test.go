@@ -0,0 +1,53 @@
+package filetree
+
+import (
+	"testing"
+)
+
+func TestReintroductions(t *testing.T) {
+	first := NewFileTree()
+	first.AddPath("/app/lib/big.so", fileOfSize("/app/lib/big.so", 500))
+	first.AddPath("/app/lib/small.so", fileOfSize("/app/lib/small.so", 5))
+	first.AddPath("/etc/config", fileOfSize("/etc/config", 10))
+
+	second := NewFileTree()
+	second.AddPath("/app/.wh.lib", FileInfo{})
+	second.AddPath("/etc/.wh.config", FileInfo{})
+
+	third := NewFileTree()
+	third.AddPath("/app/lib/big.so", fileOfSize("/app/lib/big.so", 600))
+	third.AddPath("/etc/config", fileOfSize("/etc/config", 10))
+	third.AddPath("/etc/other", fileOfSize("/etc/other", 10))
+
+	result := Reintroductions([]*FileTree{first, second, third})
+
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 reintroduced paths, got %d", len(result))
+	}
+
+	actual := result[0]
+	if actual.Path != "/app/lib/big.so" || actual.WastedSize != 500 {
+		t.Errorf("Expected the largest reintroduction first, got %+v", actual)
+	}
+	if actual.AddedLayer != 0 || actual.RemovedLayer != 1 || actual.ReaddedLayer != 2 {
+		t.Errorf("Unexpected layers for reintroduction: %+v", actual)
+	}
+
+	if result[1].Path != "/etc/config" {
+		t.Errorf("Expected '/etc/config' to be reintroduced, got %+v", result[1])
+	}
+}
+
+func TestReintroductionsOpaque(t *testing.T) {
+	// the opaque directory removes big.so, but provides kept.so again in the same layer
+	result := Reintroductions(opaqueLayers())
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 reintroduced paths, got %d", len(result))
+	}
+	if actual := result[0]; actual.Path != "/app/lib/big.so" || actual.AddedLayer != 0 || actual.RemovedLayer != 1 || actual.ReaddedLayer != 2 || actual.WastedSize != 500 {
+		t.Errorf("Expected the file hidden by the opaque directory to be reintroduced, got %+v", actual)
+	}
+	if actual := result[1]; actual.Path != "/etc/hosts" || actual.RemovedLayer != 1 || actual.WastedSize != 10 {
+		t.Errorf("Expected the removed file to be reintroduced, got %+v", actual)
+	}
+}
@@ -0,0 +1,301 @@
+package filetree
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+// SpanStyle is the semantic meaning of a piece of rendered text, which each Renderer may present in its own way.
+type SpanStyle int
+
+const (
+	// StyleAttributes is the columnar file metadata (permissions, owner, size)
+	StyleAttributes SpanStyle = iota
+	// StyleGuide is the branches and indentation that connect a node to its parents
+	StyleGuide
+	// StyleName is the name of the file
+	StyleName
+	// StyleLinkTarget is the target of a symlink or hardlink (including the arrow)
+	StyleLinkTarget
+	// StyleBadge is the set of badges attached to the file by analyzers
+	StyleBadge
+	// StylePager is the indication of which page of children a paginated directory shows
+	StylePager
+)
+
+// Span is a piece of text within a Row, annotated with its semantic style.
+type Span struct {
+	Text  string
+	Style SpanStyle
+}
+
+// GlyphSet holds the characters used to draw the branches of a tree.
+type GlyphSet struct {
+	Space       string
+	Branch      string
+	Middle      string
+	Last        string
+	Uncollapsed string
+	Collapsed   string
+	Link        string
+}
+
+// DefaultGlyphs draws the tree with unicode box drawing characters.
+var DefaultGlyphs = GlyphSet{
+	Space:       noBranchSpace,
+	Branch:      branchSpace,
+	Middle:      middleItem,
+	Last:        lastItem,
+	Uncollapsed: uncollapsedItem,
+	Collapsed:   collapsedItem,
+	Link:        " → ",
+}
+
+// ASCIIGlyphs draws the tree with plain ASCII characters, for terminals (and logs) that garble box drawing characters.
+var ASCIIGlyphs = GlyphSet{
+	Space:       "    ",
+	Branch:      "|   ",
+	Middle:      "|-",
+	Last:        "`-",
+	Uncollapsed: "- ",
+	Collapsed:   "+ ",
+	Link:        " -> ",
+}
+
+// GlyphSets are the glyph sets that may be selected by name.
+var GlyphSets = map[string]GlyphSet{
+	"unicode": DefaultGlyphs,
+	"ascii":   ASCIIGlyphs,
+}
+
+// Row is the structured representation of a single visible node within a rendered tree, independent of how the
+// tree is eventually drawn.
+type Row struct {
+	Node *FileNode
+	// LastAncestors indicates, for each ancestor level, if the ancestor was the last of its siblings (thus no
+	// branch needs to be drawn at that level)
+	LastAncestors []bool
+	// Last indicates if this node is the last of its siblings
+	Last bool
+	// Collapsed indicates if this node has children that are not shown
+	Collapsed bool
+}
+
+// Spans breaks the row into styled pieces of text, using the given glyphs to draw the tree branches.
+func (row Row) Spans(glyphs GlyphSet, showAttributes bool) []Span {
+	var spans []Span
+	if showAttributes {
+		spans = append(spans, Span{Text: row.Node.metadata(), Style: StyleAttributes}, Span{Text: " ", Style: StyleGuide})
+	}
+
+	var guide string
+	for _, last := range row.LastAncestors {
+		if last {
+			guide += glyphs.Space
+		} else {
+			guide += glyphs.Branch
+		}
+	}
+	if row.Last {
+		guide += glyphs.Last
+	} else {
+		guide += glyphs.Middle
+	}
+	if row.Collapsed {
+		guide += glyphs.Collapsed
+	} else {
+		guide += glyphs.Uncollapsed
+	}
+	spans = append(spans, Span{Text: guide, Style: StyleGuide}, Span{Text: row.Node.Name, Style: StyleName})
+
+	if linkTarget := row.Node.linkTarget(); linkTarget != "" {
+		spans = append(spans, Span{Text: glyphs.Link + linkTarget, Style: StyleLinkTarget})
+	}
+
+	if badges := row.Node.Data.ViewInfo.Badges; len(badges) > 0 {
+		text := " "
+		for _, badge := range badges {
+			text += badge.Symbol
+		}
+		spans = append(spans, Span{Text: text, Style: StyleBadge})
+	}
+
+	if first, last, total, paged := row.Node.PageRange(); paged {
+		text := fmt.Sprintf(" (showing %s–%s of %s)", humanize.Comma(int64(first)), humanize.Comma(int64(last)), humanize.Comma(int64(total)))
+		spans = append(spans, Span{Text: text, Style: StylePager})
+	}
+	return spans
+}
+
+// Renderer turns the rows of a tree into text for a particular frontend.
+type Renderer interface {
+	Render(rows []Row) string
+}
+
+// ANSIRenderer renders rows for a terminal, coloring each file by its DiffType.
+type ANSIRenderer struct {
+	Glyphs         GlyphSet
+	ShowAttributes bool
+	// CompactAttributes only shows the size of the attributes (see CompactAttributeFormat), for narrow terminals
+	CompactAttributes bool
+}
+
+// Render returns the rows as lines of text with ANSI color sequences.
+func (renderer ANSIRenderer) Render(rows []Row) string {
+	var result strings.Builder
+	for _, row := range rows {
+		for _, span := range row.Spans(renderer.Glyphs, renderer.ShowAttributes) {
+			if span.Style == StyleAttributes && renderer.CompactAttributes {
+				span.Text = row.Node.compactMetadata()
+			}
+			if span.Style == StyleGuide || span.Style == StyleBadge || span.Style == StylePager {
+				result.WriteString(span.Text)
+			} else {
+				result.WriteString(diffTypeColor[row.Node.Data.DiffType].Sprint(span.Text))
+			}
+		}
+		result.WriteString(newLine)
+	}
+	return result.String()
+}
+
+// PlainRenderer renders rows as lines of text without any styling.
+type PlainRenderer struct {
+	Glyphs         GlyphSet
+	ShowAttributes bool
+}
+
+// Render returns the rows as lines of plain text.
+func (renderer PlainRenderer) Render(rows []Row) string {
+	var result strings.Builder
+	for _, row := range rows {
+		for _, span := range row.Spans(renderer.Glyphs, renderer.ShowAttributes) {
+			result.WriteString(span.Text)
+		}
+		result.WriteString(newLine)
+	}
+	return result.String()
+}
+
+// htmlStyleClasses are the CSS classes given to each span style by the HTMLRenderer.
+var htmlStyleClasses = map[SpanStyle]string{
+	StyleAttributes: "attributes",
+	StyleGuide:      "guide",
+	StyleName:       "name",
+	StyleLinkTarget: "link",
+	StyleBadge:      "badge",
+	StylePager:      "pager",
+}
+
+// HTMLRenderer renders rows as HTML elements, leaving the presentation to CSS. Each row is a div with the class of
+// its DiffType (e.g. "row added") and each span is given the class of its style (e.g. "name").
+type HTMLRenderer struct {
+	Glyphs         GlyphSet
+	ShowAttributes bool
+}
+
+// Render returns the rows as HTML.
+func (renderer HTMLRenderer) Render(rows []Row) string {
+	var result strings.Builder
+	for _, row := range rows {
+		result.WriteString(`<div class="row ` + strings.ToLower(row.Node.Data.DiffType.String()) + `">`)
+		for _, span := range row.Spans(renderer.Glyphs, renderer.ShowAttributes) {
+			result.WriteString(`<span class="` + htmlStyleClasses[span.Style] + `">` + html.EscapeString(span.Text) + `</span>`)
+		}
+		result.WriteString("</div>" + newLine)
+	}
+	return result.String()
+}
+
+// Rows returns the structured rows of the tree between the given rows. Since each node is rendered on its own row,
+// only the visible nodes not affected by a collapsed parent are returned. The rows of a counted tree (see CountRows)
+// are walked once and kept until a node is collapsed, expanded, hidden, shown, added or removed, such that scrolling
+// through a large tree only slices the kept rows. The returned rows must not be modified.
+func (tree *FileTree) Rows(startRow, stopRow int) []Row {
+	rows := tree.rowList
+	if rows == nil {
+		rows = tree.walkRows()
+		if tree.Root.counted {
+			tree.rowList = rows
+		}
+	}
+	if startRow < 0 {
+		startRow = 0
+	}
+	if stopRow >= len(rows) {
+		stopRow = len(rows) - 1
+	}
+	if startRow > stopRow {
+		return make([]Row, 0)
+	}
+	// cap the slice such that appending to it never overwrites the kept rows
+	return rows[startRow : stopRow+1 : stopRow+1]
+}
+
+// walkRows visits the tree for the rows of every visible node, in the order they are rendered (see Rows).
+func (tree *FileTree) walkRows() []Row {
+	// rowParams is a Row in the making, along with the state needed for the rows of its children
+	type rowParams struct {
+		row         Row
+		childSpaces []bool
+	}
+
+	var rows = make([]Row, 0)
+
+	// visit depth-first using a stack (the next node to visit is at the end), which keeps very wide and very deep
+	// trees linear to traverse
+	var paramsToVisit = []rowParams{{row: Row{Node: tree.Root, LastAncestors: []bool{}}}}
+	for len(paramsToVisit) > 0 {
+		// pop the next node
+		currentParams := paramsToVisit[len(paramsToVisit)-1]
+		paramsToVisit = paramsToVisit[:len(paramsToVisit)-1]
+		currentNode := currentParams.row.Node
+
+		// take note of the next nodes to visit later (we should always visit nodes in order)
+		keys := currentNode.sortedChildNames()
+
+		var childParams = make([]rowParams, 0)
+		for idx, name := range keys {
+			child := currentNode.Children[name]
+			// don't visit this node...
+			if child.Data.ViewInfo.Hidden || currentNode.Data.ViewInfo.Collapsed {
+				continue
+			}
+
+			// visit this node...
+			isLast := idx == (len(currentNode.Children) - 1)
+
+			// completely copy the reference slice
+			childSpaces := make([]bool, len(currentParams.childSpaces))
+			copy(childSpaces, currentParams.childSpaces)
+
+			if len(child.Children) > 0 && !child.Data.ViewInfo.Collapsed {
+				childSpaces = append(childSpaces, isLast)
+			}
+
+			childParams = append(childParams, rowParams{
+				row: Row{
+					Node:          child,
+					LastAncestors: currentParams.childSpaces,
+					Last:          isLast,
+					Collapsed:     child.Data.ViewInfo.Collapsed && len(child.Children) > 0,
+				},
+				childSpaces: childSpaces,
+			})
+		}
+		// keep the child nodes to visit later, in reverse such that the first child is popped first
+		for idx := len(childParams) - 1; idx >= 0; idx-- {
+			paramsToVisit = append(paramsToVisit, childParams[idx])
+		}
+
+		// never process the root node
+		if currentNode != tree.Root {
+			rows = append(rows, currentParams.row)
+		}
+	}
+
+	return rows
+}
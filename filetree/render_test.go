@@ -0,0 +1,117 @@
+package filetree
+
+import (
+	"archive/tar"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRows(t *testing.T) {
+	tree := NewFileTree()
+	tree.AddPath("/etc/nginx/nginx.conf", FileInfo{})
+	tree.AddPath("/etc/hosts", FileInfo{})
+	tree.AddPath("/var/log", FileInfo{})
+
+	rows := tree.Rows(0, tree.Size)
+	if len(rows) != 6 {
+		t.Fatalf("Expected 6 rows, got %d", len(rows))
+	}
+
+	nginx := rows[2]
+	if nginx.Node.Path() != "/etc/nginx" {
+		t.Fatalf("Expected third row to be /etc/nginx, got %s", nginx.Node.Path())
+	}
+	if !nginx.Last || len(nginx.LastAncestors) != 1 || nginx.LastAncestors[0] {
+		t.Errorf("Unexpected row structure: %+v", nginx)
+	}
+
+	tree.Root.Children["etc"].Data.ViewInfo.Collapsed = true
+	rows = tree.Rows(0, tree.Size)
+	if len(rows) != 3 || !rows[0].Collapsed {
+		t.Errorf("Expected 3 rows with the first collapsed, got %d", len(rows))
+	}
+}
+
+func TestPlainRenderer(t *testing.T) {
+	tree := NewFileTree()
+	tree.AddPath("/bin/sh", FileInfo{TarHeader: tar.Header{Typeflag: tar.TypeSymlink, Linkname: "/bin/bash"}})
+	tree.AddPath("/bin/bash", FileInfo{})
+
+	expected :=
+		"`-- bin\n" +
+			"    |-- bash\n" +
+			"    `-- sh -> /bin/bash\n"
+	actual := PlainRenderer{Glyphs: ASCIIGlyphs}.Render(tree.Rows(0, tree.Size))
+	if expected != actual {
+		t.Errorf("Expected tree string:\n--->%s<---\nGot:\n--->%s<---", expected, actual)
+	}
+}
+
+func TestStringWithGlyphs(t *testing.T) {
+	tree := NewFileTree()
+	tree.AddPath("/etc/hosts", FileInfo{})
+	tree.AddPath("/etc/passwd", FileInfo{})
+	tree.AddPath("/var", FileInfo{})
+
+	expected :=
+		"|-- etc\n" +
+			"|   |-- hosts\n" +
+			"|   `-- passwd\n" +
+			"`-- var\n"
+	if actual := tree.StringWith(ASCIIGlyphs, false); expected != actual {
+		t.Errorf("Expected tree string:\n--->%s<---\nGot:\n--->%s<---", expected, actual)
+	}
+	if tree.StringWith(DefaultGlyphs, false) != tree.String(false) {
+		t.Errorf("Expected the default glyphs to render as String does")
+	}
+}
+
+func TestCompactAttributes(t *testing.T) {
+	tree := NewFileTree()
+	tree.AddPath("/etc/hosts", FileInfo{TarHeader: tar.Header{Typeflag: tar.TypeReg, Mode: 0644, Size: 2000}})
+
+	rows := tree.Rows(0, tree.Size)
+	compact := ANSIRenderer{Glyphs: ASCIIGlyphs, ShowAttributes: true, CompactAttributes: true}.Render(rows[1:])
+	if !strings.Contains(compact, fmt.Sprintf(CompactAttributeFormat, "2.0 kB")) || strings.Contains(compact, "rw-") {
+		t.Errorf("Expected only the size in the compact attributes, got %q", compact)
+	}
+	full := ANSIRenderer{Glyphs: ASCIIGlyphs, ShowAttributes: true}.Render(rows[1:])
+	if !strings.Contains(full, "rw-") {
+		t.Errorf("Expected the permissions in the attributes, got %q", full)
+	}
+}
+
+func TestHTMLRenderer(t *testing.T) {
+	tree := NewFileTree()
+	node, _ := tree.AddPath("/a<b>", FileInfo{})
+	node.Data.DiffType = Added
+
+	expected := `<div class="row added"><span class="guide">└── </span><span class="name">a&lt;b&gt;</span></div>` + "\n"
+	actual := HTMLRenderer{Glyphs: DefaultGlyphs}.Render(tree.Rows(0, tree.Size))
+	if expected != actual {
+		t.Errorf("Expected html:\n--->%s<---\nGot:\n--->%s<---", expected, actual)
+	}
+}
+
+func TestRowsWideTree(t *testing.T) {
+	width := 200000
+	tree := NewFileTree()
+	dir, _ := tree.AddPath("/wide", FileInfo{})
+	for idx := 0; idx < width; idx++ {
+		dir.AddChild(fmt.Sprintf("file-%06d", idx), FileInfo{})
+	}
+
+	rows := tree.Rows(0, tree.Size)
+	if len(rows) != width+1 {
+		t.Fatalf("Expected %d rows, got %d", width+1, len(rows))
+	}
+	if !rows[width].Last || rows[width].Node.Name != fmt.Sprintf("file-%06d", width-1) {
+		t.Errorf("Expected the last row to be the last file, got %s", rows[width].Node.Name)
+	}
+
+	rows = tree.Rows(10, 12)
+	if len(rows) != 3 || rows[0].Node.Name != "file-000009" {
+		t.Errorf("Unexpected rows between 10 and 12: %d rows", len(rows))
+	}
+}
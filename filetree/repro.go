@@ -0,0 +1,98 @@
+package filetree
+
+// NondeterministicPaths are the paths (glob patterns, where "**" matches any number of path components) that
+// legitimately differ between two builds of the same image: caches and logs written as a side effect of the build,
+// byte code compiled on install, and identifiers generated for each build.
+var NondeterministicPaths = []string{
+	"/tmp/**",
+	"/var/tmp/**",
+	"/var/log/**",
+	"/var/cache/**",
+	"/root/.cache/**",
+	"/var/lib/apt/lists/**",
+	"/var/lib/dpkg/*-old",
+	"/etc/machine-id",
+	"/var/lib/dbus/machine-id",
+	"**/__pycache__/**",
+	"**/*.pyc",
+}
+
+// ReproDifference is a file that differs between two builds of an image.
+type ReproDifference struct {
+	Path string
+	// DiffType is Added (only in the second build), Removed (only in the first build) or Changed
+	DiffType DiffType
+	// Attributes are the attributes that differ (only for Changed files)
+	Attributes []AttributeChange
+}
+
+// ReproReport is the outcome of comparing two builds of an image (see CompareBuilds).
+type ReproReport struct {
+	// Differences are the files that differ, in path order
+	Differences []ReproDifference
+	// Ignored is the number of differing files on the ignored (non-deterministic) paths
+	Ignored int
+}
+
+// Reproducible indicates if the builds are semantically identical, differing only on the ignored paths.
+func (report ReproReport) Reproducible() bool {
+	return len(report.Differences) == 0
+}
+
+// CompareBuilds compares the final (fully stacked) trees of two builds of an image, to verify that the build is
+// reproducible. Files are compared by their metadata (see MetadataComparer), which never includes their modification
+// times, and the files on any of the given paths (see NondeterministicPaths) are ignored. Directories are compared by
+// their own attributes (rather than their contents), such that a file that differs is only reported once.
+func CompareBuilds(first, second *FileTree, ignore []string) (ReproReport, error) {
+	var report ReproReport
+	compared := first.Copy()
+	compared.Comparer = MetadataComparer{}
+	if err := compared.CompareTree(second); err != nil {
+		return report, err
+	}
+
+	ignored := func(path string) bool {
+		for _, pattern := range ignore {
+			if globMatch(pattern, path) {
+				return true
+			}
+		}
+		return false
+	}
+	err := compared.ObserveDepthParentFirst(func(node *FileNode) error {
+		if node.IsWhiteout() {
+			return nil
+		}
+		difference := ReproDifference{Path: node.Path(), DiffType: node.Data.DiffType}
+		switch {
+		case node.Data.DiffType == Added || node.Data.DiffType == Removed:
+			// a directory only in one of the builds is reported by its files
+			if !node.IsLeaf() {
+				return nil
+			}
+		case node.IsLeaf() || node.Data.FileInfo.TarHeader.FileInfo().IsDir():
+			other, err := second.lookup(node.Path())
+			if err != nil {
+				// reached through a symlink of the second build (see CompareTree)
+				other, err = second.ResolvePath(node.Path())
+			}
+			if err != nil {
+				return nil
+			}
+			difference.Attributes = compared.Comparer.Changes(node.Data.FileInfo, other.Data.FileInfo)
+			if len(difference.Attributes) == 0 {
+				return nil
+			}
+			difference.DiffType = Changed
+		default:
+			return nil
+		}
+		if ignored(difference.Path) {
+			report.Ignored++
+			return nil
+		}
+		report.Differences = append(report.Differences, difference)
+		return nil
+	}, nil)
+	return report, err
+}
@@ -0,0 +1,74 @@
+package filetree
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestCompareBuilds(t *testing.T) {
+	build := func(contents map[string]string, modTime time.Time) *FileTree {
+		tree := NewFileTree()
+		for path, content := range contents {
+			header := &tar.Header{Name: path, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content)), ModTime: modTime}
+			tree.AddPath(path, fileInfoOf(t, bytes.NewBufferString(content), header, path))
+		}
+		return tree
+	}
+
+	first := build(map[string]string{
+		"/usr/bin/app":           "binary",
+		"/etc/app.conf":          "port=80",
+		"/var/log/build.log":     "first build",
+		"/etc/machine-id":        "1234",
+		"/usr/lib/app/module.py": "print()",
+	}, time.Unix(1000, 0))
+	second := build(map[string]string{
+		"/usr/bin/app":                        "binary",
+		"/etc/app.conf":                       "port=81",
+		"/var/log/build.log":                  "second build",
+		"/etc/machine-id":                     "5678",
+		"/usr/lib/app/module.py":              "print()",
+		"/usr/lib/app/__pycache__/module.pyc": "bytecode",
+		"/usr/share/doc/app/README":           "docs",
+	}, time.Unix(2000, 0))
+
+	report, err := CompareBuilds(first, second, NondeterministicPaths)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if report.Reproducible() {
+		t.Fatalf("Expected the builds to differ")
+	}
+	expected := []ReproDifference{
+		{Path: "/etc/app.conf", DiffType: Changed},
+		{Path: "/usr/share/doc/app/README", DiffType: Added},
+	}
+	if len(report.Differences) != len(expected) {
+		t.Fatalf("Expected %d differences, got %+v", len(expected), report.Differences)
+	}
+	for idx, difference := range report.Differences {
+		if difference.Path != expected[idx].Path || difference.DiffType != expected[idx].DiffType {
+			t.Errorf("Expected %s to be %v, got %s %v", expected[idx].Path, expected[idx].DiffType, difference.Path, difference.DiffType)
+		}
+	}
+	if attributes := report.Differences[0].Attributes; len(attributes) != 1 || attributes[0].Attribute != "contents" {
+		t.Errorf("Expected the contents of /etc/app.conf to differ, got %v", attributes)
+	}
+	if report.Ignored != 3 {
+		t.Errorf("Expected 3 ignored differences, got %d", report.Ignored)
+	}
+
+	// the same build at another time is reproducible
+	report, _ = CompareBuilds(first, build(map[string]string{
+		"/usr/bin/app":           "binary",
+		"/etc/app.conf":          "port=80",
+		"/var/log/build.log":     "first build",
+		"/etc/machine-id":        "1234",
+		"/usr/lib/app/module.py": "print()",
+	}, time.Unix(3000, 0)), NondeterministicPaths)
+	if !report.Reproducible() || report.Ignored != 0 {
+		t.Errorf("Expected identical builds, got %+v", report)
+	}
+}
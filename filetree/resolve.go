@@ -0,0 +1,70 @@
+package filetree
+
+import (
+	"archive/tar"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// MaxSymlinkDepth is the number of symlinks followed while resolving a single path (as with linux, which gives up
+// after 40).
+const MaxSymlinkDepth = 40
+
+// ResolvePath fetches the node at the given path like GetNode, but follows the symlinks (as stored in the node
+// metadata) of every path component, including the last one. Relative link targets are resolved from the directory
+// holding the link, absolute targets from the root of the tree. This connects paths across layouts such as merged-usr
+// (/bin -> usr/bin). An error is returned when a component does not exist, a symlink cycle is found, or more than
+// MaxSymlinkDepth symlinks are followed.
+func (tree *FileTree) ResolvePath(nodePath string) (*FileNode, error) {
+	remaining := strings.Split(path.Clean("/"+nodePath), "/")
+	node := tree.Root
+	// ancestors are the nodes leading to the current node (nodes shared with other trees do not know their parent in
+	// this tree, see cow.go)
+	var ancestors []*FileNode
+	followed := 0
+	visited := make(map[string]bool)
+	for len(remaining) > 0 {
+		name := remaining[0]
+		remaining = remaining[1:]
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			if len(ancestors) > 0 {
+				node = ancestors[len(ancestors)-1]
+				ancestors = ancestors[:len(ancestors)-1]
+			}
+			continue
+		}
+
+		child := node.Children[name]
+		if child == nil {
+			return nil, missingChild(node, nodePath)
+		}
+		if child.Data.FileInfo.TarHeader.Typeflag != tar.TypeSymlink {
+			ancestors = append(ancestors, node)
+			node = child
+			continue
+		}
+
+		// the same link resolving the same remainder again can only be a cycle
+		key := child.Path() + "\x00" + strings.Join(remaining, "/")
+		if visited[key] {
+			return nil, fmt.Errorf("%w: symlink cycle resolving %s at %s", ErrSymlinkLoop, nodePath, child.Path())
+		}
+		visited[key] = true
+		followed++
+		if followed > MaxSymlinkDepth {
+			return nil, fmt.Errorf("%w resolving %s", ErrSymlinkLoop, nodePath)
+		}
+
+		target := child.Data.FileInfo.TarHeader.Linkname
+		if strings.HasPrefix(target, "/") {
+			node = tree.Root
+			ancestors = nil
+		}
+		remaining = append(strings.Split(target, "/"), remaining...)
+	}
+	return node, nil
+}
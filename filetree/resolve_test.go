@@ -0,0 +1,100 @@
+package filetree
+
+import (
+	"archive/tar"
+	"strings"
+	"testing"
+)
+
+func symlinkTo(path, target string) FileInfo {
+	return FileInfo{
+		Path:      path,
+		TypeFlag:  tar.TypeSymlink,
+		TarHeader: tar.Header{Name: path, Typeflag: tar.TypeSymlink, Linkname: target},
+	}
+}
+
+func TestResolvePath(t *testing.T) {
+	tree := NewFileTree()
+	tree.AddPath("/usr/bin/bash", fileOfSize("/usr/bin/bash", 100))
+	tree.AddPath("/usr/lib/libc.so.6", fileOfSize("/usr/lib/libc.so.6", 10))
+	tree.AddPath("/bin", symlinkTo("/bin", "usr/bin"))
+	tree.AddPath("/lib", symlinkTo("/lib", "/usr/lib"))
+	tree.AddPath("/usr/bin/sh", symlinkTo("/usr/bin/sh", "bash"))
+	tree.AddPath("/usr/lib/libc.so", symlinkTo("/usr/lib/libc.so", "../lib/./libc.so.6"))
+	tree.AddPath("/loop/a", symlinkTo("/loop/a", "b"))
+	tree.AddPath("/loop/b", symlinkTo("/loop/b", "a"))
+	tree.AddPath("/dangling", symlinkTo("/dangling", "/nowhere"))
+
+	cases := map[string]string{
+		"/bin/bash":            "/usr/bin/bash",
+		"/bin/sh":              "/usr/bin/bash",
+		"bin/../bin/sh":        "/usr/bin/bash",
+		"/lib/libc.so":         "/usr/lib/libc.so.6",
+		"/usr/bin/bash":        "/usr/bin/bash",
+		"/bin":                 "/usr/bin",
+		"/../../lib/libc.so.6": "/usr/lib/libc.so.6",
+	}
+	for path, expected := range cases {
+		node, err := tree.ResolvePath(path)
+		if err != nil {
+			t.Errorf("Expected %s to resolve, got: %v", path, err)
+			continue
+		}
+		if node.Path() != expected {
+			t.Errorf("Expected %s to resolve to %s, got %s", path, expected, node.Path())
+		}
+	}
+
+	failures := map[string]string{
+		"/loop/a":      "cycle",
+		"/dangling":    "does not exist",
+		"/bin/missing": "does not exist",
+	}
+	for path, expected := range failures {
+		if _, err := tree.ResolvePath(path); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected resolving %s to fail with %q, got: %v", path, expected, err)
+		}
+	}
+
+	// a chain of links longer than the limit
+	deep := NewFileTree()
+	deep.AddPath("/target", fileOfSize("/target", 1))
+	previous := "/target"
+	for idx := 0; idx <= MaxSymlinkDepth; idx++ {
+		link := "/link" + strings.Repeat("x", idx)
+		deep.AddPath(link, symlinkTo(link, previous))
+		previous = link
+	}
+	if _, err := deep.ResolvePath(previous); err == nil || !strings.Contains(err.Error(), "too many") {
+		t.Errorf("Expected resolving a too long chain of links to fail, got: %v", err)
+	}
+}
+
+func TestCompareTreeMergedUsr(t *testing.T) {
+	lower := NewFileTree()
+	lower.AddPath("/bin/sh", fileOfSize("/bin/sh", 100))
+	lower.AddPath("/bin/removed", fileOfSize("/bin/removed", 1))
+
+	upper := NewFileTree()
+	upper.AddPath("/usr/bin/sh", fileOfSize("/usr/bin/sh", 100))
+	upper.AddPath("/bin", symlinkTo("/bin", "usr/bin"))
+
+	if err := lower.CompareTree(upper); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	expected := map[string]DiffType{
+		"/bin/sh":      Unchanged,
+		"/bin/removed": Removed,
+		"/usr/bin/sh":  Added,
+	}
+	for path, diffType := range expected {
+		node, err := lower.GetNode(path)
+		if err != nil {
+			t.Fatalf("Expected %s to exist: %v", path, err)
+		}
+		if err := AssertDiffType(node, diffType); err != nil {
+			t.Errorf("%v", err)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package filetree
+
+// CountRows counts the rows the visible descendants of every node take in the rendered tree (see Rows) in a single
+// pass, such that the rows of a large tree can be located (RowIndex, NodeAt) without visiting every row before them.
+// Counts are kept until the tree is modified beneath a node, and are updated along the path to the root when a single
+// node is collapsed, expanded, hidden or shown with SetCollapsed or SetHidden. The ViewInfo of a counted tree should
+// not be changed directly (or the rows should be counted again afterwards, which also drops the rows kept by Rows).
+func (tree *FileTree) CountRows() {
+	tree.rowList = nil
+	tree.Root.countRows()
+}
+
+// RowCount returns the number of rows of the rendered tree (counting the rows first when needed).
+func (tree *FileTree) RowCount() int {
+	return tree.Root.visibleRows()
+}
+
+// visibleRows returns the number of rows the visible descendants of this node take, counting them when needed.
+func (node *FileNode) visibleRows() int {
+	if !node.counted {
+		node.countRows()
+	}
+	return node.rows
+}
+
+// countRows counts the rows of every visible node of the subtree that is not already counted, deepest nodes first.
+func (node *FileNode) countRows() {
+	// the traversal is iterative (not recursive) to support arbitrarily deep trees
+	type frame struct {
+		node     *FileNode
+		expanded bool
+	}
+
+	stack := []*frame{{node: node}}
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		if top.node.counted {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+		if !top.expanded {
+			top.expanded = true
+			if !top.node.Data.ViewInfo.Collapsed {
+				for _, child := range top.node.Children {
+					if !child.Data.ViewInfo.Hidden && !child.counted {
+						stack = append(stack, &frame{node: child})
+					}
+				}
+			}
+			continue
+		}
+		stack = stack[:len(stack)-1]
+
+		var rows int
+		if !top.node.Data.ViewInfo.Collapsed {
+			for _, child := range top.node.Children {
+				if !child.Data.ViewInfo.Hidden {
+					rows += 1 + child.rows
+				}
+			}
+		}
+		top.node.rows = rows
+		top.node.counted = true
+	}
+}
+
+// invalidateRows drops the kept row counts of this node and of all of its parents, which include the rows of this
+// node, along with the kept rows of the tree (see Rows).
+func (node *FileNode) invalidateRows() {
+	for current := node; current != nil; current = current.Parent {
+		current.counted = false
+		if current.Parent == nil && current.Tree != nil {
+			current.Tree.rowList = nil
+		}
+	}
+}
+
+// SetCollapsed collapses (or expands) this node, only updating the row counts of the node and its parents.
+func (node *FileNode) SetCollapsed(collapsed bool) {
+	if node.Data.ViewInfo.Collapsed == collapsed {
+		return
+	}
+	node.Data.ViewInfo.Collapsed = collapsed
+	node.invalidateRows()
+}
+
+// SetHidden hides (or shows) this node, only updating the row counts of its parents.
+func (node *FileNode) SetHidden(hidden bool) {
+	if node.Data.ViewInfo.Hidden == hidden {
+		return
+	}
+	node.Data.ViewInfo.Hidden = hidden
+	if node.Parent != nil {
+		node.Parent.invalidateRows()
+	}
+}
+
+// CollapseAll collapses this node and every directory beneath it.
+func (node *FileNode) CollapseAll() {
+	node.collapseFrom(0)
+}
+
+// ExpandAll expands this node and every directory beneath it.
+func (node *FileNode) ExpandAll() {
+	node.collapseFrom(-1)
+}
+
+// SetCollapsedBelowDepth collapses every directory at the given depth (where the children of the root are at depth
+// 1) and beneath it, while expanding every directory above it, such that the rendered tree shows the given number of
+// levels (at least one).
+func (tree *FileTree) SetCollapsedBelowDepth(depth int) {
+	if depth < 1 {
+		depth = 1
+	}
+	// the root is at depth 0, and so is never collapsed
+	tree.materialize()
+	tree.Root.collapseFrom(depth)
+}
+
+// collapseFrom collapses every directory of the subtree of this node at the given depth (relative to this node, at
+// depth 0) and beneath it, expanding the directories above it; a negative depth expands every directory. Shared nodes
+// are copied into the tree of this node, and the row counts of the subtree and of the parents of this node are
+// dropped.
+func (node *FileNode) collapseFrom(depth int) {
+	type entry struct {
+		node  *FileNode
+		depth int
+	}
+
+	node.invalidateRows()
+	stack := []entry{{node, 0}}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		current.node.Data.ViewInfo.Collapsed = depth >= 0 && current.depth >= depth
+		current.node.counted = false
+		for name, child := range current.node.Children {
+			if len(child.Children) > 0 {
+				stack = append(stack, entry{current.node.ownChild(name), current.depth + 1})
+			}
+		}
+	}
+}
+
+// RowIndex returns the row of the given node in the rendered tree (see Rows), or -1 when the node is not shown (it, or
+// any of its parents, is hidden or beneath a collapsed directory). Only the siblings of the node and of its parents are
+// visited.
+func (tree *FileTree) RowIndex(node *FileNode) int {
+	if node == nil || node == tree.Root {
+		return -1
+	}
+	var row int
+	for current := node; current != tree.Root; current = current.Parent {
+		parent := current.Parent
+		if parent == nil || current.Data.ViewInfo.Hidden || parent.Data.ViewInfo.Collapsed {
+			return -1
+		}
+		for _, name := range parent.sortedChildNames() {
+			if name == current.Name {
+				break
+			}
+			if sibling := parent.Children[name]; !sibling.Data.ViewInfo.Hidden {
+				row += 1 + sibling.visibleRows()
+			}
+		}
+		// the row of the parent itself precedes the rows of its children
+		if parent != tree.Root {
+			row++
+		}
+	}
+	return row
+}
+
+// NodeAt returns the node shown at the given row of the rendered tree (see Rows), or nil when there is no such row.
+// Only the nodes along the path to the row (and their siblings) are visited.
+func (tree *FileTree) NodeAt(row int) *FileNode {
+	if row < 0 {
+		return nil
+	}
+	current := tree.Root
+	for !current.Data.ViewInfo.Collapsed {
+		var next *FileNode
+		for _, name := range current.sortedChildNames() {
+			child := current.Children[name]
+			if child.Data.ViewInfo.Hidden {
+				continue
+			}
+			if row == 0 {
+				return child
+			}
+			row--
+			if rows := child.visibleRows(); row < rows {
+				next = child
+				break
+			} else {
+				row -= rows
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		current = next
+	}
+	return nil
+}
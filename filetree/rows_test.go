@@ -0,0 +1,141 @@
+package filetree
+
+import (
+	"testing"
+)
+
+// checkRows verifies that the counted rows of the tree agree with the rows walked from the start of the tree.
+func checkRows(t *testing.T, tree *FileTree) {
+	t.Helper()
+	uncounted := tree.Copy()
+	expected := uncounted.Rows(0, uncounted.Size)
+
+	if tree.RowCount() != len(expected) {
+		t.Fatalf("Expected %d rows, counted %d", len(expected), tree.RowCount())
+	}
+	for idx, row := range expected {
+		node := tree.NodeAt(idx)
+		if node == nil || node.Path() != row.Node.Path() {
+			t.Fatalf("Expected %s at row %d, got %v", row.Node.Path(), idx, node)
+		}
+		if actual := tree.RowIndex(node); actual != idx {
+			t.Errorf("Expected %s at row %d, got row %d", node.Path(), idx, actual)
+		}
+		// render from the row on, skipping the (counted) rows before it
+		if rows := tree.Rows(idx, idx); len(rows) != 1 || rows[0].Node != node {
+			t.Errorf("Expected row %d to be %s, got %v", idx, node.Path(), rows)
+		}
+	}
+	if tree.NodeAt(len(expected)) != nil {
+		t.Errorf("Expected no node past the last row")
+	}
+}
+
+func TestCountRows(t *testing.T) {
+	tree := NewFileTree()
+	for _, path := range []string{"/etc/hosts", "/etc/ssl/certs/ca.pem", "/etc/ssl/openssl.cnf", "/usr/bin/ls", "/usr/lib/libc.so", "/var/log/"} {
+		tree.AddPath(path, FileInfo{})
+	}
+	tree.CountRows()
+	checkRows(t, tree)
+
+	ssl, _ := tree.GetNode("/etc/ssl")
+	ssl.SetCollapsed(true)
+	checkRows(t, tree)
+	if node, _ := tree.GetNode("/etc/ssl/certs"); tree.RowIndex(node) != -1 {
+		t.Errorf("Expected a node beneath a collapsed directory not to be shown")
+	}
+
+	usr, _ := tree.GetNode("/usr/bin")
+	usr.SetHidden(true)
+	checkRows(t, tree)
+
+	ssl.SetCollapsed(false)
+	tree.AddPath("/etc/ssl/private/key.pem", FileInfo{})
+	tree.RemovePath("/var/log")
+	checkRows(t, tree)
+}
+
+func TestCollapseBelowDepth(t *testing.T) {
+	lower := NewFileTree()
+	lower.AddPath("/etc/ssl/certs/ca.pem", FileInfo{})
+	lower.AddPath("/usr/lib/x86_64/libc.so", FileInfo{})
+	upper := NewFileTree()
+	upper.AddPath("/usr/share/doc/README", FileInfo{})
+	tree := StackRange([]*FileTree{lower, upper}, 0, 1)
+	tree.CountRows()
+
+	tree.SetCollapsedBelowDepth(2)
+	checkRows(t, tree)
+	for _, path := range []string{"/etc", "/usr"} {
+		if node, _ := tree.GetNode(path); node.Data.ViewInfo.Collapsed {
+			t.Errorf("Expected %s to be expanded", path)
+		}
+	}
+	for _, path := range []string{"/etc/ssl", "/usr/lib", "/usr/share"} {
+		if node, _ := tree.GetNode(path); !node.Data.ViewInfo.Collapsed {
+			t.Errorf("Expected %s to be collapsed", path)
+		}
+	}
+	if tree.RowCount() != 5 {
+		t.Errorf("Expected 5 rows, got %d", tree.RowCount())
+	}
+	if node, _ := lower.GetNode("/etc/ssl"); node.Data.ViewInfo.Collapsed {
+		t.Errorf("Expected the stacked tree not to modify the lower tree")
+	}
+
+	usr, _ := tree.GetNode("/usr")
+	usr.ExpandAll()
+	checkRows(t, tree)
+	if tree.RowCount() != 9 {
+		t.Errorf("Expected 9 rows, got %d", tree.RowCount())
+	}
+
+	tree.Root.CollapseAll()
+	tree.Root.SetCollapsed(false)
+	checkRows(t, tree)
+	if tree.RowCount() != 2 {
+		t.Errorf("Expected 2 rows, got %d", tree.RowCount())
+	}
+}
+
+func TestRowsKept(t *testing.T) {
+	tree := NewFileTree()
+	for _, path := range []string{"/etc/hosts", "/etc/ssl/certs/ca.pem", "/usr/bin/ls", "/var/log/"} {
+		tree.AddPath(path, FileInfo{})
+	}
+	tree.CountRows()
+	if rows := tree.Rows(0, 100); len(rows) != 10 {
+		t.Fatalf("Expected 10 rows, got %d", len(rows))
+	}
+	if tree.rowList == nil {
+		t.Fatalf("Expected the rows to be kept")
+	}
+	if rows := tree.Rows(8, 9); len(rows) != 2 || rows[0].Node.Path() != "/var" || rows[1].Node.Path() != "/var/log" {
+		t.Errorf("Expected the last two rows to be /var and /var/log, got %v", rows)
+	}
+	if rows := tree.Rows(10, 12); len(rows) != 0 {
+		t.Errorf("Expected no rows past the last row, got %d", len(rows))
+	}
+
+	// every change to the shown rows drops the kept rows
+	ssl, _ := tree.GetNode("/etc/ssl")
+	ssl.SetCollapsed(true)
+	checkRows(t, tree)
+	if rows := tree.Rows(0, 100); len(rows) != 8 {
+		t.Errorf("Expected 8 rows once collapsed, got %d", len(rows))
+	}
+	usr, _ := tree.GetNode("/usr")
+	usr.SetHidden(true)
+	if rows := tree.Rows(0, 100); len(rows) != 5 {
+		t.Errorf("Expected 5 rows once hidden, got %d", len(rows))
+	}
+	tree.AddPath("/var/log/syslog", FileInfo{})
+	if rows := tree.Rows(0, 100); len(rows) != 6 || rows[5].Node.Path() != "/var/log/syslog" {
+		t.Errorf("Expected the added file as the last row, got %v", rows)
+	}
+	tree.RemovePath("/etc")
+	if rows := tree.Rows(0, 100); len(rows) != 3 {
+		t.Errorf("Expected 3 rows once removed, got %d", len(rows))
+	}
+}
@@ -0,0 +1,62 @@
+package filetree
+
+// ComputeAggregateSizes computes the cumulative size of every node of the tree (the size of the node itself and of
+// all of its descendants, not counting removed nodes) in a single pass, such that AggregateSize is answered without
+// visiting the subtree again. Sizes are kept until the tree is modified beneath a node.
+func (tree *FileTree) ComputeAggregateSizes() {
+	tree.Root.computeAggregateSize()
+}
+
+// AggregateSize returns the cumulative size of this node and all of its descendants, not counting removed nodes. The
+// size is computed (and kept) on first use when ComputeAggregateSizes has not been called since the subtree changed.
+func (node *FileNode) AggregateSize() int64 {
+	if !node.sized {
+		node.computeAggregateSize()
+	}
+	return node.aggregateSize
+}
+
+// computeAggregateSize sizes every node of the subtree that is not already sized, deepest nodes first.
+func (node *FileNode) computeAggregateSize() {
+	// the traversal is iterative (not recursive) to support arbitrarily deep trees
+	type frame struct {
+		node     *FileNode
+		children []*FileNode
+	}
+
+	stack := []*frame{{node: node}}
+	for len(stack) > 0 {
+		top := stack[len(stack)-1]
+		if top.children == nil && !top.node.sized {
+			top.children = make([]*FileNode, 0, len(top.node.Children))
+			for _, child := range top.node.Children {
+				top.children = append(top.children, child)
+				if !child.sized {
+					stack = append(stack, &frame{node: child})
+				}
+			}
+			continue
+		}
+		stack = stack[:len(stack)-1]
+		if top.node.sized {
+			continue
+		}
+
+		var size int64
+		if top.node.Data.DiffType != Removed {
+			size = top.node.Data.FileInfo.Size()
+		}
+		for _, child := range top.children {
+			size += child.aggregateSize
+		}
+		top.node.aggregateSize = size
+		top.node.sized = true
+	}
+}
+
+// invalidateSize drops the kept size of this node and of all of its parents, which include the size of this node.
+func (node *FileNode) invalidateSize() {
+	for current := node; current != nil && current.sized; current = current.Parent {
+		current.sized = false
+	}
+}
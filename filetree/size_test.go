@@ -0,0 +1,53 @@
+package filetree
+
+import "testing"
+
+func TestAggregateSize(t *testing.T) {
+	tree := NewFileTree()
+	tree.AddPath("/etc/hosts", fileOfSize("/etc/hosts", 100))
+	tree.AddPath("/etc/ssl/cert.pem", fileOfSize("/etc/ssl/cert.pem", 1000))
+	tree.AddPath("/etc/ssl/key.pem", fileOfSize("/etc/ssl/key.pem", 10))
+	tree.AddPath("/bin/sh", fileOfSize("/bin/sh", 5000))
+	tree.ComputeAggregateSizes()
+
+	sizeOf := func(path string) int64 {
+		node, err := tree.GetNode(path)
+		if err != nil {
+			t.Fatalf("Expected %s to exist: %v", path, err)
+		}
+		return node.AggregateSize()
+	}
+
+	cases := map[string]int64{"/etc": 1110, "/etc/ssl": 1010, "/etc/hosts": 100, "/bin": 5000}
+	for path, expected := range cases {
+		if actual := sizeOf(path); actual != expected {
+			t.Errorf("Expected %s to be %d bytes, got %d", path, expected, actual)
+		}
+	}
+	if actual := tree.Root.AggregateSize(); actual != 6110 {
+		t.Errorf("Expected the root to be 6110 bytes, got %d", actual)
+	}
+
+	// modifications beneath a directory invalidate the sizes of its parents
+	tree.AddPath("/etc/ssl/ca.pem", fileOfSize("/etc/ssl/ca.pem", 1))
+	if actual := sizeOf("/etc"); actual != 1111 {
+		t.Errorf("Expected an added file to be counted, got %d", actual)
+	}
+	tree.RemovePath("/etc/hosts")
+	if actual := sizeOf("/etc"); actual != 1011 {
+		t.Errorf("Expected a removed path not to be counted, got %d", actual)
+	}
+	key, _ := tree.GetNode("/etc/ssl/key.pem")
+	key.AssignDiffType(Removed)
+	if actual := sizeOf("/etc/ssl"); actual != 1001 {
+		t.Errorf("Expected a removed file not to be counted, got %d", actual)
+	}
+	if actual := tree.Root.AggregateSize(); actual != 6001 {
+		t.Errorf("Expected the root to be 6001 bytes, got %d", actual)
+	}
+
+	// copies keep the computed sizes
+	if actual := tree.Copy().Root.AggregateSize(); actual != 6001 {
+		t.Errorf("Expected the copy to be 6001 bytes, got %d", actual)
+	}
+}
@@ -0,0 +1,40 @@
+package filetree
+
+import "fmt"
+
+// SquashSimulation is the outcome of squashing a range of layers into a single layer (see SimulateSquash).
+type SquashSimulation struct {
+	// From and To are the first and last layer of the range (inclusive, counting from the oldest layer)
+	From int
+	To   int
+	// Size is the bytes the layers of the range store, and SquashedSize the bytes the squashed layer would store
+	Size         uint64
+	SquashedSize uint64
+}
+
+// Savings is the bytes that squashing the range saves.
+func (simulation SquashSimulation) Savings() uint64 {
+	return simulation.Size - simulation.SquashedSize
+}
+
+// SimulateSquash computes the size of the single layer that the given range of layers (inclusive, counting from the
+// oldest layer) would be squashed into: only the files of the range that are still visible after its last layer are
+// stored, while the copies the range overwrites or removes (of its own files) are not.
+func SimulateSquash(trees []*FileTree, from, to int) (SquashSimulation, error) {
+	simulation := SquashSimulation{From: from, To: to}
+	if from < 0 || to >= len(trees) || from > to {
+		return simulation, fmt.Errorf("invalid layer range %d-%d (the image has %d layers)", from, to, len(trees))
+	}
+	for idx := from; idx <= to; idx++ {
+		simulation.Size += trees[idx].FileSize
+	}
+	for path, layer := range providers(trees[:to+1]) {
+		if layer < from {
+			continue
+		}
+		if node, err := trees[layer].lookup(path); err == nil {
+			simulation.SquashedSize += uint64(node.Data.FileInfo.Size())
+		}
+	}
+	return simulation, nil
+}
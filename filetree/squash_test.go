@@ -0,0 +1,40 @@
+package filetree
+
+import (
+	"testing"
+)
+
+func TestSimulateSquash(t *testing.T) {
+	sizedTree := func(files map[string]int64) *FileTree {
+		tree := NewFileTree()
+		for path, size := range files {
+			tree.AddPath(path, fileOfSize(path, size))
+			tree.FileSize += uint64(size)
+		}
+		return tree
+	}
+	base := sizedTree(map[string]int64{"/bin/sh": 100, "/etc/config": 10})
+	build := sizedTree(map[string]int64{"/src/app.c": 50, "/tmp/objects.o": 300})
+	cleanup := NewFileTree()
+	cleanup.AddPath("/tmp/.wh.objects.o", FileInfo{})
+	cleanup.AddPath("/etc/.wh.config", FileInfo{})
+	install := sizedTree(map[string]int64{"/usr/bin/app": 80})
+	trees := []*FileTree{base, build, cleanup, install}
+
+	simulation, err := SimulateSquash(trees, 1, 3)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if simulation.Size != 430 || simulation.SquashedSize != 130 || simulation.Savings() != 300 {
+		t.Errorf("Expected the removed object file to be saved, got %+v", simulation)
+	}
+
+	simulation, _ = SimulateSquash(trees, 1, 1)
+	if simulation.Savings() != 0 {
+		t.Errorf("Expected no savings for a single layer, got %+v", simulation)
+	}
+
+	if _, err := SimulateSquash(trees, 2, 4); err == nil {
+		t.Errorf("Expected an error for a range beyond the last layer")
+	}
+}
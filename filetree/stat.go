@@ -0,0 +1,69 @@
+package filetree
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// PathStat describes a single path across all layers of an image.
+type PathStat struct {
+	// Info is the metadata of the path as last added or modified
+	Info FileInfo
+	// Layer is the layer that last added or modified the path
+	Layer int
+	// AddedLayer is the layer that introduced the path (since it was last removed, if ever)
+	AddedLayer int
+	// RemovedLayer is the layer that removed the path (or -1 when it was not removed)
+	RemovedLayer int
+}
+
+// InFinalImage indicates if the path survives to the final image.
+func (stat *PathStat) InFinalImage() bool {
+	return stat.RemovedLayer < 0
+}
+
+// isRemovedBy indicates if the given layer tree has a whiteout for the given path (or any of its parent directories),
+// or hides the path with an opaque parent directory without providing it again.
+func isRemovedBy(tree *FileTree, filePath string) bool {
+	for current := filePath; current != "/"; current = path.Dir(current) {
+		if _, err := tree.GetNode(path.Join(path.Dir(current), whiteoutPrefix+path.Base(current))); err == nil {
+			return true
+		}
+		if parent, err := tree.GetNode(path.Dir(current)); err == nil && parent.Opaque {
+			if _, err = tree.GetNode(filePath); err != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// StatPath follows the given path through the given layer trees (oldest first), noting which layers added, modified
+// and removed it.
+func StatPath(trees []*FileTree, filePath string) (*PathStat, error) {
+	filePath = path.Clean("/" + strings.TrimSpace(filePath))
+
+	var stat *PathStat
+	for idx, tree := range trees {
+		if stat != nil && stat.InFinalImage() && isRemovedBy(tree, filePath) {
+			stat.RemovedLayer = idx
+			continue
+		}
+		node, err := tree.GetNode(filePath)
+		if err != nil || node.IsWhiteout() {
+			continue
+		}
+		if stat == nil || !stat.InFinalImage() {
+			stat = &PathStat{AddedLayer: idx}
+		}
+		stat.Info = node.Data.FileInfo
+		stat.Layer = idx
+		stat.RemovedLayer = -1
+	}
+
+	if stat == nil {
+		return nil, fmt.Errorf("%w in any layer: %s", ErrPathNotFound, filePath)
+	}
+	return stat, nil
+}
@@ -0,0 +1,39 @@
+package filetree
+
+import (
+	"testing"
+)
+
+func TestStatPath(t *testing.T) {
+	base := NewFileTree()
+	base.AddPath("/usr/bin/python3", fileOfSize("/usr/bin/python3", 100))
+	base.AddPath("/tmp/build/cache", fileOfSize("/tmp/build/cache", 10))
+
+	upgrade := NewFileTree()
+	upgrade.AddPath("/usr/bin/python3", fileOfSize("/usr/bin/python3", 200))
+
+	cleanup := NewFileTree()
+	cleanup.AddPath("/tmp/.wh.build", FileInfo{})
+
+	trees := []*FileTree{base, upgrade, cleanup}
+
+	stat, err := StatPath(trees, "usr/bin/python3")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if stat.AddedLayer != 0 || stat.Layer != 1 || !stat.InFinalImage() || stat.Info.TarHeader.Size != 200 {
+		t.Errorf("Unexpected stat: %+v", stat)
+	}
+
+	stat, err = StatPath(trees, "/tmp/build/cache")
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if stat.AddedLayer != 0 || stat.Layer != 0 || stat.InFinalImage() || stat.RemovedLayer != 2 {
+		t.Errorf("Unexpected stat: %+v", stat)
+	}
+
+	if _, err = StatPath(trees, "/etc/missing"); err == nil {
+		t.Errorf("Expected an error for a missing path")
+	}
+}
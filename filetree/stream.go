@@ -0,0 +1,38 @@
+package filetree
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+)
+
+// NewFromTarReader builds the tree of a single layer from its tar stream. Each file is read once as it streams by
+// (its contents are digested, never buffered), so layers of any size can be read with little memory. Whiteout files
+// are kept as whiteout nodes (see IsWhiteout) and opaque whiteouts mark their directory as Opaque, both of which remove
+// the paths of lower layers once the tree is stacked.
+func NewFromTarReader(reader io.Reader) (*FileTree, error) {
+	tree := NewFileTree()
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return tree, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read layer: %v", err)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeXGlobalHeader, tar.TypeXHeader:
+			continue
+		}
+		info, err := NewFileInfo(tarReader, header, header.Name)
+		if err != nil {
+			return nil, fmt.Errorf("could not read layer: %v", err)
+		}
+		tree.FileSize += uint64(info.Size())
+		if _, err := tree.AddPath(header.Name, info); err != nil {
+			return nil, fmt.Errorf("could not add %s: %v", header.Name, err)
+		}
+	}
+}
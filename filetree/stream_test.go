@@ -0,0 +1,109 @@
+package filetree
+
+import (
+	"archive/tar"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/wagoodman/dive/testkit"
+)
+
+func TestNewFromTarReader(t *testing.T) {
+	withAnalysis(t, AnalyzeEntropy)
+	layer := testkit.Layer{Entries: []testkit.Entry{
+		testkit.Dir("/etc"),
+		testkit.File("/etc/hosts", "127.0.0.1 localhost\n"),
+		testkit.Whiteout("/etc/motd"),
+		testkit.SparseFile("/var/lib/data.db", 1<<20, "header"),
+	}}
+	layerTar, err := layer.LayerTar()
+	if err != nil {
+		t.Fatalf("could not build layer: %v", err)
+	}
+
+	tree, err := NewFromTarReader(bytes.NewReader(layerTar))
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if tree.FileSize != 1<<20+20 {
+		t.Errorf("Expected the sizes of the files to be counted, got %d", tree.FileSize)
+	}
+
+	hosts, err := tree.GetNode("/etc/hosts")
+	if err != nil {
+		t.Fatalf("Expected /etc/hosts in the tree: %v", err)
+	}
+	if hosts.Data.FileInfo.MD5sum == [16]byte{} || hosts.Data.FileInfo.TarHeader.Size != 20 {
+		t.Errorf("Expected the contents of /etc/hosts to be digested, got %+v", hosts.Data.FileInfo)
+	}
+
+	data, err := tree.GetNode("/var/lib/data.db")
+	if err != nil || data.Data.FileInfo.Entropy > 0.1 {
+		t.Errorf("Expected a mostly empty /var/lib/data.db, got %v (%v)", data, err)
+	}
+
+	whiteout, err := tree.GetNode("/etc/.wh.motd")
+	if err != nil || !whiteout.IsWhiteout() {
+		t.Fatalf("Expected a whiteout for /etc/motd, got %v (%v)", whiteout, err)
+	}
+	lower := NewFileTree()
+	lower.AddPath("/etc/motd", FileInfo{})
+	if err := lower.Stack(tree); err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if _, err := lower.GetNode("/etc/motd"); err == nil {
+		t.Errorf("Expected the whiteout to remove /etc/motd")
+	}
+
+	if _, err := NewFromTarReader(bytes.NewReader([]byte("not a tar file, but long enough to hold a header block? no"))); err == nil {
+		t.Errorf("Expected an error for a corrupt layer")
+	}
+	// an interrupted download ends within the contents of a file
+	truncated := layerTar[:bytes.Index(layerTar, []byte("127.0.0.1"))+3]
+	if _, err := NewFromTarReader(bytes.NewReader(truncated)); err == nil {
+		t.Errorf("Expected an error for a truncated layer")
+	}
+}
+
+func TestHardlinksCountedOnce(t *testing.T) {
+	// like some rpm based image builders, record the size of the linked file in the hard link header
+	var buffer bytes.Buffer
+	writer := tar.NewWriter(&buffer)
+	content := strings.Repeat("x", 1000)
+	headers := []*tar.Header{
+		{Name: "usr/lib/libfoo.so.1", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))},
+		{Name: "usr/lib/libfoo.so", Typeflag: tar.TypeLink, Mode: 0644, Linkname: "usr/lib/libfoo.so.1", Size: int64(len(content))},
+		{Name: "usr/bin/foo", Typeflag: tar.TypeLink, Mode: 0644, Linkname: "./usr/lib/libfoo.so.1", Size: int64(len(content))},
+	}
+	for _, header := range headers {
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatalf("could not write %s: %v", header.Name, err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			writer.Write([]byte(content))
+		}
+	}
+	writer.Close()
+
+	tree, err := NewFromTarReader(&buffer)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if tree.FileSize != 1000 {
+		t.Errorf("Expected the linked file to be counted once, got %d bytes", tree.FileSize)
+	}
+	for _, path := range []string{"/usr/lib/libfoo.so.1", "/usr/lib/libfoo.so", "/usr/bin/foo"} {
+		node, err := tree.GetNode(path)
+		if err != nil {
+			t.Fatalf("Expected %s to exist: %v", path, err)
+		}
+		if node.Data.FileInfo.LinkID != "/usr/lib/libfoo.so.1" {
+			t.Errorf("Expected %s to share the inode of the linked file, got %q", path, node.Data.FileInfo.LinkID)
+		}
+	}
+	lib, _ := tree.GetNode("/usr/lib")
+	if size := lib.AggregateSize(); size != 1000 {
+		t.Errorf("Expected the directory size to count the linked file once, got %d bytes", size)
+	}
+}
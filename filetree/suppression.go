@@ -0,0 +1,150 @@
+package filetree
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SuppressAll is the check of a suppression that applies to the findings of every check.
+const SuppressAll = "*"
+
+// suppressionDate is the layout of the expiry date of a suppression.
+const suppressionDate = "2006-01-02"
+
+// suppressionLabel is the image label (and label prefix) declaring suppressions: "dive.ignore" suppresses the given
+// paths for every check, "dive.ignore.<check>" for a single check, while "dive.ignore.justification" and
+// "dive.ignore.expires" apply to all suppressions declared by the image.
+const suppressionLabel = "dive.ignore"
+
+// Suppression is an accepted exception to a check (a test assertion, or the findings of an analysis such as volumes
+// or entropy) for the paths it matches. Suppressed findings are not reported as failures, but are still listed along
+// with the justification, such that accepted exceptions remain visible. A suppression no longer applies once it
+// expires.
+type Suppression struct {
+	// Check is the name of the suppressed check, or "*" (or empty) for every check
+	Check string `yaml:"check"`
+	// Path is the glob of the suppressed paths (where "**" matches any number of directories)
+	Path string `yaml:"path"`
+	// Justification explains why the exception is accepted
+	Justification string `yaml:"justification"`
+	// Expires is the last day (YYYY-MM-DD) the suppression applies (it does not expire when empty)
+	Expires string `yaml:"expires"`
+	// Source describes where the suppression is declared (a config file or an image label)
+	Source string `yaml:"-"`
+}
+
+// Suppressions are the accepted exceptions of an image.
+type Suppressions []Suppression
+
+// Validate verifies that the suppression has a path, a justification and a well formed expiry date.
+func (suppression Suppression) Validate() error {
+	if suppression.Path == "" {
+		return fmt.Errorf("suppression from %s has no path", suppression.Source)
+	}
+	if strings.TrimSpace(suppression.Justification) == "" {
+		return fmt.Errorf("suppression of %s from %s has no justification", suppression.Path, suppression.Source)
+	}
+	if suppression.Expires != "" {
+		if _, err := time.Parse(suppressionDate, suppression.Expires); err != nil {
+			return fmt.Errorf("suppression of %s from %s has an invalid expiry date '%s' (expected YYYY-MM-DD)", suppression.Path, suppression.Source, suppression.Expires)
+		}
+	}
+	return nil
+}
+
+// ExpiredAt indicates if the suppression no longer applies at the given time (after the last day it applies).
+func (suppression Suppression) ExpiredAt(now time.Time) bool {
+	if suppression.Expires == "" {
+		return false
+	}
+	expires, err := time.Parse(suppressionDate, suppression.Expires)
+	if err != nil {
+		return true
+	}
+	return !now.UTC().Before(expires.AddDate(0, 0, 1))
+}
+
+// String describes the suppression for auditing (e.g. "installed by the vendor (until 2024-06-30, .dive.yaml)").
+func (suppression Suppression) String() string {
+	details := []string{suppression.Source}
+	if suppression.Expires != "" {
+		details = append([]string{"until " + suppression.Expires}, details...)
+	}
+	return fmt.Sprintf("%s (%s)", suppression.Justification, strings.Join(details, ", "))
+}
+
+// SuppressionsFromLabels derives the suppressions declared by the given image labels, e.g.:
+//
+//	dive.ignore=/opt/models/**
+//	dive.ignore.volumes=/var/lib/mysql/**,/var/log/**
+//	dive.ignore.justification=models are mounted read-only in production
+//	dive.ignore.expires=2024-12-31
+func SuppressionsFromLabels(labels map[string]string) Suppressions {
+	justification := labels[suppressionLabel+".justification"]
+	if justification == "" {
+		justification = "declared by the image"
+	}
+	expires := labels[suppressionLabel+".expires"]
+
+	var keys []string
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var suppressions Suppressions
+	for _, key := range keys {
+		var check string
+		switch {
+		case key == suppressionLabel:
+			check = SuppressAll
+		case key == suppressionLabel+".justification" || key == suppressionLabel+".expires":
+			continue
+		case strings.HasPrefix(key, suppressionLabel+"."):
+			check = strings.TrimPrefix(key, suppressionLabel+".")
+		default:
+			continue
+		}
+		for _, pattern := range strings.Split(labels[key], ",") {
+			if pattern = strings.TrimSpace(pattern); pattern != "" {
+				suppressions = append(suppressions, Suppression{
+					Check:         check,
+					Path:          pattern,
+					Justification: justification,
+					Expires:       expires,
+					Source:        "label " + key,
+				})
+			}
+		}
+	}
+	return suppressions
+}
+
+// Match returns the suppression of the given check that applies to the given path at the given time, if any.
+func (suppressions Suppressions) Match(check, path string, now time.Time) (Suppression, bool) {
+	for _, suppression := range suppressions {
+		if suppression.Check != "" && suppression.Check != SuppressAll && suppression.Check != check {
+			continue
+		}
+		if suppression.ExpiredAt(now) {
+			continue
+		}
+		if globMatch(suppression.Path, path) {
+			return suppression, true
+		}
+	}
+	return Suppression{}, false
+}
+
+// Expired returns the suppressions that no longer apply at the given time.
+func (suppressions Suppressions) Expired(now time.Time) Suppressions {
+	var expired Suppressions
+	for _, suppression := range suppressions {
+		if suppression.ExpiredAt(now) {
+			expired = append(expired, suppression)
+		}
+	}
+	return expired
+}
@@ -0,0 +1,54 @@
+package filetree
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSuppressions(t *testing.T) {
+	now := time.Date(2024, 6, 30, 18, 0, 0, 0, time.UTC)
+	suppressions := append(SuppressionsFromLabels(map[string]string{
+		"dive.ignore":               "/opt/models/**",
+		"dive.ignore.volumes":       "/var/lib/mysql/**, /var/log/**",
+		"dive.ignore.justification": "mounted at runtime",
+		"maintainer":                "someone",
+	}), Suppression{Check: "test", Path: "/tmp/**", Justification: "build cache", Expires: "2024-06-29", Source: "config"})
+
+	if len(suppressions) != 4 {
+		t.Fatalf("Expected 4 suppressions, got %d: %+v", len(suppressions), suppressions)
+	}
+	cases := []struct {
+		check, path string
+		expected    bool
+	}{
+		{"entropy", "/opt/models/weights.bin", true},
+		{"volumes", "/var/lib/mysql/seed.sql", true},
+		{"entropy", "/var/lib/mysql/seed.sql", false},
+		{"volumes", "/var/log", true},
+		{"test", "/tmp/build", false},
+	}
+	for _, test := range cases {
+		suppression, ok := suppressions.Match(test.check, test.path, now)
+		if ok != test.expected {
+			t.Errorf("Expected %s of %s to be suppressed: %v", test.check, test.path, test.expected)
+		}
+		if ok && suppression.Justification != "mounted at runtime" {
+			t.Errorf("Expected the justification of the labels, got %q", suppression.Justification)
+		}
+	}
+
+	// a suppression applies through its last day
+	if _, ok := suppressions.Match("test", "/tmp/build", now.AddDate(0, 0, -1)); !ok {
+		t.Errorf("Expected the suppression to apply on its last day")
+	}
+	if expired := suppressions.Expired(now); len(expired) != 1 || expired[0].Path != "/tmp/**" {
+		t.Errorf("Expected the config suppression to be expired, got %+v", expired)
+	}
+
+	if err := (Suppression{Path: "/tmp", Source: "config"}).Validate(); err == nil {
+		t.Errorf("Expected a suppression without justification to be invalid")
+	}
+	if err := (Suppression{Path: "/tmp", Justification: "x", Expires: "30/06/2024"}).Validate(); err == nil {
+		t.Errorf("Expected a malformed expiry date to be invalid")
+	}
+}
@@ -0,0 +1,76 @@
+package filetree
+
+import (
+	"sort"
+)
+
+// Tags are key/value annotations analyzers attach to files (e.g. a secret scanner tagging "secret" with the kind of
+// secret found, or an SBOM matcher tagging "package" with the package owning the file), to be queried and rendered
+// later. Tags are part of the FileInfo of a node, thus survive stacking, copying and serialization like the rest of
+// the file metadata. Unlike the Badges of the UI, they are not limited to a fixed set of findings.
+
+// Tag returns the value of the tag with the given key, and if the node has the tag at all.
+func (node *FileNode) Tag(key string) (string, bool) {
+	value, ok := node.Data.FileInfo.Tags[key]
+	return value, ok
+}
+
+// SetTag attaches the tag with the given key and value to the node (replacing any value the tag had). The node must
+// belong to its tree (as returned by GetNode), see SetTag of FileTree.
+func (node *FileNode) SetTag(key, value string) {
+	if node.Data.FileInfo.Tags == nil {
+		node.Data.FileInfo.Tags = make(map[string]string)
+	}
+	node.Data.FileInfo.Tags[key] = value
+}
+
+// RemoveTag detaches the tag with the given key from the node. The node must belong to its tree (as returned by
+// GetNode).
+func (node *FileNode) RemoveTag(key string) {
+	delete(node.Data.FileInfo.Tags, key)
+	if len(node.Data.FileInfo.Tags) == 0 {
+		node.Data.FileInfo.Tags = nil
+	}
+}
+
+// SetTag attaches the tag with the given key and value to the node at the given path.
+func (tree *FileTree) SetTag(path, key, value string) error {
+	node, err := tree.GetNode(path)
+	if err != nil {
+		return err
+	}
+	node.SetTag(key, value)
+	return nil
+}
+
+// NodesWithTag returns every node of the tree that has the tag with the given key, in path order. The nodes may be
+// shared with other trees (see cow.go), so must not be modified: use SetTag of the tree to change their tags.
+func (tree *FileTree) NodesWithTag(key string) []*FileNode {
+	var paths []string
+	nodes := make(map[string]*FileNode)
+	visitKeyed(tree.Root, "", func(node *FileNode, nodePath string) {
+		if _, ok := node.Data.FileInfo.Tags[key]; ok && node != tree.Root {
+			paths = append(paths, nodePath)
+			nodes[nodePath] = node
+		}
+	})
+	sort.Strings(paths)
+
+	result := make([]*FileNode, len(paths))
+	for idx, nodePath := range paths {
+		result[idx] = nodes[nodePath]
+	}
+	return result
+}
+
+// copyTags duplicates the given tags (nil when there are none).
+func copyTags(tags map[string]string) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(tags))
+	for key, value := range tags {
+		result[key] = value
+	}
+	return result
+}
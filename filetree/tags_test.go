@@ -0,0 +1,46 @@
+package filetree
+
+import (
+	"testing"
+)
+
+func TestNodesWithTag(t *testing.T) {
+	lower := NewFileTree()
+	lower.AddPath("/etc/ssl/key.pem", FileInfo{})
+	lower.AddPath("/etc/hosts", FileInfo{})
+	if err := lower.SetTag("/etc/ssl/key.pem", "secret", "private key"); err != nil {
+		t.Fatalf("Could not tag: %v", err)
+	}
+	upper := NewFileTree()
+	upper.AddPath("/app/.env", FileInfo{})
+	upper.SetTag("/app/.env", "secret", "aws key")
+
+	stacked := StackRange([]*FileTree{lower, upper}, 0, 1)
+	var paths []string
+	for _, node := range stacked.NodesWithTag("secret") {
+		paths = append(paths, node.Path())
+	}
+	if len(paths) != 2 || paths[0] != "/app/.env" || paths[1] != "/etc/ssl/key.pem" {
+		t.Errorf("Expected both secrets in path order, got %v", paths)
+	}
+
+	// tagging a node of the stacked tree leaves the layer it is shared with unchanged
+	if err := stacked.SetTag("/etc/hosts", "secret", "none"); err != nil {
+		t.Fatalf("Could not tag: %v", err)
+	}
+	if len(stacked.NodesWithTag("secret")) != 3 || len(lower.NodesWithTag("secret")) != 1 {
+		t.Errorf("Expected the tag on the stacked tree only")
+	}
+	node, _ := stacked.GetNode("/etc/hosts")
+	if value, ok := node.Tag("secret"); !ok || value != "none" {
+		t.Errorf("Expected tag value 'none', got %q", value)
+	}
+	node.RemoveTag("secret")
+	if _, ok := node.Tag("secret"); ok || node.Data.FileInfo.Tags != nil {
+		t.Errorf("Expected the tag to be removed")
+	}
+
+	if err := stacked.SetTag("/missing", "secret", ""); err == nil {
+		t.Errorf("Expected an error tagging a missing path")
+	}
+}
@@ -4,7 +4,8 @@ import (
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
-	"sort"
+	"path"
+	"regexp"
 	"strings"
 )
 
@@ -16,6 +17,7 @@ const (
 	lastItem             = "└─"
 	whiteoutPrefix       = ".wh."
 	doubleWhiteoutPrefix = ".wh..wh.."
+	opaqueWhiteout       = ".wh..wh..opq"
 	uncollapsedItem      = "─ "
 	collapsedItem        = "⊕ "
 )
@@ -27,6 +29,18 @@ type FileTree struct {
 	FileSize uint64
 	Name     string
 	Id       uuid.UUID
+	// Comparer determines how nodes of this tree changed when compared to another tree (DefaultComparer when nil)
+	Comparer Comparer
+	// Warnings are the non-fatal issues noted while building the tree from a layer (see Warn)
+	Warnings []Warning
+	// shared indicates the tree may hold nodes of other trees (see cow.go)
+	shared bool
+	// lent indicates nodes of the tree may be held by other trees, which must not be removed (see cow.go)
+	lent bool
+	// nodes indexes the nodes of the tree by path (nil until the first lookup, see index.go)
+	nodes map[string]*FileNode
+	// rowList is the rows of the whole rendered tree (nil until first rendered, see Rows)
+	rowList []Row
 }
 
 // NewFileTree creates an empty FileTree
@@ -40,102 +54,24 @@ func NewFileTree() (tree *FileTree) {
 	return tree
 }
 
-// renderParams is a representation of a FileNode in the context of the greater tree. All
-// data stored is necessary for rendering a single line in a tree format.
-type renderParams struct {
-	node          *FileNode
-	spaces        []bool
-	childSpaces   []bool
-	showCollapsed bool
-	isLast        bool
-}
-
-// renderStringTreeBetween returns a string representing the given tree between the given rows. Since each node
-// is rendered on its own line, the returned string shows the visible nodes not affected by a collapsed parent.
-func (tree *FileTree) renderStringTreeBetween(startRow, stopRow int, showAttributes bool) string {
-	// generate a list of nodes to render
-	var params = make([]renderParams, 0)
-	var result string
-
-	// visit from the front of the list
-	var paramsToVisit = []renderParams{{node: tree.Root, spaces: []bool{}, showCollapsed: false, isLast: false}}
-	for currentRow := 0; len(paramsToVisit) > 0 && currentRow <= stopRow; currentRow++ {
-		// pop the first node
-		var currentParams renderParams
-		currentParams, paramsToVisit = paramsToVisit[0], paramsToVisit[1:]
-
-		// take note of the next nodes to visit later
-		var keys []string
-		for key := range currentParams.node.Children {
-			keys = append(keys, key)
-		}
-		// we should always visit nodes in order
-		sort.Strings(keys)
-
-		var childParams = make([]renderParams, 0)
-		for idx, name := range keys {
-			child := currentParams.node.Children[name]
-			// don't visit this node...
-			if child.Data.ViewInfo.Hidden || currentParams.node.Data.ViewInfo.Collapsed {
-				continue
-			}
-
-			// visit this node...
-			isLast := idx == (len(currentParams.node.Children) - 1)
-			showCollapsed := child.Data.ViewInfo.Collapsed && len(child.Children) > 0
-
-			// completely copy the reference slice
-			childSpaces := make([]bool, len(currentParams.childSpaces))
-			copy(childSpaces, currentParams.childSpaces)
-
-			if len(child.Children) > 0 && !child.Data.ViewInfo.Collapsed {
-				childSpaces = append(childSpaces, isLast)
-			}
-
-			childParams = append(childParams, renderParams{
-				node:          child,
-				spaces:        currentParams.childSpaces,
-				childSpaces:   childSpaces,
-				showCollapsed: showCollapsed,
-				isLast:        isLast,
-			})
-		}
-		// keep the child nodes to visit later
-		paramsToVisit = append(childParams, paramsToVisit...)
-
-		// never process the root node
-		if currentParams.node == tree.Root {
-			currentRow--
-			continue
-		}
-
-		// process the current node
-		if currentRow >= startRow && currentRow <= stopRow {
-			params = append(params, currentParams)
-		}
-	}
-
-	// render the result
-	for idx := range params {
-		currentParams := params[idx]
-
-		if showAttributes {
-			result += currentParams.node.MetadataString() + " "
-		}
-		result += currentParams.node.renderTreeLine(currentParams.spaces, currentParams.isLast, currentParams.showCollapsed)
-	}
-
-	return result
-}
-
 // String returns the entire tree in an ASCII representation.
 func (tree *FileTree) String(showAttributes bool) string {
-	return tree.renderStringTreeBetween(0, tree.Size, showAttributes)
+	return tree.StringWith(DefaultGlyphs, showAttributes)
+}
+
+// StringWith returns the entire tree in an ASCII representation, drawn with the given glyphs.
+func (tree *FileTree) StringWith(glyphs GlyphSet, showAttributes bool) string {
+	return ANSIRenderer{Glyphs: glyphs, ShowAttributes: showAttributes}.Render(tree.Rows(0, tree.Size))
 }
 
 // StringBetween returns a partial tree in an ASCII representation.
 func (tree *FileTree) StringBetween(start, stop uint, showAttributes bool) string {
-	return tree.renderStringTreeBetween(int(start), int(stop), showAttributes)
+	return tree.StringBetweenWith(DefaultGlyphs, start, stop, showAttributes)
+}
+
+// StringBetweenWith returns a partial tree in an ASCII representation, drawn with the given glyphs.
+func (tree *FileTree) StringBetweenWith(glyphs GlyphSet, start, stop uint, showAttributes bool) string {
+	return ANSIRenderer{Glyphs: glyphs, ShowAttributes: showAttributes}.Render(tree.Rows(int(start), int(stop)))
 }
 
 // Copy returns a copy of the given FileTree
@@ -143,7 +79,10 @@ func (tree *FileTree) Copy() *FileTree {
 	newTree := NewFileTree()
 	newTree.Size = tree.Size
 	newTree.FileSize = tree.FileSize
+	newTree.Comparer = tree.Comparer
 	newTree.Root = tree.Root.Copy(newTree.Root)
+	// the copied root must remain the root (without a parent), otherwise all paths in the copy are prefixed with "//"
+	newTree.Root.Parent = nil
 
 	// update the tree pointers
 	newTree.VisitDepthChildFirst(func(node *FileNode) error {
@@ -162,49 +101,176 @@ type VisitEvaluator func(*FileNode) bool
 
 // VisitDepthChildFirst iterates the given tree depth-first, evaluating the deepest depths first (visit on bubble up)
 func (tree *FileTree) VisitDepthChildFirst(visitor Visitor, evaluator VisitEvaluator) error {
+	// the visitor may modify any node
+	tree.materialize()
 	return tree.Root.VisitDepthChildFirst(visitor, evaluator)
 }
 
 // VisitDepthParentFirst iterates the given tree depth-first, evaluating the shallowest depths first (visit while sinking down)
 func (tree *FileTree) VisitDepthParentFirst(visitor Visitor, evaluator VisitEvaluator) error {
+	// the visitor may modify any node
+	tree.materialize()
+	return tree.Root.VisitDepthParentFirst(visitor, evaluator)
+}
+
+// ObserveDepthChildFirst iterates the tree like VisitDepthChildFirst, but visits the nodes shared with other trees in
+// place rather than copying them into the tree first (see cow.go), so the visitor must not modify any node.
+func (tree *FileTree) ObserveDepthChildFirst(visitor Visitor, evaluator VisitEvaluator) error {
+	return tree.Root.VisitDepthChildFirst(visitor, evaluator)
+}
+
+// ObserveDepthParentFirst iterates the tree like VisitDepthParentFirst, but visits the nodes shared with other trees
+// in place rather than copying them into the tree first (see cow.go), so the visitor must not modify any node.
+func (tree *FileTree) ObserveDepthParentFirst(visitor Visitor, evaluator VisitEvaluator) error {
 	return tree.Root.VisitDepthParentFirst(visitor, evaluator)
 }
 
 // Stack takes two trees and combines them together. This is done by "stacking" the given tree on top of the owning tree.
+// Whiteouts of the given tree remove the paths of the owning tree, and opaque directories of the given tree replace
+// the contents of the directories of the owning tree. Subtrees of the given tree that are new to the owning tree (and
+// hold no whiteouts) are shared rather than copied (see cow.go).
 func (tree *FileTree) Stack(upper *FileTree) error {
+	// whiteouts notes the nodes of the given tree with whiteouts (or opaque directories) beneath them
+	whiteouts := make(map[*FileNode]bool)
+	err := upper.ObserveDepthChildFirst(func(node *FileNode) error {
+		if node.IsWhiteout() || node.Opaque || whiteouts[node] {
+			whiteouts[node.Parent] = true
+		}
+		if !node.Opaque {
+			return nil
+		}
+		lowerNode, err := tree.GetNode(node.Path())
+		if err != nil {
+			return nil
+		}
+		for name := range lowerNode.Children {
+			lowerNode.removeChild(name)
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		return err
+	}
+
+	// shared notes the nodes of the given tree whose subtree is part of the owning tree as is
+	shared := make(map[*FileNode]bool)
 	graft := func(node *FileNode) error {
+		if !node.IsWhiteout() && !node.Opaque && !whiteouts[node] {
+			existing, _ := tree.lookup(node.Path())
+			if existing == node {
+				shared[node] = true
+				return nil
+			}
+			if existing == nil {
+				parent, err := tree.GetNode(node.Parent.Path())
+				if err != nil {
+					return fmt.Errorf("cannot add node %s: %w", node.Path(), err)
+				}
+				parent.share(node)
+				shared[node] = true
+				return nil
+			}
+		}
+
 		if node.IsWhiteout() {
+			if node.beneathOpaque() {
+				// the opaque directory already removed the path
+				return nil
+			}
 			err := tree.RemovePath(node.Path())
 			if err != nil {
-				return fmt.Errorf("cannot remove node %s: %v", node.Path(), err.Error())
+				return fmt.Errorf("cannot remove node %s: %w", node.Path(), err)
 			}
 		} else {
-			newNode, err := tree.AddPath(node.Path(), node.Data.FileInfo)
+			_, err := tree.AddPath(node.Path(), node.Data.FileInfo)
 			if err != nil {
-				return fmt.Errorf("cannot add node %s: %v", newNode.Path(), err.Error())
+				return fmt.Errorf("cannot add node %s: %w", node.Path(), err)
 			}
 		}
 		return nil
 	}
-	return upper.VisitDepthChildFirst(graft, nil)
+	return upper.ObserveDepthParentFirst(graft, func(node *FileNode) bool {
+		return !shared[node.Parent]
+	})
 }
 
 // GetNode fetches a single node when given a slash-delimited string from root ('/') to the desired node (e.g. '/a/node/path')
 func (tree *FileTree) GetNode(path string) (*FileNode, error) {
+	return tree.getNode(path, true)
+}
+
+// lookup fetches a single node like GetNode, but may return a node shared with another tree, which must not be
+// modified.
+func (tree *FileTree) lookup(path string) (*FileNode, error) {
+	return tree.getNode(path, false)
+}
+
+// getNode fetches a single node, copying every shared node along the path into the tree when it is to be owned.
+func (tree *FileTree) getNode(path string, own bool) (*FileNode, error) {
+	node, found := tree.indexed(path)
+	if !found {
+		return nil, missingChild(node, path)
+	}
+	if !own || node.Tree == tree {
+		// a node of the tree is only ever beneath nodes of the tree
+		return node, nil
+	}
+
 	nodeNames := strings.Split(strings.Trim(path, "/"), "/")
-	node := tree.Root
+	node = tree.Root
 	for _, name := range nodeNames {
 		if name == "" {
 			continue
 		}
-		if node.Children[name] == nil {
-			return nil, fmt.Errorf("path does not exist: %s", path)
-		}
-		node = node.Children[name]
+		node = node.ownChild(name)
 	}
 	return node, nil
 }
 
+// FindAll returns the nodes (in tree order) whose path matches the given glob pattern, where a "**" path component
+// matches any number of components (e.g. "/usr/lib/**" or "**/*.so"). A pattern that does not start with "/" matches
+// at any depth, as if it were prefixed with "**/" (e.g. "*.so"). The nodes may be shared with other trees (see cow.go),
+// a node to be modified is fetched with GetNode.
+func (tree *FileTree) FindAll(pattern string) ([]*FileNode, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+	}
+	if !strings.HasPrefix(pattern, "/") && !strings.HasPrefix(pattern, "**") {
+		pattern = "**/" + pattern
+	}
+
+	var matches []*FileNode
+	err := tree.ObserveDepthParentFirst(func(node *FileNode) error {
+		if globMatch(pattern, node.Path()) {
+			matches = append(matches, node)
+		}
+		return nil
+	}, nil)
+	return matches, err
+}
+
+// FilterNodes shows the nodes whose path matches the given expression and hides the others (or, when inverted, hides
+// the nodes whose path matches and shows the others), returning the number of nodes it showed or hid. Directories with
+// visible children are shown, so matches remain reachable. A nil expression matches every node, showing them all.
+func (tree *FileTree) FilterNodes(re *regexp.Regexp, invert bool) int {
+	var count int
+	tree.VisitDepthChildFirst(func(node *FileNode) error {
+		visible := re == nil || re.MatchString(node.Path()) != invert
+		for _, child := range node.Children {
+			if !child.Data.ViewInfo.Hidden {
+				visible = true
+				break
+			}
+		}
+		if node.Data.ViewInfo.Hidden == visible {
+			node.SetHidden(!visible)
+			count++
+		}
+		return nil
+	}, nil)
+	return count
+}
+
 // AddPath adds a new node to the tree with the given payload
 func (tree *FileTree) AddPath(path string, data FileInfo) (*FileNode, error) {
 	nodeNames := strings.Split(strings.Trim(path, "/"), "/")
@@ -213,9 +279,18 @@ func (tree *FileTree) AddPath(path string, data FileInfo) (*FileNode, error) {
 		if name == "" {
 			continue
 		}
+		if name == opaqueWhiteout && idx == len(nodeNames)-1 {
+			// the opaque whiteout file is not a node of its own, it marks its directory
+			node.Opaque = true
+			return node, nil
+		}
+		if node.IsWhiteout() {
+			// a whiteout removes a path, nothing lies beneath it
+			return nil, fmt.Errorf("could not add child node '%s' beneath %s: %w", name, node.Path(), ErrWhiteoutConflict)
+		}
 		// find or create node
 		if node.Children[name] != nil {
-			node = node.Children[name]
+			node = node.ownChild(name)
 		} else {
 			// don't attach the payload. The payload is destined for the
 			// Path's end node, not any intermediary node.
@@ -223,13 +298,14 @@ func (tree *FileTree) AddPath(path string, data FileInfo) (*FileNode, error) {
 
 			if node == nil {
 				// the child could not be added
-				return node, fmt.Errorf(fmt.Sprintf("could not add child node '%s'", name))
+				return node, fmt.Errorf("could not add child node '%s': %w", name, ErrWhiteoutConflict)
 			}
 		}
 
 		// attach payload to the last specified node
 		if idx == len(nodeNames)-1 {
 			node.Data.FileInfo = data
+			node.invalidateSize()
 		}
 
 	}
@@ -245,30 +321,120 @@ func (tree *FileTree) RemovePath(path string) error {
 	return node.Remove()
 }
 
+// OnlyBeneath removes every node of the tree that is not the given directory, one of its parents, or beneath it. The
+// nodes are removed from the tree alone, a tree sharing its nodes with other trees (see Stack) leaves them unchanged.
+func (tree *FileTree) OnlyBeneath(dir string) {
+	parent := "/"
+	for _, name := range strings.Split(strings.Trim(path.Clean("/"+dir), "/"), "/") {
+		if name == "" {
+			return
+		}
+		node, err := tree.lookup(parent)
+		if err != nil {
+			return
+		}
+		for childName := range node.Children {
+			if childName != name {
+				tree.RemovePath(path.Join(parent, childName))
+			}
+		}
+		parent = path.Join(parent, name)
+	}
+}
+
 // Compare marks the FileNodes in the owning tree with DiffType annotations when compared to the given tree.
 func (tree *FileTree) Compare(upper *FileTree) error {
+	err := upper.ObserveDepthParentFirst(func(upperNode *FileNode) error {
+		if !upperNode.Opaque {
+			return nil
+		}
+		lowerNode, err := tree.GetNode(upperNode.Path())
+		if err != nil {
+			return nil
+		}
+		return markHidden(lowerNode, upperNode)
+	}, nil)
+	if err != nil {
+		return err
+	}
+
 	graft := func(upperNode *FileNode) error {
 		if upperNode.IsWhiteout() {
 			err := tree.markRemoved(upperNode.Path())
 			if err != nil {
-				return fmt.Errorf("cannot remove upperNode %s: %v", upperNode.Path(), err.Error())
+				return fmt.Errorf("cannot remove upperNode %s: %w", upperNode.Path(), err)
 			}
 		} else {
 			lowerNode, _ := tree.GetNode(upperNode.Path())
 			if lowerNode == nil {
 				newNode, err := tree.AddPath(upperNode.Path(), upperNode.Data.FileInfo)
 				if err != nil {
-					return fmt.Errorf("cannot add new upperNode %s: %v", upperNode.Path(), err.Error())
+					return fmt.Errorf("cannot add new upperNode %s: %w", upperNode.Path(), err)
 				}
 				newNode.AssignDiffType(Added)
 			} else {
 				diffType := lowerNode.compare(upperNode)
+				lowerNode.Data.Changes = nil
+				if diffType == Changed {
+					lowerNode.Data.Changes = tree.comparer().Changes(lowerNode.Data.FileInfo, upperNode.Data.FileInfo)
+				}
 				return lowerNode.deriveDiffType(diffType)
 			}
 		}
 		return nil
 	}
-	return upper.VisitDepthChildFirst(graft, nil)
+	return upper.ObserveDepthChildFirst(graft, nil)
+}
+
+// CompareTree marks the FileNodes in the owning tree with DiffType annotations when compared to the given complete
+// (e.g. fully stacked) tree. Unlike Compare, which expects a single layer with whiteout markers, any node that does not
+// exist in the given tree (not even through its symlinks, see ResolvePath) is marked as Removed.
+func (tree *FileTree) CompareTree(other *FileTree) error {
+	existing := make(map[string]bool)
+	tree.ObserveDepthChildFirst(func(node *FileNode) error {
+		existing[node.Path()] = true
+		return nil
+	}, nil)
+
+	err := tree.Compare(other)
+	if err != nil {
+		return err
+	}
+
+	return tree.VisitDepthChildFirst(func(node *FileNode) error {
+		// this includes parent directories created while adding new nodes, which Compare does not mark as Added
+		if !existing[node.Path()] {
+			return node.AssignDiffType(Added)
+		}
+		otherNode, err := other.GetNode(node.Path())
+		if err != nil {
+			// the path may still be reachable through a symlink (e.g. /bin/sh once /bin links to usr/bin)
+			resolved, err := other.ResolvePath(node.Path())
+			if err != nil {
+				return node.AssignDiffType(Removed)
+			}
+			diffType := compareInfo(tree.comparer(), node.Data.FileInfo, resolved.Data.FileInfo)
+			if node.IsLeaf() {
+				return node.AssignDiffType(diffType)
+			}
+			return node.deriveDiffType(diffType)
+		}
+		if !node.IsLeaf() {
+			return node.deriveDiffType(node.compare(otherNode))
+		}
+		return nil
+	}, nil)
+}
+
+// CompareTrees returns the combined tree of the given complete (e.g. fully stacked) trees: a copy of the lower tree
+// that also holds the nodes only found in the upper tree, with every node marked with how it changed in the upper tree
+// (see CompareTree). Neither tree is modified.
+func CompareTrees(lower, upper *FileTree) (*FileTree, error) {
+	combined := lower.Copy()
+	if err := combined.CompareTree(upper); err != nil {
+		return nil, err
+	}
+	return combined, nil
 }
 
 // markRemoved annotates the FileNode at the given path as Removed.
@@ -280,9 +446,29 @@ func (tree *FileTree) markRemoved(path string) error {
 	return node.AssignDiffType(Removed)
 }
 
-// StackRange combines an array of trees into a single tree
+// markHidden marks the descendants of the given lower node that the given (opaque) upper node does not provide again
+// as Removed, as an opaque directory hides everything lower layers placed in it.
+func markHidden(lowerNode, upperNode *FileNode) error {
+	for name := range lowerNode.Children {
+		lowerChild := lowerNode.ownChild(name)
+		upperChild, ok := upperNode.Children[name]
+		if !ok {
+			if err := lowerChild.AssignDiffType(Removed); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := markHidden(lowerChild, upperChild); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StackRange combines an array of trees into a single tree. The stacked tree shares every subtree it does not modify
+// with the given trees (see cow.go).
 func StackRange(trees []*FileTree, start, stop int) *FileTree {
-	tree := trees[0].Copy()
+	tree := trees[0].shallowCopy()
 	for idx := start; idx <= stop; idx++ {
 		err := tree.Stack(trees[idx])
 		if err != nil {
@@ -1,8 +1,15 @@
 package filetree
 
 import (
+	"archive/tar"
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"regexp"
 	"testing"
+
+	"github.com/wagoodman/dive/testkit"
 )
 
 func stringInSlice(a string, list []string) bool {
@@ -187,8 +194,8 @@ func TestStack(t *testing.T) {
 	// whiteout the following files
 	tree2.AddPath("/var/run/.wh.bashful", FileInfo{})
 	tree2.AddPath("/.wh.tmp", FileInfo{})
-	// ignore opaque whiteout files entirely
-	tree2.AddPath("/.wh..wh..opq", FileInfo{})
+	// hide everything lower layers placed in this directory (only the modified file remains)
+	tree2.AddPath("/var/run/.wh..wh..opq", FileInfo{})
 
 	err := tree1.Stack(tree2)
 
@@ -252,6 +259,15 @@ func TestCopy(t *testing.T) {
 		t.Errorf("Expected tree string:\n--->%s<---\nGot:\n--->%s<---", expected, actual)
 	}
 
+	original, _ := tree.GetNode("/etc/nginx/public")
+	copied, _ := NewFileTree.GetNode("/etc/nginx/public")
+	if copied.Path() != "/etc/nginx/public" {
+		t.Errorf("Expected copied path '/etc/nginx/public', got '%s'", copied.Path())
+	}
+	if original.Parent == copied.Parent || original.Parent.Tree != tree {
+		t.Errorf("Expected the original tree to be left untouched by the copy")
+	}
+
 }
 
 func TestCompareWithNoChanges(t *testing.T) {
@@ -526,3 +542,347 @@ func TestRemoveOnIterate(t *testing.T) {
 	}
 
 }
+
+func TestCompareTree(t *testing.T) {
+	lowerTree := NewFileTree()
+	upperTree := NewFileTree()
+	lowerPaths := [...]string{"/etc", "/etc/hosts", "/etc/sudoers", "/usr", "/usr/bin", "/usr/bin/bash", "/root"}
+	upperPaths := [...]string{"/etc", "/etc/hosts", "/etc/sudoers", "/usr", "/usr/bin", "/usr/bin/zsh", "/opt", "/opt/app"}
+
+	for _, value := range lowerPaths {
+		lowerTree.AddPath(value, FileInfo{
+			Path:     value,
+			TypeFlag: 1,
+			MD5sum:   [16]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		})
+	}
+
+	for _, value := range upperPaths {
+		md5sum := [16]byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+		if value == "/etc/sudoers" {
+			md5sum = [16]byte{1, 1, 1, 0, 1, 0, 0, 0, 0, 0, 0, 0}
+		}
+		upperTree.AddPath(value, FileInfo{
+			Path:     value,
+			TypeFlag: 1,
+			MD5sum:   md5sum,
+		})
+	}
+
+	err := lowerTree.CompareTree(upperTree)
+	if err != nil {
+		t.Errorf("Expected tree compare to have no errors, got: %v", err)
+	}
+
+	expected := map[string]DiffType{
+		"/etc":          Changed,
+		"/etc/hosts":    Unchanged,
+		"/etc/sudoers":  Changed,
+		"/usr":          Changed,
+		"/usr/bin":      Changed,
+		"/usr/bin/bash": Removed,
+		"/usr/bin/zsh":  Added,
+		"/root":         Removed,
+		"/opt":          Added,
+		"/opt/app":      Added,
+	}
+	failedAssertions := []error{}
+	err = lowerTree.VisitDepthChildFirst(func(n *FileNode) error {
+		if err := AssertDiffType(n, expected[n.Path()]); err != nil {
+			failedAssertions = append(failedAssertions, err)
+		}
+		return nil
+	}, nil)
+	if err != nil {
+		t.Errorf("Expected no errors when visiting nodes, got: %+v", err)
+	}
+
+	if len(failedAssertions) > 0 {
+		str := "\n"
+		for _, value := range failedAssertions {
+			str += fmt.Sprintf("  - %s\n", value.Error())
+		}
+		t.Errorf("Expected no errors when evaluating nodes, got: %s", str)
+	}
+}
+
+func TestCompareTrees(t *testing.T) {
+	lowerTree := NewFileTree()
+	lowerTree.AddPath("/etc/hosts", FileInfo{TypeFlag: 1})
+	lowerTree.AddPath("/root/.profile", FileInfo{TypeFlag: 1})
+	upperTree := NewFileTree()
+	upperTree.AddPath("/etc/hosts", FileInfo{TypeFlag: 1, MD5sum: [16]byte{1}})
+	upperTree.AddPath("/opt/app", FileInfo{TypeFlag: 1})
+
+	combined, err := CompareTrees(lowerTree, upperTree)
+	if err != nil {
+		t.Fatalf("Expected tree compare to have no errors, got: %v", err)
+	}
+	expected := map[string]DiffType{
+		"/etc/hosts":     Changed,
+		"/root/.profile": Removed,
+		"/opt/app":       Added,
+	}
+	for path, diffType := range expected {
+		node, err := combined.GetNode(path)
+		if err != nil {
+			t.Fatalf("Expected %s in the combined tree, got: %v", path, err)
+		}
+		if err := AssertDiffType(node, diffType); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := lowerTree.GetNode("/opt/app"); err == nil {
+		t.Errorf("Expected the lower tree not to be modified")
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	tree := NewFileTree()
+	for _, path := range []string{"/usr/lib/libc.so", "/usr/lib/x86/libm.so", "/usr/bin/env", "/lib.so/readme", "/opt/app.so"} {
+		tree.AddPath(path, FileInfo{})
+	}
+
+	cases := map[string][]string{
+		"**/*.so":      {"/lib.so", "/opt/app.so", "/usr/lib/libc.so", "/usr/lib/x86/libm.so"},
+		"*.so":         {"/lib.so", "/opt/app.so", "/usr/lib/libc.so", "/usr/lib/x86/libm.so"},
+		"/*.so":        {"/lib.so"},
+		"/usr/lib/**":  {"/usr/lib", "/usr/lib/libc.so", "/usr/lib/x86", "/usr/lib/x86/libm.so"},
+		"/usr/*/env":   {"/usr/bin/env"},
+		"/nothing/**":  nil,
+		"/usr/lib/*.a": nil,
+	}
+	for pattern, expected := range cases {
+		nodes, err := tree.FindAll(pattern)
+		if err != nil {
+			t.Fatalf("pattern %q: unexpected error: %v", pattern, err)
+		}
+		var actual []string
+		for _, node := range nodes {
+			actual = append(actual, node.Path())
+		}
+		if fmt.Sprint(actual) != fmt.Sprint(expected) {
+			t.Errorf("pattern %q: expected %v, got %v", pattern, expected, actual)
+		}
+	}
+
+	if _, err := tree.FindAll("/usr/[lib"); err == nil {
+		t.Errorf("expected an error for an invalid pattern")
+	}
+}
+
+// fixtureTree reads a layer tar built by the testkit into a tree.
+func fixtureTree(t *testing.T, layer testkit.Layer) *FileTree {
+	layerTar, err := layer.LayerTar()
+	if err != nil {
+		t.Fatalf("could not build layer: %v", err)
+	}
+	tree := NewFileTree()
+	reader := tar.NewReader(bytes.NewReader(layerTar))
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("could not read layer: %v", err)
+		}
+		tree.AddPath(header.Name, fileInfoOf(t, reader, header, header.Name))
+	}
+	return tree
+}
+
+func TestStackFixtureLayers(t *testing.T) {
+	fixture, err := testkit.Fixture("", "whiteouts", "hardlinks", "long-paths")
+	if err != nil {
+		t.Fatalf("could not build fixture: %v", err)
+	}
+	var trees []*FileTree
+	for _, layer := range fixture.Layers {
+		trees = append(trees, fixtureTree(t, layer))
+	}
+	stacked := StackRange(trees, 0, len(trees)-1)
+
+	for _, removed := range []string{"/etc/motd", "/usr/bin/tool"} {
+		if _, err := stacked.GetNode(removed); err == nil {
+			t.Errorf("expected %s to be removed by a whiteout", removed)
+		}
+	}
+	for _, present := range []string{"/etc/hostname", "/usr/local/lib/libfixture.so", "/usr/local/lib/libfixture.so.0"} {
+		if _, err := stacked.GetNode(present); err != nil {
+			t.Errorf("expected %s to be present: %v", present, err)
+		}
+	}
+
+	link, _ := stacked.GetNode("/usr/local/lib/libfixture.so")
+	if link != nil && link.Data.FileInfo.TarHeader.Typeflag != tar.TypeLink {
+		t.Errorf("expected a hardlink, got type %v", link.Data.FileInfo.TarHeader.Typeflag)
+	}
+
+	longPaths, err := stacked.FindAll("/srv/**/file.txt")
+	if err != nil || len(longPaths) != 1 || len(longPaths[0].Path()) < 240 {
+		t.Errorf("expected a single long path, got %v (%v)", longPaths, err)
+	}
+}
+
+func TestStackOpaqueFixtureLayers(t *testing.T) {
+	fixture, err := testkit.Fixture("", "opaque")
+	if err != nil {
+		t.Fatalf("could not build fixture: %v", err)
+	}
+	var trees []*FileTree
+	for _, layer := range fixture.Layers {
+		trees = append(trees, fixtureTree(t, layer))
+	}
+
+	upper := trees[len(trees)-1]
+	dir, err := upper.GetNode("/opt/cache")
+	if err != nil || !dir.Opaque {
+		t.Fatalf("expected /opt/cache to be opaque, got %v (%v)", dir, err)
+	}
+	if _, err := upper.GetNode("/opt/cache/.wh..wh..opq"); err == nil {
+		t.Errorf("expected the opaque whiteout not to be a node")
+	}
+
+	stacked := StackRange(trees, 0, len(trees)-1)
+	for _, hidden := range []string{"/opt/cache/a.bin", "/opt/cache/b.bin"} {
+		if _, err := stacked.GetNode(hidden); err == nil {
+			t.Errorf("expected %s to be hidden by the opaque directory", hidden)
+		}
+	}
+	if _, err := stacked.GetNode("/opt/cache/c.bin"); err != nil {
+		t.Errorf("expected /opt/cache/c.bin to be present: %v", err)
+	}
+	if node, _ := stacked.GetNode("/opt/cache"); node == nil || node.Opaque {
+		t.Errorf("expected the stacked directory not to be opaque, got %v", node)
+	}
+
+	lower := StackRange(trees, 0, len(trees)-2)
+	if err := lower.Compare(upper); err != nil {
+		t.Fatalf("could not compare: %v", err)
+	}
+	expected := map[string]DiffType{
+		"/opt/cache/a.bin": Removed,
+		"/opt/cache/b.bin": Removed,
+		"/opt/cache/c.bin": Added,
+		"/etc/hostname":    Unchanged,
+	}
+	for nodePath, diffType := range expected {
+		node, err := lower.GetNode(nodePath)
+		if err != nil {
+			t.Errorf("expected %s to exist: %v", nodePath, err)
+			continue
+		}
+		if node.Data.DiffType != diffType {
+			t.Errorf("expected %s to be %v, got %v", nodePath, diffType, node.Data.DiffType)
+		}
+	}
+}
+
+func TestCompareOpaqueNested(t *testing.T) {
+	lower := NewFileTree()
+	lower.AddPath("/srv/www/index.html", FileInfo{})
+	lower.AddPath("/srv/www/static/app.js", FileInfo{})
+	lower.AddPath("/srv/www/static/app.css", FileInfo{})
+
+	upper := NewFileTree()
+	upper.AddPath("/srv/www/.wh..wh..opq", FileInfo{})
+	upper.AddPath("/srv/www/static/app.js", FileInfo{})
+
+	if err := lower.Compare(upper); err != nil {
+		t.Fatalf("could not compare: %v", err)
+	}
+	expected := map[string]DiffType{
+		"/srv/www/index.html":     Removed,
+		"/srv/www/static/app.css": Removed,
+		"/srv/www/static/app.js":  Unchanged,
+	}
+	for nodePath, diffType := range expected {
+		node, _ := lower.GetNode(nodePath)
+		if node == nil || node.Data.DiffType != diffType {
+			t.Errorf("expected %s to be %v, got %v", nodePath, diffType, node)
+		}
+	}
+
+	if err := lower.Stack(upper); err != nil {
+		t.Fatalf("could not stack: %v", err)
+	}
+	for _, hidden := range []string{"/srv/www/index.html", "/srv/www/static/app.css"} {
+		if _, err := lower.GetNode(hidden); err == nil {
+			t.Errorf("expected %s to be hidden by the opaque directory", hidden)
+		}
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	tree := NewFileTree()
+	tree.AddPath("/etc/hosts", fileOfSize("/etc/hosts", 10))
+	tree.AddPath("/etc/.wh.motd", FileInfo{})
+
+	if _, err := tree.GetNode("/etc/missing"); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("Expected a missing path to be ErrPathNotFound, got %v", err)
+	}
+	if _, err := tree.GetNode("/etc/hosts/file"); !errors.Is(err, ErrNotADirectory) {
+		t.Errorf("Expected a path beneath a file to be ErrNotADirectory, got %v", err)
+	}
+	if _, err := tree.ResolvePath("/etc/hosts/file"); !errors.Is(err, ErrNotADirectory) {
+		t.Errorf("Expected a resolved path beneath a file to be ErrNotADirectory, got %v", err)
+	}
+	if _, err := tree.AddPath("/etc/.wh.motd/file", FileInfo{}); !errors.Is(err, ErrWhiteoutConflict) {
+		t.Errorf("Expected a path beneath a whiteout to be ErrWhiteoutConflict, got %v", err)
+	}
+	if _, err := tree.AddPath("/etc/.wh..wh..tmp", FileInfo{}); !errors.Is(err, ErrWhiteoutConflict) {
+		t.Errorf("Expected a whiteout flag file to be ErrWhiteoutConflict, got %v", err)
+	}
+
+	upper := NewFileTree()
+	upper.AddPath("/var/.wh.log", FileInfo{})
+	if err := tree.Stack(upper); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("Expected a whiteout of a missing path to wrap ErrPathNotFound, got %v", err)
+	}
+}
+
+func TestFilterNodes(t *testing.T) {
+	tree := NewFileTree()
+	for _, path := range []string{"/etc/nginx/nginx.conf", "/etc/hosts", "/usr/bin/nginx", "/usr/bin/env"} {
+		tree.AddPath(path, FileInfo{})
+	}
+	visible := func() []string {
+		var paths []string
+		tree.VisitDepthParentFirst(func(node *FileNode) error {
+			if !node.Data.ViewInfo.Hidden {
+				paths = append(paths, node.Path())
+			}
+			return nil
+		}, nil)
+		return paths
+	}
+
+	cases := []struct {
+		re      *regexp.Regexp
+		invert  bool
+		changed int
+		visible []string
+	}{
+		{regexp.MustCompile("nginx"), false, 2, []string{"/etc", "/etc/nginx", "/etc/nginx/nginx.conf", "/usr", "/usr/bin", "/usr/bin/nginx"}},
+		// the filter shows nodes as well as hides them, so filters replace each other
+		{regexp.MustCompile("nginx"), true, 5, []string{"/etc", "/etc/hosts", "/usr", "/usr/bin", "/usr/bin/env"}},
+		{regexp.MustCompile("^/usr"), false, 3, []string{"/usr", "/usr/bin", "/usr/bin/env", "/usr/bin/nginx"}},
+		{regexp.MustCompile("^/usr"), false, 0, []string{"/usr", "/usr/bin", "/usr/bin/env", "/usr/bin/nginx"}},
+		// a nil expression shows everything
+		{nil, false, 4, []string{"/etc", "/etc/hosts", "/etc/nginx", "/etc/nginx/nginx.conf", "/usr", "/usr/bin", "/usr/bin/env", "/usr/bin/nginx"}},
+	}
+	for idx, test := range cases {
+		tree.CountRows()
+		if changed := tree.FilterNodes(test.re, test.invert); changed != test.changed {
+			t.Errorf("%d: expected %d nodes to be shown or hidden, got %d", idx, test.changed, changed)
+		}
+		if fmt.Sprint(visible()) != fmt.Sprint(test.visible) {
+			t.Errorf("%d: expected %v to be visible, got %v", idx, test.visible, visible())
+		}
+		// the row counts are kept up to date
+		if rows := tree.RowCount(); rows != len(test.visible) {
+			t.Errorf("%d: expected %d rows, got %d", idx, len(test.visible), rows)
+		}
+	}
+}
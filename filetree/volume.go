@@ -0,0 +1,52 @@
+package filetree
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// VolumeFinding is a file in the final image that lies under a volume (or tmpfs) mount point, and so is shadowed by
+// whatever is mounted there at runtime.
+type VolumeFinding struct {
+	Volume string
+	Path   string
+	Layer  int
+	Size   int64
+}
+
+// VolumeFindings lists every file of the final (stacked) filesystem that is baked under one of the given mount
+// points, along with the layer that provided it. Findings are ordered by volume, then by size (largest first).
+func VolumeFindings(trees []*FileTree, volumes []string) []VolumeFinding {
+	var findings []VolumeFinding
+	for filePath, layer := range providers(trees) {
+		for _, volume := range volumes {
+			volume = path.Clean("/" + volume)
+			if volume != "/" && !strings.HasPrefix(filePath, volume+"/") {
+				continue
+			}
+			node, err := trees[layer].GetNode(filePath)
+			if err != nil {
+				continue
+			}
+			findings = append(findings, VolumeFinding{
+				Volume: volume,
+				Path:   filePath,
+				Layer:  layer,
+				Size:   node.Data.FileInfo.TarHeader.FileInfo().Size(),
+			})
+			break
+		}
+	}
+
+	sort.Slice(findings, func(i, j int) bool {
+		if findings[i].Volume != findings[j].Volume {
+			return findings[i].Volume < findings[j].Volume
+		}
+		if findings[i].Size != findings[j].Size {
+			return findings[i].Size > findings[j].Size
+		}
+		return findings[i].Path < findings[j].Path
+	})
+	return findings
+}
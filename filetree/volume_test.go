@@ -0,0 +1,27 @@
+package filetree
+
+import (
+	"testing"
+)
+
+func TestVolumeFindings(t *testing.T) {
+	base := NewFileTree()
+	base.AddPath("/var/lib/mysql/ibdata1", fileOfSize("/var/lib/mysql/ibdata1", 1000))
+	base.AddPath("/var/lib/mysql-files/readme", fileOfSize("/var/lib/mysql-files/readme", 10))
+	base.AddPath("/run/app.pid", fileOfSize("/run/app.pid", 5))
+
+	app := NewFileTree()
+	app.AddPath("/var/lib/mysql/seed.sql", fileOfSize("/var/lib/mysql/seed.sql", 2000))
+	app.AddPath("/run/.wh.app.pid", FileInfo{})
+
+	findings := VolumeFindings([]*FileTree{base, app}, []string{"/var/lib/mysql/", "/run"})
+	if len(findings) != 2 {
+		t.Fatalf("Expected 2 findings, got %d: %+v", len(findings), findings)
+	}
+	if findings[0].Path != "/var/lib/mysql/seed.sql" || findings[0].Layer != 1 || findings[0].Volume != "/var/lib/mysql" {
+		t.Errorf("Unexpected first finding: %+v", findings[0])
+	}
+	if findings[1].Path != "/var/lib/mysql/ibdata1" || findings[1].Layer != 0 || findings[1].Size != 1000 {
+		t.Errorf("Unexpected second finding: %+v", findings[1])
+	}
+}
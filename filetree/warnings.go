@@ -0,0 +1,100 @@
+package filetree
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WarningKind classifies a non-fatal issue found while analyzing an image.
+type WarningKind string
+
+const (
+	// WarningXattr is an extended attribute that could not be read (e.g. unparsable capabilities)
+	WarningXattr WarningKind = "xattr"
+	// WarningSkippedEntry is a tar entry that is not part of any tree (the path is missing from the analysis)
+	WarningSkippedEntry WarningKind = "skipped-entry"
+	// WarningSymlinkCycle is a symlink of the final filesystem that cannot be resolved since it loops
+	WarningSymlinkCycle WarningKind = "symlink-cycle"
+	// WarningMetadataOnly is a layer whose contents could not be read (e.g. a foreign layer), shown by its metadata alone
+	WarningMetadataOnly WarningKind = "metadata-only"
+)
+
+// libarchiveXattrPrefix marks the xattrs written by libarchive (bsdtar) as base64 PAX records, which are not read.
+const libarchiveXattrPrefix = "LIBARCHIVE.xattr."
+
+// Warning is a non-fatal issue found while analyzing an image: the analysis goes on, but what it shows about the
+// given path may be incomplete.
+type Warning struct {
+	Kind WarningKind `json:"kind"`
+	// Layer is the tar path of the layer the issue was found in (empty for the final filesystem)
+	Layer   string `json:"layer,omitempty"`
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// String describes the warning on a single line.
+func (warning Warning) String() string {
+	return fmt.Sprintf("%s: %s (%s)", warning.Path, warning.Message, warning.Kind)
+}
+
+// Warn notes a non-fatal issue found in the given path while building the tree.
+func (tree *FileTree) Warn(kind WarningKind, path, format string, args ...interface{}) {
+	tree.Warnings = append(tree.Warnings, Warning{
+		Kind:    kind,
+		Layer:   tree.Name,
+		Path:    "/" + strings.TrimPrefix(path, "/"),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// WarnHeader notes the issues of the extended attributes of the given tar header, which are kept only partially (or
+// not at all) by NewFileInfo.
+func (tree *FileTree) WarnHeader(header *tar.Header) {
+	var names []string
+	for key := range header.PAXRecords {
+		if strings.HasPrefix(key, libarchiveXattrPrefix) {
+			names = append(names, strings.TrimPrefix(key, libarchiveXattrPrefix))
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		tree.Warn(WarningXattr, header.Name, "xattr %s is stored as a libarchive record, which is not read", name)
+	}
+	if raw, ok := header.PAXRecords[paxXattrPrefix+capabilityXattr]; ok {
+		if _, err := parseCapabilities(raw); err != nil {
+			tree.Warn(WarningXattr, header.Name, "could not parse the capabilities: %v", err)
+		}
+	}
+}
+
+// symlinkWarnings notes every symlink of the tree that cannot be resolved since it loops (directly or through other
+// symlinks). Dangling symlinks are common (e.g. links into volumes) and are not noted.
+func (tree *FileTree) symlinkWarnings() []Warning {
+	var warnings []Warning
+	visitKeyed(tree.Root, "", func(node *FileNode, nodePath string) {
+		if node.Data.FileInfo.TarHeader.Typeflag != tar.TypeSymlink || node.IsWhiteout() {
+			return
+		}
+		if _, err := tree.ResolvePath(nodePath); errors.Is(err, ErrSymlinkLoop) {
+			warnings = append(warnings, Warning{Kind: WarningSymlinkCycle, Path: nodePath, Message: err.Error()})
+		}
+	})
+	sort.Slice(warnings, func(i, j int) bool { return warnings[i].Path < warnings[j].Path })
+	return warnings
+}
+
+// CollectWarnings gathers the issues noted while building each of the given layer trees (oldest first), followed by
+// the issues of the final filesystem they stack up to.
+func CollectWarnings(trees []*FileTree) []Warning {
+	var warnings []Warning
+	for _, tree := range trees {
+		warnings = append(warnings, tree.Warnings...)
+	}
+	if len(trees) > 0 {
+		warnings = append(warnings, StackRange(trees, 0, len(trees)-1).symlinkWarnings()...)
+	}
+	return warnings
+}
@@ -0,0 +1,47 @@
+package filetree
+
+import (
+	"archive/tar"
+	"testing"
+)
+
+func TestCollectWarnings(t *testing.T) {
+	lower := NewFileTree()
+	lower.Name = "lower/layer.tar"
+	lower.AddPath("/loop/a", symlinkTo("/loop/a", "b"))
+	lower.AddPath("/dangling", symlinkTo("/dangling", "/nowhere"))
+	lower.WarnHeader(&tar.Header{Name: "bin/ping", PAXRecords: map[string]string{
+		"LIBARCHIVE.xattr.user.comment":    "aGk=",
+		"SCHILY.xattr.security.capability": "\x01",
+	}})
+
+	upper := NewFileTree()
+	upper.Name = "upper/layer.tar"
+	upper.AddPath("/loop/b", symlinkTo("/loop/b", "a"))
+	upper.Warn(WarningSkippedEntry, "etc/skipped", "unexpected entry")
+
+	warnings := CollectWarnings([]*FileTree{lower, upper})
+	expected := []Warning{
+		{Kind: WarningXattr, Layer: "lower/layer.tar", Path: "/bin/ping"},
+		{Kind: WarningXattr, Layer: "lower/layer.tar", Path: "/bin/ping"},
+		{Kind: WarningSkippedEntry, Layer: "upper/layer.tar", Path: "/etc/skipped"},
+		{Kind: WarningSymlinkCycle, Path: "/loop/a"},
+		{Kind: WarningSymlinkCycle, Path: "/loop/b"},
+	}
+	if len(warnings) != len(expected) {
+		t.Fatalf("Expected %d warnings, got %d: %v", len(expected), len(warnings), warnings)
+	}
+	for idx, warning := range warnings {
+		if warning.Kind != expected[idx].Kind || warning.Layer != expected[idx].Layer || warning.Path != expected[idx].Path {
+			t.Errorf("Expected warning %d to be %+v, got %+v", idx, expected[idx], warning)
+		}
+		if warning.Message == "" {
+			t.Errorf("Expected warning %d to have a message", idx)
+		}
+	}
+
+	// the cycle only exists once both layers are stacked
+	if warnings := CollectWarnings([]*FileTree{lower}); len(warnings) != 2 {
+		t.Errorf("Expected only the xattr warnings of a single layer, got %v", warnings)
+	}
+}
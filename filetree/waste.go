@@ -0,0 +1,54 @@
+package filetree
+
+import "sort"
+
+// LayerWaste is the space a single layer wastes by overwriting or removing files of lower layers, which are still
+// stored by those layers.
+type LayerWaste struct {
+	// Layer counts from the oldest layer
+	Layer      int
+	WastedSize int64
+	// Paths are the files the layer made redundant, the largest first
+	Paths []string
+}
+
+// WasteByLayer attributes the given inefficiencies (see Efficiency) to the layers that caused them: a layer that
+// overwrites or removes a file wastes the bytes of the copy it hides. The layers are ordered by their wasted size
+// (largest first), leaving out the layers that waste nothing.
+func WasteByLayer(inefficiencies EfficiencySlice) []LayerWaste {
+	type hiddenFile struct {
+		path string
+		size int64
+	}
+	hidden := make(map[int][]hiddenFile)
+	for _, data := range inefficiencies {
+		for idx := 1; idx < len(data.Nodes) && idx < len(data.Layers); idx++ {
+			if size := data.Sizes[idx-1]; size > 0 {
+				hidden[data.Layers[idx]] = append(hidden[data.Layers[idx]], hiddenFile{path: data.Path, size: size})
+			}
+		}
+	}
+
+	result := make([]LayerWaste, 0, len(hidden))
+	for layer, files := range hidden {
+		sort.Slice(files, func(i, j int) bool {
+			if files[i].size != files[j].size {
+				return files[i].size > files[j].size
+			}
+			return files[i].path < files[j].path
+		})
+		waste := LayerWaste{Layer: layer}
+		for _, file := range files {
+			waste.WastedSize += file.size
+			waste.Paths = append(waste.Paths, file.path)
+		}
+		result = append(result, waste)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].WastedSize != result[j].WastedSize {
+			return result[i].WastedSize > result[j].WastedSize
+		}
+		return result[i].Layer < result[j].Layer
+	})
+	return result
+}
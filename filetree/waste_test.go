@@ -0,0 +1,36 @@
+package filetree
+
+import (
+	"testing"
+)
+
+func TestWasteByLayer(t *testing.T) {
+	first := NewFileTree()
+	first.AddPath("/usr/lib/big.so", fileOfSize("/usr/lib/big.so", 500))
+	first.AddPath("/etc/config", fileOfSize("/etc/config", 10))
+	first.AddPath("/var/cache/index", fileOfSize("/var/cache/index", 40))
+
+	second := NewFileTree()
+	second.AddPath("/usr/lib/big.so", fileOfSize("/usr/lib/big.so", 600))
+	second.AddPath("/etc/config", fileOfSize("/etc/config", 20))
+
+	third := NewFileTree()
+	third.AddPath("/var/cache/.wh.index", FileInfo{})
+	third.AddPath("/etc/config", fileOfSize("/etc/config", 30))
+
+	_, inefficiencies := Efficiency([]*FileTree{first, second, third})
+	result := WasteByLayer(inefficiencies)
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 wasting layers, got %+v", result)
+	}
+
+	if result[0].Layer != 1 || result[0].WastedSize != 510 {
+		t.Errorf("Expected the second layer to waste the most, got %+v", result[0])
+	}
+	if len(result[0].Paths) != 2 || result[0].Paths[0] != "/usr/lib/big.so" {
+		t.Errorf("Expected the largest hidden file first, got %v", result[0].Paths)
+	}
+	if result[1].Layer != 2 || result[1].WastedSize != 60 || len(result[1].Paths) != 2 {
+		t.Errorf("Expected the third layer to waste the overwritten and removed files, got %+v", result[1])
+	}
+}
@@ -0,0 +1,216 @@
+package filetree
+
+import (
+	"archive/tar"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	capabilityXattr = "security.capability"
+	selinuxXattr    = "security.selinux"
+	paxXattrPrefix  = "SCHILY.xattr."
+
+	vfsCapRevisionMask   = 0xFF000000
+	vfsCapRevision1      = 0x01000000
+	vfsCapRevision2      = 0x02000000
+	vfsCapRevision3      = 0x03000000
+	vfsCapFlagsEffective = 0x000001
+)
+
+// capabilityNames are the linux capability names, indexed by capability number.
+var capabilityNames = []string{
+	"cap_chown", "cap_dac_override", "cap_dac_read_search", "cap_fowner", "cap_fsetid", "cap_kill", "cap_setgid",
+	"cap_setuid", "cap_setpcap", "cap_linux_immutable", "cap_net_bind_service", "cap_net_broadcast", "cap_net_admin",
+	"cap_net_raw", "cap_ipc_lock", "cap_ipc_owner", "cap_sys_module", "cap_sys_rawio", "cap_sys_chroot", "cap_sys_ptrace",
+	"cap_sys_pacct", "cap_sys_admin", "cap_sys_boot", "cap_sys_nice", "cap_sys_resource", "cap_sys_time",
+	"cap_sys_tty_config", "cap_mknod", "cap_lease", "cap_audit_write", "cap_audit_control", "cap_setfcap",
+	"cap_mac_override", "cap_mac_admin", "cap_syslog", "cap_wake_alarm", "cap_block_suspend", "cap_audit_read",
+	"cap_perfmon", "cap_bpf", "cap_checkpoint_restore",
+}
+
+// CapabilityFinding is a file that was given linux file capabilities by a layer.
+type CapabilityFinding struct {
+	Path         string
+	Layer        int
+	Capabilities string
+}
+
+// xattrs returns the extended attributes stored in the PAX records of the given tar header.
+func xattrs(header *tar.Header) map[string]string {
+	result := make(map[string]string)
+	for key, value := range header.PAXRecords {
+		if strings.HasPrefix(key, paxXattrPrefix) {
+			result[strings.TrimPrefix(key, paxXattrPrefix)] = value
+		}
+	}
+	return result
+}
+
+// copyXattrs duplicates the given xattrs (nil when there are none).
+func copyXattrs(attributes map[string]string) map[string]string {
+	if len(attributes) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(attributes))
+	for name, value := range attributes {
+		result[name] = value
+	}
+	return result
+}
+
+// sameXattrs indicates if both files have the same xattrs, not counting the capabilities (which are compared on
+// their own).
+func sameXattrs(before, after map[string]string) bool {
+	count := func(attributes map[string]string) int {
+		if _, ok := attributes[capabilityXattr]; ok {
+			return len(attributes) - 1
+		}
+		return len(attributes)
+	}
+	if count(before) != count(after) {
+		return false
+	}
+	for name, value := range before {
+		if other, ok := after[name]; name != capabilityXattr && (!ok || other != value) {
+			return false
+		}
+	}
+	return true
+}
+
+// xattrValue returns the value of the given xattr for display: capabilities in getcap notation, SELinux labels
+// without their terminating NUL and any other value as text (quoted when it is not printable).
+func xattrValue(name, value string) string {
+	switch name {
+	case capabilityXattr:
+		if capabilities, err := parseCapabilities(value); err == nil {
+			return capabilities
+		}
+	case selinuxXattr:
+		value = strings.TrimRight(value, "\x00")
+	}
+	for _, char := range value {
+		if !unicode.IsPrint(char) {
+			return strconv.Quote(value)
+		}
+	}
+	return value
+}
+
+// Capabilities returns the linux file capabilities of the file (its security.capability xattr) in getcap notation,
+// e.g. "cap_net_bind_service=ep". It is empty when the file has none, or when they cannot be parsed (see WarnHeader).
+func (data *FileInfo) Capabilities() string {
+	raw, ok := data.Xattrs[capabilityXattr]
+	if !ok {
+		return ""
+	}
+	capabilities, _ := parseCapabilities(raw)
+	return capabilities
+}
+
+// SELinuxLabel returns the SELinux label of the file (its security.selinux xattr), empty when it has none.
+func (data *FileInfo) SELinuxLabel() string {
+	return strings.TrimRight(data.Xattrs[selinuxXattr], "\x00")
+}
+
+// XattrsString lists the xattrs of the file (in name order) for display, e.g.
+// "security.capability=cap_net_bind_service=ep user.mime_type=text/plain".
+func (data *FileInfo) XattrsString() string {
+	names := make([]string, 0, len(data.Xattrs))
+	for name := range data.Xattrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for idx, name := range names {
+		names[idx] = name + "=" + xattrValue(name, data.Xattrs[name])
+	}
+	return strings.Join(names, " ")
+}
+
+// parseCapabilities decodes a security.capability xattr value (struct vfs_cap_data) into the textual form used by
+// getcap (e.g. "cap_net_bind_service=ep").
+func parseCapabilities(raw string) (string, error) {
+	data := []byte(raw)
+	if len(data) < 4 {
+		return "", fmt.Errorf("capability data too short (%d bytes)", len(data))
+	}
+	magic := binary.LittleEndian.Uint32(data[0:4])
+
+	var words int
+	switch magic & vfsCapRevisionMask {
+	case vfsCapRevision1:
+		words = 1
+	case vfsCapRevision2, vfsCapRevision3:
+		words = 2
+	default:
+		return "", fmt.Errorf("unknown capability revision: 0x%x", magic&vfsCapRevisionMask)
+	}
+	if len(data) < 4+words*8 {
+		return "", fmt.Errorf("capability data too short (%d bytes)", len(data))
+	}
+
+	var permitted, inheritable uint64
+	for idx := 0; idx < words; idx++ {
+		offset := 4 + idx*8
+		permitted |= uint64(binary.LittleEndian.Uint32(data[offset:offset+4])) << uint(32*idx)
+		inheritable |= uint64(binary.LittleEndian.Uint32(data[offset+4:offset+8])) << uint(32*idx)
+	}
+	effective := magic&vfsCapFlagsEffective != 0
+
+	// group the capabilities that share the same set of flags
+	groups := make(map[string][]string)
+	var order []string
+	for bit := uint(0); bit < 64; bit++ {
+		var flags string
+		if effective && permitted&(1<<bit) != 0 {
+			flags += "e"
+		}
+		if inheritable&(1<<bit) != 0 {
+			flags += "i"
+		}
+		if permitted&(1<<bit) != 0 {
+			flags += "p"
+		}
+		if flags == "" {
+			continue
+		}
+		name := fmt.Sprintf("cap_%d", bit)
+		if int(bit) < len(capabilityNames) {
+			name = capabilityNames[bit]
+		}
+		if _, ok := groups[flags]; !ok {
+			order = append(order, flags)
+		}
+		groups[flags] = append(groups[flags], name)
+	}
+
+	sort.Strings(order)
+	var result []string
+	for _, flags := range order {
+		result = append(result, strings.Join(groups[flags], ",")+"="+flags)
+	}
+	return strings.Join(result, " "), nil
+}
+
+// CapabilityFindings lists every file that is given linux file capabilities by any of the given layers.
+func CapabilityFindings(trees []*FileTree) []CapabilityFinding {
+	var findings []CapabilityFinding
+	for idx, tree := range trees {
+		tree.VisitDepthParentFirst(func(node *FileNode) error {
+			if capabilities := node.Data.FileInfo.Capabilities(); capabilities != "" {
+				findings = append(findings, CapabilityFinding{
+					Path:         node.Path(),
+					Layer:        idx,
+					Capabilities: capabilities,
+				})
+			}
+			return nil
+		}, nil)
+	}
+	return findings
+}
@@ -0,0 +1,113 @@
+package filetree
+
+import (
+	"archive/tar"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func capabilityData(magic uint32, permitted, inheritable uint32) string {
+	data := make([]byte, 20)
+	binary.LittleEndian.PutUint32(data[0:4], magic)
+	binary.LittleEndian.PutUint32(data[4:8], permitted)
+	binary.LittleEndian.PutUint32(data[8:12], inheritable)
+	return string(data)
+}
+
+func TestParseCapabilities(t *testing.T) {
+	// cap_net_bind_service (10) and cap_net_raw (13), effective + permitted
+	raw := capabilityData(vfsCapRevision2|vfsCapFlagsEffective, 1<<10|1<<13, 0)
+	actual, err := parseCapabilities(raw)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if expected := "cap_net_bind_service,cap_net_raw=ep"; actual != expected {
+		t.Errorf("Expected capabilities '%s' got '%s'", expected, actual)
+	}
+
+	raw = capabilityData(vfsCapRevision2, 1<<0, 1<<1)
+	actual, err = parseCapabilities(raw)
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if expected := "cap_dac_override=i cap_chown=p"; actual != expected {
+		t.Errorf("Expected capabilities '%s' got '%s'", expected, actual)
+	}
+
+	if _, err = parseCapabilities("\x00"); err == nil {
+		t.Errorf("Expected an error for truncated capability data")
+	}
+}
+
+func TestCapabilityFindings(t *testing.T) {
+	header := &tar.Header{
+		Name:     "usr/bin/ping",
+		Typeflag: tar.TypeReg,
+		PAXRecords: map[string]string{
+			"SCHILY.xattr.security.capability": capabilityData(vfsCapRevision2|vfsCapFlagsEffective, 1<<13, 0),
+			"SCHILY.xattr.security.selinux":    "system_u:object_r:ping_exec_t:s0\x00",
+		},
+	}
+	info := NewFileInfoFromHeader(header, header.Name)
+
+	if info.SELinuxLabel() != "system_u:object_r:ping_exec_t:s0" {
+		t.Errorf("Unexpected SELinux label: %q", info.SELinuxLabel())
+	}
+
+	lower := NewFileTree()
+	lower.AddPath("/usr/bin/ls", FileInfo{})
+	upper := NewFileTree()
+	upper.AddPath("/usr/bin/ping", info)
+
+	findings := CapabilityFindings([]*FileTree{lower, upper})
+	if len(findings) != 1 {
+		t.Fatalf("Expected 1 finding, got %d", len(findings))
+	}
+	if findings[0].Path != "/usr/bin/ping" || findings[0].Layer != 1 || findings[0].Capabilities != "cap_net_raw=ep" {
+		t.Errorf("Unexpected finding: %+v", findings[0])
+	}
+}
+
+func TestXattrs(t *testing.T) {
+	header := &tar.Header{
+		Name:     "usr/sbin/httpd",
+		Typeflag: tar.TypeReg,
+		PAXRecords: map[string]string{
+			"SCHILY.xattr.security.capability": capabilityData(vfsCapRevision2|vfsCapFlagsEffective, 1<<10, 0),
+			"SCHILY.xattr.user.mime_type":      "application/x-executable",
+			"mtime":                            "1700000000",
+		},
+	}
+	info := NewFileInfoFromHeader(header, header.Name)
+	if len(info.Xattrs) != 2 {
+		t.Fatalf("Expected 2 xattrs, got %v", info.Xattrs)
+	}
+	if expected := "security.capability=cap_net_bind_service=ep user.mime_type=application/x-executable"; info.XattrsString() != expected {
+		t.Errorf("Expected xattrs '%s' got '%s'", expected, info.XattrsString())
+	}
+
+	tree := NewFileTree()
+	node, _ := tree.AddPath("/usr/sbin/httpd", info)
+	if metadata := node.metadata(); metadata[10] != '+' {
+		t.Errorf("Expected capabilities to be marked, got %q", metadata)
+	}
+	if !strings.HasSuffix(node.MetadataString(), info.XattrsString()) {
+		t.Errorf("Expected the xattrs in the metadata, got %q", node.MetadataString())
+	}
+
+	// the capabilities were dropped and an xattr changed
+	plain := *info.Copy()
+	delete(plain.Xattrs, "security.capability")
+	plain.Xattrs["user.mime_type"] = "text/plain"
+	changes := info.AttributeChanges(plain)
+	if len(changes) != 2 || changes[0].Attribute != "capabilities" || changes[1].Attribute != "xattrs" {
+		t.Fatalf("Unexpected changes: %v", changes)
+	}
+	if changes[0].String() != "capabilities cap_net_bind_service=ep→" {
+		t.Errorf("Unexpected change: %s", changes[0])
+	}
+	if len(info.Xattrs) != 2 {
+		t.Errorf("Expected the copy not to share the xattrs of the file")
+	}
+}
@@ -0,0 +1,133 @@
+package i18n
+
+// German is the German translation.
+var German = &Locale{
+	Name:               "de",
+	DecimalSeparator:   ",",
+	ThousandsSeparator: ".",
+	Messages: map[string]string{
+		// panes
+		"Layers":                    "Schichten",
+		"Current Layer Contents":    "Inhalt der aktuellen Schicht",
+		"Aggregated Layer Contents": "Zusammengefasster Inhalt der Schichten",
+		"Image & Layer Details":     "Image- & Schichtdetails",
+		"Global":                    "Allgemein",
+		"Filetree":                  "Dateibaum",
+		"Details":                   "Details",
+		"Help":                      "Hilfe",
+		"Filter":                    "Filter",
+		"Warnings":                  "Warnungen",
+
+		// key bindings
+		"Quit":                                  "Beenden",
+		"Switch view":                           "Ansicht wechseln",
+		"Filter files":                          "Dateien filtern",
+		"Key bindings":                          "Tastenbelegung",
+		"Show key bindings":                     "Tastenbelegung anzeigen",
+		"Press ? to see all key bindings":       "? zeigt die gesamte Tastenbelegung",
+		"Close key bindings":                    "Tastenbelegung schließen",
+		"Scroll down":                           "Nach unten blättern",
+		"Scroll up":                             "Nach oben blättern",
+		"Move down":                             "Nach unten",
+		"Move up":                               "Nach oben",
+		"Move to parent directory":              "Zum übergeordneten Verzeichnis",
+		"Collapse/expand directory":             "Verzeichnis ein-/ausklappen",
+		"Show/hide added files":                 "Hinzugefügte Dateien ein-/ausblenden",
+		"Show/hide removed files":               "Entfernte Dateien ein-/ausblenden",
+		"Show/hide modified files":              "Geänderte Dateien ein-/ausblenden",
+		"Show/hide unmodified files":            "Unveränderte Dateien ein-/ausblenden",
+		"Copy the path of the selected file":    "Pfad der ausgewählten Datei kopieren",
+		"Select the next layer":                 "Nächste Schicht auswählen",
+		"Select the previous layer":             "Vorherige Schicht auswählen",
+		"Show layer changes":                    "Änderungen der Schicht anzeigen",
+		"Show aggregated changes":               "Zusammengefasste Änderungen anzeigen",
+		"Copy the digest of the selected layer": "Digest der ausgewählten Schicht kopieren",
+		"Copy the command that created the selected layer":      "Befehl der ausgewählten Schicht kopieren",
+		"Toggle the raw command of the selected layer":          "Unverarbeiteten Befehl der ausgewählten Schicht umschalten",
+		"Show the xattrs and capabilities of the selected file": "Xattrs und Capabilities der ausgewählten Datei anzeigen",
+		"Always hide the selected path for this image (toggle)": "Ausgewählten Pfad für dieses Image immer ausblenden (umschalten)",
+		"Show/hide always hidden paths":                         "Immer ausgeblendete Pfade ein-/ausblenden",
+		"Group/ungroup package manager database changes":        "Änderungen an Paketdatenbanken gruppieren/aufteilen",
+		"Show the next page of a large directory":               "Nächste Seite eines großen Verzeichnisses",
+		"Show the previous page of a large directory":           "Vorherige Seite eines großen Verzeichnisses",
+		"Only show files with the next badge":                   "Nur Dateien mit der nächsten Markierung anzeigen",
+		"Collapse all directories":                              "Alle Verzeichnisse einklappen",
+		"Expand all directories":                                "Alle Verzeichnisse ausklappen",
+		"Collapse directories below the given depth":            "Verzeichnisse unterhalb der gegebenen Tiefe einklappen",
+		"Show analysis warnings":                                "Warnungen der Analyse anzeigen",
+		"Close analysis warnings":                               "Warnungen der Analyse schließen",
+		"Previous filter":                                       "Vorheriger Filter",
+		"Next filter":                                           "Nächster Filter",
+		"Keep filter in history":                                "Filter im Verlauf behalten",
+		"Save search":                                           "Suche speichern",
+
+		"Show/hide what each layer contributed to the selected directory": "Beitrag jeder Schicht zum ausgewählten Verzeichnis ein-/ausblenden",
+
+		// status bar
+		"Collapse dir":     "Verz. einklappen",
+		"Added files":      "Hinzugefügt",
+		"Removed files":    "Entfernt",
+		"Modified files":   "Geändert",
+		"Unmodified files": "Unverändert",
+		"Badge filter":     "Markierungsfilter",
+		"Always hidden":    "Immer ausgeblendet",
+		"Group pkg db":     "Paketdatenbanken gruppieren",
+		"Page large dirs":  "Große Verz. blättern",
+		"%d warnings":      "%d Warnungen",
+
+		// details
+		"Digest: ":                 "Digest: ",
+		"Tar ID: ":                 "Tar-ID: ",
+		"Metadata only: ":          "Nur Metadaten: ",
+		"(metadata only) ":         "(nur Metadaten) ",
+		"Command:":                 "Befehl:",
+		"Command (as recorded):":   "Befehl (wie aufgezeichnet):",
+		"\nModified file:":         "\nGeänderte Datei:",
+		"\nConfig changes:":        "\nKonfigurationsänderungen:",
+		"Image efficiency score:":  "Effizienz des Images:",
+		"Potential wasted space:":  "Möglicherweise verschwendeter Platz:",
+		"Count":                    "Anzahl",
+		"Total Space":              "Gesamtgröße",
+		"Path":                     "Pfad",
+		"Capabilities":             "Capabilities",
+		"Layer":                    "Schicht",
+		"Re-added after deletion:": "Nach dem Löschen erneut hinzugefügt:",
+		"Files with capabilities:": "Dateien mit Capabilities:",
+		"Duplicate file contents:": "Mehrfach gespeicherte Dateiinhalte:",
+		"Copies":                   "Kopien",
+		"Reclaimable":              "Einsparbar",
+		"Paths":                    "Pfade",
+		"Partial analysis:":        "Unvollständige Analyse:",
+		" the memory limit was reached, so small files are aggregated per directory and file contents were not compared\n": " das Speicherlimit wurde erreicht, daher sind kleine Dateien je Verzeichnis zusammengefasst und Dateiinhalte wurden nicht verglichen\n",
+
+		// wasted space by instruction
+		"Wasted by this instruction:":  "Von dieser Anweisung verschwendet:",
+		"  … and %s more files":        "  … und %s weitere Dateien",
+		"Wasted space by instruction:": "Verschwendeter Platz je Anweisung:",
+		"Wasted":                       "Verschwendet",
+
+		"Compared to:": "Verglichen mit:",
+		"Image size:":  "Größe des Images:",
+		"Growth":       "Zuwachs",
+		"Instruction":  "Anweisung",
+		"%s files added (%s), %s files removed (%s), %s files modified (%s)\n\n": "%s Dateien hinzugefügt (%s), %s Dateien entfernt (%s), %s Dateien geändert (%s)\n\n",
+		"Read at startup:":       "Beim Start gelesen:",
+		"Never read at startup:": "Beim Start nie gelesen:",
+		"%s files":               "%s Dateien",
+		"Unread":                 "Ungelesen",
+		"Files":                  "Dateien",
+
+		// blame
+		"Layer contributions to:": "Beiträge der Schichten zu:",
+		"Added":                   "Hinzugefügt",
+		"Overwritten":             "Überschrieben",
+		"Deleted":                 "Gelöscht",
+		"Remaining":               "Verbleibend",
+
+		// warnings
+		"Analysis warnings (press W or Esc to close)":     "Warnungen der Analyse (W oder Esc zum Schließen)",
+		"No issues were found while analyzing the image.": "Bei der Analyse des Images wurden keine Probleme gefunden.",
+		"Final filesystem": "Endgültiges Dateisystem",
+		"Layer %s":         "Schicht %s",
+	},
+}
@@ -0,0 +1,113 @@
+// Package i18n translates the user facing text of dive and formats numbers and sizes for the selected locale.
+// Messages are looked up by their English text, such that untranslated messages (and the default English locale)
+// need no catalog entries.
+package i18n
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/dustin/go-humanize"
+)
+
+// Locale holds the translated messages and number formatting conventions for a single language.
+type Locale struct {
+	Name string
+	// DecimalSeparator and ThousandsSeparator are used when formatting numbers and sizes
+	DecimalSeparator   string
+	ThousandsSeparator string
+	// Messages maps the English text of a message to its translation
+	Messages map[string]string
+}
+
+// English is the default locale (the messages in the source are English).
+var English = &Locale{
+	Name:               "en",
+	DecimalSeparator:   ".",
+	ThousandsSeparator: ",",
+}
+
+// locales are the available locales by language code.
+var locales = map[string]*Locale{
+	English.Name: English,
+	German.Name:  German,
+}
+
+// current is the locale all text is translated to.
+var current = English
+
+// Locales returns the language codes of the available locales.
+func Locales() []string {
+	var names []string
+	for name := range locales {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// languageCode reduces a POSIX locale name (e.g. "de_DE.UTF-8") to its language code ("de").
+func languageCode(name string) string {
+	if idx := strings.IndexAny(name, "_.@-"); idx >= 0 {
+		name = name[:idx]
+	}
+	return strings.ToLower(name)
+}
+
+// SetLocale selects the locale to use, given a language code or a POSIX locale name.
+func SetLocale(name string) error {
+	locale, ok := locales[languageCode(name)]
+	if !ok {
+		return fmt.Errorf("unsupported locale '%s' (expected one of %s)", name, strings.Join(Locales(), ", "))
+	}
+	current = locale
+	return nil
+}
+
+// EnvironmentLocale returns the locale requested by the environment (LC_ALL, LC_MESSAGES, then LANG), if it is
+// available.
+func EnvironmentLocale() (string, bool) {
+	for _, variable := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		value := os.Getenv(variable)
+		if value == "" {
+			continue
+		}
+		// the first variable set decides, even when it names a locale dive has no translation for
+		_, ok := locales[languageCode(value)]
+		return value, ok
+	}
+	return "", false
+}
+
+// T translates the given message.
+func T(message string) string {
+	if translated, ok := current.Messages[message]; ok {
+		return translated
+	}
+	return message
+}
+
+// Tf translates the given format string and formats it with the given arguments.
+func Tf(format string, args ...interface{}) string {
+	return fmt.Sprintf(T(format), args...)
+}
+
+// localizeNumber swaps the (English) separators of a formatted number for those of the current locale.
+func localizeNumber(number string) string {
+	if current == English {
+		return number
+	}
+	return strings.NewReplacer(".", current.DecimalSeparator, ",", current.ThousandsSeparator).Replace(number)
+}
+
+// Bytes formats a size in human readable units (e.g. "1,2 MB" in German).
+func Bytes(size uint64) string {
+	return localizeNumber(humanize.Bytes(size))
+}
+
+// Number formats a count with thousands separators (e.g. "1.234.567" in German).
+func Number(number int64) string {
+	return localizeNumber(humanize.Comma(number))
+}
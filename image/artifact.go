@@ -0,0 +1,250 @@
+package image
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/wagoodman/dive/filetree"
+)
+
+const (
+	// ociIndexMediaType is the media type of an OCI image index (a list of manifests)
+	ociIndexMediaType = "application/vnd.oci.image.index.v1+json"
+	// ociRefAnnotation names a manifest of an OCI image layout (e.g. a tag)
+	ociRefAnnotation = "org.opencontainers.image.ref.name"
+	// ociTitleAnnotation names the file a layer of an artifact was pushed from
+	ociTitleAnnotation = "org.opencontainers.image.title"
+)
+
+// ociDescriptor refers to a blob of an OCI image layout (a manifest, an index, a config or a layer).
+type ociDescriptor struct {
+	MediaType    string            `json:"mediaType"`
+	ArtifactType string            `json:"artifactType"`
+	Digest       string            `json:"digest"`
+	Size         int64             `json:"size"`
+	Annotations  map[string]string `json:"annotations"`
+}
+
+// ociIndex lists the manifests of an OCI image layout (index.json) or of a multi-platform image.
+type ociIndex struct {
+	Manifests []ociDescriptor `json:"manifests"`
+}
+
+// ociManifest describes an OCI image or artifact: its config and its layers.
+type ociManifest struct {
+	MediaType    string            `json:"mediaType"`
+	ArtifactType string            `json:"artifactType"`
+	Config       ociDescriptor     `json:"config"`
+	Layers       []ociDescriptor   `json:"layers"`
+	Annotations  map[string]string `json:"annotations"`
+}
+
+// ArtifactLayer is a single layer (blob) of an OCI artifact. Layers holding a (possibly gzip compressed) tar are read
+// into a tree, any other layer is summarized by its content type.
+type ArtifactLayer struct {
+	Digest    string
+	MediaType string
+	Size      int64
+	// Title is the file the layer was pushed from, if recorded
+	Title string
+	// Tree is the file tree of a tar-like layer (nil for any other layer)
+	Tree *filetree.FileTree
+	// ContentType is the detected type of the (uncompressed) content of a layer that is not tar-like
+	ContentType string
+}
+
+// Artifact is an OCI artifact (e.g. a Helm chart or a WASM module) or image, as stored in an OCI image layout.
+type Artifact struct {
+	Reference    string
+	ArtifactType string
+	Annotations  map[string]string
+	Layers       []ArtifactLayer
+}
+
+// LoadArtifact reads the artifact of the given reference (the org.opencontainers.image.ref.name of a manifest, which
+// may be omitted when the layout holds a single manifest) from the OCI image layout at the given directory.
+func LoadArtifact(layoutDir, reference string) (*Artifact, error) {
+	descriptor, err := resolveLayoutManifest(layoutDir, reference)
+	if err != nil {
+		return nil, err
+	}
+	var manifest ociManifest
+	if err := readLayoutJSON(layoutDir, descriptor.Digest, &manifest); err != nil {
+		return nil, err
+	}
+
+	artifact := &Artifact{
+		Reference:    descriptor.Annotations[ociRefAnnotation],
+		ArtifactType: manifest.ArtifactType,
+		Annotations:  manifest.Annotations,
+	}
+	if artifact.ArtifactType == "" {
+		artifact.ArtifactType = manifest.Config.MediaType
+	}
+	for _, layer := range manifest.Layers {
+		analyzed, err := analyzeArtifactLayer(layoutDir, layer)
+		if err != nil {
+			return nil, err
+		}
+		artifact.Layers = append(artifact.Layers, analyzed)
+	}
+	return artifact, nil
+}
+
+// resolveLayoutManifest finds the manifest of the given reference in the index of the layout, descending into nested
+// indexes (multi-platform images, where the first manifest is taken).
+func resolveLayoutManifest(layoutDir, reference string) (ociDescriptor, error) {
+	if _, err := os.Stat(filepath.Join(layoutDir, "oci-layout")); err != nil {
+		return ociDescriptor{}, fmt.Errorf("%s is not an OCI image layout: %v", layoutDir, err)
+	}
+	var index ociIndex
+	if err := readJSONFile(filepath.Join(layoutDir, "index.json"), &index); err != nil {
+		return ociDescriptor{}, err
+	}
+
+	var candidates []ociDescriptor
+	var names []string
+	for _, manifest := range index.Manifests {
+		name := manifest.Annotations[ociRefAnnotation]
+		names = append(names, name)
+		if reference == "" || name == reference {
+			candidates = append(candidates, manifest)
+		}
+	}
+	switch {
+	case len(candidates) == 0:
+		return ociDescriptor{}, fmt.Errorf("no manifest named '%s' in %s (found: %s)", reference, layoutDir, strings.Join(names, ", "))
+	case len(candidates) > 1:
+		return ociDescriptor{}, fmt.Errorf("%s holds %d manifests, select one of: %s", layoutDir, len(candidates), strings.Join(names, ", "))
+	}
+
+	descriptor := candidates[0]
+	for descriptor.MediaType == ociIndexMediaType {
+		var nested ociIndex
+		if err := readLayoutJSON(layoutDir, descriptor.Digest, &nested); err != nil {
+			return ociDescriptor{}, err
+		}
+		if len(nested.Manifests) == 0 {
+			return ociDescriptor{}, fmt.Errorf("index %s holds no manifests", descriptor.Digest)
+		}
+		// keep the name given by the layout
+		nested.Manifests[0].Annotations = descriptor.Annotations
+		descriptor = nested.Manifests[0]
+	}
+	return descriptor, nil
+}
+
+// blobPath returns the location of the blob of the given digest (e.g. "sha256:abc...") in the layout.
+func blobPath(layoutDir, digest string) (string, error) {
+	parts := strings.SplitN(digest, ":", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" || strings.ContainsAny(digest, `/\`) {
+		return "", fmt.Errorf("invalid blob digest '%s'", digest)
+	}
+	return filepath.Join(layoutDir, "blobs", parts[0], parts[1]), nil
+}
+
+// readLayoutJSON decodes the JSON blob of the given digest from the layout.
+func readLayoutJSON(layoutDir, digest string, value interface{}) error {
+	path, err := blobPath(layoutDir, digest)
+	if err != nil {
+		return err
+	}
+	return readJSONFile(path, value)
+}
+
+func readJSONFile(path string, value interface{}) error {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(contents, value); err != nil {
+		return fmt.Errorf("could not read %s: %v", path, err)
+	}
+	return nil
+}
+
+// analyzeArtifactLayer reads the tree of a tar-like layer, or detects the content type of any other layer.
+func analyzeArtifactLayer(layoutDir string, descriptor ociDescriptor) (ArtifactLayer, error) {
+	layer := ArtifactLayer{
+		Digest:    descriptor.Digest,
+		MediaType: descriptor.MediaType,
+		Size:      descriptor.Size,
+		Title:     descriptor.Annotations[ociTitleAnnotation],
+	}
+	path, err := blobPath(layoutDir, descriptor.Digest)
+	if err != nil {
+		return layer, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return layer, fmt.Errorf("could not open layer %s: %v", descriptor.Digest, err)
+	}
+	defer file.Close()
+
+	content, err := uncompressed(bufio.NewReader(file))
+	if err != nil {
+		return layer, fmt.Errorf("could not read layer %s: %v", descriptor.Digest, err)
+	}
+	head, _ := content.Peek(512)
+	if !isTar(head) {
+		layer.ContentType = contentType(head)
+		return layer, nil
+	}
+
+	tree := filetree.NewFileTree()
+	tree.Name = descriptor.Digest
+	err = readFileList(tree, content, func(element filetree.FileInfo) {
+		tree.FileSize += uint64(element.Size())
+		if _, err := tree.AddPath(element.Path, element); err != nil {
+			tree.Warn(filetree.WarningSkippedEntry, element.Path, "%v", err)
+		}
+	})
+	if err != nil {
+		return layer, fmt.Errorf("could not read layer %s: %v", descriptor.Digest, err)
+	}
+	layer.Tree = tree
+	return layer, nil
+}
+
+// uncompressed returns the content of the given blob, decompressing it when it is gzip compressed.
+func uncompressed(reader *bufio.Reader) (*bufio.Reader, error) {
+	magic, _ := reader.Peek(2)
+	if !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		return reader, nil
+	}
+	gzipReader, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	return bufio.NewReader(gzipReader), nil
+}
+
+// isTar indicates if the given header block is the header of a tar entry.
+func isTar(head []byte) bool {
+	if len(head) < 512 {
+		return false
+	}
+	_, err := tar.NewReader(bytes.NewReader(head)).Next()
+	return err == nil || err == io.ErrUnexpectedEOF
+}
+
+// contentType detects the type of the content starting with the given bytes.
+func contentType(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("\x00asm")):
+		return "application/wasm"
+	case bytes.HasPrefix(head, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		return "application/zstd"
+	}
+	return http.DetectContentType(head)
+}
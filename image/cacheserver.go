@@ -0,0 +1,354 @@
+package image
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wagoodman/dive/filetree"
+	"golang.org/x/net/context"
+)
+
+// CacheSocket is the unix socket the cache server listens on (see ServeCache), which is tried first whenever an image
+// is analyzed for the user interface (see InitializeData). It defaults to a socket in the runtime directory of the user
+// ($XDG_RUNTIME_DIR), or else in a directory of the temporary directory that only the user may access.
+var CacheSocket = defaultCacheSocket()
+
+const (
+	// cacheDialTimeout bounds connecting to the cache server, such that dive starts as fast as ever when none is
+	// running.
+	cacheDialTimeout = 200 * time.Millisecond
+	// cacheKeepAlive is how often the cache server sends whitespace (which the decoder of the response skips) while it
+	// analyzes an image.
+	cacheKeepAlive = 2 * time.Second
+)
+
+// cacheIdleTimeout bounds waiting for the cache server to send anything, such that a server that hangs does not hang
+// dive. The server keeps the connection alive (see cacheKeepAlive) while it analyzes an image.
+var cacheIdleTimeout = 10 * time.Second
+
+// defaultCacheSocket returns the default location of the socket of the cache server.
+func defaultCacheSocket() string {
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return filepath.Join(runtimeDir, "dive.sock")
+	}
+	return filepath.Join(os.TempDir(), fmt.Sprintf("dive-%d", os.Getuid()), "cache.sock")
+}
+
+// ListenCache listens on the given unix socket for the cache server (see ServeCache), creating its directory when
+// missing such that only the user may access it. The socket itself is only accessible to the user as well.
+func ListenCache(socket string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(socket), 0700); err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(socket, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// cacheRequest asks the cache server for the analysis of an image.
+type cacheRequest struct {
+	Image string `json:"image"`
+}
+
+// cachedLayer is a Layer as sent by the cache server (its trees are sent once, for all layers).
+type cachedLayer struct {
+	TarPath         string            `json:"tarPath"`
+	History         ImageHistoryEntry `json:"history"`
+	Index           int               `json:"index"`
+	MetadataChanges []MetadataChange  `json:"metadataChanges,omitempty"`
+	Digest          string            `json:"digest,omitempty"`
+	MetadataOnly    string            `json:"metadataOnly,omitempty"`
+}
+
+// cacheResponse is the analysis of an image as sent by the cache server: the layers (newest first, as returned by
+// LoadImage) and the layer trees (oldest first).
+type cacheResponse struct {
+	Error  string               `json:"error,omitempty"`
+	Layers []*cachedLayer       `json:"layers,omitempty"`
+	Trees  []*filetree.FileTree `json:"trees,omitempty"`
+	// Degraded indicates the analysis was degraded to stay within the memory limit of the server (see Degraded)
+	Degraded bool `json:"degraded,omitempty"`
+}
+
+// cacheEntry is the analysis of an image held by the cache server, valid as long as the reference names the same
+// image ID.
+type cacheEntry struct {
+	id       string
+	response []byte
+	used     time.Time
+}
+
+// cacheServer holds the analysis of the most recently requested images.
+type cacheServer struct {
+	lock      sync.Mutex
+	entries   map[string]*cacheEntry
+	maxImages int
+	// loading is held while an image is loaded: images are loaded one at a time, since the memory gauge (see Degraded)
+	// and the progress output are shared by the process
+	loading sync.Mutex
+	// load analyzes an image (LoadImage)
+	load func(imageID string) ([]*Layer, []*filetree.FileTree, float64, filetree.EfficiencySlice, error)
+}
+
+// ServeCache answers analysis requests on the given listener until it is closed, keeping the analysis of up to the
+// given number of images in memory. A cached analysis is served as long as the reference still names the same image,
+// so a rebuilt tag is analyzed again.
+func ServeCache(listener net.Listener, maxImages int) error {
+	server := &cacheServer{entries: make(map[string]*cacheEntry), maxImages: maxImages, load: LoadImage}
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return err
+		}
+		go server.handle(conn)
+	}
+}
+
+// handle answers a single request, keeping the connection alive while the image is analyzed.
+func (server *cacheServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	var request cacheRequest
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&request); err != nil {
+		logrus.Debug("invalid cache request: ", err)
+		return
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(cacheKeepAlive)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				conn.Write([]byte("\n"))
+			case <-done:
+				return
+			}
+		}
+	}()
+	response, err := server.respond(request.Image)
+	close(done)
+	<-stopped
+	if err != nil {
+		response, _ = json.Marshal(cacheResponse{Error: err.Error()})
+	}
+	if _, err := conn.Write(response); err != nil {
+		logrus.Debug("could not send cached analysis: ", err)
+	}
+}
+
+// respond returns the (encoded) analysis of the given image (see analysis). A failure to analyze the image, even a
+// panic, is returned as an error, such that one image cannot bring the server down.
+func (server *cacheServer) respond(reference string) (response []byte, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			logrus.Errorf("could not analyze %s: %v", reference, recovered)
+			response, err = nil, fmt.Errorf("could not analyze the image: %v", recovered)
+		}
+	}()
+	return server.analysis(reference)
+}
+
+// analysis returns the (encoded) analysis of the given image, analyzing it when it is not cached or the reference
+// names another image than when it was cached (see imageID). Images are analyzed one at a time (cached analyses are
+// served meanwhile), and images whose ID cannot be determined are analyzed on every request without being cached.
+func (server *cacheServer) analysis(reference string) ([]byte, error) {
+	id := imageID(reference)
+	if response, ok := server.cached(reference, id); ok {
+		return response, nil
+	}
+
+	server.loading.Lock()
+	defer server.loading.Unlock()
+	if response, ok := server.cached(reference, id); ok {
+		// analyzed for another request while waiting
+		return response, nil
+	}
+
+	memory.reset()
+	layers, trees, _, _, err := server.load(reference)
+	if err != nil {
+		return nil, err
+	}
+	response := cacheResponse{Trees: trees, Degraded: Degraded()}
+	for _, layer := range layers {
+		if layer == nil {
+			response.Layers = append(response.Layers, nil)
+			continue
+		}
+		response.Layers = append(response.Layers, &cachedLayer{
+			TarPath:         layer.TarPath,
+			History:         layer.History,
+			Index:           layer.Index,
+			MetadataChanges: layer.MetadataChanges,
+			Digest:          layer.Digest,
+			MetadataOnly:    layer.MetadataOnly,
+		})
+	}
+	encoded, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+
+	if id == "" {
+		// the image was pulled while analyzing it
+		id = imageID(reference)
+	}
+	if id == "" {
+		return encoded, nil
+	}
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	server.entries[reference] = &cacheEntry{id: id, response: encoded, used: time.Now()}
+	server.evict()
+	return encoded, nil
+}
+
+// cached returns the cached analysis of the given image, if it was cached for the given image ID.
+func (server *cacheServer) cached(reference, id string) ([]byte, bool) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	entry, ok := server.entries[reference]
+	if !ok || id == "" || entry.id != id {
+		return nil, false
+	}
+	entry.used = time.Now()
+	return entry.response, true
+}
+
+// evict drops the least recently used analyses beyond the number of images to keep.
+func (server *cacheServer) evict() {
+	for len(server.entries) > server.maxImages {
+		var oldest string
+		for reference, entry := range server.entries {
+			if oldest == "" || entry.used.Before(server.entries[oldest].used) {
+				oldest = reference
+			}
+		}
+		delete(server.entries, oldest)
+	}
+}
+
+// imageID returns the ID of the image the given reference names (empty when it is not found): the digest of the
+// manifest for images of an OCI image layout or a registry, or else the ID of the image in the docker daemon.
+func imageID(reference string) string {
+	switch {
+	case strings.HasPrefix(reference, LayoutPrefix):
+		descriptor, err := resolveLayoutManifest(splitLayoutReference(reference))
+		if err != nil {
+			return ""
+		}
+		return descriptor.Digest
+	case strings.HasPrefix(reference, RegistryPrefix):
+		ref, err := parseRegistryReference(reference)
+		if err != nil {
+			return ""
+		}
+		digest, err := newRegistryClient(ref).manifestDigest(ref.Reference)
+		if err != nil {
+			return ""
+		}
+		return digest
+	}
+
+	dockerClient, err := newDockerClient()
+	if err != nil {
+		return ""
+	}
+	inspect, _, err := dockerClient.ImageInspectWithRaw(context.Background(), reference)
+	if err != nil {
+		return ""
+	}
+	return inspect.ID
+}
+
+// loadCached fetches the analysis of the given image from the cache server, returning false when no cache server is
+// running (or it could not analyze the image). Analyses of a selection of layers, or of the daemon of a docker context
+// given explicitly (see SetDockerContext), are never cached: the cache server connects to the daemon of the environment
+// it was started in. A socket that does not belong to the user is never trusted, since another user of the machine
+// could serve forged analyses on it.
+func loadCached(reference string) ([]*Layer, []*filetree.FileTree, bool) {
+	if selectedLayers != nil || dockerContext != "" {
+		return nil, nil, false
+	}
+	info, err := os.Lstat(CacheSocket)
+	if err != nil {
+		return nil, nil, false
+	}
+	if info.Mode()&os.ModeSocket == 0 || !ownedByCurrentUser(info) {
+		logrus.Debug("ignoring the cache socket, it does not belong to the user: ", CacheSocket)
+		return nil, nil, false
+	}
+	conn, err := net.DialTimeout("unix", CacheSocket, cacheDialTimeout)
+	if err != nil {
+		return nil, nil, false
+	}
+	defer conn.Close()
+
+	if err := json.NewEncoder(conn).Encode(cacheRequest{Image: reference}); err != nil {
+		logrus.Debug("could not request cached analysis: ", err)
+		return nil, nil, false
+	}
+	var response cacheResponse
+	if err := json.NewDecoder(bufio.NewReader(idleReader{conn})).Decode(&response); err != nil {
+		logrus.Debug("could not read cached analysis: ", err)
+		return nil, nil, false
+	}
+	if response.Error != "" {
+		logrus.Debug("cache server could not analyze the image: ", response.Error)
+		return nil, nil, false
+	}
+	if response.Degraded {
+		memory.markDegraded()
+	}
+
+	trees := response.Trees
+	layers := make([]*Layer, len(response.Layers))
+	for idx, cached := range response.Layers {
+		if cached == nil {
+			continue
+		}
+		if cached.Index < 0 || cached.Index >= len(trees) {
+			logrus.Debug("invalid cached layer index: ", cached.Index)
+			return nil, nil, false
+		}
+		layers[idx] = &Layer{
+			TarPath:         cached.TarPath,
+			History:         cached.History,
+			Index:           cached.Index,
+			Tree:            trees[len(trees)-1-cached.Index],
+			RefTrees:        trees,
+			MetadataChanges: cached.MetadataChanges,
+			Digest:          cached.Digest,
+			MetadataOnly:    cached.MetadataOnly,
+		}
+	}
+	return layers, trees, true
+}
+
+// idleReader reads from a connection, failing when nothing is received for cacheIdleTimeout.
+type idleReader struct {
+	conn net.Conn
+}
+
+func (reader idleReader) Read(buffer []byte) (int, error) {
+	reader.conn.SetReadDeadline(time.Now().Add(cacheIdleTimeout))
+	return reader.conn.Read(buffer)
+}
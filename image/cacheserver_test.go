@@ -0,0 +1,235 @@
+package image
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/wagoodman/dive/filetree"
+)
+
+// serveTestCache runs a cache server on a socket in a new temporary directory for the duration of the test.
+func serveTestCache(t *testing.T, maxImages int) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "dive-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(dir, "run", "cache.sock")
+	listener, err := ListenCache(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go ServeCache(listener, maxImages)
+
+	previous := CacheSocket
+	CacheSocket = socket
+	t.Cleanup(func() {
+		CacheSocket = previous
+		listener.Close()
+		os.RemoveAll(dir)
+	})
+	return socket
+}
+
+func hasPath(tree *filetree.FileTree, path string) bool {
+	_, err := tree.GetNode(path)
+	return err == nil
+}
+
+func TestCacheRoundTrip(t *testing.T) {
+	quietly(t)
+	socket := serveTestCache(t, 1)
+	image := newTestImage(t, layerTar(t, "etc/hosts", "usr/bin/app"), layerTar(t, "etc/hosts"))
+	dir := image.writeLayout(t)
+	reference := LayoutPrefix + dir
+
+	info, err := os.Stat(socket)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected a socket only the user may access, got %v (%v)", info.Mode(), err)
+	}
+	if info, err := os.Stat(filepath.Dir(socket)); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("Expected a directory only the user may access, got %v (%v)", info.Mode(), err)
+	}
+
+	layers, trees, ok := loadCached(reference)
+	if !ok {
+		t.Fatalf("Expected the cache server to analyze the image")
+	}
+	if len(layers) != 2 || len(trees) != 2 || layers[0].Tree != trees[1] || layers[1].RefTrees[0] != trees[0] {
+		t.Fatalf("Expected 2 layers referring to their trees, got %d layers and %d trees", len(layers), len(trees))
+	}
+	if _, err := trees[0].GetNode("/usr/bin/app"); err != nil {
+		t.Errorf("Expected the trees to be sent: %v", err)
+	}
+	if layers[0].Digest != layers[0].History.ID || layers[0].History.CreatedBy != "RUN step 1" {
+		t.Errorf("Expected the layer metadata to be sent, got %+v", layers[0])
+	}
+
+	// the image did not change (the manifest is the same), so the layers are not read again
+	for _, layer := range image.manifest.Layers {
+		path, _ := blobPath(dir, layer.Digest)
+		os.Remove(path)
+	}
+	if _, trees, ok := loadCached(reference); !ok || len(trees) != 2 || !hasPath(trees[0], "/usr/bin/app") {
+		t.Errorf("Expected the analysis to be served from the cache")
+	}
+
+	// another image evicts the analysis (only one is kept)
+	other := newTestImage(t, layerTar(t, "bin/sh")).writeLayout(t)
+	if _, trees, ok := loadCached(LayoutPrefix + other); !ok || len(trees) != 1 {
+		t.Errorf("Expected the other image to be analyzed")
+	}
+	if _, trees, ok := loadCached(reference); ok && hasPath(trees[0], "/usr/bin/app") {
+		t.Errorf("Expected the evicted image to be analyzed again (without its layers)")
+	}
+}
+
+func TestCacheDockerContext(t *testing.T) {
+	quietly(t)
+	serveTestCache(t, 1)
+	reference := LayoutPrefix + newTestImage(t, layerTar(t, "etc/hosts")).writeLayout(t)
+
+	// the endpoint of the current docker context is the one the cache server uses as well
+	previousEndpoint := dockerEndpoint.DockerEndpoint
+	dockerEndpoint.Host = "unix:///var/run/docker.sock"
+	defer func() { dockerEndpoint.DockerEndpoint = previousEndpoint }()
+	if _, _, ok := loadCached(reference); !ok {
+		t.Errorf("Expected the cache server to be used with the current docker context")
+	}
+
+	SetDockerContext("remote")
+	defer SetDockerContext("")
+	if _, _, ok := loadCached(reference); ok {
+		t.Errorf("Expected the cache server not to be used with an explicit docker context")
+	}
+}
+
+func TestCacheHangingServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dive-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "cache.sock")
+	listener, err := ListenCache(socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		// accept, but never answer
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	previous, previousTimeout := CacheSocket, cacheIdleTimeout
+	CacheSocket, cacheIdleTimeout = socket, 100*time.Millisecond
+	defer func() { CacheSocket, cacheIdleTimeout = previous, previousTimeout }()
+
+	started := time.Now()
+	if _, _, ok := loadCached("alpine"); ok {
+		t.Errorf("Expected no analysis from a server that does not answer")
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Errorf("Expected to give up on the server quickly, waited %v", elapsed)
+	}
+}
+
+func TestCacheForeignSocket(t *testing.T) {
+	socket := serveTestCache(t, 1)
+	if err := os.Lchown(socket, 65534, 65534); err != nil {
+		t.Skip("cannot give the socket to another user: ", err)
+	}
+	if _, _, ok := loadCached("alpine"); ok {
+		t.Errorf("Expected a socket of another user not to be used")
+	}
+
+	// a socket of the user is used (and fails to analyze the image, without a daemon)
+	if _, err := net.Dial("unix", socket); err != nil {
+		t.Errorf("Expected the server to still listen: %v", err)
+	}
+}
+
+func TestCacheDegraded(t *testing.T) {
+	quietly(t)
+	t.Cleanup(memory.reset)
+	server := &cacheServer{entries: make(map[string]*cacheEntry), maxImages: 2}
+	server.load = func(imageID string) ([]*Layer, []*filetree.FileTree, float64, filetree.EfficiencySlice, error) {
+		switch imageID {
+		case "large":
+			memory.markDegraded()
+		case "broken":
+			panic("corrupt layer")
+		}
+		return nil, []*filetree.FileTree{filetree.NewFileTree()}, 1, nil, nil
+	}
+	degraded := func(reference string) bool {
+		encoded, err := server.respond(reference)
+		if err != nil {
+			t.Fatalf("%s: could not analyze: %v", reference, err)
+		}
+		var response cacheResponse
+		if err := json.Unmarshal(encoded, &response); err != nil {
+			t.Fatalf("%s: could not decode: %v", reference, err)
+		}
+		return response.Degraded
+	}
+
+	if !degraded("large") {
+		t.Errorf("Expected the degraded analysis to be sent as such")
+	}
+	// a degraded analysis does not degrade the analysis of the next image
+	if degraded("small") {
+		t.Errorf("Expected the analysis of another image not to be degraded")
+	}
+	if _, err := server.respond("broken"); err == nil || !strings.Contains(err.Error(), "corrupt layer") {
+		t.Errorf("Expected a panic to be answered as an error, got %v", err)
+	}
+	if degraded("small") {
+		t.Errorf("Expected the server to keep serving after a panic")
+	}
+}
+
+func TestCacheDegradedClient(t *testing.T) {
+	t.Cleanup(memory.reset)
+	dir, err := ioutil.TempDir("", "dive-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	listener, err := ListenCache(filepath.Join(dir, "cache.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		json.NewDecoder(conn).Decode(&cacheRequest{})
+		json.NewEncoder(conn).Encode(cacheResponse{Trees: []*filetree.FileTree{filetree.NewFileTree()}, Degraded: true})
+	}()
+	previous := CacheSocket
+	CacheSocket = filepath.Join(dir, "cache.sock")
+	defer func() { CacheSocket = previous }()
+
+	if _, _, ok := loadCached("alpine"); !ok {
+		t.Fatalf("Expected the analysis to be served")
+	}
+	if !Degraded() {
+		t.Errorf("Expected a degraded analysis of the cache server to be shown as degraded")
+	}
+}
@@ -0,0 +1,232 @@
+package image
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"golang.org/x/net/context"
+)
+
+const (
+	whiteoutPrefix = ".wh."
+	opaqueWhiteout = ".wh..wh..opq"
+)
+
+// layerFile is what a single layer does to the file being looked for.
+type layerFile struct {
+	// removed indicates the layer removes the file (or one of its parent directories) from the layers below it
+	removed bool
+	// header is set when the layer adds the file
+	header *tar.Header
+	// layer is the name of the layer tar within the saved image, and entry the number of entries of the file before
+	// the one the layer adds (a layer tar may hold the file several times, the last entry wins)
+	layer string
+	entry int
+}
+
+// cleanTarPath normalizes a path within a layer tar (e.g. "./etc/hosts" or "/etc/hosts") to "etc/hosts".
+func cleanTarPath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// findLayerFile reads through a single layer tar, noting if the layer adds or removes the file at the given path. The
+// contents of the file are not read.
+func findLayerFile(reader *tar.Reader, target string) (layerFile, error) {
+	var result layerFile
+	entries := 0
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return result, err
+		}
+
+		name := cleanTarPath(header.Name)
+		dir, base := path.Split(name)
+		dir = strings.TrimSuffix(dir, "/")
+
+		switch {
+		case base == opaqueWhiteout:
+			// an opaque directory hides all contents of the directory from the layers below
+			if strings.HasPrefix(target, dir+"/") {
+				result.removed = true
+			}
+		case strings.HasPrefix(base, whiteoutPrefix):
+			removed := path.Join(dir, strings.TrimPrefix(base, whiteoutPrefix))
+			if target == removed || strings.HasPrefix(target, removed+"/") {
+				result.removed = true
+			}
+		case name == target:
+			result.header = header
+			result.entry = entries
+			entries++
+		}
+	}
+	return result, nil
+}
+
+// copyLayerFile copies the contents of the given entry of the file at the given path from a single layer tar (see
+// layerFile) to the given writer.
+func copyLayerFile(reader *tar.Reader, target string, entry int, writer io.Writer) error {
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return fmt.Errorf("%s is missing from the layer", "/"+target)
+		}
+		if err != nil {
+			return err
+		}
+		if cleanTarPath(header.Name) != target {
+			continue
+		}
+		if entry > 0 {
+			entry--
+			continue
+		}
+		_, err = io.Copy(writer, reader)
+		return err
+	}
+}
+
+// errFileCopied stops reading the saved image once the file is copied.
+var errFileCopied = fmt.Errorf("file copied")
+
+// ReadFile writes the contents of the file at the given path to the given writer, as seen in the given layer (counting
+// from the oldest layer) or in the final image when the layer is negative. Files removed by a later layer (whiteouts)
+// and files shadowed by a later layer are resolved just as the container runtime would.
+func ReadFile(imageID, filePath string, layerIndex int, writer io.Writer) error {
+	ctx := context.Background()
+	dockerClient, err := newDockerClient()
+	if err != nil {
+		return fmt.Errorf("could not connect to the Docker daemon: %v", err)
+	}
+	if _, _, err = dockerClient.ImageInspectWithRaw(ctx, imageID); err != nil {
+		return fmt.Errorf("could not find image '%s' (pull it first): %v", imageID, err)
+	}
+	return readSavedFile(func() (io.ReadCloser, error) {
+		readCloser, err := dockerClient.ImageSave(ctx, []string{imageID})
+		if err != nil {
+			return nil, fmt.Errorf("could not save image '%s': %v", imageID, err)
+		}
+		return readCloser, nil
+	}, filePath, layerIndex, writer)
+}
+
+// readSavedFile writes the contents of the file at the given path (see ReadFile) from the image saved by the given
+// function. The file is streamed rather than held in memory: the saved image is read once to find the layer holding
+// the file (the manifest, which orders the layers, may come after the layers), and once more to copy the file from it.
+func readSavedFile(save func() (io.ReadCloser, error), filePath string, layerIndex int, writer io.Writer) error {
+	target := cleanTarPath(filePath)
+
+	var manifest ImageManifest
+	layerFiles := make(map[string]layerFile)
+	layerLinks := make(map[string]string)
+	err := readSaved(save, func(header *tar.Header, reader *tar.Reader) error {
+		name := header.Name
+		switch {
+		case strings.HasSuffix(name, "layer.tar") && header.Typeflag == tar.TypeSymlink:
+			// some layer tars are relative symlinks to other layer tars
+			layerLinks[name] = path.Join(path.Dir(name), header.Linkname)
+		case strings.HasSuffix(name, "layer.tar") && header.Typeflag == tar.TypeReg:
+			file, err := findLayerFile(tar.NewReader(reader), target)
+			if err != nil {
+				return fmt.Errorf("could not read layer %s: %v", name, err)
+			}
+			file.layer = name
+			layerFiles[name] = file
+		case name == "manifest.json":
+			var err error
+			manifest, err = NewImageManifest(reader, header)
+			if err != nil {
+				return fmt.Errorf("could not read image manifest: %v", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for name, linkName := range layerLinks {
+		layerFiles[name] = layerFiles[linkName]
+	}
+
+	if layerIndex < 0 {
+		layerIndex = len(manifest.LayerTarPaths) - 1
+	}
+	if layerIndex >= len(manifest.LayerTarPaths) {
+		return fmt.Errorf("layer %d does not exist (the image has %d layers)", layerIndex, len(manifest.LayerTarPaths))
+	}
+
+	// stack the layers up to the requested layer
+	var current *layerFile
+	for idx := 0; idx <= layerIndex; idx++ {
+		file, ok := layerFiles[manifest.LayerTarPaths[idx]]
+		if !ok {
+			return fmt.Errorf("could not find layer %s in the image", manifest.LayerTarPaths[idx])
+		}
+		if file.removed {
+			current = nil
+		}
+		if file.header != nil {
+			current = &file
+		}
+	}
+
+	if current == nil {
+		return fmt.Errorf("%s does not exist in layer %d", "/"+target, layerIndex)
+	}
+	switch current.header.Typeflag {
+	case tar.TypeDir:
+		return fmt.Errorf("%s is a directory", "/"+target)
+	case tar.TypeSymlink:
+		return fmt.Errorf("%s is a symbolic link to %s", "/"+target, current.header.Linkname)
+	case tar.TypeLink:
+		return fmt.Errorf("%s is a hard link to /%s", "/"+target, cleanTarPath(current.header.Linkname))
+	}
+
+	err = readSaved(save, func(header *tar.Header, reader *tar.Reader) error {
+		if header.Name != current.layer {
+			return nil
+		}
+		if err := copyLayerFile(tar.NewReader(reader), target, current.entry, writer); err != nil {
+			return fmt.Errorf("could not read layer %s: %v", header.Name, err)
+		}
+		return errFileCopied
+	})
+	if err == errFileCopied {
+		return nil
+	}
+	if err == nil {
+		err = fmt.Errorf("could not find layer %s in the image", current.layer)
+	}
+	return err
+}
+
+// readSaved passes every entry of the image saved by the given function to the given function, which may stop the
+// reading by returning an error.
+func readSaved(save func() (io.ReadCloser, error), each func(header *tar.Header, reader *tar.Reader) error) error {
+	readCloser, err := save()
+	if err != nil {
+		return err
+	}
+	defer readCloser.Close()
+
+	tarReader := tar.NewReader(readCloser)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := each(header, tarReader); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package image
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+// savedImage returns a function that saves an image holding the given layer tars (oldest first), as "docker save"
+// does, the manifest coming after the layers. The returned counter is the number of times the image was saved.
+func savedImage(t *testing.T, layers ...[]byte) (func() (io.ReadCloser, error), *int) {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer := tar.NewWriter(&buffer)
+	var manifest ImageManifest
+	for idx, layer := range layers {
+		name := fmt.Sprintf("layer%d/layer.tar", idx)
+		manifest.LayerTarPaths = append(manifest.LayerTarPaths, name)
+		if err := writer.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(layer))}); err != nil {
+			t.Fatal(err)
+		}
+		writer.Write(layer)
+	}
+	encoded, err := json.Marshal([]ImageManifest{manifest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteHeader(&tar.Header{Name: "manifest.json", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(encoded))}); err != nil {
+		t.Fatal(err)
+	}
+	writer.Write(encoded)
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	saves := 0
+	return func() (io.ReadCloser, error) {
+		saves++
+		return ioutil.NopCloser(bytes.NewReader(buffer.Bytes())), nil
+	}, &saves
+}
+
+func TestReadSavedFile(t *testing.T) {
+	quietly(t)
+	save, saves := savedImage(t,
+		layerTar(t, "etc/hosts", "etc/motd", "opt/app/run.sh"),
+		layerTar(t, "./etc/hosts", "etc/.wh.motd"),
+		layerTar(t, "opt/app/.wh..wh..opq", "opt/app/main"),
+	)
+
+	cases := []struct {
+		path     string
+		layer    int
+		expected string
+		err      string
+	}{
+		{path: "/etc/hosts", layer: -1, expected: "contents of ./etc/hosts"},
+		{path: "/etc/hosts", layer: 0, expected: "contents of etc/hosts"},
+		{path: "etc/motd", layer: 0, expected: "contents of etc/motd"},
+		{path: "/etc/motd", layer: -1, err: "does not exist"},
+		{path: "/opt/app/run.sh", layer: 1, expected: "contents of opt/app/run.sh"},
+		{path: "/opt/app/run.sh", layer: -1, err: "does not exist"},
+		{path: "/opt/app/main", layer: -1, expected: "contents of opt/app/main"},
+		{path: "/etc/hosts", layer: 3, err: "does not exist"},
+	}
+	for _, test := range cases {
+		var output bytes.Buffer
+		err := readSavedFile(save, test.path, test.layer, &output)
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s (layer %d): expected an error containing %q, got %v", test.path, test.layer, test.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s (layer %d): unexpected error: %v", test.path, test.layer, err)
+			continue
+		}
+		if output.String() != test.expected {
+			t.Errorf("%s (layer %d): expected %q, got %q", test.path, test.layer, test.expected, output.String())
+		}
+	}
+
+	// the image is saved once to find the file and once more to copy it, instead of holding it in memory
+	*saves = 0
+	if err := readSavedFile(save, "/etc/hosts", -1, ioutil.Discard); err != nil || *saves != 2 {
+		t.Errorf("Expected the image to be saved twice, got %d (%v)", *saves, err)
+	}
+}
+
+func TestReadSavedFileRepeatedEntry(t *testing.T) {
+	quietly(t)
+	// a layer tar may hold a file several times, the last entry wins
+	var buffer bytes.Buffer
+	writer := tar.NewWriter(&buffer)
+	for _, contents := range []string{"first", "second"} {
+		writer.WriteHeader(&tar.Header{Name: "etc/hosts", Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(contents))})
+		writer.Write([]byte(contents))
+	}
+	writer.Close()
+	save, _ := savedImage(t, buffer.Bytes())
+
+	var output bytes.Buffer
+	if err := readSavedFile(save, "/etc/hosts", -1, &output); err != nil || output.String() != "second" {
+		t.Errorf("Expected the last entry, got %q (%v)", output.String(), err)
+	}
+}
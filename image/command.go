@@ -0,0 +1,218 @@
+package image
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/wagoodman/dive/utils"
+)
+
+// shellPrefix is how the classic builder (and BuildKit, after RUN) records the shell running a command.
+const shellPrefix = "/bin/sh -c"
+
+// buildArgsPattern matches the build arguments prepended to the commands of RUN instructions (e.g. "|2 A=1 B=2").
+var buildArgsPattern = regexp.MustCompile(`^\|(\d+)\s+`)
+
+// dockerfileInstructions are the instructions highlighted at the start of a command.
+var dockerfileInstructions = map[string]bool{
+	"FROM": true, "RUN": true, "CMD": true, "LABEL": true, "MAINTAINER": true, "EXPOSE": true, "ENV": true,
+	"ADD": true, "COPY": true, "ENTRYPOINT": true, "VOLUME": true, "USER": true, "WORKDIR": true, "ARG": true,
+	"ONBUILD": true, "STOPSIGNAL": true, "HEALTHCHECK": true, "SHELL": true,
+}
+
+// stripBuildArgs removes the build arguments recorded before a command ("|2 A=1 B=2 /bin/sh -c ...").
+func stripBuildArgs(command string) string {
+	match := buildArgsPattern.FindStringSubmatch(command)
+	if match == nil {
+		return command
+	}
+	count, _ := strconv.Atoi(match[1])
+	rest := command[len(match[0]):]
+	for idx := 0; idx < count; idx++ {
+		fields := strings.SplitN(rest, " ", 2)
+		if !strings.Contains(fields[0], "=") || len(fields) < 2 {
+			return command
+		}
+		rest = strings.TrimSpace(fields[1])
+	}
+	return rest
+}
+
+// NormalizeCommand returns the Dockerfile instruction a layer was created by, given its raw history entry (created_by):
+// the shell of the classic builder ("/bin/sh -c", as "RUN"), its "#(nop)" marker for instructions that ran no command,
+// the recorded build arguments and the BuildKit suffix are removed, such that the commands of both builders read like
+// the Dockerfile (e.g. "RUN apt-get update", "ENV A=b" or "COPY file:... in /app").
+func NormalizeCommand(createdBy string) string {
+	command := strings.TrimSpace(createdBy)
+	command = strings.TrimSpace(strings.TrimSuffix(command, "# buildkit"))
+
+	run := false
+	if strings.HasPrefix(command, "RUN ") {
+		run = true
+		command = strings.TrimSpace(strings.TrimPrefix(command, "RUN "))
+	}
+	command = stripBuildArgs(command)
+	if command == shellPrefix || strings.HasPrefix(command, shellPrefix+" ") {
+		command = strings.TrimSpace(strings.TrimPrefix(command, shellPrefix))
+		if strings.HasPrefix(command, "#(nop)") {
+			return strings.TrimSpace(strings.TrimPrefix(command, "#(nop)"))
+		}
+		run = true
+	}
+	if run {
+		return strings.TrimSpace("RUN " + command)
+	}
+	return command
+}
+
+// splitClauses splits a shell command at the (unquoted) command separators (&&, || and ;), each later clause starting
+// with the && or || that precedes it (a ; ends the clause before it).
+func splitClauses(command string) []string {
+	var clauses []string
+	var quote rune
+	start := 0
+	runes := []rune(command)
+	for idx := 0; idx < len(runes); idx++ {
+		current := runes[idx]
+		switch {
+		case quote != 0:
+			if current == '\\' && quote == '"' {
+				idx++
+			} else if current == quote {
+				quote = 0
+			}
+		case current == '\\':
+			idx++
+		case current == '\'' || current == '"':
+			quote = current
+		case current == ';':
+			// a semicolon ends its clause (rather than starting the next one)
+			if clause := strings.TrimSpace(string(runes[start : idx+1])); clause != ";" {
+				clauses = append(clauses, clause)
+			}
+			start = idx + 1
+		case (current == '&' || current == '|') && idx+1 < len(runes) && runes[idx+1] == current:
+			if clause := strings.TrimSpace(string(runes[start:idx])); clause != "" {
+				clauses = append(clauses, clause)
+			}
+			start = idx
+			idx++
+		}
+	}
+	if clause := strings.TrimSpace(string(runes[start:])); clause != "" {
+		clauses = append(clauses, clause)
+	}
+	return clauses
+}
+
+// wrapLine breaks a line longer than the given width at spaces, continuing it on indented lines.
+func wrapLine(line, indent string, width int) []string {
+	var lines []string
+	for utils.DisplayWidth(line) > width && width > len(indent)+2 {
+		cut := strings.LastIndex(line[:utils.ColumnIndex(line, width-2)], " ")
+		if cut <= len(indent) {
+			break
+		}
+		lines = append(lines, line[:cut]+" \\")
+		line = indent + strings.TrimSpace(line[cut:])
+	}
+	return append(lines, line)
+}
+
+// FormatCommand pretty-prints the given (normalized) command to lines of at most the given width (where possible):
+// each clause of a RUN command (separated by &&, || or ;) starts a new line, and long lines are wrapped at spaces,
+// continued as in a Dockerfile (with a trailing backslash).
+func FormatCommand(command string, width int) []string {
+	const indent = "    "
+	clauses := []string{command}
+	if strings.HasPrefix(command, "RUN ") {
+		clauses = splitClauses(command)
+	}
+	var lines []string
+	for idx, clause := range clauses {
+		if idx > 0 {
+			clause = indent + clause
+		}
+		wrapped := wrapLine(clause, indent+indent, width)
+		if idx < len(clauses)-1 {
+			wrapped[len(wrapped)-1] += " \\"
+		}
+		lines = append(lines, wrapped...)
+	}
+	return lines
+}
+
+// HighlightCommand colors a line of a (formatted) command like shell syntax: the instruction, command separators and
+// pipes, options, variables and quoted strings are highlighted.
+func HighlightCommand(line string) string {
+	instruction := color.New(color.FgBlue, color.Bold).SprintFunc()
+	operator := color.New(color.FgMagenta, color.Bold).SprintFunc()
+	option := color.New(color.FgCyan).SprintFunc()
+	variable := color.New(color.FgYellow).SprintFunc()
+	quoted := color.New(color.FgGreen).SprintFunc()
+
+	var result strings.Builder
+	runes := []rune(line)
+	first := true
+	for idx := 0; idx < len(runes); {
+		current := runes[idx]
+		end := idx + 1
+		switch {
+		case current == ' ':
+			for end < len(runes) && runes[end] == ' ' {
+				end++
+			}
+			result.WriteString(string(runes[idx:end]))
+		case current == '\'' || current == '"':
+			for end < len(runes) && runes[end] != current {
+				if runes[end] == '\\' && current == '"' {
+					end++
+				}
+				end++
+			}
+			if end < len(runes) {
+				end++
+			}
+			result.WriteString(quoted(string(runes[idx:end])))
+		case current == '&' || current == '|' || current == ';' || current == '>' || current == '<':
+			for end < len(runes) && strings.ContainsRune("&|;><", runes[end]) {
+				end++
+			}
+			result.WriteString(operator(string(runes[idx:end])))
+		case current == '$':
+			if end < len(runes) && runes[end] == '{' {
+				for end < len(runes) && runes[end] != '}' {
+					end++
+				}
+				if end < len(runes) {
+					end++
+				}
+			} else {
+				for end < len(runes) && (runes[end] == '_' || runes[end] >= 'a' && runes[end] <= 'z' || runes[end] >= 'A' && runes[end] <= 'Z' || runes[end] >= '0' && runes[end] <= '9') {
+					end++
+				}
+			}
+			result.WriteString(variable(string(runes[idx:end])))
+		default:
+			for end < len(runes) && !strings.ContainsRune(" '\"&|;><$", runes[end]) {
+				end++
+			}
+			word := string(runes[idx:end])
+			switch {
+			case first && dockerfileInstructions[word]:
+				result.WriteString(instruction(word))
+			case strings.HasPrefix(word, "-") && len(word) > 1:
+				result.WriteString(option(word))
+			default:
+				result.WriteString(word)
+			}
+		}
+		if current != ' ' {
+			first = false
+		}
+		idx = end
+	}
+	return result.String()
+}
@@ -0,0 +1,134 @@
+package image
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/wagoodman/dive/utils"
+)
+
+func TestNormalizeCommand(t *testing.T) {
+	cases := []struct {
+		createdBy string
+		expected  string
+	}{
+		// classic builder
+		{`/bin/sh -c apt-get update && apt-get install -y curl`, `RUN apt-get update && apt-get install -y curl`},
+		{`/bin/sh -c #(nop)  ENV A=b`, `ENV A=b`},
+		{`/bin/sh -c #(nop) COPY file:0123abcd in /app `, `COPY file:0123abcd in /app`},
+		{`|2 VERSION=1.0 TARGET=prod /bin/sh -c make $TARGET`, `RUN make $TARGET`},
+		{`/bin/sh -c`, `RUN`},
+		// BuildKit
+		{`RUN /bin/sh -c apk add --no-cache git # buildkit`, `RUN apk add --no-cache git`},
+		{`RUN |1 VERSION=1.0 /bin/sh -c echo $VERSION # buildkit`, `RUN echo $VERSION`},
+		{`RUN go build ./... # buildkit`, `RUN go build ./...`},
+		{`COPY . /src # buildkit`, `COPY . /src`},
+		{`WORKDIR /src`, `WORKDIR /src`},
+		// not a build argument list, or a malformed one, is kept
+		{`|2 VERSION=1.0 /bin/sh -c make`, `|2 VERSION=1.0 /bin/sh -c make`},
+		{`|x /bin/sh -c make`, `|x /bin/sh -c make`},
+		{`/bin/shell -c make`, `/bin/shell -c make`},
+		{`  `, ``},
+	}
+	for _, test := range cases {
+		if command := NormalizeCommand(test.createdBy); command != test.expected {
+			t.Errorf("%q: expected %q, got %q", test.createdBy, test.expected, command)
+		}
+	}
+}
+
+func TestSplitClauses(t *testing.T) {
+	cases := []struct {
+		command  string
+		expected []string
+	}{
+		{`RUN apt-get update && apt-get install -y curl`, []string{`RUN apt-get update`, `&& apt-get install -y curl`}},
+		{`RUN make || true; rm -rf /tmp/*`, []string{`RUN make`, `|| true;`, `rm -rf /tmp/*`}},
+		{`RUN echo "a && b" && echo 'c; d'`, []string{`RUN echo "a && b"`, `&& echo 'c; d'`}},
+		{`RUN echo "say \"&&\"" && true`, []string{`RUN echo "say \"&&\""`, `&& true`}},
+		{`RUN echo a \&\& b`, []string{`RUN echo a \&\& b`}},
+		{`RUN cat file | grep x`, []string{`RUN cat file | grep x`}},
+		{`RUN true;`, []string{`RUN true;`}},
+		{`RUN true ; ; false`, []string{`RUN true ;`, `false`}},
+		{``, nil},
+	}
+	for _, test := range cases {
+		if clauses := splitClauses(test.command); !reflect.DeepEqual(clauses, test.expected) {
+			t.Errorf("%q: expected %q, got %q", test.command, test.expected, clauses)
+		}
+	}
+}
+
+func TestFormatCommand(t *testing.T) {
+	cases := []struct {
+		command  string
+		width    int
+		expected []string
+	}{
+		{`RUN apt-get update && apt-get install -y curl`, 80, []string{
+			`RUN apt-get update \`,
+			`    && apt-get install -y curl`,
+		}},
+		// long clauses are wrapped at spaces, with a deeper indent
+		{`RUN apt-get install -y curl git make gcc`, 24, []string{
+			`RUN apt-get install \`,
+			`        -y curl git \`,
+			`        make gcc`,
+		}},
+		// only RUN commands are split into clauses
+		{`ENV A="b && c"`, 80, []string{`ENV A="b && c"`}},
+		// a line without a space to break at within the width is kept whole
+		{`COPY file:0123456789abcdef0123456789abcdef in /`, 20, []string{`COPY file:0123456789abcdef0123456789abcdef in /`}},
+	}
+	for _, test := range cases {
+		lines := FormatCommand(test.command, test.width)
+		if !reflect.DeepEqual(lines, test.expected) {
+			t.Errorf("%q: expected\n%s\ngot\n%s", test.command, strings.Join(test.expected, "\n"), strings.Join(lines, "\n"))
+		}
+	}
+}
+
+func TestWrapLineWideCharacters(t *testing.T) {
+	// wide characters take two columns each, so lines are wrapped by their display width
+	lines := wrapLine("RUN echo 日本語 日本語 日本語", "    ", 18)
+	for _, line := range lines {
+		if width := utils.DisplayWidth(line); width > 18 {
+			t.Errorf("Expected lines of at most 18 columns, got %q (%d columns)", line, width)
+		}
+	}
+	if len(lines) < 2 {
+		t.Errorf("Expected the line to be wrapped, got %q", lines)
+	}
+}
+
+func TestHighlightCommand(t *testing.T) {
+	noColor := color.NoColor
+	defer func() { color.NoColor = noColor }()
+
+	line := `RUN apt-get install -y "$PKG" && echo ${HOME} > /tmp/log`
+	color.NoColor = true
+	if highlighted := HighlightCommand(line); highlighted != line {
+		t.Errorf("Expected the line to be unchanged without colors, got %q", highlighted)
+	}
+
+	color.NoColor = false
+	highlighted := HighlightCommand(line)
+	for _, part := range []string{
+		color.New(color.FgBlue, color.Bold).Sprint("RUN"),
+		color.New(color.FgCyan).Sprint("-y"),
+		color.New(color.FgGreen).Sprint(`"$PKG"`),
+		color.New(color.FgMagenta, color.Bold).Sprint("&&"),
+		color.New(color.FgYellow).Sprint("${HOME}"),
+		color.New(color.FgMagenta, color.Bold).Sprint(">"),
+	} {
+		if !strings.Contains(highlighted, part) {
+			t.Errorf("Expected %q to be highlighted in %q", part, highlighted)
+		}
+	}
+	// an instruction is only highlighted at the start of the line
+	if strings.Contains(HighlightCommand("echo RUN"), color.New(color.FgBlue, color.Bold).Sprint("RUN")) {
+		t.Errorf("Expected an instruction within a command not to be highlighted")
+	}
+}
@@ -0,0 +1,37 @@
+package image
+
+import (
+	"github.com/wagoodman/dive/filetree"
+)
+
+// Comparison holds the final (fully stacked) filesystems of two images, along with their combined tree, in which every
+// node is marked with how it changed from the lower image to the upper image (see filetree.CompareTrees).
+type Comparison struct {
+	Lower    *filetree.FileTree
+	Upper    *filetree.FileTree
+	Combined *filetree.FileTree
+}
+
+// Compare analyzes both images and compares their final filesystems (rather than their layers), which tells what
+// changed between two versions of an image (e.g. :v1 and :v2). The trees are named by the given references.
+func Compare(lowerImage, upperImage string) (*Comparison, error) {
+	var stacked [2]*filetree.FileTree
+	for idx, reference := range []string{lowerImage, upperImage} {
+		_, trees, _, _, err := LoadImage(reference)
+		if err != nil {
+			return nil, err
+		}
+		stacked[idx] = filetree.StackRange(trees, 0, len(trees)-1)
+		stacked[idx].Name = reference
+	}
+	return CompareTrees(stacked[0], stacked[1])
+}
+
+// CompareTrees compares the given final filesystems (of an image, or of a directory, see DirectoryTree).
+func CompareTrees(lower, upper *filetree.FileTree) (*Comparison, error) {
+	combined, err := filetree.CompareTrees(lower, upper)
+	if err != nil {
+		return nil, err
+	}
+	return &Comparison{Lower: lower, Upper: upper, Combined: combined}, nil
+}
@@ -0,0 +1,239 @@
+package image
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/wagoodman/dive/filetree"
+)
+
+// leakPatterns are context paths that are rarely meant to be copied into an image.
+var leakPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(^|/)\.git(/|$)`),
+	regexp.MustCompile(`(^|/)\.hg(/|$)`),
+	regexp.MustCompile(`(^|/)\.svn(/|$)`),
+	regexp.MustCompile(`(^|/)node_modules(/|$)`),
+	regexp.MustCompile(`(^|/)__pycache__(/|$)`),
+	regexp.MustCompile(`(^|/)\.env$`),
+	regexp.MustCompile(`(^|/)\.DS_Store$`),
+	regexp.MustCompile(`(^|/)\.dockerignore$`),
+	regexp.MustCompile(`(^|/)Dockerfile$`),
+}
+
+var copyCommandPattern = regexp.MustCompile(`(^|\s)(COPY|ADD)\s`)
+
+// CopyFinding is a single file in a COPY/ADD layer that was not expected from the declared sources.
+type CopyFinding struct {
+	Instruction DockerfileInstruction
+	Path        string
+	Size        int64
+	Reason      string
+}
+
+// copySource is a single file from the build context and where a COPY/ADD instruction places it in the image. The
+// contextPath of a here-document is empty.
+type copySource struct {
+	contextPath string
+	imagePath   string
+}
+
+// CheckCopySources verifies that the contents of each COPY/ADD layer of the final build stage match the sources
+// declared in the Dockerfile, reporting files that do not come from the build context and context files that are
+// commonly leaked into images (e.g. .git or node_modules directories). The given layers must be in the order returned
+// by InitializeData.
+func CheckCopySources(layers []*Layer, contextDir string, dockerfile io.Reader) ([]CopyFinding, error) {
+	instructions, err := ParseDockerfile(dockerfile)
+	if err != nil {
+		return nil, err
+	}
+	ignore, err := ReadDockerIgnore(contextDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var copies []DockerfileInstruction
+	for _, instruction := range FinalStage(instructions) {
+		if instruction.Command == "COPY" || instruction.Command == "ADD" {
+			copies = append(copies, instruction)
+		}
+	}
+
+	// layers are stored newest first, the last COPY/ADD layers belong to the last COPY/ADD instructions
+	var copyLayers []*Layer
+	for _, layer := range layers {
+		if copyCommandPattern.MatchString(layer.History.CreatedBy) {
+			copyLayers = append([]*Layer{layer}, copyLayers...)
+		}
+	}
+	if len(copyLayers) < len(copies) {
+		return nil, fmt.Errorf("found %d COPY/ADD instructions but only %d COPY/ADD layers", len(copies), len(copyLayers))
+	}
+	copyLayers = copyLayers[len(copyLayers)-len(copies):]
+
+	workdir := "/"
+	var findings []CopyFinding
+	for idx, instruction := range copies {
+		workdir = stageWorkdir(instructions, instruction, workdir)
+		if _, ok := instruction.Flag("from"); ok {
+			// sources from another stage or image cannot be verified against the build context
+			continue
+		}
+
+		sources, archive, err := resolveCopySources(instruction, contextDir, workdir, ignore)
+		if err != nil {
+			return nil, err
+		}
+		if archive {
+			// ADD extracts local archives, the contents cannot be derived from the context listing
+			continue
+		}
+
+		expected := make(map[string]string)
+		for _, source := range sources {
+			expected[source.imagePath] = source.contextPath
+		}
+
+		copyLayers[idx].Tree.VisitDepthParentFirst(func(node *filetree.FileNode) error {
+			if !node.IsLeaf() || node.IsWhiteout() || node.Data.FileInfo.TarHeader.FileInfo().IsDir() {
+				return nil
+			}
+			finding := CopyFinding{
+				Instruction: instruction,
+				Path:        node.Path(),
+				Size:        node.Data.FileInfo.TarHeader.FileInfo().Size(),
+			}
+			contextPath, ok := expected[node.Path()]
+			if !ok {
+				finding.Reason = "not found in the declared sources"
+				findings = append(findings, finding)
+				return nil
+			}
+			for _, pattern := range leakPatterns {
+				if pattern.MatchString(contextPath) {
+					finding.Reason = fmt.Sprintf("copied from %s (consider adding it to .dockerignore)", contextPath)
+					findings = append(findings, finding)
+					break
+				}
+			}
+			return nil
+		}, nil)
+	}
+
+	return findings, nil
+}
+
+// stageWorkdir determines the WORKDIR that is in effect for the given instruction.
+func stageWorkdir(instructions []DockerfileInstruction, target DockerfileInstruction, workdir string) string {
+	for _, instruction := range FinalStage(instructions) {
+		if instruction.Line >= target.Line {
+			break
+		}
+		if instruction.Command == "WORKDIR" && len(instruction.Args) > 0 {
+			dir := instruction.Args[0]
+			if !path.IsAbs(dir) {
+				dir = path.Join(workdir, dir)
+			}
+			workdir = path.Clean(dir)
+		}
+	}
+	return workdir
+}
+
+// resolveCopySources expands the sources of a COPY/ADD instruction into the individual context files and their
+// destination paths in the image.
+func resolveCopySources(instruction DockerfileInstruction, contextDir, workdir string, ignore *DockerIgnore) ([]copySource, bool, error) {
+	var sources []copySource
+	if len(instruction.Args) < 2 {
+		return sources, false, nil
+	}
+
+	args := instruction.Args[:len(instruction.Args)-1]
+	dest := instruction.Args[len(instruction.Args)-1]
+	if !path.IsAbs(dest) {
+		dest = path.Join(workdir, dest)
+	}
+	// a destination is a directory when it says so ("dir/", ".", "..") or when several files are copied to it
+	last := instruction.Args[len(instruction.Args)-1]
+	destIsDir := strings.HasSuffix(last, "/") || path.Base(last) == "." || path.Base(last) == ".." || len(args) > 1
+
+	for _, arg := range args {
+		if name, ok := heredocName(arg); ok {
+			// a here-document is written by the instruction itself, named after its delimiter in a directory
+			target := dest
+			if destIsDir {
+				target = path.Join(dest, name)
+			}
+			sources = append(sources, copySource{imagePath: target})
+			continue
+		}
+		if strings.Contains(arg, "://") {
+			// remote ADD sources are not part of the build context
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(contextDir, filepath.FromSlash(arg)))
+		if err != nil {
+			return nil, false, err
+		}
+		if len(matches) > 1 {
+			destIsDir = true
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, false, err
+			}
+			if !info.IsDir() {
+				if instruction.Command == "ADD" && isArchive(match) {
+					return nil, true, nil
+				}
+				relPath, _ := filepath.Rel(contextDir, match)
+				if ignore.Excludes(relPath) {
+					continue
+				}
+				target := dest
+				if destIsDir {
+					target = path.Join(dest, filepath.Base(match))
+				}
+				sources = append(sources, copySource{contextPath: filepath.ToSlash(relPath), imagePath: target})
+				continue
+			}
+
+			// the contents of a source directory are copied, not the directory itself
+			err = filepath.Walk(match, func(walkPath string, walkInfo os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				relPath, _ := filepath.Rel(contextDir, walkPath)
+				if walkInfo.IsDir() || ignore.Excludes(relPath) {
+					return nil
+				}
+				inner, _ := filepath.Rel(match, walkPath)
+				sources = append(sources, copySource{
+					contextPath: filepath.ToSlash(relPath),
+					imagePath:   path.Join(dest, filepath.ToSlash(inner)),
+				})
+				return nil
+			})
+			if err != nil {
+				return nil, false, err
+			}
+		}
+	}
+	return sources, false, nil
+}
+
+// isArchive indicates if ADD would extract the given local file instead of copying it.
+func isArchive(name string) bool {
+	for _, suffix := range []string{".tar", ".tar.gz", ".tgz", ".tar.bz2", ".tbz2", ".tar.xz", ".txz"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package image
+
+import (
+	"archive/tar"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/wagoodman/dive/filetree"
+)
+
+// buildContext writes the given files (by slash-delimited path) to a new temporary build context directory.
+func buildContext(t *testing.T, files ...string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "dive-context")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := ioutil.WriteFile(path, []byte(file), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestResolveCopySources(t *testing.T) {
+	contextDir := buildContext(t, "app/main.go", "app/lib/util.go", "app/debug.log", "README.md", "config.yaml", "dist.tar.gz")
+	ignore := &DockerIgnore{}
+	ignore.Add("**/*.log")
+
+	cases := []struct {
+		instruction string
+		workdir     string
+		// expected are the context paths and the image paths they are copied to ("" for a here-document)
+		expected [][2]string
+		archive  bool
+	}{
+		{"COPY app /srv/", "/", [][2]string{{"app/lib/util.go", "/srv/lib/util.go"}, {"app/main.go", "/srv/main.go"}}, false},
+		{"COPY config.yaml /etc/app.yaml", "/", [][2]string{{"config.yaml", "/etc/app.yaml"}}, false},
+		{"COPY config.yaml conf/", "/opt", [][2]string{{"config.yaml", "/opt/conf/config.yaml"}}, false},
+		{"COPY README.md .", "/docs", [][2]string{{"README.md", "/docs/README.md"}}, false},
+		{"COPY *.yaml README.md /etc/app", "/", [][2]string{{"config.yaml", "/etc/app/config.yaml"}, {"README.md", "/etc/app/README.md"}}, false},
+		{"COPY app/*.log /logs/", "/", nil, false},
+		{"COPY missing.txt /", "/", nil, false},
+		{"ADD dist.tar.gz /opt/", "/", nil, true},
+		{"ADD https://example.com/app.tar.gz /opt/", "/", nil, false},
+		{"COPY <<EOF /etc/motd", "/", [][2]string{{"", "/etc/motd"}}, false},
+		{"COPY <<a.txt <<b.txt data", "/srv", [][2]string{{"", "/srv/data/a.txt"}, {"", "/srv/data/b.txt"}}, false},
+		{"COPY onlyone", "/", nil, false},
+	}
+	for _, test := range cases {
+		instructions, _ := ParseDockerfile(strings.NewReader(test.instruction))
+		sources, archive, err := resolveCopySources(instructions[0], contextDir, test.workdir, ignore)
+		if err != nil {
+			t.Errorf("%s: could not resolve: %v", test.instruction, err)
+			continue
+		}
+		var resolved [][2]string
+		for _, source := range sources {
+			resolved = append(resolved, [2]string{source.contextPath, source.imagePath})
+		}
+		if !reflect.DeepEqual(resolved, test.expected) || archive != test.archive {
+			t.Errorf("%s: expected %v (archive=%v), got %v (archive=%v)", test.instruction, test.expected, test.archive, resolved, archive)
+		}
+	}
+}
+
+func TestCheckCopySources(t *testing.T) {
+	contextDir := buildContext(t, "main.go", ".git/config")
+	dockerfile := `FROM golang AS build
+RUN go build -o /out/app
+FROM alpine
+WORKDIR /app
+COPY --from=build /out/app /usr/bin/app
+COPY . .
+`
+	layerOf := func(createdBy string, files ...string) *Layer {
+		tree := filetree.NewFileTree()
+		for _, file := range files {
+			tree.AddPath(file, filetree.FileInfo{TypeFlag: tar.TypeReg, TarHeader: tar.Header{Typeflag: tar.TypeReg, Mode: 0644, Size: 10}})
+		}
+		return &Layer{History: ImageHistoryEntry{CreatedBy: createdBy}, Tree: tree}
+	}
+	// newest first, as returned by InitializeData
+	layers := []*Layer{
+		layerOf("COPY dir:0123 in .", "/app/main.go", "/app/.git/config", "/app/stray.txt"),
+		layerOf("COPY file:4567 in /usr/bin/app", "/usr/bin/app"),
+		layerOf("WORKDIR /app"),
+		layerOf("/bin/sh -c #(nop) ADD file:89ab in / ", "/bin/sh"),
+	}
+
+	findings, err := CheckCopySources(layers, contextDir, strings.NewReader(dockerfile))
+	if err != nil {
+		t.Fatalf("could not check: %v", err)
+	}
+	reasons := make(map[string]string)
+	for _, finding := range findings {
+		reasons[finding.Path] = finding.Reason
+		if finding.Instruction.Line != 6 || finding.Size != 10 {
+			t.Errorf("Expected %s to be found in the COPY of line 6, got %+v", finding.Path, finding)
+		}
+	}
+	// the files from the build stage are not verified against the build context
+	if len(reasons) != 2 || !strings.Contains(reasons["/app/.git/config"], ".dockerignore") || !strings.Contains(reasons["/app/stray.txt"], "not found") {
+		t.Errorf("Expected the leaked .git and the stray file, got %v", reasons)
+	}
+
+	if _, err := CheckCopySources(layers[:1], contextDir, strings.NewReader(dockerfile)); err == nil {
+		t.Errorf("Expected an error for fewer COPY layers than instructions")
+	}
+}
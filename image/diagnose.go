@@ -0,0 +1,157 @@
+package image
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// diagnosticHeaderCount is the number of most recent tar headers kept for each layer.
+const diagnosticHeaderCount = 50
+
+// secretPattern matches assignments of values that are likely to be secret (e.g. in ENV or RUN instructions).
+var secretPattern = regexp.MustCompile(`(?i)((?:password|passwd|secret|token|api[_-]?key|access[_-]?key|credentials?)[a-z0-9_]*\s*[=:]\s*)("[^"]*"|'[^']*'|[^\s"',\]]+)`)
+
+// diagnosticHeader is the redacted form of a tar header captured for a diagnostics bundle.
+type diagnosticHeader struct {
+	Name       string    `json:"name"`
+	Linkname   string    `json:"linkname,omitempty"`
+	Typeflag   string    `json:"typeflag"`
+	Size       int64     `json:"size"`
+	Mode       string    `json:"mode"`
+	UID        int       `json:"uid"`
+	GID        int       `json:"gid"`
+	ModTime    time.Time `json:"mtime"`
+	Format     string    `json:"format"`
+	PAXRecords []string  `json:"paxRecords,omitempty"`
+}
+
+// diagnosticRecorder captures what was read of an image, so that a failure to parse it can be reported in detail.
+type diagnosticRecorder struct {
+	lock        sync.Mutex
+	environment map[string]string
+	headers     map[string][]diagnosticHeader
+	files       map[string][]byte
+}
+
+// diagnostics is the active recorder (nil when diagnostics are not enabled).
+var diagnostics *diagnosticRecorder
+
+// EnableDiagnostics captures the tar headers, manifest and config read while loading an image. When the image cannot be
+// loaded, a redacted bundle of what was captured (along with the given environment details) is written to a zip file
+// that can be attached to a bug report.
+func EnableDiagnostics(environment map[string]string) {
+	diagnostics = &diagnosticRecorder{
+		environment: environment,
+		headers:     make(map[string][]diagnosticHeader),
+		files:       make(map[string][]byte),
+	}
+}
+
+// recordHeader keeps the given header of the given layer (only the most recent headers of each layer are kept).
+func (recorder *diagnosticRecorder) recordHeader(layer string, header *tar.Header) {
+	if recorder == nil {
+		return
+	}
+	var paxKeys []string
+	for key := range header.PAXRecords {
+		// the values may hold arbitrary (user) data, only the keys are needed to reproduce format edge cases
+		paxKeys = append(paxKeys, key)
+	}
+	sort.Strings(paxKeys)
+
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	headers := append(recorder.headers[layer], diagnosticHeader{
+		Name:       header.Name,
+		Linkname:   header.Linkname,
+		Typeflag:   fmt.Sprintf("%q", header.Typeflag),
+		Size:       header.Size,
+		Mode:       fmt.Sprintf("%o", header.Mode),
+		UID:        header.Uid,
+		GID:        header.Gid,
+		ModTime:    header.ModTime,
+		Format:     header.Format.String(),
+		PAXRecords: paxKeys,
+	})
+	if len(headers) > diagnosticHeaderCount {
+		headers = headers[len(headers)-diagnosticHeaderCount:]
+	}
+	recorder.headers[layer] = headers
+}
+
+// recordFile keeps the contents of an image metadata file (e.g. the manifest or config), redacting likely secrets.
+func (recorder *diagnosticRecorder) recordFile(name string, contents []byte) {
+	if recorder == nil {
+		return
+	}
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+	recorder.files[name] = secretPattern.ReplaceAll(contents, []byte("${1}<redacted>"))
+}
+
+// write bundles everything captured along with the given failure into a zip file in the working directory, returning
+// the path of the file.
+func (recorder *diagnosticRecorder) write(imageID string, failure error) (string, error) {
+	recorder.lock.Lock()
+	defer recorder.lock.Unlock()
+
+	path := fmt.Sprintf("dive-diagnostics-%s.zip", time.Now().Format("20060102-150405"))
+	file, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	archive := zip.NewWriter(file)
+
+	environment := map[string]string{
+		"image": imageID,
+		"error": failure.Error(),
+		"go":    runtime.Version(),
+		"os":    runtime.GOOS,
+		"arch":  runtime.GOARCH,
+		"api":   dockerVersion,
+	}
+	if host := os.Getenv("DOCKER_HOST"); host != "" {
+		// only the kind of endpoint is relevant, not where it is
+		environment["dockerHost"] = strings.SplitN(host, "://", 2)[0] + "://<redacted>"
+	}
+	for key, value := range recorder.environment {
+		environment[key] = value
+	}
+
+	entries := map[string]interface{}{"environment.json": environment}
+	for layer, headers := range recorder.headers {
+		entries["headers/"+strings.Replace(strings.Trim(layer, "/"), "/", "_", -1)+".json"] = headers
+	}
+	for name, value := range entries {
+		writer, err := archive.Create(name)
+		if err != nil {
+			return "", err
+		}
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(value); err != nil {
+			return "", err
+		}
+	}
+	for name, contents := range recorder.files {
+		writer, err := archive.Create("image/" + name)
+		if err != nil {
+			return "", err
+		}
+		if _, err := writer.Write(contents); err != nil {
+			return "", err
+		}
+	}
+
+	return path, archive.Close()
+}
@@ -0,0 +1,149 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/context"
+)
+
+// sshDialer returns a dialer that reaches the daemon of the given ssh endpoint (e.g. "ssh://user@host:2222") by running
+// "docker system dial-stdio" on the host over ssh, as the docker CLI does. Authentication is left to ssh itself (keys,
+// an agent and ~/.ssh/config all apply).
+func sshDialer(endpoint string) (func(ctx context.Context, network, addr string) (net.Conn, error), error) {
+	args, err := sshArgs(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dialCommand(exec.Command("ssh", args...))
+	}, nil
+}
+
+// sshArgs returns the arguments of ssh to run "docker system dial-stdio" on the host of the given ssh endpoint.
+func sshArgs(endpoint string) ([]string, error) {
+	parsed, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ssh endpoint %q: %v", endpoint, err)
+	}
+	if parsed.Scheme != "ssh" || parsed.Hostname() == "" || (parsed.Path != "" && parsed.Path != "/") {
+		return nil, fmt.Errorf("invalid ssh endpoint %q (expected ssh://[user@]host[:port])", endpoint)
+	}
+	var args []string
+	if parsed.User != nil {
+		args = append(args, "-l", parsed.User.Username())
+	}
+	if port := parsed.Port(); port != "" {
+		args = append(args, "-p", port)
+	}
+	return append(args, "--", parsed.Hostname(), "docker", "system", "dial-stdio"), nil
+}
+
+// commandConn is a connection to the standard input and output of a command (e.g. "docker system dial-stdio").
+type commandConn struct {
+	command *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  io.ReadCloser
+	stderr  bytes.Buffer
+	waiting sync.Once
+	closing sync.Once
+}
+
+// dialCommand starts the given command, connecting to its standard input and output.
+func dialCommand(command *exec.Cmd) (*commandConn, error) {
+	conn := &commandConn{command: command}
+	var err error
+	if conn.stdin, err = command.StdinPipe(); err != nil {
+		return nil, err
+	}
+	if conn.stdout, err = command.StdoutPipe(); err != nil {
+		return nil, err
+	}
+	command.Stderr = &conn.stderr
+	if err = command.Start(); err != nil {
+		return nil, fmt.Errorf("could not run %s: %v", strings.Join(command.Args, " "), err)
+	}
+	return conn, nil
+}
+
+// Read reads from the output of the command. When the command exits early, the error it printed is returned.
+func (conn *commandConn) Read(buffer []byte) (int, error) {
+	read, err := conn.stdout.Read(buffer)
+	if err == io.EOF {
+		// all output is read, the error output is complete once the command exited
+		conn.wait()
+		if conn.stderr.Len() > 0 {
+			err = fmt.Errorf("%s: %s", conn.command.Args[0], strings.TrimSpace(conn.stderr.String()))
+		}
+	}
+	return read, err
+}
+
+// wait waits for the command to exit.
+func (conn *commandConn) wait() {
+	conn.waiting.Do(func() { conn.command.Wait() })
+}
+
+// Write writes to the input of the command.
+func (conn *commandConn) Write(buffer []byte) (int, error) {
+	return conn.stdin.Write(buffer)
+}
+
+// CloseWrite closes the input of the command, leaving its output to be read (for a hijacked connection).
+func (conn *commandConn) CloseWrite() error {
+	return conn.stdin.Close()
+}
+
+// Close closes the connection, stopping the command.
+func (conn *commandConn) Close() error {
+	conn.closing.Do(func() {
+		conn.stdin.Close()
+		conn.stdout.Close()
+		conn.command.Process.Kill()
+		conn.wait()
+	})
+	return nil
+}
+
+// LocalAddr is a placeholder, the connection has no network address.
+func (conn *commandConn) LocalAddr() net.Addr {
+	return commandAddr{}
+}
+
+// RemoteAddr is a placeholder, the connection has no network address.
+func (conn *commandConn) RemoteAddr() net.Addr {
+	return commandAddr{}
+}
+
+// SetDeadline is not supported (the connection is closed instead), it is accepted for the sake of net.Conn.
+func (conn *commandConn) SetDeadline(time.Time) error {
+	return nil
+}
+
+// SetReadDeadline is not supported, see SetDeadline.
+func (conn *commandConn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+// SetWriteDeadline is not supported, see SetDeadline.
+func (conn *commandConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+// commandAddr is the (placeholder) address of a commandConn.
+type commandAddr struct{}
+
+func (commandAddr) Network() string {
+	return "command"
+}
+
+func (commandAddr) String() string {
+	return "command"
+}
@@ -0,0 +1,59 @@
+package image
+
+import (
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSSHArgs(t *testing.T) {
+	cases := []struct {
+		endpoint string
+		expected []string
+	}{
+		{"ssh://host", []string{"--", "host", "docker", "system", "dial-stdio"}},
+		{"ssh://me@host:2222", []string{"-l", "me", "-p", "2222", "--", "host", "docker", "system", "dial-stdio"}},
+		{"ssh://me@10.0.0.2/", []string{"-l", "me", "--", "10.0.0.2", "docker", "system", "dial-stdio"}},
+		{"ssh://host/var/run/docker.sock", nil},
+		{"ssh://", nil},
+		{"tcp://host:2376", nil},
+	}
+	for _, test := range cases {
+		args, err := sshArgs(test.endpoint)
+		if (err == nil) != (test.expected != nil) || !reflect.DeepEqual(args, test.expected) {
+			t.Errorf("%s: expected %q, got %q (%v)", test.endpoint, test.expected, args, err)
+		}
+	}
+}
+
+func TestCommandConn(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat is not available")
+	}
+	conn, err := dialCommand(exec.Command("cat"))
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	defer conn.Close()
+	if _, err := io.WriteString(conn, "GET /_ping"); err != nil {
+		t.Fatalf("could not write: %v", err)
+	}
+	conn.CloseWrite()
+	echoed, err := ioutil.ReadAll(conn)
+	if err != nil || string(echoed) != "GET /_ping" {
+		t.Errorf("Expected the input to be echoed, got %q (%v)", echoed, err)
+	}
+
+	// a command that fails reports what it printed
+	conn, err = dialCommand(exec.Command("sh", "-c", "echo 'Permission denied (publickey)' >&2"))
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	defer conn.Close()
+	if _, err := ioutil.ReadAll(conn); err == nil || !strings.Contains(err.Error(), "Permission denied") {
+		t.Errorf("Expected the error of the command, got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package image
+
+import (
+	"archive/tar"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/wagoodman/dive/filetree"
+)
+
+// DirectoryTree builds the tree of a directory (e.g. a build context) as a build would copy it into an image at the
+// given path: the paths excluded by the directory's .dockerignore are left out, and every file is owned by root (as
+// with COPY without --chown).
+func DirectoryTree(dir, imagePath string) (*filetree.FileTree, error) {
+	ignore, err := ReadDockerIgnore(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := filetree.NewFileTree()
+	tree.Name = dir
+	err = filepath.Walk(dir, func(walkPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(dir, walkPath)
+		if err != nil || relPath == "." {
+			return err
+		}
+		if ignore.Excludes(relPath) {
+			// the excluded directory may still hold re-included paths
+			if info.IsDir() && !ignore.hasExceptions() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		var linkTarget string
+		if info.Mode()&os.ModeSymlink != 0 {
+			if linkTarget, err = os.Readlink(walkPath); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, linkTarget)
+		if err != nil {
+			return err
+		}
+		name := path.Join("/", imagePath, filepath.ToSlash(relPath))[1:]
+		header.Name = name
+		header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+
+		fileInfo := filetree.NewFileInfoFromHeader(header, name)
+		if header.Typeflag == tar.TypeReg {
+			file, err := os.Open(walkPath)
+			if err != nil {
+				return err
+			}
+			fileInfo, err = filetree.NewFileInfo(file, header, name)
+			file.Close()
+			if err != nil {
+				return err
+			}
+		}
+		tree.FileSize += uint64(fileInfo.Size())
+		_, err = tree.AddPath(name, fileInfo)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
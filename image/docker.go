@@ -0,0 +1,103 @@
+package image
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/sirupsen/logrus"
+	"github.com/wagoodman/dive/utils"
+	"golang.org/x/net/context"
+)
+
+// dockerContext is the docker context to connect to (see SetDockerContext).
+var dockerContext string
+
+// dockerEndpoint is the daemon endpoint of the docker context, resolved when the first client is built.
+var dockerEndpoint struct {
+	once sync.Once
+	utils.DockerEndpoint
+	err error
+}
+
+// SetDockerContext connects to the daemon of the given docker context instead of the one given by the environment. The
+// context is only inspected once a connection to the daemon is needed (not for images read from a registry or an OCI
+// layout), so selecting a context that cannot be reached does not affect any other command.
+func SetDockerContext(name string) {
+	dockerContext = name
+}
+
+// resolveDockerEndpoint determines the daemon endpoint to connect to: the one of the docker context given with
+// SetDockerContext, otherwise the one given by DOCKER_HOST (left to the environment), otherwise the one of the current
+// docker context. The docker CLI may not be available at all, in which case the default daemon is used.
+func resolveDockerEndpoint() (utils.DockerEndpoint, error) {
+	dockerEndpoint.once.Do(func() {
+		if dockerContext == "" && os.Getenv("DOCKER_HOST") != "" {
+			return
+		}
+		endpoint, err := utils.DockerContextEndpoint(dockerContext)
+		if err != nil {
+			if dockerContext != "" {
+				dockerEndpoint.err = err
+			} else {
+				logrus.Debug(err)
+			}
+			return
+		}
+		dockerEndpoint.DockerEndpoint = endpoint
+	})
+	return dockerEndpoint.DockerEndpoint, dockerEndpoint.err
+}
+
+// newDockerClient connects to the Docker daemon, honoring the DOCKER_HOST, DOCKER_TLS_VERIFY and DOCKER_CERT_PATH
+// environment variables unless the docker context selects another endpoint (see resolveDockerEndpoint).
+func newDockerClient() (*client.Client, error) {
+	endpoint, err := resolveDockerEndpoint()
+	if err != nil {
+		return nil, err
+	}
+	options := []client.Opt{client.FromEnv}
+	if strings.HasPrefix(endpoint.Host, "ssh://") {
+		dial, err := sshDialer(endpoint.Host)
+		if err != nil {
+			return nil, err
+		}
+		// the host only names the daemon within the requests, the connection itself is made by the dialer
+		options = append(options, client.WithHost("http://docker.example.com"), client.WithDialContext(dial))
+	} else if endpoint.Host != "" {
+		options = append(options, client.WithHost(endpoint.Host))
+	}
+	if endpoint.TLSPath != "" {
+		options = append(options, client.WithTLSClientConfig(
+			filepath.Join(endpoint.TLSPath, "ca.pem"),
+			filepath.Join(endpoint.TLSPath, "cert.pem"),
+			filepath.Join(endpoint.TLSPath, "key.pem"),
+		))
+	}
+	options = append(options, client.WithVersion(dockerVersion))
+	return client.NewClientWithOpts(options...)
+}
+
+// DaemonVersion connects to the Docker daemon (as the analysis would) and returns its endpoint and version, or why it
+// could not be reached within the given time.
+func DaemonVersion(timeout time.Duration) (host, version string, err error) {
+	dockerClient, err := newDockerClient()
+	if err != nil {
+		return "", "", err
+	}
+	host = dockerClient.DaemonHost()
+	if endpoint, _ := resolveDockerEndpoint(); strings.HasPrefix(endpoint.Host, "ssh://") {
+		host = endpoint.Host
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	server, err := dockerClient.ServerVersion(ctx)
+	if err != nil {
+		return host, "", err
+	}
+	return host, fmt.Sprintf("%s (API %s, %s/%s)", server.Version, server.APIVersion, server.Os, server.Arch), nil
+}
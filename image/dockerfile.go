@@ -0,0 +1,180 @@
+package image
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// DockerfileInstruction is a single parsed instruction from a Dockerfile.
+type DockerfileInstruction struct {
+	Command string
+	Flags   []string
+	Args    []string
+	Line    int
+	// Heredocs are the here-documents of the instruction, in the order they are started in Args
+	Heredocs []DockerfileHeredoc
+}
+
+// Flag returns the value of the given instruction flag (e.g. "from" for --from=builder) and whether it was set.
+func (instruction *DockerfileInstruction) Flag(name string) (string, bool) {
+	prefix := "--" + name
+	for _, flag := range instruction.Flags {
+		if flag == prefix {
+			return "", true
+		}
+		if strings.HasPrefix(flag, prefix+"=") {
+			return strings.TrimPrefix(flag, prefix+"="), true
+		}
+	}
+	return "", false
+}
+
+// String shows the instruction as it would appear in a Dockerfile.
+func (instruction *DockerfileInstruction) String() string {
+	return strings.Join(append(append([]string{instruction.Command}, instruction.Flags...), instruction.Args...), " ")
+}
+
+// ParseDockerfile reads the instructions from a Dockerfile, joining line continuations, dropping comments and
+// collecting the here-documents of RUN, COPY and ADD instructions (e.g. "COPY <<EOF /etc/motd").
+func ParseDockerfile(reader io.Reader) ([]DockerfileInstruction, error) {
+	var lines []string
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var instructions []DockerfileInstruction
+	var current string
+	var startLine int
+	for idx := 0; idx < len(lines); idx++ {
+		line := strings.TrimSpace(lines[idx])
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if current == "" {
+			if line == "" {
+				continue
+			}
+			startLine = idx + 1
+		}
+
+		if strings.HasSuffix(line, "\\") {
+			current += strings.TrimSuffix(line, "\\") + " "
+			continue
+		}
+		current += line
+		instruction, err := newDockerfileInstruction(current, startLine)
+		if err != nil {
+			return nil, err
+		}
+		idx = instruction.readHeredocs(lines, idx+1) - 1
+		instructions = append(instructions, instruction)
+		current = ""
+	}
+	if strings.TrimSpace(current) != "" {
+		instruction, err := newDockerfileInstruction(current, startLine)
+		if err != nil {
+			return nil, err
+		}
+		instructions = append(instructions, instruction)
+	}
+	return instructions, nil
+}
+
+// DockerfileHeredoc is a here-document of an instruction, e.g. the file "COPY <<EOF /etc/motd" writes.
+type DockerfileHeredoc struct {
+	Name    string
+	Content string
+}
+
+// heredocPattern matches the start of a here-document ("<<EOF", "<<-EOF" or "<<'EOF'").
+var heredocPattern = regexp.MustCompile(`^<<(-?)(["']?)([A-Za-z_][A-Za-z0-9_.-]*)(["']?)$`)
+
+// heredocName returns the name of the here-document the given argument starts, and whether it starts one.
+func heredocName(arg string) (string, bool) {
+	match := heredocPattern.FindStringSubmatch(arg)
+	if match == nil || match[2] != match[4] {
+		return "", false
+	}
+	return match[3], true
+}
+
+// readHeredocs reads the here-documents the instruction starts from the given lines, beginning at the given line
+// index, and returns the index of the line after them.
+func (instruction *DockerfileInstruction) readHeredocs(lines []string, idx int) int {
+	switch instruction.Command {
+	case "RUN", "COPY", "ADD":
+	default:
+		return idx
+	}
+	for _, arg := range instruction.Args {
+		name, ok := heredocName(arg)
+		if !ok {
+			continue
+		}
+		// "<<-" strips the leading tabs of the document and of its terminator
+		stripTabs := strings.HasPrefix(arg, "<<-")
+		var content strings.Builder
+		for ; idx < len(lines); idx++ {
+			line := lines[idx]
+			if stripTabs {
+				line = strings.TrimLeft(line, "\t")
+			}
+			if line == name {
+				idx++
+				break
+			}
+			content.WriteString(line + "\n")
+		}
+		instruction.Heredocs = append(instruction.Heredocs, DockerfileHeredoc{Name: name, Content: content.String()})
+	}
+	return idx
+}
+
+// newDockerfileInstruction splits a single logical Dockerfile line into its command, flags, and arguments. A line with
+// no command (e.g. a lone line continuation followed by a blank line) is an error.
+func newDockerfileInstruction(line string, lineNumber int) (DockerfileInstruction, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return DockerfileInstruction{}, fmt.Errorf("line %d: expected an instruction", lineNumber)
+	}
+	instruction := DockerfileInstruction{
+		Command: strings.ToUpper(fields[0]),
+		Line:    lineNumber,
+	}
+
+	rest := strings.TrimSpace(strings.TrimPrefix(line, fields[0]))
+	for strings.HasPrefix(rest, "--") {
+		flag := strings.Fields(rest)[0]
+		instruction.Flags = append(instruction.Flags, flag)
+		rest = strings.TrimSpace(strings.TrimPrefix(rest, flag))
+	}
+
+	// the exec (JSON array) form is allowed for most instructions
+	if strings.HasPrefix(rest, "[") {
+		var args []string
+		if err := json.Unmarshal([]byte(rest), &args); err == nil {
+			instruction.Args = args
+			return instruction, nil
+		}
+	}
+	instruction.Args = strings.Fields(rest)
+	return instruction, nil
+}
+
+// FinalStage returns the instructions of the last build stage (everything after the last FROM).
+func FinalStage(instructions []DockerfileInstruction) []DockerfileInstruction {
+	for idx := len(instructions) - 1; idx >= 0; idx-- {
+		if instructions[idx].Command == "FROM" {
+			return instructions[idx+1:]
+		}
+	}
+	return instructions
+}
@@ -0,0 +1,132 @@
+package image
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDockerfile(t *testing.T) {
+	cases := []struct {
+		name       string
+		dockerfile string
+		expected   []DockerfileInstruction
+	}{
+		{
+			name: "continuations and comments",
+			dockerfile: `# syntax=docker/dockerfile:1
+FROM alpine AS build
+
+RUN apk add \
+    # comments within an instruction are dropped
+    curl \
+    git
+`,
+			expected: []DockerfileInstruction{
+				{Command: "FROM", Args: []string{"alpine", "AS", "build"}, Line: 2},
+				{Command: "RUN", Args: []string{"apk", "add", "curl", "git"}, Line: 4},
+			},
+		},
+		{
+			name:       "exec form",
+			dockerfile: `cmd ["sh", "-c", "echo hello world"]`,
+			expected: []DockerfileInstruction{
+				{Command: "CMD", Args: []string{"sh", "-c", "echo hello world"}, Line: 1},
+			},
+		},
+		{
+			name:       "flags",
+			dockerfile: "COPY --from=build --chown=app:app /out/app /usr/bin/app",
+			expected: []DockerfileInstruction{
+				{Command: "COPY", Flags: []string{"--from=build", "--chown=app:app"}, Args: []string{"/out/app", "/usr/bin/app"}, Line: 1},
+			},
+		},
+		{
+			name:       "unterminated continuation",
+			dockerfile: "RUN make \\\n    install \\",
+			expected: []DockerfileInstruction{
+				{Command: "RUN", Args: []string{"make", "install"}, Line: 1},
+			},
+		},
+		{
+			name: "heredocs",
+			dockerfile: `RUN <<EOF
+# not a comment
+COPY not-an-instruction /
+EOF
+COPY <<-'CONF' /etc/app.conf
+	key=value
+	CONF
+COPY <<a.txt <<b.txt /data/
+first
+a.txt
+second
+b.txt
+RUN echo $((1<<2))
+`,
+			expected: []DockerfileInstruction{
+				{Command: "RUN", Args: []string{"<<EOF"}, Line: 1, Heredocs: []DockerfileHeredoc{
+					{Name: "EOF", Content: "# not a comment\nCOPY not-an-instruction /\n"},
+				}},
+				{Command: "COPY", Args: []string{"<<-'CONF'", "/etc/app.conf"}, Line: 5, Heredocs: []DockerfileHeredoc{
+					{Name: "CONF", Content: "key=value\n"},
+				}},
+				{Command: "COPY", Args: []string{"<<a.txt", "<<b.txt", "/data/"}, Line: 8, Heredocs: []DockerfileHeredoc{
+					{Name: "a.txt", Content: "first\n"},
+					{Name: "b.txt", Content: "second\n"},
+				}},
+				{Command: "RUN", Args: []string{"echo", "$((1<<2))"}, Line: 13},
+			},
+		},
+	}
+
+	for _, test := range cases {
+		instructions, err := ParseDockerfile(strings.NewReader(test.dockerfile))
+		if err != nil {
+			t.Errorf("%s: could not parse: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(instructions, test.expected) {
+			t.Errorf("%s: expected\n%+v\ngot\n%+v", test.name, test.expected, instructions)
+		}
+	}
+}
+
+func TestParseDockerfileInvalid(t *testing.T) {
+	for _, dockerfile := range []string{
+		"FROM alpine\n\\\n\nRUN true\n",
+		"FROM alpine\n  \\\n\\\n\n",
+	} {
+		if _, err := ParseDockerfile(strings.NewReader(dockerfile)); err == nil || !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("%q: expected an error for line 2, got %v", dockerfile, err)
+		}
+	}
+}
+
+func TestDockerfileInstructionFlag(t *testing.T) {
+	instruction := DockerfileInstruction{Command: "COPY", Flags: []string{"--from=build", "--link"}}
+	cases := []struct {
+		flag  string
+		value string
+		set   bool
+	}{
+		{"from", "build", true},
+		{"link", "", true},
+		{"chown", "", false},
+		{"fro", "", false},
+	}
+	for _, test := range cases {
+		value, set := instruction.Flag(test.flag)
+		if value != test.value || set != test.set {
+			t.Errorf("--%s: expected %q (%v), got %q (%v)", test.flag, test.value, test.set, value, set)
+		}
+	}
+}
+
+func TestFinalStage(t *testing.T) {
+	instructions, _ := ParseDockerfile(strings.NewReader("FROM golang AS build\nRUN go build\nFROM alpine\nCOPY --from=build /app /app\n"))
+	final := FinalStage(instructions)
+	if len(final) != 1 || final[0].Command != "COPY" {
+		t.Errorf("Expected the COPY of the last stage, got %+v", final)
+	}
+}
@@ -0,0 +1,191 @@
+package image
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignorePattern is a single (possibly negated) rule from a .dockerignore file.
+type ignorePattern struct {
+	regex   *regexp.Regexp
+	exclude bool
+}
+
+// DockerIgnore holds the rules from a build context's .dockerignore file.
+type DockerIgnore struct {
+	patterns []ignorePattern
+}
+
+// ReadDockerIgnore loads the .dockerignore file from the given build context directory. A missing file yields
+// a DockerIgnore that excludes nothing.
+func ReadDockerIgnore(contextDir string) (*DockerIgnore, error) {
+	ignore := &DockerIgnore{}
+
+	file, err := os.Open(filepath.Join(contextDir, ".dockerignore"))
+	if os.IsNotExist(err) {
+		return ignore, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		err = ignore.Add(scanner.Text())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ignore, scanner.Err()
+}
+
+// Add appends a single .dockerignore rule. Later rules take precedence over earlier ones.
+func (ignore *DockerIgnore) Add(rule string) error {
+	rule = strings.TrimSpace(rule)
+	if rule == "" || strings.HasPrefix(rule, "#") {
+		return nil
+	}
+
+	exclude := true
+	if strings.HasPrefix(rule, "!") {
+		exclude = false
+		rule = strings.TrimSpace(rule[1:])
+	}
+	rule = filepath.ToSlash(filepath.Clean(rule))
+	rule = strings.TrimPrefix(rule, "/")
+
+	regex, err := regexp.Compile(globToRegex(rule))
+	if err != nil {
+		return err
+	}
+	ignore.patterns = append(ignore.patterns, ignorePattern{regex: regex, exclude: exclude})
+	return nil
+}
+
+// Excludes indicates if the given slash-delimited path (relative to the build context) would be left out of the
+// build context. A path is also excluded when one of its parent directories is excluded.
+func (ignore *DockerIgnore) Excludes(relPath string) bool {
+	relPath = strings.TrimPrefix(filepath.ToSlash(filepath.Clean(relPath)), "/")
+
+	excluded := false
+	for _, pattern := range ignore.patterns {
+		if pattern.exclude == excluded {
+			continue
+		}
+		if pattern.matches(relPath) {
+			excluded = pattern.exclude
+		}
+	}
+	return excluded
+}
+
+// hasExceptions indicates if any rule re-includes paths (e.g. "!docs/README.md"), which may be within an excluded
+// directory.
+func (ignore *DockerIgnore) hasExceptions() bool {
+	for _, pattern := range ignore.patterns {
+		if !pattern.exclude {
+			return true
+		}
+	}
+	return false
+}
+
+// matches checks the pattern against the given path and each of its parent directories.
+func (pattern ignorePattern) matches(relPath string) bool {
+	candidate := relPath
+	for {
+		if pattern.regex.MatchString(candidate) {
+			return true
+		}
+		idx := strings.LastIndex(candidate, "/")
+		if idx < 0 {
+			return false
+		}
+		candidate = candidate[:idx]
+	}
+}
+
+// globToRegex converts a .dockerignore style glob (supporting *, ?, **, and character classes such as [a-z] or [^0-9])
+// into an anchored regular expression. A "[" that starts no class is matched literally.
+func globToRegex(glob string) string {
+	var result strings.Builder
+	result.WriteString("^")
+	for idx := 0; idx < len(glob); idx++ {
+		char := glob[idx]
+		switch {
+		case char == '*' && idx+1 < len(glob) && glob[idx+1] == '*':
+			// '**' matches any number of directories, including none
+			idx++
+			if idx+1 < len(glob) && glob[idx+1] == '/' {
+				idx++
+				result.WriteString("(.*/)?")
+			} else {
+				result.WriteString(".*")
+			}
+		case char == '*':
+			result.WriteString("[^/]*")
+		case char == '?':
+			result.WriteString("[^/]")
+		case char == '[':
+			class, end, ok := globClass(glob, idx)
+			if !ok {
+				result.WriteString(`\[`)
+				break
+			}
+			result.WriteString(class)
+			idx = end
+		case char == '\\' && idx+1 < len(glob):
+			idx++
+			result.WriteString(regexp.QuoteMeta(string(glob[idx])))
+		default:
+			result.WriteString(regexp.QuoteMeta(string(char)))
+		}
+	}
+	result.WriteString("$")
+	return result.String()
+}
+
+// globClass converts the character class of a glob starting at the given index (e.g. "[a-z]" or "[^0-9]") into a
+// regular expression, returning the index of its closing bracket. A "]" right after the opening bracket belongs to the
+// class, and a negated class does not match "/". It fails when the class is not closed.
+func globClass(glob string, start int) (string, int, bool) {
+	var class strings.Builder
+	class.WriteString("[")
+	idx := start + 1
+	if idx < len(glob) && glob[idx] == '^' {
+		class.WriteString("^/")
+		idx++
+	}
+	empty := true
+	for ; idx < len(glob); idx++ {
+		char := glob[idx]
+		switch {
+		case char == ']' && !empty:
+			class.WriteString("]")
+			return class.String(), idx, true
+		case char == '-' && !empty && idx+1 < len(glob) && glob[idx+1] != ']':
+			// a range (e.g. "a-z"), a leading or trailing '-' is matched literally
+			class.WriteString("-")
+		case char == '\\' && idx+1 < len(glob):
+			idx++
+			class.WriteString(quoteClassChar(glob[idx]))
+		default:
+			class.WriteString(quoteClassChar(char))
+		}
+		empty = false
+	}
+	return "", start, false
+}
+
+// quoteClassChar quotes a single character (byte) for use within a character class of a regular expression.
+func quoteClassChar(char byte) string {
+	if char >= 0x80 || char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char >= '0' && char <= '9' {
+		// bytes of multibyte characters are kept as they are, such that the character stays whole
+		return string([]byte{char})
+	}
+	return string([]byte{'\\', char})
+}
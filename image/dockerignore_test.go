@@ -0,0 +1,107 @@
+package image
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGlobToRegex(t *testing.T) {
+	cases := []struct {
+		glob  string
+		regex string
+	}{
+		{"node_modules", `^node_modules$`},
+		{"*.log", `^[^/]*\.log$`},
+		{"file?.txt", `^file[^/]\.txt$`},
+		{"**/*.tmp", `^(.*/)?[^/]*\.tmp$`},
+		{"docs/**", `^docs/.*$`},
+		{`\*.md`, `^\*\.md$`},
+		{"a+b(1)", `^a\+b\(1\)$`},
+		{"log[0-9].txt", `^log[0-9]\.txt$`},
+		{"[^.]*", `^[^/\.][^/]*$`},
+		{"[]-]x", `^[\]\-]x$`},
+		{`[\]a]`, `^[\]a]$`},
+		{"a[b", `^a\[b$`},
+	}
+	for _, test := range cases {
+		if regex := globToRegex(test.glob); regex != test.regex {
+			t.Errorf("%s: expected %s, got %s", test.glob, test.regex, regex)
+		}
+	}
+}
+
+func TestDockerIgnoreExcludes(t *testing.T) {
+	ignore := &DockerIgnore{}
+	for _, rule := range []string{
+		"# comment",
+		"",
+		"node_modules",
+		"*.log",
+		"!keep.log",
+		"docs",
+		"!docs/README.md",
+		"**/*.tmp",
+		"/secrets/*.pem",
+		"build/",
+		"cache[0-9]",
+	} {
+		if err := ignore.Add(rule); err != nil {
+			t.Fatalf("could not add %q: %v", rule, err)
+		}
+	}
+
+	cases := []struct {
+		path     string
+		excluded bool
+	}{
+		{"node_modules", true},
+		{"node_modules/left-pad/index.js", true},
+		{"src/node_modules/index.js", false},
+		{"debug.log", true},
+		{"keep.log", false},
+		{"logs/debug.log", false},
+		{"docs/guide.md", true},
+		{"docs/README.md", false},
+		{"c.tmp", true},
+		{"a/b/c.tmp", true},
+		{"secrets/key.pem", true},
+		{"./secrets/key.pem", true},
+		{"secrets/key.pub", false},
+		{"build/app", true},
+		{"README.md", false},
+		{"cache1/data", true},
+		{"cachex", false},
+	}
+	for _, test := range cases {
+		if excluded := ignore.Excludes(test.path); excluded != test.excluded {
+			t.Errorf("%s: expected excluded=%v, got %v", test.path, test.excluded, excluded)
+		}
+	}
+	if !ignore.hasExceptions() {
+		t.Errorf("Expected the ! rules to be exceptions")
+	}
+}
+
+func TestReadDockerIgnore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dive-context")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ignore, err := ReadDockerIgnore(dir)
+	if err != nil || ignore.Excludes(".git") || ignore.hasExceptions() {
+		t.Errorf("Expected a missing .dockerignore to exclude nothing (%v)", err)
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, ".dockerignore"), []byte(".git\n*.md\n!README.md\n"), 0644)
+	ignore, err = ReadDockerIgnore(dir)
+	if err != nil {
+		t.Fatalf("could not read .dockerignore: %v", err)
+	}
+	if !ignore.Excludes(".git/config") || !ignore.Excludes("CHANGES.md") || ignore.Excludes("README.md") {
+		t.Errorf("Expected the rules of .dockerignore to apply")
+	}
+}
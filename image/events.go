@@ -0,0 +1,142 @@
+package image
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/wagoodman/dive/filetree"
+)
+
+// event types written while an image is analyzed
+const (
+	EventLayerStarted = "layer_started"
+	EventLayerParsed  = "layer_parsed"
+	EventFinding      = "finding"
+	EventWarning      = "warning"
+	EventSummary      = "summary"
+)
+
+// SchemaVersion is the version of every JSON output of dive (the events, and the JSON of the commands), written as its
+// "schemaVersion" field (see filetree.SchemaVersion).
+const SchemaVersion = filetree.SchemaVersion
+
+// Event is a single event written while an image is analyzed (see EnableEvents).
+type Event interface {
+	// EventType is the type of the event, written as its "type" field
+	EventType() string
+	header() *EventHeader
+}
+
+// EventHeader holds the fields common to all events.
+type EventHeader struct {
+	SchemaVersion string `json:"schemaVersion"`
+	Type          string `json:"type"`
+	Time          string `json:"time"`
+}
+
+func (header *EventHeader) header() *EventHeader {
+	return header
+}
+
+// LayerStartedEvent is written when the analysis of a layer starts.
+type LayerStartedEvent struct {
+	EventHeader
+	Layer string `json:"layer"`
+	Size  int64  `json:"size"`
+}
+
+func (LayerStartedEvent) EventType() string {
+	return EventLayerStarted
+}
+
+// LayerParsedEvent is written when a layer is analyzed.
+type LayerParsedEvent struct {
+	EventHeader
+	Layer string `json:"layer"`
+	Files int    `json:"files"`
+	Size  uint64 `json:"size"`
+}
+
+func (LayerParsedEvent) EventType() string {
+	return EventLayerParsed
+}
+
+// FindingEvent is written for wasted space ("wasted": the path, its cumulative size and occurrences) and when the
+// analysis is degraded ("degraded": the memory limit and the heap size, see SetMemoryLimit).
+type FindingEvent struct {
+	EventHeader
+	Kind        string `json:"kind"`
+	Path        string `json:"path,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+	Occurrences int    `json:"occurrences,omitempty"`
+	Limit       uint64 `json:"limit,omitempty"`
+	Heap        uint64 `json:"heap,omitempty"`
+}
+
+func (FindingEvent) EventType() string {
+	return EventFinding
+}
+
+// WarningEvent is written for every warning raised while the layers were read (see filetree.Warning).
+type WarningEvent struct {
+	EventHeader
+	Kind    filetree.WarningKind `json:"kind"`
+	Layer   string               `json:"layer"`
+	Path    string               `json:"path"`
+	Message string               `json:"message"`
+}
+
+func (WarningEvent) EventType() string {
+	return EventWarning
+}
+
+// SummaryEvent is written once the image is analyzed.
+type SummaryEvent struct {
+	EventHeader
+	Image      string  `json:"image"`
+	Layers     int     `json:"layers"`
+	Size       uint64  `json:"size"`
+	WastedSize uint64  `json:"wastedSize"`
+	Efficiency float64 `json:"efficiency"`
+	Warnings   int     `json:"warnings"`
+}
+
+func (SummaryEvent) EventType() string {
+	return EventSummary
+}
+
+// Events lists a value of every type of event, e.g. to describe them.
+var Events = []Event{&LayerStartedEvent{}, &LayerParsedEvent{}, &FindingEvent{}, &WarningEvent{}, &SummaryEvent{}}
+
+// eventWriter writes events as newline delimited JSON (one object per line).
+type eventWriter struct {
+	lock    sync.Mutex
+	encoder *json.Encoder
+}
+
+// events receives all events (nil when events are not enabled).
+var events *eventWriter
+
+// EnableEvents writes progress and result events as newline delimited JSON to the given writer while images are
+// analyzed, so other tools can follow the analysis as it happens.
+func EnableEvents(writer io.Writer) {
+	events = &eventWriter{encoder: json.NewEncoder(writer)}
+}
+
+// emitEvent writes a single event (if events are enabled), filling in its header.
+func emitEvent(event Event) {
+	if events == nil {
+		return
+	}
+	*event.header() = EventHeader{
+		SchemaVersion: SchemaVersion,
+		Type:          event.EventType(),
+		Time:          time.Now().UTC().Format(time.RFC3339Nano),
+	}
+
+	events.lock.Lock()
+	defer events.lock.Unlock()
+	events.encoder.Encode(event)
+}
@@ -0,0 +1,36 @@
+package image
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestEmitEvent(t *testing.T) {
+	var output bytes.Buffer
+	EnableEvents(&output)
+	t.Cleanup(func() { events = nil })
+
+	emitEvent(&FindingEvent{Kind: "wasted", Path: "/etc/hosts", Size: 20, Occurrences: 2})
+	emitEvent(&FindingEvent{Kind: "degraded", Limit: 100, Heap: 90})
+
+	decoder := json.NewDecoder(&output)
+	expected := []map[string]interface{}{
+		{"type": EventFinding, "schemaVersion": SchemaVersion, "kind": "wasted", "path": "/etc/hosts", "size": 20.0, "occurrences": 2.0},
+		{"type": EventFinding, "schemaVersion": SchemaVersion, "kind": "degraded", "limit": 100.0, "heap": 90.0},
+	}
+	for _, fields := range expected {
+		var event map[string]interface{}
+		if err := decoder.Decode(&event); err != nil {
+			t.Fatalf("Expected an event, got: %v", err)
+		}
+		if _, ok := event["time"]; !ok || len(event) != len(fields)+1 {
+			t.Errorf("Expected the fields %v and the time, got %v", fields, event)
+		}
+		for key, value := range fields {
+			if event[key] != value {
+				t.Errorf("Expected %s=%v, got %v", key, value, event[key])
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package image
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/wagoodman/dive/filetree"
+)
+
+// ExportSquashed writes the final filesystem of the image (the stack of the given layer trees, oldest first) as a
+// tar archive, see filetree.FileTree.ExportSquashed. The content of every file is taken from the layer that last
+// provided it; since the saved image is not kept once it is analyzed, the image is saved again and the files of the
+// final filesystem are staged in a temporary directory while exporting.
+func ExportSquashed(imageID string, trees []*filetree.FileTree, writer io.Writer) error {
+	final := filetree.StackRange(trees, 0, len(trees)-1)
+
+	// providers notes the layer that last provided each regular file of the final filesystem
+	providers := make(map[string]string)
+	filetree.StackFiles(trees, func(layer int, node *filetree.FileNode) {
+		if node.Data.FileInfo.TarHeader.Typeflag == tar.TypeReg {
+			providers[cleanTarPath(node.Path())] = trees[layer].Name
+		} else {
+			delete(providers, cleanTarPath(node.Path()))
+		}
+	}, func(layer int, path string) {
+		delete(providers, cleanTarPath(path))
+	})
+
+	imageTarPath, tmpDir, err := saveImage(imageID)
+	if tmpDir != "" {
+		defer os.RemoveAll(tmpDir)
+	}
+	if err != nil {
+		return err
+	}
+	stageDir := filepath.Join(tmpDir, "rootfs")
+	if err := stageProvidedFiles(imageTarPath, stageDir, providers); err != nil {
+		return err
+	}
+
+	return final.ExportSquashed(writer, filetree.ExportTar, func(node *filetree.FileNode) (io.ReadCloser, error) {
+		return os.Open(filepath.Join(stageDir, filepath.FromSlash(cleanTarPath(node.Path()))))
+	})
+}
+
+// stageProvidedFiles writes every file of the saved image to the given directory that is provided by the layer noted
+// for its path.
+func stageProvidedFiles(imageTarPath, stageDir string, providers map[string]string) error {
+	// some layer tars are relative symlinks to other layer tars, which are found first
+	aliases := make(map[string][]string)
+	err := readImageTar(imageTarPath, func(header *tar.Header, _ io.Reader) error {
+		if strings.HasSuffix(header.Name, "layer.tar") && header.Typeflag == tar.TypeSymlink {
+			target := path.Join(path.Dir(header.Name), header.Linkname)
+			aliases[target] = append(aliases[target], header.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return readImageTar(imageTarPath, func(header *tar.Header, reader io.Reader) error {
+		if !strings.HasSuffix(header.Name, "layer.tar") || header.Typeflag != tar.TypeReg {
+			return nil
+		}
+		names := map[string]bool{header.Name: true}
+		for _, alias := range aliases[header.Name] {
+			names[alias] = true
+		}
+
+		layerReader := tar.NewReader(reader)
+		for {
+			entry, err := layerReader.Next()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("could not read layer %s: %v", header.Name, err)
+			}
+			name := cleanTarPath(entry.Name)
+			if entry.Typeflag != tar.TypeReg || !names[providers[name]] {
+				continue
+			}
+			if err := stageFile(filepath.Join(stageDir, filepath.FromSlash(name)), layerReader); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// readImageTar passes every entry of the saved image to the given function.
+func readImageTar(imageTarPath string, each func(header *tar.Header, reader io.Reader) error) error {
+	file, err := os.Open(imageTarPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := tar.NewReader(file)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := each(header, reader); err != nil {
+			return err
+		}
+	}
+}
+
+// stageFile writes the content of the given reader to the given location (creating its directory).
+func stageFile(location string, reader io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(location), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(location)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = io.Copy(file, reader)
+	return err
+}
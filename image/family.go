@@ -0,0 +1,18 @@
+package image
+
+import (
+	"strings"
+)
+
+// Family returns the repository of the given image reference without its tag or digest (e.g. "ubuntu" for
+// "ubuntu:18.04", "localhost:5000/app" for "localhost:5000/app:1.0@sha256:..."), which groups the builds of an image.
+func Family(reference string) string {
+	if idx := strings.Index(reference, "@"); idx >= 0 {
+		reference = reference[:idx]
+	}
+	// a tag follows the last colon after the last slash (a colon before it separates the registry port)
+	if idx := strings.LastIndex(reference, ":"); idx > strings.LastIndex(reference, "/") {
+		reference = reference[:idx]
+	}
+	return reference
+}
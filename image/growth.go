@@ -0,0 +1,92 @@
+package image
+
+import (
+	"regexp"
+	"sort"
+)
+
+// contentDigestPattern matches the content digests the classic builder embeds in COPY/ADD history entries (e.g.
+// "COPY file:8b0e...e1 in /app"), which change with every change of the copied files.
+var contentDigestPattern = regexp.MustCompile(`\b(file|dir|multi):[0-9a-f]{64}\b`)
+
+// LayerGrowth is the change in size of a single layer, identified by the instruction that created it, between two
+// builds of an image.
+type LayerGrowth struct {
+	Instruction string
+	// Layer is the index of the layer in the newer image (-1 when the layer only exists in the older image)
+	Layer   int
+	OldSize uint64
+	NewSize uint64
+}
+
+// Delta is the number of bytes the layer grew by (negative when it shrunk).
+func (growth LayerGrowth) Delta() int64 {
+	return int64(growth.NewSize) - int64(growth.OldSize)
+}
+
+// layerInstruction returns the (normalized) instruction that created the given layer, which reads the same for the
+// classic builder and BuildKit.
+func layerInstruction(layer *Layer) string {
+	return layer.Command()
+}
+
+// layerKey identifies a layer across builds of the same Dockerfile.
+func layerKey(layer *Layer) string {
+	return contentDigestPattern.ReplaceAllString(layerInstruction(layer), "$1:*")
+}
+
+// chronological returns the given layers (in the order returned by InitializeData) from oldest to newest.
+func chronological(layers []*Layer) []*Layer {
+	result := make([]*Layer, len(layers))
+	for idx, layer := range layers {
+		result[len(layers)-1-idx] = layer
+	}
+	return result
+}
+
+// Growth attributes the change in size between two builds of an image to the layers (and so the instructions) that
+// caused it. Layers are matched by the instructions that created them, such that inserted and removed instructions
+// are reported on their own. The given layers must be in the order returned by InitializeData; the result is ordered
+// by growth, largest first.
+func Growth(oldLayers, newLayers []*Layer) []LayerGrowth {
+	before, after := chronological(oldLayers), chronological(newLayers)
+
+	// find the longest common subsequence of instructions
+	lengths := make([][]int, len(before)+1)
+	for idx := range lengths {
+		lengths[idx] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if layerKey(before[i]) == layerKey(after[j]) {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	var result []LayerGrowth
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case i < len(before) && j < len(after) && layerKey(before[i]) == layerKey(after[j]):
+			result = append(result, LayerGrowth{Instruction: layerInstruction(after[j]), Layer: j, OldSize: before[i].History.Size, NewSize: after[j].History.Size})
+			i++
+			j++
+		case j < len(after) && (i == len(before) || lengths[i][j+1] >= lengths[i+1][j]):
+			result = append(result, LayerGrowth{Instruction: layerInstruction(after[j]), Layer: j, NewSize: after[j].History.Size})
+			j++
+		default:
+			result = append(result, LayerGrowth{Instruction: layerInstruction(before[i]), Layer: -1, OldSize: before[i].History.Size})
+			i++
+		}
+	}
+
+	sort.SliceStable(result, func(a, b int) bool {
+		return result[a].Delta() > result[b].Delta()
+	})
+	return result
+}
@@ -4,30 +4,29 @@ import (
 	"archive/tar"
 	"bufio"
 	"bytes"
+	"crypto/sha256"
 	"encoding/json"
 	"fmt"
-	"github.com/sirupsen/logrus"
 	"io"
 	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 
-	"github.com/docker/docker/client"
+	"github.com/dustin/go-humanize"
 	"github.com/wagoodman/dive/filetree"
 	"github.com/wagoodman/dive/utils"
-	"github.com/wagoodman/jotframe"
 	"golang.org/x/net/context"
 )
 
 // TODO: this file should be rethought... but since it's only for preprocessing it'll be tech debt for now.
 const dockerVersion = "1.26"
 
-func check(e error) {
-	if e != nil {
-		panic(e)
-	}
-}
+// streamLayerSize is the size above which a layer tar is parsed while it is read from the image archive instead of
+// being read into memory first (and parsed alongside the other layers).
+const streamLayerSize = 512 * 1024 * 1024
 
 type ProgressBar struct {
 	percent    int
@@ -75,6 +74,8 @@ type ImageManifest struct {
 	ConfigPath    string   `json:"Config"`
 	RepoTags      []string `json:"RepoTags"`
 	LayerTarPaths []string `json:"Layers"`
+	// LayerSources are the layers stored elsewhere (not in the archive), by diff_id
+	LayerSources map[string]LayerSource `json:"LayerSources,omitempty"`
 }
 
 type ImageConfig struct {
@@ -96,32 +97,41 @@ type ImageHistoryEntry struct {
 	EmptyLayer bool   `json:"empty_layer"`
 }
 
-func NewImageManifest(reader *tar.Reader, header *tar.Header) ImageManifest {
-	size := header.Size
-	manifestBytes := make([]byte, size)
-	_, err := reader.Read(manifestBytes)
-	if err != nil && err != io.EOF {
-		logrus.Panic(err)
+func NewImageManifest(reader *tar.Reader, header *tar.Header) (ImageManifest, error) {
+	manifestBytes := make([]byte, header.Size)
+	_, err := io.ReadFull(reader, manifestBytes)
+	if err != nil {
+		return ImageManifest{}, err
 	}
+	diagnostics.recordFile(header.Name, manifestBytes)
 	var manifest []ImageManifest
 	err = json.Unmarshal(manifestBytes, &manifest)
 	if err != nil {
-		logrus.Panic(err)
+		return ImageManifest{}, err
 	}
-	return manifest[0]
+	if len(manifest) == 0 {
+		return ImageManifest{}, fmt.Errorf("image manifest is empty")
+	}
+	return manifest[0], nil
 }
 
-func NewImageConfig(reader *tar.Reader, header *tar.Header) ImageConfig {
-	size := header.Size
-	configBytes := make([]byte, size)
-	_, err := reader.Read(configBytes)
-	if err != nil && err != io.EOF {
-		logrus.Panic(err)
+func NewImageConfig(reader *tar.Reader, header *tar.Header) (ImageConfig, error) {
+	configBytes := make([]byte, header.Size)
+	_, err := io.ReadFull(reader, configBytes)
+	if err != nil {
+		return ImageConfig{}, err
 	}
+	diagnostics.recordFile(header.Name, configBytes)
+	return parseImageConfig(configBytes)
+}
+
+// parseImageConfig decodes the given image config, pairing the history entries that have layer contents with the
+// diff_ids of the layers.
+func parseImageConfig(configBytes []byte) (ImageConfig, error) {
 	var imageConfig ImageConfig
-	err = json.Unmarshal(configBytes, &imageConfig)
+	err := json.Unmarshal(configBytes, &imageConfig)
 	if err != nil {
-		logrus.Panic(err)
+		return ImageConfig{}, err
 	}
 
 	layerIdx := 0
@@ -129,22 +139,24 @@ func NewImageConfig(reader *tar.Reader, header *tar.Header) ImageConfig {
 		if imageConfig.History[idx].EmptyLayer {
 			imageConfig.History[idx].ID = "<missing>"
 		} else {
+			if layerIdx >= len(imageConfig.RootFs.DiffIds) {
+				return ImageConfig{}, fmt.Errorf("image history has more layers than the image rootfs (%d)", len(imageConfig.RootFs.DiffIds))
+			}
 			imageConfig.History[idx].ID = imageConfig.RootFs.DiffIds[layerIdx]
 			layerIdx++
 		}
 	}
 
-	return imageConfig
+	return imageConfig, nil
 }
 
-func GetImageConfig(imageTarPath string, manifest ImageManifest) ImageConfig {
+func GetImageConfig(imageTarPath string, manifest ImageManifest) (ImageConfig, error) {
 	var config ImageConfig
 	// read through the image contents and build a tree
-	fmt.Println("  Fetching image config...")
+	printProgress("  Fetching image config...")
 	tarFile, err := os.Open(imageTarPath)
 	if err != nil {
-		fmt.Println(err)
-		utils.Exit(1)
+		return config, err
 	}
 	defer tarFile.Close()
 
@@ -157,90 +169,191 @@ func GetImageConfig(imageTarPath string, manifest ImageManifest) ImageConfig {
 		}
 
 		if err != nil {
-			fmt.Println(err)
-			utils.Exit(1)
+			return config, err
 		}
 
 		name := header.Name
 		if name == manifest.ConfigPath {
-			config = NewImageConfig(tarReader, header)
+			config, err = NewImageConfig(tarReader, header)
+			if err != nil {
+				return config, fmt.Errorf("could not read image config: %v", err)
+			}
 		}
 	}
 
 	// obtain the image history
-	return config
+	return config, nil
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (counter *countingReader) Read(buffer []byte) (int, error) {
+	read, err := counter.reader.Read(buffer)
+	counter.count += int64(read)
+	return read, err
 }
 
-func processLayerTar(line *jotframe.Line, layerMap map[string]*filetree.FileTree, name string, tarredBytes []byte) {
+// processLayerTar builds the tree of a single layer from the given layer tar (of the given size), showing the
+// progress (by bytes read) on the given line. A negative size is unknown (e.g. for a compressed blob), in which case
+// the bytes read so far are shown instead.
+func processLayerTar(line io.WriteCloser, name string, reader io.Reader, size int64) (*filetree.FileTree, error) {
+	defer line.Close()
+
 	tree := filetree.NewFileTree()
 	tree.Name = name
 
-	fileInfos := getFileList(tarredBytes)
-
 	shortName := name[:15]
-	pb := NewProgressBar(int64(len(fileInfos)))
-	for idx, element := range fileInfos {
-		tree.FileSize += uint64(element.TarHeader.FileInfo().Size())
-		tree.AddPath(element.Path, element)
+	counter := &countingReader{reader: reader}
+	pb := NewProgressBar(size)
+	aggregates := make(map[string]*aggregateEntry)
+	var files int
+	err := readFileList(tree, counter, func(element filetree.FileInfo) {
+		files++
+		tree.FileSize += uint64(element.Size())
+		if isAggregated(element) {
+			aggregate(aggregates, element)
+		} else if _, err := tree.AddPath(element.Path, element); err != nil {
+			tree.Warn(filetree.WarningSkippedEntry, element.Path, "%v", err)
+		}
 
-		if pb.Update(int64(idx)) {
+		if size < 0 {
+			if files%1000 == 0 {
+				io.WriteString(line, fmt.Sprintf("    ├─ %s : %s read", shortName, humanize.Bytes(uint64(counter.count))))
+			}
+		} else if pb.Update(counter.count) {
 			io.WriteString(line, fmt.Sprintf("    ├─ %s : %s", shortName, pb.String()))
 		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not read layer %s: %v", name, err)
+	}
+	if size < 0 {
+		// the size is known now
+		pb = NewProgressBar(counter.count)
+	}
+	pb.Update(pb.rawTotal)
+	for _, entry := range aggregates {
+		info := entry.FileInfo()
+		if _, err := tree.AddPath(info.Path, info); err != nil {
+			tree.Warn(filetree.WarningSkippedEntry, info.Path, "%v", err)
+		}
 	}
 	pb.Done()
 	io.WriteString(line, fmt.Sprintf("    ├─ %s : %s", shortName, pb.String()))
 
-	layerMap[tree.Name] = tree
-	line.Close()
+	emitEvent(&LayerParsedEvent{Layer: name, Files: files, Size: tree.FileSize})
+
+	return tree, nil
 }
 
+// InitializeData fetches and analyzes the given image, exiting the process if the image cannot be analyzed. When a
+// cache server is running (see ServeCache), the analysis is taken from it.
 func InitializeData(imageID string) ([]*Layer, []*filetree.FileTree, float64, filetree.EfficiencySlice) {
+	if layers, trees, ok := loadCached(imageID); ok {
+		printProgress("  Using the cached analysis...")
+		efficiency, inefficiencies := filetree.Efficiency(trees)
+		return layers, trees, efficiency, inefficiencies
+	}
+	layers, trees, efficiency, inefficiencies, err := LoadImage(imageID)
+	if err != nil {
+		fmt.Println(err)
+		utils.Exit(1)
+	}
+	return layers, trees, efficiency, inefficiencies
+}
+
+// LoadImage fetches the given image from the docker daemon (pulling it if needed) and builds a file tree for each layer.
+// When diagnostics are enabled and the image cannot be loaded, a diagnostics bundle is written.
+func LoadImage(imageID string) ([]*Layer, []*filetree.FileTree, float64, filetree.EfficiencySlice, error) {
+	analysis := startSpan("dive.analyze", nil, map[string]interface{}{"image.reference": imageID})
+	layers, trees, efficiency, inefficiencies, err := loadImage(imageID, analysis)
+	analysis.finish(err)
+	if err != nil && diagnostics != nil {
+		path, writeErr := diagnostics.write(imageID, err)
+		if writeErr != nil {
+			return nil, nil, 0, nil, fmt.Errorf("%v\n(could not write diagnostics: %v)", err, writeErr)
+		}
+		return nil, nil, 0, nil, fmt.Errorf("%v\n(diagnostics written to %s, please attach it to a bug report)", err, path)
+	}
+	return layers, trees, efficiency, inefficiencies, err
+}
+
+func loadImage(imageID string, analysis *span) ([]*Layer, []*filetree.FileTree, float64, filetree.EfficiencySlice, error) {
+	if strings.HasPrefix(imageID, LayoutPrefix) {
+		return loadLayoutImage(imageID, analysis)
+	}
+	if strings.HasPrefix(imageID, RegistryPrefix) {
+		return loadRegistryImage(imageID, analysis)
+	}
+
 	var manifest ImageManifest
 	var layerMap = make(map[string]*filetree.FileTree)
-	var trees = make([]*filetree.FileTree, 0)
+	var layerDigests = make(map[string]string)
+	var layerLinks = make(map[string]string)
+	// layerQuirks are why the contents of some layers could not be read (see layerQuirk), by tar path
+	var layerQuirks = make(map[string]string)
+	var layerMapLock sync.Mutex
+	var layerErr error
 
 	// pull the image if it does not exist
 	ctx := context.Background()
-	dockerClient, err := client.NewClientWithOpts(client.WithVersion(dockerVersion))
+	dockerClient, err := newDockerClient()
 	if err != nil {
-		fmt.Println("Could not connect to the Docker daemon:" + err.Error())
-		utils.Exit(1)
+		return nil, nil, 0, nil, fmt.Errorf("could not connect to the Docker daemon: %v", err)
 	}
 	_, _, err = dockerClient.ImageInspectWithRaw(ctx, imageID)
 	if err != nil {
 		// don't use the API, the CLI has more informative output
+		pull := startSpan("dive.pull", analysis, nil)
 		utils.RunDockerCmd("pull", imageID)
+		pull.finish(nil)
 	}
 
 	// save this image to disk temporarily to get the content info
-	imageTarPath, tmpDir := saveImage(imageID)
-	// fmt.Println(imageTarPath)
-	// fmt.Println(tmpDir)
-	// imageTarPath := "/tmp/dive280665036/image.tar"
-	defer os.RemoveAll(tmpDir)
+	save := startSpan("dive.save", analysis, nil)
+	imageTarPath, tmpDir, err := saveImage(imageID)
+	save.finish(err)
+	if tmpDir != "" {
+		defer os.RemoveAll(tmpDir)
+	}
+	if err != nil {
+		return nil, nil, 0, nil, err
+	}
+
+	selectedPaths, err := selectedTarPaths(imageTarPath)
+	if err != nil {
+		return nil, nil, 0, nil, err
+	}
 
 	// read through the image contents and build a tree
 	tarFile, err := os.Open(imageTarPath)
 	if err != nil {
-		fmt.Println(err)
-		utils.Exit(1)
+		return nil, nil, 0, nil, err
 	}
 	defer tarFile.Close()
 
 	fi, err := tarFile.Stat()
 	if err != nil {
-		logrus.Panic(err)
+		return nil, nil, 0, nil, err
 	}
 	totalSize := fi.Size()
 	var observedBytes int64
 	var percent int
 
 	tarReader := tar.NewReader(tarFile)
-	frame := jotframe.NewFixedFrame(1, true, false, false)
-	lastLine := frame.Lines()[0]
+	frame := newProgressFrame(1, true, false, false)
+	var lastLine io.WriteCloser = discardLine{}
+	if lines := frame.Lines(); len(lines) > 0 {
+		lastLine = lines[0]
+	}
 	io.WriteString(lastLine, "    ╧")
 	lastLine.Close()
 
+	var wg sync.WaitGroup
 	for {
 		header, err := tarReader.Next()
 
@@ -250,10 +363,11 @@ func InitializeData(imageID string) ([]*Layer, []*filetree.FileTree, float64, fi
 		}
 
 		if err != nil {
-			fmt.Println(err)
-			utils.Exit(1)
+			return nil, nil, 0, nil, err
 		}
 
+		diagnostics.recordHeader("image", header)
+
 		observedBytes += header.Size
 		percent = int(100.0 * (float64(observedBytes) / float64(totalSize)))
 		io.WriteString(frame.Header(), fmt.Sprintf("  Discovering layers... %d %%", percent))
@@ -263,41 +377,145 @@ func InitializeData(imageID string) ([]*Layer, []*filetree.FileTree, float64, fi
 		// some layer tars can be relative layer symlinks to other layer tars
 		if header.Typeflag == tar.TypeSymlink || header.Typeflag == tar.TypeReg {
 
-			if strings.HasSuffix(name, "layer.tar") {
+			if strings.HasSuffix(name, "layer.tar") && selectedPaths != nil && !selectedPaths[name] {
+				// skip layers that were not selected for analysis, leaving them empty
+				tree := filetree.NewFileTree()
+				tree.Name = name
+				layerMapLock.Lock()
+				layerMap[name] = tree
+				layerMapLock.Unlock()
+			} else if strings.HasSuffix(name, "layer.tar") {
 				line, err := frame.Prepend()
 				if err != nil {
-					logrus.Panic(err)
+					return nil, nil, 0, nil, err
 				}
 				shortName := name[:15]
 				io.WriteString(line, "    ├─ "+shortName+" : loading...")
+				emitEvent(&LayerStartedEvent{Layer: name, Size: header.Size})
+
+				if header.Size > streamLayerSize {
+					// parse very large layers while reading them (sequentially), rather than holding them in memory
+					digest := sha256.New()
+					stream := bufio.NewReader(io.TeeReader(tarReader, digest))
+					head, _ := stream.Peek(1024)
+					if reason := layerQuirk(head, header.Size); reason != "" {
+						if _, err = io.Copy(ioutil.Discard, stream); err != nil {
+							return nil, nil, 0, nil, err
+						}
+						io.WriteString(line, "    ├─ "+shortName+" : metadata only ("+reason+")")
+						line.Close()
+						layerMapLock.Lock()
+						layerMap[name] = metadataOnlyTree(name, reason)
+						layerQuirks[name] = reason
+						layerDigests[name] = fmt.Sprintf("sha256:%x", digest.Sum(nil))
+						layerMapLock.Unlock()
+						continue
+					}
+					parse := startSpan("dive.layer", analysis, map[string]interface{}{"layer": name, "layer.size": header.Size, "streamed": true})
+					tree, err := processLayerTar(line, name, stream, header.Size)
+					parse.finish(err)
+					if err != nil {
+						return nil, nil, 0, nil, err
+					}
+					// the layer tar may be padded beyond its end-of-archive marker, which is part of its digest
+					if _, err = io.Copy(ioutil.Discard, stream); err != nil {
+						return nil, nil, 0, nil, err
+					}
+					layerMapLock.Lock()
+					layerMap[name] = tree
+					layerDigests[name] = fmt.Sprintf("sha256:%x", digest.Sum(nil))
+					layerMapLock.Unlock()
+					continue
+				}
 
 				var tarredBytes = make([]byte, header.Size)
 
-				_, err = tarReader.Read(tarredBytes)
-				if err != nil && err != io.EOF {
-					logrus.Panic(err)
+				_, err = io.ReadFull(tarReader, tarredBytes)
+				if err != nil {
+					return nil, nil, 0, nil, err
+				}
+				if header.Typeflag == tar.TypeSymlink {
+					layerLinks[name] = path.Join(path.Dir(name), header.Linkname)
+				} else {
+					layerDigests[name] = fmt.Sprintf("sha256:%x", sha256.Sum256(tarredBytes))
+					if reason := layerQuirk(tarredBytes, header.Size); reason != "" {
+						io.WriteString(line, "    ├─ "+shortName+" : metadata only ("+reason+")")
+						line.Close()
+						layerMapLock.Lock()
+						layerMap[name] = metadataOnlyTree(name, reason)
+						layerQuirks[name] = reason
+						layerMapLock.Unlock()
+						continue
+					}
 				}
 
-				go processLayerTar(line, layerMap, name, tarredBytes)
+				wg.Add(1)
+				go func(line io.WriteCloser, name string, tarredBytes []byte) {
+					defer wg.Done()
+					parse := startSpan("dive.layer", analysis, map[string]interface{}{"layer": name, "layer.size": len(tarredBytes)})
+					tree, err := processLayerTar(line, name, bytes.NewReader(tarredBytes), int64(len(tarredBytes)))
+					parse.finish(err)
+
+					layerMapLock.Lock()
+					defer layerMapLock.Unlock()
+					if err != nil {
+						layerErr = err
+						return
+					}
+					layerMap[name] = tree
+				}(line, name, tarredBytes)
 			} else if name == "manifest.json" {
-				manifest = NewImageManifest(tarReader, header)
+				manifest, err = NewImageManifest(tarReader, header)
+				if err != nil {
+					return nil, nil, 0, nil, fmt.Errorf("could not read image manifest: %v", err)
+				}
 			}
 		}
 	}
+	wg.Wait()
 	frame.Header().Close()
 	frame.Wait()
 	frame.Remove(lastLine)
-	fmt.Println("")
+	printProgress("")
+
+	if layerErr != nil {
+		return nil, nil, 0, nil, layerErr
+	}
+
+	for name, linkName := range layerLinks {
+		layerDigests[name] = layerDigests[linkName]
+	}
 
 	// obtain the image history
-	config := GetImageConfig(imageTarPath, manifest)
+	config, err := GetImageConfig(imageTarPath, manifest)
+	if err != nil {
+		return nil, nil, 0, nil, err
+	}
+	return analyzeLayers(imageID, manifest, config, layerMap, layerDigests, layerQuirks, analysis)
+}
+
+// analyzeLayers pairs the trees of the layers read from an image (by tar path) with the history of the image config,
+// and analyzes the efficiency of the image. Layers without a tree are shown by their metadata alone.
+func analyzeLayers(imageID string, manifest ImageManifest, config ImageConfig, layerMap map[string]*filetree.FileTree, layerDigests, layerQuirks map[string]string, analysis *span) ([]*Layer, []*filetree.FileTree, float64, filetree.EfficiencySlice, error) {
+	var trees = make([]*filetree.FileTree, 0)
 
 	// build the content tree
-	fmt.Println("  Building tree...")
-	for _, treeName := range manifest.LayerTarPaths {
-		trees = append(trees, layerMap[treeName])
+	printProgress("  Building tree...")
+	for idx, treeName := range manifest.LayerTarPaths {
+		tree, ok := layerMap[treeName]
+		if !ok {
+			// foreign (nondistributable) layers are left out of the archive, the rest of the image is still analyzed
+			reason := "not included in the image archive"
+			if idx < len(config.RootFs.DiffIds) {
+				if source, ok := manifest.LayerSources[config.RootFs.DiffIds[idx]]; ok {
+					reason = fmt.Sprintf("foreign layer (%s) not included in the image archive", source.MediaType)
+				}
+			}
+			tree = metadataOnlyTree(treeName, reason)
+			layerQuirks[treeName] = reason
+		}
+		trees = append(trees, tree)
 	}
-
 	// build the layers array
 	layers := make([]*Layer, len(trees))
 
@@ -305,9 +523,21 @@ func InitializeData(imageID string) ([]*Layer, []*filetree.FileTree, float64, fi
 	// as you iterate chronologically through history (ignoring history items that have no layer contents)
 	layerIdx := len(trees) - 1
 	tarPathIdx := 0
+	var lastLayer *Layer
+	var pendingChanges []MetadataChange
 	for idx := 0; idx < len(config.History); idx++ {
-		// ignore empty layers, we are only observing layers with content
+		change, isMetadata := parseMetadataChange(config.History[idx].CreatedBy)
+
+		// ignore empty layers, we are only observing layers with content. Any configuration changes they make are
+		// attributed to the preceding layer with content (or the first layer, if there is none).
 		if config.History[idx].EmptyLayer {
+			if isMetadata {
+				if lastLayer != nil {
+					lastLayer.MetadataChanges = append(lastLayer.MetadataChanges, change)
+				} else {
+					pendingChanges = append(pendingChanges, change)
+				}
+			}
 			continue
 		}
 
@@ -315,60 +545,110 @@ func InitializeData(imageID string) ([]*Layer, []*filetree.FileTree, float64, fi
 		config.History[idx].Size = uint64(tree.FileSize)
 
 		layers[layerIdx] = &Layer{
-			History:  config.History[idx],
-			Index:    layerIdx,
-			Tree:     trees[layerIdx],
-			RefTrees: trees,
-			TarPath:  manifest.LayerTarPaths[tarPathIdx],
+			History:         config.History[idx],
+			Index:           layerIdx,
+			Tree:            tree,
+			RefTrees:        trees,
+			TarPath:         manifest.LayerTarPaths[tarPathIdx],
+			Digest:          layerDigests[manifest.LayerTarPaths[tarPathIdx]],
+			MetadataChanges: pendingChanges,
+			MetadataOnly:    layerQuirks[manifest.LayerTarPaths[tarPathIdx]],
 		}
+		pendingChanges = nil
+		if isMetadata {
+			layers[layerIdx].MetadataChanges = append(layers[layerIdx].MetadataChanges, change)
+		}
+		lastLayer = layers[layerIdx]
 
 		layerIdx--
 		tarPathIdx++
 	}
 
-	fmt.Println("  Analyzing layers...")
+	printProgress("  Analyzing layers...")
+	analyze := startSpan("dive.efficiency", analysis, nil)
 	efficiency, inefficiencies := filetree.Efficiency(trees)
+	analyze.finish(nil)
 
-	return layers, trees, efficiency, inefficiencies
+	var imageSize, wastedSize uint64
+	for _, tree := range trees {
+		imageSize += tree.FileSize
+	}
+	for _, data := range inefficiencies {
+		if len(data.Nodes) > 1 {
+			wastedSize += uint64(data.CumulativeSize)
+			emitEvent(&FindingEvent{Kind: "wasted", Path: data.Path, Size: data.CumulativeSize, Occurrences: len(data.Nodes)})
+		}
+	}
+	var warnings []filetree.Warning
+	if events != nil {
+		warnings = filetree.CollectWarnings(trees)
+		for _, warning := range warnings {
+			emitEvent(&WarningEvent{Kind: warning.Kind, Layer: warning.Layer, Path: warning.Path, Message: warning.Message})
+		}
+	}
+	emitEvent(&SummaryEvent{
+		Image:      imageID,
+		Layers:     len(trees),
+		Size:       imageSize,
+		WastedSize: wastedSize,
+		Efficiency: efficiency,
+		Warnings:   len(warnings),
+	})
+	attributes := map[string]interface{}{"image.reference": imageID}
+	recordMetric("dive.image.size", "By", float64(imageSize), attributes)
+	recordMetric("dive.image.wasted", "By", float64(wastedSize), attributes)
+	recordMetric("dive.image.efficiency", "1", efficiency, attributes)
+	recordMetric("dive.image.layers", "{layer}", float64(len(trees)), attributes)
+
+	return layers, trees, efficiency, inefficiencies, nil
 }
 
-func saveImage(imageID string) (string, string) {
+func saveImage(imageID string) (string, string, error) {
 	ctx := context.Background()
-	dockerClient, err := client.NewClientWithOpts(client.WithVersion(dockerVersion))
+	dockerClient, err := newDockerClient()
 	if err != nil {
-		fmt.Println("Could not connect to the Docker daemon:" + err.Error())
-		utils.Exit(1)
+		return "", "", fmt.Errorf("could not connect to the Docker daemon: %v", err)
 	}
 
-	frame := jotframe.NewFixedFrame(0, false, false, true)
+	frame := newProgressFrame(0, false, false, true)
+	defer frame.Close()
 	line, err := frame.Append()
-	check(err)
+	if err != nil {
+		return "", "", err
+	}
 	io.WriteString(line, "  Fetching metadata...")
 
 	result, _, err := dockerClient.ImageInspectWithRaw(ctx, imageID)
+	if err != nil {
+		return "", "", fmt.Errorf("could not inspect image '%s': %v", imageID, err)
+	}
 	totalSize := result.Size
 
 	frame.Remove(line)
 	line, err = frame.Append()
-	check(err)
+	if err != nil {
+		return "", "", err
+	}
 	io.WriteString(line, "  Fetching image...")
 
 	readCloser, err := dockerClient.ImageSave(ctx, []string{imageID})
-	check(err)
+	if err != nil {
+		return "", "", fmt.Errorf("could not save image '%s': %v", imageID, err)
+	}
 	defer readCloser.Close()
 
 	tmpDir, err := ioutil.TempDir("", "dive")
-	check(err)
+	if err != nil {
+		return "", "", err
+	}
 
 	imageTarPath := filepath.Join(tmpDir, "image.tar")
 	imageFile, err := os.Create(imageTarPath)
-	check(err)
+	if err != nil {
+		return "", tmpDir, err
+	}
+	defer imageFile.Close()
 
-	defer func() {
-		if err := imageFile.Close(); err != nil {
-			logrus.Panic(err)
-		}
-	}()
 	imageWriter := bufio.NewWriter(imageFile)
 	pb := NewProgressBar(totalSize)
 
@@ -378,7 +658,7 @@ func saveImage(imageID string) (string, string) {
 	for {
 		n, err := readCloser.Read(buf)
 		if err != nil && err != io.EOF {
-			logrus.Panic(err)
+			return "", tmpDir, err
 		}
 		if n == 0 {
 			break
@@ -391,25 +671,24 @@ func saveImage(imageID string) (string, string) {
 		}
 
 		if _, err := imageWriter.Write(buf[:n]); err != nil {
-			logrus.Panic(err)
+			return "", tmpDir, err
 		}
 	}
 
 	if err = imageWriter.Flush(); err != nil {
-		logrus.Panic(err)
+		return "", tmpDir, err
 	}
 
 	pb.Done()
 	io.WriteString(line, fmt.Sprintf("  Fetching image... %s", pb.String()))
-	frame.Close()
 
-	return imageTarPath, tmpDir
+	return imageTarPath, tmpDir, nil
 }
 
-func getFileList(tarredBytes []byte) []filetree.FileInfo {
-	var files []filetree.FileInfo
-
-	reader := bytes.NewReader(tarredBytes)
+// readFileList reads the given layer tar as a stream, passing the FileInfo of each entry to the given function. The
+// issues of entries that are skipped or only partially read are noted on the given tree (of the layer).
+func readFileList(tree *filetree.FileTree, reader io.Reader, each func(filetree.FileInfo)) error {
+	layerName := tree.Name
 	tarReader := tar.NewReader(reader)
 	for {
 		header, err := tarReader.Next()
@@ -419,20 +698,27 @@ func getFileList(tarredBytes []byte) []filetree.FileInfo {
 		}
 
 		if err != nil {
-			fmt.Println(err)
-			utils.Exit(1)
+			return err
 		}
+		diagnostics.recordHeader(layerName, header)
 
 		name := header.Name
 
 		switch header.Typeflag {
-		case tar.TypeXGlobalHeader:
-			fmt.Printf("ERRG: XGlobalHeader: %v: %s\n", header.Typeflag, name)
-		case tar.TypeXHeader:
-			fmt.Printf("ERRG: XHeader: %v: %s\n", header.Typeflag, name)
+		case tar.TypeXGlobalHeader, tar.TypeXHeader:
+			tree.Warn(filetree.WarningSkippedEntry, name, "unexpected PAX header entry (type %q)", header.Typeflag)
 		default:
-			files = append(files, filetree.NewFileInfo(tarReader, header, name))
+			tree.WarnHeader(header)
+			if memory.underPressure() {
+				each(filetree.NewFileInfoFromHeader(header, name))
+				continue
+			}
+			info, err := filetree.NewFileInfo(tarReader, header, name)
+			if err != nil {
+				return err
+			}
+			each(info)
 		}
 	}
-	return files
+	return nil
 }
@@ -9,15 +9,27 @@ import (
 
 const (
 	LayerFormat = "%-25s %7s  %s"
+	// CompactLayerFormat is the LayerFormat of narrow terminals, which leaves out the ID
+	CompactLayerFormat = "%7s  %s"
+	// WideLayerFormat is the LayerFormat of wide terminals, which adds the index (counting from the oldest layer) and
+	// the digest of the layer
+	WideLayerFormat = "%5s  %-25s %-19s %7s  %s"
 )
 
 // Layer represents a Docker image layer and metadata
 type Layer struct {
-	TarPath  string
-	History  ImageHistoryEntry
-	Index    int
-	Tree     *filetree.FileTree
-	RefTrees []*filetree.FileTree
+	TarPath         string
+	History         ImageHistoryEntry
+	Index           int
+	Tree            *filetree.FileTree
+	RefTrees        []*filetree.FileTree
+	MetadataChanges []MetadataChange
+	// Digest is the digest of the uncompressed layer tar as saved by the daemon, which should match the diff_id
+	// recorded for the layer in the image config (History.ID)
+	Digest string
+	// MetadataOnly is why the contents of the layer could not be read (e.g. a foreign layer, see layerQuirk), leaving
+	// its tree empty, or "" when they were read
+	MetadataOnly string
 }
 
 // ShortId returns the truncated id of the current layer.
@@ -47,11 +59,28 @@ func (layer *Layer) ShortId() string {
 	return id
 }
 
+// Command returns the (normalized) Dockerfile instruction that created the layer (see NormalizeCommand), the raw
+// form is kept in History.CreatedBy.
+func (layer *Layer) Command() string {
+	return NormalizeCommand(layer.History.CreatedBy)
+}
+
+// ShortDigest returns the truncated digest of the layer tar (see Digest), or "-" when it is not known.
+func (layer *Layer) ShortDigest() string {
+	if layer.Digest == "" {
+		return "-"
+	}
+	if len(layer.Digest) > 19 {
+		return layer.Digest[:19]
+	}
+	return layer.Digest
+}
+
 // String represents a layer in a columnar format.
 func (layer *Layer) String() string {
 
 	return fmt.Sprintf(LayerFormat,
 		layer.ShortId(),
 		humanize.Bytes(uint64(layer.History.Size)),
-		strings.TrimPrefix(layer.History.CreatedBy, "/bin/sh -c "))
+		layer.Command())
 }
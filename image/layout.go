@@ -0,0 +1,226 @@
+package image
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/wagoodman/dive/filetree"
+)
+
+// LayoutPrefix marks an image reference as an OCI image layout directory (as written by "skopeo copy ... oci:DIR" or
+// "buildah push ... oci:DIR"), which is read without a Docker daemon: "oci:DIR", or "oci:DIR:REF" to select the
+// manifest named REF when the layout holds several.
+const LayoutPrefix = "oci:"
+
+const (
+	// ociImageConfigMediaType is the media type of the config of an OCI image
+	ociImageConfigMediaType = "application/vnd.oci.image.config.v1+json"
+	// dockerImageConfigMediaType is the media type of the config of a Docker image (kept by skopeo for Docker images)
+	dockerImageConfigMediaType = "application/vnd.docker.container.image.v1+json"
+)
+
+// splitLayoutReference splits an "oci:DIR[:REF]" image reference into the layout directory and the manifest name
+// (which is "" when omitted). A directory whose name holds a colon is taken as a whole when it exists.
+func splitLayoutReference(imageID string) (string, string) {
+	spec := strings.TrimPrefix(imageID, LayoutPrefix)
+	if info, err := os.Stat(spec); err == nil && info.IsDir() {
+		return spec, ""
+	}
+	idx := strings.LastIndex(spec, ":")
+	if idx <= 0 || strings.ContainsAny(spec[idx+1:], `/\`) {
+		return spec, ""
+	}
+	return spec[:idx], spec[idx+1:]
+}
+
+// blobSource provides the blobs of an image, e.g. from an OCI image layout directory or a registry.
+type blobSource interface {
+	// openBlob opens the blob of the given descriptor, returning an error satisfying os.IsNotExist when it is missing
+	openBlob(descriptor ociDescriptor) (io.ReadCloser, error)
+}
+
+// layoutBlobs are the blobs of an OCI image layout directory.
+type layoutBlobs string
+
+func (layoutDir layoutBlobs) openBlob(descriptor ociDescriptor) (io.ReadCloser, error) {
+	path, err := blobPath(string(layoutDir), descriptor.Digest)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// loadLayoutImage reads the image of the given "oci:DIR[:REF]" reference from an OCI image layout directory.
+func loadLayoutImage(imageID string, analysis *span) ([]*Layer, []*filetree.FileTree, float64, filetree.EfficiencySlice, error) {
+	layoutDir, reference := splitLayoutReference(imageID)
+	descriptor, err := resolveLayoutManifest(layoutDir, reference)
+	if err != nil {
+		return nil, nil, 0, nil, err
+	}
+	var ociImage ociManifest
+	if err := readLayoutJSON(layoutDir, descriptor.Digest, &ociImage); err != nil {
+		return nil, nil, 0, nil, err
+	}
+	return loadManifestImage(imageID, layoutBlobs(layoutDir), ociImage, analysis)
+}
+
+// loadManifestImage reads the image of the given OCI (or Docker v2) manifest from the given blobs, building a tree for
+// each layer like loadImage does for an image saved by the Docker daemon. Foreign layers, whose blobs are not copied by
+// default, and blobs that are no layer tars are shown by their metadata alone, other missing blobs fail the analysis.
+func loadManifestImage(imageID string, source blobSource, ociImage ociManifest, analysis *span) ([]*Layer, []*filetree.FileTree, float64, filetree.EfficiencySlice, error) {
+	if mediaType := ociImage.Config.MediaType; mediaType != ociImageConfigMediaType && mediaType != dockerImageConfigMediaType {
+		return nil, nil, 0, nil, fmt.Errorf("%s is not a container image (config of type '%s'), see 'dive artifact'", imageID, mediaType)
+	}
+	for idx := range selectedLayers {
+		if idx >= len(ociImage.Layers) {
+			return nil, nil, 0, nil, fmt.Errorf("layer %d does not exist (the image has %d layers)", idx, len(ociImage.Layers))
+		}
+	}
+
+	printProgress("  Fetching image config...")
+	configBytes, err := readBlob(source, ociImage.Config)
+	if err != nil {
+		return nil, nil, 0, nil, err
+	}
+	diagnostics.recordFile(ociImage.Config.Digest, configBytes)
+	config, err := parseImageConfig(configBytes)
+	if err != nil {
+		return nil, nil, 0, nil, fmt.Errorf("could not read image config: %v", err)
+	}
+
+	manifest := ImageManifest{ConfigPath: ociImage.Config.Digest}
+	layerMap := make(map[string]*filetree.FileTree)
+	layerDigests := make(map[string]string)
+	layerQuirks := make(map[string]string)
+
+	frame := newProgressFrame(1, true, false, false)
+	var lastLine io.WriteCloser = discardLine{}
+	if lines := frame.Lines(); len(lines) > 0 {
+		lastLine = lines[0]
+	}
+	io.WriteString(lastLine, "    ╧")
+	lastLine.Close()
+
+	for idx, layer := range ociImage.Layers {
+		name := layer.Digest
+		if _, err := blobPath("", name); err != nil || len(name) < 15 {
+			return nil, nil, 0, nil, fmt.Errorf("invalid blob digest '%s' of layer %d", name, idx)
+		}
+		manifest.LayerTarPaths = append(manifest.LayerTarPaths, name)
+		io.WriteString(frame.Header(), fmt.Sprintf("  Discovering layers... %d/%d", idx+1, len(ociImage.Layers)))
+		if selectedLayers != nil && !selectedLayers[idx] {
+			// skip layers that were not selected for analysis, leaving them empty
+			tree := filetree.NewFileTree()
+			tree.Name = name
+			layerMap[name] = tree
+			continue
+		}
+
+		line, err := frame.Prepend()
+		if err != nil {
+			return nil, nil, 0, nil, err
+		}
+		io.WriteString(line, "    ├─ "+name[:15]+" : loading...")
+		emitEvent(&LayerStartedEvent{Layer: name, Size: layer.Size})
+
+		parse := startSpan("dive.layer", analysis, map[string]interface{}{"layer": name, "layer.size": layer.Size})
+		tree, digest, reason, err := readLayerBlob(line, source, layer)
+		parse.finish(err)
+		if err != nil {
+			return nil, nil, 0, nil, err
+		}
+		if reason != "" {
+			tree = metadataOnlyTree(name, reason)
+			layerQuirks[name] = reason
+		}
+		layerMap[name] = tree
+		layerDigests[name] = digest
+	}
+	io.WriteString(frame.Header(), "  Discovering layers... Done!")
+	frame.Header().Close()
+	frame.Wait()
+	frame.Remove(lastLine)
+	printProgress("")
+
+	return analyzeLayers(imageID, manifest, config, layerMap, layerDigests, layerQuirks, analysis)
+}
+
+// readBlob reads the whole (small) blob of the given descriptor, such as a manifest or a config.
+func readBlob(source blobSource, descriptor ociDescriptor) ([]byte, error) {
+	blob, err := source.openBlob(descriptor)
+	if err != nil {
+		return nil, fmt.Errorf("could not open blob %s: %v", descriptor.Digest, err)
+	}
+	defer blob.Close()
+	contents, err := ioutil.ReadAll(blob)
+	if err != nil {
+		return nil, fmt.Errorf("could not read blob %s: %v", descriptor.Digest, err)
+	}
+	return contents, nil
+}
+
+// readLayerBlob builds the tree of the layer of the given descriptor from its (possibly gzip compressed) blob, reading
+// it as a stream and showing the progress on the given line, and returns the digest of the uncompressed layer tar. The
+// blob is verified against the digest of the descriptor. When the blob is no layer tar, or is missing as foreign layers
+// may be, the tree is nil and why is returned instead (see layerQuirk and missingBlobQuirk).
+func readLayerBlob(line io.WriteCloser, source blobSource, descriptor ociDescriptor) (*filetree.FileTree, string, string, error) {
+	shortName := descriptor.Digest[:15]
+	file, err := source.openBlob(descriptor)
+	if os.IsNotExist(err) {
+		reason := missingBlobQuirk(descriptor)
+		if reason == "" {
+			line.Close()
+			return nil, "", "", fmt.Errorf("layer %s is missing from the image: %v", descriptor.Digest, err)
+		}
+		io.WriteString(line, "    ├─ "+shortName+" : metadata only ("+reason+")")
+		line.Close()
+		return nil, "", reason, nil
+	}
+	if err != nil {
+		line.Close()
+		return nil, "", "", fmt.Errorf("could not open layer %s: %v", descriptor.Digest, err)
+	}
+	defer file.Close()
+
+	blobDigest := sha256.New()
+	blob := bufio.NewReader(io.TeeReader(file, blobDigest))
+	content, err := uncompressed(blob)
+	if err != nil {
+		line.Close()
+		return nil, "", "", fmt.Errorf("could not read layer %s: %v", descriptor.Digest, err)
+	}
+	// the size of the layer tar is only known when the blob is not compressed
+	size := int64(-1)
+	if content == blob {
+		size = descriptor.Size
+	}
+
+	digest := sha256.New()
+	head, _ := content.Peek(1024)
+	stream := io.TeeReader(content, digest)
+	if reason := layerQuirk(head, descriptor.Size); reason != "" {
+		io.WriteString(line, "    ├─ "+shortName+" : metadata only ("+reason+")")
+		line.Close()
+		return nil, "", reason, nil
+	}
+	tree, err := processLayerTar(line, descriptor.Digest, stream, size)
+	if err != nil {
+		return nil, "", "", err
+	}
+	// the layer tar may be padded beyond its end-of-archive marker, which is part of its digest, and so may the blob
+	if _, err = io.Copy(ioutil.Discard, stream); err != nil {
+		return nil, "", "", fmt.Errorf("could not read layer %s: %v", descriptor.Digest, err)
+	}
+	if _, err = io.Copy(ioutil.Discard, blob); err != nil {
+		return nil, "", "", fmt.Errorf("could not read layer %s: %v", descriptor.Digest, err)
+	}
+	if strings.HasPrefix(descriptor.Digest, "sha256:") && fmt.Sprintf("sha256:%x", blobDigest.Sum(nil)) != descriptor.Digest {
+		return nil, "", "", fmt.Errorf("layer %s does not match its digest (the blob is corrupt)", descriptor.Digest)
+	}
+	return tree, fmt.Sprintf("sha256:%x", digest.Sum(nil)), "", nil
+}
@@ -0,0 +1,187 @@
+package image
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testImage is an OCI image held in memory, to be written as an OCI image layout or served by a registry.
+type testImage struct {
+	// blobs are the blobs of the image by digest, including the manifest
+	blobs    map[string][]byte
+	manifest ociManifest
+	// manifestDigest is the digest of the manifest blob
+	manifestDigest string
+}
+
+// layerTar returns a layer tar holding the given files (by path), in path order.
+func layerTar(t *testing.T, files ...string) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer := tar.NewWriter(&buffer)
+	for _, path := range files {
+		contents := "contents of " + path
+		header := &tar.Header{Name: path, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(contents))}
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		writer.Write([]byte(contents))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+// gzipped compresses the given blob.
+func gzipped(t *testing.T, blob []byte) []byte {
+	t.Helper()
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	writer.Write(blob)
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func blobDigest(blob []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(blob))
+}
+
+// newTestImage builds an image of the given layer tars (oldest first), each created by a RUN instruction. The layers
+// are gzip compressed.
+func newTestImage(t *testing.T, layers ...[]byte) *testImage {
+	t.Helper()
+	image := &testImage{blobs: make(map[string][]byte)}
+	var config ImageConfig
+	config.RootFs.Type = "layers"
+	for idx, layer := range layers {
+		config.RootFs.DiffIds = append(config.RootFs.DiffIds, blobDigest(layer))
+		config.History = append(config.History, ImageHistoryEntry{CreatedBy: fmt.Sprintf("RUN step %d", idx)})
+		image.manifest.Layers = append(image.manifest.Layers, image.addBlob(t, "application/vnd.oci.image.layer.v1.tar+gzip", gzipped(t, layer)))
+	}
+	configBlob, err := json.Marshal(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image.manifest.MediaType = "application/vnd.oci.image.manifest.v1+json"
+	image.manifest.Config = image.addBlob(t, ociImageConfigMediaType, configBlob)
+	image.updateManifest(t)
+	return image
+}
+
+// addBlob adds the given blob to the image, returning its descriptor.
+func (image *testImage) addBlob(t *testing.T, mediaType string, blob []byte) ociDescriptor {
+	digest := blobDigest(blob)
+	image.blobs[digest] = blob
+	return ociDescriptor{MediaType: mediaType, Digest: digest, Size: int64(len(blob))}
+}
+
+// updateManifest stores the manifest of the image again (after changing it).
+func (image *testImage) updateManifest(t *testing.T) {
+	t.Helper()
+	delete(image.blobs, image.manifestDigest)
+	blob, err := json.Marshal(image.manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	image.manifestDigest = image.addBlob(t, image.manifest.MediaType, blob).Digest
+}
+
+// writeLayout writes the image as an OCI image layout to a new temporary directory, naming it "latest".
+func (image *testImage) writeLayout(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "dive-layout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	if err := os.MkdirAll(filepath.Join(dir, "blobs", "sha256"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for digest, blob := range image.blobs {
+		path, _ := blobPath(dir, digest)
+		if err := ioutil.WriteFile(path, blob, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	index := ociIndex{Manifests: []ociDescriptor{{
+		MediaType:   image.manifest.MediaType,
+		Digest:      image.manifestDigest,
+		Size:        int64(len(image.blobs[image.manifestDigest])),
+		Annotations: map[string]string{ociRefAnnotation: "latest"},
+	}}}
+	indexBlob, _ := json.Marshal(index)
+	ioutil.WriteFile(filepath.Join(dir, "index.json"), indexBlob, 0644)
+	ioutil.WriteFile(filepath.Join(dir, "oci-layout"), []byte(`{"imageLayoutVersion":"1.0.0"}`), 0644)
+	return dir
+}
+
+// quietly suppresses the progress output of loading images for the duration of the test.
+func quietly(t *testing.T) {
+	quiet := Quiet
+	Quiet = true
+	t.Cleanup(func() { Quiet = quiet })
+}
+
+func TestSplitLayoutReference(t *testing.T) {
+	cases := []struct {
+		reference, dir, name string
+	}{
+		{"oci:/tmp/layout", "/tmp/layout", ""},
+		{"oci:/tmp/layout:v1", "/tmp/layout", "v1"},
+		{"oci:layout:v1.2", "layout", "v1.2"},
+		{"oci:/tmp/a:b/layout", "/tmp/a:b/layout", ""},
+	}
+	for _, test := range cases {
+		dir, name := splitLayoutReference(test.reference)
+		if dir != test.dir || name != test.name {
+			t.Errorf("%s: expected %q and %q, got %q and %q", test.reference, test.dir, test.name, dir, name)
+		}
+	}
+}
+
+func TestLoadLayoutImage(t *testing.T) {
+	quietly(t)
+	lower := layerTar(t, "etc/hosts", "usr/bin/app")
+	upper := layerTar(t, "etc/hosts")
+	dir := newTestImage(t, lower, upper).writeLayout(t)
+
+	for _, reference := range []string{LayoutPrefix + dir, LayoutPrefix + dir + ":latest"} {
+		layers, trees, _, inefficiencies, err := LoadImage(reference)
+		if err != nil {
+			t.Fatalf("%s: could not load the image: %v", reference, err)
+		}
+		if len(layers) != 2 || len(trees) != 2 {
+			t.Fatalf("Expected 2 layers, got %d (and %d trees)", len(layers), len(trees))
+		}
+		// layers are newest first
+		if layers[0].History.CreatedBy != "RUN step 1" || layers[0].Tree != trees[1] {
+			t.Errorf("Expected the newest layer first, got %q", layers[0].History.CreatedBy)
+		}
+		for idx, layer := range layers {
+			if layer.Digest != layer.History.ID {
+				t.Errorf("Expected layer %d to match its diff_id %s, got %s", idx, layer.History.ID, layer.Digest)
+			}
+		}
+		if _, err := trees[0].GetNode("/usr/bin/app"); err != nil {
+			t.Errorf("Expected /usr/bin/app in the first layer: %v", err)
+		}
+		if len(inefficiencies) != 1 || inefficiencies[0].Path != "/etc/hosts" || len(inefficiencies[0].Nodes) != 2 {
+			t.Errorf("Expected /etc/hosts to be wasted, got %+v", inefficiencies)
+		}
+	}
+
+	if _, _, _, _, err := LoadImage(LayoutPrefix + dir + ":missing"); err == nil {
+		t.Errorf("Expected an error for a missing manifest name")
+	}
+}
@@ -0,0 +1,138 @@
+package image
+
+import (
+	"archive/tar"
+	"fmt"
+	"path"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/wagoodman/dive/filetree"
+)
+
+const (
+	// memoryDegradeRatio is the share of the memory limit at which analysis starts to degrade
+	memoryDegradeRatio = 0.8
+	// memoryCheckInterval is the number of files read between samples of the heap size
+	memoryCheckInterval = 1000
+	// AggregateFileSize is the size at or below which files are aggregated per directory once analysis is degraded
+	AggregateFileSize = 64 * 1024
+)
+
+// memoryGauge tracks the heap size while images are loaded, degrading the analysis when a limit is approached rather
+// than running out of memory.
+type memoryGauge struct {
+	lock     sync.Mutex
+	limit    uint64
+	reads    int
+	degraded bool
+}
+
+var memory memoryGauge
+
+// SetMemoryLimit caps the memory used while loading images (zero for no limit). When the heap approaches the limit, the
+// analysis degrades gracefully: file contents are no longer read (so no digests, entropy or licenses are captured) and
+// small files are aggregated into a single entry per directory.
+func SetMemoryLimit(limit uint64) {
+	memory.lock.Lock()
+	defer memory.lock.Unlock()
+	memory.limit = limit
+}
+
+// Degraded indicates if the analysis of any image was degraded to stay within the memory limit, such that the results
+// are partial.
+func Degraded() bool {
+	memory.lock.Lock()
+	defer memory.lock.Unlock()
+	return memory.degraded
+}
+
+// reset starts a new analysis, which is not degraded until the heap approaches the limit again.
+func (gauge *memoryGauge) reset() {
+	gauge.lock.Lock()
+	defer gauge.lock.Unlock()
+	gauge.degraded = false
+	gauge.reads = 0
+}
+
+// markDegraded notes that an analysis was degraded elsewhere (e.g. by the cache server), such that the results are
+// partial.
+func (gauge *memoryGauge) markDegraded() {
+	gauge.lock.Lock()
+	defer gauge.lock.Unlock()
+	gauge.degraded = true
+}
+
+// underPressure indicates if the analysis should be degraded, sampling the heap size every memoryCheckInterval calls.
+// Once degraded, the analysis stays degraded (until the next analysis of the cache server, see reset).
+func (gauge *memoryGauge) underPressure() bool {
+	gauge.lock.Lock()
+	defer gauge.lock.Unlock()
+	if gauge.degraded || gauge.limit == 0 {
+		return gauge.degraded
+	}
+	gauge.reads++
+	if gauge.reads%memoryCheckInterval != 0 {
+		return false
+	}
+
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	if float64(stats.HeapAlloc) < memoryDegradeRatio*float64(gauge.limit) {
+		return false
+	}
+	gauge.degraded = true
+	logrus.Warnf("heap size (%d bytes) is approaching the memory limit (%d bytes), degrading analysis", stats.HeapAlloc, gauge.limit)
+	printProgress("  Memory limit approached: skipping file contents and aggregating small files (results are partial)")
+	emitEvent(&FindingEvent{Kind: "degraded", Limit: gauge.limit, Heap: stats.HeapAlloc})
+	return true
+}
+
+// aggregateEntry stands in for the small files of a directory once the analysis is degraded.
+type aggregateEntry struct {
+	dir   string
+	count int
+	size  int64
+	mtime time.Time
+}
+
+// isAggregated indicates if the given file is folded into its directory's aggregate entry, which is the case for small
+// regular files once the analysis is degraded.
+func isAggregated(info filetree.FileInfo) bool {
+	return Degraded() && info.TypeFlag == tar.TypeReg && info.TarHeader.Size <= AggregateFileSize &&
+		!strings.HasPrefix(path.Base(info.Path), ".wh.")
+}
+
+// aggregate folds the given file into the aggregate entry of its directory.
+func aggregate(aggregates map[string]*aggregateEntry, info filetree.FileInfo) {
+	dir := path.Dir(path.Clean("/" + info.Path))
+	entry, ok := aggregates[dir]
+	if !ok {
+		entry = &aggregateEntry{dir: dir}
+		aggregates[dir] = entry
+	}
+	entry.count++
+	entry.size += info.TarHeader.Size
+	if info.TarHeader.ModTime.After(entry.mtime) {
+		entry.mtime = info.TarHeader.ModTime
+	}
+}
+
+// FileInfo returns the entry as a file within its directory (e.g. "/usr/share/doc/[120 small files]").
+func (entry *aggregateEntry) FileInfo() filetree.FileInfo {
+	entryPath := path.Join(entry.dir, fmt.Sprintf("[%d small files]", entry.count))
+	return filetree.FileInfo{
+		Path:     entryPath,
+		TypeFlag: tar.TypeReg,
+		TarHeader: tar.Header{
+			Name:     entryPath,
+			Typeflag: tar.TypeReg,
+			Mode:     0644,
+			Size:     entry.size,
+			ModTime:  entry.mtime,
+		},
+	}
+}
@@ -0,0 +1,143 @@
+package image
+
+import (
+	"sort"
+	"strings"
+)
+
+// metadataInstructions are the Dockerfile instructions that change the image configuration but not the filesystem.
+var metadataInstructions = []string{"ENV", "EXPOSE", "VOLUME", "WORKDIR", "USER", "LABEL", "ENTRYPOINT", "CMD", "STOPSIGNAL", "HEALTHCHECK", "SHELL", "ARG", "ONBUILD"}
+
+// MetadataChange is a single change to the image configuration (e.g. ENV, EXPOSE, VOLUME, WORKDIR) made by a
+// history entry.
+type MetadataChange struct {
+	Instruction string
+	Value       string
+}
+
+// String shows the change as the Dockerfile instruction that made it.
+func (change MetadataChange) String() string {
+	return change.Instruction + " " + change.Value
+}
+
+// parseMetadataChange extracts the configuration change from a history entry's created_by string, supporting both the
+// classic builder ("/bin/sh -c #(nop)  ENV A=b") and BuildKit ("ENV A=b") forms.
+func parseMetadataChange(createdBy string) (MetadataChange, bool) {
+	command := NormalizeCommand(createdBy)
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return MetadataChange{}, false
+	}
+	for _, instruction := range metadataInstructions {
+		if fields[0] == instruction {
+			return MetadataChange{
+				Instruction: instruction,
+				Value:       strings.TrimSpace(strings.TrimPrefix(command, fields[0])),
+			}, true
+		}
+	}
+	return MetadataChange{}, false
+}
+
+// parseVolumes extracts the mount points of a VOLUME instruction value, which may be in the JSON form
+// (["/data", "/logs"]), the form recorded by the classic builder ([/data /logs]), or the plain form (/data /logs).
+func parseVolumes(value string) []string {
+	value = strings.TrimSpace(value)
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	var volumes []string
+	for _, field := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' }) {
+		if volume := strings.Trim(field, `"'`); volume != "" {
+			volumes = append(volumes, volume)
+		}
+	}
+	return volumes
+}
+
+// Volumes lists the (unique) mount points declared by VOLUME instructions in any of the given layers.
+func Volumes(layers []*Layer) []string {
+	seen := make(map[string]bool)
+	var volumes []string
+	for _, layer := range layers {
+		for _, change := range layer.MetadataChanges {
+			if change.Instruction != "VOLUME" {
+				continue
+			}
+			for _, volume := range parseVolumes(change.Value) {
+				if !seen[volume] {
+					seen[volume] = true
+					volumes = append(volumes, volume)
+				}
+			}
+		}
+	}
+	sort.Strings(volumes)
+	return volumes
+}
+
+// Labels collects the labels set by LABEL instructions in the given layers (newest first, as loaded), where a label
+// set by a later layer replaces the same label set by an earlier one.
+func Labels(layers []*Layer) map[string]string {
+	labels := make(map[string]string)
+	for idx := len(layers) - 1; idx >= 0; idx-- {
+		for _, change := range layers[idx].MetadataChanges {
+			if change.Instruction != "LABEL" {
+				continue
+			}
+			for key, value := range parseLabels(change.Value) {
+				labels[key] = value
+			}
+		}
+	}
+	return labels
+}
+
+// parseLabels extracts the labels of a LABEL instruction value, either in the key=value form (a="b c" d=e, where
+// values may be quoted) or in the legacy form of a single label (key value).
+func parseLabels(value string) map[string]string {
+	labels := make(map[string]string)
+	fields := splitQuoted(value)
+	if len(fields) > 0 && !strings.Contains(fields[0], "=") {
+		rest := strings.TrimPrefix(strings.TrimSpace(value), fields[0])
+		labels[unquote(fields[0])] = unquote(strings.TrimSpace(rest))
+		return labels
+	}
+	for _, field := range fields {
+		if pos := strings.Index(field, "="); pos > 0 {
+			labels[unquote(field[:pos])] = unquote(field[pos+1:])
+		}
+	}
+	return labels
+}
+
+// splitQuoted splits the value on whitespace that is not within quotes.
+func splitQuoted(value string) []string {
+	var fields []string
+	var current strings.Builder
+	var quote rune
+	for _, char := range value {
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '"' || char == '\'':
+			quote = char
+		case char == ' ' || char == '\t':
+			if current.Len() > 0 {
+				fields = append(fields, current.String())
+				current.Reset()
+			}
+			continue
+		}
+		current.WriteRune(char)
+	}
+	if current.Len() > 0 {
+		fields = append(fields, current.String())
+	}
+	return fields
+}
+
+// unquote drops the quotes around (or within) the value.
+func unquote(value string) string {
+	return strings.NewReplacer(`"`, "", `'`, "").Replace(value)
+}
@@ -0,0 +1,102 @@
+package image
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMetadataChange(t *testing.T) {
+	cases := []struct {
+		createdBy string
+		expected  MetadataChange
+		ok        bool
+	}{
+		{`/bin/sh -c #(nop)  ENV PATH=/usr/local/bin:/usr/bin`, MetadataChange{"ENV", "PATH=/usr/local/bin:/usr/bin"}, true},
+		{`ENV GOLANG_VERSION=1.22 GOPATH=/go # buildkit`, MetadataChange{"ENV", "GOLANG_VERSION=1.22 GOPATH=/go"}, true},
+		{`/bin/sh -c #(nop)  LABEL maintainer=me@example.com`, MetadataChange{"LABEL", "maintainer=me@example.com"}, true},
+		{`LABEL org.opencontainers.image.title="my app" version=1.0`, MetadataChange{"LABEL", `org.opencontainers.image.title="my app" version=1.0`}, true},
+		{`/bin/sh -c #(nop)  USER app:app`, MetadataChange{"USER", "app:app"}, true},
+		{`USER 1000`, MetadataChange{"USER", "1000"}, true},
+		{`/bin/sh -c #(nop) WORKDIR /app`, MetadataChange{"WORKDIR", "/app"}, true},
+		{`WORKDIR /srv/www # buildkit`, MetadataChange{"WORKDIR", "/srv/www"}, true},
+		{`/bin/sh -c #(nop)  EXPOSE 80/tcp 443/tcp`, MetadataChange{"EXPOSE", "80/tcp 443/tcp"}, true},
+		{`EXPOSE map[8080/tcp:{}]`, MetadataChange{"EXPOSE", "map[8080/tcp:{}]"}, true},
+		{`/bin/sh -c #(nop)  VOLUME [/data]`, MetadataChange{"VOLUME", "[/data]"}, true},
+		{`/bin/sh -c #(nop)  CMD ["nginx" "-g" "daemon off;"]`, MetadataChange{"CMD", `["nginx" "-g" "daemon off;"]`}, true},
+		{`ARG VERSION`, MetadataChange{"ARG", "VERSION"}, true},
+		{`/bin/sh -c #(nop)  ENV`, MetadataChange{"ENV", ""}, true},
+		// filesystem changes and malformed entries are no metadata changes
+		{`/bin/sh -c apt-get update`, MetadataChange{}, false},
+		{`RUN /bin/sh -c echo ENV A=b # buildkit`, MetadataChange{}, false},
+		{`|1 USER=app /bin/sh -c useradd $USER`, MetadataChange{}, false},
+		{`/bin/sh -c #(nop) COPY file:abc in /app`, MetadataChange{}, false},
+		{`/bin/sh -c #(nop) `, MetadataChange{}, false},
+		{`ENVIRONMENT=production`, MetadataChange{}, false},
+		{`env A=b`, MetadataChange{}, false},
+		{``, MetadataChange{}, false},
+		{`   `, MetadataChange{}, false},
+	}
+	for _, test := range cases {
+		change, ok := parseMetadataChange(test.createdBy)
+		if ok != test.ok || change != test.expected {
+			t.Errorf("%q: expected %+v (%v), got %+v (%v)", test.createdBy, test.expected, test.ok, change, ok)
+		}
+	}
+}
+
+func TestParseVolumes(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected []string
+	}{
+		{`["/data", "/logs"]`, []string{"/data", "/logs"}},
+		{`[/data /logs]`, []string{"/data", "/logs"}},
+		{`/data /logs`, []string{"/data", "/logs"}},
+		{`'/data'`, []string{"/data"}},
+		{`[]`, nil},
+		{``, nil},
+	}
+	for _, test := range cases {
+		if volumes := parseVolumes(test.value); !reflect.DeepEqual(volumes, test.expected) {
+			t.Errorf("%q: expected %v, got %v", test.value, test.expected, volumes)
+		}
+	}
+}
+
+func TestParseLabels(t *testing.T) {
+	cases := []struct {
+		value    string
+		expected map[string]string
+	}{
+		{`a=b c=d`, map[string]string{"a": "b", "c": "d"}},
+		{`title="my app" "quoted key"=value`, map[string]string{"title": "my app", "quoted key": "value"}},
+		{`description='single quoted'`, map[string]string{"description": "single quoted"}},
+		{`maintainer Jane Doe <jane@example.com>`, map[string]string{"maintainer": "Jane Doe <jane@example.com>"}},
+		{`empty=`, map[string]string{"empty": ""}},
+		{`=value`, map[string]string{}},
+		{``, map[string]string{}},
+	}
+	for _, test := range cases {
+		if labels := parseLabels(test.value); !reflect.DeepEqual(labels, test.expected) {
+			t.Errorf("%q: expected %v, got %v", test.value, test.expected, labels)
+		}
+	}
+}
+
+func TestVolumesAndLabels(t *testing.T) {
+	layerOf := func(changes ...MetadataChange) *Layer {
+		return &Layer{MetadataChanges: changes}
+	}
+	// newest first, as loaded
+	layers := []*Layer{
+		layerOf(MetadataChange{"LABEL", "version=2"}, MetadataChange{"VOLUME", "[/data /cache]"}),
+		layerOf(MetadataChange{"ENV", "A=b"}),
+		layerOf(MetadataChange{"LABEL", "version=1 vendor=acme"}, MetadataChange{"VOLUME", `["/data"]`}),
+	}
+	if volumes := Volumes(layers); !reflect.DeepEqual(volumes, []string{"/cache", "/data"}) {
+		t.Errorf("Expected the unique volumes, got %v", volumes)
+	}
+	if labels := Labels(layers); !reflect.DeepEqual(labels, map[string]string{"version": "2", "vendor": "acme"}) {
+		t.Errorf("Expected the newest labels to win, got %v", labels)
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package image
+
+import (
+	"os"
+	"syscall"
+)
+
+// ownedByCurrentUser indicates if the file of the given info belongs to the user running dive.
+func ownedByCurrentUser(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(stat.Uid) == os.Getuid()
+}
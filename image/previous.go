@@ -0,0 +1,46 @@
+package image
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/wagoodman/dive/utils"
+)
+
+// PreviousTag finds the build of the same repository that precedes the given image: the most recently created local
+// image of the repository, created before the given image, that is a different image. This is typically the prior
+// release (or the prior "latest") of the image.
+func PreviousTag(reference string) (string, error) {
+	family := Family(reference)
+	if family == reference {
+		reference += ":latest"
+	}
+
+	// images are listed newest first
+	output, err := utils.DockerCmdOutput("image", "ls", family, "--format", "{{.Repository}}:{{.Tag}}\t{{.ID}}")
+	if err != nil {
+		return "", fmt.Errorf("could not list the images of %s: %v", family, err)
+	}
+
+	var currentID string
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 2 || strings.HasSuffix(fields[0], ":<none>") {
+			continue
+		}
+		tag, id := fields[0], fields[1]
+		switch {
+		case currentID == "" && tag == reference:
+			currentID = id
+		case currentID != "" && id != currentID:
+			return tag, nil
+		}
+	}
+	if currentID == "" {
+		return "", fmt.Errorf("could not find %s among the local images", reference)
+	}
+	return "", fmt.Errorf("there is no earlier build of %s among the local images", reference)
+}
@@ -0,0 +1,97 @@
+package image
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/wagoodman/jotframe"
+)
+
+// Quiet suppresses all progress output while an image is loaded, for commands that write machine readable output to
+// stdout.
+var Quiet bool
+
+// discardLine stands in for a progress line when progress output is suppressed.
+type discardLine struct{}
+
+func (discardLine) Write(p []byte) (int, error) { return len(p), nil }
+func (discardLine) Close() error                { return nil }
+
+// progressFrame shows the progress of loading an image on the terminal (or nothing at all when Quiet).
+type progressFrame struct {
+	frame *jotframe.FixedFrame
+}
+
+// newProgressFrame creates a terminal frame with the given number of lines (see jotframe.NewFixedFrame).
+func newProgressFrame(lines int, hasHeader, hasFooter, trailOnRemove bool) *progressFrame {
+	if Quiet {
+		return &progressFrame{}
+	}
+	return &progressFrame{frame: jotframe.NewFixedFrame(lines, hasHeader, hasFooter, trailOnRemove)}
+}
+
+// Lines returns the initial lines of the frame.
+func (progress *progressFrame) Lines() []io.WriteCloser {
+	var lines []io.WriteCloser
+	if progress.frame == nil {
+		return lines
+	}
+	for _, line := range progress.frame.Lines() {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Header returns the header line of the frame.
+func (progress *progressFrame) Header() io.WriteCloser {
+	if progress.frame == nil {
+		return discardLine{}
+	}
+	return progress.frame.Header()
+}
+
+// Prepend adds a line to the top of the frame.
+func (progress *progressFrame) Prepend() (io.WriteCloser, error) {
+	if progress.frame == nil {
+		return discardLine{}, nil
+	}
+	return progress.frame.Prepend()
+}
+
+// Append adds a line to the bottom of the frame.
+func (progress *progressFrame) Append() (io.WriteCloser, error) {
+	if progress.frame == nil {
+		return discardLine{}, nil
+	}
+	return progress.frame.Append()
+}
+
+// Remove takes the given line out of the frame.
+func (progress *progressFrame) Remove(line io.WriteCloser) error {
+	if jotLine, ok := line.(*jotframe.Line); ok && progress.frame != nil {
+		return progress.frame.Remove(jotLine)
+	}
+	return nil
+}
+
+// Wait blocks until all lines of the frame are closed.
+func (progress *progressFrame) Wait() {
+	if progress.frame != nil {
+		progress.frame.Wait()
+	}
+}
+
+// Close stops any further updates to the frame.
+func (progress *progressFrame) Close() error {
+	if progress.frame != nil {
+		return progress.frame.Close()
+	}
+	return nil
+}
+
+// printProgress prints a progress message on its own line (unless Quiet).
+func printProgress(message string) {
+	if !Quiet {
+		fmt.Println(message)
+	}
+}
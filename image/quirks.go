@@ -0,0 +1,66 @@
+package image
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/wagoodman/dive/filetree"
+)
+
+// LayerSource describes a layer that is stored elsewhere (such as a foreign, nondistributable layer of a Windows base
+// image), as recorded by "docker save" in the image manifest.
+type LayerSource struct {
+	MediaType string   `json:"mediaType"`
+	Size      int64    `json:"size"`
+	URLs      []string `json:"urls,omitempty"`
+}
+
+// layerQuirk returns why the layer tar of the given size, starting with the given bytes (at least the first 512), cannot
+// be read as a filesystem diff, or "" when it can. Some platforms and registries leave zero-size placeholder blobs or
+// blobs of other media types (e.g. still compressed) in place of the layer tar.
+func layerQuirk(head []byte, size int64) string {
+	switch {
+	case size == 0:
+		return "zero-size placeholder blob"
+	case isTar(head) || isEmptyTar(head):
+		return ""
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return "foreign media type (application/gzip)"
+	}
+	return fmt.Sprintf("foreign media type (%s)", contentType(head))
+}
+
+// nondistributableMediaTypes are the media types of layers that registries and "skopeo copy" leave out by default
+// (foreign layers, such as the base layers of Windows images), which are to be fetched from elsewhere.
+var nondistributableMediaTypes = map[string]bool{
+	"application/vnd.docker.image.rootfs.foreign.diff.tar.gzip":    true,
+	"application/vnd.oci.image.layer.nondistributable.v1.tar":      true,
+	"application/vnd.oci.image.layer.nondistributable.v1.tar+gzip": true,
+	"application/vnd.oci.image.layer.nondistributable.v1.tar+zstd": true,
+}
+
+// missingBlobQuirk returns why the blob of the given layer may be missing without the image being broken, or "" when
+// the blob should have been there: only nondistributable layers and zero-size placeholders may be left out.
+func missingBlobQuirk(descriptor ociDescriptor) string {
+	switch {
+	case nondistributableMediaTypes[descriptor.MediaType]:
+		return fmt.Sprintf("foreign layer (%s) not included in the image", descriptor.MediaType)
+	case descriptor.Size == 0:
+		return "zero-size placeholder blob"
+	}
+	return ""
+}
+
+// isEmptyTar indicates if the content starting with the given bytes is a tar without entries (ending right away).
+func isEmptyTar(head []byte) bool {
+	return len(head) >= 512 && bytes.Count(head[:512], []byte{0}) == 512
+}
+
+// metadataOnlyTree returns the (empty) tree of a layer whose contents cannot be read, noting why such that the layer is
+// shown with its metadata alone rather than failing the analysis.
+func metadataOnlyTree(name, reason string) *filetree.FileTree {
+	tree := filetree.NewFileTree()
+	tree.Name = name
+	tree.Warn(filetree.WarningMetadataOnly, "/", "the layer contents could not be read: %s", reason)
+	return tree
+}
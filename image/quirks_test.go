@@ -0,0 +1,141 @@
+package image
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/wagoodman/dive/filetree"
+)
+
+func TestLayerQuirk(t *testing.T) {
+	layer := layerTar(t, "etc/hosts")
+	cases := []struct {
+		name     string
+		head     []byte
+		size     int64
+		expected string
+	}{
+		{"layer tar", layer, int64(len(layer)), ""},
+		{"empty tar", make([]byte, 1024), 1024, ""},
+		{"zero-size placeholder", nil, 0, "zero-size placeholder blob"},
+		{"zero-size with contents", layer, 0, "zero-size placeholder blob"},
+		{"still compressed", gzipped(t, layer)[:20], 20, "foreign media type (application/gzip)"},
+		{"zstd", []byte{0x28, 0xb5, 0x2f, 0xfd, 0, 0}, 6, "foreign media type (application/zstd)"},
+		{"wasm", []byte("\x00asm\x01\x00\x00\x00"), 8, "foreign media type (application/wasm)"},
+		{"json", []byte(`{"schemaVersion": 2}`), 20, "foreign media type (text/plain; charset=utf-8)"},
+		{"short zeros", make([]byte, 100), 100, "foreign media type (application/octet-stream)"},
+	}
+	for _, test := range cases {
+		if reason := layerQuirk(test.head, test.size); reason != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, reason)
+		}
+	}
+}
+
+func TestIsEmptyTar(t *testing.T) {
+	withByte := make([]byte, 512)
+	withByte[100] = 1
+	cases := []struct {
+		name     string
+		head     []byte
+		expected bool
+	}{
+		{"end-of-archive marker", make([]byte, 1024), true},
+		{"single zero block", make([]byte, 512), true},
+		{"short", make([]byte, 511), false},
+		{"not zeros", withByte, false},
+		{"nothing", nil, false},
+		{"layer tar", layerTar(t, "etc/hosts"), false},
+	}
+	for _, test := range cases {
+		if empty := isEmptyTar(test.head); empty != test.expected {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, empty)
+		}
+	}
+}
+
+func TestMissingBlobQuirk(t *testing.T) {
+	cases := []struct {
+		descriptor ociDescriptor
+		expected   string
+	}{
+		{ociDescriptor{MediaType: "application/vnd.docker.image.rootfs.foreign.diff.tar.gzip", Size: 100}, "foreign layer (application/vnd.docker.image.rootfs.foreign.diff.tar.gzip) not included in the image"},
+		{ociDescriptor{MediaType: "application/vnd.oci.image.layer.nondistributable.v1.tar+gzip", Size: 100}, "foreign layer (application/vnd.oci.image.layer.nondistributable.v1.tar+gzip) not included in the image"},
+		{ociDescriptor{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Size: 0}, "zero-size placeholder blob"},
+		{ociDescriptor{MediaType: "application/vnd.oci.image.layer.v1.tar+gzip", Size: 100}, ""},
+		{ociDescriptor{MediaType: "application/vnd.docker.image.rootfs.diff.tar.gzip", Size: 100}, ""},
+	}
+	for _, test := range cases {
+		if reason := missingBlobQuirk(test.descriptor); reason != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.descriptor.MediaType, test.expected, reason)
+		}
+	}
+}
+
+func TestMetadataOnlyTree(t *testing.T) {
+	tree := metadataOnlyTree("sha256:abc", "zero-size placeholder blob")
+	if tree.Name != "sha256:abc" || len(tree.Root.Children) != 0 {
+		t.Errorf("Expected an empty tree of the layer, got %q with %d children", tree.Name, len(tree.Root.Children))
+	}
+	if len(tree.Warnings) != 1 {
+		t.Fatalf("Expected a single warning, got %+v", tree.Warnings)
+	}
+	warning := tree.Warnings[0]
+	if warning.Kind != filetree.WarningMetadataOnly || warning.Layer != "sha256:abc" || !strings.Contains(warning.Message, "zero-size placeholder blob") {
+		t.Errorf("Expected the layer to be noted as metadata only, got %+v", warning)
+	}
+}
+
+func TestLayoutLayerQuirks(t *testing.T) {
+	quietly(t)
+	foreignType := "application/vnd.oci.image.layer.nondistributable.v1.tar+gzip"
+	cases := []struct {
+		name string
+		// change makes the given (middle) layer of the image quirky, returning whether its blob is to be removed
+		change  func(image *testImage, layer *ociDescriptor) bool
+		quirk   string
+		success bool
+	}{
+		{"ordinary layer", func(image *testImage, layer *ociDescriptor) bool { return false }, "", true},
+		{"missing foreign layer", func(image *testImage, layer *ociDescriptor) bool {
+			layer.MediaType = foreignType
+			return true
+		}, "foreign layer (" + foreignType + ") not included in the image", true},
+		{"zero-size placeholder", func(image *testImage, layer *ociDescriptor) bool {
+			*layer = image.addBlob(t, layer.MediaType, nil)
+			return false
+		}, "zero-size placeholder blob", true},
+		{"foreign media type", func(image *testImage, layer *ociDescriptor) bool {
+			*layer = image.addBlob(t, layer.MediaType, gzipped(t, []byte(`{"not": "a tar"}`)))
+			return false
+		}, "foreign media type (text/plain; charset=utf-8)", true},
+		{"missing ordinary layer", func(image *testImage, layer *ociDescriptor) bool { return true }, "", false},
+	}
+
+	for _, test := range cases {
+		image := newTestImage(t, layerTar(t, "etc/hosts"), layerTar(t, "etc/motd"), layerTar(t, "etc/passwd"))
+		remove := test.change(image, &image.manifest.Layers[1])
+		image.updateManifest(t)
+		dir := image.writeLayout(t)
+		if remove {
+			path, _ := blobPath(dir, image.manifest.Layers[1].Digest)
+			os.Remove(path)
+		}
+
+		layers, _, _, _, err := LoadImage(LayoutPrefix + dir)
+		if (err == nil) != test.success {
+			t.Errorf("%s: expected success=%v, got %v", test.name, test.success, err)
+			continue
+		}
+		if !test.success {
+			continue
+		}
+		if quirk := layers[1].MetadataOnly; quirk != test.quirk {
+			t.Errorf("%s: expected the layer to be read with quirk %q, got %q", test.name, test.quirk, quirk)
+		}
+		if layers[0].MetadataOnly != "" || layers[2].MetadataOnly != "" || !strings.HasPrefix(layers[0].Digest, "sha256:") {
+			t.Errorf("%s: expected the other layers to be read", test.name)
+		}
+	}
+}
@@ -0,0 +1,497 @@
+package image
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/wagoodman/dive/filetree"
+	"golang.org/x/net/context"
+)
+
+// RegistryPrefix marks an image reference to be read straight from a registry, without a Docker daemon:
+// "registry://ghcr.io/org/app:tag" or "registry://ghcr.io/org/app@sha256:...". References without a registry host refer
+// to Docker Hub (e.g. "registry://alpine:3.19").
+const RegistryPrefix = "registry://"
+
+const (
+	// dockerHubRegistry serves the images of Docker Hub (docker.io)
+	dockerHubRegistry = "registry-1.docker.io"
+	// dockerHubAuthKey is the key of the Docker Hub credentials in the docker config
+	dockerHubAuthKey = "https://index.docker.io/v1/"
+
+	// ociManifestMediaType is the media type of an OCI image manifest
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	// dockerManifestMediaType is the media type of a Docker v2 image manifest
+	dockerManifestMediaType = "application/vnd.docker.distribution.manifest.v2+json"
+	// dockerManifestListMediaType is the media type of a Docker v2 manifest list (a multi-platform image)
+	dockerManifestListMediaType = "application/vnd.docker.distribution.manifest.list.v2+json"
+
+	// registryConnectTimeout bounds connecting to a registry (including the TLS handshake)
+	registryConnectTimeout = 30 * time.Second
+	// registryResponseTimeout bounds waiting for a registry to answer a request
+	registryResponseTimeout = 60 * time.Second
+)
+
+// registryIdleTimeout bounds waiting for the next bytes of a response, such that a stalled download fails instead of
+// hanging dive. Layers are streamed for as long as they keep arriving, however large they are.
+var registryIdleTimeout = 60 * time.Second
+
+// manifestMediaTypes are the manifests accepted from a registry: single images and (multi-platform) indexes, of both
+// the OCI and the Docker v2 formats.
+var manifestMediaTypes = []string{
+	ociManifestMediaType,
+	ociIndexMediaType,
+	dockerManifestMediaType,
+	dockerManifestListMediaType,
+}
+
+// challengeParam matches a parameter of a WWW-Authenticate challenge (e.g. realm="https://ghcr.io/token").
+var challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// registryReference is an image reference of a registry.
+type registryReference struct {
+	// Host is the registry host (with its port, if any)
+	Host       string
+	Repository string
+	// Reference is the tag or the digest of the image
+	Reference string
+}
+
+// parseRegistryReference parses a "registry://[HOST/]REPOSITORY[:TAG|@DIGEST]" image reference, the tag defaulting to
+// "latest" like the Docker CLI does.
+func parseRegistryReference(imageID string) (registryReference, error) {
+	name := strings.TrimPrefix(imageID, RegistryPrefix)
+	var ref registryReference
+	digest := ""
+	if idx := strings.Index(name, "@"); idx >= 0 {
+		name, digest = name[:idx], name[idx+1:]
+	}
+	// a tag is ignored when a digest is given (e.g. "alpine:3.19@sha256:...")
+	if idx := strings.LastIndex(name, ":"); idx > strings.LastIndex(name, "/") {
+		name, ref.Reference = name[:idx], name[idx+1:]
+	} else {
+		ref.Reference = "latest"
+	}
+	if digest != "" || strings.Contains(imageID, "@") {
+		ref.Reference = digest
+	}
+
+	// the first path component is the registry host when it looks like one
+	ref.Host = "docker.io"
+	if idx := strings.Index(name, "/"); idx >= 0 {
+		if host := name[:idx]; strings.ContainsAny(host, ".:") || host == "localhost" {
+			ref.Host, name = host, name[idx+1:]
+		}
+	}
+	if ref.Host == "docker.io" {
+		ref.Host = dockerHubRegistry
+		if name != "" && !strings.Contains(name, "/") {
+			name = "library/" + name
+		}
+	}
+	ref.Repository = name
+	if name == "" || ref.Reference == "" {
+		return ref, fmt.Errorf("invalid image reference '%s' (expected %sHOST/REPOSITORY:TAG)", imageID, RegistryPrefix)
+	}
+	return ref, nil
+}
+
+// registryClient fetches the manifests and blobs of a repository with the registry HTTP API, authenticating with the
+// credentials of the docker config (see registryCredentials) when the registry asks for them.
+type registryClient struct {
+	ref      registryReference
+	scheme   string
+	client   *http.Client
+	username string
+	password string
+	// authorization is the Authorization header of the requests, once authenticated
+	authorization string
+}
+
+// newRegistryClient creates a client for the repository of the given reference. Registries on the local machine are
+// accessed over plain HTTP (as the Docker daemon allows by default), all others over HTTPS.
+func newRegistryClient(ref registryReference) *registryClient {
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           (&net.Dialer{Timeout: registryConnectTimeout}).DialContext,
+		TLSHandshakeTimeout:   registryConnectTimeout,
+		ResponseHeaderTimeout: registryResponseTimeout,
+	}
+	client := &registryClient{ref: ref, scheme: "https", client: &http.Client{Transport: transport}}
+	host := ref.Host
+	if idx := strings.LastIndex(host, ":"); idx >= 0 {
+		host = host[:idx]
+	}
+	if host == "localhost" || host == "127.0.0.1" {
+		client.scheme = "http"
+	}
+	client.username, client.password = registryCredentials(ref.Host)
+	return client
+}
+
+// get requests the given path of the repository (e.g. "manifests/latest"), authenticating once when challenged. The
+// caller closes the body of the response.
+func (registry *registryClient) get(path string, accept []string) (*http.Response, error) {
+	location := fmt.Sprintf("%s://%s/v2/%s/%s", registry.scheme, registry.ref.Host, registry.ref.Repository, path)
+	for attempt := 0; ; attempt++ {
+		request, err := http.NewRequest("GET", location, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(accept) > 0 {
+			request.Header.Set("Accept", strings.Join(accept, ", "))
+		}
+		if registry.authorization != "" {
+			request.Header.Set("Authorization", registry.authorization)
+		}
+		response, err := registry.do(request)
+		if err != nil {
+			return nil, err
+		}
+		if response.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			challenge := response.Header.Get("WWW-Authenticate")
+			response.Body.Close()
+			if err := registry.authenticate(challenge); err != nil {
+				return nil, fmt.Errorf("could not authenticate with %s: %v", registry.ref.Host, err)
+			}
+			continue
+		}
+		if response.StatusCode == http.StatusNotFound {
+			response.Body.Close()
+			return nil, &os.PathError{Op: "fetch", Path: location, Err: os.ErrNotExist}
+		}
+		if response.StatusCode != http.StatusOK {
+			response.Body.Close()
+			return nil, fmt.Errorf("could not fetch %s: %s", location, response.Status)
+		}
+		return response, nil
+	}
+}
+
+// do sends the given request, failing when the response (or the next bytes of its body) does not arrive within
+// registryIdleTimeout.
+func (registry *registryClient) do(request *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancel(request.Context())
+	timer := time.AfterFunc(registryIdleTimeout, cancel)
+	response, err := registry.client.Do(request.WithContext(ctx))
+	if err != nil {
+		timer.Stop()
+		cancel()
+		return nil, err
+	}
+	response.Body = &idleBody{ReadCloser: response.Body, timer: timer, cancel: cancel}
+	return response, nil
+}
+
+// idleBody is the body of a response, which is cancelled when nothing is read from it for registryIdleTimeout.
+type idleBody struct {
+	io.ReadCloser
+	timer  *time.Timer
+	cancel context.CancelFunc
+}
+
+func (body *idleBody) Read(buffer []byte) (int, error) {
+	body.timer.Reset(registryIdleTimeout)
+	return body.ReadCloser.Read(buffer)
+}
+
+func (body *idleBody) Close() error {
+	body.timer.Stop()
+	defer body.cancel()
+	return body.ReadCloser.Close()
+}
+
+// authenticate answers the given WWW-Authenticate challenge of the registry: with the credentials themselves for
+// Basic authentication, or by requesting a pull token for Bearer authentication (anonymously without credentials).
+func (registry *registryClient) authenticate(challenge string) error {
+	scheme := strings.ToLower(strings.SplitN(challenge, " ", 2)[0])
+	params := make(map[string]string)
+	for _, match := range challengeParam.FindAllStringSubmatch(challenge, -1) {
+		params[strings.ToLower(match[1])] = match[2]
+	}
+
+	switch scheme {
+	case "basic":
+		if registry.username == "" {
+			return fmt.Errorf("no credentials for %s (run 'docker login %s')", registry.ref.Host, registry.ref.Host)
+		}
+		credentials := base64.StdEncoding.EncodeToString([]byte(registry.username + ":" + registry.password))
+		registry.authorization = "Basic " + credentials
+		return nil
+	case "bearer":
+	default:
+		return fmt.Errorf("unsupported authentication challenge '%s'", challenge)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return fmt.Errorf("invalid token realm '%s'", params["realm"])
+	}
+	query := realm.Query()
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:pull", registry.ref.Repository))
+	realm.RawQuery = query.Encode()
+
+	request, err := http.NewRequest("GET", realm.String(), nil)
+	if err != nil {
+		return err
+	}
+	if registry.username != "" {
+		request.SetBasicAuth(registry.username, registry.password)
+	}
+	response, err := registry.do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("token request failed: %s", response.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&token); err != nil {
+		return fmt.Errorf("could not read token: %v", err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	registry.authorization = "Bearer " + token.Token
+	return nil
+}
+
+// openBlob streams the blob of the given descriptor from the registry.
+func (registry *registryClient) openBlob(descriptor ociDescriptor) (io.ReadCloser, error) {
+	response, err := registry.get("blobs/"+descriptor.Digest, nil)
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+// manifest fetches the manifest of the given reference (a tag or a digest), verifying it against the digest when given
+// one, and returns it with its media type.
+func (registry *registryClient) manifest(reference string) ([]byte, string, error) {
+	response, err := registry.get("manifests/"+reference, manifestMediaTypes)
+	if err != nil {
+		return nil, "", err
+	}
+	defer response.Body.Close()
+	contents, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	if strings.HasPrefix(reference, "sha256:") && fmt.Sprintf("sha256:%x", sha256.Sum256(contents)) != reference {
+		return nil, "", fmt.Errorf("manifest %s does not match its digest", reference)
+	}
+	mediaType := strings.TrimSpace(strings.SplitN(response.Header.Get("Content-Type"), ";", 2)[0])
+	return contents, mediaType, nil
+}
+
+// manifestDigest returns the digest of the manifest of the given reference (a tag or a digest), which identifies the
+// image as it is now.
+func (registry *registryClient) manifestDigest(reference string) (string, error) {
+	if strings.HasPrefix(reference, "sha256:") {
+		return reference, nil
+	}
+	contents, _, err := registry.manifest(reference)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(contents)), nil
+}
+
+// registryIndex lists the manifests of a multi-platform image, by platform.
+type registryIndex struct {
+	Manifests []struct {
+		ociDescriptor
+		Platform registryPlatform `json:"platform"`
+	} `json:"manifests"`
+}
+
+// registryPlatform is the platform an image of a multi-platform image runs on.
+type registryPlatform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant"`
+}
+
+// hostPlatform is the platform of this machine: linux on its architecture, with the variant of ARM architectures.
+func hostPlatform() registryPlatform {
+	platform := registryPlatform{OS: "linux", Architecture: runtime.GOARCH}
+	switch runtime.GOARCH {
+	case "arm64":
+		platform.Variant = "v8"
+	case "arm":
+		platform.Variant = "v7"
+		if info, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range info.Settings {
+				if setting.Key == "GOARM" && setting.Value != "" {
+					platform.Variant = "v" + strings.SplitN(setting.Value, ",", 2)[0]
+				}
+			}
+		}
+	}
+	return platform
+}
+
+// selectManifest returns the digest of the manifest of the index for the given platform: the one of the same variant,
+// or else of the same architecture without a variant (e.g. arm64, which is v8), or else the first one.
+func (index registryIndex) selectManifest(platform registryPlatform) string {
+	digest := index.Manifests[0].Digest
+	for _, candidate := range index.Manifests {
+		if candidate.Platform.OS != platform.OS || candidate.Platform.Architecture != platform.Architecture {
+			continue
+		}
+		if candidate.Platform.Variant == platform.Variant {
+			return candidate.Digest
+		}
+		if candidate.Platform.Variant == "" {
+			digest = candidate.Digest
+		}
+	}
+	return digest
+}
+
+// resolveImageManifest fetches the image manifest of the reference, taking the manifest of the platform of this
+// machine (see hostPlatform and selectManifest) from a multi-platform image.
+func (registry *registryClient) resolveImageManifest() (ociManifest, error) {
+	var manifest ociManifest
+	contents, mediaType, err := registry.manifest(registry.ref.Reference)
+	if err != nil {
+		return manifest, err
+	}
+	if mediaType == ociIndexMediaType || mediaType == dockerManifestListMediaType {
+		var index registryIndex
+		if err := json.Unmarshal(contents, &index); err != nil {
+			return manifest, fmt.Errorf("could not read image index: %v", err)
+		}
+		if len(index.Manifests) == 0 {
+			return manifest, fmt.Errorf("image index of %s holds no manifests", registry.ref.Reference)
+		}
+		if contents, _, err = registry.manifest(index.selectManifest(hostPlatform())); err != nil {
+			return manifest, err
+		}
+	}
+	if err := json.Unmarshal(contents, &manifest); err != nil {
+		return manifest, fmt.Errorf("could not read image manifest: %v", err)
+	}
+	return manifest, nil
+}
+
+// loadRegistryImage reads the image of the given "registry://..." reference straight from its registry, streaming each
+// layer blob into its tree.
+func loadRegistryImage(imageID string, analysis *span) ([]*Layer, []*filetree.FileTree, float64, filetree.EfficiencySlice, error) {
+	ref, err := parseRegistryReference(imageID)
+	if err != nil {
+		return nil, nil, 0, nil, err
+	}
+	printProgress(fmt.Sprintf("  Fetching manifest from %s...", ref.Host))
+	registry := newRegistryClient(ref)
+	manifest, err := registry.resolveImageManifest()
+	if err != nil {
+		return nil, nil, 0, nil, err
+	}
+	return loadManifestImage(imageID, registry, manifest, analysis)
+}
+
+// dockerConfig holds the registry credentials of the docker config (~/.docker/config.json).
+type dockerConfig struct {
+	Auths map[string]struct {
+		Auth     string `json:"auth"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"auths"`
+	CredsStore  string            `json:"credsStore"`
+	CredHelpers map[string]string `json:"credHelpers"`
+}
+
+// registryCredentials returns the credentials stored by "docker login" for the given registry host: from the
+// credential helper (keychain) configured for it, or else from the docker config itself. No credentials (anonymous
+// access) are returned when none are stored or they cannot be read.
+func registryCredentials(host string) (string, string) {
+	configDir := os.Getenv("DOCKER_CONFIG")
+	if configDir == "" {
+		home, err := homedir.Dir()
+		if err != nil {
+			return "", ""
+		}
+		configDir = filepath.Join(home, ".docker")
+	}
+	var config dockerConfig
+	if err := readJSONFile(filepath.Join(configDir, "config.json"), &config); err != nil {
+		return "", ""
+	}
+
+	key := host
+	if host == dockerHubRegistry {
+		key = dockerHubAuthKey
+	}
+	helper := config.CredHelpers[key]
+	if helper == "" {
+		helper = config.CredsStore
+	}
+	if helper != "" {
+		if username, secret, err := helperCredentials(helper, key); err == nil {
+			return username, secret
+		}
+	}
+
+	for server, auth := range config.Auths {
+		if server != key && registryHost(server) != host {
+			continue
+		}
+		if auth.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if parts := strings.SplitN(string(decoded), ":", 2); err == nil && len(parts) == 2 {
+				return parts[0], parts[1]
+			}
+		}
+		return auth.Username, auth.Password
+	}
+	return "", ""
+}
+
+// registryHost returns the host of a server of the docker config, which may be given as a URL.
+func registryHost(server string) string {
+	server = strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	return strings.SplitN(server, "/", 2)[0]
+}
+
+// helperCredentials asks the given docker credential helper (e.g. "osxkeychain" for docker-credential-osxkeychain)
+// for the credentials of the given server.
+func helperCredentials(helper, server string) (string, string, error) {
+	command := exec.Command("docker-credential-"+helper, "get")
+	command.Stdin = strings.NewReader(server)
+	output, err := command.Output()
+	if err != nil {
+		return "", "", err
+	}
+	var credentials struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(output, &credentials); err != nil {
+		return "", "", err
+	}
+	return credentials.Username, credentials.Secret, nil
+}
@@ -0,0 +1,93 @@
+package image
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// selectedLayers are the indexes (counting from the oldest layer) of the only layers to analyze, or nil to analyze
+// every layer.
+var selectedLayers map[int]bool
+
+// ParseLayerSelection parses a comma separated list of layer indexes and inclusive ranges (e.g. "0-3,7").
+func ParseLayerSelection(spec string) (map[int]bool, error) {
+	selection := make(map[int]bool)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		bounds := strings.SplitN(part, "-", 2)
+		start, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("invalid layer '%s'", part)
+		}
+		stop := start
+		if len(bounds) == 2 {
+			stop, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+			if err != nil || stop < start {
+				return nil, fmt.Errorf("invalid layer range '%s'", part)
+			}
+		}
+		for idx := start; idx <= stop; idx++ {
+			selection[idx] = true
+		}
+	}
+	if len(selection) == 0 {
+		return nil, fmt.Errorf("no layers selected")
+	}
+	return selection, nil
+}
+
+// SelectLayers limits the analysis of images to the given layers (counting from the oldest layer). All other layers
+// are skipped without being parsed, and appear as empty layers. A nil selection analyzes every layer.
+func SelectLayers(selection map[int]bool) {
+	selectedLayers = selection
+}
+
+// selectedTarPaths reads the manifest of the saved image to determine the tar paths of the selected layers, or
+// returns nil when every layer is to be analyzed.
+func selectedTarPaths(imageTarPath string) (map[string]bool, error) {
+	if selectedLayers == nil {
+		return nil, nil
+	}
+	tarFile, err := os.Open(imageTarPath)
+	if err != nil {
+		return nil, err
+	}
+	defer tarFile.Close()
+
+	tarReader := tar.NewReader(tarFile)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("could not find the image manifest")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Name != "manifest.json" {
+			continue
+		}
+		manifest, err := NewImageManifest(tarReader, header)
+		if err != nil {
+			return nil, fmt.Errorf("could not read image manifest: %v", err)
+		}
+		paths := make(map[string]bool)
+		for idx, tarPath := range manifest.LayerTarPaths {
+			if selectedLayers[idx] {
+				paths[tarPath] = true
+			}
+		}
+		for idx := range selectedLayers {
+			if idx >= len(manifest.LayerTarPaths) {
+				return nil, fmt.Errorf("layer %d does not exist (the image has %d layers)", idx, len(manifest.LayerTarPaths))
+			}
+		}
+		return paths, nil
+	}
+}
@@ -0,0 +1,248 @@
+package image
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// telemetryTimeout bounds each export request, such that an unreachable collector does not hold up the exit.
+const telemetryTimeout = 5 * time.Second
+
+// span is a single timed phase of the analysis.
+type span struct {
+	id         string
+	parentID   string
+	name       string
+	start      time.Time
+	end        time.Time
+	attributes map[string]interface{}
+}
+
+// metric is a single measurement of an analyzed image.
+type metric struct {
+	name       string
+	unit       string
+	value      float64
+	time       time.Time
+	attributes map[string]interface{}
+}
+
+// telemetryRecorder collects the spans and metrics of the analysis, which are exported (as OTLP over HTTP, encoded as
+// JSON) to an OpenTelemetry collector when dive exits.
+type telemetryRecorder struct {
+	lock     sync.Mutex
+	endpoint string
+	version  string
+	headers  map[string]string
+	resource map[string]interface{}
+	traceID  string
+	spans    []*span
+	metrics  []metric
+}
+
+// telemetry receives all spans and metrics (nil when telemetry is not enabled).
+var telemetry *telemetryRecorder
+
+func randomID(size int) string {
+	id := make([]byte, size)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// EnableTelemetry records a span for each phase of the analysis and metrics for the size and efficiency of each
+// analyzed image, to export them to the OpenTelemetry collector at the given endpoint (e.g. http://collector:4318)
+// with ExportTelemetry. Additional request headers are taken from OTEL_EXPORTER_OTLP_HEADERS ("key=value,...") and
+// the analysis is attributed to the current user and host, and the given version of dive.
+func EnableTelemetry(endpoint, version string) {
+	resource := map[string]interface{}{
+		"service.name":    "dive",
+		"service.version": version,
+	}
+	if current, err := user.Current(); err == nil {
+		resource["enduser.id"] = current.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		resource["host.name"] = host
+	}
+
+	headers := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		if parts := strings.SplitN(pair, "=", 2); len(parts) == 2 {
+			headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+
+	telemetry = &telemetryRecorder{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		version:  version,
+		headers:  headers,
+		resource: resource,
+		traceID:  randomID(16),
+	}
+}
+
+// startSpan starts timing a phase of the analysis within the given parent phase (nil for a top level phase). The
+// returned span is nil when telemetry is not enabled, which is safe to use.
+func startSpan(name string, parent *span, attributes map[string]interface{}) *span {
+	if telemetry == nil {
+		return nil
+	}
+	started := &span{id: randomID(8), name: name, start: time.Now(), attributes: attributes}
+	if parent != nil {
+		started.parentID = parent.id
+	}
+	return started
+}
+
+// finish stops timing the phase, recording the error the phase failed with (if any).
+func (phase *span) finish(err error) {
+	if phase == nil {
+		return
+	}
+	phase.end = time.Now()
+	if err != nil {
+		if phase.attributes == nil {
+			phase.attributes = make(map[string]interface{})
+		}
+		phase.attributes["error"] = err.Error()
+	}
+	telemetry.lock.Lock()
+	defer telemetry.lock.Unlock()
+	telemetry.spans = append(telemetry.spans, phase)
+}
+
+// recordMetric records a measurement (if telemetry is enabled).
+func recordMetric(name, unit string, value float64, attributes map[string]interface{}) {
+	if telemetry == nil {
+		return
+	}
+	telemetry.lock.Lock()
+	defer telemetry.lock.Unlock()
+	telemetry.metrics = append(telemetry.metrics, metric{name: name, unit: unit, value: value, time: time.Now(), attributes: attributes})
+}
+
+// otlpAttributes encodes attributes as OTLP key values.
+func otlpAttributes(attributes map[string]interface{}) []map[string]interface{} {
+	result := []map[string]interface{}{}
+	for key, value := range attributes {
+		var encoded map[string]interface{}
+		switch typed := value.(type) {
+		case string:
+			encoded = map[string]interface{}{"stringValue": typed}
+		case bool:
+			encoded = map[string]interface{}{"boolValue": typed}
+		case int:
+			encoded = map[string]interface{}{"intValue": strconv.Itoa(typed)}
+		case int64:
+			encoded = map[string]interface{}{"intValue": strconv.FormatInt(typed, 10)}
+		case uint64:
+			encoded = map[string]interface{}{"intValue": strconv.FormatUint(typed, 10)}
+		case float64:
+			encoded = map[string]interface{}{"doubleValue": typed}
+		default:
+			encoded = map[string]interface{}{"stringValue": fmt.Sprint(typed)}
+		}
+		result = append(result, map[string]interface{}{"key": key, "value": encoded})
+	}
+	return result
+}
+
+func unixNano(moment time.Time) string {
+	return strconv.FormatInt(moment.UnixNano(), 10)
+}
+
+// post sends a single OTLP export request.
+func (recorder *telemetryRecorder) post(signal string, body interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest("POST", recorder.endpoint+"/v1/"+signal, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for key, value := range recorder.headers {
+		request.Header.Set(key, value)
+	}
+	response, err := (&http.Client{Timeout: telemetryTimeout}).Do(request)
+	if err != nil {
+		return fmt.Errorf("could not export %s: %v", signal, err)
+	}
+	response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		return fmt.Errorf("could not export %s: %s", signal, response.Status)
+	}
+	return nil
+}
+
+// ExportTelemetry sends the spans and metrics recorded so far to the collector (if telemetry is enabled).
+func ExportTelemetry() error {
+	if telemetry == nil {
+		return nil
+	}
+	telemetry.lock.Lock()
+	spans, metrics := telemetry.spans, telemetry.metrics
+	telemetry.spans, telemetry.metrics = nil, nil
+	telemetry.lock.Unlock()
+
+	resource := map[string]interface{}{"attributes": otlpAttributes(telemetry.resource)}
+	scope := map[string]interface{}{"name": "dive", "version": telemetry.version}
+
+	if len(spans) > 0 {
+		var encoded []map[string]interface{}
+		for _, phase := range spans {
+			encoded = append(encoded, map[string]interface{}{
+				"traceId":           telemetry.traceID,
+				"spanId":            phase.id,
+				"parentSpanId":      phase.parentID,
+				"name":              phase.name,
+				"kind":              1,
+				"startTimeUnixNano": unixNano(phase.start),
+				"endTimeUnixNano":   unixNano(phase.end),
+				"attributes":        otlpAttributes(phase.attributes),
+			})
+		}
+		err := telemetry.post("traces", map[string]interface{}{
+			"resourceSpans": []interface{}{map[string]interface{}{
+				"resource":   resource,
+				"scopeSpans": []interface{}{map[string]interface{}{"scope": scope, "spans": encoded}},
+			}},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(metrics) > 0 {
+		var encoded []map[string]interface{}
+		for _, measurement := range metrics {
+			encoded = append(encoded, map[string]interface{}{
+				"name": measurement.name,
+				"unit": measurement.unit,
+				"gauge": map[string]interface{}{"dataPoints": []interface{}{map[string]interface{}{
+					"asDouble":     measurement.value,
+					"timeUnixNano": unixNano(measurement.time),
+					"attributes":   otlpAttributes(measurement.attributes),
+				}}},
+			})
+		}
+		return telemetry.post("metrics", map[string]interface{}{
+			"resourceMetrics": []interface{}{map[string]interface{}{
+				"resource":     resource,
+				"scopeMetrics": []interface{}{map[string]interface{}{"scope": scope, "metrics": encoded}},
+			}},
+		})
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package testkit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// base is the first layer of every fixture image, which the layers of each feature modify.
+var base = Layer{
+	CreatedBy: "/bin/sh -c #(nop) ADD file:fixture-base in / ",
+	Entries: []Entry{
+		Dir("/etc"),
+		File("/etc/hostname", "fixture\n"),
+		File("/etc/motd", "welcome\n"),
+		Dir("/opt"),
+		Dir("/opt/cache"),
+		File("/opt/cache/a.bin", strings.Repeat("a", 1024)),
+		File("/opt/cache/b.bin", strings.Repeat("b", 2048)),
+		Dir("/usr"),
+		Dir("/usr/bin"),
+		File("/usr/bin/tool", "#!/bin/sh\necho tool\n"),
+	},
+}
+
+// features are the layers exercising each feature, by feature name.
+var features = map[string][]Layer{
+	"whiteouts": {{
+		CreatedBy: "/bin/sh -c rm /etc/motd /usr/bin/tool",
+		Entries: []Entry{
+			Dir("/etc"),
+			Whiteout("/etc/motd"),
+			Dir("/usr/bin"),
+			Whiteout("/usr/bin/tool"),
+		},
+	}},
+	"opaque": {{
+		CreatedBy: "/bin/sh -c rm -rf /opt/cache && mkdir /opt/cache && echo new > /opt/cache/c.bin",
+		Entries: []Entry{
+			Dir("/opt/cache"),
+			OpaqueDir("/opt/cache"),
+			File("/opt/cache/c.bin", "new\n"),
+		},
+	}},
+	"hardlinks": {{
+		CreatedBy: "/bin/sh -c ln /usr/local/lib/libfixture.so.1 /usr/local/lib/libfixture.so",
+		Entries: []Entry{
+			Dir("/usr/local"),
+			Dir("/usr/local/lib"),
+			File("/usr/local/lib/libfixture.so.1", strings.Repeat("\x7fELF", 256)),
+			Hardlink("/usr/local/lib/libfixture.so", "/usr/local/lib/libfixture.so.1"),
+			Symlink("/usr/local/lib/libfixture.so.0", "libfixture.so.1"),
+		},
+	}},
+	"sparse": {{
+		CreatedBy: "/bin/sh -c fallocate -l 4M /var/lib/fixture/data.db",
+		Entries: []Entry{
+			Dir("/var"),
+			Dir("/var/lib"),
+			Dir("/var/lib/fixture"),
+			SparseFile("/var/lib/fixture/data.db", 4<<20, "SQLite format 3\x00"),
+		},
+	}},
+	"long-paths": {{
+		CreatedBy: "/bin/sh -c mkdir -p /srv/deeply/nested/... && echo long > .../file.txt",
+		Entries: []Entry{
+			LongPath("/srv", 240, "long\n"),
+		},
+	}},
+}
+
+// Features returns the names of the available fixture features.
+func Features() []string {
+	var names []string
+	for name := range features {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Fixture builds the specification of an image with the given features (all features when none are given), each
+// feature adding its own layers on top of a common base layer.
+func Fixture(tag string, names ...string) (Image, error) {
+	if len(names) == 0 {
+		names = Features()
+	}
+	image := Image{Tag: tag, Layers: []Layer{base}}
+	for _, name := range names {
+		layers, ok := features[name]
+		if !ok {
+			return Image{}, fmt.Errorf("unknown fixture feature '%s' (expected one of %s)", name, strings.Join(Features(), ", "))
+		}
+		image.Layers = append(image.Layers, layers...)
+	}
+	return image, nil
+}
@@ -0,0 +1,293 @@
+// Package testkit builds small, reproducible image archives with precisely controlled contents (whiteouts, opaque
+// directories, hardlinks, sparse files, long paths) for testing dive, and tools built on dive, against the layer
+// features found in real images.
+//
+// Archives are written in the format produced by "docker save": a legacy manifest.json (with a layer.tar per layer,
+// which is what dive reads) alongside an OCI image layout (oci-layout, index.json and blobs), such that they can be
+// loaded with "docker load" or read by OCI tooling. The same specification always produces the same bytes.
+package testkit
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Timestamp is the modification and creation time recorded for every fixture entry, config and archive member.
+var Timestamp = time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Entry is a single member of a layer tar.
+type Entry struct {
+	Path     string
+	Typeflag byte
+	Mode     int64
+	Linkname string
+	Content  []byte
+	// Size is the size of a regular file when it is larger than its content, the remainder is filled with zeros
+	Size int64
+}
+
+// File is a regular file with the given content.
+func File(filePath, content string) Entry {
+	return Entry{Path: filePath, Typeflag: tar.TypeReg, Mode: 0644, Content: []byte(content)}
+}
+
+// Dir is a directory.
+func Dir(dirPath string) Entry {
+	return Entry{Path: dirPath, Typeflag: tar.TypeDir, Mode: 0755}
+}
+
+// Symlink is a symbolic link to the given target.
+func Symlink(linkPath, target string) Entry {
+	return Entry{Path: linkPath, Typeflag: tar.TypeSymlink, Mode: 0777, Linkname: target}
+}
+
+// Hardlink is a hard link to the given path (which must be in the same layer).
+func Hardlink(linkPath, target string) Entry {
+	return Entry{Path: linkPath, Typeflag: tar.TypeLink, Mode: 0644, Linkname: strings.TrimPrefix(target, "/")}
+}
+
+// Whiteout removes the given path of a lower layer.
+func Whiteout(removedPath string) Entry {
+	dir, name := path.Split(removedPath)
+	return Entry{Path: path.Join(dir, ".wh."+name), Typeflag: tar.TypeReg, Mode: 0644}
+}
+
+// OpaqueDir marks the given directory as opaque, hiding everything lower layers placed in it.
+func OpaqueDir(dirPath string) Entry {
+	return Entry{Path: path.Join(dirPath, ".wh..wh..opq"), Typeflag: tar.TypeReg, Mode: 0644}
+}
+
+// SparseFile is a large file that is all zeros except for the given content at its start, like a preallocated
+// database or log file. Note that layer tars (as saved by docker) always store such files in full.
+func SparseFile(filePath string, size int64, content string) Entry {
+	return Entry{Path: filePath, Typeflag: tar.TypeReg, Mode: 0644, Content: []byte(content), Size: size}
+}
+
+// LongPath is a file at a path of (at least) the given length, nested in directories, which does not fit the 100
+// character name field of a plain tar header.
+func LongPath(prefix string, length int, content string) Entry {
+	filePath := strings.TrimSuffix(prefix, "/")
+	for idx := 0; len(filePath) < length; idx++ {
+		filePath += fmt.Sprintf("/directory-%02d", idx)
+	}
+	return File(filePath+"/file.txt", content)
+}
+
+// Layer is a single image layer, created by the given instruction.
+type Layer struct {
+	CreatedBy string
+	Entries   []Entry
+}
+
+// Image is the specification of a fixture image.
+type Image struct {
+	// Tag is the repository tag recorded in the archive (e.g. "dive-fixture:latest")
+	Tag    string
+	Layers []Layer
+}
+
+// LayerTar writes the tar of a single layer.
+func (layer Layer) LayerTar() ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := tar.NewWriter(&buffer)
+	for _, entry := range layer.Entries {
+		size := int64(len(entry.Content))
+		if entry.Typeflag == tar.TypeReg && entry.Size > size {
+			size = entry.Size
+		}
+		if entry.Typeflag != tar.TypeReg {
+			size = 0
+		}
+		name := strings.TrimPrefix(entry.Path, "/")
+		if entry.Typeflag == tar.TypeDir {
+			name += "/"
+		}
+		header := &tar.Header{
+			Name:     name,
+			Typeflag: entry.Typeflag,
+			Mode:     entry.Mode,
+			Linkname: entry.Linkname,
+			Size:     size,
+			ModTime:  Timestamp,
+			Format:   tar.FormatPAX,
+		}
+		if err := writer.WriteHeader(header); err != nil {
+			return nil, fmt.Errorf("could not write %s: %v", entry.Path, err)
+		}
+		if size > 0 {
+			if _, err := writer.Write(entry.Content); err != nil {
+				return nil, err
+			}
+			if padding := size - int64(len(entry.Content)); padding > 0 {
+				if _, err := io.CopyN(writer, zeros{}, padding); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// zeros is an endless source of zero bytes.
+type zeros struct{}
+
+func (zeros) Read(buffer []byte) (int, error) {
+	for idx := range buffer {
+		buffer[idx] = 0
+	}
+	return len(buffer), nil
+}
+
+func digestOf(content []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(content))
+}
+
+// archiveMember is a single file of the image archive.
+type archiveMember struct {
+	name    string
+	content []byte
+}
+
+// WriteArchive writes the image archive (as "docker save" would) to the given writer.
+func (image Image) WriteArchive(writer io.Writer) error {
+	var members []archiveMember
+	var layerPaths, diffIDs []string
+	var layerDescriptors []map[string]interface{}
+	var history []map[string]interface{}
+
+	for _, layer := range image.Layers {
+		layerTar, err := layer.LayerTar()
+		if err != nil {
+			return err
+		}
+		digest := digestOf(layerTar)
+		layerPath := digest + "/layer.tar"
+		members = append(members,
+			archiveMember{name: "blobs/sha256/" + digest, content: layerTar},
+			archiveMember{name: layerPath, content: layerTar},
+		)
+		layerPaths = append(layerPaths, layerPath)
+		diffIDs = append(diffIDs, "sha256:"+digest)
+		layerDescriptors = append(layerDescriptors, map[string]interface{}{
+			"mediaType": "application/vnd.oci.image.layer.v1.tar",
+			"digest":    "sha256:" + digest,
+			"size":      len(layerTar),
+		})
+		history = append(history, map[string]interface{}{
+			"created":    Timestamp.Format(time.RFC3339),
+			"created_by": layer.CreatedBy,
+		})
+	}
+
+	config, err := json.Marshal(map[string]interface{}{
+		"architecture": "amd64",
+		"os":           "linux",
+		"created":      Timestamp.Format(time.RFC3339),
+		"config":       map[string]interface{}{},
+		"rootfs":       map[string]interface{}{"type": "layers", "diff_ids": diffIDs},
+		"history":      history,
+	})
+	if err != nil {
+		return err
+	}
+	configDigest := digestOf(config)
+
+	manifest, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+		"config": map[string]interface{}{
+			"mediaType": "application/vnd.oci.image.config.v1+json",
+			"digest":    "sha256:" + configDigest,
+			"size":      len(config),
+		},
+		"layers": layerDescriptors,
+	})
+	if err != nil {
+		return err
+	}
+	manifestDigest := digestOf(manifest)
+
+	var repoTags []string
+	annotations := map[string]string{}
+	if image.Tag != "" {
+		repoTags = []string{image.Tag}
+		annotations["io.containerd.image.name"] = image.Tag
+		if idx := strings.LastIndex(image.Tag, ":"); idx > strings.LastIndex(image.Tag, "/") {
+			annotations["org.opencontainers.image.ref.name"] = image.Tag[idx+1:]
+		}
+	}
+	index, err := json.Marshal(map[string]interface{}{
+		"schemaVersion": 2,
+		"manifests": []map[string]interface{}{{
+			"mediaType":   "application/vnd.oci.image.manifest.v1+json",
+			"digest":      "sha256:" + manifestDigest,
+			"size":        len(manifest),
+			"annotations": annotations,
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	legacyManifest, err := json.Marshal([]map[string]interface{}{{
+		"Config":   configDigest + ".json",
+		"RepoTags": repoTags,
+		"Layers":   layerPaths,
+	}})
+	if err != nil {
+		return err
+	}
+
+	members = append(members,
+		archiveMember{name: "blobs/sha256/" + configDigest, content: config},
+		archiveMember{name: configDigest + ".json", content: config},
+		archiveMember{name: "blobs/sha256/" + manifestDigest, content: manifest},
+		archiveMember{name: "index.json", content: index},
+		archiveMember{name: "oci-layout", content: []byte(`{"imageLayoutVersion":"1.0.0"}`)},
+		archiveMember{name: "manifest.json", content: legacyManifest},
+	)
+	return writeMembers(writer, members)
+}
+
+// writeMembers writes the archive members (each once, in name order) along with their parent directories.
+func writeMembers(writer io.Writer, members []archiveMember) error {
+	sort.SliceStable(members, func(i, j int) bool { return members[i].name < members[j].name })
+
+	archive := tar.NewWriter(writer)
+	written := make(map[string]bool)
+	for _, member := range members {
+		if written[member.name] {
+			continue
+		}
+		var dirs []string
+		for dir := path.Dir(member.name); dir != "." && !written[dir]; dir = path.Dir(dir) {
+			dirs = append([]string{dir}, dirs...)
+		}
+		for _, dir := range dirs {
+			written[dir] = true
+			if err := archive.WriteHeader(&tar.Header{Name: dir + "/", Typeflag: tar.TypeDir, Mode: 0755, ModTime: Timestamp}); err != nil {
+				return err
+			}
+		}
+		written[member.name] = true
+		header := &tar.Header{Name: member.name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(member.content)), ModTime: Timestamp}
+		if err := archive.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err := archive.Write(member.content); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
@@ -0,0 +1,37 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/i18n"
+)
+
+// maxUnreadDirs is the number of (largest) unread directories listed in the details pane.
+const maxUnreadDirs = 10
+
+// StartupAccess is the file access of a container start, overlaid on the image being analyzed.
+type StartupAccess struct {
+	Profile filetree.AccessProfile
+	Usage   filetree.AccessUsage
+}
+
+// ImageAccess badges the files read at container start and is summarized in the details pane (nothing is shown when
+// nil).
+var ImageAccess *StartupAccess
+
+// accessReport describes how many bytes of the image are never read at container start, and where they are.
+func accessReport(usage filetree.AccessUsage) string {
+	report := fmt.Sprintf("%s %s (%s)\n", Formatting.Header(i18n.T("Read at startup:")), i18n.Bytes(usage.ReadSize), i18n.Tf("%s files", i18n.Number(int64(usage.ReadFiles))))
+	report += fmt.Sprintf("%s %s (%s)\n\n", Formatting.Header(i18n.T("Never read at startup:")), i18n.Bytes(usage.UnreadSize), i18n.Tf("%s files", i18n.Number(int64(usage.UnreadFiles))))
+
+	template := "%10s  %7s  %-s\n"
+	report += fmt.Sprintf(Formatting.Header(template), i18n.T("Unread"), i18n.T("Files"), i18n.T("Path"))
+	for idx, dir := range usage.UnreadDirs {
+		if idx == maxUnreadDirs {
+			break
+		}
+		report += fmt.Sprintf(template, i18n.Bytes(dir.Size), i18n.Number(int64(dir.Files)), dir.Path)
+	}
+	return report
+}
@@ -0,0 +1,207 @@
+package ui
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jroimartin/gocui"
+	"github.com/nsf/termbox-go"
+	"github.com/sirupsen/logrus"
+	"github.com/wagoodman/dive/i18n"
+	"github.com/wagoodman/dive/image"
+)
+
+// Action is a user-defined command run on the selected file, bound to a key of the file tree. The command is run by
+// the shell, with these placeholders replaced (each quoted for the shell):
+//
+//	%path   the path of the selected file in the image
+//	%layer  the index of the selected layer (counting from 0 for the oldest layer)
+//	%image  the analyzed image
+//	%file   a local copy of the selected file as of the selected layer (extracted only when used)
+//
+// The same values are given in the environment as DIVE_PATH, DIVE_LAYER, DIVE_IMAGE and DIVE_FILE. An interactive
+// command (e.g. an editor) is given the terminal while it runs, any other command runs in the background with its output
+// captured, showing the last line it printed in the status bar when it finishes.
+type Action struct {
+	Key         string `mapstructure:"key"`
+	Name        string `mapstructure:"name"`
+	Command     string `mapstructure:"command"`
+	Interactive bool   `mapstructure:"interactive"`
+}
+
+// ActionStore holds the user-defined actions and the image they are run for.
+type ActionStore struct {
+	Actions []Action
+	Image   string
+}
+
+// Actions are the user-defined actions bound to keys of the file tree (none are bound when nil).
+var Actions *ActionStore
+
+// Validate verifies that the action can be bound and run.
+func (action Action) Validate() error {
+	if len([]rune(action.Key)) != 1 {
+		return fmt.Errorf("the key of action '%s' must be a single character (got '%s')", action.Name, action.Key)
+	}
+	if strings.TrimSpace(action.Command) == "" {
+		return fmt.Errorf("action '%s' has no command", action.Name)
+	}
+	return nil
+}
+
+// shellQuote quotes the given value as a single argument for the shell.
+func shellQuote(value string) string {
+	return "'" + strings.Replace(value, "'", `'\''`, -1) + "'"
+}
+
+// expand replaces the placeholders of the command with the given (quoted) values.
+func (action Action) expand(values map[string]string) string {
+	var pairs []string
+	for _, name := range []string{"path", "layer", "image", "file"} {
+		pairs = append(pairs, "%"+name, shellQuote(values[name]))
+	}
+	return strings.NewReplacer(pairs...).Replace(action.Command)
+}
+
+// extractFile writes the file at the given path, as of the given layer of the image, to a new temporary directory,
+// returning the location of the copy and of the directory (to remove once the copy is no longer needed).
+func extractFile(imageID, filePath string, layer int) (string, string, error) {
+	dir, err := ioutil.TempDir("", "dive-action")
+	if err != nil {
+		return "", "", err
+	}
+	local := filepath.Join(dir, path.Base(filePath))
+	file, err := os.Create(local)
+	if err != nil {
+		return "", dir, err
+	}
+	defer file.Close()
+	return local, dir, image.ReadFile(imageID, filePath, layer, file)
+}
+
+// runAction runs the given action on the selected file of the file tree. An interactive command is given the terminal
+// until it exits, any other command runs in the background (the user interface stays responsive), reporting how it
+// finished in the status bar.
+func runAction(g *gocui.Gui, action Action) error {
+	node := Views.Tree.getAbsPositionNode()
+	if node == nil {
+		return nil
+	}
+	layer := Views.Layer.LayerIndex
+	values := map[string]string{
+		"path":  node.Path(),
+		"layer": strconv.Itoa(layer),
+		"image": Actions.Image,
+	}
+
+	if action.Interactive {
+		message, err := action.run(values, layer)
+		if err != nil {
+			return err
+		}
+		if message != "" {
+			Views.Status.showMessage(message)
+		}
+		Render()
+		return nil
+	}
+
+	Views.Status.showMessage(action.Name + " running...")
+	go func() {
+		message, err := action.run(values, layer)
+		g.Update(func(*gocui.Gui) error {
+			if err != nil {
+				return err
+			}
+			Views.Status.showMessage(message)
+			return nil
+		})
+	}()
+	return nil
+}
+
+// run runs the command of the action with the given values, extracting the selected file (as of the given layer) first
+// when the command uses it. An interactive command is given the terminal, the output of any other command is captured.
+// It returns the message to show in the status bar ("" when there is nothing to report), or an error when the terminal
+// cannot be restored.
+func (action Action) run(values map[string]string, layer int) (string, error) {
+	if strings.Contains(action.Command, "%file") {
+		local, dir, err := extractFile(values["image"], values["path"], layer)
+		if dir != "" {
+			defer os.RemoveAll(dir)
+		}
+		if err != nil {
+			return fmt.Sprintf("Could not extract %s: %v", values["path"], err), nil
+		}
+		values["file"] = local
+	}
+
+	command := exec.Command("sh", "-c", action.expand(values))
+	command.Env = append(os.Environ(),
+		"DIVE_PATH="+values["path"],
+		"DIVE_LAYER="+values["layer"],
+		"DIVE_IMAGE="+values["image"],
+		"DIVE_FILE="+values["file"],
+	)
+
+	if action.Interactive {
+		// hand the terminal over to the command for as long as it runs
+		termbox.Close()
+		command.Stdin, command.Stdout, command.Stderr = os.Stdin, os.Stdout, os.Stderr
+		err := command.Run()
+		if initErr := termbox.Init(); initErr != nil {
+			return "", initErr
+		}
+		if err != nil {
+			return fmt.Sprintf("%s failed: %v", action.Name, err), nil
+		}
+		return "", nil
+	}
+
+	output, err := command.CombinedOutput()
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	message := action.Name + " finished"
+	if err != nil {
+		message = fmt.Sprintf("%s failed: %v", action.Name, err)
+	}
+	if last := lines[len(lines)-1]; last != "" {
+		message += ": " + last
+	}
+	return message, nil
+}
+
+// boundHelp returns the help of the file tree key binding already registered for the given key, if any.
+func boundHelp(key string) string {
+	for _, binding := range keyBindingRegistry {
+		if binding.Pane == i18n.T("Filetree") && binding.Label == key {
+			return binding.Help
+		}
+	}
+	return ""
+}
+
+// registerActions binds the user-defined actions to their keys in the file tree, skipping any action whose key is
+// already bound.
+func registerActions(gui *gocui.Gui, viewName string) error {
+	if Actions == nil {
+		return nil
+	}
+	for _, action := range Actions.Actions {
+		action := action
+		if bound := boundHelp(action.Key); bound != "" {
+			logrus.Errorf("action '%s' is not bound: its key is already bound to '%s'", action.Name, bound)
+			continue
+		}
+		key := []rune(action.Key)[0]
+		if err := registerKeyBinding(gui, viewName, key, "Filetree", action.Key, action.Name, func(g *gocui.Gui, _ *gocui.View) error { return runAction(g, action) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package ui
+
+import (
+	"github.com/jroimartin/gocui"
+	"github.com/wagoodman/dive/filetree"
+)
+
+// largeFileSize is the size at which a file is badged as large.
+const largeFileSize = 10 * 1024 * 1024
+
+// badges reported by the built-in analyzers (the symbols are single width, since the terminal panes do not support
+// wide characters such as emoji)
+var (
+	largeFileBadge  = filetree.Badge{Symbol: "■", Name: "large"}
+	wastedFileBadge = filetree.Badge{Symbol: "⚠", Name: "wasted"}
+	capabilityBadge = filetree.Badge{Symbol: "✚", Name: "capabilities"}
+	packageDBBadge  = filetree.Badge{Symbol: "≡", Name: "package db"}
+	startupBadge    = filetree.Badge{Symbol: "▸", Name: "read at startup"}
+)
+
+// annotations holds the badges reported by the background analyzers so far.
+var annotations = filetree.NewAnnotations()
+
+// analyzer inspects the given layer trees and reports annotations for the files of interest on the given channel.
+type analyzer func(trees []*filetree.FileTree, results chan<- filetree.Annotation)
+
+// largeFileAnalyzer badges every file that is at least largeFileSize.
+func largeFileAnalyzer(trees []*filetree.FileTree, results chan<- filetree.Annotation) {
+	for _, tree := range trees {
+		tree.VisitDepthParentFirst(func(node *filetree.FileNode) error {
+			info := node.Data.FileInfo.TarHeader.FileInfo()
+			if !info.IsDir() && info.Size() >= largeFileSize {
+				results <- filetree.Annotation{Path: node.Path(), Badge: largeFileBadge}
+			}
+			return nil
+		}, nil)
+	}
+}
+
+// capabilityAnalyzer badges every file that is given linux file capabilities.
+func capabilityAnalyzer(trees []*filetree.FileTree, results chan<- filetree.Annotation) {
+	for _, finding := range filetree.CapabilityFindings(trees) {
+		results <- filetree.Annotation{Path: finding.Path, Badge: capabilityBadge}
+	}
+}
+
+// packageDBAnalyzer badges every package manager database directory (which is grouped into a single row).
+func packageDBAnalyzer(trees []*filetree.FileTree, results chan<- filetree.Annotation) {
+	seen := make(map[string]bool)
+	for _, tree := range trees {
+		tree.VisitDepthParentFirst(func(node *filetree.FileNode) error {
+			if path := node.Path(); filetree.IsPackageChurnRoot(path) && !seen[path] {
+				seen[path] = true
+				results <- filetree.Annotation{Path: path, Badge: packageDBBadge}
+			}
+			return nil
+		}, nil)
+	}
+}
+
+// wastedFileAnalyzer badges every file that is duplicated, overwritten or removed across layers.
+func wastedFileAnalyzer(inefficiencies filetree.EfficiencySlice) analyzer {
+	return func(trees []*filetree.FileTree, results chan<- filetree.Annotation) {
+		for _, data := range inefficiencies {
+			if len(data.Nodes) > 1 {
+				results <- filetree.Annotation{Path: data.Path, Badge: wastedFileBadge}
+			}
+		}
+	}
+}
+
+// startupAccessAnalyzer badges every file that is read at container start, according to the given access profile.
+func startupAccessAnalyzer(profile filetree.AccessProfile) analyzer {
+	return func(trees []*filetree.FileTree, results chan<- filetree.Annotation) {
+		for _, tree := range trees {
+			tree.VisitDepthParentFirst(func(node *filetree.FileNode) error {
+				if profile[node.Path()] {
+					results <- filetree.Annotation{Path: node.Path(), Badge: startupBadge}
+				}
+				return nil
+			}, nil)
+		}
+	}
+}
+
+// startAnalyzers runs the given analyzers in the background, adding the badges they report to the file tree as they
+// arrive rather than delaying the UI until all analysis is done.
+func startAnalyzers(g *gocui.Gui, refTrees []*filetree.FileTree, analyzers []analyzer) {
+	results := make(chan filetree.Annotation, 100)
+
+	go func() {
+		// the analyzers work on their own copy of the trees, as the UI is concurrently reading (and caching paths within)
+		// the original trees
+		trees := make([]*filetree.FileTree, len(refTrees))
+		for idx, tree := range refTrees {
+			trees[idx] = tree.Copy()
+		}
+		for _, analyze := range analyzers {
+			analyze(trees, results)
+		}
+		close(results)
+	}()
+
+	go func() {
+		for annotation := range results {
+			changed := annotations.Add(annotation)
+			// fold any annotations that have already arrived into a single update
+			for pending := len(results); pending > 0; pending-- {
+				if annotations.Add(<-results) {
+					changed = true
+				}
+			}
+			if changed {
+				g.Update(func(*gocui.Gui) error {
+					Views.Tree.Update()
+					return requestRender(g, Views.Tree)
+				})
+			}
+		}
+	}()
+}
@@ -0,0 +1,63 @@
+package ui
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// clipboardCommands are the system clipboard utilities tried (in order) before falling back to OSC52.
+var clipboardCommands = [][]string{
+	{"pbcopy"},
+	{"wl-copy"},
+	{"xclip", "-selection", "clipboard"},
+	{"xsel", "--clipboard", "--input"},
+	{"clip.exe"},
+}
+
+// copyToClipboard places the given text in the system clipboard. Within an SSH session (or when no clipboard
+// utility is available) the text is sent to the terminal as an OSC52 escape sequence instead, which most terminal
+// emulators forward to the clipboard of the local machine.
+func copyToClipboard(text string) error {
+	if os.Getenv("SSH_TTY") == "" && os.Getenv("SSH_CONNECTION") == "" {
+		for _, command := range clipboardCommands {
+			path, err := exec.LookPath(command[0])
+			if err != nil {
+				continue
+			}
+			cmd := exec.Command(path, command[1:]...)
+			cmd.Stdin = strings.NewReader(text)
+			if err := cmd.Run(); err == nil {
+				return nil
+			}
+		}
+	}
+	return writeOSC52(os.Stdout, text)
+}
+
+// writeOSC52 writes the escape sequence that asks the terminal to set its clipboard to the given text.
+func writeOSC52(writer io.Writer, text string) error {
+	sequence := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	if os.Getenv("TMUX") != "" {
+		// tmux only passes the sequence to the outer terminal when wrapped as a passthrough sequence
+		sequence = "\x1bPtmux;\x1b" + sequence + "\x1b\\"
+	}
+	_, err := io.WriteString(writer, sequence)
+	return err
+}
+
+// copyAndNotify copies the given text to the clipboard, telling the user what was copied (or why it was not).
+func copyAndNotify(description, text string) error {
+	if text == "" {
+		return nil
+	}
+	if err := copyToClipboard(text); err != nil {
+		Views.Status.showMessage(fmt.Sprintf("Could not copy %s: %v", description, err))
+		return nil
+	}
+	Views.Status.showMessage("Copied " + description)
+	return nil
+}
@@ -0,0 +1,23 @@
+package ui
+
+import "github.com/wagoodman/dive/image"
+
+// ComparisonCount is the number of files (and their bytes) for a single kind of change between two images.
+type ComparisonCount struct {
+	Files int
+	Size  uint64
+}
+
+// Comparison summarizes how the analyzed image differs from another build of it (e.g. the previous tag).
+type Comparison struct {
+	Reference string
+	Added     ComparisonCount
+	Removed   ComparisonCount
+	Changed   ComparisonCount
+	OldSize   uint64
+	NewSize   uint64
+	Growth    []image.LayerGrowth
+}
+
+// ImageComparison is shown in the details pane alongside the layer analysis (nothing is shown when nil).
+var ImageComparison *Comparison
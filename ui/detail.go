@@ -0,0 +1,31 @@
+package ui
+
+// CompactWidth is the terminal width below which the panes show fewer columns (e.g. in a tmux split), and WideWidth
+// the terminal width from which the layer pane shows extra columns (e.g. on an ultrawide monitor).
+var (
+	CompactWidth = 120
+	WideWidth    = 220
+)
+
+// detailLevel is how many columns the panes show, depending on the width of the terminal.
+type detailLevel int
+
+const (
+	// compactDetail leaves out the permissions and owners of files and the IDs of layers, and narrows the layer pane
+	compactDetail detailLevel = iota
+	normalDetail
+	// wideDetail adds the index and digest of each layer
+	wideDetail
+)
+
+// detailLevelOf returns the detail level of a terminal of the given width.
+func detailLevelOf(width int) detailLevel {
+	switch {
+	case width < CompactWidth:
+		return compactDetail
+	case width >= WideWidth:
+		return wideDetail
+	default:
+		return normalDetail
+	}
+}
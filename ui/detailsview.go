@@ -2,10 +2,11 @@ package ui
 
 import (
 	"fmt"
-	"github.com/dustin/go-humanize"
 	"github.com/jroimartin/gocui"
 	"github.com/lunixbochs/vtclean"
 	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/i18n"
+	"github.com/wagoodman/dive/image"
 	"strconv"
 	"strings"
 )
@@ -19,10 +20,21 @@ type DetailsView struct {
 	header         *gocui.View
 	efficiency     float64
 	inefficiencies filetree.EfficiencySlice
+	reintroduced   []*filetree.Reintroduction
+	capabilities   []filetree.CapabilityFinding
+	duplicates     []filetree.Duplicate
+	// waste is the space each layer wastes by overwriting or removing files of lower layers
+	waste []filetree.LayerWaste
+	// blame is what each layer contributed to the directory at blamePath (when requested from the file tree)
+	blamePath string
+	blame     []filetree.LayerBlame
 }
 
+// maxWastedPaths is the number of files listed for the space wasted by the selected layer.
+const maxWastedPaths = 5
+
 // NewDetailsView creates a new view object attached the the global [gocui] screen object.
-func NewDetailsView(name string, gui *gocui.Gui, efficiency float64, inefficiencies filetree.EfficiencySlice) (detailsView *DetailsView) {
+func NewDetailsView(name string, gui *gocui.Gui, efficiency float64, inefficiencies filetree.EfficiencySlice, reintroduced []*filetree.Reintroduction, capabilities []filetree.CapabilityFinding, duplicates []filetree.Duplicate) (detailsView *DetailsView) {
 	detailsView = new(DetailsView)
 
 	// populate main fields
@@ -30,6 +42,10 @@ func NewDetailsView(name string, gui *gocui.Gui, efficiency float64, inefficienc
 	detailsView.gui = gui
 	detailsView.efficiency = efficiency
 	detailsView.inefficiencies = inefficiencies
+	detailsView.reintroduced = reintroduced
+	detailsView.capabilities = capabilities
+	detailsView.duplicates = duplicates
+	detailsView.waste = filetree.WasteByLayer(inefficiencies)
 
 	return detailsView
 }
@@ -50,10 +66,10 @@ func (view *DetailsView) Setup(v *gocui.View, header *gocui.View) error {
 	view.header.Frame = false
 
 	// set keybindings
-	if err := view.gui.SetKeybinding(view.Name, gocui.KeyArrowDown, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return view.CursorDown() }); err != nil {
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyArrowDown, "Details", "↓", "Scroll down", func(*gocui.Gui, *gocui.View) error { return view.CursorDown() }); err != nil {
 		return err
 	}
-	if err := view.gui.SetKeybinding(view.Name, gocui.KeyArrowUp, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return view.CursorUp() }); err != nil {
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyArrowUp, "Details", "↑", "Scroll up", func(*gocui.Gui, *gocui.View) error { return view.CursorUp() }); err != nil {
 		return err
 	}
 
@@ -84,17 +100,23 @@ func (view *DetailsView) Update() error {
 }
 
 // Render flushes the state objects to the screen. The details pane reports:
-// 1. the current selected layer's command string
+// 1. the current selected layer's command string, the space it wastes, and its configuration changes
 // 2. the image efficiency score
 // 3. the estimated wasted image space
 // 4. a list of inefficient file allocations
+// 5. a list of files that were re-added after being deleted in an earlier layer
+// 6. a list of file contents stored more than once (by several layers or at several paths)
+// 7. a list of files given linux capabilities (e.g. with setcap)
+// 8. the comparison against another build of the image (when requested)
+// 9. what changed about the file selected in the file tree (when it is modified)
+// 10. what each layer contributed to a directory (when requested from the file tree)
 func (view *DetailsView) Render() error {
 	currentLayer := Views.Layer.currentLayer()
 
 	var wastedSpace int64
 
 	template := "%5s  %12s  %-s\n"
-	inefficiencyReport := fmt.Sprintf(Formatting.Header(template), "Count", "Total Space", "Path")
+	inefficiencyReport := fmt.Sprintf(Formatting.Header(template), i18n.T("Count"), i18n.T("Total Space"), i18n.T("Path"))
 
 	height := 100
 	if view.view != nil {
@@ -107,36 +129,219 @@ func (view *DetailsView) Render() error {
 
 		// todo: make this report scrollable and exportable
 		if idx < height {
-			inefficiencyReport += fmt.Sprintf(template, strconv.Itoa(len(data.Nodes)), humanize.Bytes(uint64(data.CumulativeSize)), data.Path)
+			inefficiencyReport += fmt.Sprintf(template, strconv.Itoa(len(data.Nodes)), i18n.Bytes(uint64(data.CumulativeSize)), data.Path)
+		}
+	}
+
+	var reintroducedSpace int64
+	reintroducedTemplate := "%12s  %-12s  %-s\n"
+	reintroducedReport := fmt.Sprintf(Formatting.Header(reintroducedTemplate), i18n.T("Total Space"), i18n.T("Layers"), i18n.T("Path"))
+	for idx, data := range view.reintroduced {
+		reintroducedSpace += data.WastedSize
+		if idx < height {
+			layers := fmt.Sprintf("%d→%d→%d", data.AddedLayer, data.RemovedLayer, data.ReaddedLayer)
+			reintroducedReport += fmt.Sprintf(reintroducedTemplate, i18n.Bytes(uint64(data.WastedSize)), layers, data.Path)
+		}
+	}
+
+	duplicateTemplate := "%6s  %12s  %-s\n"
+	duplicateReport := fmt.Sprintf(Formatting.Header(duplicateTemplate), i18n.T("Copies"), i18n.T("Reclaimable"), i18n.T("Paths"))
+	for idx, duplicate := range view.duplicates {
+		if idx >= height {
+			break
+		}
+		paths := make([]string, len(duplicate.Copies))
+		for copyIdx, stored := range duplicate.Copies {
+			paths[copyIdx] = fmt.Sprintf("%s (%d)", stored.Path, stored.Layer)
+		}
+		duplicateReport += fmt.Sprintf(duplicateTemplate, strconv.Itoa(len(duplicate.Copies)), i18n.Bytes(uint64(duplicate.ReclaimableSize())), strings.Join(paths, ", "))
+	}
+
+	capabilityTemplate := "%5s  %-30s  %-s\n"
+	capabilityReport := fmt.Sprintf(Formatting.Header(capabilityTemplate), i18n.T("Layer"), i18n.T("Capabilities"), i18n.T("Path"))
+	for _, finding := range view.capabilities {
+		capabilityReport += fmt.Sprintf(capabilityTemplate, strconv.Itoa(finding.Layer), finding.Capabilities, finding.Path)
+	}
+
+	// the space wasted by the selected layer, and by every instruction
+	var layerWaste *filetree.LayerWaste
+	wasteTemplate := "%5s  %12s  %-s\n"
+	wasteReport := fmt.Sprintf(Formatting.Header(wasteTemplate), i18n.T("Layer"), i18n.T("Wasted"), i18n.T("Instruction"))
+	for idx := range view.waste {
+		waste := &view.waste[idx]
+		if Views.Layer != nil && waste.Layer == Views.Layer.LayerIndex {
+			layerWaste = waste
+		}
+		if idx < height && Views.Layer != nil && waste.Layer < len(Views.Layer.Layers) {
+			layer := Views.Layer.Layers[len(Views.Layer.Layers)-1-waste.Layer]
+			instruction := layer.Command()
+			if instruction == "" {
+				instruction = layer.ShortId()
+			}
+			wasteReport += fmt.Sprintf(wasteTemplate, strconv.Itoa(waste.Layer), i18n.Bytes(uint64(waste.WastedSize)), instruction)
 		}
 	}
 
-	effStr := fmt.Sprintf("\n%s %d %%", Formatting.Header("Image efficiency score:"), int(100.0*view.efficiency))
-	spaceStr := fmt.Sprintf("%s %s\n", Formatting.Header("Potential wasted space:"), humanize.Bytes(uint64(wastedSpace)))
+	effStr := fmt.Sprintf("\n%s %d %%", Formatting.Header(i18n.T("Image efficiency score:")), int(100.0*view.efficiency))
+	spaceStr := fmt.Sprintf("%s %s\n", Formatting.Header(i18n.T("Potential wasted space:")), i18n.Bytes(uint64(wastedSpace)))
+
+	var selectedChanges []string
+	var selectedPath string
+	if Views.Tree != nil && Views.Tree.view != nil {
+		if node := Views.Tree.getAbsPositionNode(); node != nil && node.Data.DiffType == filetree.Changed {
+			selectedPath = node.Path()
+			for _, change := range node.Data.Changes {
+				selectedChanges = append(selectedChanges, change.String())
+			}
+		}
+	}
 
 	view.gui.Update(func(g *gocui.Gui) error {
 		// update header
 		view.header.Clear()
 		width, _ := g.Size()
-		headerStr := fmt.Sprintf("[Image & Layer Details]%s", strings.Repeat("─", width*2))
+		headerStr := fmt.Sprintf("[%s]%s", i18n.T("Image & Layer Details"), strings.Repeat("─", width*2))
 		fmt.Fprintln(view.header, Formatting.Header(vtclean.Clean(headerStr, false)))
 
-		// update contents
+		// update contents (which may name files in any script)
 		view.view.Clear()
-		fmt.Fprintln(view.view, Formatting.Header("Digest: ")+currentLayer.Id())
-		fmt.Fprintln(view.view, Formatting.Header("Tar ID: ")+currentLayer.TarId())
-		fmt.Fprintln(view.view, Formatting.Header("Command:"))
-		fmt.Fprintln(view.view, currentLayer.History.CreatedBy)
+		out := cellWriter{view.view}
+		if image.Degraded() {
+			fmt.Fprintln(out, Formatting.Header(i18n.T("Partial analysis:"))+i18n.T(" the memory limit was reached, so small files are aggregated per directory and file contents were not compared\n"))
+		}
+		fmt.Fprintln(out, Formatting.Header(i18n.T("Digest: "))+currentLayer.Id())
+		fmt.Fprintln(out, Formatting.Header(i18n.T("Tar ID: "))+currentLayer.TarId())
+		if currentLayer.MetadataOnly != "" {
+			fmt.Fprintln(out, Formatting.Header(i18n.T("Metadata only: "))+currentLayer.MetadataOnly)
+		}
+		if Views.Layer != nil && Views.Layer.RawCommand {
+			fmt.Fprintln(out, Formatting.Header(i18n.T("Command (as recorded):")))
+			fmt.Fprintln(out, currentLayer.History.CreatedBy)
+		} else {
+			fmt.Fprintln(out, Formatting.Header(i18n.T("Command:")))
+			viewWidth, _ := view.view.Size()
+			for _, line := range image.FormatCommand(currentLayer.Command(), viewWidth) {
+				fmt.Fprintln(out, image.HighlightCommand(line))
+			}
+		}
+
+		if layerWaste != nil {
+			fmt.Fprintf(out, "%s %s\n", Formatting.Header(i18n.T("Wasted by this instruction:")), i18n.Bytes(uint64(layerWaste.WastedSize)))
+			for idx, path := range layerWaste.Paths {
+				if idx == maxWastedPaths {
+					fmt.Fprintln(out, i18n.Tf("  … and %s more files", i18n.Number(int64(len(layerWaste.Paths)-maxWastedPaths))))
+					break
+				}
+				fmt.Fprintln(out, "  "+path)
+			}
+		}
+
+		if len(selectedChanges) > 0 {
+			fmt.Fprintln(out, Formatting.Header(i18n.T("\nModified file:"))+" "+selectedPath)
+			fmt.Fprintln(out, strings.Join(selectedChanges, ", "))
+		}
+
+		if view.blamePath != "" {
+			fmt.Fprintln(out, blameReport(view.blamePath, view.blame))
+		}
+
+		if len(currentLayer.MetadataChanges) > 0 {
+			fmt.Fprintln(out, Formatting.Header(i18n.T("\nConfig changes:")))
+			for _, change := range currentLayer.MetadataChanges {
+				fmt.Fprintln(out, change.String())
+			}
+		}
 
-		fmt.Fprintln(view.view, effStr)
-		fmt.Fprintln(view.view, spaceStr)
+		fmt.Fprintln(out, effStr)
+		fmt.Fprintln(out, spaceStr)
 
-		fmt.Fprintln(view.view, inefficiencyReport)
+		fmt.Fprintln(out, inefficiencyReport)
+
+		if len(view.waste) > 0 {
+			fmt.Fprintln(out, Formatting.Header(i18n.T("Wasted space by instruction:"))+"\n")
+			fmt.Fprintln(out, wasteReport)
+		}
+
+		if len(view.reintroduced) > 0 {
+			fmt.Fprintf(out, "%s %s\n\n", Formatting.Header(i18n.T("Re-added after deletion:")), i18n.Bytes(uint64(reintroducedSpace)))
+			fmt.Fprintln(out, reintroducedReport)
+		}
+
+		if len(view.duplicates) > 0 {
+			fmt.Fprintf(out, "%s %s\n\n", Formatting.Header(i18n.T("Duplicate file contents:")), i18n.Bytes(uint64(filetree.ReclaimableSize(view.duplicates))))
+			fmt.Fprintln(out, duplicateReport)
+		}
+
+		if len(view.capabilities) > 0 {
+			fmt.Fprintf(out, "%s %d\n\n", Formatting.Header(i18n.T("Files with capabilities:")), len(view.capabilities))
+			fmt.Fprintln(out, capabilityReport)
+		}
+
+		if ImageComparison != nil {
+			fmt.Fprintln(out, comparisonReport(ImageComparison))
+		}
+
+		if ImageAccess != nil {
+			fmt.Fprintln(out, accessReport(ImageAccess.Usage))
+		}
 		return nil
 	})
 	return nil
 }
 
+// comparisonReport describes the filesystem changes and the per-instruction growth relative to another build.
+func comparisonReport(comparison *Comparison) string {
+	signed := func(delta int64) string {
+		if delta < 0 {
+			return "-" + i18n.Bytes(uint64(-delta))
+		}
+		return "+" + i18n.Bytes(uint64(delta))
+	}
+
+	report := fmt.Sprintf("%s %s\n", Formatting.Header(i18n.T("Compared to:")), comparison.Reference)
+	report += fmt.Sprintf("%s %s → %s (%s)\n", Formatting.Header(i18n.T("Image size:")), i18n.Bytes(comparison.OldSize), i18n.Bytes(comparison.NewSize), signed(int64(comparison.NewSize)-int64(comparison.OldSize)))
+	report += i18n.Tf("%s files added (%s), %s files removed (%s), %s files modified (%s)\n\n",
+		i18n.Number(int64(comparison.Added.Files)), i18n.Bytes(comparison.Added.Size),
+		i18n.Number(int64(comparison.Removed.Files)), i18n.Bytes(comparison.Removed.Size),
+		i18n.Number(int64(comparison.Changed.Files)), i18n.Bytes(comparison.Changed.Size))
+
+	template := "%10s  %5s  %-s\n"
+	report += fmt.Sprintf(Formatting.Header(template), i18n.T("Growth"), i18n.T("Layer"), i18n.T("Instruction"))
+	for _, growth := range comparison.Growth {
+		if growth.Delta() == 0 {
+			continue
+		}
+		layer := "-"
+		if growth.Layer >= 0 {
+			layer = strconv.Itoa(growth.Layer)
+		}
+		report += fmt.Sprintf(template, signed(growth.Delta()), layer, growth.Instruction)
+	}
+	return report
+}
+
+// blameReport lists what each layer added to, overwrote in and deleted from the given directory, skipping the layers
+// that did not touch it.
+func blameReport(dirPath string, blame []filetree.LayerBlame) string {
+	dash := func(size uint64) string {
+		if size == 0 {
+			return "-"
+		}
+		return i18n.Bytes(size)
+	}
+
+	report := fmt.Sprintf("\n%s %s\n", Formatting.Header(i18n.T("Layer contributions to:")), dirPath)
+	template := "%5s  %10s  %11s  %10s  %10s\n"
+	report += fmt.Sprintf(Formatting.Header(template), i18n.T("Layer"), i18n.T("Added"), i18n.T("Overwritten"), i18n.T("Deleted"), i18n.T("Remaining"))
+	for _, layer := range blame {
+		if layer.Added == 0 && layer.Overwritten == 0 && layer.Deleted == 0 {
+			continue
+		}
+		report += fmt.Sprintf(template, strconv.Itoa(layer.Layer), dash(layer.Added), dash(layer.Overwritten), dash(layer.Deleted), dash(layer.Remaining))
+	}
+	return report
+}
+
 // KeyHelp indicates all the possible actions a user can take while the current pane is selected (currently does nothing).
 func (view *DetailsView) KeyHelp() string {
 	return "TBD"
@@ -9,6 +9,7 @@ import (
 	"github.com/jroimartin/gocui"
 	"github.com/lunixbochs/vtclean"
 	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/i18n"
 )
 
 const (
@@ -18,6 +19,9 @@ const (
 
 type CompareType int
 
+// Glyphs draw the branches of the file tree.
+var Glyphs = filetree.DefaultGlyphs
+
 // FileTreeView holds the UI objects and data models for populating the right pane. Specifically the pane that
 // shows selected layer or aggregate file ASCII tree.
 type FileTreeView struct {
@@ -29,6 +33,10 @@ type FileTreeView struct {
 	ViewTree              *filetree.FileTree
 	RefTrees              []*filetree.FileTree
 	HiddenDiffTypes       []bool
+	BadgeFilter           string
+	ShowRuleHidden        bool
+	GroupPackageChurn     bool
+	expandedChurn         map[string]bool
 	TreeIndex             uint
 	bufferIndex           uint
 	bufferIndexUpperBound uint
@@ -45,6 +53,8 @@ func NewFileTreeView(name string, gui *gocui.Gui, tree *filetree.FileTree, refTr
 	treeView.ModelTree = tree
 	treeView.RefTrees = refTrees
 	treeView.HiddenDiffTypes = make([]bool, 4)
+	treeView.GroupPackageChurn = true
+	treeView.expandedChurn = make(map[string]bool)
 
 	return treeView
 }
@@ -64,28 +74,70 @@ func (view *FileTreeView) Setup(v *gocui.View, header *gocui.View) error {
 	view.header.Frame = false
 
 	// set keybindings
-	if err := view.gui.SetKeybinding(view.Name, gocui.KeyArrowDown, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return view.CursorDown() }); err != nil {
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyArrowDown, "Filetree", "↓", "Move down", func(*gocui.Gui, *gocui.View) error { return view.CursorDown() }); err != nil {
+		return err
+	}
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyArrowUp, "Filetree", "↑", "Move up", func(*gocui.Gui, *gocui.View) error { return view.CursorUp() }); err != nil {
+		return err
+	}
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyArrowLeft, "Filetree", "←", "Move to parent directory", func(*gocui.Gui, *gocui.View) error { return view.CursorLeft() }); err != nil {
+		return err
+	}
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeySpace, "Filetree", "Space", "Collapse/expand directory", func(*gocui.Gui, *gocui.View) error { return view.toggleCollapse() }); err != nil {
+		return err
+	}
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyCtrlA, "Filetree", "^A", "Show/hide added files", func(*gocui.Gui, *gocui.View) error { return view.toggleShowDiffType(filetree.Added) }); err != nil {
+		return err
+	}
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyCtrlR, "Filetree", "^R", "Show/hide removed files", func(*gocui.Gui, *gocui.View) error { return view.toggleShowDiffType(filetree.Removed) }); err != nil {
+		return err
+	}
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyCtrlM, "Filetree", "^M", "Show/hide modified files", func(*gocui.Gui, *gocui.View) error { return view.toggleShowDiffType(filetree.Changed) }); err != nil {
+		return err
+	}
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyCtrlU, "Filetree", "^U", "Show/hide unmodified files", func(*gocui.Gui, *gocui.View) error { return view.toggleShowDiffType(filetree.Unchanged) }); err != nil {
+		return err
+	}
+	if err := registerKeyBinding(view.gui, view.Name, 'y', "Filetree", "y", "Copy the path of the selected file", func(*gocui.Gui, *gocui.View) error { return view.copyPath() }); err != nil {
+		return err
+	}
+	if err := registerKeyBinding(view.gui, view.Name, 'x', "Filetree", "x", "Show the xattrs and capabilities of the selected file", func(*gocui.Gui, *gocui.View) error { return view.showXattrs() }); err != nil {
 		return err
 	}
-	if err := view.gui.SetKeybinding(view.Name, gocui.KeyArrowUp, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return view.CursorUp() }); err != nil {
+	if err := registerKeyBinding(view.gui, view.Name, 'b', "Filetree", "b", "Show/hide what each layer contributed to the selected directory", func(*gocui.Gui, *gocui.View) error { return view.toggleBlame() }); err != nil {
 		return err
 	}
-	if err := view.gui.SetKeybinding(view.Name, gocui.KeyArrowLeft, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return view.CursorLeft() }); err != nil {
+	if err := registerKeyBinding(view.gui, view.Name, 'h', "Filetree", "h", "Always hide the selected path for this image (toggle)", func(*gocui.Gui, *gocui.View) error { return view.toggleHideRule() }); err != nil {
 		return err
 	}
-	if err := view.gui.SetKeybinding(view.Name, gocui.KeySpace, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return view.toggleCollapse() }); err != nil {
+	if err := registerKeyBinding(view.gui, view.Name, 'H', "Filetree", "H", "Show/hide always hidden paths", func(*gocui.Gui, *gocui.View) error { return view.toggleShowRuleHidden() }); err != nil {
 		return err
 	}
-	if err := view.gui.SetKeybinding(view.Name, gocui.KeyCtrlA, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return view.toggleShowDiffType(filetree.Added) }); err != nil {
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyCtrlP, "Filetree", "^P", "Group/ungroup package manager database changes", func(*gocui.Gui, *gocui.View) error { return view.toggleGroupPackageChurn() }); err != nil {
 		return err
 	}
-	if err := view.gui.SetKeybinding(view.Name, gocui.KeyCtrlR, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return view.toggleShowDiffType(filetree.Removed) }); err != nil {
+	if err := registerKeyBinding(view.gui, view.Name, ']', "Filetree", "]", "Show the next page of a large directory", func(*gocui.Gui, *gocui.View) error { return view.turnPage(1) }); err != nil {
 		return err
 	}
-	if err := view.gui.SetKeybinding(view.Name, gocui.KeyCtrlM, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return view.toggleShowDiffType(filetree.Changed) }); err != nil {
+	if err := registerKeyBinding(view.gui, view.Name, '[', "Filetree", "[", "Show the previous page of a large directory", func(*gocui.Gui, *gocui.View) error { return view.turnPage(-1) }); err != nil {
 		return err
 	}
-	if err := view.gui.SetKeybinding(view.Name, gocui.KeyCtrlU, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return view.toggleShowDiffType(filetree.Unchanged) }); err != nil {
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyCtrlB, "Filetree", "^B", "Only show files with the next badge", func(*gocui.Gui, *gocui.View) error { return view.cycleBadgeFilter() }); err != nil {
+		return err
+	}
+	if err := registerKeyBinding(view.gui, view.Name, '-', "Filetree", "-", "Collapse all directories", func(*gocui.Gui, *gocui.View) error { return view.collapseToDepth(1) }); err != nil {
+		return err
+	}
+	if err := registerKeyBinding(view.gui, view.Name, '+', "Filetree", "+", "Expand all directories", func(*gocui.Gui, *gocui.View) error { return view.expandAll() }); err != nil {
+		return err
+	}
+	for depth := 1; depth <= 9; depth++ {
+		depth := depth
+		if err := registerKeyBinding(view.gui, view.Name, rune('0'+depth), "Filetree", "1-9", "Collapse directories below the given depth", func(*gocui.Gui, *gocui.View) error { return view.collapseToDepth(depth) }); err != nil {
+			return err
+		}
+	}
+	if err := registerActions(view.gui, view.Name); err != nil {
 		return err
 	}
 
@@ -141,6 +193,7 @@ func (view *FileTreeView) setTreeByLayer(bottomTreeStart, bottomTreeStop, topTre
 		return nil
 	}
 	view.ModelTree.VisitDepthChildFirst(visitor, nil)
+	view.collapsePackageChurn(newTree)
 
 	view.resetCursor()
 
@@ -181,7 +234,7 @@ func (view *FileTreeView) doCursorDown() {
 // this range into the view buffer. This is much faster when tree sizes are large.
 func (view *FileTreeView) CursorDown() error {
 	view.doCursorDown()
-	return view.Render()
+	return requestRender(view.gui, view, Views.Details)
 }
 
 // CursorUp moves the cursor up and renders the view.
@@ -191,48 +244,21 @@ func (view *FileTreeView) CursorDown() error {
 func (view *FileTreeView) CursorUp() error {
 	if view.TreeIndex > 0 {
 		view.doCursorUp()
-		return view.Render()
+		return requestRender(view.gui, view, Views.Details)
 	}
 	return nil
 }
 
 //CursorLeft moves the cursor up until we reach the Parent Node or top of the tree
 func (view *FileTreeView) CursorLeft() error {
-	var visitor func(*filetree.FileNode) error
-	var evaluator func(*filetree.FileNode) bool
-	var dfsCounter, newIndex uint
-	oldIndex := view.TreeIndex
-	parentPath := view.getAbsPositionNode().Parent.Path()
-
-	visitor = func(curNode *filetree.FileNode) error {
-		if strings.Compare(parentPath, curNode.Path()) == 0 {
-			newIndex = dfsCounter
-		}
-		dfsCounter++
+	node := view.ViewTree.NodeAt(int(view.TreeIndex))
+	if node == nil {
 		return nil
 	}
-	var filterBytes []byte
-	var filterRegex *regexp.Regexp
-	read, err := Views.Filter.view.Read(filterBytes)
-	if read > 0 && err == nil {
-		regex, err := regexp.Compile(string(filterBytes))
-		if err == nil {
-			filterRegex = regex
-		}
-	}
-
-	evaluator = func(curNode *filetree.FileNode) bool {
-		regexMatch := true
-		if filterRegex != nil {
-			match := filterRegex.Find([]byte(curNode.Path()))
-			regexMatch = match != nil
-		}
-		return !curNode.Parent.Data.ViewInfo.Collapsed && !curNode.Data.ViewInfo.Hidden && regexMatch
-	}
-
-	err = view.ModelTree.VisitDepthParentFirst(visitor, evaluator)
-	if err != nil {
-		logrus.Panic(err)
+	oldIndex := view.TreeIndex
+	var newIndex uint
+	if parentRow := view.ViewTree.RowIndex(node.Parent); parentRow >= 0 {
+		newIndex = uint(parentRow)
 	}
 
 	view.TreeIndex = newIndex
@@ -248,60 +274,203 @@ func (view *FileTreeView) CursorLeft() error {
 		view.bufferIndex = 0
 	}
 
-	view.Update()
+	Views.Details.Render()
 	return view.Render()
 }
 
-// getAbsPositionNode determines the selected screen cursor's location in the file tree, returning the selected FileNode.
-func (view *FileTreeView) getAbsPositionNode() (node *filetree.FileNode) {
-	var visitor func(*filetree.FileNode) error
-	var evaluator func(*filetree.FileNode) bool
-	var dfsCounter uint
+// getAbsPositionNode determines the selected screen cursor's location in the file tree, returning the selected FileNode
+// (of the model tree, such that it may be changed).
+func (view *FileTreeView) getAbsPositionNode() *filetree.FileNode {
+	if view.ViewTree == nil {
+		return nil
+	}
+	shown := view.ViewTree.NodeAt(int(view.TreeIndex))
+	if shown == nil {
+		return nil
+	}
+	node, err := view.ModelTree.GetNode(shown.Path())
+	if err != nil {
+		logrus.Debugf("selected node is not in the model tree: %v", err)
+		return nil
+	}
+	return node
+}
 
-	visitor = func(curNode *filetree.FileNode) error {
-		if dfsCounter == view.TreeIndex {
-			node = curNode
-		}
-		dfsCounter++
+// toggleCollapse will collapse/expand the selected FileNode. Only the rows of the node change, so the view tree is
+// updated in place (rather than rebuilt from the model tree).
+func (view *FileTreeView) toggleCollapse() error {
+	node := view.getAbsPositionNode()
+	if node == nil {
 		return nil
 	}
-	var filterBytes []byte
-	var filterRegex *regexp.Regexp
-	read, err := Views.Filter.view.Read(filterBytes)
-	if read > 0 && err == nil {
-		regex, err := regexp.Compile(string(filterBytes))
-		if err == nil {
-			filterRegex = regex
-		}
+	node.Data.ViewInfo.Collapsed = !node.Data.ViewInfo.Collapsed
+	if filetree.IsPackageChurnRoot(node.Path()) {
+		view.expandedChurn[node.Path()] = !node.Data.ViewInfo.Collapsed
 	}
+	if shown, err := view.ViewTree.GetNode(node.Path()); err == nil {
+		shown.SetCollapsed(node.Data.ViewInfo.Collapsed)
+	}
+	return view.Render()
+}
 
-	evaluator = func(curNode *filetree.FileNode) bool {
-		regexMatch := true
-		if filterRegex != nil {
-			match := filterRegex.Find([]byte(curNode.Path()))
-			regexMatch = match != nil
-		}
-		return !curNode.Parent.Data.ViewInfo.Collapsed && !curNode.Data.ViewInfo.Hidden && regexMatch
+// collapseToDepth collapses every directory below the given depth (showing that many levels of the tree), expanding
+// the directories above it.
+func (view *FileTreeView) collapseToDepth(depth int) error {
+	if view.ViewTree == nil {
+		return nil
+	}
+	view.ModelTree.SetCollapsedBelowDepth(depth)
+	view.ViewTree.SetCollapsedBelowDepth(depth)
+	view.keepChurnState()
+	view.resetCursor()
+	return view.Render()
+}
+
+// expandAll expands every directory of the tree.
+func (view *FileTreeView) expandAll() error {
+	if view.ViewTree == nil {
+		return nil
 	}
+	view.ModelTree.Root.ExpandAll()
+	view.ViewTree.Root.ExpandAll()
+	view.keepChurnState()
+	return view.Render()
+}
+
+// keepChurnState notes which package manager database directories are expanded, such that they are kept expanded
+// (or collapsed) when another layer is selected.
+func (view *FileTreeView) keepChurnState() {
+	for _, node := range filetree.PackageChurnRoots(view.ModelTree) {
+		view.expandedChurn[node.Path()] = !node.Data.ViewInfo.Collapsed
+	}
+}
 
-	err = view.ModelTree.VisitDepthParentFirst(visitor, evaluator)
+// copyPath copies the path of the selected FileNode to the clipboard.
+func (view *FileTreeView) copyPath() error {
+	node := view.getAbsPositionNode()
+	if node == nil {
+		return nil
+	}
+	return copyAndNotify("path "+node.Path(), node.Path())
+}
+
+// showXattrs tells the user the extended attributes (including the linux file capabilities and SELinux label) of the
+// selected FileNode.
+func (view *FileTreeView) showXattrs() error {
+	node := view.getAbsPositionNode()
+	if node == nil {
+		return nil
+	}
+	xattrs := node.Data.FileInfo.XattrsString()
+	if xattrs == "" {
+		Views.Status.showMessage("No xattrs on " + node.Path())
+		return nil
+	}
+	Views.Status.showMessage(node.Path() + ": " + xattrs)
+	return nil
+}
+
+// toggleBlame shows (or hides) the per-layer breakdown of the size of the selected directory in the details pane.
+func (view *FileTreeView) toggleBlame() error {
+	node := view.getAbsPositionNode()
+	if node == nil {
+		return nil
+	}
+	if !node.Data.FileInfo.TarHeader.FileInfo().IsDir() && len(node.Children) == 0 {
+		Views.Status.showMessage(node.Path() + " is not a directory")
+		return nil
+	}
+	if Views.Details.blamePath == node.Path() {
+		Views.Details.blamePath, Views.Details.blame = "", nil
+		return Views.Details.Render()
+	}
+	blame, err := filetree.BlameDirectory(view.RefTrees, node.Path())
 	if err != nil {
-		logrus.Panic(err)
+		Views.Status.showMessage(err.Error())
+		return nil
 	}
+	Views.Details.blamePath, Views.Details.blame = node.Path(), blame
+	return Views.Details.Render()
+}
 
-	return node
+// toggleHideRule adds (or removes) a persistent rule that hides the selected path whenever this image is explored.
+func (view *FileTreeView) toggleHideRule() error {
+	node := view.getAbsPositionNode()
+	if node == nil {
+		return nil
+	}
+	if HideRules == nil {
+		Views.Status.showMessage("Hide rules are not available")
+		return nil
+	}
+	hidden, err := HideRules.toggle(node.Path())
+	switch {
+	case err != nil:
+		Views.Status.showMessage(fmt.Sprintf("Could not save hide rules: %v", err))
+	case hidden:
+		Views.Status.showMessage("Always hiding " + node.Path() + " for " + HideRules.Family)
+	default:
+		Views.Status.showMessage("No longer hiding " + node.Path() + " for " + HideRules.Family)
+	}
+	view.Update()
+	return view.Render()
 }
 
-// toggleCollapse will collapse/expand the selected FileNode.
-func (view *FileTreeView) toggleCollapse() error {
+// turnPage shows the next (or previous) page of children of the selected directory, or of the directory holding the
+// selected file, when it has too many children to show at once.
+func (view *FileTreeView) turnPage(delta int) error {
 	node := view.getAbsPositionNode()
-	if node != nil {
-		node.Data.ViewInfo.Collapsed = !node.Data.ViewInfo.Collapsed
+	if node == nil {
+		return nil
+	}
+	if !node.TurnPage(delta) && (node.Parent == nil || !node.Parent.TurnPage(delta)) {
+		return nil
 	}
 	view.Update()
 	return view.Render()
 }
 
+// toggleShowRuleHidden will show/hide the paths that are hidden by persistent hide rules.
+func (view *FileTreeView) toggleShowRuleHidden() error {
+	view.ShowRuleHidden = !view.ShowRuleHidden
+
+	view.resetCursor()
+
+	Update()
+	Render()
+	return nil
+}
+
+// collapsePackageChurn collapses the package manager database directories that have changes (e.g. /var/lib/dpkg) into a
+// single row, since their churn buries the changes of interest. Directories expanded by the user are left expanded.
+func (view *FileTreeView) collapsePackageChurn(tree *filetree.FileTree) {
+	if !view.GroupPackageChurn {
+		return
+	}
+	for _, node := range filetree.PackageChurnRoots(tree) {
+		node.Data.ViewInfo.Collapsed = !view.expandedChurn[node.Path()]
+	}
+}
+
+// toggleGroupPackageChurn will group/ungroup the changes to package manager databases.
+func (view *FileTreeView) toggleGroupPackageChurn() error {
+	view.GroupPackageChurn = !view.GroupPackageChurn
+	view.expandedChurn = make(map[string]bool)
+	if view.GroupPackageChurn {
+		view.collapsePackageChurn(view.ModelTree)
+	} else {
+		for _, node := range filetree.PackageChurnRoots(view.ModelTree) {
+			node.Data.ViewInfo.Collapsed = false
+		}
+	}
+
+	view.resetCursor()
+
+	Update()
+	Render()
+	return nil
+}
+
 // toggleShowDiffType will show/hide the selected DiffType in the filetree pane.
 func (view *FileTreeView) toggleShowDiffType(diffType filetree.DiffType) error {
 	view.HiddenDiffTypes[diffType] = !view.HiddenDiffTypes[diffType]
@@ -313,12 +482,33 @@ func (view *FileTreeView) toggleShowDiffType(diffType filetree.DiffType) error {
 	return nil
 }
 
+// cycleBadgeFilter will only show files with the next badge in the legend (wrapping around to showing all files).
+func (view *FileTreeView) cycleBadgeFilter() error {
+	legend := annotations.Legend()
+	next := ""
+	if view.BadgeFilter == "" && len(legend) > 0 {
+		next = legend[0].Name
+	}
+	for idx, badge := range legend {
+		if badge.Name == view.BadgeFilter && idx+1 < len(legend) {
+			next = legend[idx+1].Name
+		}
+	}
+	view.BadgeFilter = next
+
+	view.resetCursor()
+
+	Update()
+	Render()
+	return nil
+}
+
 // filterRegex will return a regular expression object to match the user's filter input.
 func filterRegex() *regexp.Regexp {
 	if Views.Filter == nil || Views.Filter.view == nil {
 		return nil
 	}
-	filterString := strings.TrimSpace(Views.Filter.view.Buffer())
+	filterString := strings.TrimSpace(Views.Filter.query())
 	if len(filterString) < 1 {
 		return nil
 	}
@@ -333,23 +523,33 @@ func filterRegex() *regexp.Regexp {
 
 // Update refreshes the state objects for future rendering.
 func (view *FileTreeView) Update() error {
-	regex := filterRegex()
-
-	// keep the view selection in parity with the current DiffType selection
+	// show the nodes matching the filter, then hide the rest in parity with the current DiffType selection
+	filter := filterRegex()
+	view.ModelTree.FilterNodes(filter, false)
 	view.ModelTree.VisitDepthChildFirst(func(node *filetree.FileNode) error {
-		node.Data.ViewInfo.Hidden = view.HiddenDiffTypes[node.Data.DiffType]
+		if view.HiddenDiffTypes[node.Data.DiffType] {
+			node.Data.ViewInfo.Hidden = true
+		}
 		visibleChild := false
 		for _, child := range node.Children {
 			if !child.Data.ViewInfo.Hidden {
 				visibleChild = true
 			}
 		}
-		if regex != nil && !visibleChild {
-			match := regex.FindString(node.Path())
-			node.Data.ViewInfo.Hidden = len(match) == 0
+		// a directory shown for its matching children is hidden again when those children are hidden
+		if filter != nil && len(node.Children) > 0 && !visibleChild && !filter.MatchString(node.Path()) {
+			node.Data.ViewInfo.Hidden = true
+		}
+		node.Data.ViewInfo.Badges = annotations.Badges(node.Path())
+		if view.BadgeFilter != "" && !visibleChild && !annotations.Has(node.Path(), view.BadgeFilter) {
+			node.Data.ViewInfo.Hidden = true
+		}
+		if !view.ShowRuleHidden && HideRules.hidden(node.Path()) {
+			node.Data.ViewInfo.Hidden = true
 		}
 		return nil
 	}, nil)
+	view.ModelTree.Paginate()
 
 	// make a new tree with only visible nodes
 	view.ViewTree = view.ModelTree.Copy()
@@ -359,22 +559,34 @@ func (view *FileTreeView) Update() error {
 		}
 		return nil
 	}, nil)
+	// directory sizes are shown on every render, size them once here (and count the rows, to render any window of
+	// rows and locate the selected node without walking the rows before it)
+	view.ViewTree.ComputeAggregateSizes()
+	view.ViewTree.CountRows()
 	return nil
 }
 
 // Render flushes the state objects (file tree) to the pane.
 func (view *FileTreeView) Render() error {
-	treeString := view.ViewTree.StringBetween(view.bufferIndexLowerBound, view.bufferIndexUpperBound, true)
+	width, _ := view.gui.Size()
+	compact := detailLevelOf(width) == compactDetail
+	renderer := filetree.ANSIRenderer{Glyphs: Glyphs, ShowAttributes: true, CompactAttributes: compact}
+	treeString := renderer.Render(view.ViewTree.Rows(int(view.bufferIndexLowerBound), int(view.bufferIndexUpperBound)))
 	lines := strings.Split(treeString, "\n")
 
-	// undo a cursor down that has gone past bottom of the visible tree
-	if view.bufferIndex >= uint(len(lines))-1 {
+	// undo a cursor down that has gone past bottom of the visible tree (possibly several, when cursor moves were
+	// coalesced into a single frame)
+	for overshoot := 0; view.bufferIndex >= uint(len(lines))-1 && view.TreeIndex > 0; overshoot++ {
 		view.doCursorUp()
+		if overshoot > 0 {
+			treeString = renderer.Render(view.ViewTree.Rows(int(view.bufferIndexLowerBound), int(view.bufferIndexUpperBound)))
+			lines = strings.Split(treeString, "\n")
+		}
 	}
 
-	title := "Current Layer Contents"
+	title := i18n.T("Current Layer Contents")
 	if Views.Layer.CompareMode == CompareAll {
-		title = "Aggregated Layer Contents"
+		title = i18n.T("Aggregated Layer Contents")
 	}
 
 	// indicate when selected
@@ -382,17 +594,36 @@ func (view *FileTreeView) Render() error {
 		title = "● " + title
 	}
 
+	// explain the badges reported so far, marking the badge being filtered on
+	var legend []string
+	for _, badge := range annotations.Legend() {
+		entry := badge.Symbol + " " + badge.Name
+		if badge.Name == view.BadgeFilter {
+			entry = "[" + entry + "]"
+		}
+		legend = append(legend, entry)
+	}
+	if len(legend) > 0 {
+		title += " (" + strings.Join(legend, " ") + ")"
+	}
+
 	view.gui.Update(func(g *gocui.Gui) error {
 		// update the header
 		view.header.Clear()
 		width, _ := g.Size()
 		headerStr := fmt.Sprintf("[%s]%s\n", title, strings.Repeat("─", width*2))
-		headerStr += fmt.Sprintf(filetree.AttributeFormat+" %s", "P", "ermission", "UID:GID", "Size", "Filetree")
+		if compact {
+			headerStr += fmt.Sprintf(filetree.CompactAttributeFormat+" %s", "Size", "Filetree")
+		} else {
+			headerStr += fmt.Sprintf(filetree.AttributeFormat+" %s", "P", "ermission", " ", "UID:GID", "Size", "Filetree")
+		}
 		fmt.Fprintln(view.header, Formatting.Header(vtclean.Clean(headerStr, false)))
 
 		// update the contents
 		view.view.Clear()
+		paneWidth, _ := view.view.Size()
 		for idx, line := range lines {
+			line = fitLine(line, paneWidth)
 			if uint(idx) == view.bufferIndex {
 				fmt.Fprintln(view.view, Formatting.Selected(vtclean.Clean(line, false)))
 			} else {
@@ -411,5 +642,9 @@ func (view *FileTreeView) KeyHelp() string {
 		renderStatusOption("^A", "Added files", !view.HiddenDiffTypes[filetree.Added]) +
 		renderStatusOption("^R", "Removed files", !view.HiddenDiffTypes[filetree.Removed]) +
 		renderStatusOption("^M", "Modified files", !view.HiddenDiffTypes[filetree.Changed]) +
-		renderStatusOption("^U", "Unmodified files", !view.HiddenDiffTypes[filetree.Unchanged])
+		renderStatusOption("^U", "Unmodified files", !view.HiddenDiffTypes[filetree.Unchanged]) +
+		renderStatusOption("^B", "Badge filter", view.BadgeFilter != "") +
+		renderStatusOption("H", "Always hidden", view.ShowRuleHidden) +
+		renderStatusOption("^P", "Group pkg db", view.GroupPackageChurn) +
+		renderStatusOption("]/[", "Page large dirs", false)
 }
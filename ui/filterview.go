@@ -2,10 +2,17 @@ package ui
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/jroimartin/gocui"
 )
 
+// filterHeader and saveHeader prompt for the path filter, and for the name of a search being saved.
+const (
+	filterHeader = "Path Filter: "
+	saveHeader   = "Save Search As: "
+)
+
 // DetailsView holds the UI objects and data models for populating the bottom row. Specifically the pane that
 // allows the user to filter the file tree by path.
 type FilterView struct {
@@ -16,6 +23,13 @@ type FilterView struct {
 	headerStr string
 	maxLength int
 	hidden    bool
+	// historyIdx is the position in the filter history shown with ↑/↓ (the length of the history for the query being
+	// typed, which is kept as the draft)
+	historyIdx int
+	draft      string
+	// naming is set while the user names the search being saved (the pending query)
+	naming  bool
+	pending string
 }
 
 // NewFilterView creates a new view object attached the the global [gocui] screen object.
@@ -25,7 +39,7 @@ func NewFilterView(name string, gui *gocui.Gui) (filterView *FilterView) {
 	// populate main fields
 	filterView.Name = name
 	filterView.gui = gui
-	filterView.headerStr = "Path Filter: "
+	filterView.headerStr = filterHeader
 	filterView.hidden = true
 
 	return filterView
@@ -48,6 +62,20 @@ func (view *FilterView) Setup(v *gocui.View, header *gocui.View) error {
 	view.header.Wrap = false
 	view.header.Frame = false
 
+	// set keybindings
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyArrowUp, "Filter", "↑", "Previous filter", func(*gocui.Gui, *gocui.View) error { return view.CursorUp() }); err != nil {
+		return err
+	}
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyArrowDown, "Filter", "↓", "Next filter", func(*gocui.Gui, *gocui.View) error { return view.CursorDown() }); err != nil {
+		return err
+	}
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyEnter, "Filter", "⏎", "Keep filter in history", func(*gocui.Gui, *gocui.View) error { return view.submit() }); err != nil {
+		return err
+	}
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyCtrlS, "Filter", "^S", "Save search", func(*gocui.Gui, *gocui.View) error { return view.startSaving() }); err != nil {
+		return err
+	}
+
 	view.Render()
 
 	return nil
@@ -61,16 +89,133 @@ func (view *FilterView) IsVisible() bool {
 	return !view.hidden
 }
 
-// CursorDown moves the cursor down in the filter pane (currently indicates nothing).
+// CursorDown shows the next (more recent) filter of the history, or the filter that was being typed.
 func (view *FilterView) CursorDown() error {
+	if Searches == nil || view.naming || view.historyIdx >= len(Searches.History) {
+		return nil
+	}
+	view.historyIdx++
+	if view.historyIdx == len(Searches.History) {
+		view.setQuery(view.draft)
+	} else {
+		view.setQuery(Searches.History[view.historyIdx])
+	}
 	return nil
 }
 
-// CursorUp moves the cursor up in the filter pane (currently indicates nothing).
+// CursorUp shows the previous (older) filter of the history, keeping the filter that was being typed.
 func (view *FilterView) CursorUp() error {
+	if Searches == nil || view.naming || view.historyIdx <= 0 {
+		return nil
+	}
+	if view.historyIdx > len(Searches.History) {
+		view.historyIdx = len(Searches.History)
+	}
+	if view.historyIdx == len(Searches.History) {
+		view.draft = view.view.Buffer()
+	}
+	view.historyIdx--
+	view.setQuery(Searches.History[view.historyIdx])
 	return nil
 }
 
+// setQuery replaces the input with the given text, refreshing the file tree.
+func (view *FilterView) setQuery(text string) {
+	view.view.Clear()
+	view.view.SetCursor(0, 0)
+	view.view.SetOrigin(0, 0)
+	for _, ch := range strings.TrimRight(text, "\n") {
+		view.view.EditWrite(ch)
+	}
+	view.refreshTree()
+}
+
+// query returns the filter to apply to the file tree: the input, where "@name" stands for a saved search.
+func (view *FilterView) query() string {
+	if view.naming {
+		return view.pending
+	}
+	return Searches.expand(strings.TrimSpace(view.view.Buffer()))
+}
+
+// submit keeps the current filter in the history, or saves the search being named.
+func (view *FilterView) submit() error {
+	if view.naming {
+		name := strings.TrimSpace(view.view.Buffer())
+		query := view.pending
+		view.stopSaving()
+		if name == "" {
+			return nil
+		}
+		if err := Searches.save(name, query); err != nil {
+			Views.Status.showMessage(fmt.Sprintf("Could not save search %s: %v", name, err))
+		} else {
+			Views.Status.showMessage("Saved search @" + name)
+		}
+		return nil
+	}
+	view.remember()
+	return nil
+}
+
+// remember adds the current filter to the history.
+func (view *FilterView) remember() {
+	if view.naming {
+		return
+	}
+	if err := Searches.remember(view.view.Buffer()); err != nil {
+		Views.Status.showMessage(fmt.Sprintf("Could not save the filter history: %v", err))
+	}
+	if Searches != nil {
+		view.historyIdx = len(Searches.History)
+	}
+}
+
+// startSaving prompts for the name to save the current filter under.
+func (view *FilterView) startSaving() error {
+	query := strings.TrimSpace(view.view.Buffer())
+	if Searches == nil || view.naming || query == "" {
+		return nil
+	}
+	view.pending = Searches.expand(query)
+	view.naming = true
+	view.headerStr = saveHeader
+	view.view.Clear()
+	view.view.SetCursor(0, 0)
+	view.view.SetOrigin(0, 0)
+	return view.Render()
+}
+
+// stopSaving goes back to filtering with the query that was being saved.
+func (view *FilterView) stopSaving() {
+	query := view.pending
+	view.naming = false
+	view.pending = ""
+	view.headerStr = filterHeader
+	view.setQuery(query)
+	view.Render()
+}
+
+// reset leaves the history and naming the search (e.g. when the filter pane is closed).
+func (view *FilterView) reset() {
+	view.naming = false
+	view.pending = ""
+	view.headerStr = filterHeader
+	view.draft = ""
+	view.historyIdx = 0
+	if Searches != nil {
+		view.historyIdx = len(Searches.History)
+	}
+}
+
+// refreshTree filters the file tree by the current input.
+func (view *FilterView) refreshTree() {
+	if Views.Tree != nil {
+		Views.Tree.Update()
+		Views.Tree.Render()
+	}
+}
+
 // Edit intercepts the key press events in the filer view to update the file view in real time.
 func (view *FilterView) Edit(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
 	if !view.IsVisible() {
@@ -88,9 +233,8 @@ func (view *FilterView) Edit(v *gocui.View, key gocui.Key, ch rune, mod gocui.Mo
 	case key == gocui.KeyBackspace || key == gocui.KeyBackspace2:
 		v.EditDelete(true)
 	}
-	if Views.Tree != nil {
-		Views.Tree.Update()
-		Views.Tree.Render()
+	if !view.naming {
+		view.refreshTree()
 	}
 }
 
@@ -103,6 +247,7 @@ func (view *FilterView) Update() error {
 func (view *FilterView) Render() error {
 	view.gui.Update(func(g *gocui.Gui) error {
 		// render the header
+		view.header.Clear()
 		fmt.Fprintln(view.header, Formatting.Header(view.headerStr))
 
 		return nil
@@ -112,5 +257,8 @@ func (view *FilterView) Render() error {
 
 // KeyHelp indicates all the possible actions a user can take while the current pane is selected.
 func (view *FilterView) KeyHelp() string {
-	return Formatting.StatusControlNormal("▏Type to filter the file tree ")
+	if view.naming {
+		return Formatting.StatusControlNormal("▏Type the name to save the search as (then apply it with @name) ")
+	}
+	return Formatting.StatusControlNormal("▏Type to filter the file tree (@name for a saved search) ")
 }
@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/jroimartin/gocui"
+)
+
+// HelpView holds the UI objects for the key binding overlay. The listed bindings are generated from the key binding
+// registry, so any action registered by a pane is shown here.
+type HelpView struct {
+	Name     string
+	gui      *gocui.Gui
+	view     *gocui.View
+	hidden   bool
+	previous string
+	seen     bool
+}
+
+// NewHelpView creates a new view object attached the the global [gocui] screen object.
+func NewHelpView(name string, gui *gocui.Gui) (helpView *HelpView) {
+	helpView = new(HelpView)
+
+	// populate main fields
+	helpView.Name = name
+	helpView.gui = gui
+	helpView.hidden = true
+
+	return helpView
+}
+
+// Setup initializes the UI concerns within the context of a global [gocui] view object.
+func (view *HelpView) Setup(v *gocui.View, header *gocui.View) error {
+
+	// set view options
+	view.view = v
+	view.view.Editable = false
+	view.view.Wrap = false
+	view.view.Frame = true
+	view.view.Title = "Key Bindings (press ? or Esc to close)"
+
+	return view.Render()
+}
+
+// keyBindings registers the actions available while the overlay is shown. This is done once since the overlay
+// pane is created and deleted each time it is toggled.
+func (view *HelpView) keyBindings() error {
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyArrowDown, "Help", "↓", "Scroll down", func(*gocui.Gui, *gocui.View) error { return view.CursorDown() }); err != nil {
+		return err
+	}
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyArrowUp, "Help", "↑", "Scroll up", func(*gocui.Gui, *gocui.View) error { return view.CursorUp() }); err != nil {
+		return err
+	}
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyEsc, "Help", "Esc", "Close key bindings", toggleHelpView); err != nil {
+		return err
+	}
+	return registerKeyBinding(view.gui, view.Name, '?', "Help", "?", "Close key bindings", toggleHelpView)
+}
+
+// IsVisible indicates if the help overlay is currently shown.
+func (view *HelpView) IsVisible() bool {
+	if view == nil {
+		return false
+	}
+	return !view.hidden
+}
+
+// CursorDown scrolls the overlay down.
+func (view *HelpView) CursorDown() error {
+	return CursorDown(view.gui, view.view)
+}
+
+// CursorUp scrolls the overlay up.
+func (view *HelpView) CursorUp() error {
+	return CursorUp(view.gui, view.view)
+}
+
+// Update refreshes the state objects for future rendering (currently does nothing).
+func (view *HelpView) Update() error {
+	return nil
+}
+
+// Render flushes the key binding listing to the overlay.
+func (view *HelpView) Render() error {
+	if view.view == nil {
+		return nil
+	}
+	panes, bindings := KeyBindings()
+
+	view.gui.Update(func(g *gocui.Gui) error {
+		view.view.Clear()
+		for _, pane := range panes {
+			fmt.Fprintln(view.view, Formatting.Header(pane))
+			for _, binding := range bindings[pane] {
+				fmt.Fprintf(view.view, "  %-10s %s\n", binding.Label, binding.Help)
+			}
+			fmt.Fprintln(view.view)
+		}
+		return nil
+	})
+	return nil
+}
+
+// KeyHelp indicates all the possible actions a user can take while the overlay is shown.
+func (view *HelpView) KeyHelp() string {
+	return renderStatusOption("Esc", "Close key bindings", false)
+}
+
+// toggleHelpView shows/hides the key binding overlay, returning the focus to the previously selected pane on close.
+func toggleHelpView(g *gocui.Gui, v *gocui.View) error {
+	view := Views.Help
+	view.seen = true
+
+	if view.hidden {
+		if current := g.CurrentView(); current != nil {
+			view.previous = current.Name()
+		}
+		view.hidden = false
+		// the overlay pane itself is created (and selected) on the next layout pass
+		Update()
+		Render()
+		return nil
+	}
+
+	view.hidden = true
+	view.view = nil
+	if err := g.DeleteView(view.Name); err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	if view.previous != "" {
+		if _, err := g.SetCurrentView(view.previous); err != nil {
+			return err
+		}
+	}
+	Update()
+	Render()
+	return nil
+}
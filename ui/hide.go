@@ -0,0 +1,34 @@
+package ui
+
+import (
+	"os"
+
+	"github.com/wagoodman/dive/filetree"
+)
+
+// HideRuleStore holds the persistent hide rules, where they are saved, and the image family they are applied for.
+type HideRuleStore struct {
+	Rules  filetree.HideRules
+	Path   string
+	Family string
+}
+
+// HideRules are the persistent hide rules applied to the file tree (none are applied when nil).
+var HideRules *HideRuleStore
+
+// hidden indicates if the given path is hidden by a persistent hide rule.
+func (store *HideRuleStore) hidden(path string) bool {
+	return store != nil && store.Rules.Matches(store.Family, path)
+}
+
+// toggle adds (or removes) a rule for exactly the given path and saves the rules, returning true if the path is now
+// hidden.
+func (store *HideRuleStore) toggle(path string) (bool, error) {
+	hidden := store.Rules.Toggle(store.Family, path)
+	file, err := os.Create(store.Path)
+	if err != nil {
+		return hidden, err
+	}
+	defer file.Close()
+	return hidden, store.Rules.Write(file)
+}
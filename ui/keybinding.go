@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"github.com/jroimartin/gocui"
+	"github.com/wagoodman/dive/i18n"
+)
+
+// KeyBinding describes a single key press action available to the user.
+type KeyBinding struct {
+	Pane  string
+	Label string
+	Help  string
+}
+
+// keyBindingRegistry holds every registered key binding in registration order.
+var keyBindingRegistry []KeyBinding
+
+// registerKeyBinding binds the given key to a handler in the named view (or globally if the view name is empty) and
+// records the binding so it can be listed to the user. Registering the same label for the same pane more than once
+// (e.g. the same action in multiple views) is only listed once.
+func registerKeyBinding(g *gocui.Gui, viewName string, key interface{}, pane, label, help string, handler func(*gocui.Gui, *gocui.View) error) error {
+	if err := g.SetKeybinding(viewName, key, gocui.ModNone, handler); err != nil {
+		return err
+	}
+
+	for _, binding := range keyBindingRegistry {
+		if binding.Pane == pane && binding.Label == label {
+			return nil
+		}
+	}
+	keyBindingRegistry = append(keyBindingRegistry, KeyBinding{
+		Pane:  i18n.T(pane),
+		Label: label,
+		Help:  i18n.T(help),
+	})
+	return nil
+}
+
+// KeyBindings returns all registered key bindings grouped by pane (in the order the panes were first registered).
+func KeyBindings() (panes []string, bindings map[string][]KeyBinding) {
+	bindings = make(map[string][]KeyBinding)
+	for _, binding := range keyBindingRegistry {
+		if _, ok := bindings[binding.Pane]; !ok {
+			panes = append(panes, binding.Pane)
+		}
+		bindings[binding.Pane] = append(bindings[binding.Pane], binding)
+	}
+	return panes, bindings
+}
@@ -2,10 +2,12 @@ package ui
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/dustin/go-humanize"
 	"github.com/jroimartin/gocui"
 	"github.com/lunixbochs/vtclean"
+	"github.com/wagoodman/dive/i18n"
 	"github.com/wagoodman/dive/image"
 	"strings"
 )
@@ -21,6 +23,8 @@ type LayerView struct {
 	Layers            []*image.Layer
 	CompareMode       CompareType
 	CompareStartIndex int
+	// RawCommand shows the commands of the layers as recorded in the image, rather than normalized
+	RawCommand bool
 }
 
 // NewDetailsView creates a new view object attached the the global [gocui] screen object.
@@ -51,22 +55,45 @@ func (view *LayerView) Setup(v *gocui.View, header *gocui.View) error {
 	view.header.Frame = false
 
 	// set keybindings
-	if err := view.gui.SetKeybinding(view.Name, gocui.KeyArrowDown, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return view.CursorDown() }); err != nil {
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyArrowDown, "Layers", "↓", "Select the next layer", func(*gocui.Gui, *gocui.View) error { return view.CursorDown() }); err != nil {
 		return err
 	}
-	if err := view.gui.SetKeybinding(view.Name, gocui.KeyArrowUp, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return view.CursorUp() }); err != nil {
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyArrowUp, "Layers", "↑", "Select the previous layer", func(*gocui.Gui, *gocui.View) error { return view.CursorUp() }); err != nil {
 		return err
 	}
-	if err := view.gui.SetKeybinding(view.Name, gocui.KeyCtrlL, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return view.setCompareMode(CompareLayer) }); err != nil {
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyCtrlL, "Layers", "^L", "Show layer changes", func(*gocui.Gui, *gocui.View) error { return view.setCompareMode(CompareLayer) }); err != nil {
 		return err
 	}
-	if err := view.gui.SetKeybinding(view.Name, gocui.KeyCtrlA, gocui.ModNone, func(*gocui.Gui, *gocui.View) error { return view.setCompareMode(CompareAll) }); err != nil {
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyCtrlA, "Layers", "^A", "Show aggregated changes", func(*gocui.Gui, *gocui.View) error { return view.setCompareMode(CompareAll) }); err != nil {
+		return err
+	}
+	if err := registerKeyBinding(view.gui, view.Name, 'y', "Layers", "y", "Copy the digest of the selected layer", func(*gocui.Gui, *gocui.View) error { return copyAndNotify("layer digest", view.currentLayer().Id()) }); err != nil {
+		return err
+	}
+	if err := registerKeyBinding(view.gui, view.Name, 'c', "Layers", "c", "Copy the command that created the selected layer", func(*gocui.Gui, *gocui.View) error { return copyAndNotify("layer command", view.currentCommand()) }); err != nil {
+		return err
+	}
+	if err := registerKeyBinding(view.gui, view.Name, 'r', "Layers", "r", "Toggle the raw command of the selected layer", func(*gocui.Gui, *gocui.View) error { return view.toggleRawCommand() }); err != nil {
 		return err
 	}
 
 	return view.Render()
 }
 
+// currentCommand returns the command that created the selected layer, as shown in the details pane.
+func (view *LayerView) currentCommand() string {
+	if view.RawCommand {
+		return view.currentLayer().History.CreatedBy
+	}
+	return view.currentLayer().Command()
+}
+
+// toggleRawCommand switches the details pane between the normalized and the raw command of the selected layer.
+func (view *LayerView) toggleRawCommand() error {
+	view.RawCommand = !view.RawCommand
+	return Views.Details.Render()
+}
+
 // IsVisible indicates if the layer view pane is currently initialized.
 func (view *LayerView) IsVisible() bool {
 	if view == nil {
@@ -165,7 +192,7 @@ func (view *LayerView) Update() error {
 func (view *LayerView) Render() error {
 
 	// indicate when selected
-	title := "Layers"
+	title := i18n.T("Layers")
 	if view.gui.CurrentView() == view.view {
 		title = "● " + title
 	}
@@ -174,29 +201,29 @@ func (view *LayerView) Render() error {
 		// update header
 		view.header.Clear()
 		width, _ := g.Size()
+		level := detailLevelOf(width)
 		headerStr := fmt.Sprintf("[%s]%s\n", title, strings.Repeat("─", width*2))
-		headerStr += fmt.Sprintf("Cmp "+image.LayerFormat, "Image ID", "Size", "Command")
+		switch level {
+		case compactDetail:
+			headerStr += fmt.Sprintf("Cmp "+image.CompactLayerFormat, "Size", "Command")
+		case wideDetail:
+			headerStr += fmt.Sprintf("Cmp "+image.WideLayerFormat, "Index", "Image ID", "Digest", "Size", "Command")
+		default:
+			headerStr += fmt.Sprintf("Cmp "+image.LayerFormat, "Image ID", "Size", "Command")
+		}
 		fmt.Fprintln(view.header, Formatting.Header(vtclean.Clean(headerStr, false)))
 
 		// update contents
 		view.view.Clear()
+		paneWidth, _ := view.view.Size()
 		for revIdx := len(view.Layers) - 1; revIdx >= 0; revIdx-- {
 			layer := view.Layers[revIdx]
 			idx := (len(view.Layers) - 1) - revIdx
 
-			layerStr := layer.String()
-			if idx == 0 {
-				var layerId string
-				if len(layer.History.ID) >= 25 {
-					layerId = layer.History.ID[0:25]
-				} else {
-					layerId = fmt.Sprintf("%-25s", layer.History.ID)
-				}
-
-				layerStr = fmt.Sprintf(image.LayerFormat, layerId, humanize.Bytes(uint64(layer.History.Size)), "FROM "+layer.ShortId())
-			}
-
+			layerStr := layerRow(layer, idx, level)
 			compareBar := view.renderCompareBar(idx)
+			// the compare bar and its margin take four columns
+			layerStr = fitLine(layerStr, paneWidth-4)
 
 			if idx == view.LayerIndex {
 				fmt.Fprintln(view.view, compareBar+"  "+Formatting.Selected(layerStr))
@@ -210,6 +237,35 @@ func (view *LayerView) Render() error {
 	return nil
 }
 
+// layerRow represents the layer at the given index (counting from the oldest layer) with the columns of the given
+// detail level. The oldest layer is shown as the base image.
+func layerRow(layer *image.Layer, idx int, level detailLevel) string {
+	id, command := layer.ShortId(), layer.Command()
+	if idx == 0 {
+		if len(layer.History.ID) >= 25 {
+			id = layer.History.ID[0:25]
+		} else {
+			id = fmt.Sprintf("%-25s", layer.History.ID)
+		}
+		command = "FROM " + layer.ShortId()
+	}
+	size := humanize.Bytes(uint64(layer.History.Size))
+	if layer.MetadataOnly != "" {
+		// the contents of the layer could not be read, so neither is its size known
+		size = "-"
+		command = i18n.T("(metadata only) ") + command
+	}
+
+	switch level {
+	case compactDetail:
+		return fmt.Sprintf(image.CompactLayerFormat, size, command)
+	case wideDetail:
+		return fmt.Sprintf(image.WideLayerFormat, strconv.Itoa(idx), id, layer.ShortDigest(), size, command)
+	default:
+		return fmt.Sprintf(image.LayerFormat, id, size, command)
+	}
+}
+
 // KeyHelp indicates all the possible actions a user can take while the current pane is selected.
 func (view *LayerView) KeyHelp() string {
 	return renderStatusOption("^L", "Show layer changes", view.CompareMode == CompareLayer) +
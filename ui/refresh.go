@@ -0,0 +1,87 @@
+package ui
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jroimartin/gocui"
+)
+
+// MaxFPS caps how many times per second the panes are redrawn while navigating (e.g. when holding down an arrow key
+// on a huge tree), a value of 0 or less redraws on every key press.
+var MaxFPS = 30
+
+// redraws tracks the panes that changed since they were last drawn (in the order they changed), which are drawn
+// together on the next frame.
+var redraws struct {
+	lock    sync.Mutex
+	dirty   []View
+	pending bool
+	last    time.Time
+}
+
+// requestRender marks the given views as changed. Rather than drawing them right away, the changed views are drawn on
+// the next frame (at most MaxFPS frames per second, in the order given), such that a burst of key presses is drawn
+// once.
+func requestRender(g *gocui.Gui, views ...View) error {
+	if MaxFPS <= 0 || g == nil {
+		for _, view := range views {
+			if err := view.Render(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	redraws.lock.Lock()
+	defer redraws.lock.Unlock()
+	for _, view := range views {
+		if !containsView(redraws.dirty, view) {
+			redraws.dirty = append(redraws.dirty, view)
+		}
+	}
+	if redraws.pending {
+		return nil
+	}
+	redraws.pending = true
+
+	delay := time.Second/time.Duration(MaxFPS) - time.Since(redraws.last)
+	if delay < 0 {
+		delay = 0
+	}
+	time.AfterFunc(delay, func() {
+		g.Update(func(*gocui.Gui) error {
+			return renderDirty()
+		})
+	})
+	return nil
+}
+
+// renderDirty draws every view that changed since the last frame.
+func renderDirty() error {
+	redraws.lock.Lock()
+	dirty := redraws.dirty
+	redraws.dirty = nil
+	redraws.pending = false
+	redraws.last = time.Now()
+	redraws.lock.Unlock()
+
+	for _, view := range dirty {
+		if !view.IsVisible() {
+			continue
+		}
+		if err := view.Render(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func containsView(views []View, view View) bool {
+	for _, existing := range views {
+		if existing == view {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+package ui
+
+import (
+	"io/ioutil"
+	"strings"
+)
+
+// maxFilterHistory is the number of filter queries kept in the history.
+const maxFilterHistory = 100
+
+// SearchStore holds the history of the filter queries (the most recent last) and where it is saved, along with the
+// named searches of the config, which are applied by filtering with "@name".
+type SearchStore struct {
+	History     []string
+	HistoryPath string
+	Saved       map[string]string
+	// Save persists a named search (e.g. to the user config)
+	Save func(name, query string) error
+}
+
+// Searches are the filter history and the saved searches (neither is kept when nil).
+var Searches *SearchStore
+
+// remember adds the given query to the end of the history (moving it there when it is in the history already) and
+// saves the history.
+func (store *SearchStore) remember(query string) error {
+	query = strings.TrimSpace(query)
+	if store == nil || query == "" {
+		return nil
+	}
+	history := make([]string, 0, len(store.History)+1)
+	for _, entry := range store.History {
+		if entry != query {
+			history = append(history, entry)
+		}
+	}
+	history = append(history, query)
+	if len(history) > maxFilterHistory {
+		history = history[len(history)-maxFilterHistory:]
+	}
+	store.History = history
+	if store.HistoryPath == "" {
+		return nil
+	}
+	return ioutil.WriteFile(store.HistoryPath, []byte(strings.Join(history, "\n")+"\n"), 0600)
+}
+
+// expand returns the query of the saved search named by the given filter ("@name"), or the filter itself.
+func (store *SearchStore) expand(filter string) string {
+	if store == nil || !strings.HasPrefix(filter, "@") {
+		return filter
+	}
+	if query, ok := store.Saved[strings.TrimPrefix(filter, "@")]; ok {
+		return query
+	}
+	return filter
+}
+
+// save names the given query, such that it can be applied with "@name", and persists it.
+func (store *SearchStore) save(name, query string) error {
+	if store.Saved == nil {
+		store.Saved = make(map[string]string)
+	}
+	store.Saved[name] = query
+	if store.Save == nil {
+		return nil
+	}
+	return store.Save(name, query)
+}
+
+// ReadFilterHistory parses a saved filter history: one query per line, the most recent last.
+func ReadFilterHistory(contents string) []string {
+	var history []string
+	for _, line := range strings.Split(contents, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			history = append(history, line)
+		}
+	}
+	return history
+}
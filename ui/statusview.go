@@ -4,15 +4,17 @@ import (
 	"fmt"
 
 	"github.com/jroimartin/gocui"
+	"github.com/wagoodman/dive/i18n"
 	"strings"
 )
 
 // DetailsView holds the UI objects and data models for populating the bottom-most pane. Specifcially the panel
 // shows the user a set of possible actions to take in the window and currently selected pane.
 type StatusView struct {
-	Name string
-	gui  *gocui.Gui
-	view *gocui.View
+	Name    string
+	gui     *gocui.Gui
+	view    *gocui.View
+	message string
 }
 
 // NewStatusView creates a new view object attached the the global [gocui] screen object.
@@ -56,16 +58,27 @@ func (view *StatusView) CursorUp() error {
 	return nil
 }
 
-// Update refreshes the state objects for future rendering (currently does nothing).
+// Update refreshes the state objects for future rendering, dropping any message shown for a previous action.
 func (view *StatusView) Update() error {
+	view.message = ""
 	return nil
 }
 
+// showMessage briefly tells the user the outcome of an action (until the next update).
+func (view *StatusView) showMessage(message string) {
+	view.message = message
+	view.Render()
+}
+
 // Render flushes the state objects to the screen.
 func (view *StatusView) Render() error {
 	view.gui.Update(func(g *gocui.Gui) error {
 		view.view.Clear()
-		fmt.Fprintln(view.view, view.KeyHelp()+Views.lookup[view.gui.CurrentView().Name()].KeyHelp()+Formatting.StatusNormal("▏"+strings.Repeat(" ", 1000)))
+		message := ""
+		if view.message != "" {
+			message = Formatting.StatusSelected("▏" + view.message + " ")
+		}
+		fmt.Fprintln(view.view, view.KeyHelp()+Views.lookup[view.gui.CurrentView().Name()].KeyHelp()+message+Formatting.StatusNormal("▏"+strings.Repeat(" ", 1000)))
 
 		return nil
 	})
@@ -75,7 +88,17 @@ func (view *StatusView) Render() error {
 
 // KeyHelp indicates all the possible global actions a user can take when any pane is selected.
 func (view *StatusView) KeyHelp() string {
+	help := renderStatusOption("?", "Key bindings", Views.Help.IsVisible())
+	if !Views.Help.seen {
+		// point first-time users at the full key reference until they have opened it
+		help = renderStatusOption("?", "Press ? to see all key bindings", true)
+	}
+	if count := len(Views.Warnings.Warnings); count > 0 {
+		// make silently incomplete data visible
+		help += renderStatusOption("W", i18n.Tf("%d warnings", count), Views.Warnings.IsVisible())
+	}
 	return renderStatusOption("^C", "Quit", false) +
 		renderStatusOption("^Space", "Switch view", false) +
-		renderStatusOption("^/", "Filter files", Views.Filter.IsVisible())
+		renderStatusOption("^/", "Filter files", Views.Filter.IsVisible()) +
+		help
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/fatih/color"
 	"github.com/jroimartin/gocui"
 	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/i18n"
 	"github.com/wagoodman/dive/image"
 	"log"
 )
@@ -41,12 +42,14 @@ var Formatting struct {
 
 // Views contains all rendered UI panes.
 var Views struct {
-	Tree    *FileTreeView
-	Layer   *LayerView
-	Status  *StatusView
-	Filter  *FilterView
-	Details *DetailsView
-	lookup  map[string]View
+	Tree     *FileTreeView
+	Layer    *LayerView
+	Status   *StatusView
+	Filter   *FilterView
+	Details  *DetailsView
+	Help     *HelpView
+	Warnings *WarningsView
+	lookup   map[string]View
 }
 
 // View defines the a renderable terminal screen pane.
@@ -76,7 +79,11 @@ func toggleView(g *gocui.Gui, v *gocui.View) error {
 
 // toggleFilterView shows/hides the file tree filter pane.
 func toggleFilterView(g *gocui.Gui, v *gocui.View) error {
-	// delete all user input from the tree view
+	// keep the filter in the history, then delete all user input from the tree view
+	if !Views.Filter.hidden {
+		Views.Filter.remember()
+	}
+	Views.Filter.reset()
 	Views.Filter.view.Clear()
 	Views.Filter.view.SetCursor(0, 0)
 
@@ -140,16 +147,33 @@ func quit(g *gocui.Gui, v *gocui.View) error {
 
 // keyBindings registers global key press actions, valid when in any pane.
 func keyBindings(g *gocui.Gui) error {
-	if err := g.SetKeybinding("", gocui.KeyCtrlC, gocui.ModNone, quit); err != nil {
+	if err := registerKeyBinding(g, "", gocui.KeyCtrlC, "Global", "^C", "Quit", quit); err != nil {
 		return err
 	}
 	//if err := g.SetKeybinding("main", gocui.MouseLeft, gocui.ModNone, toggleCollapse); err != nil {
 	//	return err
 	//}
-	if err := g.SetKeybinding("", gocui.KeyCtrlSpace, gocui.ModNone, toggleView); err != nil {
+	if err := registerKeyBinding(g, "", gocui.KeyCtrlSpace, "Global", "^Space", "Switch view", toggleView); err != nil {
+		return err
+	}
+	if err := registerKeyBinding(g, "", gocui.KeyCtrlSlash, "Global", "^/", "Filter files", toggleFilterView); err != nil {
+		return err
+	}
+	// '?' is a valid filter character, so the overlay cannot be bound globally (the filter pane would lose it)
+	for _, name := range []string{Views.Tree.Name, Views.Layer.Name, Views.Details.Name} {
+		if err := registerKeyBinding(g, name, '?', "Global", "?", "Show key bindings", toggleHelpView); err != nil {
+			return err
+		}
+	}
+	if err := Views.Help.keyBindings(); err != nil {
 		return err
 	}
-	if err := g.SetKeybinding("", gocui.KeyCtrlSlash, gocui.ModNone, toggleFilterView); err != nil {
+	for _, name := range []string{Views.Tree.Name, Views.Layer.Name, Views.Details.Name} {
+		if err := registerKeyBinding(g, name, 'W', "Global", "W", "Show analysis warnings", toggleWarningsView); err != nil {
+			return err
+		}
+	}
+	if err := Views.Warnings.keyBindings(); err != nil {
 		return err
 	}
 
@@ -176,6 +200,10 @@ func layout(g *gocui.Gui) error {
 
 	maxX, maxY := g.Size()
 	splitCols := maxX / 2
+	if detailLevelOf(maxX) == compactDetail {
+		// the layer pane has fewer columns to show, leaving more room for the file tree
+		splitCols = maxX * 2 / 5
+	}
 	debugWidth := 0
 	if debug {
 		debugWidth = maxX / 4
@@ -253,6 +281,36 @@ func layout(g *gocui.Gui) error {
 		Views.Filter.Setup(view, header)
 	}
 
+	// Help overlay
+	if Views.Help.IsVisible() {
+		view, viewErr = g.SetView(Views.Help.Name, maxX/6, maxY/6, maxX*5/6, maxY*5/6)
+		if isNewView(viewErr) {
+			Views.Help.Setup(view, nil)
+			if _, err = g.SetCurrentView(Views.Help.Name); err != nil {
+				return err
+			}
+			if _, err = g.SetViewOnTop(Views.Help.Name); err != nil {
+				return err
+			}
+			Views.Status.Render()
+		}
+	}
+
+	// Warnings overlay
+	if Views.Warnings.IsVisible() {
+		view, viewErr = g.SetView(Views.Warnings.Name, maxX/8, maxY/6, maxX*7/8, maxY*5/6)
+		if isNewView(viewErr) {
+			Views.Warnings.Setup(view, nil)
+			if _, err = g.SetCurrentView(Views.Warnings.Name); err != nil {
+				return err
+			}
+			if _, err = g.SetViewOnTop(Views.Warnings.Name); err != nil {
+				return err
+			}
+			Views.Status.Render()
+		}
+	}
+
 	return nil
 }
 
@@ -274,6 +332,7 @@ func Render() {
 
 // renderStatusOption formats key help bindings-to-title pairs.
 func renderStatusOption(control, title string, selected bool) string {
+	title = i18n.T(title)
 	if selected {
 		return Formatting.StatusSelected("▏") + Formatting.StatusControlSelected(control) + Formatting.StatusSelected("  "+title+" ")
 	} else {
@@ -313,7 +372,13 @@ func Run(layers []*image.Layer, refTrees []*filetree.FileTree, efficiency float6
 	Views.Filter = NewFilterView("command", g)
 	Views.lookup[Views.Filter.Name] = Views.Filter
 
-	Views.Details = NewDetailsView("details", g, efficiency, inefficiencies)
+	Views.Help = NewHelpView("help", g)
+	Views.lookup[Views.Help.Name] = Views.Help
+
+	Views.Warnings = NewWarningsView("warnings", g, filetree.CollectWarnings(refTrees))
+	Views.lookup[Views.Warnings.Name] = Views.Warnings
+
+	Views.Details = NewDetailsView("details", g, efficiency, inefficiencies, filetree.Reintroductions(refTrees), filetree.CapabilityFindings(refTrees), filetree.Duplicates(refTrees))
 	Views.lookup[Views.Details.Name] = Views.Details
 
 	g.Cursor = false
@@ -331,6 +396,12 @@ func Run(layers []*image.Layer, refTrees []*filetree.FileTree, efficiency float6
 		log.Panicln(err)
 	}
 
+	analyzers := []analyzer{largeFileAnalyzer, capabilityAnalyzer, packageDBAnalyzer, wastedFileAnalyzer(inefficiencies)}
+	if ImageAccess != nil {
+		analyzers = append(analyzers, startupAccessAnalyzer(ImageAccess.Profile))
+	}
+	startAnalyzers(g, refTrees, analyzers)
+
 	if err := g.MainLoop(); err != nil && err != gocui.ErrQuit {
 		log.Panicln(err)
 	}
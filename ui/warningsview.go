@@ -0,0 +1,152 @@
+package ui
+
+import (
+	"fmt"
+
+	"github.com/jroimartin/gocui"
+	"github.com/wagoodman/dive/filetree"
+	"github.com/wagoodman/dive/i18n"
+)
+
+// WarningsView holds the UI objects for the overlay listing the non-fatal issues found while analyzing the image
+// (e.g. unreadable xattrs or skipped tar entries), which mean the other panes may be incomplete for some paths.
+type WarningsView struct {
+	Name     string
+	gui      *gocui.Gui
+	view     *gocui.View
+	hidden   bool
+	previous string
+	Warnings []filetree.Warning
+}
+
+// NewWarningsView creates a new view object attached the the global [gocui] screen object.
+func NewWarningsView(name string, gui *gocui.Gui, warnings []filetree.Warning) (warningsView *WarningsView) {
+	warningsView = new(WarningsView)
+
+	// populate main fields
+	warningsView.Name = name
+	warningsView.gui = gui
+	warningsView.hidden = true
+	warningsView.Warnings = warnings
+
+	return warningsView
+}
+
+// Setup initializes the UI concerns within the context of a global [gocui] view object.
+func (view *WarningsView) Setup(v *gocui.View, header *gocui.View) error {
+
+	// set view options
+	view.view = v
+	view.view.Editable = false
+	view.view.Wrap = true
+	view.view.Frame = true
+	view.view.Title = i18n.T("Analysis warnings (press W or Esc to close)")
+
+	return view.Render()
+}
+
+// keyBindings registers the actions available while the overlay is shown. This is done once since the overlay
+// pane is created and deleted each time it is toggled.
+func (view *WarningsView) keyBindings() error {
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyArrowDown, "Warnings", "↓", "Scroll down", func(*gocui.Gui, *gocui.View) error { return view.CursorDown() }); err != nil {
+		return err
+	}
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyArrowUp, "Warnings", "↑", "Scroll up", func(*gocui.Gui, *gocui.View) error { return view.CursorUp() }); err != nil {
+		return err
+	}
+	if err := registerKeyBinding(view.gui, view.Name, gocui.KeyEsc, "Warnings", "Esc", "Close analysis warnings", toggleWarningsView); err != nil {
+		return err
+	}
+	return registerKeyBinding(view.gui, view.Name, 'W', "Warnings", "W", "Close analysis warnings", toggleWarningsView)
+}
+
+// IsVisible indicates if the warnings overlay is currently shown.
+func (view *WarningsView) IsVisible() bool {
+	if view == nil {
+		return false
+	}
+	return !view.hidden
+}
+
+// CursorDown scrolls the overlay down.
+func (view *WarningsView) CursorDown() error {
+	return CursorDown(view.gui, view.view)
+}
+
+// CursorUp scrolls the overlay up.
+func (view *WarningsView) CursorUp() error {
+	return CursorUp(view.gui, view.view)
+}
+
+// Update refreshes the state objects for future rendering (currently does nothing).
+func (view *WarningsView) Update() error {
+	return nil
+}
+
+// Render flushes the warnings, grouped by layer, to the overlay.
+func (view *WarningsView) Render() error {
+	if view.view == nil {
+		return nil
+	}
+
+	view.gui.Update(func(g *gocui.Gui) error {
+		view.view.Clear()
+		out := cellWriter{view.view}
+		if len(view.Warnings) == 0 {
+			fmt.Fprintln(out, i18n.T("No issues were found while analyzing the image."))
+			return nil
+		}
+		layer := "-"
+		for _, warning := range view.Warnings {
+			if warning.Layer != layer {
+				if layer != "-" {
+					fmt.Fprintln(out)
+				}
+				layer = warning.Layer
+				if layer == "" {
+					fmt.Fprintln(out, Formatting.Header(i18n.T("Final filesystem")))
+				} else {
+					fmt.Fprintln(out, Formatting.Header(i18n.Tf("Layer %s", layer)))
+				}
+			}
+			fmt.Fprintf(out, "  %-14s %s: %s\n", warning.Kind, warning.Path, warning.Message)
+		}
+		return nil
+	})
+	return nil
+}
+
+// KeyHelp indicates all the possible actions a user can take while the overlay is shown.
+func (view *WarningsView) KeyHelp() string {
+	return renderStatusOption("Esc", "Close analysis warnings", false)
+}
+
+// toggleWarningsView shows/hides the warnings overlay, returning the focus to the previously selected pane on close.
+func toggleWarningsView(g *gocui.Gui, v *gocui.View) error {
+	view := Views.Warnings
+
+	if view.hidden {
+		if current := g.CurrentView(); current != nil {
+			view.previous = current.Name()
+		}
+		view.hidden = false
+		// the overlay pane itself is created (and selected) on the next layout pass
+		Update()
+		Render()
+		return nil
+	}
+
+	view.hidden = true
+	view.view = nil
+	if err := g.DeleteView(view.Name); err != nil && err != gocui.ErrUnknownView {
+		return err
+	}
+	if view.previous != "" {
+		if _, err := g.SetCurrentView(view.previous); err != nil {
+			return err
+		}
+	}
+	Update()
+	Render()
+	return nil
+}
@@ -0,0 +1,28 @@
+package ui
+
+import (
+	"io"
+
+	"github.com/wagoodman/dive/utils"
+)
+
+// fitLine fits a line to a pane of the given width that does not wrap: the line is shortened with an ellipsis (see
+// utils.Truncate) and aligned to the cells gocui draws (see utils.CellAligned), such that wide and combining characters
+// (e.g. in CJK or emoji file names) neither shift the columns nor draw over the border of the pane.
+func fitLine(line string, width int) string {
+	return utils.CellAligned(utils.Truncate(line, width))
+}
+
+// cellWriter aligns everything written to a pane that wraps its lines to the cells gocui draws (see
+// utils.CellAligned), which gocui then wraps at the width of the pane.
+type cellWriter struct {
+	io.Writer
+}
+
+// Write aligns and writes the given text, which should hold whole characters (as every fmt.Fprint call does).
+func (writer cellWriter) Write(text []byte) (int, error) {
+	if _, err := io.WriteString(writer.Writer, utils.CellAligned(string(text))); err != nil {
+		return 0, err
+	}
+	return len(text), nil
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// DockerContext is the docker context that docker commands are run against (the current context when empty).
+var DockerContext string
+
+// DockerEndpoint is the daemon endpoint of a docker context.
+type DockerEndpoint struct {
+	// Host is the address of the daemon, e.g. "unix:///var/run/docker.sock", "tcp://10.0.0.2:2376" or
+	// "ssh://user@host" (reached by running "docker system dial-stdio" on the host)
+	Host string
+	// TLSPath is the directory holding ca.pem, cert.pem and key.pem for the endpoint (empty when not using TLS)
+	TLSPath string
+}
+
+// dockerContextInspect is the part of "docker context inspect" output needed to connect to a context's daemon.
+type dockerContextInspect struct {
+	Name      string
+	Endpoints struct {
+		Docker struct {
+			Host string
+		} `json:"docker"`
+	}
+	Storage struct {
+		TLSPath string
+	}
+}
+
+// DockerContextEndpoint returns the daemon endpoint of the given docker context (or of the current context when the
+// name is empty), as configured with "docker context".
+func DockerContextEndpoint(name string) (DockerEndpoint, error) {
+	args := []string{"context", "inspect"}
+	if name != "" {
+		args = append(args, name)
+	}
+	output, err := exec.Command("docker", args...).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return DockerEndpoint{}, fmt.Errorf("could not inspect docker context: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return DockerEndpoint{}, fmt.Errorf("could not inspect docker context: %v", err)
+	}
+
+	var contexts []dockerContextInspect
+	if err := json.Unmarshal(output, &contexts); err != nil || len(contexts) == 0 {
+		return DockerEndpoint{}, fmt.Errorf("could not read docker context: %v", err)
+	}
+	context := contexts[0]
+
+	endpoint := DockerEndpoint{Host: context.Endpoints.Docker.Host}
+	if context.Storage.TLSPath != "" {
+		tlsPath := filepath.Join(context.Storage.TLSPath, "docker")
+		if _, err := os.Stat(filepath.Join(tlsPath, "ca.pem")); err == nil {
+			endpoint.TLSPath = tlsPath
+		}
+	}
+	return endpoint, nil
+}
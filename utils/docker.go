@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
 	"strings"
@@ -10,6 +11,9 @@ import (
 func RunDockerCmd(cmdStr string, args ...string) error {
 
 	allArgs := cleanArgs(append([]string{cmdStr}, args...))
+	if DockerContext != "" {
+		allArgs = append([]string{"--context", DockerContext}, allArgs...)
+	}
 
 	cmd := exec.Command("docker", allArgs...)
 
@@ -30,3 +34,17 @@ func cleanArgs(s []string) []string {
 	}
 	return r
 }
+
+// DockerCmdOutput runs a given Docker command and returns its output (instead of showing it on the tty)
+func DockerCmdOutput(cmdStr string, args ...string) ([]byte, error) {
+	allArgs := cleanArgs(append([]string{cmdStr}, args...))
+	if DockerContext != "" {
+		allArgs = append([]string{"--context", DockerContext}, allArgs...)
+	}
+
+	output, err := exec.Command("docker", allArgs...).Output()
+	if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+		return output, fmt.Errorf("docker %s: %s", cmdStr, strings.TrimSpace(string(exitErr.Stderr)))
+	}
+	return output, err
+}
@@ -5,6 +5,14 @@ import (
 	"os"
 )
 
+// cleanups are run (once) by Cleanup.
+var cleanups []func()
+
+// OnCleanup registers a function to run when dive exits (see Cleanup).
+func OnCleanup(cleanup func()) {
+	cleanups = append(cleanups, cleanup)
+}
+
 // Note: this should only be used when exiting from non-gocui code
 func Exit(rc int) {
 	Cleanup()
@@ -12,5 +20,10 @@ func Exit(rc int) {
 }
 
 func Cleanup() {
+	pending := cleanups
+	cleanups = nil
+	for _, cleanup := range pending {
+		cleanup()
+	}
 	ansi.CursorShow()
 }
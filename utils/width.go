@@ -0,0 +1,165 @@
+package utils
+
+import (
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/rivo/uniseg"
+)
+
+// ellipsis ends text shortened by Truncate.
+const ellipsis = "…"
+
+// ansiReset ends the styling of text shortened within an ANSI escape sequence (see Truncate).
+const ansiReset = "\x1b[0m"
+
+// splitEscapes calls the given function with the printable runs of the text and its ANSI escape sequences (as used for
+// colors), in order, until the function returns false.
+func splitEscapes(text string, each func(segment string, escape bool) bool) {
+	for len(text) > 0 {
+		start := strings.Index(text, "\x1b[")
+		if start < 0 {
+			each(text, false)
+			return
+		}
+		if start > 0 && !each(text[:start], false) {
+			return
+		}
+		// the sequence ends with its final byte (a letter, or any of @[\]^_`{|}~)
+		end := start + 2
+		for end < len(text) && (text[end] < 0x40 || text[end] > 0x7e) {
+			end++
+		}
+		if end < len(text) {
+			end++
+		}
+		if !each(text[start:end], true) {
+			return
+		}
+		text = text[end:]
+	}
+}
+
+// graphemeWidth returns the columns a single grapheme (a user-perceived character, such as a letter with its combining
+// accents or an emoji sequence) takes: the width of its first rune with any width, as terminals draw it.
+func graphemeWidth(runes []rune) int {
+	for _, r := range runes {
+		if width := runewidth.RuneWidth(r); width > 0 {
+			return width
+		}
+	}
+	return 0
+}
+
+// DisplayWidth returns the number of terminal columns the text takes: wide characters (such as CJK characters and
+// most emoji) take two columns and combining characters none. ANSI escape sequences take no columns.
+func DisplayWidth(text string) int {
+	var width int
+	splitEscapes(text, func(segment string, escape bool) bool {
+		if !escape {
+			graphemes := uniseg.NewGraphemes(segment)
+			for graphemes.Next() {
+				width += graphemeWidth(graphemes.Runes())
+			}
+		}
+		return true
+	})
+	return width
+}
+
+// Truncate shortens the text to at most the given number of terminal columns (see DisplayWidth), ending it with an
+// ellipsis when shortened. Characters are never split, and the ANSI escape sequences before the cut are kept (the
+// styling is reset after the ellipsis).
+func Truncate(text string, width int) string {
+	if DisplayWidth(text) <= width {
+		return text
+	}
+	if width < 1 {
+		return ""
+	}
+
+	var result strings.Builder
+	var styled bool
+	used := runewidth.StringWidth(ellipsis)
+	splitEscapes(text, func(segment string, escape bool) bool {
+		if escape {
+			result.WriteString(segment)
+			styled = true
+			return true
+		}
+		graphemes := uniseg.NewGraphemes(segment)
+		for graphemes.Next() {
+			graphemeColumns := graphemeWidth(graphemes.Runes())
+			if used+graphemeColumns > width {
+				return false
+			}
+			result.WriteString(graphemes.Str())
+			used += graphemeColumns
+		}
+		return true
+	})
+	result.WriteString(ellipsis)
+	if styled {
+		result.WriteString(ansiReset)
+	}
+	return result.String()
+}
+
+// ColumnIndex returns the byte offset in the text (without ANSI escape sequences) of the first character that does not
+// fit within the given number of terminal columns (see DisplayWidth), or the length of the text when it all fits.
+func ColumnIndex(text string, columns int) int {
+	var used int
+	graphemes := uniseg.NewGraphemes(text)
+	for graphemes.Next() {
+		used += graphemeWidth(graphemes.Runes())
+		if used > columns {
+			start, _ := graphemes.Positions()
+			return start
+		}
+	}
+	return len(text)
+}
+
+// CellAligned rewrites the text for a terminal library that draws every rune in a cell of its own (such as gocui),
+// such that it takes as many cells as it takes columns on the terminal: each grapheme is reduced to its first rune with
+// any width (dropping combining characters, which would otherwise take a cell of their own), and each wide rune is
+// followed by a space for the cell it covers (which the terminal skips). ANSI escape sequences are kept.
+func CellAligned(text string) string {
+	if isASCII(text) {
+		return text
+	}
+	var result strings.Builder
+	splitEscapes(text, func(segment string, escape bool) bool {
+		if escape {
+			result.WriteString(segment)
+			return true
+		}
+		graphemes := uniseg.NewGraphemes(segment)
+		for graphemes.Next() {
+			runes := graphemes.Runes()
+			if len(runes) == 1 && runewidth.RuneWidth(runes[0]) < 2 {
+				result.WriteRune(runes[0])
+				continue
+			}
+			for _, r := range runes {
+				if width := runewidth.RuneWidth(r); width > 0 {
+					result.WriteRune(r)
+					result.WriteString(strings.Repeat(" ", width-1))
+					break
+				}
+			}
+		}
+		return true
+	})
+	return result.String()
+}
+
+// isASCII indicates if the text holds ASCII characters only, which always take a single cell.
+func isASCII(text string) bool {
+	for idx := 0; idx < len(text); idx++ {
+		if text[idx] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,136 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// family is an emoji sequence of several emoji joined by zero width joiners, which is drawn as a single emoji.
+const family = "\U0001F468\u200d\U0001F469\u200d\U0001F467"
+
+// fixedWidths makes ambiguous characters narrow for the duration of the test, regardless of the locale it runs in.
+func fixedWidths(t *testing.T) {
+	eastAsian := runewidth.DefaultCondition.EastAsianWidth
+	runewidth.DefaultCondition.EastAsianWidth = false
+	t.Cleanup(func() { runewidth.DefaultCondition.EastAsianWidth = eastAsian })
+}
+
+func TestDisplayWidth(t *testing.T) {
+	fixedWidths(t)
+	cases := []struct {
+		name     string
+		text     string
+		expected int
+	}{
+		{"empty", "", 0},
+		{"ascii", "/usr/bin/env", 12},
+		{"latin", "caf\u00e9", 4},
+		{"CJK", "日本語", 6},
+		{"hangul", "한국어.txt", 10},
+		{"halfwidth katakana", "ｶﾀｶﾅ", 4},
+		{"fullwidth letters", "ＡＢ", 4},
+		{"combining accent", "cafe\u0301", 4},
+		{"several combining marks", "a\u0323\u0301b", 2},
+		{"emoji", "\U0001F44D", 2},
+		{"emoji with skin tone", "\U0001F44D\U0001F3FD", 2},
+		{"emoji zwj sequence", family, 2},
+		{"text symbol with emoji presentation", "\u2764\ufe0f", 1},
+		{"zero width space", "a\u200bb", 2},
+		{"ansi colors", "\x1b[1;31m日本\x1b[0m ok", 7},
+		{"truncated escape", "ab\x1b[31", 2},
+	}
+	for _, test := range cases {
+		if width := DisplayWidth(test.text); width != test.expected {
+			t.Errorf("%s: expected %q to take %d columns, got %d", test.name, test.text, test.expected, width)
+		}
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	fixedWidths(t)
+	cases := []struct {
+		name     string
+		text     string
+		width    int
+		expected string
+	}{
+		{"fits", "/etc/hosts", 10, "/etc/hosts"},
+		{"ascii", "/etc/hosts", 6, "/etc/…"},
+		{"no room", "/etc/hosts", 0, ""},
+		{"only the ellipsis", "/etc/hosts", 1, "…"},
+		{"CJK not split", "日本語のファイル", 6, "日本…"},
+		{"CJK odd width", "日本語のファイル", 7, "日本語…"},
+		{"combining kept with its letter", "cafe\u0301s and more", 5, "cafe\u0301…"},
+		{"emoji sequence not split", family + " family", 3, family + "…"},
+		{"styled", "\x1b[31mred text\x1b[0m", 4, "\x1b[31mred…\x1b[0m"},
+	}
+	for _, test := range cases {
+		truncated := Truncate(test.text, test.width)
+		if truncated != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, truncated)
+		}
+		if width := DisplayWidth(truncated); width > test.width {
+			t.Errorf("%s: expected at most %d columns, got %d", test.name, test.width, width)
+		}
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	fixedWidths(t)
+	cases := []struct {
+		text     string
+		columns  int
+		expected int
+	}{
+		{"abcdef", 3, 3},
+		{"abc", 10, 3},
+		{"日本語", 3, 3},
+		{"日本語", 4, 6},
+		{"e\u0301e\u0301", 1, 3},
+		{"\U0001F44D\U0001F44D", 2, 4},
+		{"", 5, 0},
+	}
+	for _, test := range cases {
+		if index := ColumnIndex(test.text, test.columns); index != test.expected {
+			t.Errorf("%q: expected the text to be cut at byte %d for %d columns, got %d", test.text, test.expected, test.columns, index)
+		}
+	}
+}
+
+func TestCellAligned(t *testing.T) {
+	fixedWidths(t)
+	cases := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{"ascii", "/etc/hosts", "/etc/hosts"},
+		{"CJK", "日本", "日 本 "},
+		{"combining dropped", "cafe\u0301", "cafe"},
+		{"emoji sequence", family + "!", "\U0001F468 !"},
+		{"ansi kept", "\x1b[1m語\x1b[0m", "\x1b[1m語 \x1b[0m"},
+	}
+	for _, test := range cases {
+		aligned := CellAligned(test.text)
+		if aligned != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, aligned)
+		}
+		// every rune takes a cell of its own, as many as the text takes columns
+		if cells := len([]rune(stripEscapes(aligned))); cells != DisplayWidth(test.text) {
+			t.Errorf("%s: expected %d cells, got %d", test.name, DisplayWidth(test.text), cells)
+		}
+	}
+}
+
+// stripEscapes drops the ANSI escape sequences of the text.
+func stripEscapes(text string) string {
+	var result string
+	splitEscapes(text, func(segment string, escape bool) bool {
+		if !escape {
+			result += segment
+		}
+		return true
+	})
+	return result
+}